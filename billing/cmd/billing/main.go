@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,16 +17,21 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"billing/internal/config"
+	"billing/internal/domain"
 	httpapi "billing/internal/http"
 	"billing/internal/metrics"
+	"billing/internal/notify"
 	"billing/internal/storage"
 	"billing/internal/tochka"
+	invoicesusecase "billing/internal/usecase/invoices"
 	sbpusecase "billing/internal/usecase/sbp"
 )
 
 func main() {
 	cfg := config.Load()
-	setupLogger()
+	if err := setupLogger(cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatal().Err(err).Msg("billing: invalid LOG_FORMAT/LOG_LEVEL")
+	}
 
 	if cfg.PGDSN == "" {
 		log.Fatal().Msg("billing: BILLING_PG_DSN is required")
@@ -48,7 +54,27 @@ func main() {
 	}
 	defer pool.Close()
 
-	billingRepo := storage.NewPostgres(pool)
+	billingRepo := storage.NewPostgres(pool,
+		storage.WithInvoiceTTL(cfg.Invoices.TTL),
+		storage.WithOrphanCredit(cfg.Invoices.AllowOrphanCredit),
+	)
+
+	expirer := invoicesusecase.NewExpirer(billingRepo, cfg.Invoices.ExpireInterval, log.With().Str("component", "invoices").Logger())
+	go expirer.Run(ctx)
+
+	var notifications domain.NotificationQueue
+	if cfg.Notifications.BotToken != "" {
+		notifications = billingRepo
+		worker := notify.NewWorker(billingRepo, notify.NewTelegramSender(cfg.Notifications.BotAPIURL, cfg.Notifications.BotToken, 0), notify.WorkerConfig{
+			PollInterval: cfg.Notifications.PollInterval,
+			MaxAttempts:  cfg.Notifications.MaxAttempts,
+			BaseBackoff:  cfg.Notifications.BaseBackoff,
+			MaxBackoff:   cfg.Notifications.MaxBackoff,
+		}, log.With().Str("component", "notify").Logger())
+		go worker.Run(ctx)
+	} else {
+		log.Warn().Msg("billing: TG_BOT_TOKEN is not set, balance notifications disabled")
+	}
 
 	var sbpService *sbpusecase.Service
 	var webhookKey *rsa.PublicKey
@@ -60,7 +86,10 @@ func main() {
 			AccessToken: cfg.Tochka.AccessToken,
 			Timeout:     cfg.Tochka.Timeout,
 		})
-		sbpService = sbpusecase.NewService(billingRepo, tochkaClient, cfg.Tochka.NotificationURL, log.With().Str("component", "sbp").Logger())
+		sbpService = sbpusecase.NewService(billingRepo, tochkaClient, cfg.Tochka.NotificationURL, notifications, log.With().Str("component", "sbp").Logger())
+		if cfg.Tochka.ReconcileEvery > 0 {
+			go sbpService.RunReconciler(ctx, cfg.Tochka.ReconcileEvery)
+		}
 		if cfg.Tochka.NotificationURL == "" {
 			log.Warn().Msg("billing: TOCHKA_NOTIFICATION_URL is not set, webhook callbacks may fail")
 		}
@@ -71,13 +100,22 @@ func main() {
 			}
 			webhookKey = key
 		}
+		switch {
+		case cfg.Tochka.WebhookStrict && webhookKey == nil:
+			log.Fatal().Msg("billing: TOCHKA_WEBHOOK_STRICT requires TOCHKA_WEBHOOK_PUBLIC_KEY")
+		case !cfg.Tochka.WebhookStrict:
+			log.Warn().Msg("billing: TOCHKA_WEBHOOK_STRICT is off, unsigned webhooks will be accepted and could credit balances")
+		}
 	} else {
 		log.Warn().Msg("billing: tochka credentials are not fully configured, SBP endpoints disabled")
 	}
 
 	opts := []httpapi.Option{httpapi.WithLogger(log.Logger)}
 	if sbpService != nil {
-		opts = append(opts, httpapi.WithSBPService(sbpService, cfg.Tochka.WebhookSecret, webhookKey))
+		opts = append(opts,
+			httpapi.WithSBPService(sbpService, cfg.Tochka.WebhookSecret, webhookKey),
+			httpapi.WithStrictWebhook(cfg.Tochka.WebhookStrict),
+		)
 	}
 	opts = append(opts, httpapi.WithAuthToken(cfg.APIToken))
 
@@ -131,9 +169,28 @@ func main() {
 	}
 }
 
-func setupLogger() {
-	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
-	log.Logger = zerolog.New(output).With().Timestamp().Logger()
+// setupLogger настраивает глобальный логгер так же, как в остальных сервисах: LOG_FORMAT=json|console
+// и LOG_LEVEL. Ошибку настройки сообщает уже через логгер в JSON.
+func setupLogger(format, level string) error {
+	zerolog.TimeFieldFormat = time.RFC3339
+	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	lvl := zerolog.InfoLevel
+	if level != "" {
+		parsed, err := zerolog.ParseLevel(strings.ToLower(level))
+		if err != nil {
+			return fmt.Errorf("log level %q: %w", level, err)
+		}
+		lvl = parsed
+	}
+	zerolog.SetGlobalLevel(lvl)
+	switch strings.ToLower(format) {
+	case "", "json":
+	case "console":
+		log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+	default:
+		return fmt.Errorf("log format %q: expected json or console", format)
+	}
+	return nil
 }
 
 func connectDB(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
@@ -13,6 +13,17 @@ type Config struct {
 	PGDSN       string `envconfig:"BILLING_PG_DSN"`
 	APIToken    string `envconfig:"BILLING_API_TOKEN"`
 
+	Log struct {
+		Format string `envconfig:"LOG_FORMAT" default:"json"`
+		Level  string `envconfig:"LOG_LEVEL" default:"info"`
+	} `envconfig:""`
+
+	Invoices struct {
+		TTL               time.Duration `envconfig:"INVOICE_TTL" default:"24h"`
+		ExpireInterval    time.Duration `envconfig:"INVOICE_EXPIRE_INTERVAL" default:"1m"`
+		AllowOrphanCredit bool          `envconfig:"INVOICE_ALLOW_ORPHAN_CREDIT" default:"false"`
+	} `envconfig:""`
+
 	Server struct {
 		ShutdownTimeout time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"500s"`
 		ReadTimeout     time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"150s"`
@@ -34,6 +45,17 @@ type Config struct {
 		NotificationURL string        `envconfig:"TOCHKA_NOTIFICATION_URL"`
 		WebhookSecret   string        `envconfig:"TOCHKA_WEBHOOK_SECRET"`
 		WebhookKey      string        `envconfig:"TOCHKA_WEBHOOK_PUBLIC_KEY"`
+		WebhookStrict   bool          `envconfig:"TOCHKA_WEBHOOK_STRICT" default:"false"`
+		ReconcileEvery  time.Duration `envconfig:"TOCHKA_RECONCILE_INTERVAL" default:"10m"`
+	} `envconfig:""`
+
+	Notifications struct {
+		BotToken     string        `envconfig:"TG_BOT_TOKEN"`
+		BotAPIURL    string        `envconfig:"TG_BOT_API_URL" default:"https://api.telegram.org"`
+		PollInterval time.Duration `envconfig:"NOTIFY_POLL_INTERVAL" default:"5s"`
+		MaxAttempts  int           `envconfig:"NOTIFY_MAX_ATTEMPTS" default:"8"`
+		BaseBackoff  time.Duration `envconfig:"NOTIFY_BASE_BACKOFF" default:"10s"`
+		MaxBackoff   time.Duration `envconfig:"NOTIFY_MAX_BACKOFF" default:"30m"`
 	} `envconfig:""`
 }
 
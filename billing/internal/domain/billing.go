@@ -4,13 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 )
 
+const (
+	InvoiceStatusPending       = "pending"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusCancelled     = "cancelled"
+)
+
 var (
 	ErrInvoiceNotFound   = errors.New("invoice not found")
 	ErrAccountNotFound   = errors.New("account not found")
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrInvoiceCancelled  = errors.New("invoice cancelled")
+	ErrPaymentNotFound   = errors.New("payment not found")
+	// ErrPaymentAmountMismatch — поступившая сумма больше, чем осталось оплатить по счёту.
+	ErrPaymentAmountMismatch = errors.New("payment amount does not match invoice")
+	// ErrPaymentIdempotencyConflict — ключ уже занят платежом с другой суммой, счётом или аккаунтом.
+	ErrPaymentIdempotencyConflict = errors.New("payment idempotency conflict")
 )
 
 type Money struct {
@@ -37,9 +51,19 @@ type Invoice struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	PaidAt         *time.Time     `json:"paid_at"`
+	PaidAmount     int64          `json:"paid_amount"`
+	ExpiresAt      *time.Time     `json:"expires_at"`
 	QrId           string         `json:"qr_id"`
 }
 
+// Expired сообщает, что неоплаченный счёт уже не должен принимать платежи.
+func (i Invoice) Expired(now time.Time) bool {
+	if i.Status == InvoiceStatusCancelled {
+		return true
+	}
+	return i.Status == InvoiceStatusPending && i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)
+}
+
 type InvoiceSBPMetadata struct {
 	Provider      string         `json:"provider"`
 	QRID          string         `json:"qr_id,omitempty"`
@@ -97,7 +121,82 @@ type Billing interface {
 	GetInvoiceByID(ctx context.Context, invoiceID int64) (Invoice, error)
 	GetInvoiceByIdempotencyKey(ctx context.Context, key string) (Invoice, error)
 	GetInvoiceByQrId(ctx context.Context, qrId string) (Invoice, error)
+	ListPendingQRInvoices(ctx context.Context, limit int) ([]Invoice, error)
 	ChargeAccount(ctx context.Context, params ChargeAccountParams) (Payment, error)
+	// GetPaymentByIdempotencyKey находит платёж или списание по ключу, с которым его провели.
+	GetPaymentByIdempotencyKey(ctx context.Context, key string) (Payment, error)
+}
+
+// InvoiceExpirer отменяет неоплаченные счета с истёкшим сроком.
+type InvoiceExpirer interface {
+	ExpireInvoices(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ApplyInvoicePayment считает новый статус счёта и накопленную сумму после поступления amount.
+// Счёт становится paid, только когда сумма всех платежей достигла суммы счёта; переплата
+// учитывается в paid_amount, но статус оплаченного или отменённого счёта не меняется.
+func ApplyInvoicePayment(invoice Invoice, amount int64) (string, int64) {
+	paid := invoice.PaidAmount + amount
+	switch invoice.Status {
+	case InvoiceStatusPending, InvoiceStatusPartiallyPaid:
+		if paid >= invoice.Amount.Amount {
+			return InvoiceStatusPaid, paid
+		}
+		return InvoiceStatusPartiallyPaid, paid
+	default:
+		return invoice.Status, paid
+	}
+}
+
+// IncomingPaymentAction — что сделать с входящим платежом.
+type IncomingPaymentAction int
+
+const (
+	// IncomingPaymentCredit — новый платёж: зачислить и учесть в счёте.
+	IncomingPaymentCredit IncomingPaymentAction = iota
+	// IncomingPaymentReplay — повтор уже проведённого платежа с тем же ключом.
+	IncomingPaymentReplay
+	// IncomingPaymentInvoicePaid — счёт уже оплачен, баланс больше не пополняется.
+	IncomingPaymentInvoicePaid
+)
+
+// CheckIncomingPayment проверяет входящий платёж params по счёту invoice (nil — без счёта).
+// existing — платёж, уже проведённый с тем же ключом идемпотентности, если он есть.
+// Просроченный счёт принимает платёж, только если allowOrphan, и тогда без проверки остатка.
+func CheckIncomingPayment(invoice *Invoice, existing *Payment, params RegisterIncomingPaymentParams, now time.Time, allowOrphan bool) (IncomingPaymentAction, error) {
+	if invoice != nil {
+		if invoice.AccountID != params.AccountID {
+			return 0, fmt.Errorf("invoice belongs to another account")
+		}
+		if invoice.Amount.Currency != params.Amount.Currency {
+			return 0, fmt.Errorf("invoice currency mismatch")
+		}
+	}
+	if existing != nil {
+		if existing.AccountID != params.AccountID || existing.Amount != params.Amount {
+			return 0, ErrPaymentIdempotencyConflict
+		}
+		if params.InvoiceID != nil && (existing.InvoiceID == nil || *existing.InvoiceID != *params.InvoiceID) {
+			return 0, fmt.Errorf("payment invoice mismatch")
+		}
+		return IncomingPaymentReplay, nil
+	}
+	if invoice == nil {
+		return IncomingPaymentCredit, nil
+	}
+	if invoice.Status == InvoiceStatusPaid {
+		return IncomingPaymentInvoicePaid, nil
+	}
+	if invoice.Expired(now) {
+		if !allowOrphan {
+			return 0, ErrInvoiceCancelled
+		}
+		return IncomingPaymentCredit, nil
+	}
+	if outstanding := invoice.Amount.Amount - invoice.PaidAmount; params.Amount.Amount > outstanding {
+		return 0, fmt.Errorf("%w: got %d, outstanding %d", ErrPaymentAmountMismatch, params.Amount.Amount, outstanding)
+	}
+	return IncomingPaymentCredit, nil
 }
 
 func ExtractInvoiceSBPMetadata(meta map[string]any) (InvoiceSBPMetadata, bool) {
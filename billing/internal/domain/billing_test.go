@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplyInvoicePaymentAccumulatesPartialPayments(t *testing.T) {
+	invoice := Invoice{Amount: Money{Amount: 50000, Currency: "RUB"}, Status: InvoiceStatusPending}
+
+	steps := []struct {
+		amount     int64
+		wantStatus string
+		wantPaid   int64
+	}{
+		{amount: 20000, wantStatus: InvoiceStatusPartiallyPaid, wantPaid: 20000},
+		{amount: 10000, wantStatus: InvoiceStatusPartiallyPaid, wantPaid: 30000},
+		{amount: 20000, wantStatus: InvoiceStatusPaid, wantPaid: 50000},
+	}
+	for i, step := range steps {
+		status, paid := ApplyInvoicePayment(invoice, step.amount)
+		if status != step.wantStatus || paid != step.wantPaid {
+			t.Fatalf("step %d: got (%s, %d), want (%s, %d)", i, status, paid, step.wantStatus, step.wantPaid)
+		}
+		invoice.Status = status
+		invoice.PaidAmount = paid
+	}
+}
+
+func TestApplyInvoicePayment(t *testing.T) {
+	cases := []struct {
+		name       string
+		invoice    Invoice
+		amount     int64
+		wantStatus string
+		wantPaid   int64
+	}{
+		{
+			name:       "full payment",
+			invoice:    Invoice{Amount: Money{Amount: 10000}, Status: InvoiceStatusPending},
+			amount:     10000,
+			wantStatus: InvoiceStatusPaid,
+			wantPaid:   10000,
+		},
+		{
+			name:       "overpayment completes invoice",
+			invoice:    Invoice{Amount: Money{Amount: 10000}, Status: InvoiceStatusPartiallyPaid, PaidAmount: 6000},
+			amount:     5000,
+			wantStatus: InvoiceStatusPaid,
+			wantPaid:   11000,
+		},
+		{
+			name:       "paid invoice keeps status",
+			invoice:    Invoice{Amount: Money{Amount: 10000}, Status: InvoiceStatusPaid, PaidAmount: 10000},
+			amount:     1000,
+			wantStatus: InvoiceStatusPaid,
+			wantPaid:   11000,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, paid := ApplyInvoicePayment(tc.invoice, tc.amount)
+			if status != tc.wantStatus || paid != tc.wantPaid {
+				t.Fatalf("got (%s, %d), want (%s, %d)", status, paid, tc.wantStatus, tc.wantPaid)
+			}
+		})
+	}
+}
+
+func TestInvoiceExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	cases := []struct {
+		name    string
+		invoice Invoice
+		want    bool
+	}{
+		{name: "pending without expiry", invoice: Invoice{Status: InvoiceStatusPending}, want: false},
+		{name: "pending not yet expired", invoice: Invoice{Status: InvoiceStatusPending, ExpiresAt: &future}, want: false},
+		{name: "pending expired", invoice: Invoice{Status: InvoiceStatusPending, ExpiresAt: &past}, want: true},
+		{name: "partially paid never expires", invoice: Invoice{Status: InvoiceStatusPartiallyPaid, ExpiresAt: &past}, want: false},
+		{name: "cancelled", invoice: Invoice{Status: InvoiceStatusCancelled}, want: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.invoice.Expired(now); got != tc.want {
+				t.Fatalf("Expired() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckIncomingPayment(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	invoiceID := int64(3)
+	params := RegisterIncomingPaymentParams{AccountID: 1, InvoiceID: &invoiceID, Amount: Money{Amount: 20000, Currency: "RUB"}, IdempotencyKey: "trx-1"}
+	pending := Invoice{ID: invoiceID, AccountID: 1, Amount: Money{Amount: 50000, Currency: "RUB"}, Status: InvoiceStatusPending}
+	same := Payment{AccountID: 1, InvoiceID: &invoiceID, Amount: params.Amount}
+	other := same
+	other.Amount.Amount = 30000
+
+	cases := []struct {
+		name     string
+		invoice  Invoice
+		existing *Payment
+		want     IncomingPaymentAction
+		wantErr  error
+	}{
+		{name: "new payment", invoice: pending, want: IncomingPaymentCredit},
+		{name: "replayed key", invoice: pending, existing: &same, want: IncomingPaymentReplay},
+		{name: "key reused with another amount", invoice: pending, existing: &other, wantErr: ErrPaymentIdempotencyConflict},
+		{name: "paid invoice", invoice: Invoice{ID: invoiceID, AccountID: 1, Amount: pending.Amount, Status: InvoiceStatusPaid, PaidAmount: 50000}, want: IncomingPaymentInvoicePaid},
+		{name: "more than outstanding", invoice: Invoice{ID: invoiceID, AccountID: 1, Amount: pending.Amount, Status: InvoiceStatusPartiallyPaid, PaidAmount: 40000}, wantErr: ErrPaymentAmountMismatch},
+		{name: "cancelled invoice", invoice: Invoice{ID: invoiceID, AccountID: 1, Amount: pending.Amount, Status: InvoiceStatusCancelled}, wantErr: ErrInvoiceCancelled},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			invoice := tc.invoice
+			got, err := CheckIncomingPayment(&invoice, tc.existing, params, now, false)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("got (%d, %v), want %d", got, err, tc.want)
+			}
+		})
+	}
+}
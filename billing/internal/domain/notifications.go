@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Notification — сообщение пользователю в Telegram, ожидающее доставки.
+type Notification struct {
+	ID            int64      `json:"id"`
+	PaymentID     *int64     `json:"payment_id"`
+	ChatID        int64      `json:"chat_id"`
+	Text          string     `json:"text"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+type EnqueueNotificationParams struct {
+	PaymentID *int64
+	ChatID    int64
+	Text      string
+}
+
+// NotificationQueue — очередь уведомлений с повторными попытками доставки.
+type NotificationQueue interface {
+	EnqueueNotification(ctx context.Context, params EnqueueNotificationParams) error
+	ClaimDueNotifications(ctx context.Context, limit int) ([]Notification, error)
+	MarkNotificationSent(ctx context.Context, id int64) error
+	MarkNotificationRetry(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error
+	MarkNotificationFailed(ctx context.Context, id int64, lastErr string) error
+}
+
+// ExtractTelegramChatID достаёт tg_user_id из метаданных счёта: сначала с верхнего уровня,
+// затем из extra SBP-метаданных.
+func ExtractTelegramChatID(meta map[string]any) (int64, bool) {
+	if id, ok := int64FromAny(meta["tg_user_id"]); ok {
+		return id, true
+	}
+	if sbp, ok := ExtractInvoiceSBPMetadata(meta); ok {
+		if id, ok := int64FromAny(sbp.Extra["tg_user_id"]); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func int64FromAny(value any) (int64, bool) {
+	var id int64
+	switch v := value.(type) {
+	case float64:
+		id = int64(v)
+	case int64:
+		id = v
+	case int:
+		id = int64(v)
+	case json.Number:
+		parsed, err := v.Int64()
+		if err != nil {
+			return 0, false
+		}
+		id = parsed
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		id = parsed
+	default:
+		return 0, false
+	}
+	return id, id != 0
+}
@@ -26,6 +26,7 @@ type Server struct {
 	sbpService       *sbpusecase.Service
 	sbpWebhookSecret string
 	sbpWebhookKey    *rsa.PublicKey
+	sbpWebhookStrict bool
 	authToken        string
 }
 
@@ -45,6 +46,13 @@ func WithSBPService(service *sbpusecase.Service, webhookSecret string, webhookKe
 	}
 }
 
+// WithStrictWebhook включает приём только подписанных вебхуков Точки.
+func WithStrictWebhook(strict bool) Option {
+	return func(s *Server) {
+		s.sbpWebhookStrict = strict
+	}
+}
+
 func WithAuthToken(token string) Option {
 	return func(s *Server) {
 		s.authToken = token
@@ -143,10 +151,12 @@ func (s *Server) Router() http.Handler {
 	e.GET("/api/v1/invoices/idempotency/:key", s.handleGetInvoiceByIdempotencyKey)
 
 	e.POST("/api/v1/payments/incoming", s.handleRegisterIncomingPayment)
+	e.GET("/api/v1/payments/idempotency/:key", s.handleGetPaymentByIdempotencyKey)
 
 	// SBP
 	if s.sbpService != nil {
 		e.POST("/api/v1/sbp/invoices", s.handleCreateSBPInvoice)
+		e.POST("/api/v1/sbp/reconcile", s.handleSBPReconcile)
 	}
 
 	return e
@@ -334,6 +344,21 @@ func (s *Server) handleGetInvoiceByIdempotencyKey(c echo.Context) error {
 	return writeJSON(c, http.StatusOK, invoice)
 }
 
+func (s *Server) handleGetPaymentByIdempotencyKey(c echo.Context) error {
+	key := c.Param("key")
+	if key == "" {
+		return writeError(c, http.StatusBadRequest, "invalid_request", "idempotency key is required")
+	}
+	payment, err := s.billing.GetPaymentByIdempotencyKey(c.Request().Context(), key)
+	if err != nil {
+		if errors.Is(err, domain.ErrPaymentNotFound) {
+			return writeError(c, http.StatusNotFound, "payment_not_found", "payment not found")
+		}
+		return writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+	return writeJSON(c, http.StatusOK, payment)
+}
+
 func (s *Server) handleRegisterIncomingPayment(c echo.Context) error {
 	var req registerPaymentRequest
 	if err := c.Bind(&req); err != nil {
@@ -347,6 +372,10 @@ func (s *Server) handleRegisterIncomingPayment(c echo.Context) error {
 		switch {
 		case errors.Is(err, domain.ErrInvoiceNotFound):
 			return writeError(c, http.StatusNotFound, "invoice_not_found", "invoice not found")
+		case errors.Is(err, domain.ErrInvoiceCancelled):
+			return writeError(c, http.StatusConflict, "invoice_cancelled", "invoice is cancelled or expired")
+		case errors.Is(err, domain.ErrPaymentAmountMismatch):
+			return writeError(c, http.StatusConflict, "amount_mismatch", err.Error())
 		case errors.Is(err, domain.ErrAccountNotFound):
 			return writeError(c, http.StatusNotFound, "account_not_found", "account not found")
 		default:
@@ -404,6 +433,18 @@ func (s *Server) handleCreateSBPInvoice(c echo.Context) error {
 	return writeJSON(c, http.StatusOK, resp)
 }
 
+func (s *Server) handleSBPReconcile(c echo.Context) error {
+	if s.sbpService == nil {
+		return writeError(c, http.StatusServiceUnavailable, "sbp_not_configured", "sbp service is not available")
+	}
+	res, err := s.sbpService.Reconcile(c.Request().Context())
+	if err != nil {
+		s.log.Error().Err(err).Msg("sbp: manual reconcile")
+		return writeError(c, http.StatusInternalServerError, "internal_error", "reconcile failed")
+	}
+	return writeJSON(c, http.StatusOK, res)
+}
+
 func (s *Server) handleSBPWebhook(c echo.Context) error {
 	if s.sbpService == nil {
 		return writeError(c, http.StatusServiceUnavailable, "sbp_not_configured", "sbp service is not available")
@@ -423,9 +464,10 @@ func (s *Server) handleSBPWebhook(c echo.Context) error {
 	if err != nil {
 		return writeError(c, http.StatusBadRequest, "invalid_request", "failed to read body")
 	}
-	notification, err := tochka.ParseSbpWebhook(body, s.sbpWebhookKey)
+	notification, err := tochka.ParseSbpWebhook(body, s.sbpWebhookKey, s.sbpWebhookStrict)
 	if err != nil {
 		if errors.Is(err, tochka.ErrInvalidWebhookSignature) {
+			s.log.Warn().Err(err).Msg("sbp: webhook rejected")
 			return writeError(c, http.StatusUnauthorized, "unauthorized", "invalid webhook signature")
 		}
 		return writeError(c, http.StatusBadRequest, "invalid_request", "invalid webhook payload")
@@ -436,6 +478,14 @@ func (s *Server) handleSBPWebhook(c echo.Context) error {
 		if errors.Is(err, domain.ErrInvoiceNotFound) {
 			return writeError(c, http.StatusNotFound, "invoice_not_found", "invoice not found")
 		}
+		if errors.Is(err, domain.ErrInvoiceCancelled) {
+			s.log.Warn().Err(err).Str("qr_id", notification.QRID).Msg("sbp: payment for cancelled invoice rejected")
+			return writeError(c, http.StatusConflict, "invoice_cancelled", "invoice is cancelled or expired")
+		}
+		if errors.Is(err, domain.ErrPaymentAmountMismatch) {
+			s.log.Error().Err(err).Str("qr_id", notification.QRID).Msg("sbp: webhook amount does not match invoice")
+			return writeError(c, http.StatusConflict, "amount_mismatch", "payment amount does not match invoice")
+		}
 		s.log.Error().Err(err).Msg("sbp: handle webhook")
 		return writeError(c, http.StatusInternalServerError, "internal_error", "failed to register payment")
 	}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TelegramSender отправляет сообщения напрямую через Bot API, без зависимости от bot-gateway.
+type TelegramSender struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewTelegramSender(baseURL, token string, timeout time.Duration) *TelegramSender {
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &TelegramSender{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type sendMessageRequest struct {
+	ChatID                int64  `json:"chat_id"`
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+type sendMessageResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (s *TelegramSender) SendMessage(ctx context.Context, chatID int64, text string) error {
+	body, err := json.Marshal(sendMessageRequest{ChatID: chatID, Text: text, DisableWebPagePreview: true})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/bot%s/sendMessage", s.baseURL, s.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("telegram response: %w", err)
+	}
+	var parsed sendMessageResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return fmt.Errorf("telegram response status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	if !parsed.OK {
+		return fmt.Errorf("telegram error %d: %s", parsed.ErrorCode, parsed.Description)
+	}
+	return nil
+}
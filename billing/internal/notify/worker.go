@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"billing/internal/domain"
+)
+
+// Sender — транспорт доставки уведомления пользователю.
+type Sender interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+type WorkerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Worker разбирает очередь уведомлений и повторяет отправку с экспоненциальной задержкой.
+type Worker struct {
+	queue  domain.NotificationQueue
+	sender Sender
+	cfg    WorkerConfig
+	log    zerolog.Logger
+}
+
+func NewWorker(queue domain.NotificationQueue, sender Sender, cfg WorkerConfig, log zerolog.Logger) *Worker {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 10 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Minute
+	}
+	return &Worker{queue: queue, sender: sender, cfg: cfg, log: log}
+}
+
+// Run блокируется до отмены контекста.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		batch, err := w.queue.ClaimDueNotifications(ctx, w.cfg.BatchSize)
+		if err != nil {
+			w.log.Error().Err(err).Msg("notify: claim notifications failed")
+			return
+		}
+		for _, n := range batch {
+			w.deliver(ctx, n)
+		}
+		if len(batch) < w.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, n domain.Notification) {
+	err := w.sender.SendMessage(ctx, n.ChatID, n.Text)
+	if err == nil {
+		if err := w.queue.MarkNotificationSent(ctx, n.ID); err != nil {
+			w.log.Error().Err(err).Int64("notification", n.ID).Msg("notify: mark sent failed")
+		}
+		return
+	}
+
+	logger := w.log.With().Int64("notification", n.ID).Int64("chat", n.ChatID).Int("attempt", n.Attempts).Logger()
+	if n.Attempts >= w.cfg.MaxAttempts {
+		logger.Error().Err(err).Msg("notify: giving up on notification")
+		if markErr := w.queue.MarkNotificationFailed(ctx, n.ID, err.Error()); markErr != nil {
+			logger.Error().Err(markErr).Msg("notify: mark failed failed")
+		}
+		return
+	}
+	next := time.Now().Add(w.backoff(n.Attempts))
+	logger.Warn().Err(err).Time("next_attempt", next).Msg("notify: send failed, will retry")
+	if markErr := w.queue.MarkNotificationRetry(ctx, n.ID, err.Error(), next); markErr != nil {
+		logger.Error().Err(markErr).Msg("notify: mark retry failed")
+	}
+}
+
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.cfg.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= w.cfg.MaxBackoff {
+			return w.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
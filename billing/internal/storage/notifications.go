@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"billing/internal/domain"
+)
+
+// notificationClaimLease — на сколько откладываем захваченное уведомление, чтобы
+// при падении воркера оно вернулось в очередь.
+const notificationClaimLease = time.Minute
+
+func (p *Postgres) EnqueueNotification(ctx context.Context, params domain.EnqueueNotificationParams) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO billing_notifications (payment_id, chat_id, text)
+VALUES ($1, $2, $3)
+ON CONFLICT (payment_id) WHERE payment_id IS NOT NULL DO NOTHING
+`, params.PaymentID, params.ChatID, params.Text)
+	return err
+}
+
+func (p *Postgres) ClaimDueNotifications(ctx context.Context, limit int) ([]domain.Notification, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, `
+UPDATE billing_notifications
+SET attempts = attempts + 1, next_attempt_at = now() + $2::interval, updated_at = now()
+WHERE id IN (
+    SELECT id FROM billing_notifications
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, payment_id, chat_id, text, status, attempts, last_error, next_attempt_at, created_at, sent_at
+`, limit, notificationClaimLease.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.Notification
+	for rows.Next() {
+		var (
+			n         domain.Notification
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&n.ID, &n.PaymentID, &n.ChatID, &n.Text, &n.Status, &n.Attempts, &lastError, &n.NextAttemptAt, &n.CreatedAt, &n.SentAt); err != nil {
+			return nil, err
+		}
+		n.LastError = lastError.String
+		res = append(res, n)
+	}
+	return res, rows.Err()
+}
+
+func (p *Postgres) MarkNotificationSent(ctx context.Context, id int64) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+UPDATE billing_notifications
+SET status = 'sent', sent_at = now(), last_error = NULL, updated_at = now()
+WHERE id = $1
+`, id)
+	return err
+}
+
+func (p *Postgres) MarkNotificationRetry(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+UPDATE billing_notifications
+SET last_error = $2, next_attempt_at = $3, updated_at = now()
+WHERE id = $1
+`, id, lastErr, nextAttemptAt)
+	return err
+}
+
+func (p *Postgres) MarkNotificationFailed(ctx context.Context, id int64, lastErr string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+UPDATE billing_notifications
+SET status = 'failed', last_error = $2, updated_at = now()
+WHERE id = $1
+`, id, lastErr)
+	return err
+}
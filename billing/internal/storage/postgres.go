@@ -21,11 +21,33 @@ const (
 )
 
 type Postgres struct {
-	pool *pgxpool.Pool
+	pool              *pgxpool.Pool
+	invoiceTTL        time.Duration
+	allowOrphanCredit bool
 }
 
-func NewPostgres(pool *pgxpool.Pool) *Postgres {
-	return &Postgres{pool: pool}
+type Option func(*Postgres)
+
+// WithInvoiceTTL задаёт срок жизни новых счетов; 0 — счета не истекают.
+func WithInvoiceTTL(ttl time.Duration) Option {
+	return func(p *Postgres) {
+		p.invoiceTTL = ttl
+	}
+}
+
+// WithOrphanCredit разрешает зачислять платежи по отменённым счетам на баланс аккаунта.
+func WithOrphanCredit(allow bool) Option {
+	return func(p *Postgres) {
+		p.allowOrphanCredit = allow
+	}
+}
+
+func NewPostgres(pool *pgxpool.Pool, opts ...Option) *Postgres {
+	p := &Postgres{pool: pool}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *Postgres) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -111,12 +133,18 @@ func (p *Postgres) CreateInvoice(ctx context.Context, params domain.CreateInvoic
 		}
 	}
 
+	var expiresAt *time.Time
+	if p.invoiceTTL > 0 {
+		ts := time.Now().Add(p.invoiceTTL)
+		expiresAt = &ts
+	}
+
 	row := p.pool.QueryRow(ctx, `
-INSERT INTO billing_invoices (account_id, amount, currency, description, metadata, idempotency_key,qr_id)
-VALUES ($1, $2, $3, NULLIF($4,''), $5, $6,$7)
+INSERT INTO billing_invoices (account_id, amount, currency, description, metadata, idempotency_key, qr_id, expires_at)
+VALUES ($1, $2, $3, NULLIF($4,''), $5, $6, $7, $8)
 ON CONFLICT (idempotency_key) DO NOTHING
-RETURNING id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at,qr_id
-`, params.AccountID, params.Amount.Amount, params.Amount.Currency, params.Description, meta, params.IdempotencyKey, params.QrId)
+RETURNING id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
+`, params.AccountID, params.Amount.Amount, params.Amount.Currency, params.Description, meta, params.IdempotencyKey, params.QrId, expiresAt)
 	invoice, err := scanInvoice(row)
 	if err == nil {
 		return invoice, nil
@@ -140,7 +168,7 @@ func (p *Postgres) GetInvoiceByID(ctx context.Context, invoiceID int64) (domain.
 	defer cancel()
 
 	row := p.pool.QueryRow(ctx, `
-SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at
+SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
 FROM billing_invoices
 WHERE id = $1
 `, invoiceID)
@@ -162,7 +190,7 @@ func (p *Postgres) GetInvoiceByIdempotencyKey(ctx context.Context, key string) (
 	defer cancel()
 
 	row := p.pool.QueryRow(ctx, `
-SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, qr_id
+SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
 FROM billing_invoices
 WHERE idempotency_key = $1
 `, key)
@@ -184,7 +212,7 @@ func (p *Postgres) GetInvoiceByQrId(ctx context.Context, qrId string) (domain.In
 	defer cancel()
 
 	row := p.pool.QueryRow(ctx, `
-SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at,qr_id
+SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
 FROM billing_invoices
 WHERE qr_id = $1
 `, qrId)
@@ -239,17 +267,44 @@ func (p *Postgres) RegisterIncomingPayment(ctx context.Context, params domain.Re
 
 	var invoice *domain.Invoice
 	if params.InvoiceID != nil {
-		inv, err := p.lockInvoiceForUpdate(ctx, tx, *params.InvoiceID)
+		var inv domain.Invoice
+		inv, err = p.lockInvoiceForUpdate(ctx, tx, *params.InvoiceID)
 		if err != nil {
 			return domain.Payment{}, err
 		}
 		invoice = &inv
-		if invoice.AccountID != params.AccountID {
-			return domain.Payment{}, fmt.Errorf("invoice belongs to another account")
+	}
+
+	// Платежи аккаунта сериализованы блокировкой выше, поэтому найденный здесь платёж
+	// с тем же ключом не появится и не исчезнет до конца транзакции.
+	var existing *domain.Payment
+	found, err := p.getPaymentByIdempotencyKey(ctx, tx, params.IdempotencyKey)
+	switch {
+	case err == nil:
+		existing = &found
+	case !errors.Is(err, domain.ErrPaymentNotFound):
+		return domain.Payment{}, err
+	}
+	now := time.Now()
+	action, err := domain.CheckIncomingPayment(invoice, existing, params, now, p.allowOrphanCredit)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	switch action {
+	case domain.IncomingPaymentReplay, domain.IncomingPaymentInvoicePaid:
+		// Тот же платёж, увиденный вторым путём (вебхук и сверка), возвращает уже
+		// проведённую оплату; оплаченный счёт баланс больше не пополняет.
+		payment := found
+		if existing == nil {
+			payment, err = p.getLatestInvoicePayment(ctx, tx, invoice.ID)
+			if err != nil {
+				return domain.Payment{}, err
+			}
 		}
-		if invoice.Amount.Currency != params.Amount.Currency {
-			return domain.Payment{}, fmt.Errorf("invoice currency mismatch")
+		if err = tx.Commit(ctx); err != nil {
+			return domain.Payment{}, err
 		}
+		return payment, nil
 	}
 
 	var meta []byte
@@ -266,27 +321,13 @@ VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (idempotency_key) DO NOTHING
 RETURNING id, account_id, invoice_id, amount, currency, metadata, status, idempotency_key, created_at, updated_at, completed_at
 `, params.AccountID, params.InvoiceID, params.Amount.Amount, params.Amount.Currency, meta, params.IdempotencyKey)
-	payment, scanErr := scanPayment(row)
-	if scanErr != nil {
-		if !errors.Is(scanErr, pgx.ErrNoRows) {
-			return domain.Payment{}, scanErr
-		}
-		payment, err = p.getPaymentByIdempotencyKey(ctx, tx, params.IdempotencyKey)
-		if err != nil {
-			return domain.Payment{}, err
-		}
-		if payment.AccountID != params.AccountID || payment.Amount.Amount != params.Amount.Amount || payment.Amount.Currency != params.Amount.Currency {
-			return domain.Payment{}, fmt.Errorf("payment idempotency conflict")
-		}
-		if params.InvoiceID != nil {
-			if payment.InvoiceID == nil || *payment.InvoiceID != *params.InvoiceID {
-				return domain.Payment{}, fmt.Errorf("payment invoice mismatch")
-			}
-		}
-		if err := tx.Commit(ctx); err != nil {
-			return domain.Payment{}, err
+	payment, err := scanPayment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Ключ успел занять платёж другого аккаунта.
+			err = domain.ErrPaymentIdempotencyConflict
 		}
-		return payment, nil
+		return domain.Payment{}, err
 	}
 
 	_, err = tx.Exec(ctx, `
@@ -298,7 +339,6 @@ WHERE id = $2
 		return domain.Payment{}, err
 	}
 
-	now := time.Now()
 	_, err = tx.Exec(ctx, `
 UPDATE billing_payments
 SET status = 'completed', completed_at = $2, updated_at = now()
@@ -314,15 +354,21 @@ WHERE id = $1
 	payment.CompletedAt = &now
 	payment.UpdatedAt = now
 
-	if invoice != nil && invoice.Status == "pending" {
-		if invoice.Amount.Amount > params.Amount.Amount {
-			return domain.Payment{}, fmt.Errorf("partial payments are not supported")
+	if invoice != nil {
+		if invoice.Expired(now) {
+			// Поздний платёж по отменённому счёту зачислен на баланс, сам счёт остаётся cancelled.
+			invoice.Status = domain.InvoiceStatusCancelled
+		}
+		status, paidAmount := domain.ApplyInvoicePayment(*invoice, params.Amount.Amount)
+		paidAt := invoice.PaidAt
+		if status == domain.InvoiceStatusPaid && paidAt == nil {
+			paidAt = &now
 		}
 		_, err = tx.Exec(ctx, `
 UPDATE billing_invoices
-SET status = 'paid', paid_at = $2, updated_at = now()
+SET status = $2, paid_amount = $3, paid_at = $4, updated_at = now()
 WHERE id = $1
-`, *params.InvoiceID, now)
+`, *params.InvoiceID, status, paidAmount, paidAt)
 		if err != nil {
 			return domain.Payment{}, err
 		}
@@ -335,6 +381,78 @@ WHERE id = $1
 	return payment, nil
 }
 
+// GetPaymentByIdempotencyKey возвращает платёж по ключу идемпотентности.
+func (p *Postgres) GetPaymentByIdempotencyKey(ctx context.Context, key string) (domain.Payment, error) {
+	if key == "" {
+		return domain.Payment{}, fmt.Errorf("idempotency key is required")
+	}
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	row := p.pool.QueryRow(ctx, `
+SELECT id, account_id, invoice_id, amount, currency, metadata, status, idempotency_key, created_at, updated_at, completed_at
+FROM billing_payments
+WHERE idempotency_key = $1
+`, key)
+	payment, err := scanPayment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Payment{}, domain.ErrPaymentNotFound
+		}
+		return domain.Payment{}, err
+	}
+	return payment, nil
+}
+
+// ListPendingQRInvoices возвращает неоплаченные счета с привязанным QR-кодом, по которым
+// не проведено ни одного платежа, старые — первыми.
+func (p *Postgres) ListPendingQRInvoices(ctx context.Context, limit int) ([]domain.Invoice, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, `
+SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
+FROM billing_invoices
+WHERE status = 'pending' AND qr_id IS NOT NULL AND qr_id <> ''
+  AND NOT EXISTS (SELECT 1 FROM billing_payments p WHERE p.invoice_id = billing_invoices.id)
+ORDER BY created_at
+LIMIT $1
+`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []domain.Invoice
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// ExpireInvoices переводит просроченные pending-счета в cancelled и возвращает их количество.
+func (p *Postgres) ExpireInvoices(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := p.pool.Exec(ctx, `
+UPDATE billing_invoices
+SET status = 'cancelled', updated_at = now()
+WHERE status = 'pending' AND expires_at IS NOT NULL AND expires_at <= $1
+`, now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
 func (p *Postgres) ChargeAccount(ctx context.Context, params domain.ChargeAccountParams) (domain.Payment, error) {
 	if params.IdempotencyKey == "" {
 		return domain.Payment{}, fmt.Errorf("idempotency key is required")
@@ -403,7 +521,7 @@ RETURNING id, account_id, invoice_id, amount, currency, metadata, status, idempo
 			return domain.Payment{}, err
 		}
 		if payment.AccountID != params.AccountID || payment.Amount.Amount != debitAmount || payment.Amount.Currency != params.Amount.Currency {
-			return domain.Payment{}, fmt.Errorf("payment idempotency conflict")
+			return domain.Payment{}, domain.ErrPaymentIdempotencyConflict
 		}
 		if err := tx.Commit(ctx); err != nil {
 			return domain.Payment{}, err
@@ -481,7 +599,7 @@ FOR UPDATE
 
 func (p *Postgres) lockInvoiceForUpdate(ctx context.Context, tx pgx.Tx, invoiceID int64) (domain.Invoice, error) {
 	row := tx.QueryRow(ctx, `
-SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, qr_id
+SELECT id, account_id, amount, currency, description, metadata, status, idempotency_key, created_at, updated_at, paid_at, paid_amount, expires_at, qr_id
 FROM billing_invoices
 WHERE id = $1
 FOR UPDATE
@@ -502,6 +620,25 @@ SELECT id, account_id, invoice_id, amount, currency, metadata, status, idempoten
 FROM billing_payments
 WHERE idempotency_key = $1
 `, key)
+	payment, err := scanPayment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Payment{}, domain.ErrPaymentNotFound
+		}
+		return domain.Payment{}, err
+	}
+	return payment, nil
+}
+
+// getLatestInvoicePayment возвращает последний проведённый платёж по счёту.
+func (p *Postgres) getLatestInvoicePayment(ctx context.Context, tx pgx.Tx, invoiceID int64) (domain.Payment, error) {
+	row := tx.QueryRow(ctx, `
+SELECT id, account_id, invoice_id, amount, currency, metadata, status, idempotency_key, created_at, updated_at, completed_at
+FROM billing_payments
+WHERE invoice_id = $1 AND status = 'completed'
+ORDER BY completed_at DESC
+LIMIT 1
+`, invoiceID)
 	payment, err := scanPayment(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -529,7 +666,7 @@ func scanInvoice(row pgx.Row) (domain.Invoice, error) {
 		invoice  domain.Invoice
 		metadata sql.NullString
 	)
-	err := row.Scan(&invoice.ID, &invoice.AccountID, &invoice.Amount.Amount, &invoice.Amount.Currency, &invoice.Description, &metadata, &invoice.Status, &invoice.IdempotencyKey, &invoice.CreatedAt, &invoice.UpdatedAt, &invoice.PaidAt, &invoice.QrId)
+	err := row.Scan(&invoice.ID, &invoice.AccountID, &invoice.Amount.Amount, &invoice.Amount.Currency, &invoice.Description, &metadata, &invoice.Status, &invoice.IdempotencyKey, &invoice.CreatedAt, &invoice.UpdatedAt, &invoice.PaidAt, &invoice.PaidAmount, &invoice.ExpiresAt, &invoice.QrId)
 	if err != nil {
 		return domain.Invoice{}, err
 	}
@@ -206,6 +206,78 @@ func (c *Client) RegisterQRCode(ctx context.Context, req RegisterQRCodeRequest)
 	return respData, nil
 }
 
+// QRCodePaymentStatus — статус оплаты по QR-коду из Точки.
+type QRCodePaymentStatus struct {
+	QRID    string `json:"qrcId"`
+	Code    string `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	TrxID   string `json:"trxId"`
+}
+
+// Paid — Точка подтвердила оплату по QR-коду.
+func (s QRCodePaymentStatus) Paid() bool {
+	return strings.EqualFold(s.Status, "Accepted")
+}
+
+// GetQRCodeStatus запрашивает статусы оплаты для одного или нескольких QR-кодов.
+func (c *Client) GetQRCodeStatus(ctx context.Context, qrIDs ...string) ([]QRCodePaymentStatus, error) {
+	if len(qrIDs) == 0 {
+		return nil, nil
+	}
+	if c.httpClient == nil {
+		return nil, fmt.Errorf("http client is not configured")
+	}
+
+	escaped := make([]string, 0, len(qrIDs))
+	for _, id := range qrIDs {
+		escaped = append(escaped, url.PathEscape(id))
+	}
+	base := strings.TrimRight(c.cfg.BaseURL, "/")
+	endpoint := fmt.Sprintf("%s/uapi/sbp/%s/qr-codes/%s/payment-status",
+		base,
+		url.PathEscape(c.cfg.APIVersion),
+		strings.Join(escaped, ","),
+	)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.cfg.AccessToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.AccessToken)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("tochka qr status failed: %s", strings.TrimSpace(string(data)))
+	}
+
+	var parsed struct {
+		Data struct {
+			PaymentList []QRCodePaymentStatus `json:"paymentList"`
+		} `json:"Data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return parsed.Data.PaymentList, nil
+}
+
+// FormatMinorAmount переводит сумму в минорных единицах в строку вида "123.45", как в вебхуках Точки.
+func FormatMinorAmount(amount int64) string {
+	return formatMinorAmount(amount)
+}
+
 func formatMinorAmount(amount int64) string {
 	negative := amount < 0
 	if negative {
@@ -80,18 +80,59 @@ type jwtEnvelope struct {
 	Signature string          `json:"signature"`
 }
 
-// ===== Хук-конвертер суммы (адаптируйте под свой Amount).
-// По умолчанию — no-op (не трогаем Amount).
-//
-// Если у вас есть конструктор вроде NewAmountFromString("0.33"),
-// замените реализацию ниже.
+// ===== Конвертер суммы
+
+// AmountFromString разбирает сумму в рублях ("249.99", "250", "249,9") в минорные единицы.
+// Дробная часть длиннее двух знаков округляется до копейки (half-up). Отрицательные и
+// нечисловые значения считаются ошибкой.
 var AmountFromString = func(s string) (Amount, error) {
-	var a Amount
-	a.Value = s
-	// no-op: оставьте нулевым, если не готовы мапить здесь.
-	// Пример (раскомментируйте и адаптируйте):
-	// return NewAmountFromString(s) // ваш конструктор
-	return a, nil
+	value := strings.TrimSpace(s)
+	minor, err := parseRublesMinor(value)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{Value: value, Currency: "RUB", Minor: minor}, nil
+}
+
+func parseRublesMinor(value string) (int64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+	value = strings.Replace(value, ",", ".", 1)
+	whole, frac, hasFrac := strings.Cut(value, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if hasFrac && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", value)
+		}
+	}
+	if len(whole) > 15 {
+		return 0, fmt.Errorf("amount %q is too large", value)
+	}
+
+	var rubles int64
+	for _, r := range whole {
+		rubles = rubles*10 + int64(r-'0')
+	}
+	var kopecks int64
+	for i := 0; i < 2; i++ {
+		kopecks *= 10
+		if i < len(frac) {
+			kopecks += int64(frac[i] - '0')
+		}
+	}
+	if len(frac) > 2 && frac[2] >= '5' {
+		kopecks++
+	}
+	return rubles*100 + kopecks, nil
 }
 
 // ===== Публичная точка входа
@@ -101,11 +142,16 @@ var AmountFromString = func(s string) (Amount, error) {
 // 2) JSON-envelope {"header":{...}, "payload":{...}, "signature":"..."}
 // 3) "голый" JSON payload без подписи
 // Если key != nil, проверяется RS256-подпись (для 1 и 2 форматов).
-func ParseSbpWebhook(body []byte, key *rsa.PublicKey) (IncomingPaymentNotification, error) {
+// В строгом режиме (strict) принимаются только подписанные уведомления: отсутствие ключа,
+// envelope без подписи и "голый" payload отклоняются с ErrInvalidWebhookSignature.
+func ParseSbpWebhook(body []byte, key *rsa.PublicKey, strict bool) (IncomingPaymentNotification, error) {
 	body = bytes.TrimSpace(body)
 	if len(body) == 0 {
 		return IncomingPaymentNotification{}, ErrEmptyPayload
 	}
+	if strict && key == nil {
+		return IncomingPaymentNotification{}, fmt.Errorf("%w: webhook key is not configured", ErrInvalidWebhookSignature)
+	}
 
 	// Формат 1: компактный JWT
 	if isCompactJWT(body) {
@@ -142,6 +188,9 @@ func ParseSbpWebhook(body []byte, key *rsa.PublicKey) (IncomingPaymentNotificati
 		if json.Unmarshal(body, &env) == nil && len(bytes.TrimSpace(env.Payload)) > 0 {
 			payloadBytes, headerBytes, sigBytes, hasSig, err := decodeEnvelope(env)
 			if err == nil {
+				if strict && !hasSig {
+					return IncomingPaymentNotification{}, fmt.Errorf("%w: envelope is not signed", ErrInvalidWebhookSignature)
+				}
 				if key != nil && hasSig {
 					if err := verifyRS256(headerBytes, payloadBytes, sigBytes, key); err != nil {
 						return IncomingPaymentNotification{}, err
@@ -157,6 +206,9 @@ func ParseSbpWebhook(body []byte, key *rsa.PublicKey) (IncomingPaymentNotificati
 		}
 
 		// Формат 3: "голый" payload
+		if strict {
+			return IncomingPaymentNotification{}, fmt.Errorf("%w: unsigned payload", ErrInvalidWebhookSignature)
+		}
 		return buildNotificationFromPayload(body, map[string]any{
 			"format": "plain-payload",
 		})
@@ -265,12 +317,13 @@ func buildNotificationFromPayload(payloadBytes []byte, rawExtra map[string]any)
 		return IncomingPaymentNotification{}, fmt.Errorf("bind payload fields: %w", err)
 	}
 
-	// Пытаемся распарсить сумму (опционально)
 	var amt Amount
 	if p.AmountStr != "" {
-		if a, err := AmountFromString(p.AmountStr); err == nil {
-			amt = a
+		a, err := AmountFromString(p.AmountStr)
+		if err != nil {
+			return IncomingPaymentNotification{}, fmt.Errorf("parse amount: %w", err)
 		}
+		amt = a
 	}
 
 	// Дата платежа (если вдруг приходит строкой)
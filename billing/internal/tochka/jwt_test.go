@@ -0,0 +1,62 @@
+package tochka
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAmountFromString(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{name: "two decimals", input: "249.99", want: 24999},
+		{name: "no fraction", input: "250", want: 25000},
+		{name: "one decimal", input: "249.9", want: 24990},
+		{name: "trailing spaces", input: " 249.99  ", want: 24999},
+		{name: "comma separator", input: "10,5", want: 1050},
+		{name: "leading dot", input: ".5", want: 50},
+		{name: "rounds half up", input: "0.005", want: 1},
+		{name: "rounds down", input: "1.994", want: 199},
+		{name: "rounds into rubles", input: "9.995", want: 1000},
+		{name: "empty", input: "  ", wantErr: true},
+		{name: "letters", input: "12a.00", wantErr: true},
+		{name: "two dots", input: "1.2.3", wantErr: true},
+		{name: "negative", input: "-5.00", wantErr: true},
+		{name: "dangling dot", input: "5.", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := AmountFromString(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got %+v", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Minor != tc.want {
+				t.Fatalf("Minor = %d, want %d", got.Minor, tc.want)
+			}
+			if got.Money().Currency != "RUB" {
+				t.Fatalf("unexpected currency %q", got.Money().Currency)
+			}
+		})
+	}
+}
+
+func TestParseSbpWebhookStrictRejectsUnsigned(t *testing.T) {
+	body := []byte(`{"qrcId":"qr-1","amount":"100.00","operationId":"op-1"}`)
+
+	if _, err := ParseSbpWebhook(body, nil, false); err != nil {
+		t.Fatalf("non-strict mode should accept plain payload: %v", err)
+	}
+	_, err := ParseSbpWebhook(body, nil, true)
+	if !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Fatalf("expected ErrInvalidWebhookSignature, got %v", err)
+	}
+}
@@ -6,11 +6,24 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"billing/internal/domain"
 )
 
+// Amount — сумма из уведомления Точки: исходная строка для отображения и значение в копейках.
 type Amount struct {
 	Value    string
 	Currency string
+	Minor    int64
+}
+
+// Money возвращает сумму в доменном виде; валюта по умолчанию — RUB.
+func (a Amount) Money() domain.Money {
+	currency := a.Currency
+	if currency == "" {
+		currency = "RUB"
+	}
+	return domain.Money{Amount: a.Minor, Currency: currency}
 }
 
 //type IncomingPaymentNotification struct {
@@ -51,7 +64,8 @@ func ParseIncomingPaymentNotification(data []byte) (IncomingPaymentNotification,
 				notif.OrderID = firstString(orderMap, "orderId", "order_id")
 			}
 		}
-		notif.PaymentID = firstString(payload, "paymentId", "payment_id", "transactionId", "transaction_id", "refTransactionId", "ref_transaction_id")
+		// trxId — идентификатор операции СБП; его же возвращает статус QR-кода при сверке.
+		notif.PaymentID = firstString(payload, "trxId", "paymentId", "payment_id", "transactionId", "transaction_id", "refTransactionId", "ref_transaction_id")
 		notif.QRID = firstString(payload, "qrId", "qrCodeId", "qr_code_id")
 		notif.Status = firstString(payload, "status")
 		notif.PaymentPurpose = firstString(payload, "paymentPurpose", "purpose")
@@ -94,12 +108,18 @@ func ParseIncomingPaymentNotification(data []byte) (IncomingPaymentNotification,
 }
 
 func (n IncomingPaymentNotification) AmountMinor() (int64, error) {
+	if n.Amount.Minor > 0 {
+		return n.Amount.Minor, nil
+	}
 	if n.Amount.Value == "" {
 		return 0, fmt.Errorf("amount value is empty")
 	}
 	return parseDecimalMinor(n.Amount.Value, 2)
 }
 
+// IdempotencyKey возвращает ключ зачисления — идентификатор операции. По одному счёту
+// бывает несколько платежей, поэтому qrId ключом быть не может; сверка кладёт trxId
+// в PaymentID и попадает в тот же ключ, что и вебхук.
 func (n IncomingPaymentNotification) IdempotencyKey() string {
 	if n.PaymentID != "" {
 		return n.PaymentID
@@ -107,9 +127,6 @@ func (n IncomingPaymentNotification) IdempotencyKey() string {
 	if n.ID != "" {
 		return n.ID
 	}
-	if n.QRID != "" {
-		return n.QRID
-	}
 	return n.OrderID
 }
 
@@ -0,0 +1,22 @@
+package tochka
+
+import "testing"
+
+func TestIdempotencyKeySharedByWebhookAndReconcile(t *testing.T) {
+	webhook, err := ParseIncomingPaymentNotification([]byte(`{"event":"incomingSbpPayment","id":"evt-1","payload":{"qrId":"AD100","trxId":"trx-42","paymentId":"pay-7","amount":"500.00"}}`))
+	if err != nil {
+		t.Fatalf("parse webhook: %v", err)
+	}
+	reconcile := IncomingPaymentNotification{Event: "reconcile", PaymentID: "trx-42", QRID: "AD100"}
+	if webhook.IdempotencyKey() != reconcile.IdempotencyKey() {
+		t.Fatalf("webhook key %q differs from reconcile key %q", webhook.IdempotencyKey(), reconcile.IdempotencyKey())
+	}
+
+	second := IncomingPaymentNotification{PaymentID: "trx-43", QRID: "AD100"}
+	if second.IdempotencyKey() == reconcile.IdempotencyKey() {
+		t.Fatal("two payments on one QR must not share a key")
+	}
+	if got := (IncomingPaymentNotification{ID: "op-1", QRID: "AD100"}).IdempotencyKey(); got != "op-1" {
+		t.Fatalf("expected operation id fallback, got %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package invoices
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"billing/internal/domain"
+)
+
+// Expirer периодически отменяет просроченные неоплаченные счета.
+type Expirer struct {
+	repo     domain.InvoiceExpirer
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewExpirer(repo domain.InvoiceExpirer, interval time.Duration, log zerolog.Logger) *Expirer {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Expirer{repo: repo, interval: interval, log: log}
+}
+
+// Run блокируется до отмены контекста.
+func (e *Expirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Expirer) RunOnce(ctx context.Context) {
+	cancelled, err := e.repo.ExpireInvoices(ctx, time.Now())
+	if err != nil {
+		e.log.Error().Err(err).Msg("invoices: expire failed")
+		return
+	}
+	if cancelled > 0 {
+		e.log.Info().Int64("cancelled", cancelled).Msg("invoices: expired pending invoices cancelled")
+	}
+}
@@ -0,0 +1,139 @@
+package sbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"billing/internal/domain"
+	"billing/internal/tochka"
+)
+
+const (
+	reconcileBatchSize = 100
+	// reconcileStatusChunk — сколько qrId отправляем в Точку одним запросом.
+	reconcileStatusChunk = 20
+)
+
+// ReconcileResult — итог сверки неоплаченных счетов с Точкой.
+type ReconcileResult struct {
+	Checked  int     `json:"checked"`
+	Credited []int64 `json:"credited_invoice_ids"`
+	Failed   []int64 `json:"failed_invoice_ids"`
+}
+
+// Reconcile сверяет неоплаченные счета с QR-кодом без единого платежа со статусами в Точке
+// и проводит платежи, по которым вебхук так и не пришёл. Статус Точки не содержит суммы,
+// поэтому частично оплаченные счета не сверяются: для них неизвестно, сколько пришло.
+// Повторный вызов безопасен: ключ зачисления, как и у вебхука, — идентификатор операции trxId.
+func (s *Service) Reconcile(ctx context.Context) (ReconcileResult, error) {
+	var res ReconcileResult
+	invoices, err := s.billing.ListPendingQRInvoices(ctx, reconcileBatchSize)
+	if err != nil {
+		return res, fmt.Errorf("list pending invoices: %w", err)
+	}
+	byQR := make(map[string]domain.Invoice, len(invoices))
+	qrIDs := make([]string, 0, len(invoices))
+	for _, inv := range invoices {
+		if inv.Status != domain.InvoiceStatusPending || inv.PaidAmount != 0 {
+			continue
+		}
+		byQR[inv.QrId] = inv
+		qrIDs = append(qrIDs, inv.QrId)
+	}
+
+	for start := 0; start < len(qrIDs); start += reconcileStatusChunk {
+		end := start + reconcileStatusChunk
+		if end > len(qrIDs) {
+			end = len(qrIDs)
+		}
+		statuses, err := s.client.GetQRCodeStatus(ctx, qrIDs[start:end]...)
+		if err != nil {
+			return res, fmt.Errorf("tochka qr status: %w", err)
+		}
+		res.Checked += end - start
+		for _, st := range statuses {
+			if !st.Paid() {
+				continue
+			}
+			inv, ok := byQR[st.QRID]
+			if !ok {
+				continue
+			}
+			if st.TrxID == "" {
+				s.log.Error().Int64("invoice", inv.ID).Str("qr_id", st.QRID).Msg("sbp: reconcile got paid qr without trx id")
+				res.Failed = append(res.Failed, inv.ID)
+				continue
+			}
+			// Вебхук мог прийти между выборкой счетов и запросом статуса.
+			if _, err := s.billing.GetPaymentByIdempotencyKey(ctx, st.TrxID); err == nil {
+				continue
+			} else if !errors.Is(err, domain.ErrPaymentNotFound) {
+				s.log.Error().Err(err).Int64("invoice", inv.ID).Str("trx_id", st.TrxID).Msg("sbp: reconcile payment lookup failed")
+				res.Failed = append(res.Failed, inv.ID)
+				continue
+			}
+			s.log.Error().
+				Int64("invoice", inv.ID).
+				Str("qr_id", st.QRID).
+				Str("trx_id", st.TrxID).
+				Msg("sbp: reconcile found paid qr without credited payment")
+			if _, err := s.HandleIncomingPayment(ctx, reconcileNotification(inv, st)); err != nil {
+				s.log.Error().Err(err).Int64("invoice", inv.ID).Str("qr_id", st.QRID).Msg("sbp: reconcile credit failed")
+				res.Failed = append(res.Failed, inv.ID)
+				continue
+			}
+			res.Credited = append(res.Credited, inv.ID)
+		}
+	}
+	return res, nil
+}
+
+// reconcileNotification собирает уведомление так, как если бы его прислал вебхук.
+// Точка не возвращает сумму в статусе; по счёту без платежей QR оплачивается на всю сумму.
+func reconcileNotification(inv domain.Invoice, st tochka.QRCodePaymentStatus) tochka.IncomingPaymentNotification {
+	raw := map[string]any{
+		"source":  "reconcile",
+		"code":    st.Code,
+		"status":  st.Status,
+		"message": st.Message,
+		"trxId":   st.TrxID,
+	}
+	now := time.Now()
+	return tochka.IncomingPaymentNotification{
+		Event:       "reconcile",
+		PaymentID:   st.TrxID,
+		QRID:        st.QRID,
+		Status:      st.Status,
+		PaymentDate: &now,
+		Amount: tochka.Amount{
+			Value:    tochka.FormatMinorAmount(inv.Amount.Amount),
+			Currency: inv.Amount.Currency,
+			Minor:    inv.Amount.Amount,
+		},
+		Raw: raw,
+	}
+}
+
+// RunReconciler запускает сверку по расписанию до отмены контекста.
+func (s *Service) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		res, err := s.Reconcile(ctx)
+		if err != nil {
+			s.log.Error().Err(err).Msg("sbp: reconcile failed")
+			continue
+		}
+		if len(res.Credited) > 0 || len(res.Failed) > 0 {
+			s.log.Warn().Int("checked", res.Checked).Ints64("credited", res.Credited).Ints64("failed", res.Failed).Msg("sbp: reconcile finished with discrepancies")
+		}
+	}
+}
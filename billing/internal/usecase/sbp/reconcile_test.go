@@ -0,0 +1,84 @@
+package sbp
+
+import (
+	"context"
+	"testing"
+
+	"billing/internal/domain"
+	"billing/internal/tochka"
+)
+
+// statusClient отвечает на запрос статусов заранее заданным списком.
+type statusClient struct {
+	Client
+	statuses []tochka.QRCodePaymentStatus
+	asked    []string
+}
+
+func (c *statusClient) GetQRCodeStatus(_ context.Context, qrIDs ...string) ([]tochka.QRCodePaymentStatus, error) {
+	c.asked = append(c.asked, qrIDs...)
+	return c.statuses, nil
+}
+
+func accepted(qrID, trxID string) tochka.QRCodePaymentStatus {
+	return tochka.QRCodePaymentStatus{QRID: qrID, Status: "Accepted", TrxID: trxID}
+}
+
+func TestReconcileCreditsPaidInvoiceWithoutWebhook(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	client := &statusClient{statuses: []tochka.QRCodePaymentStatus{accepted("QR1", "trx-1")}}
+
+	res, err := newTestService(billing, client).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(res.Credited) != 1 || len(res.Failed) != 0 || billing.balance != 50000 {
+		t.Fatalf("expected invoice to be credited once, got %+v, balance %d", res, billing.balance)
+	}
+	if _, ok := billing.payments["trx-1"]; !ok {
+		t.Fatal("expected credit under the trx id the webhook would use")
+	}
+}
+
+func TestReconcileSkipsAlreadyCreditedPayment(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	billing.payments["trx-1"] = domain.Payment{ID: 9, AccountID: 1, Amount: domain.Money{Amount: 50000, Currency: "RUB"}}
+	client := &statusClient{statuses: []tochka.QRCodePaymentStatus{accepted("QR1", "trx-1")}}
+
+	res, err := newTestService(billing, client).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(res.Credited) != 0 || len(res.Failed) != 0 || billing.balance != 0 {
+		t.Fatalf("expected nothing to do for a credited payment, got %+v, balance %d", res, billing.balance)
+	}
+}
+
+func TestReconcileSkipsPartiallyPaidInvoice(t *testing.T) {
+	inv := qrInvoice(1, 50000)
+	inv.Status = domain.InvoiceStatusPartiallyPaid
+	inv.PaidAmount = 20000
+	billing := newMemBilling(inv)
+	client := &statusClient{statuses: []tochka.QRCodePaymentStatus{accepted("QR1", "trx-2")}}
+
+	res, err := newTestService(billing, client).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(client.asked) != 0 || len(res.Credited) != 0 || len(res.Failed) != 0 || billing.balance != 0 {
+		t.Fatalf("partially paid invoice must not be reconciled, asked %v, got %+v", client.asked, res)
+	}
+}
+
+func TestReconcileIgnoresUnknownQR(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	client := &statusClient{statuses: []tochka.QRCodePaymentStatus{accepted("QR404", "trx-1")}}
+
+	res, err := newTestService(billing, client).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if res.Checked != 1 || len(res.Credited) != 0 || len(res.Failed) != 0 || billing.balance != 0 {
+		t.Fatalf("status for an unknown qr must be ignored, got %+v, balance %d", res, billing.balance)
+	}
+}
@@ -15,12 +15,14 @@ import (
 // Client — минимальный интерфейс клиента Точки, который нам нужен.
 type Client interface {
 	RegisterQRCode(ctx context.Context, req tochka.RegisterQRCodeRequest) (tochka.RegisterQRCodeResponse, error)
+	GetQRCodeStatus(ctx context.Context, qrIDs ...string) ([]tochka.QRCodePaymentStatus, error)
 }
 
 type Service struct {
 	billing          domain.Billing
 	client           Client
 	defaultNotifyURL string
+	notifications    domain.NotificationQueue
 	log              zerolog.Logger
 }
 
@@ -41,11 +43,12 @@ type CreateInvoiceResult struct {
 	QR      tochka.RegisterQRCodeResponse
 }
 
-func NewService(b domain.Billing, client Client, notificationURL string, log zerolog.Logger) *Service {
+func NewService(b domain.Billing, client Client, notificationURL string, notifications domain.NotificationQueue, log zerolog.Logger) *Service {
 	return &Service{
 		billing:          b,
 		client:           client,
 		defaultNotifyURL: notificationURL,
+		notifications:    notifications,
 		log:              log,
 	}
 }
@@ -163,6 +166,14 @@ func (s *Service) HandleIncomingPayment(ctx context.Context, notification tochka
 	if currency == "" {
 		currency = invoice.Amount.Currency
 	}
+	if currency != invoice.Amount.Currency {
+		return domain.Payment{}, fmt.Errorf("%w: currency %s, invoice %s", domain.ErrPaymentAmountMismatch, currency, invoice.Amount.Currency)
+	}
+	// Один платёж не может превышать сумму счёта; остаток с учётом частичных оплат
+	// проверяет хранилище, уже зная, новый это платёж или повтор.
+	if amountMinor <= 0 || amountMinor > invoice.Amount.Amount {
+		return domain.Payment{}, fmt.Errorf("%w: paid %d, invoice %d", domain.ErrPaymentAmountMismatch, amountMinor, invoice.Amount.Amount)
+	}
 
 	metadata := map[string]any{
 		"provider":        "tochka",
@@ -185,6 +196,9 @@ func (s *Service) HandleIncomingPayment(ctx context.Context, notification tochka
 	}
 
 	idempotencyKey := notification.IdempotencyKey()
+	if idempotencyKey == "" {
+		return domain.Payment{}, fmt.Errorf("webhook missing operation id")
+	}
 	payment, err := s.billing.RegisterIncomingPayment(ctx, domain.RegisterIncomingPaymentParams{
 		AccountID: invoice.AccountID,
 		InvoiceID: &invoice.ID,
@@ -198,5 +212,44 @@ func (s *Service) HandleIncomingPayment(ctx context.Context, notification tochka
 	if err != nil {
 		return domain.Payment{}, fmt.Errorf("register payment: %w", err)
 	}
+	s.enqueueBalanceNotification(ctx, invoice, payment)
 	return payment, nil
 }
+
+// enqueueBalanceNotification ставит в очередь сообщение «баланс пополнен».
+// Ошибки только логируем: платёж уже зачислен, и вебхук не должен из-за этого падать.
+func (s *Service) enqueueBalanceNotification(ctx context.Context, invoice domain.Invoice, payment domain.Payment) {
+	if s.notifications == nil || payment.Status != "completed" {
+		return
+	}
+	chatID, ok := domain.ExtractTelegramChatID(invoice.Metadata)
+	if !ok {
+		s.log.Warn().Int64("invoice", invoice.ID).Msg("sbp: invoice has no tg_user_id, skip balance notification")
+		return
+	}
+	paymentID := payment.ID
+	text := fmt.Sprintf("💳 Баланс пополнен на %s.\nТекущий баланс: /balance", formatMoney(payment.Amount))
+	err := s.notifications.EnqueueNotification(ctx, domain.EnqueueNotificationParams{
+		PaymentID: &paymentID,
+		ChatID:    chatID,
+		Text:      text,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Int64("payment", payment.ID).Msg("sbp: enqueue balance notification failed")
+	}
+}
+
+func formatMoney(m domain.Money) string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	symbol := m.Currency
+	switch m.Currency {
+	case "", "RUB", "RUR":
+		symbol = "₽"
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/100, amount%100, symbol)
+}
@@ -0,0 +1,204 @@
+package sbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"billing/internal/domain"
+	"billing/internal/tochka"
+)
+
+// memBilling хранит счета и платежи в памяти и проводит платежи по тем же правилам,
+// что и хранилище: domain.CheckIncomingPayment и domain.ApplyInvoicePayment.
+type memBilling struct {
+	domain.Billing
+	allowOrphan bool
+	balance     int64
+	invoices    map[int64]*domain.Invoice
+	payments    map[string]domain.Payment
+}
+
+func newMemBilling(invoices ...domain.Invoice) *memBilling {
+	b := &memBilling{invoices: map[int64]*domain.Invoice{}, payments: map[string]domain.Payment{}}
+	for i := range invoices {
+		inv := invoices[i]
+		b.invoices[inv.ID] = &inv
+	}
+	return b
+}
+
+func (b *memBilling) GetInvoiceByQrId(_ context.Context, qrID string) (domain.Invoice, error) {
+	for _, inv := range b.invoices {
+		if inv.QrId == qrID {
+			return *inv, nil
+		}
+	}
+	return domain.Invoice{}, domain.ErrInvoiceNotFound
+}
+
+func (b *memBilling) ListPendingQRInvoices(context.Context, int) ([]domain.Invoice, error) {
+	var invoices []domain.Invoice
+	for _, inv := range b.invoices {
+		invoices = append(invoices, *inv)
+	}
+	return invoices, nil
+}
+
+func (b *memBilling) GetPaymentByIdempotencyKey(_ context.Context, key string) (domain.Payment, error) {
+	payment, ok := b.payments[key]
+	if !ok {
+		return domain.Payment{}, domain.ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+func (b *memBilling) RegisterIncomingPayment(_ context.Context, params domain.RegisterIncomingPaymentParams) (domain.Payment, error) {
+	var invoice *domain.Invoice
+	if params.InvoiceID != nil {
+		invoice = b.invoices[*params.InvoiceID]
+	}
+	var existing *domain.Payment
+	if payment, ok := b.payments[params.IdempotencyKey]; ok {
+		existing = &payment
+	}
+	now := time.Now()
+	action, err := domain.CheckIncomingPayment(invoice, existing, params, now, b.allowOrphan)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	if action != domain.IncomingPaymentCredit {
+		if existing != nil {
+			return *existing, nil
+		}
+		return domain.Payment{}, nil
+	}
+	payment := domain.Payment{
+		ID:             int64(len(b.payments) + 1),
+		AccountID:      params.AccountID,
+		InvoiceID:      params.InvoiceID,
+		Amount:         params.Amount,
+		Status:         "completed",
+		IdempotencyKey: params.IdempotencyKey,
+		CompletedAt:    &now,
+	}
+	b.payments[params.IdempotencyKey] = payment
+	b.balance += params.Amount.Amount
+	if invoice != nil {
+		if invoice.Expired(now) {
+			invoice.Status = domain.InvoiceStatusCancelled
+		}
+		invoice.Status, invoice.PaidAmount = domain.ApplyInvoicePayment(*invoice, params.Amount.Amount)
+	}
+	return payment, nil
+}
+
+func qrInvoice(id int64, amount int64) domain.Invoice {
+	return domain.Invoice{
+		ID:        id,
+		AccountID: 1,
+		Amount:    domain.Money{Amount: amount, Currency: "RUB"},
+		Status:    domain.InvoiceStatusPending,
+		QrId:      fmt.Sprintf("QR%d", id),
+	}
+}
+
+func webhookPayment(qrID, trxID string, amount int64, currency string) tochka.IncomingPaymentNotification {
+	return tochka.IncomingPaymentNotification{
+		Event:     "incomingSbpPayment",
+		QRID:      qrID,
+		PaymentID: trxID,
+		Amount:    tochka.Amount{Minor: amount, Currency: currency},
+	}
+}
+
+func newTestService(b domain.Billing, client Client) *Service {
+	return NewService(b, client, "", nil, zerolog.Nop())
+}
+
+func TestHandleIncomingPaymentAccumulatesPaymentsOnOneQR(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	svc := newTestService(billing, nil)
+
+	for i, amount := range []int64{20000, 10000, 20000} {
+		if _, err := svc.HandleIncomingPayment(context.Background(), webhookPayment("QR1", fmt.Sprintf("trx-%d", i), amount, "RUB")); err != nil {
+			t.Fatalf("payment %d: %v", i, err)
+		}
+	}
+	inv := billing.invoices[1]
+	if inv.Status != domain.InvoiceStatusPaid || inv.PaidAmount != 50000 || billing.balance != 50000 {
+		t.Fatalf("expected paid invoice and 50000 credited, got %s/%d, balance %d", inv.Status, inv.PaidAmount, billing.balance)
+	}
+}
+
+func TestHandleIncomingPaymentReplayCreditsOnce(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	svc := newTestService(billing, nil)
+	notification := webhookPayment("QR1", "trx-1", 20000, "RUB")
+
+	first, err := svc.HandleIncomingPayment(context.Background(), notification)
+	if err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	second, err := svc.HandleIncomingPayment(context.Background(), notification)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if first.ID != second.ID || billing.balance != 20000 || billing.invoices[1].PaidAmount != 20000 {
+		t.Fatalf("replay must not credit again: payments %d/%d, balance %d", first.ID, second.ID, billing.balance)
+	}
+}
+
+func TestHandleIncomingPaymentRejectsCurrencyMismatch(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	svc := newTestService(billing, nil)
+
+	_, err := svc.HandleIncomingPayment(context.Background(), webhookPayment("QR1", "trx-1", 50000, "USD"))
+	if !errors.Is(err, domain.ErrPaymentAmountMismatch) {
+		t.Fatalf("expected amount mismatch, got %v", err)
+	}
+	if billing.balance != 0 {
+		t.Fatalf("expected nothing credited, got %d", billing.balance)
+	}
+}
+
+func TestHandleIncomingPaymentRejectsOverpayment(t *testing.T) {
+	billing := newMemBilling(qrInvoice(1, 50000))
+	svc := newTestService(billing, nil)
+
+	if _, err := svc.HandleIncomingPayment(context.Background(), webhookPayment("QR1", "trx-1", 30000, "RUB")); err != nil {
+		t.Fatalf("first payment: %v", err)
+	}
+	_, err := svc.HandleIncomingPayment(context.Background(), webhookPayment("QR1", "trx-2", 30000, "RUB"))
+	if !errors.Is(err, domain.ErrPaymentAmountMismatch) {
+		t.Fatalf("expected amount mismatch, got %v", err)
+	}
+	if inv := billing.invoices[1]; inv.Status != domain.InvoiceStatusPartiallyPaid || billing.balance != 30000 {
+		t.Fatalf("overpayment must not be credited: %s, balance %d", inv.Status, billing.balance)
+	}
+}
+
+func TestHandleIncomingPaymentOnExpiredInvoice(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	inv := qrInvoice(1, 50000)
+	inv.ExpiresAt = &past
+
+	billing := newMemBilling(inv)
+	_, err := newTestService(billing, nil).HandleIncomingPayment(context.Background(), webhookPayment("QR1", "trx-1", 50000, "RUB"))
+	if !errors.Is(err, domain.ErrInvoiceCancelled) || billing.balance != 0 {
+		t.Fatalf("expected cancelled invoice to refuse payment, got %v, balance %d", err, billing.balance)
+	}
+
+	billing = newMemBilling(inv)
+	billing.allowOrphan = true
+	if _, err := newTestService(billing, nil).HandleIncomingPayment(context.Background(), webhookPayment("QR1", "trx-1", 50000, "RUB")); err != nil {
+		t.Fatalf("orphan credit: %v", err)
+	}
+	if billing.balance != 50000 || billing.invoices[1].Status != domain.InvoiceStatusCancelled {
+		t.Fatalf("expected credit with invoice left cancelled, got balance %d, %s", billing.balance, billing.invoices[1].Status)
+	}
+}
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE billing_notifications (
+    id               BIGSERIAL PRIMARY KEY,
+    payment_id       BIGINT REFERENCES billing_payments(id) ON DELETE CASCADE,
+    chat_id          BIGINT NOT NULL,
+    text             TEXT   NOT NULL,
+    status           TEXT   NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts         INT    NOT NULL DEFAULT 0,
+    last_error       TEXT,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at          TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX billing_notifications_payment_id_uq ON billing_notifications(payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX billing_notifications_due_idx ON billing_notifications(next_attempt_at) WHERE status = 'pending';
+
+COMMIT;
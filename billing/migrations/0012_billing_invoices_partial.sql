@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE billing_invoices ADD COLUMN IF NOT EXISTS paid_amount BIGINT NOT NULL DEFAULT 0;
+
+UPDATE billing_invoices SET paid_amount = amount WHERE status = 'paid' AND paid_amount = 0;
+
+ALTER TABLE billing_invoices DROP CONSTRAINT IF EXISTS billing_invoices_status_check;
+ALTER TABLE billing_invoices ADD CONSTRAINT billing_invoices_status_check
+    CHECK (status IN ('pending', 'partially_paid', 'paid', 'cancelled'));
+
+COMMIT;
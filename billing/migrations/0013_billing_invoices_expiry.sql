@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE billing_invoices ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+UPDATE billing_invoices SET expires_at = created_at + interval '24 hours' WHERE status = 'pending' AND expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS billing_invoices_pending_expires_idx ON billing_invoices(expires_at) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"tg-digest-bot/internal/adapters/mtproto"
+	"tg-digest-bot/internal/adapters/repo"
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/config"
+	"tg-digest-bot/internal/infra/db"
+)
+
+const (
+	adminCollectDefaultHours = 24
+	adminCollectMaxHours     = 24 * 7
+	adminCollectSampleSize   = 5
+	adminCollectSampleText   = 300
+	adminMetricsDefaultLimit = 100
+	adminMetricsMaxLimit     = 1000
+
+	adminMetricsDailyDefaultDays = 30
+)
+
+// adminHandler обслуживает служебные эндпоинты для поддержки.
+type adminHandler struct {
+	resolver  domain.ChannelResolver
+	collector domain.Collector
+	channels  domain.ChannelRepo
+	posts     domain.PostRepo
+	analytics domain.BusinessMetricReader
+}
+
+// newAdminHandler поднимает подключение к БД и MTProto-пул для служебных эндпоинтов.
+func newAdminHandler(ctx context.Context, cfg config.AppConfig) (*adminHandler, *pgxpool.Pool) {
+	pool, err := db.Connect(cfg.PGDSN)
+	if err != nil {
+		log.Fatal().Err(err).Msg("api: нет подключения к БД для admin API")
+	}
+	repoAdapter := repo.NewPostgres(pool)
+
+	if cfg.MTProto.SessionName == "" {
+		log.Fatal().Msg("api: не указан пул MTProto-аккаунтов (MTPROTO_SESSION_NAME)")
+	}
+	accountCtx, accountCancel := context.WithTimeout(ctx, 10*time.Second)
+	accountsMeta, err := repoAdapter.ListMTProtoAccounts(accountCtx, cfg.MTProto.SessionName)
+	accountCancel()
+	if err != nil {
+		log.Fatal().Err(err).Msg("api: не удалось загрузить MTProto-аккаунты")
+	}
+	accounts := make([]mtproto.Account, 0, len(accountsMeta))
+	for _, meta := range accountsMeta {
+		if !meta.Enabled {
+			continue
+		}
+		accounts = append(accounts, mtproto.Account{
+			Name:    meta.Name,
+			APIID:   meta.APIID,
+			APIHash: meta.APIHash,
+			Storage: mtproto.NewSessionDB(repoAdapter, meta.Name),
+		})
+	}
+	resolver, err := mtproto.NewResolver(accounts, log.Logger)
+	if err != nil {
+		log.Fatal().Err(err).Msg("api: не удалось создать MTProto резолвер")
+	}
+	collector, err := mtproto.NewCollector(accounts, log.Logger, cfg.Collect.ChannelTimeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("api: не удалось создать MTProto клиента")
+	}
+
+	return &adminHandler{resolver: resolver, collector: collector, channels: repoAdapter, posts: repoAdapter, analytics: repoAdapter}, pool
+}
+
+type adminCollectRequest struct {
+	Alias   string `json:"alias"`
+	Hours   int    `json:"hours"`
+	Persist bool   `json:"persist"`
+}
+
+type adminCollectedPost struct {
+	TGMsgID     int64     `json:"tg_msg_id"`
+	PublishedAt time.Time `json:"published_at"`
+	URL         string    `json:"url"`
+	Text        string    `json:"text"`
+}
+
+// collect принудительно собирает посты канала за указанное окно.
+// Без persist посты только возвращаются и не попадают в дайджесты пользователей.
+func (h *adminHandler) collect(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req adminCollectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Alias == "" {
+		writeError(w, http.StatusBadRequest, "alias is required")
+		return
+	}
+	if req.Hours <= 0 {
+		req.Hours = adminCollectDefaultHours
+	}
+	if req.Hours > adminCollectMaxHours {
+		writeError(w, http.StatusBadRequest, "hours is too large")
+		return
+	}
+
+	meta, err := h.resolver.ResolvePublic(req.Alias)
+	if err != nil {
+		log.Error().Err(err).Str("alias", req.Alias).Msg("api: admin collect resolve")
+		writeError(w, http.StatusBadGateway, "failed to resolve channel")
+		return
+	}
+
+	channel := domain.Channel{TGChannelID: meta.ID, Alias: meta.Alias, Title: meta.Title}
+	if req.Persist {
+		channel, err = h.channels.UpsertChannel(meta)
+		if err != nil {
+			log.Error().Err(err).Str("alias", meta.Alias).Msg("api: admin collect upsert channel")
+			writeError(w, http.StatusInternalServerError, "failed to save channel")
+			return
+		}
+	}
+
+	since := time.Now().UTC().Add(-time.Duration(req.Hours) * time.Hour)
+	posts, err := h.collector.CollectSince(channel, since)
+	if err != nil {
+		log.Error().Err(err).Str("alias", meta.Alias).Msg("api: admin collect")
+		writeError(w, http.StatusBadGateway, "failed to collect channel")
+		return
+	}
+
+	if req.Persist {
+		if err := h.posts.SavePosts(channel.ID, posts); err != nil {
+			log.Error().Err(err).Str("alias", meta.Alias).Msg("api: admin collect save posts")
+			writeError(w, http.StatusInternalServerError, "failed to save posts")
+			return
+		}
+	}
+
+	sample := make([]adminCollectedPost, 0, adminCollectSampleSize)
+	for _, post := range posts {
+		if len(sample) == adminCollectSampleSize {
+			break
+		}
+		sample = append(sample, adminCollectedPost{
+			TGMsgID:     post.TGMsgID,
+			PublishedAt: post.PublishedAt,
+			URL:         post.URL,
+			Text:        clipText(post.Text, adminCollectSampleText),
+		})
+	}
+
+	writeJSON(w, map[string]any{
+		"alias":     meta.Alias,
+		"title":     meta.Title,
+		"since":     since,
+		"count":     len(posts),
+		"persisted": req.Persist,
+		"sample":    sample,
+	})
+}
+
+type adminMetricItem struct {
+	ID         int64          `json:"id"`
+	Event      string         `json:"event"`
+	UserID     *int64         `json:"user_id,omitempty"`
+	ChannelID  *int64         `json:"channel_id,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// businessMetrics выгружает бизнес-события с фильтром по имени и времени.
+// Страницы листаются курсором after: в ответе next_after — id последнего события.
+func (h *adminHandler) businessMetrics(w http.ResponseWriter, r *http.Request) {
+	query, err := parseMetricsQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := h.analytics.QueryBusinessMetrics(r.Context(), query)
+	if err != nil {
+		log.Error().Err(err).Str("event", query.Event).Msg("api: admin query business metrics")
+		writeError(w, http.StatusInternalServerError, "failed to query metrics")
+		return
+	}
+	items := make([]adminMetricItem, 0, len(rows))
+	for _, m := range rows {
+		items = append(items, adminMetricItem{
+			ID:         m.ID,
+			Event:      m.Event,
+			UserID:     m.UserID,
+			ChannelID:  m.ChannelID,
+			Metadata:   m.Metadata,
+			OccurredAt: m.OccurredAt,
+		})
+	}
+	resp := map[string]any{"items": items}
+	if len(items) == query.Limit {
+		resp["next_after"] = items[len(items)-1].ID
+	}
+	writeJSON(w, resp)
+}
+
+// parseMetricsQuery разбирает event, from, to, after и limit. Время принимается
+// в RFC 3339 или как дата 2006-01-02 (полночь UTC).
+func parseMetricsQuery(values url.Values) (domain.BusinessMetricQuery, error) {
+	query := domain.BusinessMetricQuery{
+		Event: strings.TrimSpace(values.Get("event")),
+		Limit: adminMetricsDefaultLimit,
+	}
+	var err error
+	if query.From, err = parseAdminTime(values.Get("from")); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if query.To, err = parseAdminTime(values.Get("to")); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, errors.New("from must be before to")
+	}
+	if raw := values.Get("after"); raw != "" {
+		query.AfterID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || query.AfterID < 0 {
+			return query, errors.New("invalid after")
+		}
+	}
+	if raw := values.Get("limit"); raw != "" {
+		query.Limit, err = strconv.Atoi(raw)
+		if err != nil || query.Limit <= 0 {
+			return query, errors.New("invalid limit")
+		}
+		if query.Limit > adminMetricsMaxLimit {
+			query.Limit = adminMetricsMaxLimit
+		}
+	}
+	return query, nil
+}
+
+type adminMetricDaily struct {
+	Date          string `json:"date"`
+	Event         string `json:"event"`
+	Count         int64  `json:"count"`
+	DistinctUsers int64  `json:"distinct_users"`
+}
+
+// businessMetricsDaily отдаёт дневные агрегаты событий. Без from выгружаются последние
+// adminMetricsDailyDefaultDays дней, сегодняшний день появится после ночного пересчёта.
+func (h *adminHandler) businessMetricsDaily(w http.ResponseWriter, r *http.Request) {
+	query, err := parseMetricsQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.From.IsZero() {
+		query.From = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -adminMetricsDailyDefaultDays)
+	}
+	rows, err := h.analytics.ListBusinessMetricsDaily(r.Context(), query)
+	if err != nil {
+		log.Error().Err(err).Str("event", query.Event).Msg("api: admin list daily business metrics")
+		writeError(w, http.StatusInternalServerError, "failed to query metrics")
+		return
+	}
+	items := make([]adminMetricDaily, 0, len(rows))
+	for _, d := range rows {
+		items = append(items, adminMetricDaily{
+			Date:          d.Day.Format("2006-01-02"),
+			Event:         d.Event,
+			Count:         d.Count,
+			DistinctUsers: d.DistinctUsers,
+		})
+	}
+	writeJSON(w, map[string]any{"items": items})
+}
+
+func parseAdminTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+func clipText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
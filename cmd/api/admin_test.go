@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseMetricsQuery(t *testing.T) {
+	query, err := parseMetricsQuery(url.Values{
+		"event": {"digest_delivered"},
+		"from":  {"2024-05-01"},
+		"to":    {"2024-05-02T12:00:00+03:00"},
+		"after": {"42"},
+		"limit": {"5000"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query.Event != "digest_delivered" || query.AfterID != 42 {
+		t.Fatalf("unexpected query: %+v", query)
+	}
+	if !query.From.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !query.To.Equal(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected range: %s — %s", query.From, query.To)
+	}
+	if query.Limit != adminMetricsMaxLimit {
+		t.Fatalf("limit should be capped, got %d", query.Limit)
+	}
+
+	query, err = parseMetricsQuery(url.Values{})
+	if err != nil || query.Limit != adminMetricsDefaultLimit || !query.From.IsZero() {
+		t.Fatalf("unexpected defaults: %+v, %v", query, err)
+	}
+
+	for _, values := range []url.Values{
+		{"from": {"yesterday"}},
+		{"from": {"2024-05-02"}, "to": {"2024-05-01"}},
+		{"after": {"-1"}},
+		{"limit": {"0"}},
+	} {
+		if _, err := parseMetricsQuery(values); err == nil {
+			t.Fatalf("expected error for %v", values)
+		}
+	}
+}
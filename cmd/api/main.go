@@ -13,14 +13,24 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"tg-digest-bot/internal/adapters/billingclient"
+	"tg-digest-bot/internal/adapters/repo"
 	"tg-digest-bot/internal/domain"
 	"tg-digest-bot/internal/infra/config"
+	"tg-digest-bot/internal/infra/db"
 	httpinfra "tg-digest-bot/internal/infra/http"
+	applog "tg-digest-bot/internal/infra/log"
 	"tg-digest-bot/internal/infra/metrics"
+	"tg-digest-bot/internal/usecase/settings"
 )
 
 func main() {
 	cfg := config.Load()
+	if _, err := applog.NewLogger(cfg.AppEnv, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatal().Err(err).Msg("api: invalid LOG_FORMAT/LOG_LEVEL")
+	}
+	if err := domain.SetDefaultTimezone(cfg.DefaultTimezone); err != nil {
+		log.Fatal().Err(err).Msg("api: invalid DEFAULT_TIMEZONE")
+	}
 
 	metrics.MustRegister(prometheus.DefaultRegisterer)
 
@@ -49,6 +59,18 @@ func main() {
 		sbpClient = b
 	}
 
+	var settingsAPI *settingsHandler
+	if cfg.PGDSN != "" {
+		pool, err := db.Connect(cfg.PGDSN)
+		if err != nil {
+			log.Fatal().Err(err).Msg("api: нет подключения к БД")
+		}
+		defer pool.Close()
+		settingsAPI = &settingsHandler{settings: settings.NewService(repo.NewPostgres(pool))}
+	} else {
+		log.Warn().Msg("api: PG_DSN is not configured, settings endpoints disabled")
+	}
+
 	r := chi.NewRouter()
 
 	r.Group(func(protected chi.Router) {
@@ -79,6 +101,11 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 		})
 
+		if settingsAPI != nil {
+			protected.Get("/api/v1/settings", settingsAPI.get)
+			protected.Put("/api/v1/settings", settingsAPI.update)
+		}
+
 		protected.Put("/api/v1/settings/time", func(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, map[string]string{"status": "ok"})
 		})
@@ -126,6 +153,19 @@ func main() {
 		})
 	})
 
+	if cfg.Admin.APIToken != "" {
+		admin, adminPool := newAdminHandler(ctx, cfg)
+		defer adminPool.Close()
+		r.Group(func(adminRouter chi.Router) {
+			adminRouter.Use(httpinfra.TokenAuthMiddleware(cfg.Admin.APIToken))
+			adminRouter.Post("/api/v1/admin/collect", admin.collect)
+			adminRouter.Get("/api/v1/admin/metrics", admin.businessMetrics)
+			adminRouter.Get("/api/v1/admin/metrics/daily", admin.businessMetricsDaily)
+		})
+	} else {
+		log.Warn().Msg("api: ADMIN_API_TOKEN is not configured, admin endpoints disabled")
+	}
+
 	srv := &http.Server{Addr: ":8081", Handler: r}
 	metrics.StartServer(ctx, log.With().Str("component", "metrics").Logger(), ":9090")
 	go func() {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"tg-digest-bot/internal/domain"
+	httpinfra "tg-digest-bot/internal/infra/http"
+	"tg-digest-bot/internal/usecase/settings"
+)
+
+// settingsHandler отдаёт и меняет настройки доставки для экрана настроек Mini App.
+type settingsHandler struct {
+	settings *settings.Service
+}
+
+type settingsRequest struct {
+	DailyTime  *string `json:"daily_time"`
+	Timezone   *string `json:"timezone"`
+	DigestLang *string `json:"digest_lang"`
+}
+
+type settingsResponse struct {
+	DailyTime  string `json:"daily_time"`
+	Timezone   string `json:"timezone"`
+	DigestLang string `json:"digest_lang"`
+	Plan       string `json:"plan"`
+}
+
+func newSettingsResponse(user domain.User) settingsResponse {
+	return settingsResponse{
+		DailyTime:  user.DailyTime.Format("15:04"),
+		Timezone:   user.Timezone,
+		DigestLang: user.DigestLang,
+		Plan:       string(user.Plan().Role),
+	}
+}
+
+func (h *settingsHandler) get(w http.ResponseWriter, r *http.Request) {
+	tgUserID, ok := httpinfra.WebAppUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "user is missing in init_data")
+		return
+	}
+	user, err := h.settings.Get(r.Context(), tgUserID)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+	writeJSON(w, newSettingsResponse(user))
+}
+
+func (h *settingsHandler) update(w http.ResponseWriter, r *http.Request) {
+	tgUserID, ok := httpinfra.WebAppUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "user is missing in init_data")
+		return
+	}
+	defer r.Body.Close()
+	var req settingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	user, err := h.settings.Update(r.Context(), tgUserID, settings.Patch{
+		DailyTime:  req.DailyTime,
+		Timezone:   req.Timezone,
+		DigestLang: req.DigestLang,
+	})
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+	writeJSON(w, newSettingsResponse(user))
+}
+
+func writeSettingsError(w http.ResponseWriter, err error) {
+	var verr *settings.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "fields": verr.Fields})
+	case errors.Is(err, domain.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "user not found, open the bot first")
+	default:
+		log.Error().Err(err).Msg("api: settings")
+		writeError(w, http.StatusInternalServerError, "failed to process settings")
+	}
+}
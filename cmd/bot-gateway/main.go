@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"os/signal"
 	"syscall"
@@ -11,24 +10,46 @@ import (
 	chi "github.com/go-chi/chi/v5"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 
 	"tg-digest-bot/internal/adapters/billingclient"
 	"tg-digest-bot/internal/adapters/bot"
+	"tg-digest-bot/internal/adapters/email"
 	"tg-digest-bot/internal/adapters/mtproto"
 	"tg-digest-bot/internal/adapters/repo"
+	"tg-digest-bot/internal/adapters/telegram"
 	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/cache"
 	"tg-digest-bot/internal/infra/config"
 	"tg-digest-bot/internal/infra/db"
+	httpinfra "tg-digest-bot/internal/infra/http"
 	"tg-digest-bot/internal/infra/log"
 	"tg-digest-bot/internal/infra/metrics"
 	"tg-digest-bot/internal/infra/queue"
 	"tg-digest-bot/internal/usecase/channels"
+	digestusecase "tg-digest-bot/internal/usecase/digest"
 	"tg-digest-bot/internal/usecase/schedule"
 )
 
+// planActivationRetryInterval — как часто повторяем выдачу оплаченных, но не применённых тарифов.
+const planActivationRetryInterval = time.Minute
+
 func main() {
 	cfg := config.Load()
-	logger := log.NewLogger(cfg.AppEnv)
+	logger, err := log.NewLogger(cfg.AppEnv, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("бот: некорректные настройки логов")
+	}
+	if err := domain.SetReferralTiers(cfg.Referrals.PlusTarget, cfg.Referrals.ProTarget); err != nil {
+		logger.Fatal().Err(err).Msg("бот: некорректные REFERRAL_PLUS_TARGET/REFERRAL_PRO_TARGET")
+	}
+	if err := domain.SetDefaultTimezone(cfg.DefaultTimezone); err != nil {
+		logger.Fatal().Err(err).Msg("бот: некорректный DEFAULT_TIMEZONE")
+	}
+	botMode, err := parseBotMode(cfg.Telegram.Mode)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("бот: некорректный BOT_MODE")
+	}
 
 	metrics.MustRegister(prometheus.DefaultRegisterer)
 
@@ -42,6 +63,12 @@ func main() {
 	defer pool.Close()
 
 	repoAdapter := repo.NewPostgres(pool)
+	repoAdapter.SetReferralGuard(domain.ReferralGuard{
+		Enabled:       cfg.ReferralGuard.Enabled,
+		Window:        cfg.ReferralGuard.Window,
+		BurstLimit:    cfg.ReferralGuard.BurstLimit,
+		FreshTGUserID: cfg.ReferralGuard.FreshTGUserID,
+	})
 	var billingAdapter domain.Billing
 	if cfg.Billing.BaseURL != "" {
 		if cfg.Billing.APIToken == "" {
@@ -84,6 +111,9 @@ func main() {
 	}
 	resolverAccounts := make([]mtproto.Account, 0, len(accountsMeta))
 	for _, meta := range accountsMeta {
+		if !meta.Enabled {
+			continue
+		}
 		resolverAccounts = append(resolverAccounts, mtproto.Account{
 			Name:    meta.Name,
 			APIID:   meta.APIID,
@@ -91,34 +121,130 @@ func main() {
 			Storage: mtproto.NewSessionDB(repoAdapter, meta.Name),
 		})
 	}
+	if len(resolverAccounts) == 0 {
+		logger.Fatal().Msg("в пуле MTProto нет включённых аккаунтов")
+	}
 	resolver, err := mtproto.NewResolver(resolverAccounts, logger)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("не удалось создать MTProto резолвер")
 	}
 	channelService := channels.NewService(repoAdapter, resolver, repoAdapter)
+	channelService.SetTagLimits(channels.TagLimits{MaxCount: cfg.Limits.TagsPerChannel, MaxLength: cfg.Limits.TagLength})
 	scheduleService := schedule.NewService(repoAdapter)
 
 	botAPI, err := tgbotapi.NewBotAPI(cfg.Telegram.Token)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("не удалось создать бота")
 	}
+	botClient := telegram.NewClient(botAPI)
 
-	h := bot.NewHandler(botAPI, logger, channelService, scheduleService, repoAdapter, billingAdapter, sbpClient, digestQueue, repoAdapter, repoAdapter, cfg.Limits.DigestMax)
+	var mailer domain.EmailSender
+	if cfg.SMTP.Host != "" {
+		mailer = email.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From, cfg.SMTP.Timeout)
+	}
 
-	r := chi.NewRouter()
-	r.Post("/bot/webhook", func(w http.ResponseWriter, r *http.Request) {
-		var update tgbotapi.Update
-		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+	layoutFooter := digestusecase.DefaultFooterTemplate
+	if cfg.DigestLayout.Footer != nil {
+		layoutFooter = *cfg.DigestLayout.Footer
+	}
+	layout, err := digestusecase.NewLayout(cfg.DigestLayout.Header, layoutFooter)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("некорректный шаблон дайджеста (DIGEST_HEADER_TEMPLATE/DIGEST_FOOTER_TEMPLATE)")
+	}
+	tokenCaps, err := digestusecase.ParsePlanTokenCaps(cfg.OpenAI.MonthlyTokenCaps)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("некорректные лимиты токенов (OPENAI_MONTHLY_TOKEN_CAP_BY_PLAN)")
+	}
+	if err := cfg.Billing.TopUp.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("некорректные суммы пополнения (BILLING_TOPUP_*)")
+	}
+	topUpPresets := make([]int64, 0, len(cfg.Billing.TopUp.Presets))
+	for _, rubles := range cfg.Billing.TopUp.Presets {
+		topUpPresets = append(topUpPresets, rubles*100)
+	}
+
+	var seenUpdates domain.SeenStore = cache.NewMemorySeen()
+	if cfg.RedisURL != "" {
+		redisOpts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("некорректный REDIS_URL")
 		}
-		h.HandleUpdate(r.Context(), update)
-		w.WriteHeader(http.StatusOK)
+		redisClient := redis.NewClient(redisOpts)
+		defer redisClient.Close()
+		seenUpdates = cache.NewRedis(redisClient)
+	}
+
+	h := bot.NewHandler(bot.Deps{
+		Bot:             botClient,
+		Self:            botAPI.Self,
+		Log:             logger,
+		Channels:        channelService,
+		Schedule:        scheduleService,
+		Users:           repoAdapter,
+		Billing:         billingAdapter,
+		SBP:             sbpClient,
+		Jobs:            digestQueue,
+		Analytics:       repoAdapter,
+		Feedback:        repoAdapter,
+		Digests:         repoAdapter,
+		Posts:           repoAdapter,
+		Activations:     repoAdapter,
+		Emails:          repoAdapter,
+		Mailer:          mailer,
+		MTProtoAccounts: repoAdapter,
+		ScheduleTasks:   repoAdapter,
+		Promos:          repoAdapter,
+		Usage:           repoAdapter,
+		TokenCaps:       tokenCaps,
+		InFlight:        seenUpdates,
+		ResolveLimit:    seenUpdates,
+		MTProtoPool:     cfg.MTProto.SessionName,
+		Layout:          layout,
+		MaxDigest:       cfg.Limits.DigestMax,
+		Leaderboard:     cfg.Referrals.Leaderboard,
+		TopUpPresets:    topUpPresets,
+		TopUpMin:        cfg.Billing.TopUp.Min * 100,
+		TopUpMax:        cfg.Billing.TopUp.Max * 100,
+		LowBalance:      cfg.Billing.TopUp.LowBalance * 100,
+		ClearDataWindow: cfg.Limits.ClearDataWindow,
 	})
+	go h.RunPlanActivationRetrier(ctx, planActivationRetryInterval)
 
-	srv := &http.Server{Addr: ":8080", Handler: r}
+	if botMode == botModeWebhook && cfg.Telegram.WebhookURL != "" {
+		params := tgbotapi.Params{"url": cfg.Telegram.WebhookURL}
+		params.AddNonEmpty("secret_token", cfg.Telegram.WebhookSecret)
+		if _, err := botAPI.MakeRequest("setWebhook", params); err != nil {
+			logger.Fatal().Err(err).Msg("не удалось зарегистрировать вебхук")
+		}
+	}
+	// Меню команд перерегистрируется при каждом запуске, чтобы совпадать со справкой текущей версии.
+	for _, lang := range []string{"", "en"} {
+		menu := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeDefault(), lang, bot.MenuCommands(lang == "en")...)
+		if err := botClient.SetMyCommands(menu); err != nil {
+			logger.Warn().Err(err).Str("lang", lang).Msg("не удалось зарегистрировать меню команд")
+		}
+	}
 
 	metrics.StartServer(ctx, logger.With().Str("component", "metrics").Logger(), ":9090")
+	if botMode == botModePolling {
+		logger.Info().Msg("бот-гейтвей запущен в режиме long polling")
+		if err := runPolling(ctx, botAPI, logger, h.HandleUpdate); err != nil {
+			logger.Fatal().Err(err).Msg("не удалось запустить long polling")
+		}
+		logger.Info().Msg("остановка бота")
+		return
+	}
+
+	r := chi.NewRouter()
+	if cfg.Telegram.WebhookSecret != "" {
+		r.Use(httpinfra.TelegramWebhookMiddleware(cfg.Telegram.WebhookSecret))
+	} else {
+		logger.Warn().Msg("не задан TG_WEBHOOK_SECRET: вебхук принимает запросы без проверки источника")
+	}
+	r.Post("/bot/webhook", webhookHandler(seenUpdates, cfg.Telegram.UpdateDedupTTL, logger, h.HandleUpdate))
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+
 	go func() {
 		logger.Info().Msg("бот-гейтвей запущен")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -137,3 +263,12 @@ var _ domain.UserRepo = (*repo.Postgres)(nil)
 var _ domain.ChannelRepo = (*repo.Postgres)(nil)
 var _ domain.PostRepo = (*repo.Postgres)(nil)
 var _ domain.DigestRepo = (*repo.Postgres)(nil)
+var _ domain.PlanActivationRepo = (*repo.Postgres)(nil)
+var _ domain.EmailRepo = (*repo.Postgres)(nil)
+var _ domain.MTProtoAccountRepo = (*repo.Postgres)(nil)
+var _ domain.ScheduleTaskRepo = (*repo.Postgres)(nil)
+var _ domain.PostCacheRepo = (*repo.Postgres)(nil)
+var _ domain.DigestSearchRepo = (*repo.Postgres)(nil)
+var _ domain.SeenStore = (*cache.MemorySeen)(nil)
+var _ domain.SeenStore = (*cache.RedisCache)(nil)
+var _ domain.ChannelProber = (*mtproto.Resolver)(nil)
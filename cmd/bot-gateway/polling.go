@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+)
+
+// Режимы получения апдейтов (BOT_MODE).
+const (
+	botModeWebhook = "webhook"
+	botModePolling = "polling"
+)
+
+const (
+	// pollTimeoutSeconds — сколько Telegram держит long polling запрос без новых апдейтов.
+	pollTimeoutSeconds = 30
+	// pollDrainTimeout — сколько ждать начатые обработки апдейтов при остановке.
+	pollDrainTimeout = 5 * time.Second
+)
+
+// parseBotMode проверяет BOT_MODE; пустое значение означает вебхук.
+func parseBotMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", botModeWebhook:
+		return botModeWebhook, nil
+	case botModePolling:
+		return botModePolling, nil
+	default:
+		return "", fmt.Errorf("неизвестный BOT_MODE %q: ожидается webhook или polling", value)
+	}
+}
+
+// runPolling снимает вебхук и получает апдейты через getUpdates, пока не отменён ctx.
+func runPolling(ctx context.Context, botAPI *tgbotapi.BotAPI, log zerolog.Logger, handle func(context.Context, tgbotapi.Update)) error {
+	// getUpdates не работает, пока у бота зарегистрирован вебхук.
+	if _, err := botAPI.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("снятие вебхука: %w", err)
+	}
+	cfg := tgbotapi.NewUpdate(0)
+	cfg.Timeout = pollTimeoutSeconds
+	pollUpdates(ctx, botAPI.GetUpdatesChan(cfg), botAPI.StopReceivingUpdates, log, handle)
+	return nil
+}
+
+// pollUpdates передаёт апдейты в handle, каждый в своей горутине, как запросы вебхука.
+// После отмены ctx останавливает получение и ждёт начатые обработки не дольше pollDrainTimeout;
+// сами обработки не отменяются, чтобы пользователь не остался без ответа.
+func pollUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, stop func(), log zerolog.Logger, handle func(context.Context, tgbotapi.Update)) {
+	handleCtx := context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(pollDrainTimeout):
+			log.Warn().Msg("не дождались обработки апдейтов при остановке")
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handle(handleCtx, update)
+			}()
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestParseBotMode(t *testing.T) {
+	cases := map[string]string{"": botModeWebhook, "webhook": botModeWebhook, " Polling ": botModePolling}
+	for value, want := range cases {
+		got, err := parseBotMode(value)
+		if err != nil || got != want {
+			t.Fatalf("parseBotMode(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseBotMode("grpc"); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
+
+func TestPollUpdatesHandlesUntilCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan tgbotapi.Update)
+	stopped := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int
+	done := make(chan struct{})
+	go func() {
+		pollUpdates(ctx, updates, func() { close(stopped) }, zerolog.Nop(), func(hctx context.Context, update tgbotapi.Update) {
+			if hctx.Err() != nil {
+				t.Errorf("handler context must outlive shutdown")
+			}
+			mu.Lock()
+			handled = append(handled, update.UpdateID)
+			mu.Unlock()
+		})
+		close(done)
+	}()
+	updates <- tgbotapi.Update{UpdateID: 1}
+	updates <- tgbotapi.Update{UpdateID: 2}
+	cancel()
+	<-done
+	select {
+	case <-stopped:
+	default:
+		t.Fatalf("polling must be stopped on cancel")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 {
+		t.Fatalf("expected both updates handled before return, got %v", handled)
+	}
+}
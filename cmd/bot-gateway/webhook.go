@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// updateInFlightTTL — сколько апдейт считается обрабатываемым. Если обработка упала или процесс
+// перезапустился, повтор Telegram будет принят после этого срока.
+const updateInFlightTTL = time.Minute
+
+// webhookHandler принимает апдейты вебхука. Апдейт помечается обработанным на dedupTTL только
+// после HandleUpdate; пока он обрабатывается, повтор получает 503, и Telegram пришлёт его позже.
+func webhookHandler(seen domain.SeenStore, dedupTTL time.Duration, log zerolog.Logger, handle func(context.Context, tgbotapi.Update)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update tgbotapi.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := strconv.Itoa(update.UpdateID)
+		doneKey := "tg:update:" + id
+		if done, err := seen.Seen(r.Context(), doneKey); err != nil {
+			log.Warn().Err(err).Int("update_id", update.UpdateID).Msg("не удалось проверить повтор апдейта, обрабатываем")
+		} else if done {
+			log.Info().Int("update_id", update.UpdateID).Msg("повторный апдейт пропущен")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if first, err := seen.MarkSeen(r.Context(), "tg:update:inflight:"+id, updateInFlightTTL); err != nil {
+			log.Warn().Err(err).Int("update_id", update.UpdateID).Msg("не удалось отметить апдейт в обработке, обрабатываем")
+		} else if !first {
+			log.Info().Int("update_id", update.UpdateID).Msg("апдейт ещё обрабатывается, просим повторить позже")
+			http.Error(w, "update in progress", http.StatusServiceUnavailable)
+			return
+		}
+		handle(r.Context(), update)
+		if _, err := seen.MarkSeen(r.Context(), doneKey, dedupTTL); err != nil {
+			log.Warn().Err(err).Int("update_id", update.UpdateID).Msg("не удалось запомнить обработанный апдейт")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/infra/cache"
+)
+
+func postUpdate(h http.Handler, id string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/bot/webhook", strings.NewReader(`{"update_id":`+id+`}`)))
+	return rec.Code
+}
+
+func TestWebhookSkipsUpdateHandledBefore(t *testing.T) {
+	var handled atomic.Int32
+	h := webhookHandler(cache.NewMemorySeen(), time.Hour, zerolog.Nop(), func(context.Context, tgbotapi.Update) { handled.Add(1) })
+
+	for i := 0; i < 2; i++ {
+		if code := postUpdate(h, "7"); code != http.StatusOK {
+			t.Fatalf("delivery %d: unexpected status %d", i, code)
+		}
+	}
+	if handled.Load() != 1 {
+		t.Fatalf("expected the redelivered update to be skipped, handled %d times", handled.Load())
+	}
+}
+
+func TestWebhookAsksToRetryUpdateInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := webhookHandler(cache.NewMemorySeen(), time.Hour, zerolog.Nop(), func(context.Context, tgbotapi.Update) {
+		close(started)
+		<-release
+	})
+
+	done := make(chan int)
+	go func() { done <- postUpdate(h, "7") }()
+	<-started
+	if code := postUpdate(h, "7"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a retry to be requested while the update is handled, got %d", code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+}
+
+func TestWebhookDoesNotRememberFailedUpdate(t *testing.T) {
+	seen := cache.NewMemorySeen()
+	h := webhookHandler(seen, time.Hour, zerolog.Nop(), func(context.Context, tgbotapi.Update) { panic("boom") })
+
+	func() {
+		defer func() { _ = recover() }()
+		postUpdate(h, "7")
+	}()
+	if done, _ := seen.Seen(context.Background(), "tg:update:7"); done {
+		t.Fatal("an update whose handler failed must not be marked as handled")
+	}
+	if code := postUpdate(h, "7"); code == http.StatusOK {
+		t.Fatal("a retry of a failed update must not be acknowledged as a duplicate")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestAttemptLimitByCause(t *testing.T) {
+	w := &jobWorker{maxAttempts: 6, manualMaxAttempts: 2}
+	if got := w.attemptLimit(domain.DigestCauseScheduled); got != 6 {
+		t.Fatalf("scheduled: expected 6, got %d", got)
+	}
+	if got := w.attemptLimit(domain.DigestCauseManual); got != 2 {
+		t.Fatalf("manual: expected 2, got %d", got)
+	}
+	if !w.willRetryManual(domain.DigestJob{Cause: domain.DigestCauseManual}, 1) {
+		t.Fatalf("manual job should be retried after the first attempt")
+	}
+
+	w = &jobWorker{manualMaxAttempts: 1}
+	if got := w.attemptLimit(domain.DigestCauseScheduled); got != maxDeliveryAttempts {
+		t.Fatalf("unset limit: expected default %d, got %d", maxDeliveryAttempts, got)
+	}
+	if w.willRetryManual(domain.DigestJob{Cause: domain.DigestCauseManual}, 1) {
+		t.Fatalf("single-attempt manual job must not promise a retry")
+	}
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 
+	"tg-digest-bot/internal/adapters/email"
 	"tg-digest-bot/internal/adapters/mtproto"
 	"tg-digest-bot/internal/adapters/ranker"
 	"tg-digest-bot/internal/adapters/repo"
@@ -26,19 +29,24 @@ import (
 	"tg-digest-bot/internal/infra/openai"
 	"tg-digest-bot/internal/infra/queue"
 	digestusecase "tg-digest-bot/internal/usecase/digest"
+	"tg-digest-bot/internal/usecase/schedule"
 )
 
 func main() {
 	cfg := config.Load()
-	logger := applog.NewLogger(cfg.AppEnv)
+	logger, err := applog.NewLogger(cfg.AppEnv, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректные настройки логов")
+	}
+	if err := domain.SetDefaultTimezone(cfg.DefaultTimezone); err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректный DEFAULT_TIMEZONE")
+	}
 
 	metrics.MustRegister(prometheus.DefaultRegisterer)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	metrics.StartServer(ctx, logger.With().Str("component", "metrics").Logger(), ":9090")
-
 	pool, err := db.Connect(cfg.PGDSN)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("collector: нет подключения к БД")
@@ -75,43 +83,126 @@ func main() {
 	if len(accountsMeta) == 0 {
 		logger.Fatal().Msg("collector: пул MTProto-аккаунтов пуст")
 	}
-	collectorAccounts := make([]mtproto.Account, 0, len(accountsMeta))
-	for _, meta := range accountsMeta {
-		collectorAccounts = append(collectorAccounts, mtproto.Account{
-			Name:    meta.Name,
-			APIID:   meta.APIID,
-			APIHash: meta.APIHash,
-			Storage: mtproto.NewSessionDB(repoAdapter, meta.Name),
-		})
+	collectorAccounts := enabledAccounts(repoAdapter, accountsMeta)
+	if len(collectorAccounts) == 0 {
+		logger.Fatal().Msg("collector: в пуле MTProto нет включённых аккаунтов")
 	}
-	collector, err := mtproto.NewCollector(collectorAccounts, logger)
+	collector, err := mtproto.NewCollector(collectorAccounts, logger, cfg.Collect.ChannelTimeout)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("collector: не удалось создать MTProto клиента")
 	}
+	collector.SetCollectOverlap(cfg.MTProto.CollectOverlap)
+	collector.SetMaxMessages(cfg.Collect.MaxMessages)
+	loadExtraPools(ctx, repoAdapter, collector, cfg.MTProto.SessionName, logger)
 
 	if cfg.OpenAI.APIKey == "" {
 		logger.Fatal().Msg("collector: не указан ключ OpenAI (OPENAI_API_KEY)")
 	}
 	openaiClient := openai.NewClient(cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL, cfg.OpenAI.Timeout)
 
-	summarizerAdapter := summarizer.NewOpenAI(openaiClient, cfg.OpenAI.Model, cfg.OpenAI.Timeout)
-	rankerAdapter := ranker.NewLLM(openaiClient, cfg.OpenAI.Model, cfg.OpenAI.Timeout, cfg.Limits.DigestMax)
-	digestService := digestusecase.NewService(repoAdapter, repoAdapter, repoAdapter, repoAdapter, summarizerAdapter, rankerAdapter, collector, cfg.Limits.DigestMax)
+	summarizerAdapter := summarizer.NewOpenAI(openaiClient, cfg.OpenAI.SummaryModelName(), cfg.OpenAI.SummaryTemperature, cfg.OpenAI.Timeout)
+	rankerAdapter := ranker.NewLLM(openaiClient, cfg.OpenAI.RankModelName(), cfg.OpenAI.RankTemperature, cfg.OpenAI.Timeout, cfg.Limits.DigestMax)
+	logger.Info().Str("summary_model", cfg.OpenAI.SummaryModelName()).Str("rank_model", cfg.OpenAI.RankModelName()).Msg("collector: модели LLM")
+	digestService := digestusecase.NewService(repoAdapter, repoAdapter, repoAdapter, repoAdapter, repoAdapter, summarizerAdapter, rankerAdapter, collector, cfg.Limits.DigestMax)
 
-	worker := &jobWorker{
-		log:       logger,
-		queue:     digestQueue,
-		digests:   repoAdapter,
-		users:     repoAdapter,
-		channels:  repoAdapter,
-		statuses:  repoAdapter,
-		analytics: repoAdapter,
-		service:   digestService,
-		bot:       botAPI,
+	adPatterns := append([]string(nil), digestusecase.DefaultAdPatterns...)
+	adPatterns = append(adPatterns, strings.Split(cfg.AdFilter.Patterns, "\n")...)
+	var adClassifier domain.AdClassifier
+	if cfg.AdFilter.UseLLM {
+		adClassifier = summarizerAdapter
+	}
+	adFilter, err := digestusecase.NewAdFilter(adPatterns, adClassifier)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректные шаблоны фильтра рекламы (AD_FILTER_PATTERNS)")
+	}
+	digestService.SetAdFilter(adFilter)
+
+	tokenCaps, err := digestusecase.ParsePlanTokenCaps(cfg.OpenAI.MonthlyTokenCaps)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректные лимиты токенов (OPENAI_MONTHLY_TOKEN_CAP_BY_PLAN)")
+	}
+	digestService.SetSpendCap(&digestusecase.SpendCap{
+		Usage:      repoAdapter,
+		PlanTokens: tokenCaps,
+		Ranker:     ranker.NewSimple(24),
+		Summarizer: summarizer.NewSimple(),
+	})
+
+	planConcurrency := make(map[domain.UserRole]int, len(cfg.Collect.PlanConcurrency))
+	for name, n := range cfg.Collect.PlanConcurrency {
+		role, ok := domain.ParseUserRole(name)
+		if !ok {
+			logger.Fatal().Str("plan", name).Msg("collector: неизвестный тариф в COLLECT_CONCURRENCY_BY_PLAN")
+		}
+		planConcurrency[role] = n
+	}
+	digestService.SetCollectLimits(digestusecase.CollectLimits{
+		Concurrency:     cfg.Collect.Concurrency,
+		PlanConcurrency: planConcurrency,
+		JobTimeout:      cfg.Collect.JobTimeout,
+	})
+
+	layoutFooter := digestusecase.DefaultFooterTemplate
+	if cfg.DigestLayout.Footer != nil {
+		layoutFooter = *cfg.DigestLayout.Footer
+	}
+	layout, err := digestusecase.NewLayout(cfg.DigestLayout.Header, layoutFooter)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректный шаблон дайджеста (DIGEST_HEADER_TEMPLATE/DIGEST_FOOTER_TEMPLATE)")
+	}
+
+	empty, err := digestusecase.NewEmptyMessages(cfg.DigestEmpty.Scheduled, cfg.DigestEmpty.Manual, cfg.DigestEmpty.Channel, cfg.DigestEmpty.Tags)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("collector: некорректный шаблон сообщения о пустом дайджесте (DIGEST_EMPTY_*_TEMPLATE)")
 	}
 
+	progressPlans := make(map[domain.UserRole]struct{}, len(cfg.DigestProgress.Plans))
+	for _, name := range cfg.DigestProgress.Plans {
+		role, ok := domain.ParseUserRole(name)
+		if !ok {
+			logger.Fatal().Str("plan", name).Msg("collector: неизвестный тариф в DIGEST_PROGRESS_PLANS")
+		}
+		progressPlans[role] = struct{}{}
+	}
+
+	worker := &jobWorker{
+		log:                 logger,
+		queue:               digestQueue,
+		digests:             repoAdapter,
+		users:               repoAdapter,
+		channels:            repoAdapter,
+		statuses:            repoAdapter,
+		analytics:           repoAdapter,
+		deliveries:          repoAdapter,
+		service:             digestService,
+		layout:              layout,
+		empty:               empty,
+		bot:                 telegram.NewClient(botAPI),
+		progressPlans:       progressPlans,
+		progressMinChannels: cfg.DigestProgress.MinChannels,
+		maxAttempts:         cfg.Collect.MaxAttempts,
+		manualMaxAttempts:   cfg.Collect.ManualMaxAttempts,
+		llm:                 openaiClient,
+		usage:               repoAdapter,
+		llmPricing:          cfg.OpenAI,
+	}
+	if cfg.SMTP.Host != "" {
+		worker.mailer = email.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From, cfg.SMTP.Timeout)
+	}
+
+	// Метрики и /status живут до конца дренажа воркера, а не до сигнала остановки.
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	metrics.StartServer(metricsCtx, logger.With().Str("component", "metrics").Logger(), ":9090",
+		metrics.Route{Pattern: "/status", Handler: worker.statusHandler()})
+
 	logger.Info().Msg("collector: запуск обработки очереди")
-	worker.Run(ctx)
+	worker.Start(ctx)
+	<-ctx.Done()
+	if !worker.Wait(shutdownGracePeriod + 5*time.Second) {
+		logger.Warn().Msg("collector: текущая задача не завершилась за отведённое время")
+	}
+	stopMetrics()
 	logger.Info().Msg("collector: остановлен")
 }
 
@@ -123,11 +214,48 @@ type jobWorker struct {
 	channels  domain.ChannelRepo
 	statuses  domain.DigestJobStatusRepo
 	analytics domain.BusinessMetricRepo
-	service   *digestusecase.Service
-	bot       *tgbotapi.BotAPI
+	// deliveries — отметки отправленных дайджестов на случай повтора задачи после отправки.
+	deliveries domain.DigestDeliveryRepo
+	service    *digestusecase.Service
+	layout     *digestusecase.Layout
+	empty      *digestusecase.EmptyMessages
+	bot        telegram.Client
+	mailer     domain.EmailSender
+
+	// progressPlans — тарифы, которым долгую сборку предваряет заглушка «Собираем ваш дайджест…».
+	progressPlans       map[domain.UserRole]struct{}
+	progressMinChannels int
+
+	// maxAttempts и manualMaxAttempts — предел попыток плановой и ручной задачи; 0 — maxDeliveryAttempts.
+	maxAttempts       int
+	manualMaxAttempts int
+
+	// llm считает токены всех запросов процесса. Задачи обрабатываются по одной,
+	// поэтому разница снимков до и после сборки — расход конкретного пользователя.
+	llm        *openai.Client
+	usage      domain.UsageRepo
+	llmPricing config.OpenAIConfig
+
+	// draining закрывается при остановке: новые задачи больше не берутся.
+	draining chan struct{}
+	running  sync.WaitGroup
+
+	// inflight — задачи в обработке по идентификатору, для /status.
+	inflightMu sync.Mutex
+	inflight   map[string]inflightJob
 }
 
-const maxDeliveryAttempts = 5
+const (
+	// maxDeliveryAttempts — предел попыток задачи, если он не задан в конфигурации.
+	maxDeliveryAttempts = 5
+	// retryCollectionFreshness — сколько считаются свежими посты, собранные предыдущей попыткой задачи.
+	retryCollectionFreshness = 30 * time.Minute
+	// collectorUnavailableBackoff — шаг паузы перед повтором, пока предохранитель MTProto открыт.
+	collectorUnavailableBackoff = time.Minute
+	// shutdownGracePeriod — сколько текущая задача может дорабатывать после сигнала остановки.
+	shutdownGracePeriod   = 45 * time.Second
+	markDeliveredAttempts = 3
+)
 
 type jobOutcome int
 
@@ -136,8 +264,23 @@ const (
 	jobOutcomeRetry
 )
 
-func (w *jobWorker) Run(ctx context.Context) {
+// run читает задачи до отмены ctx. После отмены новые задачи не берутся, а текущая
+// дорабатывает с собственным контекстом, который обрывается через shutdownGracePeriod.
+func (w *jobWorker) run(ctx context.Context) {
+	defer w.running.Done()
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+	stopDrain := context.AfterFunc(ctx, func() {
+		close(w.draining)
+		w.log.Info().Dur("grace", shutdownGracePeriod).Msg("collector: остановка, новые задачи не принимаются")
+		time.AfterFunc(shutdownGracePeriod, cancelJobs)
+	})
+	defer stopDrain()
+
 	for {
+		if w.isDraining() {
+			return
+		}
 		job, ack, err := w.queue.Receive(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -184,33 +327,166 @@ func (w *jobWorker) Run(ctx context.Context) {
 			continue
 		}
 
-		outcome := w.handleJob(ctx, job, attempt, jobLog)
+		w.trackJob(job, attempt)
+		w.finishJob(jobCtx, job, ack, attempt, jobLog)
+		w.untrackJob(job.ID)
+	}
+}
+
+// Start запускает цикл обработки в отдельной горутине.
+func (w *jobWorker) Start(ctx context.Context) {
+	w.draining = make(chan struct{})
+	w.running.Add(1)
+	go w.run(ctx)
+}
 
-		if outcome == jobOutcomeRetry && attempt < maxDeliveryAttempts {
-			jobLog.Warn().Msg("collector: задача завершилась ошибкой, повторим позже")
-			if err := ack(false); err != nil {
-				jobLog.Error().Err(err).Msg("collector: не удалось вернуть задачу после ошибки")
-			}
-			continue
+// Wait ждёт выхода воркера вместе с текущей задачей, но не дольше timeout. Возвращает false, если не дождались.
+func (w *jobWorker) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (w *jobWorker) isDraining() bool {
+	select {
+	case <-w.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// finishJob обрабатывает задачу и подтверждает её. Задача помечается доставленной до ack:
+// если процесс упадёт между ними, повторная доставка будет подтверждена без повторной отправки.
+func (w *jobWorker) finishJob(ctx context.Context, job domain.DigestJob, ack domain.DigestAckFunc, attempt int, jobLog zerolog.Logger) {
+	outcome := w.handleJob(ctx, job, attempt, jobLog)
+
+	if outcome == jobOutcomeRetry && attempt < w.attemptLimit(job.Cause) {
+		jobLog.Warn().Msg("collector: задача завершилась ошибкой, повторим позже")
+		if err := ack(false); err != nil {
+			jobLog.Error().Err(err).Msg("collector: не удалось вернуть задачу после ошибки")
+		}
+		return
+	}
+
+	if outcome == jobOutcomeRetry {
+		jobLog.Error().Int("attempt", attempt).Msg("collector: достигнут предел попыток, помечаем задачу как завершённую")
+		if job.Cause == domain.DigestCauseManual {
+			// Пользователь ждёт ответа: без этого сообщения запрос пропал бы молча.
+			w.sendPlain(job.ChatID, "Не удалось подготовить дайджест, попробуйте позже.")
 		}
+	}
 
-		if outcome == jobOutcomeRetry {
-			jobLog.Error().Msg("collector: достигнут предел попыток, помечаем задачу как завершённую")
+	// Дайджест уже отправлен: возврат задачи в очередь привёл бы к дублю, поэтому
+	// сначала несколько раз пробуем записать статус и только потом сдаёмся.
+	var markErr error
+	for i := 0; i < markDeliveredAttempts; i++ {
+		if markErr = w.statuses.MarkDigestJobDelivered(job.ID); markErr == nil {
+			break
+		}
+		jobLog.Error().Err(markErr).Int("try", i+1).Msg("collector: не удалось пометить задачу доставленной")
+		time.Sleep(time.Second)
+	}
+	if markErr != nil {
+		if ackErr := ack(false); ackErr != nil {
+			jobLog.Error().Err(ackErr).Msg("collector: не удалось вернуть задачу после ошибки статуса")
 		}
+		return
+	}
 
-		if err := w.statuses.MarkDigestJobDelivered(job.ID); err != nil {
-			jobLog.Error().Err(err).Msg("collector: не удалось пометить задачу доставленной")
-			if ackErr := ack(false); ackErr != nil {
-				jobLog.Error().Err(ackErr).Msg("collector: не удалось вернуть задачу после ошибки статуса")
-			}
-			time.Sleep(time.Second)
-			continue
+	if err := ack(true); err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось подтвердить задачу")
+	}
+}
+
+// attemptLimit возвращает предел попыток задачи с причиной cause.
+func (w *jobWorker) attemptLimit(cause domain.DigestJobCause) int {
+	limit := w.maxAttempts
+	if cause == domain.DigestCauseManual && w.manualMaxAttempts > 0 {
+		limit = w.manualMaxAttempts
+	}
+	if limit < 1 {
+		return maxDeliveryAttempts
+	}
+	return limit
+}
+
+// willRetryManual сообщает, что ручную задачу после первой неудачной попытки ещё повторят:
+// пользователь узнаёт об этом сразу, а об окончательной ошибке — из finishJob.
+func (w *jobWorker) willRetryManual(job domain.DigestJob, attempt int) bool {
+	return job.Cause == domain.DigestCauseManual && attempt == 1 && attempt < w.attemptLimit(job.Cause)
+}
+
+// collect собирает посты каналов задачи. retry == true, если пул MTProto временно недоступен или пуст
+// и задачу нужно вернуть в очередь: перед этим выдерживается растущая пауза.
+func (w *jobWorker) collect(ctx context.Context, job domain.DigestJob, user domain.User, channels []domain.Channel, attempt int, jobLog zerolog.Logger) (retry bool, result digestusecase.CollectResult, err error) {
+	if attempt > 1 {
+		// Посты, собранные предыдущей попыткой, уже лежат в БД — повторно ходим только за устаревшими каналами.
+		result, err = w.service.CollectStale(ctx, user, channels, time.Now().UTC().Add(-retryCollectionFreshness))
+	} else {
+		result, err = w.service.CollectNow(ctx, user, channels)
+	}
+	for _, f := range result.Failed {
+		jobLog.Warn().Err(f.Err).Int64("channel", f.Channel.ID).Str("alias", f.Channel.Alias).Msg("collector: канал не собран")
+	}
+	poolDown := errors.Is(err, domain.ErrCollectorUnavailable) || errors.Is(err, domain.ErrNoAvailableAccounts)
+	if poolDown && attempt < w.attemptLimit(job.Cause) {
+		// Пул MTProto временно отключён предохранителем или в нём не осталось рабочих аккаунтов:
+		// виноват не канал, а пул. Немедленный повтор сжёг бы все попытки, поэтому ждём
+		// с растущей паузой и возвращаем задачу в очередь.
+		backoff := time.Duration(attempt) * collectorUnavailableBackoff
+		jobLog.Warn().Err(err).Dur("backoff", backoff).Msg("collector: сбор временно недоступен, повторим задачу позже")
+		select {
+		case <-ctx.Done():
+		case <-w.draining:
+		case <-time.After(backoff):
 		}
+		return true, result, err
+	}
+	return false, result, err
+}
 
-		if err := ack(true); err != nil {
-			jobLog.Error().Err(err).Msg("collector: не удалось подтвердить задачу")
+// collectOnly прогревает кеш постов пользователя перед рассылкой: собирает его включённые
+// каналы и завершает задачу без сборки и отправки дайджеста. Пользователю ничего не пишет.
+func (w *jobWorker) collectOnly(ctx context.Context, job domain.DigestJob, attempt int, jobLog zerolog.Logger) jobOutcome {
+	user, err := w.users.GetByTGID(job.UserTGID)
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: пользователь для прогрева не найден")
+		return jobOutcomeCompleted
+	}
+	userChannels, err := w.channels.ListUserChannels(user.ID, 100, 0)
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось получить каналы для прогрева")
+		return jobOutcomeRetry
+	}
+	channels := make([]domain.Channel, 0, len(userChannels))
+	for _, uc := range userChannels {
+		if !uc.Muted {
+			channels = append(channels, uc.Channel)
 		}
 	}
+	if len(channels) == 0 {
+		return jobOutcomeCompleted
+	}
+	start := time.Now()
+	retry, result, collectErr := w.collect(ctx, job, user, channels, attempt, jobLog)
+	if retry {
+		return jobOutcomeRetry
+	}
+	if collectErr != nil {
+		jobLog.Error().Err(collectErr).Msg("collector: ошибка прогрева кеша постов")
+		return jobOutcomeCompleted
+	}
+	jobLog.Info().Int("channels", result.Collected).Int("failed", len(result.Failed)).Dur("took", time.Since(start)).Msg("collector: кеш постов прогрет")
+	return jobOutcomeCompleted
 }
 
 func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt int, jobLog zerolog.Logger) jobOutcome {
@@ -220,12 +496,39 @@ func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt
 	if job.Date.IsZero() {
 		job.Date = time.Now().UTC()
 	}
+	if job.Cause == domain.DigestCauseCollectOnly {
+		return w.collectOnly(ctx, job, attempt, jobLog)
+	}
+	if job.Cause == domain.DigestCausePeek {
+		return w.peek(ctx, job, jobLog)
+	}
 	user, err := w.users.GetByTGID(job.UserTGID)
 	if err != nil {
 		jobLog.Error().Err(err).Msg("collector: пользователь не найден")
 		w.sendPlain(job.ChatID, "Не удалось найти ваш профиль. Отправьте /start в боте и попробуйте снова.")
 		return jobOutcomeCompleted
 	}
+	if job.Cause == domain.DigestCauseScheduled {
+		// Привязку чата могли изменить после постановки задачи — плановый дайджест идёт в текущий чат.
+		job.ChatID = user.DigestChatID()
+	}
+	byEmail := w.mailer != nil && user.DeliverByEmail()
+	// Ручной запрос означает, что пользователь снова пишет боту, поэтому пробуем доставить его как обычно.
+	// Отключённая доставка касается лички и не мешает писать в привязанный чат.
+	telegramDisabled := user.DeliveryDisabled && job.Cause != domain.DigestCauseManual && job.ChatID == user.TGUserID
+	if telegramDisabled && !byEmail {
+		// Сообщение всё равно не дойдёт — не тратим MTProto и OpenAI на сбор.
+		jobLog.Info().Msg("collector: доставка пользователю отключена, пропускаем задачу")
+		return jobOutcomeCompleted
+	}
+	if job.Cause != domain.DigestCauseManual && user.IsPaused(time.Now()) {
+		// Задача могла попасть в очередь до /pause — не присылаем дайджест, пока пауза действует.
+		jobLog.Info().Msg("collector: дайджесты пользователя на паузе, пропускаем задачу")
+		return jobOutcomeCompleted
+	}
+	if job.DigestID > 0 {
+		return w.resendSnoozedDigest(ctx, job, user, jobLog)
+	}
 	userChannels, err := w.channels.ListUserChannels(user.ID, 100, 0)
 	if err != nil {
 		jobLog.Error().Err(err).Msg("collector: не удалось получить каналы")
@@ -252,7 +555,7 @@ func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt
 	case len(job.Tags) > 0:
 		matched := make([]domain.Channel, 0)
 		for _, uc := range userChannels {
-			if matchesAnyTag(uc.Tags, job.Tags) {
+			if !uc.Muted && matchesAnyTag(uc.Tags, job.Tags) {
 				matched = append(matched, uc.Channel)
 			}
 		}
@@ -264,11 +567,24 @@ func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt
 	default:
 		channels = make([]domain.Channel, 0, len(userChannels))
 		for _, uc := range userChannels {
+			if uc.Muted {
+				continue
+			}
 			channels = append(channels, uc.Channel)
 		}
 	}
-	if err := w.service.CollectNow(ctx, channels); err != nil {
-		jobLog.Error().Err(err).Msg("collector: ошибка сбора постов")
+	toTelegram := (!byEmail || user.DeliverByTelegram()) && !telegramDisabled
+	var progress *digestProgress
+	if toTelegram && w.wantsProgress(user, len(channels)) {
+		progress = w.startProgress(job.ChatID)
+		defer w.finishProgress(progress)
+	}
+	retry, collected, collectErr := w.collect(ctx, job, user, channels, attempt, jobLog)
+	if retry {
+		return jobOutcomeRetry
+	}
+	if collectErr != nil {
+		jobLog.Error().Err(collectErr).Msg("collector: ошибка сбора постов")
 		w.sendPlain(job.ChatID, "Не удалось собрать дайджест, попробуйте позже.")
 		return jobOutcomeCompleted
 	}
@@ -276,14 +592,23 @@ func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt
 		digest domain.Digest
 		//err    error
 	)
+	usageBefore := w.llmUsage()
 	switch {
 	case job.ChannelID > 0:
 		digest, err = w.service.BuildChannelForDate(job.UserTGID, job.ChannelID, job.Date)
 	case len(job.Tags) > 0:
 		digest, err = w.service.BuildTagsForDate(job.UserTGID, job.Tags, job.Date)
+	case job.PastDay:
+		loc, locErr := time.LoadLocation(user.TimezoneName())
+		if locErr != nil {
+			loc = time.UTC
+		}
+		since, until := job.PastDayWindow(loc)
+		digest, err = w.service.BuildForWindow(job.UserTGID, job.Date, since, until)
 	default:
 		digest, err = w.service.BuildForDate(job.UserTGID, job.Date)
 	}
+	w.recordUsage(ctx, user, usageBefore, jobLog)
 	if err != nil {
 		if errors.Is(err, digestusecase.ErrChannelNotFound) {
 			w.sendPlain(job.ChatID, "Канал недоступен для дайджеста")
@@ -294,32 +619,91 @@ func (w *jobWorker) handleJob(ctx context.Context, job domain.DigestJob, attempt
 			return jobOutcomeCompleted
 		}
 		jobLog.Error().Err(err).Msg("collector: ошибка построения дайджеста")
-		w.sendPlain(job.ChatID, "Не удалось построить дайджест, попробуйте позже.")
-		return jobOutcomeCompleted
+		if attempt >= w.attemptLimit(job.Cause) {
+			w.sendPlain(job.ChatID, "Не удалось построить дайджест, попробуйте позже.")
+			return jobOutcomeCompleted
+		}
+		return jobOutcomeRetry
 	}
+	failedNotice := collectFailureNotice(collected)
 	if len(digest.Items) == 0 {
-		switch {
-		case job.ChannelID > 0:
-			w.sendPlain(job.ChatID, "В выбранном канале за последние 24 часа ничего не найдено")
-		case len(job.Tags) > 0:
-			w.sendPlain(job.ChatID, "Не удалось найти новые посты по выбранным тегам")
-		default:
-			w.sendPlain(job.ChatID, "За последние 24 часа ничего не найдено")
+		text := w.empty.Message(job, emptyDigestData(job, user, userChannels, time.Now()))
+		if job.PastDay {
+			text = fmt.Sprintf("За %s в ваших каналах ничего не найдено.", job.Date.Format("02.01.2006"))
+		}
+		if failedNotice != "" {
+			text += "\n\n" + failedNotice
 		}
+		w.sendPlain(job.ChatID, text)
 		return jobOutcomeCompleted
 	}
-	if job.ChannelID == 0 && len(job.Tags) == 0 {
-		if err := w.persistDigest(digest); err != nil {
-			jobLog.Error().Err(err).Msg("collector: не удалось сохранить дайджест")
-		}
+	keyboard := telegram.ExpandKeyboard(digest.Items)
+	digest.Scope = job.DigestScope()
+	saved, err := w.persistDigest(digest)
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось сохранить дайджест")
+	} else if job.Cause == domain.DigestCauseScheduled {
+		keyboard = telegram.WithSnoozeButton(keyboard, saved.ID)
+	}
+	// Статус задачи мог не записаться после успешной отправки, поэтому каждую отправку
+	// дополнительно сверяем с отметкой, не зависящей от job_id.
+	emailKey, telegramKey := job.DeliveryKey("email"), job.DeliveryKey("telegram")
+	sentEmail := byEmail && w.alreadySent(ctx, emailKey, jobLog)
+	sentTelegram := toTelegram && w.alreadySent(ctx, telegramKey, jobLog)
+	if (!byEmail || sentEmail) && (!toTelegram || sentTelegram) {
+		jobLog.Info().Msg("collector: дайджест уже отправлен предыдущей попыткой, не повторяем")
+		return jobOutcomeCompleted
 	}
-	message := digestusecase.FormatDigest(digest)
-	if err := w.sendDigest(job.ChatID, message); err != nil {
-		if job.Cause == domain.DigestCauseManual && attempt == 1 {
-			w.sendPlain(job.ChatID, "Не удалось собрать дайджест, попробуйте позже.")
+	if byEmail && !sentEmail {
+		subject, body := w.layout.FormatEmail(digest, user.Plan().Name)
+		if err := w.mailer.Send(ctx, user.Email, subject, body); err != nil {
+			if w.willRetryManual(job, attempt) {
+				w.sendPlain(job.ChatID, "Не удалось отправить дайджест на почту, попробуем ещё раз.")
+			}
+			jobLog.Error().Err(err).Msg("collector: отправка дайджеста на почту")
+			return jobOutcomeRetry
 		}
-		jobLog.Error().Err(err).Msg("collector: отправка дайджеста")
-		return jobOutcomeRetry
+		w.markSent(ctx, user.ID, emailKey, jobLog)
+	}
+	if toTelegram && !sentTelegram {
+		lead, message := w.formatDigest(digest, user)
+		// Ручной дайджест пользователь ждёт прямо сейчас — объясняем нехватку каналов в нём самом,
+		// плановый не трогаем и сообщаем отдельно после отправки.
+		if failedNotice != "" && job.Cause != domain.DigestCauseScheduled {
+			message += "\n\n" + failedNotice
+		}
+		if err := w.sendDigest(job.ChatID, lead, message, keyboard, progress); err != nil {
+			if telegram.IsChatUnreachable(err) && deliversToBoundChat(job, user) {
+				w.unbindDeliveryChat(user, err, jobLog)
+				return jobOutcomeRetry
+			}
+			if telegram.IsChatUnreachable(err) {
+				w.disableDelivery(ctx, job, user, err, jobLog)
+				if byEmail {
+					w.observeDigestDelivery(ctx, job, user, digest, attempt)
+				}
+				return jobOutcomeCompleted
+			}
+			if w.willRetryManual(job, attempt) {
+				w.sendPlain(job.ChatID, "Не удалось отправить дайджест, попробуем ещё раз.")
+			}
+			jobLog.Error().Err(err).Msg("collector: отправка дайджеста")
+			return jobOutcomeRetry
+		}
+		w.markSent(ctx, user.ID, telegramKey, jobLog)
+		if failedNotice != "" && job.Cause == domain.DigestCauseScheduled {
+			w.sendPlain(job.ChatID, failedNotice)
+		}
+		if user.DeliveryDisabled {
+			if err := w.users.SetDeliveryDisabled(user.ID, false); err != nil {
+				jobLog.Error().Err(err).Msg("collector: не удалось вернуть доставку")
+			}
+		}
+	} else if !toTelegram && job.Cause == domain.DigestCauseManual {
+		w.sendPlain(job.ChatID, fmt.Sprintf("📧 Дайджест отправлен на %s", user.Email))
+	}
+	if digest.Simplified && toTelegram {
+		w.notifyUsageCap(ctx, job.ChatID, user, jobLog)
 	}
 	w.observeDigestDelivery(ctx, job, user, digest, attempt)
 	return jobOutcomeCompleted
@@ -358,12 +742,115 @@ func (w *jobWorker) observeDigestDelivery(ctx context.Context, job domain.Digest
 	}
 }
 
-func (w *jobWorker) persistDigest(d domain.Digest) error {
+// disableDelivery отключает доставку пользователю, заблокировавшему бота, чтобы планировщик
+// перестал ставить ему задачи, а повторы не съедали лимит попыток. Снимается при следующем /start.
+func (w *jobWorker) disableDelivery(ctx context.Context, job domain.DigestJob, user domain.User, cause error, jobLog zerolog.Logger) {
+	jobLog.Warn().Err(cause).Msg("collector: чат недоступен, отключаем доставку пользователю")
+	if err := w.users.SetDeliveryDisabled(user.ID, true); err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось отключить доставку")
+		return
+	}
+	if w.analytics == nil {
+		return
+	}
+	userID := user.ID
+	metric := domain.BusinessMetric{
+		Event:  domain.BusinessMetricEventDeliveryDisabled,
+		UserID: &userID,
+		Metadata: map[string]any{
+			"job_id":  job.ID,
+			"cause":   string(job.Cause),
+			"chat_id": job.ChatID,
+			"error":   cause.Error(),
+		},
+	}
+	if err := w.analytics.RecordBusinessMetric(ctx, metric); err != nil {
+		jobLog.Error().Err(err).Str("event", domain.BusinessMetricEventDeliveryDisabled).Msg("collector: не удалось сохранить бизнес-метрику")
+	}
+}
+
+// deliversToBoundChat сообщает, что задача отправляется в чат, привязанный через /setchat.
+func deliversToBoundChat(job domain.DigestJob, user domain.User) bool {
+	return user.DeliveryChatID != 0 && job.ChatID == user.DeliveryChatID
+}
+
+// unbindDeliveryChat отвязывает чат, в который бот больше не может писать, и сообщает об этом
+// в личку. Повтор задачи отправит дайджест уже туда.
+func (w *jobWorker) unbindDeliveryChat(user domain.User, cause error, jobLog zerolog.Logger) {
+	jobLog.Warn().Err(cause).Int64("chat", user.DeliveryChatID).Msg("collector: привязанный чат недоступен, возвращаем доставку в личку")
+	if err := w.users.SetDeliveryChat(user.ID, 0); err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось отвязать чат доставки")
+		return
+	}
+	w.sendPlain(user.TGUserID, "Бот больше не может публиковать дайджесты в привязанном чате, поэтому они снова будут приходить сюда. Привязать чат заново: /setchat")
+}
+
+func (w *jobWorker) persistDigest(d domain.Digest) (domain.Digest, error) {
 	saved, err := w.digests.CreateDigest(d)
 	if err != nil {
-		return err
+		return domain.Digest{}, err
+	}
+	return saved, w.digests.MarkDelivered(saved.ID)
+}
+
+// resendSnoozedDigest повторно отправляет сохранённый дайджест, отложенный кнопкой «Напомнить».
+func (w *jobWorker) resendSnoozedDigest(ctx context.Context, job domain.DigestJob, user domain.User, jobLog zerolog.Logger) jobOutcome {
+	stored, err := w.digests.GetDigestWithItems(job.DigestID, user.ID)
+	if errors.Is(err, domain.ErrDigestNotFound) {
+		jobLog.Warn().Int64("digest", job.DigestID).Msg("collector: отложенный дайджест не найден")
+		return jobOutcomeCompleted
+	}
+	if err != nil {
+		jobLog.Error().Err(err).Int64("digest", job.DigestID).Msg("collector: не удалось загрузить отложенный дайджест")
+		return jobOutcomeRetry
+	}
+	keyboard := telegram.ExpandKeyboard(stored.Items)
+	if stored.SnoozeCount < domain.MaxDigestSnoozes {
+		keyboard = telegram.WithSnoozeButton(keyboard, stored.ID)
+	}
+	key := job.DeliveryKey("telegram")
+	if w.alreadySent(ctx, key, jobLog) {
+		jobLog.Info().Int64("digest", job.DigestID).Msg("collector: отложенный дайджест уже отправлен, не повторяем")
+		return jobOutcomeCompleted
+	}
+	lead, message := w.formatDigest(stored, user)
+	if err := w.sendDigest(job.ChatID, lead, message, keyboard, nil); err != nil {
+		if telegram.IsChatUnreachable(err) && deliversToBoundChat(job, user) {
+			w.unbindDeliveryChat(user, err, jobLog)
+			return jobOutcomeCompleted
+		}
+		if telegram.IsChatUnreachable(err) {
+			w.disableDelivery(ctx, job, user, err, jobLog)
+			return jobOutcomeCompleted
+		}
+		jobLog.Error().Err(err).Int64("digest", job.DigestID).Msg("collector: отправка отложенного дайджеста")
+		return jobOutcomeRetry
+	}
+	w.markSent(ctx, user.ID, key, jobLog)
+	return jobOutcomeCompleted
+}
+
+// alreadySent сообщает, что дайджест с ключом key уже отправлен. Если отметку не удалось
+// прочитать, считаем, что не отправлен: дубль лучше потерянного дайджеста.
+func (w *jobWorker) alreadySent(ctx context.Context, key string, jobLog zerolog.Logger) bool {
+	if w.deliveries == nil {
+		return false
+	}
+	sent, err := w.deliveries.WasDigestSent(ctx, key)
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось проверить отметку отправки")
+		return false
+	}
+	return sent
+}
+
+func (w *jobWorker) markSent(ctx context.Context, userID int64, key string, jobLog zerolog.Logger) {
+	if w.deliveries == nil {
+		return
+	}
+	if err := w.deliveries.MarkDigestSent(ctx, userID, key); err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось записать отметку отправки")
 	}
-	return w.digests.MarkDelivered(saved.UserID, saved.Date)
 }
 
 func (w *jobWorker) sendPlain(chatID int64, text string) {
@@ -380,22 +867,97 @@ func (w *jobWorker) sendPlain(chatID int64, text string) {
 	}
 }
 
-func (w *jobWorker) sendDigest(chatID int64, text string) error {
-	parts := telegram.SplitMessage(text)
-	for _, part := range parts {
-		msg := tgbotapi.NewMessage(chatID, part)
-		msg.ParseMode = tgbotapi.ModeHTML
-		msg.DisableWebPagePreview = true
-		start := time.Now()
-		_, err := w.bot.Send(msg)
-		metrics.ObserveNetworkRequest("telegram_bot", "send_message", strconv.FormatInt(chatID, 10), start, err)
-		if err != nil {
+// formatDigest оформляет дайджест; lead непустой, если пользователь включил /link_previews.
+func (w *jobWorker) formatDigest(digest domain.Digest, user domain.User) (lead, body string) {
+	if user.LinkPreviews {
+		return w.layout.FormatWithLead(digest, user.Plan().Name)
+	}
+	return "", w.layout.Format(digest, user.Plan().Name)
+}
+
+// sendDigest отправляет дайджест. Главный пункт lead уходит первым сообщением с превью ссылки,
+// остальное — без превью. Заглушку progress заменяет lead, а без него — дайджест из одной части;
+// иначе дайджест приходит новыми сообщениями.
+func (w *jobWorker) sendDigest(chatID int64, lead, text string, keyboard *tgbotapi.InlineKeyboardMarkup, progress *digestProgress) error {
+	var parts []string
+	if strings.TrimSpace(text) != "" {
+		parts = telegram.SplitMessage(text)
+	}
+	if lead != "" {
+		var markup *tgbotapi.InlineKeyboardMarkup
+		if len(parts) == 0 {
+			markup = keyboard
+		}
+		if err := w.sendDigestPart(chatID, lead, markup, true, progress); err != nil {
+			return err
+		}
+		progress = nil
+	}
+	if len(parts) > 1 {
+		progress = nil
+	}
+	for i, part := range parts {
+		var markup *tgbotapi.InlineKeyboardMarkup
+		if i == len(parts)-1 {
+			markup = keyboard
+		}
+		if err := w.sendDigestPart(chatID, part, markup, false, progress); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// sendDigestPart подставляет текст в заглушку progress, а если её нет или замена не удалась —
+// отправляет новое сообщение.
+func (w *jobWorker) sendDigestPart(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup, preview bool, progress *digestProgress) error {
+	if progress != nil {
+		err := w.replaceProgress(progress, text, keyboard, preview)
+		if err == nil {
+			return nil
+		}
+		w.log.Warn().Err(err).Int64("chat", chatID).Msg("collector: не удалось заменить заглушку, отправляем дайджест отдельно")
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = !preview
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	start := time.Now()
+	_, err := w.bot.Send(msg)
+	metrics.ObserveNetworkRequest("telegram_bot", "send_message", strconv.FormatInt(chatID, 10), start, err)
+	return err
+}
+
+// collectFailureNotice перечисляет каналы, которые не удалось собрать; пустая строка — все собраны.
+func collectFailureNotice(result digestusecase.CollectResult) string {
+	if len(result.Failed) == 0 {
+		return ""
+	}
+	return "⚠️ Не удалось собрать: " + strings.Join(result.FailedAliases(), ", ") + ". Посты этих каналов в дайджест не попали."
+}
+
+// emptyDigestData собирает подсказки для сообщения о пустом дайджесте: время следующей
+// доставки по расписанию и состояние канала для дайджеста по одному каналу.
+func emptyDigestData(job domain.DigestJob, user domain.User, userChannels []domain.UserChannel, now time.Time) digestusecase.EmptyData {
+	var data digestusecase.EmptyData
+	if job.Cause == domain.DigestCauseScheduled {
+		// Задача пришла в окно текущей доставки, поэтому ближайший запуск после неё — следующий.
+		if next, err := schedule.NextRun(now.Add(schedule.Window), user); err == nil {
+			data.NextRun = next
+		}
+	}
+	for _, uc := range userChannels {
+		if job.ChannelID > 0 && uc.ChannelID == job.ChannelID {
+			data.Channel = "@" + uc.Channel.Alias
+			data.Muted = uc.Muted
+			break
+		}
+	}
+	return data
+}
+
 func matchesAnyTag(channelTags, requested []string) bool {
 	if len(channelTags) == 0 || len(requested) == 0 {
 		return false
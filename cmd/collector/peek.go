@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// peek отправляет пробный дайджест канала job.Alias. Канал не добавляется, посты не сохраняются;
+// при ошибке задача не повторяется — пользователь может запросить пример снова.
+func (w *jobWorker) peek(ctx context.Context, job domain.DigestJob, jobLog zerolog.Logger) jobOutcome {
+	user, err := w.users.GetByTGID(job.UserTGID)
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: пользователь для примера канала не найден")
+		return jobOutcomeCompleted
+	}
+	usageBefore := w.llmUsage()
+	digest, err := w.service.PreviewChannel(user, domain.Channel{Alias: job.Alias})
+	w.recordUsage(ctx, user, usageBefore, jobLog)
+	if err != nil {
+		jobLog.Warn().Err(err).Str("alias", job.Alias).Msg("collector: не удалось собрать пример канала")
+		w.sendPlain(job.ChatID, fmt.Sprintf("Не удалось собрать пример дайджеста @%s. Проверьте канал командой /check @%s.", job.Alias, job.Alias))
+		return jobOutcomeCompleted
+	}
+	if len(digest.Items) == 0 {
+		w.sendPlain(job.ChatID, fmt.Sprintf("За последние 24 часа в @%s не было постов — пример показать не из чего.", job.Alias))
+		return jobOutcomeCompleted
+	}
+	alias := html.EscapeString(job.Alias)
+	text := fmt.Sprintf("🔎 Так @%s будет выглядеть в дайджесте:\n\n%s\n\nДобавить канал: /add @%s", alias, w.layout.Format(digest, user.Plan().Name), alias)
+	if err := w.sendDigest(job.ChatID, "", text, nil, nil); err != nil {
+		jobLog.Error().Err(err).Msg("collector: отправка примера канала")
+	}
+	return jobOutcomeCompleted
+}
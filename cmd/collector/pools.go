@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/adapters/mtproto"
+	"tg-digest-bot/internal/adapters/repo"
+	"tg-digest-bot/internal/domain"
+)
+
+// enabledAccounts превращает включённые аккаунты пула в клиентов MTProto с сессией в БД.
+func enabledAccounts(repoAdapter *repo.Postgres, metas []domain.MTProtoAccount) []mtproto.Account {
+	accounts := make([]mtproto.Account, 0, len(metas))
+	for _, meta := range metas {
+		if !meta.Enabled {
+			continue
+		}
+		accounts = append(accounts, mtproto.Account{
+			Name:    meta.Name,
+			APIID:   meta.APIID,
+			APIHash: meta.APIHash,
+			Storage: mtproto.NewSessionDB(repoAdapter, meta.Name),
+		})
+	}
+	return accounts
+}
+
+// loadExtraPools подключает к сборщику все пулы, кроме основного. Пул без включённых аккаунтов
+// пропускается с предупреждением — его каналы собираются через основной пул.
+func loadExtraPools(ctx context.Context, repoAdapter *repo.Postgres, collector *mtproto.Collector, defaultPool string, log zerolog.Logger) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pools, err := repoAdapter.ListMTProtoPools(listCtx)
+	if err != nil {
+		log.Warn().Err(err).Msg("collector: не удалось загрузить список пулов MTProto, используем только основной")
+		return
+	}
+	for _, pool := range pools {
+		if pool.Name == defaultPool {
+			continue
+		}
+		metas, err := repoAdapter.ListMTProtoAccounts(listCtx, pool.Name)
+		if err != nil {
+			log.Warn().Err(err).Str("pool", pool.Name).Msg("collector: не удалось загрузить аккаунты пула")
+			continue
+		}
+		accounts := enabledAccounts(repoAdapter, metas)
+		if len(accounts) == 0 {
+			log.Warn().Str("pool", pool.Name).Int("channels", pool.Channels).Msg("collector: в пуле нет включённых аккаунтов, каналы пойдут через основной пул")
+			continue
+		}
+		if err := collector.AddPool(pool.Name, accounts); err != nil {
+			log.Warn().Err(err).Str("pool", pool.Name).Msg("collector: пул MTProto пропущен")
+			continue
+		}
+		log.Info().Str("pool", pool.Name).Int("accounts", len(accounts)).Msg("collector: подключён дополнительный пул MTProto")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+const progressText = "⏳ Собираем ваш дайджест…"
+
+// digestProgress — сообщение-заглушка, которое заменяется готовым дайджестом.
+type digestProgress struct {
+	chatID    int64
+	messageID int
+	// consumed — заглушка уже заменена дайджестом и удалять её не нужно.
+	consumed bool
+}
+
+// wantsProgress решает, показывать ли заглушку: только тарифам из DIGEST_PROGRESS_PLANS
+// и только для сборок хотя бы из progressMinChannels каналов, чтобы быстрые дайджесты не давали лишних сообщений.
+func (w *jobWorker) wantsProgress(user domain.User, channels int) bool {
+	if _, ok := w.progressPlans[user.Plan().Role]; !ok {
+		return false
+	}
+	return channels >= w.progressMinChannels
+}
+
+// startProgress отправляет заглушку. При ошибке возвращает nil: дайджест просто придёт отдельным сообщением.
+func (w *jobWorker) startProgress(chatID int64) *digestProgress {
+	start := time.Now()
+	sent, err := w.bot.Send(tgbotapi.NewMessage(chatID, progressText))
+	metrics.ObserveNetworkRequest("telegram_bot", "send_message", strconv.FormatInt(chatID, 10), start, err)
+	if err != nil {
+		w.log.Warn().Err(err).Int64("chat", chatID).Msg("collector: не удалось отправить заглушку дайджеста")
+		return nil
+	}
+	return &digestProgress{chatID: chatID, messageID: sent.MessageID}
+}
+
+// replaceProgress подставляет текст дайджеста в заглушку.
+func (w *jobWorker) replaceProgress(p *digestProgress, text string, keyboard *tgbotapi.InlineKeyboardMarkup, preview bool) error {
+	edit := tgbotapi.NewEditMessageText(p.chatID, p.messageID, text)
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = !preview
+	edit.ReplyMarkup = keyboard
+	start := time.Now()
+	_, err := w.bot.Send(edit)
+	metrics.ObserveNetworkRequest("telegram_bot", "edit_message_text", strconv.FormatInt(p.chatID, 10), start, err)
+	if err == nil {
+		p.consumed = true
+	}
+	return err
+}
+
+// finishProgress удаляет заглушку, если дайджест пришёл отдельными сообщениями или задача
+// завершилась без дайджеста. Повторная попытка задачи отправит новую заглушку.
+func (w *jobWorker) finishProgress(p *digestProgress) {
+	if p == nil || p.consumed {
+		return
+	}
+	start := time.Now()
+	_, err := w.bot.Request(tgbotapi.NewDeleteMessage(p.chatID, p.messageID))
+	metrics.ObserveNetworkRequest("telegram_bot", "delete_message", strconv.FormatInt(p.chatID, 10), start, err)
+	if err != nil {
+		w.log.Warn().Err(err).Int64("chat", p.chatID).Msg("collector: не удалось удалить заглушку дайджеста")
+	}
+}
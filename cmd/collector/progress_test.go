@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestWantsProgress(t *testing.T) {
+	w := &jobWorker{
+		progressPlans:       map[domain.UserRole]struct{}{domain.UserRolePro: {}},
+		progressMinChannels: 3,
+	}
+	cases := []struct {
+		role     domain.UserRole
+		channels int
+		want     bool
+	}{
+		{domain.UserRolePro, 3, true},
+		{domain.UserRolePro, 2, false},
+		{domain.UserRoleFree, 10, false},
+		{domain.UserRole("PRO"), 5, true},
+	}
+	for _, tc := range cases {
+		if got := w.wantsProgress(domain.User{Role: tc.role}, tc.channels); got != tc.want {
+			t.Fatalf("role %q, %d channels: expected %v, got %v", tc.role, tc.channels, tc.want, got)
+		}
+	}
+
+	if (&jobWorker{}).wantsProgress(domain.User{Role: domain.UserRolePro}, 10) {
+		t.Fatalf("placeholder must be disabled when no plans are configured")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// inflightJob — задача, которую воркер обрабатывает прямо сейчас.
+type inflightJob struct {
+	job     domain.DigestJob
+	attempt int
+	started time.Time
+}
+
+// statusJob — задача в ответе /status.
+type statusJob struct {
+	ID             string    `json:"id"`
+	UserTGID       int64     `json:"user_tg_id"`
+	Cause          string    `json:"cause"`
+	ChannelID      int64     `json:"channel_id,omitempty"`
+	Attempt        int       `json:"attempt"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// statusQueue — глубина очереди задач.
+type statusQueue struct {
+	Ready   int    `json:"ready"`
+	Delayed int    `json:"delayed"`
+	Error   string `json:"error,omitempty"`
+}
+
+type collectorStatus struct {
+	Draining bool         `json:"draining"`
+	Jobs     []statusJob  `json:"jobs"`
+	Queue    *statusQueue `json:"queue,omitempty"`
+}
+
+func (w *jobWorker) trackJob(job domain.DigestJob, attempt int) {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+	if w.inflight == nil {
+		w.inflight = make(map[string]inflightJob)
+	}
+	w.inflight[job.ID] = inflightJob{job: job, attempt: attempt, started: time.Now()}
+}
+
+func (w *jobWorker) untrackJob(id string) {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+	delete(w.inflight, id)
+}
+
+// inflightJobs возвращает задачи в обработке, начиная с самой давней.
+func (w *jobWorker) inflightJobs(now time.Time) []statusJob {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+	jobs := make([]statusJob, 0, len(w.inflight))
+	for _, j := range w.inflight {
+		jobs = append(jobs, statusJob{
+			ID:             j.job.ID,
+			UserTGID:       j.job.UserTGID,
+			Cause:          string(j.job.Cause),
+			ChannelID:      j.job.ChannelID,
+			Attempt:        j.attempt,
+			StartedAt:      j.started.UTC(),
+			ElapsedSeconds: now.Sub(j.started).Seconds(),
+		})
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].StartedAt.Before(jobs[k].StartedAt) })
+	return jobs
+}
+
+// statusHandler отдаёт JSON с задачами в обработке и глубиной очереди. Только чтение:
+// эндпоинт висит на порту метрик и не должен быть доступен снаружи.
+func (w *jobWorker) statusHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.Header().Set("Allow", http.MethodGet)
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status := collectorStatus{
+			Draining: w.draining != nil && w.isDraining(),
+			Jobs:     w.inflightJobs(time.Now()),
+		}
+		if stats, ok := w.queue.(domain.DigestQueueStats); ok {
+			ready, delayed, err := stats.Depth(r.Context())
+			status.Queue = &statusQueue{Ready: ready, Delayed: delayed}
+			if err != nil {
+				w.log.Warn().Err(err).Msg("collector: не удалось получить глубину очереди")
+				status.Queue.Error = err.Error()
+			}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(rw).Encode(status); err != nil {
+			w.log.Error().Err(err).Msg("collector: не удалось отдать статус")
+		}
+	})
+}
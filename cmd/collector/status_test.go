@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+)
+
+type statsQueue struct {
+	domain.DigestQueue
+	ready, delayed int
+}
+
+func (q statsQueue) Depth(context.Context) (int, int, error) { return q.ready, q.delayed, nil }
+
+func TestStatusHandlerReportsInflightJobsAndQueueDepth(t *testing.T) {
+	w := &jobWorker{log: zerolog.Nop(), queue: statsQueue{ready: 7, delayed: 2}}
+	w.trackJob(domain.DigestJob{ID: "job-1", UserTGID: 42, Cause: domain.DigestCauseScheduled}, 2)
+
+	rec := httptest.NewRecorder()
+	w.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var status collectorStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if len(status.Jobs) != 1 || status.Jobs[0].ID != "job-1" || status.Jobs[0].UserTGID != 42 || status.Jobs[0].Attempt != 2 {
+		t.Fatalf("unexpected jobs: %+v", status.Jobs)
+	}
+	if status.Queue == nil || status.Queue.Ready != 7 || status.Queue.Delayed != 2 {
+		t.Fatalf("unexpected queue depth: %+v", status.Queue)
+	}
+
+	w.untrackJob("job-1")
+	rec = httptest.NewRecorder()
+	w.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || len(status.Jobs) != 0 {
+		t.Fatalf("expected no jobs after untrack, got %+v (%v)", status.Jobs, err)
+	}
+
+	rec = httptest.NewRecorder()
+	w.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/openai"
+)
+
+const usageCapNotice = "ℹ️ Лимит расширенных сводок на этот месяц исчерпан, поэтому дайджест собран в упрощённом виде. " +
+	"Подробные сводки вернутся 1-го числа или сразу после перехода на старший тариф: /buy plus или /buy pro."
+
+func (w *jobWorker) llmUsage() openai.Usage {
+	if w.llm == nil {
+		return openai.Usage{}
+	}
+	return w.llm.Usage()
+}
+
+// recordUsage записывает пользователю токены, потраченные с момента снимка before.
+func (w *jobWorker) recordUsage(ctx context.Context, user domain.User, before openai.Usage, jobLog zerolog.Logger) {
+	if w.llm == nil || w.usage == nil {
+		return
+	}
+	after := w.llmUsage()
+	spent := domain.LLMUsage{
+		PromptTokens:     after.PromptTokens - before.PromptTokens,
+		CompletionTokens: after.CompletionTokens - before.CompletionTokens,
+	}
+	if spent.TotalTokens() <= 0 {
+		return
+	}
+	spent.CostUSD = w.llmPricing.Cost(spent.PromptTokens, spent.CompletionTokens)
+	if err := w.usage.AddUsage(ctx, user.ID, time.Now(), spent); err != nil {
+		jobLog.Error().Err(err).Int64("tokens", spent.TotalTokens()).Msg("collector: не удалось сохранить расход LLM")
+	}
+}
+
+// notifyUsageCap один раз в месяц объясняет, почему дайджест стал упрощённым.
+func (w *jobWorker) notifyUsageCap(ctx context.Context, chatID int64, user domain.User, jobLog zerolog.Logger) {
+	if w.usage == nil {
+		return
+	}
+	first, err := w.usage.MarkUsageCapNotified(ctx, user.ID, time.Now())
+	if err != nil {
+		jobLog.Error().Err(err).Msg("collector: не удалось отметить предупреждение о лимите LLM")
+		return
+	}
+	if first {
+		w.sendPlain(chatID, usageCapNotice)
+	}
+}
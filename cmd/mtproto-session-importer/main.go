@@ -18,6 +18,7 @@ import (
 	"tg-digest-bot/internal/domain"
 	"tg-digest-bot/internal/infra/config"
 	"tg-digest-bot/internal/infra/db"
+	applog "tg-digest-bot/internal/infra/log"
 )
 
 type sessionBundle struct {
@@ -126,6 +127,9 @@ func main() {
 	}
 
 	cfg := config.Load()
+	if _, err := applog.NewLogger(cfg.AppEnv, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatal().Err(err).Msg("mtproto-importer: invalid LOG_FORMAT/LOG_LEVEL")
+	}
 	if cfg.PGDSN == "" {
 		log.Fatal().Msg("mtproto-importer: PG_DSN environment variable is required")
 	}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// dueJob — плановая задача тика с выбранной задержкой отправки в очередь.
+type dueJob struct {
+	user  domain.User
+	job   domain.DigestJob
+	delay time.Duration
+}
+
+// jitterDelay выбирает случайную задержку от нуля до window с шагом в минуту.
+func jitterDelay(window time.Duration) time.Duration {
+	minutes := int64(window / time.Minute)
+	if minutes <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(minutes+1)) * time.Minute
+}
+
+// sortByDelay упорядочивает задачи тика по возрастанию задержки, как того требует
+// RabbitDigestQueue.EnqueueJittered.
+func sortByDelay(jobs []dueJob) {
+	sort.SliceStable(jobs, func(i, k int) bool { return jobs[i].delay < jobs[k].delay })
+}
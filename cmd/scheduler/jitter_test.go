@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterDelayStaysInWholeMinutesWithinWindow(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		d := jitterDelay(5 * time.Minute)
+		if d < 0 || d > 5*time.Minute || d%time.Minute != 0 {
+			t.Fatalf("unexpected delay %s", d)
+		}
+	}
+	if d := jitterDelay(30 * time.Second); d != 0 {
+		t.Fatalf("window shorter than a minute must not delay, got %s", d)
+	}
+	if d := jitterDelay(0); d != 0 {
+		t.Fatalf("zero window must not delay, got %s", d)
+	}
+}
+
+func TestSortByDelay(t *testing.T) {
+	jobs := []dueJob{{delay: 3 * time.Minute}, {delay: 0}, {delay: time.Minute}}
+	sortByDelay(jobs)
+	for i := 1; i < len(jobs); i++ {
+		if jobs[i-1].delay > jobs[i].delay {
+			t.Fatalf("jobs not sorted: %v", jobs)
+		}
+	}
+}
@@ -14,12 +14,20 @@ import (
 	"tg-digest-bot/internal/domain"
 	"tg-digest-bot/internal/infra/config"
 	"tg-digest-bot/internal/infra/db"
+	applog "tg-digest-bot/internal/infra/log"
 	"tg-digest-bot/internal/infra/metrics"
 	"tg-digest-bot/internal/infra/queue"
+	"tg-digest-bot/internal/usecase/schedule"
 )
 
 func main() {
 	cfg := config.Load()
+	if _, err := applog.NewLogger(cfg.AppEnv, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatal().Err(err).Msg("scheduler: некорректные настройки логов")
+	}
+	if err := domain.SetDefaultTimezone(cfg.DefaultTimezone); err != nil {
+		log.Fatal().Err(err).Msg("scheduler: некорректный DEFAULT_TIMEZONE")
+	}
 
 	metrics.MustRegister(prometheus.DefaultRegisterer)
 
@@ -42,13 +50,59 @@ func main() {
 		log.Fatal().Err(err).Msg("scheduler: не удалось инициализировать очередь RabbitMQ")
 	}
 
+	if cfg.Scheduler.DryRun {
+		log.Warn().Msg("scheduler: включён SCHEDULER_DRY_RUN — дайджесты не ставятся в очередь, только логируются")
+	}
+
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
+	muteCleanup := time.NewTicker(muteCleanupInterval)
+	defer muteCleanup.Stop()
+	metricsRollup := time.NewTicker(metricsRollupInterval)
+	defer metricsRollup.Stop()
+	postPurge := time.NewTicker(postPurgeInterval)
+	defer postPurge.Stop()
+	var lastRollup time.Time
+	rollup := func(now time.Time) {
+		day := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+		if day.Equal(lastRollup) {
+			return
+		}
+		if err := repoAdapter.RollupBusinessMetrics(ctx, day); err != nil {
+			log.Error().Err(err).Time("day", day).Msg("scheduler: не удалось агрегировать бизнес-метрики")
+			return
+		}
+		lastRollup = day
+		log.Info().Time("day", day).Msg("scheduler: бизнес-метрики агрегированы")
+	}
+	rollup(time.Now())
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info().Msg("scheduler: остановка")
 			return
+		case <-metricsRollup.C:
+			rollup(time.Now())
+		case <-postPurge.C:
+			now := time.Now().UTC()
+			purged, err := repoAdapter.PurgeOldPosts(ctx, now.Add(-cfg.Retention.Posts), now.AddDate(0, 0, -domain.DigestHistoryDays), cfg.Retention.BatchSize)
+			metrics.PostsPurged.Observe(float64(purged))
+			if err != nil {
+				log.Error().Err(err).Int64("posts", purged).Msg("scheduler: не удалось удалить старые посты")
+				continue
+			}
+			if purged > 0 {
+				log.Info().Int64("posts", purged).Dur("retention", cfg.Retention.Posts).Msg("scheduler: удалены старые посты")
+			}
+		case <-muteCleanup.C:
+			cleared, err := repoAdapter.ClearExpiredMutes(time.Now().UTC())
+			if err != nil {
+				log.Error().Err(err).Msg("scheduler: не удалось снять истёкшие мьюты")
+				continue
+			}
+			if cleared > 0 {
+				log.Info().Int64("channels", cleared).Msg("scheduler: сняты истёкшие мьюты")
+			}
 		case <-ticker.C:
 			now := time.Now().UTC()
 			log.Info().Time("now", now).Msg("scheduler: тик")
@@ -57,14 +111,22 @@ func main() {
 				log.Error().Err(err).Msg("scheduler: ошибка выборки пользователей")
 				continue
 			}
+			due := make([]dueJob, 0, len(users))
 			for _, user := range users {
-				scheduledUTC, ok, err := nextScheduledWindow(now, user)
+				scheduledUTC, ok, err := schedule.NextWindow(now, user)
 				if err != nil {
 					log.Warn().Err(err).Int64("user", user.TGUserID).Msg("scheduler: некорректный часовой пояс, используем UTC")
 				}
 				if !ok {
 					continue
 				}
+				if cfg.Scheduler.DryRun {
+					log.Info().Int64("user", user.TGUserID).Str("timezone", user.TimezoneName()).
+						Time("scheduled_for", scheduledUTC).Int64("chat", user.DigestChatID()).
+						Msg("scheduler: dry run, дайджест был бы поставлен в очередь")
+					continue
+				}
+				// Бронь привязана к слоту, а не ко времени отправки, поэтому задержка не даёт дублей.
 				acquired, err := repoAdapter.AcquireScheduleTask(user.ID, scheduledUTC)
 				if err != nil {
 					log.Error().Err(err).Int64("user", user.TGUserID).Msg("scheduler: ошибка бронирования задачи")
@@ -75,23 +137,31 @@ func main() {
 				}
 				job := domain.DigestJob{
 					UserTGID:    user.TGUserID,
-					ChatID:      user.TGUserID,
+					ChatID:      user.DigestChatID(),
 					Date:        scheduledUTC,
 					RequestedAt: now,
 					Cause:       domain.DigestCauseScheduled,
 				}
 				job.ID = uuid.NewString()
-				if err := digestQueue.Enqueue(ctx, job); err != nil {
+				due = append(due, dueJob{user: user, job: job, delay: jitterDelay(cfg.Scheduler.Jitter)})
+			}
+			sortByDelay(due)
+			for _, d := range due {
+				user, job := d.user, d.job
+				if err := digestQueue.EnqueueJittered(ctx, job, d.delay); err != nil {
 					log.Error().Err(err).Int64("user", user.TGUserID).Msg("scheduler: не удалось поставить задачу дайджеста")
 					continue
 				}
 				userID := user.ID
 				meta := map[string]any{
 					"job_id":        job.ID,
-					"scheduled_for": scheduledUTC,
+					"scheduled_for": job.Date,
 					"requested_at":  job.RequestedAt,
 					"cause":         string(job.Cause),
 				}
+				if d.delay > 0 {
+					meta["jitter_seconds"] = int(d.delay.Seconds())
+				}
 				if err := repoAdapter.RecordBusinessMetric(ctx, domain.BusinessMetric{
 					Event:    domain.BusinessMetricEventDigestScheduled,
 					UserID:   &userID,
@@ -104,31 +174,14 @@ func main() {
 	}
 }
 
-const scheduleWindow = 10 * time.Minute
+// muteCleanupInterval — как часто снимать истёкшие временные мьюты. Выборка дайджеста
+// проверяет срок сама, поэтому очистка нужна только для порядка в данных.
+const muteCleanupInterval = time.Hour
 
-func nextScheduledWindow(now time.Time, user domain.User) (time.Time, bool, error) {
-	loc := time.UTC
-	var loadErr error
-	if user.Timezone != "" {
-		if l, err := time.LoadLocation(user.Timezone); err == nil {
-			loc = l
-		} else {
-			loadErr = err
-		}
-	}
-	userNow := now.In(loc)
-	daily := user.DailyTime.In(time.UTC)
-	scheduledLocal := time.Date(userNow.Year(), userNow.Month(), userNow.Day(),
-		daily.Hour(), daily.Minute(), daily.Second(), 0, loc)
-
-	if userNow.After(scheduledLocal.Add(scheduleWindow)) {
-		scheduledLocal = scheduledLocal.Add(24 * time.Hour)
-	}
+// metricsRollupInterval — как часто проверять, агрегирован ли вчерашний день. Сам пересчёт
+// идёт раз в сутки, а после ошибки повторяется на следующей проверке.
+const metricsRollupInterval = time.Hour
 
-	diff := scheduledLocal.Sub(userNow)
-	if diff < -scheduleWindow || diff > scheduleWindow {
-		return time.Time{}, false, loadErr
-	}
-
-	return scheduledLocal.UTC(), true, loadErr
-}
+// postPurgeInterval — как часто удалять посты старше срока хранения. Удаление идёт пачками,
+// поэтому большой накопившийся объём не блокирует таблицу надолго.
+const postPurgeInterval = 6 * time.Hour
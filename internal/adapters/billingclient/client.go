@@ -115,6 +115,22 @@ func (c *Client) GetInvoiceByID(ctx context.Context, invoiceID int64) (domain.In
 	return invoice, nil
 }
 
+func (c *Client) GetInvoiceStatus(ctx context.Context, invoiceID int64) (domain.InvoiceStatus, error) {
+	invoice, err := c.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return domain.InvoiceStatus{}, err
+	}
+	return domain.InvoiceStatus{
+		InvoiceID:  invoice.ID,
+		AccountID:  invoice.AccountID,
+		Status:     invoice.Status,
+		Amount:     invoice.Amount,
+		PaidAmount: invoice.PaidAmount,
+		PaidAt:     invoice.PaidAt,
+		ExpiresAt:  invoice.ExpiresAt,
+	}, nil
+}
+
 func (c *Client) GetInvoiceByIdempotencyKey(ctx context.Context, key string) (domain.Invoice, error) {
 	var invoice domain.Invoice
 	endpoint := fmt.Sprintf("/api/v1/invoices/idempotency/%s", url.PathEscape(key))
@@ -124,6 +140,15 @@ func (c *Client) GetInvoiceByIdempotencyKey(ctx context.Context, key string) (do
 	return invoice, nil
 }
 
+func (c *Client) GetPaymentByIdempotencyKey(ctx context.Context, key string) (domain.Payment, error) {
+	var payment domain.Payment
+	endpoint := fmt.Sprintf("/api/v1/payments/idempotency/%s", url.PathEscape(key))
+	if err := c.get(ctx, endpoint, &payment); err != nil {
+		return domain.Payment{}, err
+	}
+	return payment, nil
+}
+
 func (c *Client) ChargeAccount(ctx context.Context, params domain.ChargeAccountParams) (domain.Payment, error) {
 	var payment domain.Payment
 	if err := c.post(ctx, "/api/v1/accounts/charge", params, &payment); err != nil {
@@ -243,6 +268,8 @@ func mapAPIError(status int, err apiError) error {
 		return domain.ErrAccountNotFound
 	case "insufficient_funds":
 		return domain.ErrInsufficientFunds
+	case "payment_not_found":
+		return domain.ErrPaymentNotFound
 	case "invalid_request":
 		return fmt.Errorf("billing api invalid request: %s", err.Error)
 	case "":
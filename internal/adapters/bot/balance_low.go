@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// balanceLowNoticeTTL — не чаще одного предупреждения о низком балансе за этот срок.
+const balanceLowNoticeTTL = 72 * time.Hour
+
+// notifyIfBalanceLow предупреждает пользователя, что баланс заканчивается, и предлагает пополнить.
+// Вызывается после каждого списания. Возвращает true, если сообщение отправлено.
+func (h *Handler) notifyIfBalanceLow(ctx context.Context, chatID, userID int64, balance domain.Money) bool {
+	if h.lowBalance <= 0 || balance.Amount >= h.lowBalance {
+		return false
+	}
+	if h.inflight != nil {
+		first, err := h.inflight.MarkSeen(ctx, "balance_low:"+strconv.FormatInt(userID, 10), balanceLowNoticeTTL)
+		if err != nil {
+			h.log.Warn().Err(err).Int64("user", userID).Msg("bot: balance-low debounce unavailable")
+			return false
+		}
+		if !first {
+			return false
+		}
+	}
+	currency := balance.Currency
+	if currency == "" {
+		currency = "RUB"
+	}
+	h.reply(chatID, fmt.Sprintf("⚠️ Баланс заканчивается: на счёте %s. Пополните заранее, чтобы следующая оплата прошла без перерыва.", formatMoney(balance.Amount, currency)), h.topUpPresetKeyboard())
+	return true
+}
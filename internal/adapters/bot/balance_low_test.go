@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/adapters/telegram"
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/cache"
+)
+
+func TestNotifyIfBalanceLowDebounces(t *testing.T) {
+	api := &tgbotapi.BotAPI{Token: "test", Client: okBotClient{}}
+	api.SetAPIEndpoint("http://bot.test/bot%s/%s")
+	h := &Handler{bot: telegram.NewClient(api), log: zerolog.Nop(), inflight: cache.NewMemorySeen()}
+	ctx := context.Background()
+	low := domain.Money{Amount: 5000, Currency: "RUB"}
+
+	if h.notifyIfBalanceLow(ctx, 1, 7, low) {
+		t.Fatal("threshold is not configured, notice must be disabled")
+	}
+	h.lowBalance = 30000
+	if h.notifyIfBalanceLow(ctx, 1, 7, domain.Money{Amount: 30000, Currency: "RUB"}) {
+		t.Fatal("balance at the threshold is not low")
+	}
+	if !h.notifyIfBalanceLow(ctx, 1, 7, low) {
+		t.Fatal("expected a notice for a low balance")
+	}
+	if h.notifyIfBalanceLow(ctx, 1, 7, low) {
+		t.Fatal("repeated charge must not ping the user again")
+	}
+	if !h.notifyIfBalanceLow(ctx, 2, 8, low) {
+		t.Fatal("another user must get their own notice")
+	}
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+// maxBulkAdd ограничивает число каналов в одной команде /add: каждый резолвится в Telegram.
+const maxBulkAdd = 20
+
+type bulkAddStatus int
+
+const (
+	bulkAddAdded bulkAddStatus = iota
+	bulkAddPresent
+	bulkAddFailed
+)
+
+// bulkAddResult — итог добавления одного канала из списка.
+type bulkAddResult struct {
+	Input  string
+	Title  string
+	Status bulkAddStatus
+	Reason string
+}
+
+// splitAliasList разбивает ввод /add на отдельные алиасы. Разделители — пробелы,
+// переводы строк и запятые; повторы (в т.ч. в разных формах @a и t.me/a) убираются.
+func splitAliasList(payload string) []string {
+	fields := strings.FieldsFunc(payload, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	seen := make(map[string]struct{}, len(fields))
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		key := strings.ToLower(f)
+		if alias, err := channels.ParseAlias(f); err == nil {
+			key = alias
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, f)
+	}
+	return out
+}
+
+// handleBulkAdd добавляет несколько каналов подряд и присылает сводку по каждому.
+// После достижения лимита тарифа оставшиеся каналы не обрабатываются.
+func (h *Handler) handleBulkAdd(ctx context.Context, chatID, tgUserID int64, inputs []string) {
+	if len(inputs) > maxBulkAdd {
+		h.reply(chatID, fmt.Sprintf("За один раз можно добавить до %s. Разбейте список на части.", pluralCount(maxBulkAdd, "канала", "каналов", "каналов")), nil)
+		return
+	}
+	present, err := h.userChannelAliases(ctx, tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: list channels for bulk add failed")
+		h.reply(chatID, "Не удалось получить список каналов. Попробуйте позже", nil)
+		return
+	}
+
+	results := make([]bulkAddResult, 0, len(inputs))
+	skipped := 0
+	for i, input := range inputs {
+		if alias, err := channels.ParseAlias(input); err == nil {
+			if _, ok := present[alias]; ok {
+				results = append(results, bulkAddResult{Input: input, Status: bulkAddPresent})
+				continue
+			}
+		}
+		channel, err := h.channelUC.AddChannel(ctx, tgUserID, input)
+		if err != nil {
+			apiErr := channels.AsAPIError(err)
+			if apiErr.Code() == channels.CodeChannelLimit {
+				skipped = len(inputs) - i
+				break
+			}
+			if apiErr.Code() == channels.CodeInternal {
+				h.log.Error().Err(err).Int64("user", tgUserID).Str("alias", input).Msg("bot: bulk add channel failed")
+			}
+			results = append(results, bulkAddResult{Input: input, Status: bulkAddFailed, Reason: bulkAddReason(apiErr.Code())})
+			continue
+		}
+		present[strings.ToLower(channel.Alias)] = struct{}{}
+		title := channel.Title
+		if title == "" {
+			title = channel.Alias
+		}
+		results = append(results, bulkAddResult{Input: input, Title: title, Status: bulkAddAdded})
+	}
+	h.reply(chatID, buildBulkAddMessage(results, skipped), h.mainKeyboard())
+}
+
+// userChannelAliases возвращает алиасы уже подключённых каналов пользователя.
+func (h *Handler) userChannelAliases(ctx context.Context, tgUserID int64) (map[string]struct{}, error) {
+	const page = 100
+	aliases := make(map[string]struct{})
+	for offset := 0; ; offset += page {
+		list, err := h.channelUC.ListChannels(ctx, tgUserID, page, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range list {
+			aliases[strings.ToLower(ch.Channel.Alias)] = struct{}{}
+		}
+		if len(list) < page {
+			return aliases, nil
+		}
+	}
+}
+
+func bulkAddReason(code string) string {
+	switch code {
+	case channels.CodeAliasInvalid:
+		return "некорректный алиас"
+	case channels.CodeInviteLink, channels.CodePrivateChannel:
+		return "приватный или недоступен"
+	case channels.CodeChannelNotFound:
+		return "не найден"
+	default:
+		return "ошибка, попробуйте позже"
+	}
+}
+
+// buildBulkAddMessage собирает сводку по результатам массового добавления.
+func buildBulkAddMessage(results []bulkAddResult, skipped int) string {
+	var added, present, failed []string
+	for _, r := range results {
+		switch r.Status {
+		case bulkAddAdded:
+			added = append(added, "• "+r.Title)
+		case bulkAddPresent:
+			present = append(present, "• "+r.Input)
+		case bulkAddFailed:
+			failed = append(failed, fmt.Sprintf("• %s — %s", r.Input, r.Reason))
+		}
+	}
+	var sections []string
+	if len(added) > 0 {
+		sections = append(sections, "✅ Добавлены:\n"+strings.Join(added, "\n"))
+	}
+	if len(present) > 0 {
+		sections = append(sections, "☑️ Уже в подписках:\n"+strings.Join(present, "\n"))
+	}
+	if len(failed) > 0 {
+		sections = append(sections, "⚠️ Не удалось добавить:\n"+strings.Join(failed, "\n"))
+	}
+	if skipped > 0 {
+		sections = append(sections, fmt.Sprintf("⛔ Достигнут лимит каналов тарифа: не обработано — %s. Удалите лишние каналы или обновите тариф.", pluralCount(skipped, "канал", "канала", "каналов")))
+	}
+	if len(sections) == 0 {
+		return "Список каналов пуст. Отправьте /add @a @b"
+	}
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,220 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// stubBilling списывает деньги и запоминает списания по ключу идемпотентности.
+type stubBilling struct {
+	domain.Billing
+	balance   int64
+	chargeErr error
+	charges   map[string]domain.Payment
+}
+
+func (b *stubBilling) EnsureAccount(context.Context, int64) (domain.BillingAccount, error) {
+	return domain.BillingAccount{ID: 1, Balance: domain.Money{Amount: b.balance, Currency: "RUB"}}, nil
+}
+
+func (b *stubBilling) GetAccountByUserID(ctx context.Context, userID int64) (domain.BillingAccount, error) {
+	return b.EnsureAccount(ctx, userID)
+}
+
+func (b *stubBilling) ChargeAccount(_ context.Context, params domain.ChargeAccountParams) (domain.Payment, error) {
+	if b.charges == nil {
+		b.charges = map[string]domain.Payment{}
+	}
+	payment := domain.Payment{ID: int64(len(b.charges) + 100), Status: "completed", IdempotencyKey: params.IdempotencyKey}
+	b.charges[params.IdempotencyKey] = payment
+	b.balance -= params.Amount.Amount
+	return payment, b.chargeErr
+}
+
+func (b *stubBilling) GetPaymentByIdempotencyKey(_ context.Context, key string) (domain.Payment, error) {
+	payment, ok := b.charges[key]
+	if !ok {
+		return domain.Payment{}, domain.ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+// stubActivations хранит активации и покупки тарифа в памяти.
+type stubActivations struct {
+	recordErr   error
+	chargeErr   error
+	activations map[int64]domain.PlanActivation
+	charges     map[string]domain.PlanCharge
+	roles       map[int64]domain.UserRole
+}
+
+func newStubActivations() *stubActivations {
+	return &stubActivations{
+		activations: map[int64]domain.PlanActivation{},
+		charges:     map[string]domain.PlanCharge{},
+		roles:       map[int64]domain.UserRole{},
+	}
+}
+
+func (a *stubActivations) RecordPlanActivation(userID, paymentID int64, role domain.UserRole) error {
+	if a.recordErr != nil {
+		return a.recordErr
+	}
+	a.activations[paymentID] = domain.PlanActivation{PaymentID: paymentID, UserID: userID, TGUserID: 1, Role: role}
+	return nil
+}
+
+func (a *stubActivations) ActivatePlan(paymentID int64) (bool, error) {
+	activation, ok := a.activations[paymentID]
+	if !ok || activation.ActivatedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	activation.ActivatedAt = &now
+	a.activations[paymentID] = activation
+	a.roles[activation.UserID] = activation.Role
+	return true, nil
+}
+
+func (a *stubActivations) MarkPlanActivationFailed(int64, string) error { return nil }
+
+func (a *stubActivations) ListPendingPlanActivations(int) ([]domain.PlanActivation, error) {
+	var pending []domain.PlanActivation
+	for _, activation := range a.activations {
+		if activation.ActivatedAt == nil {
+			pending = append(pending, activation)
+		}
+	}
+	return pending, nil
+}
+
+func (a *stubActivations) RecordPlanCharge(chargeKey string, userID int64, role domain.UserRole) error {
+	if a.chargeErr != nil {
+		return a.chargeErr
+	}
+	a.charges[chargeKey] = domain.PlanCharge{ChargeKey: chargeKey, UserID: userID, Role: role, CreatedAt: time.Now()}
+	return nil
+}
+
+func (a *stubActivations) ResolvePlanCharge(chargeKey string) error {
+	delete(a.charges, chargeKey)
+	return nil
+}
+
+func (a *stubActivations) ListPlanCharges(before time.Time, _ int) ([]domain.PlanCharge, error) {
+	var charges []domain.PlanCharge
+	for _, charge := range a.charges {
+		if charge.CreatedAt.Before(before) {
+			charges = append(charges, charge)
+		}
+	}
+	return charges, nil
+}
+
+// failingRoleUsers не может сменить тариф напрямую.
+type failingRoleUsers struct {
+	*stubUsers
+}
+
+func (failingRoleUsers) UpdateRole(int64, domain.UserRole) error { return errors.New("db down") }
+
+func newBuyHandler(billing *stubBilling, activations *stubActivations) (*Handler, *recordingSender) {
+	users := &stubUsers{user: domain.User{ID: 7, TGUserID: 1, Role: domain.UserRoleFree}}
+	h, bot := newRoutingHandler(users)
+	h.users = failingRoleUsers{users}
+	h.billing = billing
+	h.activations = activations
+	h.offers = defaultSubscriptionOffers()
+	return h, bot
+}
+
+func buyPlus(h *Handler) {
+	sendText(h, &tgbotapi.User{ID: 1}, "/buy plus")
+}
+
+func TestBuyDoesNotChargeWhenPurchaseCannotBeRecorded(t *testing.T) {
+	billing := &stubBilling{balance: 1_000_000}
+	activations := newStubActivations()
+	activations.chargeErr = errors.New("db down")
+	h, bot := newBuyHandler(billing, activations)
+
+	buyPlus(h)
+	if len(billing.charges) != 0 {
+		t.Fatalf("expected no charge, got %v", billing.charges)
+	}
+	if !strings.Contains(bot.last(), "деньги не списаны") {
+		t.Fatalf("unexpected reply %q", bot.last())
+	}
+}
+
+func TestBuyResolvesPurchaseOnInsufficientFunds(t *testing.T) {
+	billing := &stubBilling{balance: 1_000_000, chargeErr: domain.ErrInsufficientFunds}
+	activations := newStubActivations()
+	h, bot := newBuyHandler(billing, activations)
+
+	buyPlus(h)
+	if len(activations.charges) != 0 {
+		t.Fatalf("expected purchase to be resolved, got %v", activations.charges)
+	}
+	if !strings.Contains(bot.last(), "Недостаточно средств") {
+		t.Fatalf("unexpected reply %q", bot.last())
+	}
+}
+
+func TestReconcilerActivatesChargeWithoutRecordedActivation(t *testing.T) {
+	billing := &stubBilling{balance: 1_000_000}
+	activations := newStubActivations()
+	activations.recordErr = errors.New("db down")
+	h, bot := newBuyHandler(billing, activations)
+
+	buyPlus(h)
+	if len(billing.charges) != 1 {
+		t.Fatalf("expected one charge, got %v", billing.charges)
+	}
+	if !strings.Contains(bot.last(), "активируется автоматически") {
+		t.Fatalf("unexpected reply %q", bot.last())
+	}
+	if len(activations.charges) != 1 {
+		t.Fatalf("expected purchase to stay for reconciliation, got %v", activations.charges)
+	}
+
+	activations.recordErr = nil
+	for key, charge := range activations.charges {
+		charge.CreatedAt = time.Now().Add(-2 * planChargeSettleDelay)
+		activations.charges[key] = charge
+	}
+	h.reconcilePlanCharges(context.Background())
+	h.retryPlanActivations()
+
+	if activations.roles[7] != domain.UserRolePlus {
+		t.Fatalf("expected plus to be activated, got %q", activations.roles[7])
+	}
+	if len(activations.charges) != 0 {
+		t.Fatalf("expected purchase to be resolved, got %v", activations.charges)
+	}
+	if !strings.Contains(bot.last(), "Подписка Plus активирована") {
+		t.Fatalf("expected activation notice, got %q", bot.last())
+	}
+}
+
+func TestReconcilerDropsAbandonedPurchase(t *testing.T) {
+	activations := newStubActivations()
+	h, _ := newBuyHandler(&stubBilling{}, activations)
+	activations.charges["lost"] = domain.PlanCharge{ChargeKey: "lost", UserID: 7, Role: domain.UserRolePlus, CreatedAt: time.Now().Add(-2 * planChargeAbandonAfter)}
+	activations.charges["fresh"] = domain.PlanCharge{ChargeKey: "fresh", UserID: 7, Role: domain.UserRolePlus, CreatedAt: time.Now().Add(-2 * planChargeSettleDelay)}
+
+	h.reconcilePlanCharges(context.Background())
+	if _, ok := activations.charges["lost"]; ok {
+		t.Fatal("expected abandoned purchase to be resolved")
+	}
+	if _, ok := activations.charges["fresh"]; !ok {
+		t.Fatal("expected recent purchase to stay")
+	}
+}
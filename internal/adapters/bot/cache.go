@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// handleCache показывает, сколько постов бот хранит по каналам пользователя,
+// а с аргументом clear удаляет те, что уже не нужны для дайджестов.
+func (h *Handler) handleCache(ctx context.Context, chatID, tgUserID int64, payload string) {
+	cache, ok := h.posts.(domain.PostCacheRepo)
+	if !ok {
+		h.reply(chatID, "Просмотр сохранённых постов сейчас недоступен.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	since := time.Now().UTC().Add(-domain.PostCacheWindow)
+
+	switch strings.ToLower(payload) {
+	case "":
+		counts, err := cache.CountRecentPostsByChannel(ctx, user.ID, since)
+		if err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: count cached posts failed")
+			h.reply(chatID, "Не удалось посчитать сохранённые посты. Попробуйте позже.", nil)
+			return
+		}
+		h.reply(chatID, buildCacheMessage(counts), nil)
+	case "clear":
+		deleted, err := cache.ClearUserPostCache(ctx, user.ID, since)
+		if err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: clear cached posts failed")
+			h.reply(chatID, "Не удалось удалить посты. Попробуйте позже.", nil)
+			return
+		}
+		h.reply(chatID, buildCacheClearedMessage(deleted), nil)
+	default:
+		h.reply(chatID, "Формат: /cache — посмотреть сохранённые посты, /cache clear — удалить старые.", nil)
+	}
+}
+
+// buildCacheMessage описывает сохранённые посты по каналам.
+func buildCacheMessage(counts []domain.ChannelPostCount) string {
+	if len(counts) == 0 {
+		return "У вас нет каналов — бот не хранит для вас посты."
+	}
+	hours := int(domain.PostCacheWindow / time.Hour)
+	lines := []string{"🗄 Сохранённые посты ваших каналов:"}
+	total := 0
+	for _, c := range counts {
+		name := c.Title
+		if name == "" {
+			name = "@" + c.Alias
+		}
+		total += c.Total
+		lines = append(lines, fmt.Sprintf("• %s — %s, за %d ч: %d", name, pluralCount(c.Total, "пост", "поста", "постов"), hours, c.Recent))
+	}
+	lines = append(lines, "",
+		fmt.Sprintf("Всего: %s.", pluralCount(total, "пост", "поста", "постов")),
+		fmt.Sprintf("/cache clear удалит посты старше %d ч в каналах, на которые больше никто не подписан. Свежие посты нужны для ближайшего дайджеста.", hours),
+	)
+	return strings.Join(lines, "\n")
+}
+
+func buildCacheClearedMessage(deleted int64) string {
+	if deleted == 0 {
+		return "Удалять нечего: старых постов только в ваших каналах нет. Посты каналов, на которые подписан кто-то ещё, остаются."
+	}
+	return fmt.Sprintf("🧹 Удалено: %s. Посты каналов, на которые подписан кто-то ещё, остаются.", pluralCount(int(deleted), "пост", "поста", "постов"))
+}
@@ -0,0 +1,23 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestBuildCacheMessage(t *testing.T) {
+	msg := buildCacheMessage([]domain.ChannelPostCount{
+		{Alias: "news", Title: "Новости", Total: 21, Recent: 3},
+		{Alias: "empty", Total: 0},
+	})
+	for _, want := range []string{"Новости — 21 пост, за 48 ч: 3", "@empty — 0 постов", "Всего: 21 пост."} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+	if msg := buildCacheMessage(nil); !strings.Contains(msg, "нет каналов") {
+		t.Fatalf("expected empty-state message, got:\n%s", msg)
+	}
+}
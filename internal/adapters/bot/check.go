@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+// handleCheck проверяет, можно ли собирать посты канала, не добавляя его: /check @alias.
+func (h *Handler) handleCheck(ctx context.Context, chatID int64, alias string) {
+	if alias == "" {
+		h.reply(chatID, "Отправьте /check @alias, чтобы проверить канал перед добавлением", nil)
+		return
+	}
+	probe, err := h.channelUC.CheckChannel(ctx, alias)
+	if err != nil {
+		h.replyCheckError(chatID, "/check", alias, err)
+		return
+	}
+	h.reply(chatID, buildChannelCheckMessage(probe), nil)
+}
+
+// replyCheckError объясняет, почему канал не удалось проверить командой command.
+func (h *Handler) replyCheckError(chatID int64, command, alias string, err error) {
+	switch channels.AsAPIError(err).Code() {
+	case channels.CodeAliasInvalid:
+		h.reply(chatID, fmt.Sprintf("Некорректный алиас. Пример: %s @example", command), nil)
+	case channels.CodeInviteLink:
+		h.reply(chatID, "Это ссылка-приглашение в приватный канал: такие каналы собрать нельзя.", nil)
+	default:
+		h.log.Error().Err(err).Str("alias", alias).Str("command", command).Msg("bot: check channel failed")
+		h.reply(chatID, "Не удалось проверить канал. Попробуйте позже.", nil)
+	}
+}
+
+// buildChannelCheckMessage описывает результат проверки канала.
+func buildChannelCheckMessage(probe domain.ChannelProbe) string {
+	name := probe.Meta.Title
+	if probe.Meta.Alias != "" {
+		name = fmt.Sprintf("%s (@%s)", probe.Meta.Title, probe.Meta.Alias)
+	}
+	if probe.Readable {
+		msg := fmt.Sprintf("✅ %s: готов к сбору.", name)
+		if probe.Empty {
+			msg += " Постов в канале пока нет — дайджест появится, когда они выйдут."
+		}
+		return msg + fmt.Sprintf("\nДобавить: /add @%s", probe.Meta.Alias)
+	}
+	var reason string
+	switch probe.Reason {
+	case domain.ProbeReasonNotFound:
+		return "❌ Канал не найден. Проверьте алиас."
+	case domain.ProbeReasonNotChannel:
+		return "❌ Это не канал: бот собирает посты только из публичных каналов."
+	case domain.ProbeReasonPrivate:
+		reason = "история закрыта — канал приватный или ограничил доступ к сообщениям"
+	case domain.ProbeReasonAdminRequired:
+		reason = "история доступна только администраторам канала"
+	default:
+		reason = "история недоступна"
+	}
+	if name == "" {
+		return fmt.Sprintf("❌ Канал не готов к сбору: %s.", reason)
+	}
+	return fmt.Sprintf("❌ %s не готов к сбору: %s. Дайджест по нему будет пустым.", name, reason)
+}
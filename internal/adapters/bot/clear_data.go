@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const (
+	// defaultClearDataWindow — сколько ждём /clear_data_confirm, если окно не настроено.
+	defaultClearDataWindow = 5 * time.Minute
+	// clearDataConfirmWord нужно набрать вручную: случайное нажатие на команду ничего не удалит.
+	clearDataConfirmWord = "DELETE"
+)
+
+func (h *Handler) clearDataWindow() time.Duration {
+	if h.clearWindow <= 0 {
+		return defaultClearDataWindow
+	}
+	return h.clearWindow
+}
+
+// clearDataBlockedByBalance запрещает удаление, пока на счёте есть деньги: после удаления
+// их не вернуть, и спор «пропали деньги» решается только через поддержку.
+// Если баланс проверить не удалось, удаление тоже откладывается.
+func (h *Handler) clearDataBlockedByBalance(ctx context.Context, chatID, tgUserID int64) bool {
+	if h.billing == nil {
+		return false
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить пользователя: %v", err), nil)
+		return true
+	}
+	account, err := h.billing.GetAccountByUserID(ctx, user.ID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return false
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: balance check before data deletion failed")
+		h.reply(chatID, "Не удалось проверить баланс перед удалением. Попробуйте позже.", nil)
+		return true
+	}
+	if account.Balance.Amount <= 0 {
+		return false
+	}
+	h.reply(chatID, fmt.Sprintf("На вашем счёте %s — при удалении аккаунта эти деньги будут потеряны. Удаление заблокировано: напишите в поддержку через /feedback, мы поможем вернуть средства и удалим данные.", formatMoney(account.Balance.Amount, account.Balance.Currency)), nil)
+	return true
+}
+
+func buildClearDataWarning(window time.Duration) string {
+	lines := []string{
+		"⚠️ Удаление аккаунта необратимо. Будут удалены:",
+		"• все каналы, теги и настройки фильтров;",
+		"• расписание, часовой пояс и чат доставки;",
+		"• история дайджестов и отзывов;",
+		"• тариф Plus или Pro вместе с оплаченной подпиской;",
+		"• приглашённые друзья и выданные бонусы.",
+		"",
+		fmt.Sprintf("Если вы уверены, отправьте /clear_data_confirm %s в течение %s. Передумали — /cancel.", clearDataConfirmWord, formatClearDataWindow(window)),
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatClearDataWindow(window time.Duration) string {
+	if window >= time.Minute && window%time.Minute == 0 {
+		return pluralCount(int(window/time.Minute), "минуты", "минут", "минут")
+	}
+	return pluralCount(int(window.Round(time.Second)/time.Second), "секунды", "секунд", "секунд")
+}
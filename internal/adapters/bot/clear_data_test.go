@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/adapters/telegram"
+)
+
+func TestClearDataConfirmRequiresTypedWord(t *testing.T) {
+	api := &tgbotapi.BotAPI{Token: "test", Client: okBotClient{}}
+	api.SetAPIEndpoint("http://bot.test/bot%s/%s")
+	h := &Handler{bot: telegram.NewClient(api), log: zerolog.Nop()}
+	send := func(text string) {
+		h.HandleUpdate(context.Background(), tgbotapi.Update{Message: &tgbotapi.Message{
+			Text: text,
+			From: &tgbotapi.User{ID: 7},
+			Chat: &tgbotapi.Chat{ID: 7},
+		}})
+	}
+	requested := func() (at time.Time) {
+		h.pending.with(7, func(st *pendingState) { at = st.dropExpires })
+		return at
+	}
+
+	send("/clear_data")
+	if requested().IsZero() {
+		t.Fatal("/clear_data should start the confirmation window")
+	}
+	for _, text := range []string{"/clear_data_confirm", "/clear_data_confirm delete"} {
+		send(text)
+		if requested().IsZero() {
+			t.Fatalf("%q must not consume the pending request", text)
+		}
+	}
+
+	h.pending.with(7, func(st *pendingState) { st.dropExpires = time.Now().Add(-time.Minute) })
+	send("/clear_data_confirm DELETE")
+	if !requested().IsZero() {
+		t.Fatal("an expired request should be dropped")
+	}
+}
+
+func TestBuildClearDataWarning(t *testing.T) {
+	msg := buildClearDataWarning(10 * time.Minute)
+	for _, want := range []string{"необратимо", "тариф Plus или Pro", "/clear_data_confirm DELETE", "10 минут"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in warning:\n%s", want, msg)
+		}
+	}
+	if got := formatClearDataWindow(90 * time.Second); got != "90 секунд" {
+		t.Fatalf("unexpected window text %q", got)
+	}
+}
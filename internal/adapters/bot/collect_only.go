@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/schedule"
+)
+
+const (
+	defaultCollectAhead = time.Hour
+	maxCollectAhead     = 12 * time.Hour
+)
+
+// handleCollectOnly ставит задачи только на сбор постов, чтобы заранее прогреть кеш перед
+// рассылкой. Формат: /collect_only [30m|2h] — пользователи, чей дайджест придёт в ближайшее
+// время (по умолчанию час); /collect_only <tg_id> — один пользователь.
+func (h *Handler) handleCollectOnly(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	now := time.Now().UTC()
+	if id, err := strconv.ParseInt(payload, 10, 64); err == nil && id > 0 {
+		if _, err := h.users.GetByTGID(id); err != nil {
+			h.reply(chatID, fmt.Sprintf("Пользователь %d не найден.", id), nil)
+			return
+		}
+		if err := h.enqueueCollectOnly(ctx, id, now); err != nil {
+			h.log.Error().Err(err).Int64("target", id).Msg("bot: collect only enqueue failed")
+			h.reply(chatID, "Не удалось поставить задачу в очередь, попробуйте позже", nil)
+			return
+		}
+		h.reply(chatID, fmt.Sprintf("Сбор постов для %d поставлен в очередь.", id), nil)
+		return
+	}
+	ahead := defaultCollectAhead
+	if payload != "" {
+		d, err := time.ParseDuration(payload)
+		if err != nil || d <= 0 || d > maxCollectAhead {
+			h.reply(chatID, "Формат: /collect_only [30m|2h] — до 12h, или /collect_only <tg_id>.", nil)
+			return
+		}
+		ahead = d
+	}
+	users, err := h.users.ListForDailyTime(now)
+	if err != nil {
+		h.log.Error().Err(err).Msg("bot: list users for collect only failed")
+		h.reply(chatID, "Не удалось получить пользователей. Попробуйте позже.", nil)
+		return
+	}
+	targets := usersDueWithin(users, now, ahead)
+	queued := 0
+	for _, user := range targets {
+		if err := h.enqueueCollectOnly(ctx, user.TGUserID, now); err != nil {
+			h.log.Error().Err(err).Int64("target", user.TGUserID).Msg("bot: collect only enqueue failed")
+			continue
+		}
+		queued++
+	}
+	h.log.Info().Int64("admin", tgUserID).Dur("ahead", ahead).Int("queued", queued).Int("due", len(targets)).Msg("bot: collect only jobs queued")
+	h.reply(chatID, fmt.Sprintf("Сбор постов поставлен в очередь для %d из %d пользователей с рассылкой в ближайшие %s.", queued, len(targets), ahead), nil)
+}
+
+func (h *Handler) enqueueCollectOnly(ctx context.Context, targetTGID int64, now time.Time) error {
+	return h.jobs.Enqueue(ctx, domain.DigestJob{
+		ID:          uuid.NewString(),
+		UserTGID:    targetTGID,
+		ChatID:      targetTGID,
+		Date:        now,
+		RequestedAt: now,
+		Cause:       domain.DigestCauseCollectOnly,
+	})
+}
+
+// usersDueWithin отбирает пользователей, чей плановый дайджест придёт не позже now+ahead.
+func usersDueWithin(users []domain.User, now time.Time, ahead time.Duration) []domain.User {
+	due := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		next, _ := schedule.NextRun(now, user)
+		if !next.After(now.Add(ahead)) {
+			due = append(due, user)
+		}
+	}
+	return due
+}
@@ -0,0 +1,21 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestUsersDueWithin(t *testing.T) {
+	now := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	users := []domain.User{
+		{TGUserID: 1, Timezone: "UTC", DailyTime: time.Date(0, 1, 1, 6, 30, 0, 0, time.UTC)},
+		{TGUserID: 2, Timezone: "UTC", DailyTime: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{TGUserID: 3, Timezone: "Europe/Moscow", DailyTime: time.Date(0, 1, 1, 9, 45, 0, 0, time.UTC)},
+	}
+	due := usersDueWithin(users, now, time.Hour)
+	if len(due) != 2 || due[0].TGUserID != 1 || due[1].TGUserID != 3 {
+		t.Fatalf("expected users 1 and 3 to be due within an hour, got %+v", due)
+	}
+}
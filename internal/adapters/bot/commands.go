@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandInfo описывает пользовательскую команду. Menu и MenuEN — подписи в меню Telegram
+// на русском и английском; команда без Menu в меню не попадает. Help — строки справки /help.
+type commandInfo struct {
+	Name   string
+	Menu   string
+	MenuEN string
+	Help   []string
+}
+
+// commandSection — раздел справки со своими командами.
+type commandSection struct {
+	Title    string
+	Commands []commandInfo
+}
+
+// botCommands — единый список команд для меню Telegram и /help. Служебные команды
+// разработчиков сюда не входят.
+var botCommands = []commandSection{
+	{
+		Commands: []commandInfo{
+			{Name: "start", Menu: "Начать работу с ботом", MenuEN: "Start the bot"},
+			{Name: "help", Menu: "Все команды и примеры", MenuEN: "All commands with examples"},
+			{Name: "guide", Menu: "Краткое руководство", MenuEN: "Quick start guide"},
+		},
+	},
+	{
+		Title: "Управление каналами",
+		Commands: []commandInfo{
+			{Name: "add", Menu: "Добавить канал", MenuEN: "Add a channel", Help: []string{
+				"/add @toporlive — добавить канал.",
+				"/add @a @b t.me/c — добавить сразу несколько каналов (через пробел или с новой строки).",
+			}},
+			{Name: "check", Help: []string{"/check @toporlive — проверить, что посты канала можно собирать, не добавляя его."}},
+			{Name: "peek", Help: []string{"/peek @toporlive — прислать пример дайджеста канала из последних постов, не добавляя его (раз в 5 минут)."}},
+			{Name: "search", Menu: "Найти канал по названию", MenuEN: "Search for a channel", Help: []string{
+				"/search новости — найти канал по названию среди уже известных боту.",
+				"Наберите в любом чате имя бота через @ и алиас канала — бот предложит добавить канал.",
+			}},
+			{Name: "list", Menu: "Мои каналы", MenuEN: "My channels", Help: []string{"/list — показать сохранённые каналы и действия с ними."}},
+			{Name: "mute", Menu: "Временно убрать канал из дайджеста", MenuEN: "Mute a channel", Help: []string{
+				"/mute @toporlive — временно убрать канал из дайджеста.",
+				"/mute @toporlive 3d — выключить канал на 3 дня (также 12h, 1w), потом он включится сам.",
+			}},
+			{Name: "unmute", Menu: "Вернуть канал в дайджест", MenuEN: "Unmute a channel", Help: []string{"/unmute @toporlive — вернуть канал в дайджест."}},
+			{Name: "tag", Menu: "Задать теги канала", MenuEN: "Tag a channel", Help: []string{"/tag @toporlive новости, аналитика — задать теги."}},
+			{Name: "tags", Menu: "Мои теги", MenuEN: "My tags", Help: []string{"/tags — посмотреть список ваших тегов."}},
+		},
+	},
+	{
+		Title: "Дайджесты",
+		Commands: []commandInfo{
+			{Name: "digest_now", Menu: "Дайджест за 24 часа", MenuEN: "Digest for the last 24 hours", Help: []string{"/digest_now — собрать дайджест из всех немьютнутых каналов."}},
+			{Name: "digest_tag", Menu: "Дайджест по тегу", MenuEN: "Digest by tag", Help: []string{"/digest_tag новости — дайджест только по каналам с тегом \"новости\"."}},
+			{Name: "digest_date", Help: []string{"/digest_date 2024-05-31 — дайджест за прошедший день, если рассылка не пришла (Plus и Pro, последние дни)."}},
+			{Name: "resend", Menu: "Прислать прошлый дайджест", MenuEN: "Resend a recent digest", Help: []string{"/resend — повторно прислать один из недавних дайджестов."}},
+			{Name: "last", Help: []string{"/last — сколько пунктов дал каждый канал в последний дайджест."}},
+			{Name: "find", Menu: "Поиск по присланным дайджестам", MenuEN: "Search delivered digests", Help: []string{"/find ставка ЦБ — найти пост в присланных дайджестах за последние две недели."}},
+		},
+	},
+	{
+		Title: "Биллинг",
+		Commands: []commandInfo{
+			{Name: "balance", Menu: "Баланс счёта", MenuEN: "Account balance", Help: []string{"/balance — показать баланс счёта."}},
+			{Name: "deposit", Menu: "Пополнить счёт", MenuEN: "Top up the balance", Help: []string{"/deposit 500 — создать счёт на пополнение через СБП."}},
+			{Name: "invoice", Help: []string{"/invoice 123 — проверить, дошла ли оплата по счёту."}},
+			{Name: "promo", Help: []string{"/promo КОД — активировать промокод на бонус к балансу."}},
+			{Name: "buy", Menu: "Купить подписку", MenuEN: "Buy a subscription", Help: []string{"/buy plus — купить подписку Plus (аналогично /buy pro)."}},
+		},
+	},
+	{
+		Title: "Расписание и данные",
+		Commands: []commandInfo{
+			{Name: "schedule", Menu: "Время ежедневной рассылки", MenuEN: "Daily delivery time", Help: []string{
+				"/schedule — открыть выбор времени.",
+				"/schedule 21:30 — задать своё время рассылки.",
+			}},
+			{Name: "next", Menu: "Когда придёт следующий дайджест", MenuEN: "When the next digest arrives", Help: []string{"/next — когда придёт следующий дайджест и какие каналы в него войдут."}},
+			{Name: "pause", Menu: "Приостановить дайджесты", MenuEN: "Pause digests", Help: []string{"/pause 2w — приостановить все дайджесты на две недели (без срока — до /resume), каналы сохранятся."}},
+			{Name: "resume", Menu: "Снять паузу", MenuEN: "Resume digests", Help: []string{"/resume — снять паузу."}},
+			{Name: "email", Help: []string{"/email set you@example.com — получать дайджест на почту (подробнее: /email)."}},
+			{Name: "filter_ads", Help: []string{"/filter_ads on — убирать рекламные посты из дайджестов (off — выключить)."}},
+			{Name: "filter_lang", Help: []string{"/filter_lang on — оставлять только посты на вашем языке (или /filter_lang en, off — выключить)."}},
+			{Name: "link_previews", Help: []string{"/link_previews on — присылать главный пост дайджеста отдельно, с превью ссылки (off — выключить)."}},
+			{Name: "setchat", Help: []string{"/setchat @канал — присылать плановые дайджесты в группу или канал, где бот администратор (off — обратно в личку)."}},
+			{Name: "order", Help: []string{"/order time — порядок пунктов дайджеста: relevance (по важности), time (по времени) или engagement (по реакциям)."}},
+			{Name: "comments", Help: []string{"/comments on — добавлять в дайджест реакцию читателей на обсуждаемые посты (тариф Pro, off — выключить)."}},
+			{Name: "timezone", Menu: "Часовой пояс", MenuEN: "Time zone", Help: []string{"/timezone Europe/Moscow — выбрать часовой пояс или использовать меню бота."}},
+			{Name: "whoami", Menu: "Тариф, лимиты и настройки", MenuEN: "Plan, limits and settings", Help: []string{"/whoami — показать тариф, лимиты и текущие настройки."}},
+			{Name: "cache", Help: []string{"/cache — сколько постов бот хранит по вашим каналам (/cache clear — удалить старые)."}},
+			{Name: "feedback", Menu: "Написать отзыв", MenuEN: "Send feedback"},
+			{Name: "cancel", Menu: "Отменить текущее действие", MenuEN: "Cancel the current action", Help: []string{"/cancel — отменить текущее действие (ввод времени, отзыва и т.п.)."}},
+			{Name: "clear_data", Menu: "Удалить аккаунт и данные", MenuEN: "Delete account and data", Help: []string{"/clear_data — удалить аккаунт и все сохранённые данные."}},
+		},
+	},
+}
+
+// MenuCommands возвращает команды для setMyCommands: en — английские подписи, иначе русские.
+func MenuCommands(en bool) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, section := range botCommands {
+		for _, cmd := range section.Commands {
+			if cmd.Menu == "" {
+				continue
+			}
+			description := cmd.Menu
+			if en && cmd.MenuEN != "" {
+				description = cmd.MenuEN
+			}
+			commands = append(commands, tgbotapi.BotCommand{Command: cmd.Name, Description: description})
+		}
+	}
+	return commands
+}
+
+// helpCommandLines собирает разделы справки из botCommands.
+func helpCommandLines() []string {
+	var lines []string
+	for _, section := range botCommands {
+		var entries []string
+		for _, cmd := range section.Commands {
+			for _, line := range cmd.Help {
+				entries = append(entries, "• "+line)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		lines = append(lines, "", strings.TrimSpace(section.Title)+":")
+		lines = append(lines, entries...)
+	}
+	return lines
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMenuCommandsFollowTelegramRules(t *testing.T) {
+	name := regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+	for _, en := range []bool{false, true} {
+		commands := MenuCommands(en)
+		if len(commands) == 0 || len(commands) > 100 {
+			t.Fatalf("unexpected number of menu commands: %d", len(commands))
+		}
+		seen := make(map[string]struct{}, len(commands))
+		for _, cmd := range commands {
+			if !name.MatchString(cmd.Command) {
+				t.Fatalf("invalid command name %q", cmd.Command)
+			}
+			if n := len([]rune(cmd.Description)); n == 0 || n > 256 {
+				t.Fatalf("invalid description length %d for /%s", n, cmd.Command)
+			}
+			if _, dup := seen[cmd.Command]; dup {
+				t.Fatalf("duplicate command /%s", cmd.Command)
+			}
+			seen[cmd.Command] = struct{}{}
+		}
+	}
+	if got := MenuCommands(true)[0].Description; got != "Start the bot" {
+		t.Fatalf("expected english descriptions, got %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleComments включает или выключает сбор лучших комментариев к обсуждаемым постам.
+// Сбор доступен только на тарифах с Plan().Comments; выключить настройку можно на любом.
+func (h *Handler) handleComments(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for comments failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	var enabled bool
+	switch strings.ToLower(payload) {
+	case "on", "вкл":
+		enabled = true
+	case "off", "выкл":
+		enabled = false
+	case "":
+		h.reply(chatID, fmt.Sprintf("Комментарии в дайджесте %s. Используйте /comments on или /comments off.", commentsState(user.WantsComments())), nil)
+		return
+	default:
+		h.reply(chatID, "Формат: /comments on или /comments off.", nil)
+		return
+	}
+	if enabled && !user.Plan().Comments {
+		h.reply(chatID, fmt.Sprintf("Комментарии к постам доступны на тарифе Pro, у вас — %s. Подключить: /buy pro.", user.Plan().Name), nil)
+		return
+	}
+	if err := h.users.SetCollectComments(user.ID, enabled); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set collect_comments failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	if enabled {
+		h.reply(chatID, "Комментарии включены: для самых обсуждаемых постов в дайджест попадёт реакция читателей. Каналы без группы обсуждения пропускаются.", nil)
+		return
+	}
+	h.reply(chatID, "Комментарии выключены: дайджест строится только по текстам постов.", nil)
+}
+
+func commentsState(enabled bool) string {
+	if enabled {
+		return "включены"
+	}
+	return "выключены"
+}
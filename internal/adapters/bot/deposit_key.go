@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// depositInvoiceWindow — окно, в котором повторное пополнение на ту же сумму возвращает уже
+// выставленный счёт, а не плодит новые QR.
+const depositInvoiceWindow = 10 * time.Minute
+
+// depositIdempotencyKey строит ключ счёта из пользователя, суммы и текущего окна времени.
+// Повторные нажатия «Пополнить» попадают в идемпотентность CreateInvoice и получают тот же счёт.
+func depositIdempotencyKey(userID, amountMinor int64, now time.Time) string {
+	bucket := now.UTC().Truncate(depositInvoiceWindow).Unix()
+	sum := sha256.Sum256([]byte(fmt.Sprintf("topup:%d:%d:%d", userID, amountMinor, bucket)))
+	return "topup-" + hex.EncodeToString(sum[:16])
+}
+
+// depositInvoiceReusable сообщает, можно ли снова показать пользователю счёт: он ещё ждёт оплаты
+// и не истёк.
+func depositInvoiceReusable(result domain.CreateSBPInvoiceResult, now time.Time) bool {
+	invoice := result.Invoice
+	if invoice.Status != "" && invoice.Status != domain.InvoiceStatusPending {
+		return false
+	}
+	for _, expiresAt := range []*time.Time{invoice.ExpiresAt, result.QR.ExpiresAt} {
+		if expiresAt != nil && !now.Before(*expiresAt) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestDepositIdempotencyKeyStableWithinWindow(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)
+	key := depositIdempotencyKey(7, 50000, at)
+	if again := depositIdempotencyKey(7, 50000, at.Add(8*time.Minute)); again != key {
+		t.Fatalf("repeated tap in the same window should reuse the key: %s != %s", again, key)
+	}
+	for name, other := range map[string]string{
+		"next window":  depositIdempotencyKey(7, 50000, at.Add(9*time.Minute)),
+		"other amount": depositIdempotencyKey(7, 100000, at),
+		"other user":   depositIdempotencyKey(8, 50000, at),
+	} {
+		if other == key {
+			t.Fatalf("%s should produce a new key", name)
+		}
+	}
+}
+
+func TestDepositInvoiceReusable(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	cases := map[string]struct {
+		result domain.CreateSBPInvoiceResult
+		want   bool
+	}{
+		"pending":     {domain.CreateSBPInvoiceResult{Invoice: domain.Invoice{Status: "pending", ExpiresAt: &future}}, true},
+		"paid":        {domain.CreateSBPInvoiceResult{Invoice: domain.Invoice{Status: "paid"}}, false},
+		"expired":     {domain.CreateSBPInvoiceResult{Invoice: domain.Invoice{Status: "pending", ExpiresAt: &past}}, false},
+		"qr expired":  {domain.CreateSBPInvoiceResult{Invoice: domain.Invoice{Status: "pending"}, QR: domain.SBPQRCode{ExpiresAt: &past}}, false},
+		"no metadata": {domain.CreateSBPInvoiceResult{}, true},
+	}
+	for name, tc := range cases {
+		if got := depositInvoiceReusable(tc.result, now); got != tc.want {
+			t.Fatalf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+var (
+	errPastDateFormat = errors.New("неверный формат даты")
+	errPastDateToday  = errors.New("дата не в прошлом")
+	errPastDateTooOld = errors.New("дата раньше доступного окна")
+)
+
+// handleDigestDate собирает дайджест за прошедший день, например если рассылка не пришла
+// из-за сбоя. Формат: /digest_date YYYY-MM-DD. Глубина зависит от тарифа, запрос
+// расходует ручной лимит, как /digest_now.
+func (h *Handler) handleDigestDate(ctx context.Context, chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	maxDays := user.Plan().PastDigestDays
+	if maxDays <= 0 {
+		h.reply(chatID, "Дайджест за прошедшие дни доступен на тарифах Plus и Pro. Оформить подписку — /buy plus.", nil)
+		return
+	}
+	loc, err := time.LoadLocation(user.TimezoneName())
+	if err != nil {
+		loc = time.UTC
+	}
+	day, err := parsePastDigestDate(payload, time.Now().In(loc), maxDays)
+	if err != nil {
+		switch {
+		case errors.Is(err, errPastDateToday):
+			h.reply(chatID, "Дайджест за сегодня собирает /digest_now.", nil)
+		case errors.Is(err, errPastDateTooOld):
+			h.reply(chatID, fmt.Sprintf("На тарифе %s можно собрать дайджест не старше %s.", user.Plan().Name, pluralCount(maxDays, "дня", "дней", "дней")), nil)
+		default:
+			h.reply(chatID, "Формат: /digest_date 2024-05-31", nil)
+		}
+		return
+	}
+	if h.replyIfPaused(chatID, user) {
+		return
+	}
+	if !h.claimDigestRequest(ctx, chatID, tgUserID) {
+		return
+	}
+	if _, ok := h.reserveManualRequest(chatID, user); !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	job := domain.DigestJob{
+		ID:          uuid.NewString(),
+		UserTGID:    tgUserID,
+		ChatID:      chatID,
+		Date:        day,
+		RequestedAt: now,
+		Cause:       domain.DigestCauseManual,
+		PastDay:     true,
+	}
+	if err := h.jobs.Enqueue(ctx, job); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Time("date", day).Msg("не удалось поставить задачу дайджеста за прошедший день")
+		h.reply(chatID, "Не удалось поставить дайджест в очередь, попробуйте позже", nil)
+		return
+	}
+
+	userID := user.ID
+	h.recordBusinessMetric(ctx, domain.BusinessMetric{
+		Event:  domain.BusinessMetricEventDigestRequested,
+		UserID: &userID,
+		Metadata: map[string]any{
+			"job_id":       job.ID,
+			"chat_id":      chatID,
+			"cause":        string(job.Cause),
+			"requested_at": job.RequestedAt,
+			"past_day":     day.Format("2006-01-02"),
+		},
+	})
+	metrics.IncDigestOverall()
+	metrics.IncDigestForUser(tgUserID)
+	h.reply(chatID, h.withQueueEstimate(ctx, fmt.Sprintf("Собираем дайджест за %s, отправим его в ближайшее время", day.Format("02.01.2006"))), nil)
+}
+
+// parsePastDigestDate разбирает дату YYYY-MM-DD и проверяет, что она не позже вчерашнего дня
+// и не раньше maxDays дней назад относительно now (во времени пользователя).
+// Возвращает полночь этого дня в UTC — так дата хранится в задаче.
+func parsePastDigestDate(payload string, now time.Time, maxDays int) (time.Time, error) {
+	parsed, err := time.Parse("2006-01-02", payload)
+	if err != nil {
+		return time.Time{}, errPastDateFormat
+	}
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if !parsed.Before(today) {
+		return time.Time{}, errPastDateToday
+	}
+	if parsed.Before(today.AddDate(0, 0, -maxDays)) {
+		return time.Time{}, errPastDateTooOld
+	}
+	return parsed, nil
+}
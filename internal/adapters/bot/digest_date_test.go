@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePastDigestDate(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	// 01:30 по времени пользователя — в UTC ещё предыдущий день.
+	now := time.Date(2024, 6, 1, 1, 30, 0, 0, loc)
+	cases := []struct {
+		payload string
+		want    time.Time
+		err     error
+	}{
+		{payload: "2024-05-31", want: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{payload: "2024-05-29", want: time.Date(2024, 5, 29, 0, 0, 0, 0, time.UTC)},
+		{payload: "2024-05-28", err: errPastDateTooOld},
+		{payload: "2024-06-01", err: errPastDateToday},
+		{payload: "2024-06-02", err: errPastDateToday},
+		{payload: "31.05.2024", err: errPastDateFormat},
+		{payload: "", err: errPastDateFormat},
+	}
+	for _, tc := range cases {
+		got, err := parsePastDigestDate(tc.payload, now, 3)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("%q: expected %v, got %v", tc.payload, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tc.want) {
+			t.Fatalf("%q: expected %s, got %s (%v)", tc.payload, tc.want, got, err)
+		}
+	}
+}
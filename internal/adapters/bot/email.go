@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const emailCodeTTL = 15 * time.Minute
+
+// handleEmail управляет доставкой дайджеста на почту:
+// /email set <адрес>, /email confirm <код>, /email mode telegram|email|both, /email off.
+func (h *Handler) handleEmail(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if h.emails == nil || h.mailer == nil {
+		h.reply(chatID, "Доставка на почту пока недоступна.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for email failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+
+	command, arg, _ := strings.Cut(payload, " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(command) {
+	case "":
+		h.reply(chatID, buildEmailStatusMessage(user), nil)
+	case "set":
+		h.handleEmailSet(ctx, chatID, user, arg)
+	case "confirm":
+		h.handleEmailConfirm(chatID, user, arg)
+	case "mode":
+		h.handleEmailMode(chatID, user, arg)
+	case "off":
+		if err := h.emails.ClearEmail(user.ID); err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: clear email failed")
+			h.reply(chatID, "Не удалось отключить почту. Попробуйте позже.", nil)
+			return
+		}
+		h.reply(chatID, "Почта отвязана, дайджесты снова приходят только в Telegram.", nil)
+	default:
+		h.reply(chatID, buildEmailStatusMessage(user), nil)
+	}
+}
+
+func (h *Handler) handleEmailSet(ctx context.Context, chatID int64, user domain.User, arg string) {
+	address, ok := parseEmailAddress(arg)
+	if !ok {
+		h.reply(chatID, "Укажите адрес: /email set you@example.com", nil)
+		return
+	}
+	code, err := generateConfirmationCode()
+	if err != nil {
+		h.log.Error().Err(err).Msg("bot: generate email code failed")
+		h.reply(chatID, "Не удалось отправить код. Попробуйте позже.", nil)
+		return
+	}
+	if err := h.emails.SetPendingEmail(user.ID, address, code, time.Now().UTC().Add(emailCodeTTL)); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: save pending email failed")
+		h.reply(chatID, "Не удалось сохранить адрес. Попробуйте позже.", nil)
+		return
+	}
+	body := fmt.Sprintf("<p>Код подтверждения почты: <b>%s</b></p><p>Отправьте боту команду <code>/email confirm %s</code>. Код действует %d минут.</p>", code, code, int(emailCodeTTL.Minutes()))
+	if err := h.mailer.Send(ctx, address, "Подтверждение почты для дайджестов", body); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: send email code failed")
+		h.reply(chatID, "Не удалось отправить письмо. Проверьте адрес и попробуйте ещё раз.", nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("Мы отправили код на %s. Пришлите его командой /email confirm <код>.", address), nil)
+}
+
+func (h *Handler) handleEmailConfirm(chatID int64, user domain.User, code string) {
+	if code == "" {
+		h.reply(chatID, "Формат: /email confirm <код>", nil)
+		return
+	}
+	address, err := h.emails.ConfirmEmail(user.ID, code, time.Now().UTC())
+	if errors.Is(err, domain.ErrEmailCodeInvalid) {
+		h.reply(chatID, "Код неверный или истёк. Запросите новый: /email set <адрес>.", nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: confirm email failed")
+		h.reply(chatID, "Не удалось подтвердить почту. Попробуйте позже.", nil)
+		return
+	}
+	if user.DeliveryMode == "" || user.DeliveryMode == domain.DeliveryTelegram {
+		if err := h.emails.SetDeliveryMode(user.ID, domain.DeliveryBoth); err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set delivery mode failed")
+		}
+	}
+	h.reply(chatID, fmt.Sprintf("Почта %s подтверждена. Дайджест будет приходить и в Telegram, и на почту. Изменить: /email mode telegram|email|both.", address), nil)
+}
+
+func (h *Handler) handleEmailMode(chatID int64, user domain.User, arg string) {
+	mode, ok := domain.ParseDeliveryMode(strings.ToLower(arg))
+	if !ok {
+		h.reply(chatID, "Формат: /email mode telegram|email|both", nil)
+		return
+	}
+	if mode != domain.DeliveryTelegram && !user.EmailVerified {
+		h.reply(chatID, "Сначала подтвердите почту: /email set you@example.com", nil)
+		return
+	}
+	if err := h.emails.SetDeliveryMode(user.ID, mode); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set delivery mode failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, "Режим доставки: "+deliveryModeLabel(mode)+".", nil)
+}
+
+func buildEmailStatusMessage(user domain.User) string {
+	lines := []string{"📧 Доставка на почту"}
+	if user.Email != "" && user.EmailVerified {
+		lines = append(lines, "Адрес: "+user.Email, "Режим: "+deliveryModeLabel(user.DeliveryMode))
+	} else {
+		lines = append(lines, "Почта не подключена.")
+	}
+	lines = append(lines,
+		"",
+		"• /email set you@example.com — привязать адрес (придёт код).",
+		"• /email confirm 123456 — подтвердить адрес.",
+		"• /email mode telegram|email|both — куда присылать дайджест.",
+		"• /email off — отвязать почту.",
+	)
+	return strings.Join(lines, "\n")
+}
+
+func deliveryModeLabel(mode domain.DeliveryMode) string {
+	switch mode {
+	case domain.DeliveryEmail:
+		return "только почта"
+	case domain.DeliveryBoth:
+		return "Telegram и почта"
+	default:
+		return "только Telegram"
+	}
+}
+
+func parseEmailAddress(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, "\r\n") {
+		return "", false
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return "", false
+	}
+	return strings.ToLower(addr.Address), true
+}
+
+func generateConfirmationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const (
+	findCallbackPrefix = "find:"
+	findPageSize       = 5
+	findSnippetLimit   = 160
+	// callbackDataLimit — ограничение Telegram на длину callback_data в байтах.
+	callbackDataLimit = 64
+)
+
+// handleFind ищет запрос по текстам постов из дайджестов, доставленных пользователю.
+func (h *Handler) handleFind(ctx context.Context, chatID, tgUserID int64, query string, offset int) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		h.reply(chatID, "Отправьте /find <запрос>, например /find ставка ЦБ", nil)
+		return
+	}
+	search, ok := h.digests.(domain.DigestSearchRepo)
+	if !ok {
+		h.reply(chatID, "Поиск по дайджестам сейчас недоступен.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	hits, err := search.SearchUserDigestPosts(ctx, user.ID, query, domain.DigestHistoryDays, findPageSize+1, offset)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Str("query", query).Msg("bot: search digest posts failed")
+		h.reply(chatID, "Не удалось выполнить поиск. Попробуйте позже.", nil)
+		return
+	}
+	hasNext := len(hits) > findPageSize
+	if hasNext {
+		hits = hits[:findPageSize]
+	}
+	h.reply(chatID, buildFindMessage(query, hits, offset), findPageKeyboard(query, offset, hasNext))
+}
+
+// buildFindMessage перечисляет найденные посты с датой дайджеста и ссылкой.
+func buildFindMessage(query string, hits []domain.DigestSearchHit, offset int) string {
+	if len(hits) == 0 {
+		if offset > 0 {
+			return "Больше совпадений нет."
+		}
+		return fmt.Sprintf("В дайджестах за последние %s ничего не нашлось по запросу «%s».",
+			pluralCount(domain.DigestHistoryDays, "день", "дня", "дней"), query)
+	}
+	lines := []string{fmt.Sprintf("🔎 Найдено в ваших дайджестах по запросу «%s»:", query)}
+	for i, hit := range hits {
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("%d. %s · %s", offset+i+1, hit.Date.Format("02.01.2006"), hit.Channel))
+		snippet := strings.TrimSpace(hit.Headline)
+		if snippet == "" {
+			snippet = strings.Join(strings.Fields(hit.Post.Text), " ")
+		}
+		if runes := []rune(snippet); len(runes) > findSnippetLimit {
+			snippet = string(runes[:findSnippetLimit]) + "…"
+		}
+		if snippet != "" {
+			lines = append(lines, snippet)
+		}
+		if hit.Post.URL != "" {
+			lines = append(lines, hit.Post.URL)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findPageKeyboard строит кнопки перехода между страницами результатов.
+// Запрос хранится в callback_data, поэтому для слишком длинных запросов кнопок нет.
+func findPageKeyboard(query string, offset int, hasNext bool) *tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if offset > 0 {
+		prev := offset - findPageSize
+		if prev < 0 {
+			prev = 0
+		}
+		if data, ok := findCallbackData(query, prev); ok {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", data))
+		}
+	}
+	if hasNext {
+		if data, ok := findCallbackData(query, offset+findPageSize); ok {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("Дальше ▶️", data))
+		}
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &markup
+}
+
+func findCallbackData(query string, offset int) (string, bool) {
+	data := findCallbackPrefix + strconv.Itoa(offset) + ":" + query
+	return data, len(data) <= callbackDataLimit
+}
+
+// parseFindCallback разбирает callback_data вида find:<offset>:<query>.
+func parseFindCallback(data string) (string, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(data, findCallbackPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+	offset, err := strconv.Atoi(parts[0])
+	if err != nil || offset < 0 {
+		return "", 0, false
+	}
+	return parts[1], offset, true
+}
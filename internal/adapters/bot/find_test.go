@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestBuildFindMessage(t *testing.T) {
+	hits := []domain.DigestSearchHit{
+		{Date: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), Channel: "Новости", Headline: "ЦБ сохранил ставку", Post: domain.Post{URL: "https://t.me/news/1"}},
+		{Date: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC), Channel: "@markets", Post: domain.Post{Text: "Рынки  ждут\nрешения"}},
+	}
+	msg := buildFindMessage("ставка", hits, 5)
+	for _, want := range []string{"«ставка»", "6. 12.10.2026 · Новости", "ЦБ сохранил ставку", "https://t.me/news/1", "7. 11.10.2026 · @markets", "Рынки ждут решения"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+	if msg := buildFindMessage("ставка", nil, 0); !strings.Contains(msg, "ничего не нашлось") {
+		t.Fatalf("expected empty-state message, got:\n%s", msg)
+	}
+}
+
+func TestFindPagination(t *testing.T) {
+	markup := findPageKeyboard("ставка", findPageSize, true)
+	if markup == nil || len(markup.InlineKeyboard[0]) != 2 {
+		t.Fatalf("expected back and next buttons, got %+v", markup)
+	}
+	next := *markup.InlineKeyboard[0][1].CallbackData
+	query, offset, ok := parseFindCallback(next)
+	if !ok || query != "ставка" || offset != 2*findPageSize {
+		t.Fatalf("unexpected callback %q parsed as %q, %d, %v", next, query, offset, ok)
+	}
+	if markup := findPageKeyboard(strings.Repeat("очень длинный запрос ", 5), 0, true); markup != nil {
+		t.Fatalf("expected no buttons for a query that does not fit callback data, got %+v", markup)
+	}
+	if markup := findPageKeyboard("ставка", 0, false); markup != nil {
+		t.Fatalf("expected no buttons on a single page, got %+v", markup)
+	}
+}
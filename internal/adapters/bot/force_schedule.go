@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/schedule"
+)
+
+// handleForceSchedule снимает сегодняшнюю бронь планировщика и сразу ставит дайджест
+// с причиной scheduled, чтобы проверить плановый путь без ожидания следующего дня.
+// Формат: /force_schedule [tg_id]; без аргумента — для самого разработчика.
+func (h *Handler) handleForceSchedule(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.scheduleTasks == nil {
+		h.reply(chatID, "Плановые задачи не настроены.", nil)
+		return
+	}
+	targetTGID := tgUserID
+	if payload != "" {
+		id, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil || id <= 0 {
+			h.reply(chatID, "Формат: /force_schedule [tg_id].", nil)
+			return
+		}
+		targetTGID = id
+	}
+	target, err := h.users.GetByTGID(targetTGID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Пользователь %d не найден.", targetTGID), nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	scheduledUTC, tzErr := schedule.TodaySlot(now, target)
+	if tzErr != nil {
+		h.log.Warn().Err(tzErr).Int64("target", targetTGID).Msg("bot: некорректный часовой пояс, используем UTC")
+	}
+	if err := h.scheduleTasks.ReleaseScheduleTask(target.ID, scheduledUTC); err != nil {
+		h.log.Error().Err(err).Int64("target", targetTGID).Msg("bot: release schedule task failed")
+		h.reply(chatID, "Не удалось снять бронь задачи. Попробуйте позже.", nil)
+		return
+	}
+	// Бронируем слот заново, чтобы планировщик не поставил второй дайджест, если окно ещё открыто.
+	acquired, err := h.scheduleTasks.AcquireScheduleTask(target.ID, scheduledUTC)
+	if err != nil {
+		h.log.Error().Err(err).Int64("target", targetTGID).Msg("bot: acquire schedule task failed")
+		h.reply(chatID, "Не удалось забронировать задачу. Попробуйте позже.", nil)
+		return
+	}
+	if !acquired {
+		h.reply(chatID, "Задачу на сегодня только что поставил планировщик — дождитесь дайджеста.", nil)
+		return
+	}
+
+	job := domain.DigestJob{
+		UserTGID:    target.TGUserID,
+		ChatID:      target.DigestChatID(),
+		Date:        scheduledUTC,
+		RequestedAt: now,
+		Cause:       domain.DigestCauseScheduled,
+	}
+	job.ID = uuid.NewString()
+	if err := h.jobs.Enqueue(ctx, job); err != nil {
+		h.log.Error().Err(err).Int64("target", targetTGID).Msg("bot: force schedule enqueue failed")
+		if relErr := h.scheduleTasks.ReleaseScheduleTask(target.ID, scheduledUTC); relErr != nil {
+			h.log.Error().Err(relErr).Int64("target", targetTGID).Msg("bot: release schedule task failed")
+		}
+		h.reply(chatID, "Не удалось поставить дайджест в очередь, попробуйте позже", nil)
+		return
+	}
+
+	userID := target.ID
+	h.recordBusinessMetric(ctx, domain.BusinessMetric{
+		Event:  domain.BusinessMetricEventDigestScheduled,
+		UserID: &userID,
+		Metadata: map[string]any{
+			"job_id":        job.ID,
+			"scheduled_for": scheduledUTC,
+			"requested_at":  job.RequestedAt,
+			"cause":         string(job.Cause),
+			"forced_by":     tgUserID,
+		},
+	})
+	h.log.Info().Int64("admin", tgUserID).Int64("target", targetTGID).Time("scheduled_for", scheduledUTC).Str("job", job.ID).Msg("bot: scheduled digest forced")
+	h.reply(chatID, fmt.Sprintf("Плановый дайджест для %d поставлен в очередь (слот %s UTC).", targetTGID, scheduledUTC.Format("2006-01-02 15:04")), nil)
+}
@@ -8,7 +8,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -19,12 +18,14 @@ import (
 	"tg-digest-bot/internal/domain"
 	"tg-digest-bot/internal/infra/metrics"
 	"tg-digest-bot/internal/usecase/channels"
+	digestusecase "tg-digest-bot/internal/usecase/digest"
 	"tg-digest-bot/internal/usecase/schedule"
 )
 
 // Handler обслуживает вебхук бота.
 type Handler struct {
-	bot             *tgbotapi.BotAPI
+	bot             sender
+	self            tgbotapi.User
 	log             zerolog.Logger
 	channelUC       *channels.Service
 	scheduleUC      *schedule.Service
@@ -34,35 +35,126 @@ type Handler struct {
 	jobs            domain.DigestQueue
 	analytics       domain.BusinessMetricRepo
 	feedback        domain.FeedbackRepo
+	digests         domain.DigestRepo
+	posts           domain.PostRepo
+	activations     domain.PlanActivationRepo
+	emails          domain.EmailRepo
+	mailer          domain.EmailSender
+	mtprotoAccounts domain.MTProtoAccountRepo
+	scheduleTasks   domain.ScheduleTaskRepo
+	mtprotoPool     string
+	layout          *digestusecase.Layout
 	maxDigest       int
-	mu              sync.Mutex
-	pendingDrop     map[int64]time.Time
-	pendingTime     map[int64]struct{}
-	pendingTZ       map[int64]struct{}
-	pendingFeedback map[int64]struct{}
+	leaderboard     bool
+	pending         pendingStore
 	offers          map[string]subscriptionOffer
+	topUpPresets    []int64
+	topUpMin        int64
+	topUpMax        int64
+	lowBalance      int64
+	promos          domain.PromoRepo
+	clearWindow     time.Duration
+	usage           domain.UsageRepo
+	tokenCaps       map[domain.UserRole]int64
+	inflight        domain.SeenStore
+	resolveLimit    domain.SeenStore
+}
+
+// Deps — зависимости и настройки обработчика. Необязательные зависимости можно не задавать:
+// nil выключает связанную с ними возможность.
+type Deps struct {
+	Bot             sender
+	Self            tgbotapi.User
+	Log             zerolog.Logger
+	Channels        *channels.Service
+	Schedule        *schedule.Service
+	Users           domain.UserRepo
+	Billing         domain.Billing
+	SBP             domain.BillingSBP
+	Jobs            domain.DigestQueue
+	Analytics       domain.BusinessMetricRepo
+	Feedback        domain.FeedbackRepo
+	Digests         domain.DigestRepo
+	Posts           domain.PostRepo
+	Activations     domain.PlanActivationRepo
+	Emails          domain.EmailRepo
+	Mailer          domain.EmailSender
+	MTProtoAccounts domain.MTProtoAccountRepo
+	ScheduleTasks   domain.ScheduleTaskRepo
+	// Promos включает /promo и /promo_create.
+	Promos domain.PromoRepo
+	// Usage и TokenCaps показывают в /whoami месячный расход LLM и лимит тарифа
+	// (см. OPENAI_MONTHLY_TOKEN_CAP_BY_PLAN).
+	Usage     domain.UsageRepo
+	TokenCaps map[domain.UserRole]int64
+	// InFlight включает защиту от двойного нажатия при ручном запросе дайджеста.
+	// Между инстансами бота защита работает, только если хранилище общее.
+	InFlight domain.SeenStore
+	// ResolveLimit разрешает inline-режиму резолвить незнакомые алиасы через MTProto,
+	// не чаще раза в inlineResolveInterval на пользователя. Без него ищутся только известные каналы.
+	ResolveLimit domain.SeenStore
+
+	MTProtoPool string
+	Layout      *digestusecase.Layout
+	MaxDigest   int
+	Leaderboard bool
+	// TopUpPresets — кнопки быстрого пополнения в копейках; nil — стандартный набор.
+	TopUpPresets []int64
+	// TopUpMin и TopUpMax — допустимая сумма пополнения в копейках; TopUpMax = 0 снимает
+	// верхнюю границу, TopUpMin = 0 — стандартный минимум.
+	TopUpMin int64
+	TopUpMax int64
+	// LowBalance включает предупреждение, когда после списания на счёте остаётся меньше
+	// стольких копеек; 0 отключает его.
+	LowBalance int64
+	// ClearDataWindow — сколько действует запрос /clear_data до подтверждения.
+	ClearDataWindow time.Duration
 }
 
 // NewHandler создаёт обработчик.
-func NewHandler(bot *tgbotapi.BotAPI, log zerolog.Logger, channelUC *channels.Service, scheduleUC *schedule.Service, userRepo domain.UserRepo, billing domain.Billing, sbpService domain.BillingSBP, jobs domain.DigestQueue, metricsRepo domain.BusinessMetricRepo, feedbackRepo domain.FeedbackRepo, maxDigest int) *Handler {
-	return &Handler{
-		bot:             bot,
-		log:             log,
-		channelUC:       channelUC,
-		scheduleUC:      scheduleUC,
-		users:           userRepo,
-		billing:         billing,
-		sbp:             sbpService,
-		jobs:            jobs,
-		analytics:       metricsRepo,
-		feedback:        feedbackRepo,
-		maxDigest:       maxDigest,
-		pendingDrop:     make(map[int64]time.Time),
-		pendingTime:     make(map[int64]struct{}),
-		pendingTZ:       make(map[int64]struct{}),
-		pendingFeedback: make(map[int64]struct{}),
+func NewHandler(deps Deps) *Handler {
+	h := &Handler{
+		bot:             deps.Bot,
+		self:            deps.Self,
+		log:             deps.Log,
+		channelUC:       deps.Channels,
+		scheduleUC:      deps.Schedule,
+		users:           deps.Users,
+		billing:         deps.Billing,
+		sbp:             deps.SBP,
+		jobs:            deps.Jobs,
+		analytics:       deps.Analytics,
+		feedback:        deps.Feedback,
+		digests:         deps.Digests,
+		posts:           deps.Posts,
+		activations:     deps.Activations,
+		emails:          deps.Emails,
+		mailer:          deps.Mailer,
+		mtprotoAccounts: deps.MTProtoAccounts,
+		scheduleTasks:   deps.ScheduleTasks,
+		promos:          deps.Promos,
+		usage:           deps.Usage,
+		tokenCaps:       deps.TokenCaps,
+		inflight:        deps.InFlight,
+		resolveLimit:    deps.ResolveLimit,
+		mtprotoPool:     deps.MTProtoPool,
+		layout:          deps.Layout,
+		maxDigest:       deps.MaxDigest,
+		leaderboard:     deps.Leaderboard,
 		offers:          defaultSubscriptionOffers(),
+		topUpPresets:    deps.TopUpPresets,
+		topUpMin:        deps.TopUpMin,
+		topUpMax:        deps.TopUpMax,
+		lowBalance:      deps.LowBalance,
+		clearWindow:     deps.ClearDataWindow,
 	}
+	if h.topUpPresets == nil {
+		h.topUpPresets = defaultTopUpPresets
+	}
+	if h.topUpMin <= 0 {
+		h.topUpMin = defaultTopUpMin
+	}
+	return h
 }
 
 // HandleUpdate обрабатывает входящий апдейт.
@@ -71,6 +163,10 @@ func (h *Handler) HandleUpdate(ctx context.Context, upd tgbotapi.Update) {
 		h.handleMessage(ctx, upd.Message)
 	} else if upd.CallbackQuery != nil {
 		h.handleCallback(ctx, upd.CallbackQuery)
+	} else if upd.InlineQuery != nil {
+		h.handleInlineQuery(ctx, upd.InlineQuery)
+	} else if upd.ChosenInlineResult != nil {
+		h.handleChosenInlineResult(ctx, upd.ChosenInlineResult)
 	}
 }
 
@@ -86,12 +182,153 @@ func (h *Handler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 		if h.tryHandleScheduleInput(ctx, msg.Chat.ID, msg.From.ID, text) {
 			return
 		}
+		if h.tryHandleTagInput(ctx, msg.Chat.ID, msg.From.ID, text) {
+			return
+		}
+		if h.tryHandleSetChatInput(ctx, msg.Chat.ID, msg.From.ID, text) {
+			return
+		}
 	}
 	switch {
 	case strings.HasPrefix(text, "/start"):
 		h.handleStart(ctx, msg)
 	case strings.HasPrefix(text, "/help"):
 		h.handleHelp(msg.Chat.ID)
+	case strings.HasPrefix(text, "/guide"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleGuide(msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/cancel"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleCancel(msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/grant_channels"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/grant_channels"))
+		h.handleGrantChannels(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/grant_requests"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/grant_requests"))
+		h.handleGrantRequests(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/mtproto_pools"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleMTProtoPools(ctx, msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/channel_pool"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/channel_pool"))
+		h.handleChannelPool(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/mtproto_list"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleMTProtoList(ctx, msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/mtproto_enable"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(text, "/mtproto_enable"))
+		h.handleMTProtoToggle(ctx, msg.Chat.ID, msg.From.ID, name, true)
+	case strings.HasPrefix(text, "/mtproto_disable"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(text, "/mtproto_disable"))
+		h.handleMTProtoToggle(ctx, msg.Chat.ID, msg.From.ID, name, false)
+	case strings.HasPrefix(text, "/force_schedule"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/force_schedule"))
+		h.handleForceSchedule(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/referral_approve"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/referral_approve"))
+		h.handleReferralApprove(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/collect_only"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/collect_only"))
+		h.handleCollectOnly(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/filter_lang"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/filter_lang"))
+		h.handleFilterLang(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/link_previews"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/link_previews"))
+		h.handleLinkPreviews(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/comments"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/comments"))
+		h.handleComments(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/setchat"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/setchat"))
+		h.handleSetChat(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/order"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/order"))
+		h.handleOrder(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/filter_ads"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/filter_ads"))
+		h.handleFilterAds(msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/email"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/email"))
+		h.handleEmail(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/whoami"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleWhoAmI(ctx, msg.Chat.ID, msg.From.ID)
 	case strings.HasPrefix(text, "/timezone"):
 		if msg.From == nil {
 			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
@@ -112,6 +349,27 @@ func (h *Handler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 		}
 		amount := strings.TrimSpace(strings.TrimPrefix(text, "/deposit"))
 		h.handleDeposit(ctx, msg.Chat.ID, msg.From.ID, amount)
+	case strings.HasPrefix(text, "/invoice"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/invoice"))
+		h.handleInvoiceStatus(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/promo_create"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/promo_create"))
+		h.handlePromoCreate(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/promo"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/promo"))
+		h.handlePromo(ctx, msg.Chat.ID, msg.From.ID, payload)
 	case strings.HasPrefix(text, "/buy"):
 		if msg.From == nil {
 			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
@@ -119,13 +377,77 @@ func (h *Handler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 		}
 		plan := strings.TrimSpace(strings.TrimPrefix(text, "/buy"))
 		h.handleBuySubscription(ctx, msg.Chat.ID, msg.From.ID, plan)
+	case strings.HasPrefix(text, "/find"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		query := strings.TrimSpace(strings.TrimPrefix(text, "/find"))
+		h.handleFind(ctx, msg.Chat.ID, msg.From.ID, query, 0)
+	case strings.HasPrefix(text, "/search"):
+		query := strings.TrimSpace(strings.TrimPrefix(text, "/search"))
+		h.handleSearch(ctx, msg.Chat.ID, msg.From.ID, query)
 	case strings.HasPrefix(text, "/add"):
 		alias := strings.TrimSpace(strings.TrimPrefix(text, "/add"))
 		h.handleAdd(ctx, msg.Chat.ID, msg.From.ID, alias)
+	case strings.HasPrefix(text, "/cache"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/cache"))
+		h.handleCache(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/peek"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		alias := strings.TrimSpace(strings.TrimPrefix(text, "/peek"))
+		h.handlePeek(ctx, msg.Chat.ID, msg.From.ID, alias)
+	case strings.HasPrefix(text, "/check"):
+		alias := strings.TrimSpace(strings.TrimPrefix(text, "/check"))
+		h.handleCheck(ctx, msg.Chat.ID, alias)
 	case strings.HasPrefix(text, "/list"):
 		h.handleList(ctx, msg.Chat.ID, msg.From.ID)
 	case strings.HasPrefix(text, "/digest_now"):
 		h.handleDigestNow(ctx, msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/digest_date"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/digest_date"))
+		h.handleDigestDate(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/pause"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handlePause(msg.Chat.ID, msg.From.ID, strings.TrimPrefix(text, "/pause"))
+	case strings.HasPrefix(text, "/resume"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleResume(msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/next"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleNext(ctx, msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/resend"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleResendList(msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/last"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleLast(msg.Chat.ID, msg.From.ID)
 	case strings.HasPrefix(text, "/schedule"):
 		if msg.From == nil {
 			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
@@ -158,10 +480,25 @@ func (h *Handler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 		}
 		payload := strings.TrimSpace(strings.TrimPrefix(text, "/feedback"))
 		h.handleFeedback(ctx, msg.Chat.ID, msg.From.ID, payload)
-	case strings.HasPrefix(text, "/clear_data"):
-		h.handleClearRequest(msg.Chat.ID, msg.From.ID)
+	case strings.HasPrefix(text, "/leaderboard") && h.leaderboard:
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleLeaderboard(msg.Chat.ID, msg.From.ID)
 	case strings.HasPrefix(text, "/clear_data_confirm"):
-		h.handleClearConfirm(ctx, msg.Chat.ID, msg.From.ID)
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(text, "/clear_data_confirm"))
+		h.handleClearConfirm(ctx, msg.Chat.ID, msg.From.ID, payload)
+	case strings.HasPrefix(text, "/clear_data"):
+		if msg.From == nil {
+			h.reply(msg.Chat.ID, "Не удалось определить пользователя", nil)
+			return
+		}
+		h.handleClearRequest(ctx, msg.Chat.ID, msg.From.ID)
 	default:
 		h.reply(msg.Chat.ID, "Неизвестная команда. Используйте /help", nil)
 	}
@@ -201,6 +538,12 @@ func (h *Handler) handleStart(ctx context.Context, msg *tgbotapi.Message) {
 		h.reply(msg.Chat.ID, fmt.Sprintf("Ошибка сохранения профиля: %v", err), nil)
 		return
 	}
+	if !created {
+		// /start означает, что пользователь снова пишет боту: возвращаем доставку, если её отключил collector.
+		if err := h.users.SetDeliveryDisabled(user.ID, false); err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: re-enable delivery failed")
+		}
+	}
 	payload := ""
 	if msg.Text != "" {
 		fields := strings.Fields(msg.Text)
@@ -216,22 +559,26 @@ func (h *Handler) handleStart(ctx context.Context, msg *tgbotapi.Message) {
 		} else {
 			user = result.User
 			referralResult = result
+			if result.Flagged && result.Referrer != nil {
+				h.log.Warn().Int64("user", user.ID).Int64("referrer", result.Referrer.ID).Msg("bot: referral flagged for review")
+				userID := user.ID
+				h.recordBusinessMetric(ctx, domain.BusinessMetric{
+					Event:  domain.BusinessMetricEventReferralFlagged,
+					UserID: &userID,
+					Metadata: map[string]any{
+						"referrer_id": result.Referrer.ID,
+						"tg_user_id":  user.TGUserID,
+						"is_bot":      user.IsBot,
+					},
+				})
+			}
 		}
 	}
 
-	sections := h.buildStartSections(user)
-	for i, section := range sections {
-		if strings.TrimSpace(section) == "" {
-			continue
-		}
-		if i == 0 {
-			h.reply(msg.Chat.ID, section, h.mainKeyboard())
-			continue
-		}
-		h.reply(msg.Chat.ID, section, nil)
-	}
+	h.replySections(msg.Chat.ID, h.buildStartSections(user))
 
-	if strings.TrimSpace(user.Timezone) == "" {
+	// Новым пользователям пояс назначен по умолчанию — просим подтвердить его или выбрать свой.
+	if created || strings.TrimSpace(user.Timezone) == "" {
 		h.promptTimezone(msg.Chat.ID, msg.From.ID, user.Timezone)
 	}
 
@@ -244,6 +591,406 @@ func (h *Handler) handleHelp(chatID int64) {
 	h.reply(chatID, h.buildHelpMessage(), h.mainKeyboard())
 }
 
+func (h *Handler) handleCancel(chatID, tgUserID int64) {
+	if h.cancelPending(chatID, tgUserID) {
+		h.reply(chatID, "Отменено.", h.mainKeyboard())
+		return
+	}
+	h.reply(chatID, "Отменено. Активных действий не было.", h.mainKeyboard())
+}
+
+// cancelPending сбрасывает все ожидающие ввода состояния пользователя.
+// Возвращает true, если хотя бы одно состояние было активно.
+func (h *Handler) cancelPending(chatID, tgUserID int64) bool {
+	active := false
+	h.pending.with(tgUserID, func(st *pendingState) {
+		active = !st.dropExpires.IsZero() || st.awaitingTime || st.awaitingTZ || st.editingTags != 0 || st.bindChat != 0
+		st.dropExpires = time.Time{}
+		st.awaitingTime, st.timeAttempts = false, 0
+		st.awaitingTZ = false
+		st.editingTags = 0
+		st.bindChat, st.bindCode, st.bindExpires = 0, "", time.Time{}
+	})
+	h.pending.with(chatID, func(st *pendingState) {
+		active = active || st.awaitingFB
+		st.awaitingFB = false
+	})
+	return active
+}
+
+func (h *Handler) handleWhoAmI(ctx context.Context, chatID, tgUserID int64) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	channelCount, err := h.channelUC.CountChannels(ctx, tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: count channels for whoami failed")
+		channelCount = -1
+	}
+	now := time.Now().UTC()
+	var llm *llmUsageSummary
+	if h.usage != nil {
+		usage, err := h.usage.GetUsage(ctx, user.ID, now)
+		if err != nil {
+			h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: get llm usage for whoami failed")
+		} else {
+			llm = &llmUsageSummary{Tokens: usage.TotalTokens(), Cap: h.tokenCaps[user.Plan().Role]}
+		}
+	}
+	h.reply(chatID, buildWhoAmIMessage(user, channelCount, llm, now), nil)
+}
+
+// llmUsageSummary — расход токенов LLM за текущий месяц и лимит тарифа (0 — без ограничений).
+type llmUsageSummary struct {
+	Tokens int64
+	Cap    int64
+}
+
+// handleFilterAds включает или выключает отсев рекламных постов в дайджестах.
+func (h *Handler) handleFilterAds(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for filter_ads failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	var enabled bool
+	switch strings.ToLower(payload) {
+	case "on", "вкл":
+		enabled = true
+	case "off", "выкл":
+		enabled = false
+	case "":
+		state := "выключен"
+		if user.FilterAds {
+			state = "включён"
+		}
+		h.reply(chatID, fmt.Sprintf("Фильтр рекламы %s. Используйте /filter_ads on или /filter_ads off.", state), nil)
+		return
+	default:
+		h.reply(chatID, "Формат: /filter_ads on или /filter_ads off.", nil)
+		return
+	}
+	if err := h.users.SetFilterAds(user.ID, enabled); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set filter_ads failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	if enabled {
+		h.reply(chatID, "Фильтр рекламы включён: посты с пометками #реклама, erid и партнёрскими ссылками не попадут в дайджест.", nil)
+		return
+	}
+	h.reply(chatID, "Фильтр рекламы выключен.", nil)
+}
+
+// handleLinkPreviews включает или выключает отдельное сообщение с превью для главного пункта дайджеста.
+func (h *Handler) handleLinkPreviews(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for link_previews failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	var enabled bool
+	switch strings.ToLower(payload) {
+	case "on", "вкл":
+		enabled = true
+	case "off", "выкл":
+		enabled = false
+	case "":
+		state := "выключено"
+		if user.LinkPreviews {
+			state = "включено"
+		}
+		h.reply(chatID, fmt.Sprintf("Превью главного поста %s. Используйте /link_previews on или /link_previews off.", state), nil)
+		return
+	default:
+		h.reply(chatID, "Формат: /link_previews on или /link_previews off.", nil)
+		return
+	}
+	if err := h.users.SetLinkPreviews(user.ID, enabled); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set link_previews failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	if enabled {
+		h.reply(chatID, "Превью включено: главный пост дайджеста будет приходить отдельным сообщением с карточкой ссылки.", nil)
+		return
+	}
+	h.reply(chatID, "Превью выключено: дайджест снова приходит компактно, без карточек ссылок.", nil)
+}
+
+// handleFilterLang задаёт язык постов в дайджесте: on — язык Telegram, код языка — явно, off — выключить.
+func (h *Handler) handleFilterLang(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for filter_lang failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	var lang string
+	switch strings.ToLower(payload) {
+	case "":
+		if user.DigestLang == "" {
+			h.reply(chatID, "Фильтр языка выключен: в дайджест попадают посты на любом языке. Включить — /filter_lang on или /filter_lang en.", nil)
+			return
+		}
+		h.reply(chatID, fmt.Sprintf("В дайджест попадают только посты на языке %s. Выключить — /filter_lang off.", user.DigestLang), nil)
+		return
+	case "off", "выкл":
+		lang = ""
+	case "on", "вкл":
+		lang = digestusecase.NormalizeLang(user.Locale)
+		if !digestusecase.IsSupportedLang(lang) {
+			h.reply(chatID, "Не удалось определить ваш язык по настройкам Telegram. Укажите его явно, например /filter_lang ru.", nil)
+			return
+		}
+	default:
+		lang = digestusecase.NormalizeLang(payload)
+		if !digestusecase.IsSupportedLang(lang) {
+			h.reply(chatID, "Поддерживаются языки: ru, uk, en, de, fr, es, it, pt. Формат: /filter_lang ru или /filter_lang off.", nil)
+			return
+		}
+	}
+	if err := h.users.SetDigestLang(user.ID, lang); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set digest_lang failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	if lang == "" {
+		h.reply(chatID, "Фильтр языка выключен.", nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("Фильтр языка включён: в дайджест попадут посты на языке %s. Посты, язык которых не распознан, остаются.", lang), nil)
+}
+
+// handleGrantChannels задаёт пользователю индивидуальный лимит каналов.
+// Формат: /grant_channels <tg_id> <n>, где n=0 — без ограничений, reset — вернуть лимит тарифа.
+func (h *Handler) handleGrantChannels(chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	targetTGID, limit, err := parseGrantChannelsArgs(payload)
+	if err != nil {
+		h.reply(chatID, "Формат: /grant_channels <tg_id> <n>. 0 — без ограничений, reset — лимит по тарифу.", nil)
+		return
+	}
+	target, err := h.users.GetByTGID(targetTGID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Пользователь %d не найден.", targetTGID), nil)
+		return
+	}
+	if err := h.users.SetChannelLimitOverride(target.ID, limit); err != nil {
+		h.log.Error().Err(err).Int64("target", targetTGID).Msg("bot: set channel limit override failed")
+		h.reply(chatID, "Не удалось сохранить лимит. Попробуйте позже.", nil)
+		return
+	}
+	h.log.Info().Int64("admin", tgUserID).Int64("target", targetTGID).Interface("limit", limit).Msg("bot: channel limit override updated")
+	switch {
+	case limit == nil:
+		h.reply(chatID, fmt.Sprintf("Пользователю %d возвращён лимит тарифа %s.", targetTGID, target.Plan().Name), nil)
+	case *limit == 0:
+		h.reply(chatID, fmt.Sprintf("Пользователю %d сняты ограничения по каналам.", targetTGID), nil)
+	default:
+		h.reply(chatID, fmt.Sprintf("Пользователю %d установлен лимит: %s.", targetTGID, pluralCount(*limit, "канал", "канала", "каналов")), nil)
+	}
+}
+
+func parseGrantChannelsArgs(payload string) (int64, *int, error) {
+	fields := strings.Fields(payload)
+	if len(fields) != 2 {
+		return 0, nil, fmt.Errorf("ожидалось два аргумента")
+	}
+	targetTGID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || targetTGID <= 0 {
+		return 0, nil, fmt.Errorf("некорректный tg_id")
+	}
+	if strings.EqualFold(fields[1], "reset") {
+		return targetTGID, nil, nil
+	}
+	limit, err := strconv.Atoi(fields[1])
+	if err != nil || limit < 0 {
+		return 0, nil, fmt.Errorf("некорректный лимит")
+	}
+	return targetTGID, &limit, nil
+}
+
+// handleGrantRequests сбрасывает или пополняет сегодняшнюю квоту ручных дайджестов пользователя.
+// Формат: /grant_requests <tg_id> <n>, где n=0 или reset — обнулить счётчик, n>0 — выдать n запросов сверх лимита.
+func (h *Handler) handleGrantRequests(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	targetTGID, extra, err := parseGrantRequestsArgs(payload)
+	if err != nil {
+		h.reply(chatID, "Формат: /grant_requests <tg_id> <n>. 0 или reset — обнулить счётчик за сегодня, n — выдать n запросов.", nil)
+		return
+	}
+	target, err := h.users.GetByTGID(targetTGID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Пользователь %d не найден.", targetTGID), nil)
+		return
+	}
+	state, err := h.users.GrantManualRequests(target.ID, extra, time.Now())
+	if err != nil {
+		h.log.Error().Err(err).Int64("target", targetTGID).Msg("bot: grant manual requests failed")
+		h.reply(chatID, "Не удалось изменить квоту. Попробуйте позже.", nil)
+		return
+	}
+	h.log.Info().Int64("admin", tgUserID).Int64("target", targetTGID).Int("extra", extra).Msg("bot: manual requests granted")
+	targetID := target.ID
+	h.recordBusinessMetric(ctx, domain.BusinessMetric{
+		Event:  domain.BusinessMetricEventManualRequestsGranted,
+		UserID: &targetID,
+		Metadata: map[string]any{
+			"admin_tg_id": tgUserID,
+			"extra":       extra,
+			"used_today":  state.UsedToday,
+		},
+	})
+
+	action := "сброшен счётчик ручных запросов за сегодня"
+	if extra > 0 {
+		action = "выдано дополнительно " + pluralCount(extra, "запрос", "запроса", "запросов")
+	}
+	remaining := "без ограничений"
+	if left := state.RemainingToday(); left >= 0 {
+		remaining = strconv.Itoa(left)
+	}
+	h.reply(chatID, fmt.Sprintf("Пользователю %d %s. Осталось сегодня: %s.", targetTGID, action, remaining), nil)
+}
+
+func parseGrantRequestsArgs(payload string) (int64, int, error) {
+	fields := strings.Fields(payload)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("ожидалось два аргумента")
+	}
+	targetTGID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || targetTGID <= 0 {
+		return 0, 0, fmt.Errorf("некорректный tg_id")
+	}
+	if strings.EqualFold(fields[1], "reset") {
+		return targetTGID, 0, nil
+	}
+	extra, err := strconv.Atoi(fields[1])
+	if err != nil || extra < 0 || extra > maxGrantedRequests {
+		return 0, 0, fmt.Errorf("некорректное количество")
+	}
+	return targetTGID, extra, nil
+}
+
+// buildWhoAmIMessage собирает сводку эффективных настроек пользователя.
+// Для роли developer дополнительно выводятся внутренние идентификаторы; llm == nil скрывает расход LLM.
+func buildWhoAmIMessage(user domain.User, channelCount int, llm *llmUsageSummary, now time.Time) string {
+	plan := user.Plan()
+	tz := strings.TrimSpace(user.Timezone)
+	if tz == "" {
+		tz = fmt.Sprintf("%s (по умолчанию)", domain.DefaultTimezone())
+	}
+
+	channels := "н/д"
+	if channelCount >= 0 {
+		channels = strconv.Itoa(channelCount)
+	}
+	if limit := user.ChannelLimit(); limit > 0 {
+		channels = fmt.Sprintf("%s из %d", channels, limit)
+	}
+	if user.ChannelLimitOverride != nil {
+		if *user.ChannelLimitOverride == 0 {
+			channels += " (индивидуально: без ограничений)"
+		} else {
+			channels += " (индивидуальный лимит)"
+		}
+	}
+
+	usedToday := 0
+	if user.ManualRequestsDate != nil {
+		y1, d1 := user.ManualRequestsDate.UTC().Year(), user.ManualRequestsDate.UTC().YearDay()
+		y2, d2 := now.UTC().Year(), now.UTC().YearDay()
+		if y1 == y2 && d1 == d2 {
+			usedToday = user.ManualRequestsToday
+		}
+	}
+	manual := fmt.Sprintf("%d сегодня, %d всего", usedToday, user.ManualRequestsTotal)
+	switch {
+	case plan.ManualDailyLimit <= 0:
+		manual += " (без ограничений)"
+	case plan.ManualIntroTotal > 0 && user.ManualRequestsTotal < plan.ManualIntroTotal:
+		manual += fmt.Sprintf(" (стартовых осталось %d)", plan.ManualIntroTotal-user.ManualRequestsTotal)
+	default:
+		manual += fmt.Sprintf(" (лимит %d в день)", plan.ManualDailyLimit)
+	}
+
+	adFilterState := "выключен"
+	if user.FilterAds {
+		adFilterState = "включён"
+	}
+	previewState := "выключено"
+	if user.LinkPreviews {
+		previewState = "включено"
+	}
+	langFilterState := "любой"
+	if user.DigestLang != "" {
+		langFilterState = user.DigestLang
+	}
+
+	deliveryTime := user.DailyTime.Format("15:04")
+	if user.IsPaused(now) {
+		deliveryTime += " (на паузе без срока)"
+		if user.PausedUntil != nil {
+			loc, err := time.LoadLocation(user.TimezoneName())
+			if err != nil {
+				loc = time.UTC
+			}
+			deliveryTime = fmt.Sprintf("%s (на паузе до %s)", user.DailyTime.Format("15:04"), user.PausedUntil.In(loc).Format("02.01 15:04"))
+		}
+	}
+
+	lines := []string{
+		"🪪 Ваш профиль:",
+		fmt.Sprintf("• Тариф: %s", plan.Name),
+		fmt.Sprintf("• Часовой пояс: %s", tz),
+		fmt.Sprintf("• Время рассылки: %s", deliveryTime),
+		fmt.Sprintf("• Каналы: %s", channels),
+		fmt.Sprintf("• Ручные дайджесты: %s", manual),
+		fmt.Sprintf("• Фильтр рекламы: %s", adFilterState),
+		fmt.Sprintf("• Язык постов: %s", langFilterState),
+		fmt.Sprintf("• Превью главного поста: %s", previewState),
+		fmt.Sprintf("• Комментарии к постам: %s", commentsState(user.WantsComments())),
+		fmt.Sprintf("• Порядок пунктов: %s", digestOrderLabel(user.DigestOrder)),
+		fmt.Sprintf("• Чат дайджестов: %s", deliveryChatLabel(user.DeliveryChatID)),
+		fmt.Sprintf("• Приглашено: %s", pluralCount(user.ReferralsCount, "друг", "друга", "друзей")),
+	}
+	if llm != nil {
+		line := fmt.Sprintf("• Расширенные сводки: %d токенов за месяц", llm.Tokens)
+		switch {
+		case llm.Cap <= 0:
+			line += " (без ограничений)"
+		case llm.Tokens >= llm.Cap:
+			line = fmt.Sprintf("• Расширенные сводки: лимит %d токенов исчерпан, до 1-го числа дайджесты упрощённые", llm.Cap)
+		default:
+			line = fmt.Sprintf("• Расширенные сводки: %d из %d токенов за месяц", llm.Tokens, llm.Cap)
+		}
+		lines = append(lines, line)
+	}
+	if plan.Role == domain.UserRoleDeveloper {
+		lines = append(lines,
+			"",
+			"Служебное:",
+			fmt.Sprintf("• user_id: %d", user.ID),
+			fmt.Sprintf("• tg_user_id: %d", user.TGUserID),
+			fmt.Sprintf("• role: %s", user.Role),
+			fmt.Sprintf("• locale: %s", user.Locale),
+		)
+		if user.ReferredByID != nil {
+			lines = append(lines, fmt.Sprintf("• referred_by: %d", *user.ReferredByID))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (h *Handler) handleFeedback(ctx context.Context, chatID, tgUserID int64, payload string) {
 	if tgUserID == 0 {
 		h.reply(chatID, "Не удалось определить пользователя", nil)
@@ -251,9 +998,7 @@ func (h *Handler) handleFeedback(ctx context.Context, chatID, tgUserID int64, pa
 	}
 	message := strings.TrimSpace(payload)
 	if message == "" {
-		h.mu.Lock()
-		h.pendingFeedback[chatID] = struct{}{}
-		h.mu.Unlock()
+		h.setPendingFeedback(chatID)
 		h.reply(chatID, "Напишите ваш отзыв одним сообщением, и я обязательно передам его команде.", nil)
 		return
 	}
@@ -261,12 +1006,10 @@ func (h *Handler) handleFeedback(ctx context.Context, chatID, tgUserID int64, pa
 }
 
 func (h *Handler) tryHandleFeedbackInput(ctx context.Context, chatID, tgUserID int64, text string) bool {
-	h.mu.Lock()
-	_, pending := h.pendingFeedback[chatID]
-	if pending {
-		delete(h.pendingFeedback, chatID)
-	}
-	h.mu.Unlock()
+	pending := false
+	h.pending.with(chatID, func(st *pendingState) {
+		pending, st.awaitingFB = st.awaitingFB, false
+	})
 	if !pending {
 		return false
 	}
@@ -274,12 +1017,14 @@ func (h *Handler) tryHandleFeedbackInput(ctx context.Context, chatID, tgUserID i
 	return true
 }
 
+func (h *Handler) setPendingFeedback(chatID int64) {
+	h.pending.with(chatID, func(st *pendingState) { st.awaitingFB = true })
+}
+
 func (h *Handler) submitFeedback(ctx context.Context, chatID, tgUserID int64, text string) {
 	message := strings.TrimSpace(text)
 	if message == "" {
-		h.mu.Lock()
-		h.pendingFeedback[chatID] = struct{}{}
-		h.mu.Unlock()
+		h.setPendingFeedback(chatID)
 		h.reply(chatID, "Отзыв не может быть пустым. Попробуйте ещё раз.", nil)
 		return
 	}
@@ -366,9 +1111,14 @@ func (h *Handler) handleDeposit(ctx context.Context, chatID, tgUserID int64, pay
 		h.reply(chatID, "Укажите сумму в рублях, например /deposit 500 или /deposit 249.99", h.topUpPresetKeyboard())
 		return
 	}
-	if amountMinor < 100 {
+	if amountMinor < h.topUpMin {
 		h.log.Warn().Int64("user", tgUserID).Int64("amount_minor", amountMinor).Msg("bot: deposit below minimum")
-		h.reply(chatID, "Минимальная сумма пополнения — 1 ₽.", h.topUpPresetKeyboard())
+		h.reply(chatID, fmt.Sprintf("Минимальная сумма пополнения — %s.", formatMoney(h.topUpMin, "RUB")), h.topUpPresetKeyboard())
+		return
+	}
+	if h.topUpMax > 0 && amountMinor > h.topUpMax {
+		h.log.Warn().Int64("user", tgUserID).Int64("amount_minor", amountMinor).Msg("bot: deposit above maximum")
+		h.reply(chatID, fmt.Sprintf("Максимальная сумма одного пополнения — %s. Проверьте, нет ли лишнего нуля.", formatMoney(h.topUpMax, "RUB")), h.topUpPresetKeyboard())
 		return
 	}
 	account, err := h.billing.EnsureAccount(ctx, user.ID)
@@ -382,7 +1132,8 @@ func (h *Handler) handleDeposit(ctx context.Context, chatID, tgUserID int64, pay
 		currency = "RUB"
 	}
 	description := fmt.Sprintf("Пополнение баланса TG Digest Bot на %s", formatMoney(amountMinor, currency))
-	idempotencyKey := uuid.NewString()
+	requestedAt := time.Now()
+	idempotencyKey := depositIdempotencyKey(user.ID, amountMinor, requestedAt)
 	metadata := map[string]any{
 		"type":        "topup",
 		"source":      "telegram_bot",
@@ -404,16 +1155,28 @@ func (h *Handler) handleDeposit(ctx context.Context, chatID, tgUserID int64, pay
 		},
 	}
 	result, err := h.sbp.CreateInvoiceWithQRCode(ctx, params)
+	if err == nil && !depositInvoiceReusable(result, requestedAt) {
+		// Счёт из этого окна уже оплачен или истёк — нужен новый.
+		params.IdempotencyKey = uuid.NewString()
+		result, err = h.sbp.CreateInvoiceWithQRCode(ctx, params)
+	}
 	if err != nil {
 		h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: create sbp invoice failed")
 		h.reply(chatID, "Не удалось создать счёт для пополнения. Попробуйте позже.", nil)
 		return
 	}
 	amountFmt := formatMoney(result.Invoice.Amount.Amount, result.Invoice.Amount.Currency)
+	header := "🧾 Счёт на пополнение создан."
+	if !result.Invoice.CreatedAt.IsZero() && result.Invoice.CreatedAt.Before(requestedAt) {
+		header = "🧾 Счёт на эту сумму уже выставлен — используйте его, новый не нужен."
+	}
 	lines := []string{
-		"🧾 Счёт на пополнение создан.",
+		header,
 		fmt.Sprintf("Сумма: %s.", amountFmt),
 	}
+	if result.Invoice.ID > 0 {
+		lines = append(lines, fmt.Sprintf("Номер счёта: %d — проверить оплату: /invoice %d", result.Invoice.ID, result.Invoice.ID))
+	}
 	if result.QR.PaymentLink != "" {
 		lines = append(lines, fmt.Sprintf("Ссылка на оплату: %s", result.QR.PaymentLink))
 	}
@@ -424,7 +1187,20 @@ func (h *Handler) handleDeposit(ctx context.Context, chatID, tgUserID int64, pay
 		"",
 		"Оплатите счёт в приложении банка. Баланс обновится автоматически после поступления денег.",
 	)
-	h.reply(chatID, strings.Join(lines, "\n"), h.topUpInvoiceKeyboard(result.QR.PaymentLink))
+	text := strings.Join(lines, "\n")
+	keyboard := h.topUpInvoiceKeyboard(result.QR.PaymentLink, result.Invoice.ID)
+	if result.QR.PaymentLink != "" {
+		// QR удобнее ссылки: его сканируют камерой в приложении банка.
+		image, err := renderQRCode(result.QR.PaymentLink, qrMaxSize)
+		if err == nil {
+			err = h.replyPhoto(chatID, "sbp-qr.png", image, text+"\nИли отсканируйте QR-код.", keyboard)
+		}
+		if err == nil {
+			return
+		}
+		h.log.Warn().Err(err).Int64("user", tgUserID).Msg("bot: send sbp qr failed, falling back to text")
+	}
+	h.reply(chatID, text, keyboard)
 }
 
 func (h *Handler) handleBuySubscription(ctx context.Context, chatID, tgUserID int64, payload string) {
@@ -451,7 +1227,7 @@ func (h *Handler) handleBuySubscription(ctx context.Context, chatID, tgUserID in
 		h.reply(chatID, "Укажите тариф: /buy plus или /buy pro.", h.subscriptionKeyboard(user))
 		return
 	}
-	if planPriority(user.Role) >= planPriority(offer.Role) {
+	if domain.RolePriority(user.Role) >= domain.RolePriority(offer.Role) {
 		h.reply(chatID, fmt.Sprintf("У вас уже активен тариф %s или выше.", domain.PlanForRole(user.Role).Name), h.subscriptionKeyboard(user))
 		return
 	}
@@ -483,24 +1259,42 @@ func (h *Handler) handleBuySubscription(ctx context.Context, chatID, tgUserID in
 		"duration":   offer.Duration,
 	}
 	description := fmt.Sprintf("Подписка %s", offer.Title)
+	chargeKey := uuid.NewString()
+	if h.activations != nil {
+		// Покупка записывается до списания, чтобы сверка нашла списание, что бы ни случилось дальше.
+		if err := h.activations.RecordPlanCharge(chargeKey, user.ID, offer.Role); err != nil {
+			h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: record plan charge failed")
+			h.reply(chatID, "Не удалось оформить подписку, деньги не списаны. Попробуйте позже.", nil)
+			return
+		}
+	}
 	payment, err := h.billing.ChargeAccount(ctx, domain.ChargeAccountParams{
 		AccountID:      account.ID,
 		Amount:         domain.Money{Amount: offer.PriceMinor, Currency: currency},
 		Description:    description,
 		Metadata:       metadata,
-		IdempotencyKey: uuid.NewString(),
+		IdempotencyKey: chargeKey,
 	})
 	if err != nil {
 		if errors.Is(err, domain.ErrInsufficientFunds) {
+			h.resolvePlanCharge(chargeKey)
 			h.reply(chatID, "Недостаточно средств на счёте. Пополните баланс командой /deposit 500.", h.topUpPresetKeyboard())
 			return
 		}
 		h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: charge account failed")
+		if h.activations != nil {
+			h.reply(chatID, "Не удалось подтвердить оплату. Если деньги списались, подписка активируется автоматически в течение нескольких минут.", nil)
+			return
+		}
 		h.reply(chatID, "Не удалось списать оплату. Попробуйте позже или обратитесь в поддержку.", nil)
 		return
 	}
-	if err := h.users.UpdateRole(user.ID, offer.Role); err != nil {
-		h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: update role failed")
+	if err := h.activatePaidPlan(user.ID, payment.ID, offer.Role, chargeKey); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("payment", payment.ID).Msg("billing: plan activation failed")
+		if h.activations != nil {
+			h.reply(chatID, "Оплата прошла, подписка активируется автоматически в течение нескольких минут. Мы пришлём сообщение, когда всё будет готово.", nil)
+			return
+		}
 		h.reply(chatID, "Оплата прошла, но не удалось активировать подписку. Напишите в поддержку, мы всё исправим.", nil)
 		return
 	}
@@ -525,6 +1319,117 @@ func (h *Handler) handleBuySubscription(ctx context.Context, chatID, tgUserID in
 	}
 	lines = append(lines, "", "Спасибо, что поддерживаете проект!")
 	h.reply(chatID, strings.Join(lines, "\n"), h.balanceKeyboard())
+	if balErr == nil {
+		h.notifyIfBalanceLow(ctx, chatID, user.ID, balance.Balance)
+	}
+}
+
+// activatePaidPlan выдаёт оплаченный тариф. Активация сначала фиксируется по payment id,
+// поэтому при сбое её доведёт до конца RunPlanActivationRetrier. Если записать её не удалось,
+// покупка остаётся в plan_charges и активацию восстановит сверка с биллингом.
+func (h *Handler) activatePaidPlan(userID, paymentID int64, role domain.UserRole, chargeKey string) error {
+	if h.activations == nil {
+		return h.users.UpdateRole(userID, role)
+	}
+	if err := h.activations.RecordPlanActivation(userID, paymentID, role); err != nil {
+		h.log.Error().Err(err).Int64("payment", paymentID).Msg("billing: record plan activation failed, updating role directly")
+		return h.users.UpdateRole(userID, role)
+	}
+	h.resolvePlanCharge(chargeKey)
+	if _, err := h.activations.ActivatePlan(paymentID); err != nil {
+		if markErr := h.activations.MarkPlanActivationFailed(paymentID, err.Error()); markErr != nil {
+			h.log.Error().Err(markErr).Int64("payment", paymentID).Msg("billing: mark plan activation failed")
+		}
+		return err
+	}
+	return nil
+}
+
+// RunPlanActivationRetrier периодически применяет оплаченные тарифы, которые не удалось выдать сразу.
+func (h *Handler) RunPlanActivationRetrier(ctx context.Context, interval time.Duration) {
+	if h.activations == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.reconcilePlanCharges(ctx)
+		h.retryPlanActivations()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resolvePlanCharge снимает покупку со сверки. Ошибку только логируем: лишняя запись
+// безопасна, сверка найдёт уже записанную активацию и удалит покупку сама.
+func (h *Handler) resolvePlanCharge(chargeKey string) {
+	if h.activations == nil {
+		return
+	}
+	if err := h.activations.ResolvePlanCharge(chargeKey); err != nil {
+		h.log.Warn().Err(err).Str("charge_key", chargeKey).Msg("billing: resolve plan charge failed")
+	}
+}
+
+// reconcilePlanCharges ищет в биллинге списания по незавершённым покупкам тарифа и записывает
+// по ним активации, которые затем применит retryPlanActivations. Покупка без списания дольше
+// planChargeAbandonAfter снимается со сверки.
+func (h *Handler) reconcilePlanCharges(ctx context.Context) {
+	if h.billing == nil {
+		return
+	}
+	now := time.Now()
+	charges, err := h.activations.ListPlanCharges(now.Add(-planChargeSettleDelay), 50)
+	if err != nil {
+		h.log.Error().Err(err).Msg("billing: list plan charges")
+		return
+	}
+	for _, charge := range charges {
+		payment, err := h.billing.GetPaymentByIdempotencyKey(ctx, charge.ChargeKey)
+		if errors.Is(err, domain.ErrPaymentNotFound) {
+			if now.Sub(charge.CreatedAt) > planChargeAbandonAfter {
+				h.resolvePlanCharge(charge.ChargeKey)
+			}
+			continue
+		}
+		if err != nil {
+			h.log.Error().Err(err).Str("charge_key", charge.ChargeKey).Msg("billing: look up plan charge failed")
+			continue
+		}
+		if err := h.activations.RecordPlanActivation(charge.UserID, payment.ID, charge.Role); err != nil {
+			h.log.Error().Err(err).Int64("payment", payment.ID).Msg("billing: record reconciled plan activation failed")
+			continue
+		}
+		h.log.Warn().Int64("payment", payment.ID).Int64("user", charge.UserID).Msg("billing: recovered plan charge without activation")
+		h.resolvePlanCharge(charge.ChargeKey)
+	}
+}
+
+func (h *Handler) retryPlanActivations() {
+	pending, err := h.activations.ListPendingPlanActivations(50)
+	if err != nil {
+		h.log.Error().Err(err).Msg("billing: list pending plan activations")
+		return
+	}
+	for _, activation := range pending {
+		applied, err := h.activations.ActivatePlan(activation.PaymentID)
+		if err != nil {
+			h.log.Error().Err(err).Int64("payment", activation.PaymentID).Int("attempts", activation.Attempts).Msg("billing: retry plan activation failed")
+			if markErr := h.activations.MarkPlanActivationFailed(activation.PaymentID, err.Error()); markErr != nil {
+				h.log.Error().Err(markErr).Int64("payment", activation.PaymentID).Msg("billing: mark plan activation failed")
+			}
+			continue
+		}
+		if !applied {
+			continue
+		}
+		h.log.Warn().Int64("payment", activation.PaymentID).Int64("user", activation.UserID).Msg("billing: plan activated after retry")
+		plan := domain.PlanForRole(activation.Role)
+		h.reply(activation.TGUserID, fmt.Sprintf("✅ Подписка %s активирована. Спасибо за оплату!", plan.Name), h.balanceKeyboard())
+	}
 }
 
 func (h *Handler) sendTopUpMenu(chatID int64) {
@@ -546,7 +1451,7 @@ func (h *Handler) sendSubscriptionMenu(chatID int64, user domain.User) {
 	lines = append(lines, "🛒 Доступные подписки:")
 	available := 0
 	for _, offer := range offers {
-		if planPriority(user.Role) >= planPriority(offer.Role) {
+		if domain.RolePriority(user.Role) >= domain.RolePriority(offer.Role) {
 			continue
 		}
 		available++
@@ -573,32 +1478,106 @@ func (h *Handler) sendSubscriptionMenu(chatID int64, user domain.User) {
 }
 
 func (h *Handler) handleAdd(ctx context.Context, chatID int64, tgUserID int64, alias string) {
-	if alias == "" {
+	list := splitAliasList(alias)
+	if len(list) == 0 {
 		h.reply(chatID, "Отправьте /add @alias", nil)
 		return
 	}
+	if len(list) > 1 {
+		h.handleBulkAdd(ctx, chatID, tgUserID, list)
+		return
+	}
+	alias = list[0]
 	channel, err := h.channelUC.AddChannel(ctx, tgUserID, alias)
 	if err != nil {
-		switch {
-		case errors.Is(err, channels.ErrAliasInvalid):
-			h.reply(chatID, "Некорректный алиас. Пример: /add @example", nil)
-		case errors.Is(err, channels.ErrChannelLimit):
-			user, getErr := h.users.GetByTGID(tgUserID)
-			if getErr != nil {
-				h.reply(chatID, "Превышен лимит каналов для вашего тарифа.", nil)
-				return
-			}
-			plan := user.Plan()
-			if plan.ChannelLimit > 0 {
-				h.reply(chatID, fmt.Sprintf("Тариф %s позволяет добавить до %d каналов. Удалите канал или обновите тариф.", plan.Name, plan.ChannelLimit), nil)
-			} else {
-				h.reply(chatID, "Для вашего тарифа нет ограничений по каналам, но произошла ошибка. Попробуйте позже.", nil)
-			}
-		case errors.Is(err, channels.ErrPrivateChannel):
-			h.reply(chatID, "Канал приватный или недоступен. Добавьте публичный канал.", nil)
-		default:
-			h.reply(chatID, fmt.Sprintf("Ошибка добавления: %v", err), nil)
+		h.replyAddChannelError(chatID, tgUserID, err)
+		return
+	}
+	title := channel.Title
+	if title == "" {
+		title = channel.Alias
+	}
+	h.reply(chatID, fmt.Sprintf("Готово: %s", title), h.mainKeyboard())
+}
+
+func (h *Handler) replyAddChannelError(chatID, tgUserID int64, err error) {
+	apiErr := channels.AsAPIError(err)
+	switch apiErr.Code() {
+	case channels.CodeAliasInvalid:
+		h.reply(chatID, "Некорректный алиас. Пример: /add @example", nil)
+	case channels.CodeChannelLimit:
+		user, getErr := h.users.GetByTGID(tgUserID)
+		if getErr != nil {
+			h.reply(chatID, "Превышен лимит каналов для вашего тарифа.", nil)
+			return
+		}
+		plan := user.Plan()
+		if limit := user.ChannelLimit(); user.ChannelLimitOverride != nil && limit > 0 {
+			h.reply(chatID, fmt.Sprintf("Ваш лимит — %s. Удалите канал или напишите в поддержку.", pluralCount(limit, "канал", "канала", "каналов")), nil)
+		} else if limit > 0 {
+			h.reply(chatID, fmt.Sprintf("Тариф %s позволяет добавить до %s. Удалите канал или обновите тариф.", plan.Name, pluralCount(limit, "канала", "каналов", "каналов")), nil)
+		} else {
+			h.reply(chatID, "Для вашего тарифа нет ограничений по каналам, но произошла ошибка. Попробуйте позже.", nil)
 		}
+	case channels.CodeInviteLink:
+		h.reply(chatID, "Это приватный канал: по ссылкам-приглашениям (t.me/+…, t.me/joinchat/…) дайджест не собрать. Добавьте публичный канал: /add @alias", nil)
+	case channels.CodePrivateChannel:
+		h.reply(chatID, "Канал приватный или недоступен. Добавьте публичный канал.", nil)
+	case channels.CodeChannelNotFound:
+		h.reply(chatID, "Канал не найден. Попробуйте добавить его по алиасу: /add @alias", nil)
+	default:
+		h.reply(chatID, fmt.Sprintf("Ошибка добавления: %v", err), nil)
+	}
+}
+
+// handleSearch ищет каналы по ключевому слову и предлагает добавить найденные.
+func (h *Handler) handleSearch(ctx context.Context, chatID, tgUserID int64, query string) {
+	if strings.TrimSpace(query) == "" {
+		h.reply(chatID, "Отправьте /search <слово>, например /search новости", nil)
+		return
+	}
+	found, err := h.channelUC.SearchChannels(ctx, query, searchResultsLimit)
+	if err != nil {
+		h.log.Error().Err(err).Str("query", query).Msg("bot: search channels failed")
+		h.reply(chatID, "Не удалось выполнить поиск. Попробуйте позже.", nil)
+		return
+	}
+	subscribed := make(map[int64]struct{})
+	if list, err := h.channelUC.ListChannels(ctx, tgUserID, 100, 0); err == nil {
+		for _, uc := range list {
+			subscribed[uc.ChannelID] = struct{}{}
+		}
+	}
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(found))
+	for _, ch := range found {
+		if _, ok := subscribed[ch.ID]; ok {
+			continue
+		}
+		title := ch.Title
+		if title == "" {
+			title = ch.Alias
+		}
+		label := fmt.Sprintf("➕ %s (@%s)", title, ch.Alias)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("add_found:%d", ch.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		h.reply(chatID, "Ничего не нашлось. Если знаете алиас канала, добавьте его командой /add @alias", nil)
+		return
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.reply(chatID, fmt.Sprintf("Нашлось %s. Нажмите, чтобы добавить:", pluralCount(len(rows), "канал", "канала", "каналов")), &markup)
+}
+
+func (h *Handler) handleAddFound(ctx context.Context, chatID, tgUserID, channelID int64) {
+	if channelID <= 0 {
+		h.reply(chatID, "Некорректный идентификатор канала", nil)
+		return
+	}
+	channel, err := h.channelUC.AddFoundChannel(ctx, tgUserID, channelID)
+	if err != nil {
+		h.replyAddChannelError(chatID, tgUserID, err)
 		return
 	}
 	title := channel.Title
@@ -618,32 +1597,125 @@ func (h *Handler) handleList(ctx context.Context, chatID int64, tgUserID int64)
 		h.reply(chatID, "У вас пока нет каналов", nil)
 		return
 	}
-	var b strings.Builder
-	for i, ch := range channels {
-		title := ch.Channel.Title
-		if title == "" {
-			title = ch.Channel.Alias
+	loc := h.userLocation(tgUserID)
+	var b strings.Builder
+	for i, ch := range channels {
+		title := ch.Channel.Title
+		if title == "" {
+			title = ch.Channel.Alias
+		}
+		line := fmt.Sprintf("%d. %s (@%s)", i+1, title, ch.Channel.Alias)
+		if ch.Muted {
+			if ch.MutedUntil != nil {
+				line += " 🔕 до " + ch.MutedUntil.In(loc).Format("02.01")
+			} else {
+				line += " 🔕"
+			}
+		}
+		if len(ch.Tags) > 0 {
+			line += fmt.Sprintf(" — теги: %s", strings.Join(ch.Tags, ", "))
+		}
+		b.WriteString(line + "\n")
+	}
+	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(channels))
+	for _, ch := range channels {
+		action := "mute"
+		label := "🔕 Мьют"
+		if ch.Muted {
+			action = "unmute"
+			label = "🔔 Вкл"
+		}
+		toggle := tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s:%d", action, ch.ChannelID))
+		tags := tgbotapi.NewInlineKeyboardButtonData("🏷 Теги", fmt.Sprintf("%s%d", editTagsCallbackPrefix, ch.ChannelID))
+		del := tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("delete:%d", ch.ChannelID))
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(toggle, tags, del))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	h.reply(chatID, b.String(), &markup)
+}
+
+// handleNext показывает, когда придёт следующий дайджест по расписанию и что в него попадёт.
+func (h *Handler) handleNext(ctx context.Context, chatID, tgUserID int64) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: get user for next failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	from := time.Now()
+	if user.IsPaused(from) {
+		if user.PausedUntil == nil {
+			h.reply(chatID, buildPausedMessage(user, h.userLocation(tgUserID))+" Вернуть рассылку: /resume", nil)
+			return
+		}
+		from = *user.PausedUntil
+	}
+	next, err := schedule.NextRun(from, user)
+	if err != nil {
+		h.log.Warn().Err(err).Int64("user", tgUserID).Msg("bot: некорректный часовой пояс, используем UTC")
+	}
+	channels, err := h.channelUC.ListChannels(ctx, tgUserID, 100, 0)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("не удалось получить каналы пользователя")
+		h.reply(chatID, "Не удалось получить список каналов. Попробуйте позже", nil)
+		return
+	}
+	h.reply(chatID, buildNextDigestMessage(next, channels), nil)
+}
+
+// buildNextDigestMessage описывает ближайший плановый дайджест. Время next уже
+// должно быть в часовом поясе пользователя.
+func buildNextDigestMessage(next time.Time, channels []domain.UserChannel) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏰ Следующий дайджест: %s в %s (%s)\n", next.Format("02.01"), next.Format("15:04"), next.Location().String())
+
+	var included, skipped []string
+	tagSet := make(map[string]struct{})
+	for _, ch := range channels {
+		title := ch.Channel.Title
+		if title == "" {
+			title = ch.Channel.Alias
+		}
+		line := fmt.Sprintf("%s (@%s)", title, ch.Channel.Alias)
+		// Временный мьют, истекающий до рассылки, канал уже не исключает.
+		if ch.Muted && (ch.MutedUntil == nil || ch.MutedUntil.After(next)) {
+			if ch.MutedUntil != nil {
+				line += " — до " + ch.MutedUntil.In(next.Location()).Format("02.01")
+			}
+			skipped = append(skipped, line)
+			continue
+		}
+		included = append(included, line)
+		for _, tag := range ch.Tags {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				tagSet[trimmed] = struct{}{}
+			}
+		}
+	}
+
+	if len(included) == 0 {
+		b.WriteString("\nВ дайджест пока ничего не попадёт: добавьте канал командой /add или включите его через /unmute.")
+	} else {
+		fmt.Fprintf(&b, "\nКаналы (%d):\n", len(included))
+		for _, line := range included {
+			b.WriteString("• " + line + "\n")
 		}
-		line := fmt.Sprintf("%d. %s (@%s)", i+1, title, ch.Channel.Alias)
-		if len(ch.Tags) > 0 {
-			line += fmt.Sprintf(" — теги: %s", strings.Join(ch.Tags, ", "))
+	}
+	if len(tagSet) > 0 {
+		tags := make([]string, 0, len(tagSet))
+		for tag := range tagSet {
+			tags = append(tags, tag)
 		}
-		b.WriteString(line + "\n")
+		sort.Strings(tags)
+		fmt.Fprintf(&b, "\nТеги: %s\n", strings.Join(tags, ", "))
 	}
-	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(channels))
-	for _, ch := range channels {
-		action := "mute"
-		label := "🔕 Мьют"
-		if ch.Muted {
-			action = "unmute"
-			label = "🔔 Вкл"
+	if len(skipped) > 0 {
+		b.WriteString("\n🔕 Не войдут (мьют):\n")
+		for _, line := range skipped {
+			b.WriteString("• " + line + "\n")
 		}
-		toggle := tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s:%d", action, ch.ChannelID))
-		del := tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("delete:%d", ch.ChannelID))
-		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(toggle, del))
 	}
-	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	h.reply(chatID, b.String(), &markup)
+	return strings.TrimRight(b.String(), "\n")
 }
 
 func (h *Handler) handleDigestNow(ctx context.Context, chatID int64, tgUserID int64) {
@@ -700,6 +1772,170 @@ func (h *Handler) handleDigestNow(ctx context.Context, chatID int64, tgUserID in
 	h.reply(chatID, "Выберите дайджест за последние 24 часа", &markup)
 }
 
+func (h *Handler) handleResendList(chatID, tgUserID int64) {
+	if h.digests == nil {
+		h.reply(chatID, "История дайджестов временно недоступна.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	from := time.Now().UTC().AddDate(0, 0, -resendHistoryDays)
+	history, err := h.digests.ListDigestHistory(user.ID, from)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: list digest history failed")
+		h.reply(chatID, "Не удалось получить историю дайджестов. Попробуйте позже.", nil)
+		return
+	}
+	if len(history) == 0 {
+		h.reply(chatID, fmt.Sprintf("За последние %s сохранённых дайджестов нет.", pluralCount(resendHistoryDays, "день", "дня", "дней")), nil)
+		return
+	}
+	if len(history) > resendHistoryLimit {
+		history = history[:resendHistoryLimit]
+	}
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(history))
+	for _, d := range history {
+		label := fmt.Sprintf("📰 %s%s", d.Date.Format("02.01.2006"), digestScopeLabel(d.Scope))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("resend:%d", d.ID)),
+		))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.reply(chatID, "Выберите дайджест, который нужно отправить повторно:", &markup)
+}
+
+// digestScopeLabel подписывает в /resend дайджесты канала, тегов и прошедшего дня.
+func digestScopeLabel(scope string) string {
+	switch {
+	case scope == "":
+		return ""
+	case scope == "day":
+		return " · за день"
+	case strings.HasPrefix(scope, "channel:"):
+		return " · канал"
+	case strings.HasPrefix(scope, "tags:"):
+		return " · #" + strings.ReplaceAll(strings.TrimPrefix(scope, "tags:"), ",", " #")
+	}
+	return ""
+}
+
+func (h *Handler) handleResend(chatID, tgUserID, digestID int64) {
+	if h.digests == nil {
+		h.reply(chatID, "История дайджестов временно недоступна.", nil)
+		return
+	}
+	if digestID <= 0 {
+		h.reply(chatID, "Не удалось определить дайджест", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	stored, err := h.digests.GetDigestWithItems(digestID, user.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDigestNotFound) {
+			h.reply(chatID, "Дайджест не найден", nil)
+			return
+		}
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("digest", digestID).Msg("bot: load digest for resend failed")
+		h.reply(chatID, "Не удалось загрузить дайджест. Попробуйте позже.", nil)
+		return
+	}
+	if len(stored.Items) == 0 {
+		h.reply(chatID, "В этом дайджесте не сохранилось ни одного поста.", nil)
+		return
+	}
+	h.replyHTML(chatID, h.layout.Format(stored, user.Plan().Name), telegram.ExpandKeyboard(stored.Items))
+}
+
+// handleSnooze откладывает сохранённый дайджест на domain.DigestSnoozeDelay.
+func (h *Handler) handleSnooze(ctx context.Context, chatID, tgUserID, digestID int64) {
+	delayed, ok := h.jobs.(domain.DelayedDigestQueue)
+	if !ok || h.digests == nil {
+		h.reply(chatID, "Отложить дайджест сейчас нельзя.", nil)
+		return
+	}
+	if digestID <= 0 {
+		h.reply(chatID, "Не удалось определить дайджест", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	stored, err := h.digests.GetDigestWithItems(digestID, user.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDigestNotFound) {
+			h.reply(chatID, "Дайджест не найден", nil)
+			return
+		}
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("digest", digestID).Msg("bot: load digest for snooze failed")
+		h.reply(chatID, "Не удалось отложить дайджест. Попробуйте позже.", nil)
+		return
+	}
+	allowed, err := h.digests.SnoozeDigest(digestID, user.ID, domain.MaxDigestSnoozes)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("digest", digestID).Msg("bot: snooze digest failed")
+		h.reply(chatID, "Не удалось отложить дайджест. Попробуйте позже.", nil)
+		return
+	}
+	if !allowed {
+		h.reply(chatID, fmt.Sprintf("Этот дайджест уже откладывали %s — больше нельзя.", pluralCount(domain.MaxDigestSnoozes, "раз", "раза", "раз")), nil)
+		return
+	}
+	job := domain.DigestJob{
+		UserTGID:    tgUserID,
+		ChatID:      chatID,
+		Date:        stored.Date,
+		RequestedAt: time.Now().UTC(),
+		Cause:       domain.DigestCauseSnoozed,
+		DigestID:    digestID,
+	}
+	if err := delayed.EnqueueDelayed(ctx, job, domain.DigestSnoozeDelay); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("digest", digestID).Msg("bot: enqueue snoozed digest failed")
+		h.reply(chatID, "Не удалось отложить дайджест. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, "⏰ Хорошо, пришлю этот дайджест ещё раз через 2 часа.", nil)
+}
+
+func (h *Handler) handleExpandPost(chatID, tgUserID, postID int64) {
+	if h.posts == nil || postID <= 0 {
+		h.reply(chatID, "Не удалось открыть пост", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	post, err := h.posts.GetPost(postID, user.ID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrPostNotFound) {
+			h.log.Error().Err(err).Int64("post", postID).Msg("bot: get post for expand failed")
+		}
+		h.reply(chatID, "Пост больше недоступен", nil)
+		return
+	}
+	text := strings.TrimSpace(post.FullText)
+	if text == "" {
+		text = strings.TrimSpace(post.Text)
+	}
+	if text == "" {
+		text = "Пост без текста."
+	}
+	if post.URL != "" {
+		text += "\n\n" + post.URL
+	}
+	h.reply(chatID, text, nil)
+}
+
 func (h *Handler) handleTagCommand(ctx context.Context, chatID, tgUserID int64, payload string) {
 	if payload == "" {
 		h.reply(chatID, "Используйте формат: /tag @alias новости, аналитика", nil)
@@ -746,8 +1982,13 @@ func (h *Handler) handleTagCommand(ctx context.Context, chatID, tgUserID int64,
 		return
 	}
 	if err := h.channelUC.UpdateChannelTags(ctx, tgUserID, channelID, tags); err != nil {
-		h.reply(chatID, fmt.Sprintf("Не удалось сохранить теги: %v", err), nil)
-		return
+		var limitErr *channels.TagLimitError
+		if !errors.As(err, &limitErr) {
+			h.reply(chatID, fmt.Sprintf("Не удалось сохранить теги: %v", err), nil)
+			return
+		}
+		tags = limitErr.Kept
+		h.reply(chatID, buildTagLimitMessage(limitErr), nil)
 	}
 	if len(tags) == 0 {
 		h.reply(chatID, fmt.Sprintf("Теги для %s очищены", title), nil)
@@ -788,7 +2029,7 @@ func (h *Handler) handleTagsList(ctx context.Context, chatID, tgUserID int64) {
 	var b strings.Builder
 	b.WriteString("Ваши теги:\n")
 	for _, tag := range tags {
-		b.WriteString(fmt.Sprintf("- %s — %d канал(а)\n", tag, counter[tag]))
+		b.WriteString(fmt.Sprintf("- %s — %s\n", tag, pluralCount(counter[tag], "канал", "канала", "каналов")))
 	}
 	b.WriteString("\nИспользуйте /digest_tag тег, чтобы получить дайджест.")
 	h.reply(chatID, b.String(), nil)
@@ -834,6 +2075,8 @@ func (h *Handler) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery
 	case strings.HasPrefix(data, "billing_topup:"):
 		amount := strings.TrimPrefix(data, "billing_topup:")
 		h.handleDeposit(ctx, cb.Message.Chat.ID, cb.From.ID, amount)
+	case strings.HasPrefix(data, checkInvoiceCallbackPrefix):
+		h.handleInvoiceStatus(ctx, cb.Message.Chat.ID, cb.From.ID, strings.TrimPrefix(data, checkInvoiceCallbackPrefix))
 	case data == "billing_subscribe":
 		h.handleBuySubscription(ctx, cb.Message.Chat.ID, cb.From.ID, "")
 	case strings.HasPrefix(data, "plan_buy:"):
@@ -881,8 +2124,37 @@ func (h *Handler) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery
 	case strings.HasPrefix(data, "delete:"):
 		id := parseID(data)
 		h.handleDeleteChannel(ctx, cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, editTagsCallbackPrefix):
+		id := parseID(data)
+		h.handleEditTags(ctx, cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, tagAddCallbackPrefix):
+		id := parseID(data)
+		h.handleTagAdd(ctx, cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, tagRemoveCallbackPrefix):
+		if id, index, ok := parseTagRemoveCallback(data); ok {
+			h.handleTagRemove(ctx, cb.Message.Chat.ID, cb.From.ID, id, index)
+		}
+	case strings.HasPrefix(data, tagClearCallbackPrefix):
+		id := parseID(data)
+		h.handleTagClear(ctx, cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, telegram.ExpandCallbackPrefix):
+		id := parseID(data)
+		h.handleExpandPost(cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, "add_found:"):
+		id := parseID(data)
+		h.handleAddFound(ctx, cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, findCallbackPrefix):
+		if query, offset, ok := parseFindCallback(data); ok {
+			h.handleFind(ctx, cb.Message.Chat.ID, cb.From.ID, query, offset)
+		}
+	case strings.HasPrefix(data, "resend:"):
+		id := parseID(data)
+		h.handleResend(cb.Message.Chat.ID, cb.From.ID, id)
+	case strings.HasPrefix(data, telegram.SnoozeCallbackPrefix):
+		id := parseID(data)
+		h.handleSnooze(ctx, cb.Message.Chat.ID, cb.From.ID, id)
 	case data == "more_items":
-		h.reply(cb.Message.Chat.ID, fmt.Sprintf("Пока доступно только %d элементов. Обновите дайджест позже.", h.maxDigest), nil)
+		h.replyMoreItems(cb.Message.Chat.ID, cb.From.ID)
 	}
 	start := time.Now()
 	_, err := h.bot.Request(tgbotapi.NewCallback(cb.ID, ""))
@@ -892,6 +2164,21 @@ func (h *Handler) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery
 	}
 }
 
+func (h *Handler) replyMoreItems(chatID, tgUserID int64) {
+	limit := h.maxDigest
+	planName := ""
+	if user, err := h.users.GetByTGID(tgUserID); err == nil {
+		plan := user.Plan()
+		limit = plan.DigestItems(h.maxDigest)
+		planName = plan.Name
+	}
+	if planName == "" {
+		h.reply(chatID, fmt.Sprintf("Пока доступно только %s. Обновите дайджест позже.", pluralCount(limit, "элемент", "элемента", "элементов")), nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("На тарифе %s в дайджест попадает до %s. Больше пунктов доступно на старших тарифах — /buy.", planName, pluralCount(limit, "элемента", "элементов", "элементов")), nil)
+}
+
 func (h *Handler) handleTimezone(ctx context.Context, chatID, tgUserID int64, payload string) {
 	payload = strings.TrimSpace(payload)
 	if payload != "" {
@@ -934,9 +2221,8 @@ func (h *Handler) handleSetTimezone(ctx context.Context, chatID, tgUserID int64,
 }
 
 func (h *Handler) tryHandleTimezoneInput(ctx context.Context, chatID, tgUserID int64, value string) bool {
-	h.mu.Lock()
-	_, pending := h.pendingTZ[tgUserID]
-	h.mu.Unlock()
+	pending := false
+	h.pending.with(tgUserID, func(st *pendingState) { pending = st.awaitingTZ })
 	if !pending {
 		return false
 	}
@@ -950,15 +2236,11 @@ func (h *Handler) tryHandleTimezoneInput(ctx context.Context, chatID, tgUserID i
 }
 
 func (h *Handler) setPendingTimezone(tgUserID int64) {
-	h.mu.Lock()
-	h.pendingTZ[tgUserID] = struct{}{}
-	h.mu.Unlock()
+	h.pending.with(tgUserID, func(st *pendingState) { st.awaitingTZ = true })
 }
 
 func (h *Handler) clearPendingTimezone(tgUserID int64) {
-	h.mu.Lock()
-	delete(h.pendingTZ, tgUserID)
-	h.mu.Unlock()
+	h.pending.with(tgUserID, func(st *pendingState) { st.awaitingTZ = false })
 }
 
 func (h *Handler) promptTimezone(chatID, tgUserID int64, current string) {
@@ -967,7 +2249,7 @@ func (h *Handler) promptTimezone(chatID, tgUserID int64, current string) {
 	if tz := strings.TrimSpace(current); tz != "" {
 		lines = append(lines, fmt.Sprintf("Сейчас установлен: %s.", tz))
 	} else {
-		lines = append(lines, "Он пока не установлен.")
+		lines = append(lines, fmt.Sprintf("Пока используется пояс по умолчанию: %s.", domain.DefaultTimezone()))
 	}
 	lines = append(lines,
 		"",
@@ -985,12 +2267,8 @@ func (h *Handler) handleSchedule(chatID, tgUserID int64) {
 	}
 	h.setPendingSchedule(tgUserID)
 	current := user.DailyTime.Format("15:04")
-	tzSuffix := ""
-	if user.Timezone != "" {
-		tzSuffix = fmt.Sprintf(" (%s)", user.Timezone)
-	}
 	message := []string{
-		fmt.Sprintf("Текущее время ежедневной рассылки: %s%s.", current, tzSuffix),
+		fmt.Sprintf("Текущее время ежедневной рассылки: %s (%s).", current, user.TimezoneName()),
 		"",
 		"Выберите подходящий вариант ниже или укажите своё время.",
 		"Можно просто отправить 21:30 или воспользоваться командой /schedule 21:30.",
@@ -1003,7 +2281,11 @@ func (h *Handler) handleSetTime(ctx context.Context, chatID, tgUserID int64, val
 	value = strings.TrimSpace(value)
 	tm, err := ParseLocalTime(value)
 	if err != nil {
-		h.reply(chatID, "Некорректный формат времени. Используйте ЧЧ:ММ", nil)
+		if h.registerScheduleInputFailure(tgUserID) {
+			h.reply(chatID, "Не удалось распознать время, ввод отменён. Вернуться к настройке можно через /schedule.", h.mainKeyboard())
+			return
+		}
+		h.reply(chatID, "Некорректный формат времени. Используйте ЧЧ:ММ или /cancel, чтобы отменить ввод.", nil)
 		return
 	}
 	if err := h.scheduleUC.UpdateDailyTime(ctx, tgUserID, tm); err != nil {
@@ -1015,10 +2297,9 @@ func (h *Handler) handleSetTime(ctx context.Context, chatID, tgUserID int64, val
 }
 
 func (h *Handler) tryHandleScheduleInput(ctx context.Context, chatID, tgUserID int64, value string) bool {
-	h.mu.Lock()
-	_, pending := h.pendingTime[tgUserID]
-	h.mu.Unlock()
-	if !pending {
+	pending := false
+	h.pending.with(tgUserID, func(st *pendingState) { pending = st.awaitingTime })
+	if !pending || strings.HasPrefix(strings.TrimSpace(value), "/") {
 		return false
 	}
 	if strings.TrimSpace(value) == "" {
@@ -1030,22 +2311,47 @@ func (h *Handler) tryHandleScheduleInput(ctx context.Context, chatID, tgUserID i
 }
 
 func (h *Handler) setPendingSchedule(tgUserID int64) {
-	h.mu.Lock()
-	h.pendingTime[tgUserID] = struct{}{}
-	h.mu.Unlock()
+	h.pending.with(tgUserID, func(st *pendingState) { st.awaitingTime, st.timeAttempts = true, 0 })
+}
+
+// registerScheduleInputFailure учитывает неудачную попытку ввода времени и после
+// maxScheduleInputAttempts сбрасывает ожидание, чтобы бот не «застревал» на вопросе о времени.
+// Возвращает true, если ожидание было сброшено.
+func (h *Handler) registerScheduleInputFailure(tgUserID int64) bool {
+	cleared := false
+	h.pending.with(tgUserID, func(st *pendingState) {
+		if !st.awaitingTime {
+			return
+		}
+		st.timeAttempts++
+		if st.timeAttempts >= maxScheduleInputAttempts {
+			st.awaitingTime, st.timeAttempts = false, 0
+			cleared = true
+		}
+	})
+	return cleared
 }
 
 func (h *Handler) clearPendingSchedule(tgUserID int64) {
-	h.mu.Lock()
-	delete(h.pendingTime, tgUserID)
-	h.mu.Unlock()
+	h.pending.with(tgUserID, func(st *pendingState) { st.awaitingTime, st.timeAttempts = false, 0 })
 }
 
-func (h *Handler) handleMuteCommand(ctx context.Context, chatID, tgUserID int64, alias string, mute bool) {
-	if alias == "" {
-		h.reply(chatID, "Укажите алиас канала, например /mute @example", nil)
+func (h *Handler) handleMuteCommand(ctx context.Context, chatID, tgUserID int64, payload string, mute bool) {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		h.reply(chatID, "Укажите алиас канала, например /mute @example или /mute @example 3d", nil)
 		return
 	}
+	alias := fields[0]
+	var muteFor time.Duration
+	if mute && len(fields) > 1 {
+		d, err := parseMuteDuration(fields[1])
+		if err != nil {
+			h.reply(chatID, "Срок укажите в часах, днях или неделях, например 12h, 3d или 1w (не больше 90 дней).", nil)
+			return
+		}
+		muteFor = d
+	}
 	parsed, err := channels.ParseAlias(alias)
 	if err != nil {
 		h.reply(chatID, "Некорректный алиас", nil)
@@ -1067,9 +2373,66 @@ func (h *Handler) handleMuteCommand(ctx context.Context, chatID, tgUserID int64,
 		h.reply(chatID, "Канал не найден среди ваших подписок", nil)
 		return
 	}
+	if muteFor > 0 {
+		h.muteFor(ctx, chatID, tgUserID, channelID, muteFor)
+		return
+	}
 	h.toggleMute(ctx, chatID, tgUserID, channelID, mute)
 }
 
+func (h *Handler) muteFor(ctx context.Context, chatID, tgUserID, channelID int64, d time.Duration) {
+	until := time.Now().UTC().Add(d)
+	if err := h.channelUC.MuteFor(ctx, tgUserID, channelID, until); err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось обновить статус: %v", err), nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("Канал выключен в дайджесте до %s и включится автоматически.", until.In(h.userLocation(tgUserID)).Format("02.01 15:04")), nil)
+}
+
+// userLocation возвращает часовой пояс пользователя, пояс по умолчанию, если он не выбран,
+// или UTC, если профиль недоступен.
+func (h *Handler) userLocation(tgUserID int64) *time.Location {
+	if user, err := h.users.GetByTGID(tgUserID); err == nil {
+		if loc, err := time.LoadLocation(user.TimezoneName()); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// maxMuteDuration ограничивает временный мьют, чтобы канал не «потерялся» навсегда.
+const maxMuteDuration = 90 * 24 * time.Hour
+
+// parseMuteDuration разбирает срок мьюта вида 30m, 12h, 3d или 1w.
+func parseMuteDuration(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return 0, fmt.Errorf("некорректный срок")
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("некорректный срок")
+	}
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("некорректная единица срока")
+	}
+	d := time.Duration(n) * unit
+	if d > maxMuteDuration {
+		return 0, fmt.Errorf("срок больше %v", maxMuteDuration)
+	}
+	return d, nil
+}
+
 func (h *Handler) toggleMute(ctx context.Context, chatID, tgUserID, channelID int64, mute bool) {
 	if channelID == 0 {
 		h.reply(chatID, "Некорректный идентификатор канала", nil)
@@ -1098,6 +2461,28 @@ func (h *Handler) handleDeleteChannel(ctx context.Context, chatID, tgUserID, cha
 	h.reply(chatID, "Канал удалён", nil)
 }
 
+// digestInFlightTTL — сколько после ручного запроса дайджеста повторные нажатия отклоняются.
+const digestInFlightTTL = 10 * time.Second
+
+// claimDigestRequest отклоняет повторный запрос дайджеста в течение digestInFlightTTL,
+// чтобы двойное нажатие не списало два ручных запроса. Если хранилище недоступно,
+// запрос пропускается.
+func (h *Handler) claimDigestRequest(ctx context.Context, chatID, tgUserID int64) bool {
+	if h.inflight == nil {
+		return true
+	}
+	first, err := h.inflight.MarkSeen(ctx, "digest:inflight:"+strconv.FormatInt(tgUserID, 10), digestInFlightTTL)
+	if err != nil {
+		h.log.Warn().Err(err).Int64("user", tgUserID).Msg("bot: in-flight guard unavailable")
+		return true
+	}
+	if !first {
+		h.reply(chatID, "⏳ Запрос уже выполняется, дайджест скоро придёт.", nil)
+		return false
+	}
+	return true
+}
+
 func (h *Handler) reserveManualRequest(chatID int64, user domain.User) (domain.ManualRequestState, bool) {
 	state, err := h.users.ReserveManualRequest(user.ID, time.Now().UTC())
 	if err != nil {
@@ -1119,10 +2504,10 @@ func (h *Handler) replyManualLimit(chatID int64, state domain.ManualRequestState
 	case state.Plan.ManualDailyLimit <= 0:
 		lines = append(lines, "Лимитов для этого тарифа нет, попробуйте повторить запрос позже или обратитесь в поддержку.")
 	case state.Plan.Role == domain.UserRoleFree && state.Plan.ManualIntroTotal > 0:
-		lines = append(lines, fmt.Sprintf("После первых %d запросов доступен %d запрос в сутки.", state.Plan.ManualIntroTotal, state.Plan.ManualDailyLimit))
+		lines = append(lines, fmt.Sprintf("После первых %s доступно %s в сутки.", pluralCount(state.Plan.ManualIntroTotal, "запроса", "запросов", "запросов"), pluralCount(state.Plan.ManualDailyLimit, "запрос", "запроса", "запросов")))
 		lines = append(lines, "Попробуйте завтра или обновите тариф.")
 	default:
-		lines = append(lines, fmt.Sprintf("Лимит — %d запросов в сутки. Попробуйте завтра или обновите тариф.", state.Plan.ManualDailyLimit))
+		lines = append(lines, fmt.Sprintf("Лимит — %s в сутки. Попробуйте завтра или обновите тариф.", pluralCount(state.Plan.ManualDailyLimit, "запрос", "запроса", "запросов")))
 	}
 	h.reply(chatID, strings.Join(lines, "\n"), nil)
 }
@@ -1156,6 +2541,12 @@ func (h *Handler) enqueueDigest(ctx context.Context, chatID, tgUserID, channelID
 		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
 		return
 	}
+	if h.replyIfPaused(chatID, user) {
+		return
+	}
+	if !h.claimDigestRequest(ctx, chatID, tgUserID) {
+		return
+	}
 	if _, ok := h.reserveManualRequest(chatID, user); !ok {
 		return
 	}
@@ -1203,11 +2594,11 @@ func (h *Handler) enqueueDigest(ctx context.Context, chatID, tgUserID, channelID
 	}
 
 	if channelID > 0 {
-		h.reply(chatID, fmt.Sprintf("Собираем дайджест по каналу %s, отправим его в ближайшее время", channelName), nil)
+		h.reply(chatID, h.withQueueEstimate(ctx, fmt.Sprintf("Собираем дайджест по каналу %s, отправим его в ближайшее время", channelName)), nil)
 		return
 	}
 
-	h.reply(chatID, "Собираем дайджест по всем каналам, отправим его в ближайшее время", nil)
+	h.reply(chatID, h.withQueueEstimate(ctx, "Собираем дайджест по всем каналам, отправим его в ближайшее время"), nil)
 }
 
 func (h *Handler) enqueueDigestByTags(ctx context.Context, chatID, tgUserID int64, tags []string) {
@@ -1221,6 +2612,12 @@ func (h *Handler) enqueueDigestByTags(ctx context.Context, chatID, tgUserID int6
 		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
 		return
 	}
+	if h.replyIfPaused(chatID, user) {
+		return
+	}
+	if !h.claimDigestRequest(ctx, chatID, tgUserID) {
+		return
+	}
 	if _, ok := h.reserveManualRequest(chatID, user); !ok {
 		return
 	}
@@ -1256,7 +2653,7 @@ func (h *Handler) enqueueDigestByTags(ctx context.Context, chatID, tgUserID int6
 
 	metrics.IncDigestOverall()
 	metrics.IncDigestForUser(tgUserID)
-	h.reply(chatID, fmt.Sprintf("Собираем дайджест по тегам: %s", strings.Join(cleaned, ", ")), nil)
+	h.reply(chatID, h.withQueueEstimate(ctx, fmt.Sprintf("Собираем дайджест по тегам: %s", strings.Join(cleaned, ", "))), nil)
 }
 
 func (h *Handler) recordBusinessMetric(ctx context.Context, metric domain.BusinessMetric) {
@@ -1321,7 +2718,7 @@ func (h *Handler) subscriptionOffersOrdered() []subscriptionOffer {
 	}
 	sort.Slice(offers, func(i, j int) bool {
 		if offers[i].PriceMinor == offers[j].PriceMinor {
-			return planPriority(offers[i].Role) < planPriority(offers[j].Role)
+			return domain.RolePriority(offers[i].Role) < domain.RolePriority(offers[j].Role)
 		}
 		return offers[i].PriceMinor < offers[j].PriceMinor
 	})
@@ -1340,11 +2737,11 @@ func (h *Handler) balanceKeyboard() *tgbotapi.InlineKeyboardMarkup {
 }
 
 func (h *Handler) topUpPresetKeyboard() *tgbotapi.InlineKeyboardMarkup {
-	if len(defaultTopUpPresets) == 0 {
+	if len(h.topUpPresets) == 0 {
 		return h.balanceKeyboard()
 	}
-	row := make([]tgbotapi.InlineKeyboardButton, 0, len(defaultTopUpPresets))
-	for _, amount := range defaultTopUpPresets {
+	row := make([]tgbotapi.InlineKeyboardButton, 0, len(h.topUpPresets))
+	for _, amount := range h.topUpPresets {
 		label, payload := presetButtonData(amount)
 		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "billing_topup:"+payload))
 	}
@@ -1357,13 +2754,18 @@ func (h *Handler) topUpPresetKeyboard() *tgbotapi.InlineKeyboardMarkup {
 	return &markup
 }
 
-func (h *Handler) topUpInvoiceKeyboard(link string) *tgbotapi.InlineKeyboardMarkup {
+func (h *Handler) topUpInvoiceKeyboard(link string, invoiceID int64) *tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	if strings.TrimSpace(link) != "" {
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonURL("🔗 Оплатить", link),
 		))
 	}
+	if invoiceID > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Проверить оплату", fmt.Sprintf("%s%d", checkInvoiceCallbackPrefix, invoiceID)),
+		))
+	}
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("💳 Баланс", "billing_balance"),
 		tgbotapi.NewInlineKeyboardButtonData("🛒 Подписка", "billing_subscribe"),
@@ -1376,7 +2778,7 @@ func (h *Handler) subscriptionKeyboard(user domain.User) *tgbotapi.InlineKeyboar
 	offers := h.subscriptionOffersOrdered()
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, offer := range offers {
-		if planPriority(user.Role) >= planPriority(offer.Role) {
+		if domain.RolePriority(user.Role) >= domain.RolePriority(offer.Role) {
 			continue
 		}
 		label := fmt.Sprintf("%s — %s", offer.Title, formatMoney(offer.PriceMinor, "RUB"))
@@ -1395,22 +2797,15 @@ func (h *Handler) subscriptionKeyboard(user domain.User) *tgbotapi.InlineKeyboar
 	return &markup
 }
 
-func planPriority(role domain.UserRole) int {
-	switch role {
-	case domain.UserRoleFree:
-		return 0
-	case domain.UserRolePlus:
-		return 1
-	case domain.UserRolePro:
-		return 2
-	case domain.UserRoleDeveloper:
-		return 3
-	default:
-		return -1
-	}
+// formatMoney показывает сумму с символом валюты; копейки выводятся, только если они есть:
+// «500 ₽», но «249.99 ₽».
+func formatMoney(amount int64, currency string) string {
+	return formatMinorAmount(amount) + " " + currencySymbol(currency)
 }
 
-func formatMoney(amount int64, currency string) string {
+// formatMinorAmount переводит копейки в рубли без символа валюты. Результат разбирает
+// parseAmountToMinor, поэтому он же служит аргументом /deposit.
+func formatMinorAmount(amount int64) string {
 	sign := ""
 	if amount < 0 {
 		sign = "-"
@@ -1418,8 +2813,10 @@ func formatMoney(amount int64, currency string) string {
 	}
 	major := amount / 100
 	minor := amount % 100
-	symbol := currencySymbol(currency)
-	return fmt.Sprintf("%s%d.%02d %s", sign, major, minor, symbol)
+	if minor == 0 {
+		return fmt.Sprintf("%s%d", sign, major)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, major, minor)
 }
 
 func currencySymbol(currency string) string {
@@ -1435,15 +2832,7 @@ func currencySymbol(currency string) string {
 }
 
 func presetButtonData(amount int64) (label string, payload string) {
-	label = formatMoney(amount, "RUB")
-	major := amount / 100
-	minor := amount % 100
-	payload = fmt.Sprintf("%d.%02d", major, minor)
-	payload = strings.TrimRight(strings.TrimRight(payload, "0"), ".")
-	if payload == "" {
-		payload = "0"
-	}
-	return label, payload
+	return formatMoney(amount, "RUB"), formatMinorAmount(amount)
 }
 
 func parseAmountToMinor(input string) (int64, error) {
@@ -1493,26 +2882,32 @@ func parseAmountToMinor(input string) (int64, error) {
 	return major*100 + minor, nil
 }
 
-func (h *Handler) handleClearRequest(chatID, tgUserID int64) {
-	h.mu.Lock()
-	h.pendingDrop[tgUserID] = time.Now()
-	h.mu.Unlock()
-	h.reply(chatID, "Отправьте /clear_data_confirm в течение 5 минут, чтобы удалить все данные.", nil)
+func (h *Handler) handleClearRequest(ctx context.Context, chatID, tgUserID int64) {
+	if h.clearDataBlockedByBalance(ctx, chatID, tgUserID) {
+		return
+	}
+	window := h.clearDataWindow()
+	h.pending.with(tgUserID, func(st *pendingState) { st.dropExpires = time.Now().Add(window) })
+	h.reply(chatID, buildClearDataWarning(window), nil)
 }
 
-func (h *Handler) handleClearConfirm(ctx context.Context, chatID, tgUserID int64) {
-	h.mu.Lock()
-	requested, ok := h.pendingDrop[tgUserID]
-	if ok && time.Since(requested) > 5*time.Minute {
-		delete(h.pendingDrop, tgUserID)
-		ok = false
-	}
-	if ok {
-		delete(h.pendingDrop, tgUserID)
+func (h *Handler) handleClearConfirm(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if payload != clearDataConfirmWord {
+		// Состояние не сбрасываем: пользователь может дописать слово подтверждения.
+		h.reply(chatID, fmt.Sprintf("Удаление необратимо. Для подтверждения отправьте /clear_data_confirm %s", clearDataConfirmWord), nil)
+		return
 	}
-	h.mu.Unlock()
+	ok := false
+	h.pending.with(tgUserID, func(st *pendingState) {
+		ok = time.Now().Before(st.dropExpires)
+		st.dropExpires = time.Time{}
+	})
 	if !ok {
-		h.reply(chatID, "Запрос не найден. Сначала отправьте /clear_data", nil)
+		h.reply(chatID, "Запрос не найден или устарел. Сначала отправьте /clear_data", nil)
+		return
+	}
+	// Баланс мог пополниться, пока шло подтверждение.
+	if h.clearDataBlockedByBalance(ctx, chatID, tgUserID) {
 		return
 	}
 	user, err := h.users.GetByTGID(tgUserID)
@@ -1553,6 +2948,25 @@ func (h *Handler) reply(chatID int64, text string, keyboard *tgbotapi.InlineKeyb
 	}
 }
 
+func (h *Handler) replyHTML(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	parts := telegram.SplitMessage(text)
+	for i, part := range parts {
+		msg := tgbotapi.NewMessage(chatID, part)
+		msg.ParseMode = tgbotapi.ModeHTML
+		msg.DisableWebPagePreview = true
+		if i == len(parts)-1 && keyboard != nil {
+			msg.ReplyMarkup = keyboard
+		}
+		start := time.Now()
+		_, err := h.bot.Send(msg)
+		metrics.ObserveNetworkRequest("telegram_bot", "send_message", strconv.FormatInt(chatID, 10), start, err)
+		if err != nil {
+			h.log.Error().Err(err).Msg("не удалось отправить сообщение")
+			return
+		}
+	}
+}
+
 func (h *Handler) mainKeyboard() *tgbotapi.InlineKeyboardMarkup {
 	buttons := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -1626,9 +3040,6 @@ func userDisplayName(user domain.User) string {
 }
 
 func (h *Handler) buildStartSections(user domain.User) []string {
-	plan := user.Plan()
-	channelLine, manualLine := h.mainPlanLines(plan)
-
 	greeting := []string{}
 	if name := userDisplayName(user); name != "" {
 		greeting = append(greeting,
@@ -1639,8 +3050,16 @@ func (h *Handler) buildStartSections(user domain.User) []string {
 		greeting = append(greeting, "👋 Добро пожаловать в TG Digest Bot!")
 	}
 
-	intro := append(greeting,
-		"",
+	intro := append(greeting, "")
+	intro = append(intro, h.planSummaryLines(user)...)
+	return append([]string{strings.Join(intro, "\n")}, h.buildGuideSections(user)...)
+}
+
+// planSummaryLines описывает текущий тариф и его лимиты для приветствия и /guide.
+func (h *Handler) planSummaryLines(user domain.User) []string {
+	plan := user.Plan()
+	channelLine, manualLine := h.mainPlanLines(plan)
+	return []string{
 		fmt.Sprintf("Ваш текущий тариф: %s.", plan.Name),
 		"",
 		"Основные лимиты:",
@@ -1648,8 +3067,12 @@ func (h *Handler) buildStartSections(user domain.User) []string {
 		fmt.Sprintf("• %s", manualLine),
 		"",
 		"Используйте кнопки под сообщением, чтобы сразу перейти к нужному действию.",
-	)
+	}
+}
 
+// buildGuideSections собирает быстрый старт и реферальную сводку. Рендеринг не меняет состояние,
+// поэтому его показывают и /start, и /guide.
+func (h *Handler) buildGuideSections(user domain.User) []string {
 	quickStart := []string{
 		"🚀 Быстрый старт:",
 		"• ➕ Добавьте канал через кнопку «Добавить канал» или команду /add @alias.",
@@ -1661,26 +3084,56 @@ func (h *Handler) buildStartSections(user domain.User) []string {
 		"• 🌍 Укажите часовой пояс кнопкой «Часовой пояс» или командой /timezone Europe/Moscow.",
 	}
 
-	var sections []string
-	sections = append(sections, strings.Join(intro, "\n"), strings.Join(quickStart, "\n"))
-
+	sections := []string{strings.Join(quickStart, "\n")}
 	if referral := h.buildReferralPreview(user); referral != "" {
 		sections = append(sections, referral)
 	}
-
 	return sections
 }
 
+// handleGuide повторно показывает приветственное руководство с текущим тарифом и рефералами.
+// В отличие от /start профиль не обновляется и реферальный код не применяется.
+func (h *Handler) handleGuide(chatID, tgUserID int64) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		h.reply(chatID, "Отправьте /start, чтобы начать работу с ботом.", nil)
+		return
+	}
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	summary := append([]string{"📘 Краткое руководство", ""}, h.planSummaryLines(user)...)
+	h.replySections(chatID, append([]string{strings.Join(summary, "\n")}, h.buildGuideSections(user)...))
+}
+
+// replySections отправляет непустые секции отдельными сообщениями; главное меню — под первой.
+func (h *Handler) replySections(chatID int64, sections []string) {
+	first := true
+	for _, section := range sections {
+		if strings.TrimSpace(section) == "" {
+			continue
+		}
+		if first {
+			h.reply(chatID, section, h.mainKeyboard())
+			first = false
+			continue
+		}
+		h.reply(chatID, section, nil)
+	}
+}
+
 func (h *Handler) buildReferralPreview(user domain.User) string {
 	code := strings.TrimSpace(user.ReferralCode)
 	if code == "" {
 		return ""
 	}
 	link := h.referralLink(user)
+	plusTarget, proTarget := domain.ReferralProgressTargets()
 	lines := []string{
 		"🎁 Реферальная программа:",
-		"• Пригласите 3 друзей — тариф Plus, 5 — Pro.",
-		fmt.Sprintf("• Уже приглашено: %d.", user.ReferralsCount),
+		fmt.Sprintf("• Пригласите %s — тариф Plus, %d — Pro.", pluralCount(plusTarget, "друга", "друзей", "друзей"), proTarget),
+		fmt.Sprintf("• Уже приглашено: %s.", pluralCount(user.ReferralsCount, "друг", "друга", "друзей")),
 	}
 	if link != "" {
 		lines = append(lines, fmt.Sprintf("• Ваша ссылка: %s", link))
@@ -1694,7 +3147,7 @@ func (h *Handler) referralLink(user domain.User) string {
 	if code == "" {
 		return ""
 	}
-	username := strings.TrimSpace(h.bot.Self.UserName)
+	username := strings.TrimSpace(h.self.UserName)
 	if username == "" {
 		return ""
 	}
@@ -1737,17 +3190,17 @@ func (h *Handler) buildReferralInfoMessage(user domain.User) string {
 	lines := []string{
 		"🎁 Реферальная программа",
 		"",
-		fmt.Sprintf("Приглашено друзей: %d.", user.ReferralsCount),
-		fmt.Sprintf("• %d приглашений — тариф Plus.", plusTarget),
-		fmt.Sprintf("• %d приглашений — тариф Pro.", proTarget),
+		fmt.Sprintf("Приглашено: %s.", pluralCount(user.ReferralsCount, "друг", "друга", "друзей")),
+		fmt.Sprintf("• %s — тариф Plus.", pluralCount(plusTarget, "приглашение", "приглашения", "приглашений")),
+		fmt.Sprintf("• %s — тариф Pro.", pluralCount(proTarget, "приглашение", "приглашения", "приглашений")),
 	}
 	switch {
 	case user.ReferralsCount < plusTarget:
 		remaining := plusTarget - user.ReferralsCount
-		lines = append(lines, "", fmt.Sprintf("До тарифа Plus осталось пригласить %d.", remaining))
+		lines = append(lines, "", fmt.Sprintf("До тарифа Plus осталось пригласить %s.", pluralCount(remaining, "друга", "друзей", "друзей")))
 	case user.ReferralsCount < proTarget:
 		remaining := proTarget - user.ReferralsCount
-		lines = append(lines, "", fmt.Sprintf("До тарифа Pro осталось пригласить %d.", remaining))
+		lines = append(lines, "", fmt.Sprintf("До тарифа Pro осталось пригласить %s.", pluralCount(remaining, "друга", "друзей", "друзей")))
 	default:
 		lines = append(lines, "", "Вы уже достигли максимального тарифа по рефералам. Спасибо, что делитесь ботом!")
 	}
@@ -1758,6 +3211,9 @@ func (h *Handler) buildReferralInfoMessage(user domain.User) string {
 		"",
 		"Ссылка учитывает только новых пользователей и не засчитывается при переходе самим собой.",
 	)
+	if h.leaderboard {
+		lines = append(lines, "", "Рейтинг пригласивших — /leaderboard.")
+	}
 	return strings.Join(lines, "\n")
 }
 
@@ -1798,7 +3254,7 @@ func (h *Handler) notifyPlanUpgrade(user domain.User, previousRole domain.UserRo
 	channelLine, manualLine := h.mainPlanLines(plan)
 	lines := []string{
 		"🎉 Ваш тариф обновлён!",
-		fmt.Sprintf("Вы перешли с %s на %s благодаря %d приглашённым друзьям.", prevPlan.Name, plan.Name, user.ReferralsCount),
+		fmt.Sprintf("Вы перешли с %s на %s благодаря %s.", prevPlan.Name, plan.Name, pluralCount(user.ReferralsCount, "приглашённому другу", "приглашённым друзьям", "приглашённым друзьям")),
 		"",
 		"Новые лимиты:",
 		fmt.Sprintf("• %s", channelLine),
@@ -1810,34 +3266,13 @@ func (h *Handler) notifyPlanUpgrade(user domain.User, previousRole domain.UserRo
 }
 
 func (h *Handler) buildHelpMessage() string {
-	sections := []string{
-		"📖 Основные команды и примеры:",
-		"",
-		"Управление каналами:",
-		"• /add @toporlive — добавить канал.",
-		"• /list — показать сохранённые каналы и действия с ними.",
-		"• /mute @toporlive — временно убрать канал из дайджеста.",
-		"• /unmute @toporlive — вернуть канал в дайджест.",
-		"• /tag @toporlive новости, аналитика — задать теги.",
-		"• /tags — посмотреть список ваших тегов.",
-		"",
-		"Дайджесты:",
-		"• /digest_now — собрать дайджест из всех немьютнутых каналов.",
-		"• /digest_tag новости — дайджест только по каналам с тегом \"новости\".",
-		"",
-		"Биллинг:",
-		"• /balance — показать баланс счёта.",
-		"• /deposit 500 — создать счёт на пополнение через СБП.",
-		"• /buy plus — купить подписку Plus (аналогично /buy pro).",
-		"",
-		"Расписание и данные:",
-		"• /schedule — открыть выбор времени.",
-		"• /schedule 21:30 — задать своё время рассылки.",
-		"• /timezone Europe/Moscow — выбрать часовой пояс или использовать меню бота.",
-		"• /clear_data — удалить аккаунт и все сохранённые данные.",
+	sections := []string{"📖 Основные команды и примеры:"}
+	sections = append(sections, helpCommandLines()...)
+	sections = append(sections,
 		"",
 		"Подсказка: используйте меню под сообщением, чтобы быстро перейти к нужному действию.",
-	}
+		"Краткое руководство для новичков снова покажет /guide.",
+	)
 	return strings.Join(sections, "\n")
 }
 
@@ -1955,6 +3390,23 @@ type subscriptionOffer struct {
 
 var defaultTopUpPresets = []int64{30000, 50000, 100000}
 
+// defaultTopUpMin — минимальное пополнение в копейках, если Deps.TopUpMin не задан.
+const defaultTopUpMin = 100
+
+const (
+	searchResultsLimit = 10
+	resendHistoryDays  = domain.DigestHistoryDays
+	resendHistoryLimit = 10
+	// maxScheduleInputAttempts — сколько раз подряд можно ошибиться при вводе времени.
+	maxScheduleInputAttempts = 3
+	// planChargeSettleDelay — сколько сверка не трогает свежую покупку, пока её завершает /buy.
+	planChargeSettleDelay = 2 * time.Minute
+	// planChargeAbandonAfter — через сколько покупка без списания в биллинге снимается со сверки.
+	planChargeAbandonAfter = 24 * time.Hour
+	// maxGrantedRequests ограничивает разовую выдачу ручных запросов, чтобы опечатка не сняла лимит совсем.
+	maxGrantedRequests = 100
+)
+
 func defaultSubscriptionOffers() map[string]subscriptionOffer {
 	return map[string]subscriptionOffer{
 		"plus": {
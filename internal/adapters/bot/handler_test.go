@@ -1,6 +1,20 @@
 package bot
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/adapters/telegram"
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/cache"
+)
 
 func TestParseLocalTime(t *testing.T) {
 	tm, err := ParseLocalTime(" 09:15 ")
@@ -17,3 +31,448 @@ func TestParseLocalTimeInvalid(t *testing.T) {
 		t.Fatal("expected error for invalid time format")
 	}
 }
+
+func TestBuildWhoAmIMessage(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	today := now.Add(-time.Hour)
+	user := domain.User{
+		ID:                  7,
+		TGUserID:            42,
+		Timezone:            "Europe/Moscow",
+		DailyTime:           time.Date(0, 1, 1, 21, 30, 0, 0, time.UTC),
+		Role:                domain.UserRolePlus,
+		ManualRequestsTotal: 5,
+		ManualRequestsToday: 2,
+		ManualRequestsDate:  &today,
+		ReferralsCount:      3,
+	}
+
+	msg := buildWhoAmIMessage(user, 4, &llmUsageSummary{Tokens: 1500, Cap: 100000}, now)
+	for _, want := range []string{"Plus", "Europe/Moscow", "21:30", "4 из 10", "2 сегодня, 5 всего", "Приглашено: 3 друга", "1500 из 100000 токенов"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "user_id") {
+		t.Fatalf("expected internal ids to be hidden for non-developers")
+	}
+
+	if msg := buildWhoAmIMessage(user, 4, &llmUsageSummary{Tokens: 100000, Cap: 100000}, now); !strings.Contains(msg, "лимит 100000 токенов исчерпан") {
+		t.Fatalf("expected exhausted llm cap in message, got:\n%s", msg)
+	}
+
+	user.Role = domain.UserRoleDeveloper
+	msg = buildWhoAmIMessage(user, 4, nil, now)
+	if !strings.Contains(msg, "user_id: 7") || !strings.Contains(msg, "tg_user_id: 42") {
+		t.Fatalf("expected internal ids for developer, got:\n%s", msg)
+	}
+}
+
+func TestCancelPendingClearsAllStates(t *testing.T) {
+	h := &Handler{}
+	h.pending.with(1, func(st *pendingState) { st.dropExpires = time.Now().Add(time.Minute) })
+	h.setPendingSchedule(1)
+	h.setPendingTimezone(1)
+	h.setPendingFeedback(10)
+	if !h.cancelPending(10, 1) {
+		t.Fatal("expected pending states to be reported")
+	}
+	h.pending.with(1, func(st *pendingState) {
+		if !st.dropExpires.IsZero() || st.awaitingTime || st.awaitingTZ {
+			t.Fatal("expected all pending states to be cleared")
+		}
+	})
+	h.pending.with(10, func(st *pendingState) {
+		if st.awaitingFB {
+			t.Fatal("expected pending feedback to be cleared")
+		}
+	})
+	if h.cancelPending(10, 1) {
+		t.Fatal("expected nothing to cancel on second call")
+	}
+}
+
+func TestRegisterScheduleInputFailureClearsAfterLimit(t *testing.T) {
+	h := &Handler{}
+	h.setPendingSchedule(1)
+	for i := 1; i < maxScheduleInputAttempts; i++ {
+		if h.registerScheduleInputFailure(1) {
+			t.Fatalf("attempt %d: pending state cleared too early", i)
+		}
+	}
+	if !h.registerScheduleInputFailure(1) {
+		t.Fatal("expected pending state to be cleared after the last attempt")
+	}
+	h.pending.with(1, func(st *pendingState) {
+		if st.awaitingTime {
+			t.Fatal("expected pending time to be removed")
+		}
+	})
+	if h.registerScheduleInputFailure(1) {
+		t.Fatal("no pending state should not report clearing")
+	}
+}
+
+func TestPlural(t *testing.T) {
+	cases := []struct {
+		n    int
+		want string
+	}{
+		{0, "каналов"},
+		{1, "канал"},
+		{2, "канала"},
+		{4, "канала"},
+		{5, "каналов"},
+		{11, "каналов"},
+		{12, "каналов"},
+		{14, "каналов"},
+		{20, "каналов"},
+		{21, "канал"},
+		{22, "канала"},
+		{101, "канал"},
+		{111, "каналов"},
+	}
+	for _, tc := range cases {
+		if got := plural(tc.n, "канал", "канала", "каналов"); got != tc.want {
+			t.Fatalf("plural(%d) = %q, want %q", tc.n, got, tc.want)
+		}
+	}
+	if got := pluralCount(3, "друг", "друга", "друзей"); got != "3 друга" {
+		t.Fatalf("unexpected pluralCount: %q", got)
+	}
+}
+
+func TestParseGrantChannelsArgs(t *testing.T) {
+	id, limit, err := parseGrantChannelsArgs("42 0")
+	if err != nil || id != 42 || limit == nil || *limit != 0 {
+		t.Fatalf("expected unlimited override for 42, got id=%d limit=%v err=%v", id, limit, err)
+	}
+	id, limit, err = parseGrantChannelsArgs("42 reset")
+	if err != nil || id != 42 || limit != nil {
+		t.Fatalf("expected reset for 42, got id=%d limit=%v err=%v", id, limit, err)
+	}
+	for _, bad := range []string{"", "42", "abc 5", "42 -1", "42 5 7"} {
+		if _, _, err := parseGrantChannelsArgs(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestBuildWhoAmIMessageShowsChannelOverride(t *testing.T) {
+	unlimited := 0
+	user := domain.User{Role: domain.UserRoleFree, ChannelLimitOverride: &unlimited}
+	msg := buildWhoAmIMessage(user, 7, nil, time.Now())
+	if !strings.Contains(msg, "Каналы: 7 (индивидуально: без ограничений)") {
+		t.Fatalf("expected unlimited override in message, got:\n%s", msg)
+	}
+}
+
+func TestBuildWhoAmIMessageShowsPause(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	until := now.Add(72 * time.Hour)
+	user := domain.User{Timezone: "Europe/Moscow", DailyTime: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Paused: true, PausedUntil: &until}
+	if msg := buildWhoAmIMessage(user, 1, nil, now); !strings.Contains(msg, "09:00 (на паузе до 13.05 15:00)") {
+		t.Fatalf("expected pause deadline in user time, got:\n%s", msg)
+	}
+	if msg := buildWhoAmIMessage(user, 1, nil, until); strings.Contains(msg, "на паузе") {
+		t.Fatalf("expected expired pause to be hidden, got:\n%s", msg)
+	}
+	user.PausedUntil = nil
+	if msg := buildWhoAmIMessage(user, 1, nil, until); !strings.Contains(msg, "на паузе без срока") {
+		t.Fatalf("expected open-ended pause, got:\n%s", msg)
+	}
+}
+
+func TestParseEmailAddress(t *testing.T) {
+	if got, ok := parseEmailAddress("User@Example.com"); !ok || got != "user@example.com" {
+		t.Fatalf("expected normalized address, got %q ok=%v", got, ok)
+	}
+	for _, bad := range []string{"", "not-an-email", "Name <user@example.com>", "user@example.com\r\nBcc: x@y.z"} {
+		if _, ok := parseEmailAddress(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestGenerateConfirmationCode(t *testing.T) {
+	code, err := generateConfirmationCode()
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	if len(code) != 6 {
+		t.Fatalf("expected 6-digit code, got %q", code)
+	}
+}
+
+func TestParseMuteDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30m": 30 * time.Minute,
+		"12h": 12 * time.Hour,
+		"3d":  72 * time.Hour,
+		"1W":  7 * 24 * time.Hour,
+	}
+	for input, want := range cases {
+		got, err := parseMuteDuration(input)
+		if err != nil || got != want {
+			t.Fatalf("parseMuteDuration(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "d", "0d", "-1d", "3x", "100d"} {
+		if _, err := parseMuteDuration(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestBuildMTProtoAccountsMessage(t *testing.T) {
+	msg := buildMTProtoAccountsMessage("main", []domain.MTProtoAccount{
+		{Name: "alpha", Phone: "+7900", Username: "alpha_bot", Enabled: true, HasSession: true},
+		{Name: "beta", Enabled: false},
+	})
+	for _, want := range []string{"пула main", "alpha — ✅ включён", "+7900, @alpha_bot", "beta — ⛔ отключён", "нет сессии"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message:\n%s", want, msg)
+		}
+	}
+	if empty := buildMTProtoAccountsMessage("main", nil); !strings.Contains(empty, "нет MTProto-аккаунтов") {
+		t.Fatalf("unexpected empty pool message: %s", empty)
+	}
+}
+
+func TestBuildMTProtoPoolsMessage(t *testing.T) {
+	msg := buildMTProtoPoolsMessage("main", []domain.MTProtoPoolStats{
+		{Name: "eu", Accounts: 2, Enabled: 2, WithSession: 1, Channels: 3},
+		{Name: "main", Accounts: 3, Enabled: 3, WithSession: 3},
+		{Name: "us", Accounts: 1},
+	})
+	for _, want := range []string{"⚠️ eu — включено 2 из 2, с сессией 1", "закреплено 3 канала", "✅ main (по умолчанию)", "⛔ us"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message:\n%s", want, msg)
+		}
+	}
+}
+
+func TestParseGrantRequestsArgs(t *testing.T) {
+	id, extra, err := parseGrantRequestsArgs("42 5")
+	if err != nil || id != 42 || extra != 5 {
+		t.Fatalf("unexpected result: %d %d %v", id, extra, err)
+	}
+	id, extra, err = parseGrantRequestsArgs("42 reset")
+	if err != nil || id != 42 || extra != 0 {
+		t.Fatalf("reset must map to zero: %d %d %v", id, extra, err)
+	}
+	for _, bad := range []string{"", "42", "x 1", "42 -1", "42 1000", "42 1 2"} {
+		if _, _, err := parseGrantRequestsArgs(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestBuildNextDigestMessage(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	next := time.Date(2024, 5, 11, 9, 0, 0, 0, loc)
+	expired := next.Add(-time.Hour)
+	later := next.Add(72 * time.Hour)
+	channels := []domain.UserChannel{
+		{Channel: domain.Channel{Alias: "news_daily", Title: "News"}, Tags: []string{"новости", " аналитика "}},
+		{Channel: domain.Channel{Alias: "tech_daily"}, Muted: true, MutedUntil: &expired, Tags: []string{"tech"}},
+		{Channel: domain.Channel{Alias: "sport_daily", Title: "Sport"}, Muted: true, MutedUntil: &later, Tags: []string{"sport"}},
+		{Channel: domain.Channel{Alias: "memes_daily", Title: "Memes"}, Muted: true},
+	}
+
+	msg := buildNextDigestMessage(next, channels)
+	for _, want := range []string{"11.05 в 09:00 (Europe/Moscow)", "Каналы (2)", "News (@news_daily)", "tech_daily (@tech_daily)", "Теги: tech, аналитика, новости", "Sport (@sport_daily) — до 14.05", "Memes (@memes_daily)"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "sport,") || strings.Contains(msg, ", sport") {
+		t.Fatalf("expected muted channel tags to be excluded, got:\n%s", msg)
+	}
+
+	msg = buildNextDigestMessage(next, nil)
+	if !strings.Contains(msg, "/add") {
+		t.Fatalf("expected hint for empty digest, got:\n%s", msg)
+	}
+}
+
+func TestBuildLeaderboardMessage(t *testing.T) {
+	top := []domain.ReferrerStanding{
+		{UserID: 1, Rank: 1, FirstName: "Anna", ReferralsCount: 7},
+		{UserID: 2, Rank: 2, Username: "boris_k", ReferralsCount: 4},
+		{UserID: 3, Rank: 2, ReferralsCount: 4},
+	}
+
+	msg := buildLeaderboardMessage(top, domain.ReferrerStanding{UserID: 2, Rank: 2, ReferralsCount: 4})
+	for _, want := range []string{"1. Anna — 7 друзей", "2. @bo*** — 4 друга (вы)", "2. Аноним — 4 друга"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "boris_k") || strings.Contains(msg, "Ваше место") {
+		t.Fatalf("expected masked username and no separate rank line, got:\n%s", msg)
+	}
+
+	msg = buildLeaderboardMessage(top, domain.ReferrerStanding{UserID: 9, Rank: 15, ReferralsCount: 1})
+	if !strings.Contains(msg, "Ваше место: 15 — 1 друг.") {
+		t.Fatalf("expected own rank outside top, got:\n%s", msg)
+	}
+
+	msg = buildLeaderboardMessage(nil, domain.ReferrerStanding{UserID: 9, Rank: 1})
+	if !strings.Contains(msg, "станьте первым") || !strings.Contains(msg, "никого не пригласили") {
+		t.Fatalf("expected empty leaderboard hints, got:\n%s", msg)
+	}
+}
+
+func TestStartSectionsEndWithGuide(t *testing.T) {
+	h := &Handler{bot: telegram.NewClient(&tgbotapi.BotAPI{})}
+	user := domain.User{FirstName: "Анна", ReferralCode: "ABCD2345"}
+	start := h.buildStartSections(user)
+	guide := h.buildGuideSections(user)
+	if len(guide) != 2 || len(start) != len(guide)+1 {
+		t.Fatalf("expected greeting plus quick start and referral sections, got %d and %d", len(start), len(guide))
+	}
+	if !strings.Contains(start[0], "Привет, Анна") {
+		t.Fatalf("expected greeting first, got:\n%s", start[0])
+	}
+	for i, section := range guide {
+		if start[i+1] != section {
+			t.Fatalf("guide section %d differs from start:\n%s\n---\n%s", i, section, start[i+1])
+		}
+	}
+}
+
+func TestReferralMessagesUseConfiguredTiers(t *testing.T) {
+	plus, pro := domain.ReferralProgressTargets()
+	t.Cleanup(func() { _ = domain.SetReferralTiers(plus, pro) })
+	if err := domain.SetReferralTiers(2, 4); err != nil {
+		t.Fatalf("set tiers: %v", err)
+	}
+
+	h := &Handler{bot: telegram.NewClient(&tgbotapi.BotAPI{})}
+	user := domain.User{ReferralCode: "ABCD2345", ReferralsCount: 1}
+	if preview := h.buildReferralPreview(user); !strings.Contains(preview, "Пригласите 2 друзей — тариф Plus, 4 — Pro.") {
+		t.Fatalf("preview should use configured tiers, got:\n%s", preview)
+	}
+	info := h.buildReferralInfoMessage(user)
+	for _, want := range []string{"2 приглашения — тариф Plus", "4 приглашения — тариф Pro", "До тарифа Plus осталось пригласить 1 друга"} {
+		if !strings.Contains(info, want) {
+			t.Fatalf("expected %q in info, got:\n%s", want, info)
+		}
+	}
+}
+
+func TestSplitAliasList(t *testing.T) {
+	got := splitAliasList("@Golang t.me/golang\n@rust_lang, https://t.me/s/Rust_Lang  @tech_news")
+	want := []string{"@Golang", "@rust_lang", "@tech_news"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBuildBulkAddMessage(t *testing.T) {
+	msg := buildBulkAddMessage([]bulkAddResult{
+		{Input: "@golang", Title: "Go News", Status: bulkAddAdded},
+		{Input: "@rust_lang", Status: bulkAddPresent},
+		{Input: "t.me/+abc", Status: bulkAddFailed, Reason: "приватный или недоступен"},
+	}, 2)
+	for _, want := range []string{"Добавлены:\n• Go News", "Уже в подписках:\n• @rust_lang", "• t.me/+abc — приватный или недоступен", "не обработано — 2 канала"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message:\n%s", want, msg)
+		}
+	}
+}
+
+func TestBuildChannelCheckMessage(t *testing.T) {
+	meta := domain.ChannelMeta{Alias: "golang", Title: "Go News", Public: true}
+	msg := buildChannelCheckMessage(domain.ChannelProbe{Meta: meta, Readable: true})
+	if !strings.Contains(msg, "готов к сбору") || !strings.Contains(msg, "/add @golang") {
+		t.Fatalf("unexpected readable message: %s", msg)
+	}
+	msg = buildChannelCheckMessage(domain.ChannelProbe{Meta: meta, Reason: domain.ProbeReasonPrivate})
+	if !strings.Contains(msg, "Go News (@golang) не готов к сбору") || !strings.Contains(msg, "история закрыта") {
+		t.Fatalf("unexpected private message: %s", msg)
+	}
+	msg = buildChannelCheckMessage(domain.ChannelProbe{Reason: domain.ProbeReasonNotFound})
+	if !strings.Contains(msg, "не найден") {
+		t.Fatalf("unexpected not found message: %s", msg)
+	}
+}
+
+func TestClaimDigestRequestRejectsDoubleTap(t *testing.T) {
+	api := &tgbotapi.BotAPI{Token: "test", Client: okBotClient{}}
+	api.SetAPIEndpoint("http://bot.test/bot%s/%s")
+	h := &Handler{bot: telegram.NewClient(api), log: zerolog.Nop(), inflight: cache.NewMemorySeen()}
+
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.claimDigestRequest(context.Background(), 1, 42) {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed.Load() != 1 {
+		t.Fatalf("expected exactly one of two taps to pass, got %d", claimed.Load())
+	}
+	if !h.claimDigestRequest(context.Background(), 2, 43) {
+		t.Fatalf("another user must not be blocked")
+	}
+}
+
+func TestTopUpPresetKeyboardUsesConfiguredPresets(t *testing.T) {
+	h := NewHandler(Deps{TopUpPresets: []int64{20000, 150000}})
+	markup := h.topUpPresetKeyboard()
+	row := markup.InlineKeyboard[0]
+	if len(row) != 2 {
+		t.Fatalf("expected two preset buttons, got %d", len(row))
+	}
+	if data := *row[1].CallbackData; data != "billing_topup:1500" {
+		t.Fatalf("unexpected preset callback %q", data)
+	}
+
+	h.topUpPresets = nil
+	if markup := h.topUpPresetKeyboard(); len(markup.InlineKeyboard[0]) != 2 || *markup.InlineKeyboard[0][0].CallbackData != "billing_topup" {
+		t.Fatal("without presets the balance keyboard should be shown")
+	}
+}
+
+func TestFormatMoneyHidesZeroKopecks(t *testing.T) {
+	cases := map[int64]string{
+		50000:  "500 ₽",
+		24999:  "249.99 ₽",
+		24990:  "249.90 ₽",
+		5:      "0.05 ₽",
+		0:      "0 ₽",
+		-30000: "-300 ₽",
+		-150:   "-1.50 ₽",
+	}
+	for amount, want := range cases {
+		if got := formatMoney(amount, "RUB"); got != want {
+			t.Fatalf("formatMoney(%d) = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestPresetButtonDataRoundTrip(t *testing.T) {
+	for _, amount := range []int64{100, 30000, 50000, 100000, 24999, 24990, 5} {
+		label, payload := presetButtonData(amount)
+		if label != formatMoney(amount, "RUB") {
+			t.Fatalf("unexpected label %q for %d", label, amount)
+		}
+		parsed, err := parseAmountToMinor(payload)
+		if err != nil {
+			t.Fatalf("payload %q for %d does not parse: %v", payload, amount, err)
+		}
+		if parsed != amount {
+			t.Fatalf("round trip of %d via %q gave %d", amount, payload, parsed)
+		}
+	}
+}
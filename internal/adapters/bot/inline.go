@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+const (
+	// inlineResultPrefix начинает идентификатор результата; при выборе результата канал добавляется пользователю.
+	inlineResultPrefix = "add_found:"
+	inlineResultsLimit = 10
+	// inlineCacheSeconds — сколько Telegram может показывать ответ на тот же запрос без обращения к боту.
+	inlineCacheSeconds = 30
+	// inlineResolveInterval — как часто один пользователь может резолвить незнакомый алиас через MTProto.
+	inlineResolveInterval = 3 * time.Second
+)
+
+// handleInlineQuery отвечает на @bot <алиас> списком каналов, которые можно добавить.
+func (h *Handler) handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
+	query := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(q.Query), "@"))
+	var found []domain.Channel
+	if query != "" {
+		var err error
+		found, err = h.channelUC.SearchChannels(ctx, query, inlineResultsLimit)
+		if err != nil {
+			h.log.Error().Err(err).Str("query", query).Msg("bot: inline search channels failed")
+		}
+		// Недописанный алиас не тратит лимит: резолвим только то, что может быть алиасом канала.
+		if _, aliasErr := channels.ParseAlias(query); aliasErr == nil && !hasAlias(found, query) && h.allowInlineResolve(ctx, q.From) {
+			channel, err := h.channelUC.LookupChannel(ctx, query)
+			if err != nil {
+				h.log.Debug().Err(err).Str("query", query).Msg("bot: inline resolve failed")
+			} else {
+				found = append([]domain.Channel{channel}, found...)
+			}
+		}
+	}
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: q.ID,
+		Results:       buildInlineResults(found),
+		CacheTime:     inlineCacheSeconds,
+		IsPersonal:    true,
+	}
+	start := time.Now()
+	_, err := h.bot.Request(answer)
+	metrics.ObserveNetworkRequest("telegram_bot", "answer_inline_query", strconv.FormatInt(q.From.ID, 10), start, err)
+	if err != nil {
+		h.log.Error().Err(err).Msg("не удалось ответить на inline-запрос")
+	}
+}
+
+// handleChosenInlineResult добавляет пользователю выбранный в inline-режиме канал.
+// Telegram присылает выбор, только если для бота включён /setinlinefeedback.
+func (h *Handler) handleChosenInlineResult(ctx context.Context, chosen *tgbotapi.ChosenInlineResult) {
+	if chosen.From == nil || !strings.HasPrefix(chosen.ResultID, inlineResultPrefix) {
+		return
+	}
+	h.handleAddFound(ctx, chosen.From.ID, chosen.From.ID, parseID(chosen.ResultID))
+}
+
+func (h *Handler) allowInlineResolve(ctx context.Context, from *tgbotapi.User) bool {
+	if h.resolveLimit == nil || from == nil {
+		return false
+	}
+	fresh, err := h.resolveLimit.MarkSeen(ctx, fmt.Sprintf("inline:resolve:%d", from.ID), inlineResolveInterval)
+	if err != nil {
+		h.log.Warn().Err(err).Int64("user", from.ID).Msg("bot: inline resolve limit check failed")
+		return false
+	}
+	return fresh
+}
+
+func hasAlias(found []domain.Channel, query string) bool {
+	for _, ch := range found {
+		if strings.EqualFold(ch.Alias, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildInlineResults превращает каналы в статьи inline-ответа; в чат уходит ссылка на канал.
+func buildInlineResults(found []domain.Channel) []interface{} {
+	results := make([]interface{}, 0, len(found))
+	seen := make(map[int64]struct{}, len(found))
+	for _, ch := range found {
+		if _, ok := seen[ch.ID]; ok || ch.ID <= 0 {
+			continue
+		}
+		seen[ch.ID] = struct{}{}
+		title := ch.Title
+		if title == "" {
+			title = "@" + ch.Alias
+		}
+		article := tgbotapi.NewInlineQueryResultArticle(
+			fmt.Sprintf("%s%d", inlineResultPrefix, ch.ID),
+			title,
+			fmt.Sprintf("📡 %s — https://t.me/%s", title, ch.Alias),
+		)
+		article.Description = fmt.Sprintf("@%s · выберите, чтобы добавить в дайджест", ch.Alias)
+		results = append(results, article)
+		if len(results) == inlineResultsLimit {
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestBuildInlineResults(t *testing.T) {
+	results := buildInlineResults([]domain.Channel{
+		{ID: 7, Alias: "toporlive", Title: "Топор"},
+		{ID: 7, Alias: "toporlive", Title: "Топор"},
+		{ID: 9, Alias: "noname"},
+		{Alias: "unsaved"},
+	})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results without duplicates and unsaved channels, got %d", len(results))
+	}
+	first := results[0].(tgbotapi.InlineQueryResultArticle)
+	if first.ID != "add_found:7" || first.Title != "Топор" {
+		t.Fatalf("unexpected first result: %+v", first)
+	}
+	if parseID(first.ID) != 7 {
+		t.Fatalf("result id must be parsable by parseID, got %q", first.ID)
+	}
+	if second := results[1].(tgbotapi.InlineQueryResultArticle); second.Title != "@noname" {
+		t.Fatalf("expected alias as title for untitled channel, got %q", second.Title)
+	}
+}
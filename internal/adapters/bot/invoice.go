@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const checkInvoiceCallbackPrefix = "check_invoice:"
+
+// handleInvoiceStatus проверяет оплату счёта по запросу пользователя: /invoice <номер> или кнопка
+// «Проверить оплату». Пригодится, когда вебхук банка задерживается.
+func (h *Handler) handleInvoiceStatus(ctx context.Context, chatID, tgUserID int64, payload string) {
+	invoiceID, err := strconv.ParseInt(strings.TrimSpace(payload), 10, 64)
+	if err != nil || invoiceID <= 0 {
+		h.reply(chatID, "Формат: /invoice 123 — номер счёта есть в сообщении о пополнении.", nil)
+		return
+	}
+	if h.billing == nil {
+		h.reply(chatID, "Биллинг временно недоступен. Попробуйте позже.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	account, err := h.billing.EnsureAccount(ctx, user.ID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("billing: ensure account failed")
+		h.reply(chatID, "Не удалось проверить счёт. Попробуйте позже.", nil)
+		return
+	}
+	status, err := h.billing.GetInvoiceStatus(ctx, invoiceID)
+	// Чужой счёт отвечаем так же, как несуществующий, чтобы не раскрывать номера.
+	if errors.Is(err, domain.ErrInvoiceNotFound) || (err == nil && status.AccountID != account.ID) {
+		h.reply(chatID, fmt.Sprintf("Счёт %d не найден.", invoiceID), nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("invoice", invoiceID).Msg("billing: get invoice status failed")
+		h.reply(chatID, "Не удалось проверить счёт. Попробуйте позже.", nil)
+		return
+	}
+	if status.Status == domain.InvoiceStatusPaid {
+		// Баланс перечитываем: EnsureAccount выше мог вернуть его до зачисления.
+		if fresh, err := h.billing.GetAccountByUserID(ctx, user.ID); err == nil {
+			account = fresh
+		}
+	}
+	keyboard := h.balanceKeyboard()
+	if status.Status == domain.InvoiceStatusPending || status.Status == domain.InvoiceStatusPartiallyPaid {
+		keyboard = h.topUpInvoiceKeyboard("", invoiceID)
+	}
+	h.reply(chatID, buildInvoiceStatusMessage(status, account.Balance, time.Now()), keyboard)
+}
+
+func buildInvoiceStatusMessage(status domain.InvoiceStatus, balance domain.Money, now time.Time) string {
+	amount := formatMoney(status.Amount.Amount, status.Amount.Currency)
+	switch status.Status {
+	case domain.InvoiceStatusPaid:
+		return fmt.Sprintf("✅ Счёт %d на %s оплачен.\nБаланс: %s.", status.InvoiceID, amount, formatMoney(balance.Amount, balance.Currency))
+	case domain.InvoiceStatusPartiallyPaid:
+		return fmt.Sprintf("🕓 По счёту %d получено %s из %s. Ждём оставшуюся сумму.", status.InvoiceID, formatMoney(status.PaidAmount, status.Amount.Currency), amount)
+	case domain.InvoiceStatusCancelled:
+		return fmt.Sprintf("Счёт %d отменён. Создайте новый: /deposit.", status.InvoiceID)
+	}
+	if status.ExpiresAt != nil && !now.Before(*status.ExpiresAt) {
+		return fmt.Sprintf("Срок оплаты счёта %d истёк. Создайте новый: /deposit.", status.InvoiceID)
+	}
+	return fmt.Sprintf("🕓 Оплата по счёту %d на %s ещё не поступила. Если вы уже оплатили, проверьте ещё раз через пару минут.", status.InvoiceID, amount)
+}
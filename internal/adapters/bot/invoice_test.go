@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestBuildInvoiceStatusMessage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	rub := func(amount int64) domain.Money { return domain.Money{Amount: amount, Currency: "RUB"} }
+	cases := map[string]struct {
+		status domain.InvoiceStatus
+		want   string
+	}{
+		"paid":      {domain.InvoiceStatus{InvoiceID: 7, Status: "paid", Amount: rub(50000)}, "Баланс: 1"},
+		"partial":   {domain.InvoiceStatus{InvoiceID: 7, Status: "partially_paid", Amount: rub(50000), PaidAmount: 20000}, "получено 200"},
+		"cancelled": {domain.InvoiceStatus{InvoiceID: 7, Status: "cancelled"}, "отменён"},
+		"expired":   {domain.InvoiceStatus{InvoiceID: 7, Status: "pending", ExpiresAt: &past}, "истёк"},
+		"pending":   {domain.InvoiceStatus{InvoiceID: 7, Status: "pending", Amount: rub(50000)}, "ещё не поступила"},
+	}
+	for name, tc := range cases {
+		msg := buildInvoiceStatusMessage(tc.status, rub(120000), now)
+		if !strings.Contains(msg, tc.want) {
+			t.Fatalf("%s: expected %q in %q", name, tc.want, msg)
+		}
+	}
+}
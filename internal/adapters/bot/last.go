@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// handleLast показывает, сколько пунктов каждый канал дал в последний доставленный ежедневный дайджест.
+func (h *Handler) handleLast(chatID, tgUserID int64) {
+	if h.digests == nil {
+		h.reply(chatID, "История дайджестов временно недоступна.", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for last failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	from := time.Now().UTC().AddDate(0, 0, -domain.DigestHistoryDays)
+	history, err := h.digests.ListDigestHistory(user.ID, from)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: list digest history failed")
+		h.reply(chatID, "Не удалось получить историю дайджестов. Попробуйте позже.", nil)
+		return
+	}
+	var last *domain.Digest
+	for i := range history {
+		if history[i].DeliveredAt != nil && history[i].Scope == "" {
+			last = &history[i]
+			break
+		}
+	}
+	if last == nil {
+		h.reply(chatID, "Доставленных дайджестов пока нет. Когда придёт первый, здесь будет видно, сколько пунктов дал каждый канал.", nil)
+		return
+	}
+	shares, err := h.digests.DigestChannelBreakdown(last.ID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("digest", last.ID).Msg("bot: digest channel breakdown failed")
+		h.reply(chatID, "Не удалось посчитать вклад каналов. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, buildDigestBreakdownMessage(last.Date, shares), nil)
+}
+
+// buildDigestBreakdownMessage перечисляет каналы с числом пунктов; каналы без пунктов идут последними.
+func buildDigestBreakdownMessage(date time.Time, shares []domain.DigestChannelShare) string {
+	total := 0
+	for _, share := range shares {
+		total += share.Items
+	}
+	lines := []string{fmt.Sprintf("📊 Дайджест за %s: %s.", date.Format("02.01.2006"), pluralCount(total, "пункт", "пункта", "пунктов"))}
+	empty := false
+	for _, share := range shares {
+		line := fmt.Sprintf("• %s — %d", channelTitle(share.Channel), share.Items)
+		if share.Muted {
+			line += " (выключен)"
+		}
+		if share.Items == 0 {
+			empty = true
+		}
+		lines = append(lines, line)
+	}
+	if empty {
+		lines = append(lines, "", "Канал получает 0, если он выключен, его посты отфильтрованы (реклама, язык) или оказались менее важными, чем у других каналов.")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestBuildDigestBreakdownMessage(t *testing.T) {
+	date := time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)
+	msg := buildDigestBreakdownMessage(date, []domain.DigestChannelShare{
+		{Channel: domain.Channel{Alias: "news", Title: "Новости"}, Items: 4},
+		{Channel: domain.Channel{Alias: "quiet"}, Muted: true},
+	})
+	want := "📊 Дайджест за 03.05.2024: 4 пункта.\n" +
+		"• Новости — 4\n" +
+		"• @quiet — 0 (выключен)\n" +
+		"\n" +
+		"Канал получает 0, если он выключен, его посты отфильтрованы (реклама, язык) или оказались менее важными, чем у других каналов."
+	if msg != want {
+		t.Fatalf("unexpected message:\n%s", msg)
+	}
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+// isDeveloper проверяет, что команду вызвал разработчик, и отвечает отказом в противном случае.
+func (h *Handler) isDeveloper(chatID, tgUserID int64) bool {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil || user.Role != domain.UserRoleDeveloper {
+		h.reply(chatID, "Команда доступна только разработчикам.", nil)
+		return false
+	}
+	return true
+}
+
+// handleMTProtoList показывает аккаунты пула MTProto с их состоянием.
+func (h *Handler) handleMTProtoList(ctx context.Context, chatID, tgUserID int64) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.mtprotoAccounts == nil {
+		h.reply(chatID, "Управление MTProto-аккаунтами не настроено.", nil)
+		return
+	}
+	accounts, err := h.mtprotoAccounts.ListMTProtoAccounts(ctx, h.mtprotoPool)
+	if err != nil {
+		h.log.Error().Err(err).Str("pool", h.mtprotoPool).Msg("bot: list mtproto accounts failed")
+		h.reply(chatID, "Не удалось загрузить аккаунты. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, buildMTProtoAccountsMessage(h.mtprotoPool, accounts), nil)
+}
+
+// handleMTProtoToggle включает или отключает аккаунт: /mtproto_enable <name>, /mtproto_disable <name>.
+func (h *Handler) handleMTProtoToggle(ctx context.Context, chatID, tgUserID int64, name string, enabled bool) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.mtprotoAccounts == nil {
+		h.reply(chatID, "Управление MTProto-аккаунтами не настроено.", nil)
+		return
+	}
+	command := "/mtproto_disable"
+	if enabled {
+		command = "/mtproto_enable"
+	}
+	if name == "" || strings.ContainsAny(name, " \t") {
+		h.reply(chatID, fmt.Sprintf("Формат: %s <имя аккаунта>. Список — /mtproto_list.", command), nil)
+		return
+	}
+	err := h.mtprotoAccounts.SetMTProtoAccountEnabled(ctx, h.mtprotoPool, name, enabled)
+	if errors.Is(err, domain.ErrMTProtoAccountNotFound) {
+		h.reply(chatID, fmt.Sprintf("Аккаунт %s не найден в пуле %s.", name, h.mtprotoPool), nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Str("account", name).Bool("enabled", enabled).Msg("bot: toggle mtproto account failed")
+		h.reply(chatID, "Не удалось изменить аккаунт. Попробуйте позже.", nil)
+		return
+	}
+	h.log.Info().Int64("admin", tgUserID).Str("account", name).Bool("enabled", enabled).Msg("bot: mtproto account toggled")
+	state := "отключён"
+	if enabled {
+		state = "включён"
+	}
+	h.reply(chatID, fmt.Sprintf("Аккаунт %s %s. Пулы collector и bot-gateway подхватят изменение при следующем запуске.", name, state), nil)
+}
+
+// handleMTProtoPools показывает все пулы MTProto: сколько в них аккаунтов, живых сессий и каналов.
+func (h *Handler) handleMTProtoPools(ctx context.Context, chatID, tgUserID int64) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.mtprotoAccounts == nil {
+		h.reply(chatID, "Управление MTProto-аккаунтами не настроено.", nil)
+		return
+	}
+	pools, err := h.mtprotoAccounts.ListMTProtoPools(ctx)
+	if err != nil {
+		h.log.Error().Err(err).Msg("bot: list mtproto pools failed")
+		h.reply(chatID, "Не удалось загрузить пулы. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, buildMTProtoPoolsMessage(h.mtprotoPool, pools), nil)
+}
+
+// handleChannelPool закрепляет канал за пулом: /channel_pool @alias <пул|default>.
+func (h *Handler) handleChannelPool(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.mtprotoAccounts == nil {
+		h.reply(chatID, "Управление MTProto-аккаунтами не настроено.", nil)
+		return
+	}
+	fields := strings.Fields(payload)
+	if len(fields) != 2 {
+		h.reply(chatID, "Формат: /channel_pool @alias <пул>. Вернуть пул по умолчанию — /channel_pool @alias default. Пулы — /mtproto_pools.", nil)
+		return
+	}
+	alias, err := channels.ParseAlias(fields[0])
+	if err != nil {
+		h.reply(chatID, "Некорректный алиас канала.", nil)
+		return
+	}
+	pool := fields[1]
+	if pool == "default" || pool == h.mtprotoPool {
+		pool = ""
+	}
+	err = h.mtprotoAccounts.SetChannelMTProtoPool(ctx, alias, pool)
+	if errors.Is(err, domain.ErrChannelNotFound) {
+		h.reply(chatID, fmt.Sprintf("Канал @%s не найден.", alias), nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Str("alias", alias).Str("pool", pool).Msg("bot: set channel mtproto pool failed")
+		h.reply(chatID, "Не удалось изменить пул канала. Попробуйте позже.", nil)
+		return
+	}
+	h.log.Info().Int64("admin", tgUserID).Str("alias", alias).Str("pool", pool).Msg("bot: channel mtproto pool changed")
+	if pool == "" {
+		h.reply(chatID, fmt.Sprintf("Канал @%s собирается через пул по умолчанию (%s).", alias, h.mtprotoPool), nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("Канал @%s закреплён за пулом %s. Если пул не загружен в collector, сбор пойдёт через пул по умолчанию.", alias, pool), nil)
+}
+
+func buildMTProtoPoolsMessage(defaultPool string, pools []domain.MTProtoPoolStats) string {
+	if len(pools) == 0 {
+		return "Пулы MTProto не найдены."
+	}
+	var b strings.Builder
+	b.WriteString("Пулы MTProto:\n")
+	for _, pool := range pools {
+		status := "✅"
+		switch {
+		case pool.Enabled == 0:
+			status = "⛔"
+		case pool.WithSession < pool.Enabled:
+			status = "⚠️"
+		}
+		name := pool.Name
+		if name == defaultPool {
+			name += " (по умолчанию)"
+		}
+		fmt.Fprintf(&b, "\n%s %s — включено %d из %d, с сессией %d", status, name, pool.Enabled, pool.Accounts, pool.WithSession)
+		if pool.Channels > 0 {
+			fmt.Fprintf(&b, "\n  закреплено %s", pluralCount(pool.Channels, "канал", "канала", "каналов"))
+		}
+	}
+	return b.String()
+}
+
+func buildMTProtoAccountsMessage(pool string, accounts []domain.MTProtoAccount) string {
+	if len(accounts) == 0 {
+		return fmt.Sprintf("В пуле %s нет MTProto-аккаунтов.", pool)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "MTProto-аккаунты пула %s:\n", pool)
+	for _, account := range accounts {
+		status := "✅ включён"
+		if !account.Enabled {
+			status = "⛔ отключён"
+		}
+		fmt.Fprintf(&b, "\n%s — %s", account.Name, status)
+		var contacts []string
+		if account.Phone != "" {
+			contacts = append(contacts, account.Phone)
+		}
+		if account.Username != "" {
+			contacts = append(contacts, "@"+strings.TrimPrefix(account.Username, "@"))
+		}
+		if len(contacts) > 0 {
+			fmt.Fprintf(&b, "\n  %s", strings.Join(contacts, ", "))
+		}
+		session := "сессия сохранена"
+		if !account.HasSession {
+			session = "нет сессии — нужен импорт"
+		}
+		fmt.Fprintf(&b, "\n  %s", session)
+		if !account.UpdatedAt.IsZero() {
+			fmt.Fprintf(&b, ", обновлён %s", account.UpdatedAt.UTC().Format("02.01.2006 15:04 UTC"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// handleOrder задаёт порядок пунктов дайджеста: relevance, time или engagement.
+func (h *Handler) handleOrder(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for order failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	if payload == "" {
+		h.reply(chatID, fmt.Sprintf("Пункты дайджеста сейчас идут %s. Изменить: /order relevance, /order time или /order engagement.", digestOrderLabel(user.DigestOrder)), nil)
+		return
+	}
+	order, ok := domain.ParseDigestOrder(strings.ToLower(payload))
+	if !ok {
+		h.reply(chatID, "Формат: /order relevance, /order time или /order engagement.", nil)
+		return
+	}
+	if err := h.users.SetDigestOrder(user.ID, order); err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: set digest order failed")
+		h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, fmt.Sprintf("Готово: пункты дайджеста пойдут %s. Отбор постов при этом не меняется.", digestOrderLabel(order)), nil)
+}
+
+func digestOrderLabel(order domain.DigestOrder) string {
+	switch order {
+	case domain.DigestOrderTime:
+		return "по времени публикации, сначала новые"
+	case domain.DigestOrderEngagement:
+		return "по просмотрам и реакциям"
+	default:
+		return "по важности"
+	}
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// handlePause ставит все дайджесты пользователя на паузу: без аргумента — до /resume,
+// с ним — на срок в формате /mute (12h, 3d, 2w).
+func (h *Handler) handlePause(chatID, tgUserID int64, payload string) {
+	var until *time.Time
+	if payload = strings.TrimSpace(payload); payload != "" {
+		d, err := parseMuteDuration(payload)
+		if err != nil {
+			h.reply(chatID, "Формат: /pause — до команды /resume, /pause 2w — на две недели (также 12h, 3d).", nil)
+			return
+		}
+		t := time.Now().UTC().Add(d)
+		until = &t
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	if err := h.users.SetPaused(user.ID, true, until); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: pause digests failed")
+		h.reply(chatID, "Не удалось поставить дайджесты на паузу. Попробуйте позже.", nil)
+		return
+	}
+	user.Paused, user.PausedUntil = true, until
+	h.reply(chatID, buildPausedMessage(user, h.userLocation(tgUserID))+"\nКаналы и настройки сохранены. Вернуть рассылку: /resume", nil)
+}
+
+// handleResume снимает паузу дайджестов.
+func (h *Handler) handleResume(chatID, tgUserID int64) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	if !user.IsPaused(time.Now()) {
+		h.reply(chatID, "Дайджесты не на паузе.", nil)
+		return
+	}
+	if err := h.users.SetPaused(user.ID, false, nil); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: resume digests failed")
+		h.reply(chatID, "Не удалось снять паузу. Попробуйте позже.", nil)
+		return
+	}
+	h.reply(chatID, "▶️ Дайджесты снова приходят по расписанию.", nil)
+}
+
+// replyIfPaused отвечает на ручной запрос дайджеста, пока действует пауза, и сообщает, что запрос отклонён.
+func (h *Handler) replyIfPaused(chatID int64, user domain.User) bool {
+	if !user.IsPaused(time.Now()) {
+		return false
+	}
+	h.reply(chatID, buildPausedMessage(user, h.userLocation(user.TGUserID))+"\nЧтобы получить дайджест, снимите паузу командой /resume.", nil)
+	return true
+}
+
+// buildPausedMessage описывает действующую паузу во времени пользователя.
+func buildPausedMessage(user domain.User, loc *time.Location) string {
+	if user.PausedUntil == nil {
+		return "⏸ Дайджесты на паузе."
+	}
+	return fmt.Sprintf("⏸ Дайджесты на паузе до %s.", user.PausedUntil.In(loc).Format("02.01 15:04"))
+}
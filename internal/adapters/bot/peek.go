@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+// peekCooldown — как часто пользователь может запрашивать пробный дайджест канала:
+// каждый запрос тратит MTProto и OpenAI, а подписку не приносит.
+const peekCooldown = 5 * time.Minute
+
+// handlePeek ставит в очередь пробный дайджест канала, который пользователь ещё не добавил:
+// /peek @alias. Канал и его посты не сохраняются.
+func (h *Handler) handlePeek(ctx context.Context, chatID, tgUserID int64, alias string) {
+	if alias == "" {
+		h.reply(chatID, "Отправьте /peek @alias, чтобы посмотреть пример дайджеста канала перед добавлением", nil)
+		return
+	}
+	if _, err := channels.ParseAlias(alias); err != nil {
+		h.replyCheckError(chatID, "/peek", alias, err)
+		return
+	}
+	if !h.claimPeek(ctx, chatID, tgUserID) {
+		return
+	}
+	probe, err := h.channelUC.CheckChannel(ctx, alias)
+	if err != nil {
+		h.replyCheckError(chatID, "/peek", alias, err)
+		return
+	}
+	if !probe.Readable || probe.Empty {
+		h.reply(chatID, buildChannelCheckMessage(probe), nil)
+		return
+	}
+	now := time.Now().UTC()
+	job := domain.DigestJob{
+		ID:          uuid.NewString(),
+		UserTGID:    tgUserID,
+		ChatID:      chatID,
+		Date:        now,
+		RequestedAt: now,
+		Cause:       domain.DigestCausePeek,
+		Alias:       probe.Meta.Alias,
+	}
+	if err := h.jobs.Enqueue(ctx, job); err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Str("alias", probe.Meta.Alias).Msg("bot: peek enqueue failed")
+		h.reply(chatID, "Не удалось поставить пример в очередь, попробуйте позже", nil)
+		return
+	}
+	h.reply(chatID, h.withQueueEstimate(ctx, fmt.Sprintf("Собираем пример дайджеста @%s из последних постов — пришлём его в ближайшее время", probe.Meta.Alias)), nil)
+}
+
+// claimPeek пропускает не больше одного пробного дайджеста за peekCooldown. В отличие от
+// ручных дайджестов, без хранилища запрос отклоняется: иначе ограничение не работает вовсе.
+func (h *Handler) claimPeek(ctx context.Context, chatID, tgUserID int64) bool {
+	if h.inflight == nil {
+		h.reply(chatID, "Пример дайджеста сейчас недоступен. Попробуйте позже.", nil)
+		return false
+	}
+	first, err := h.inflight.MarkSeen(ctx, "peek:"+strconv.FormatInt(tgUserID, 10), peekCooldown)
+	if err != nil {
+		h.log.Warn().Err(err).Int64("user", tgUserID).Msg("bot: peek cooldown unavailable")
+		h.reply(chatID, "Пример дайджеста сейчас недоступен. Попробуйте позже.", nil)
+		return false
+	}
+	if !first {
+		h.reply(chatID, fmt.Sprintf("Пример дайджеста можно запрашивать раз в %s.", pluralCount(int(peekCooldown/time.Minute), "минуту", "минуты", "минут")), nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pendingPruneInterval — как часто pendingStore вычищает записи пользователей, которые не вернулись.
+const pendingPruneInterval = 10 * time.Minute
+
+// pendingState — незавершённые диалоги одного пользователя (или чата — для отзывов).
+type pendingState struct {
+	mu sync.Mutex
+	// removed — запись уже удалена из хранилища; её нужно взять заново.
+	removed bool
+	// dropExpires — до какого момента ждём подтверждения удаления данных; нулевое значение — запроса нет.
+	dropExpires  time.Time
+	awaitingTime bool
+	timeAttempts int
+	awaitingTZ   bool
+	awaitingFB   bool
+	// editingTags — канал, в который добавляются теги следующим сообщением; 0 — ввода нет.
+	editingTags int64
+	// bindChat — чат, который привязывается через /setchat, и опубликованный в нём код.
+	bindChat    int64
+	bindCode    string
+	bindExpires time.Time
+}
+
+// idle сообщает, что незавершённых диалогов не осталось: все поля пустые или истекли.
+func (st *pendingState) idle(now time.Time) bool {
+	return !now.Before(st.dropExpires) && !st.awaitingTime && st.timeAttempts == 0 && !st.awaitingTZ &&
+		!st.awaitingFB && st.editingTags == 0 && (st.bindChat == 0 || !now.Before(st.bindExpires))
+}
+
+// pendingStore хранит состояния с отдельной блокировкой на каждого пользователя,
+// чтобы действия разных пользователей не ждали друг друга. Нулевое значение готово
+// к работе. Пустые и истёкшие записи удаляются, чтобы хранилище не росло с числом пользователей.
+type pendingStore struct {
+	states    sync.Map // int64 -> *pendingState
+	lastPrune atomic.Int64
+}
+
+// with выполняет fn под блокировкой состояния id. Внутри fn нельзя делать сетевые вызовы.
+func (s *pendingStore) with(id int64, fn func(st *pendingState)) {
+	s.maybePrune(time.Now())
+	for {
+		v, ok := s.states.Load(id)
+		if !ok {
+			v, _ = s.states.LoadOrStore(id, &pendingState{})
+		}
+		if s.apply(id, v.(*pendingState), fn) {
+			return
+		}
+	}
+}
+
+// apply выполняет fn над st и удаляет опустевшую запись. false — запись удалили,
+// пока ждали блокировку, и fn не вызывалась.
+func (s *pendingStore) apply(id int64, st *pendingState, fn func(st *pendingState)) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.removed {
+		return false
+	}
+	fn(st)
+	s.dropIfIdle(id, st, time.Now())
+	return true
+}
+
+// dropIfIdle удаляет запись, если в ней не осталось диалогов. Вызывается под st.mu.
+func (s *pendingStore) dropIfIdle(id int64, st *pendingState, now time.Time) {
+	if st.idle(now) {
+		st.removed = true
+		s.states.CompareAndDelete(id, st)
+	}
+}
+
+// maybePrune не чаще pendingPruneInterval удаляет истёкшие записи всех пользователей.
+func (s *pendingStore) maybePrune(now time.Time) {
+	last := s.lastPrune.Load()
+	if now.UnixNano()-last < int64(pendingPruneInterval) || !s.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	s.prune(now)
+}
+
+// prune удаляет записи, в которых все диалоги завершены или истекли.
+func (s *pendingStore) prune(now time.Time) {
+	s.states.Range(func(key, value any) bool {
+		st := value.(*pendingState)
+		st.mu.Lock()
+		if !st.removed {
+			s.dropIfIdle(key.(int64), st, now)
+		}
+		st.mu.Unlock()
+		return true
+	})
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/adapters/telegram"
+)
+
+// okBotClient отвечает на любой запрос к Bot API успешной отправкой сообщения.
+type okBotClient struct{}
+
+func (okBotClient) Do(*http.Request) (*http.Response, error) {
+	body := `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+}
+
+func TestHandleUpdatePendingStateConcurrentUsers(t *testing.T) {
+	api := &tgbotapi.BotAPI{Token: "test", Client: okBotClient{}}
+	api.SetAPIEndpoint("http://bot.test/bot%s/%s")
+	h := &Handler{bot: telegram.NewClient(api), log: zerolog.Nop()}
+
+	const users = 50
+	script := []string{"/feedback", "/clear_data", "/cancel", "/feedback", "отзыв", "/clear_data", "/cancel"}
+	var wg sync.WaitGroup
+	for i := 1; i <= users; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			for round := 0; round < 20; round++ {
+				for _, text := range script {
+					h.HandleUpdate(context.Background(), tgbotapi.Update{Message: &tgbotapi.Message{
+						Text: text,
+						From: &tgbotapi.User{ID: id},
+						Chat: &tgbotapi.Chat{ID: id},
+					}})
+				}
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	for i := int64(1); i <= users; i++ {
+		if h.cancelPending(i, i) {
+			t.Fatalf("user %d: expected no pending state after the script", i)
+		}
+	}
+}
+
+func pendingEntries(s *pendingStore) int {
+	n := 0
+	s.states.Range(func(any, any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestPendingStoreDropsIdleEntries(t *testing.T) {
+	var s pendingStore
+	s.with(1, func(st *pendingState) { st.awaitingTZ = true })
+	s.with(2, func(st *pendingState) { st.bindChat, st.bindExpires = -100, time.Now().Add(time.Minute) })
+	s.with(3, func(*pendingState) {})
+	if got := pendingEntries(&s); got != 2 {
+		t.Fatalf("expected 2 active entries, got %d", got)
+	}
+
+	s.with(1, func(st *pendingState) { st.awaitingTZ = false })
+	if got := pendingEntries(&s); got != 1 {
+		t.Fatalf("expected cleared entry to be dropped, got %d entries", got)
+	}
+
+	s.prune(time.Now().Add(2 * time.Minute))
+	if got := pendingEntries(&s); got != 0 {
+		t.Fatalf("expected expired /setchat entry to be pruned, got %d entries", got)
+	}
+	s.with(2, func(st *pendingState) {
+		if st.bindChat != 0 {
+			t.Fatal("expected a fresh state after pruning")
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package bot
+
+import "fmt"
+
+// plural выбирает русскую форму слова для числа n: one — 1, 21, 101; few — 2–4, 22–24;
+// many — 0, 5–20, 11–14 и остальные.
+func plural(n int, one, few, many string) string {
+	if n < 0 {
+		n = -n
+	}
+	mod100 := n % 100
+	if mod100 >= 11 && mod100 <= 14 {
+		return many
+	}
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	default:
+		return many
+	}
+}
+
+// pluralCount возвращает число вместе с согласованной формой слова, например "3 канала".
+func pluralCount(n int, one, few, many string) string {
+	return fmt.Sprintf("%d %s", n, plural(n, one, few, many))
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+var promoCodeRegex = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// normalizePromoCode приводит код к верхнему регистру, чтобы «spring24» и «SPRING24» совпадали.
+func normalizePromoCode(input string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(input))
+	return code, promoCodeRegex.MatchString(code)
+}
+
+// handlePromo активирует промокод: /promo <код>.
+func (h *Handler) handlePromo(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if h.promos == nil {
+		h.reply(chatID, "Промокоды временно недоступны.", nil)
+		return
+	}
+	code, ok := normalizePromoCode(payload)
+	if !ok {
+		h.reply(chatID, "Формат: /promo КОД", nil)
+		return
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	redemption, err := h.promos.RedeemPromoCode(ctx, code, user.ID, time.Now().UTC())
+	if err != nil {
+		if text, known := promoErrorText(err); known {
+			h.reply(chatID, text, nil)
+			return
+		}
+		h.log.Error().Err(err).Int64("user", tgUserID).Str("code", code).Msg("bot: redeem promo code failed")
+		h.reply(chatID, "Не удалось активировать промокод. Попробуйте позже.", nil)
+		return
+	}
+	h.applyPromoBalance(ctx, chatID, user, redemption.Promo)
+}
+
+// applyPromoBalance зачисляет бонус промокода. Ключ идемпотентности выводится из кода и пользователя,
+// так что повтор /promo после сбоя не зачислит бонус дважды.
+func (h *Handler) applyPromoBalance(ctx context.Context, chatID int64, user domain.User, promo domain.PromoCode) {
+	retry := "Промокод за вами сохранён — повторите /promo " + promo.Code + " чуть позже."
+	if h.billing == nil {
+		h.reply(chatID, "Биллинг временно недоступен. "+retry, nil)
+		return
+	}
+	account, err := h.billing.EnsureAccount(ctx, user.ID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", user.TGUserID).Msg("billing: ensure account failed")
+		h.reply(chatID, "Не удалось зачислить бонус. "+retry, nil)
+		return
+	}
+	currency := account.Balance.Currency
+	if currency == "" {
+		currency = "RUB"
+	}
+	_, err = h.billing.RegisterIncomingPayment(ctx, domain.RegisterIncomingPaymentParams{
+		AccountID: account.ID,
+		Amount:    domain.Money{Amount: promo.Value, Currency: currency},
+		Metadata: map[string]any{
+			"type":       "promo",
+			"source":     "promo",
+			"code":       promo.Code,
+			"user_id":    user.ID,
+			"tg_user_id": user.TGUserID,
+		},
+		IdempotencyKey: fmt.Sprintf("promo:%s:%d", promo.Code, user.ID),
+	})
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", user.TGUserID).Str("code", promo.Code).Msg("billing: credit promo balance failed")
+		h.reply(chatID, "Не удалось зачислить бонус. "+retry, nil)
+		return
+	}
+	if err := h.promos.MarkPromoApplied(ctx, promo.Code, user.ID); err != nil {
+		// Зачисление уже прошло; повтор /promo вернёт тот же платёж по ключу идемпотентности.
+		h.log.Error().Err(err).Int64("user", user.TGUserID).Str("code", promo.Code).Msg("bot: mark promo applied failed")
+	}
+	h.recordPromoRedeemed(ctx, user.ID, promo)
+	lines := []string{fmt.Sprintf("🎁 Промокод активирован: на баланс зачислено %s.", formatMoney(promo.Value, currency))}
+	if fresh, err := h.billing.GetAccountByUserID(ctx, user.ID); err == nil {
+		lines = append(lines, fmt.Sprintf("Баланс: %s.", formatMoney(fresh.Balance.Amount, fresh.Balance.Currency)))
+	}
+	h.reply(chatID, strings.Join(lines, "\n"), h.balanceKeyboard())
+}
+
+func (h *Handler) recordPromoRedeemed(ctx context.Context, userID int64, promo domain.PromoCode) {
+	metadata := map[string]any{
+		"code":  promo.Code,
+		"kind":  string(promo.Kind),
+		"value": promo.Value,
+	}
+	h.recordBusinessMetric(ctx, domain.BusinessMetric{
+		Event:    domain.BusinessMetricEventPromoRedeemed,
+		UserID:   &userID,
+		Metadata: metadata,
+	})
+}
+
+// promoErrorText объясняет пользователю, почему промокод не сработал.
+func promoErrorText(err error) (string, bool) {
+	switch {
+	case errors.Is(err, domain.ErrPromoNotFound):
+		return "Такого промокода нет. Проверьте, нет ли опечатки.", true
+	case errors.Is(err, domain.ErrPromoExpired):
+		return "Срок действия промокода истёк.", true
+	case errors.Is(err, domain.ErrPromoExhausted):
+		return "Промокод уже активировали максимальное число раз.", true
+	case errors.Is(err, domain.ErrPromoAlreadyUsed):
+		return "Вы уже активировали этот промокод.", true
+	case errors.Is(err, domain.ErrPromoPlanUnsupported):
+		return "Тарифные промокоды пока не действуют — промокод остаётся неиспользованным.", true
+	}
+	return "", false
+}
+
+// handlePromoCreate создаёт промокод (только разработчики):
+// /promo_create <код> balance <рубли> <активаций> [дней действия]
+// Тарифные промокоды не создаются, пока у тарифов нет срока: иначе тариф выдавался бы навсегда.
+func (h *Handler) handlePromoCreate(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if h.promos == nil {
+		h.reply(chatID, "Промокоды не настроены.", nil)
+		return
+	}
+	promo, err := parsePromoCreateArgs(payload, time.Now().UTC())
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("%v\nФормат: /promo_create КОД balance 500 100 [30]", err), nil)
+		return
+	}
+	promo.CreatedBy = tgUserID
+	err = h.promos.CreatePromoCode(ctx, promo)
+	if errors.Is(err, domain.ErrPromoExists) {
+		h.reply(chatID, fmt.Sprintf("Промокод %s уже существует.", promo.Code), nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Str("code", promo.Code).Msg("bot: create promo code failed")
+		h.reply(chatID, "Не удалось создать промокод. Попробуйте позже.", nil)
+		return
+	}
+	h.log.Info().Int64("admin", tgUserID).Str("code", promo.Code).Str("kind", string(promo.Kind)).Int64("value", promo.Value).Msg("bot: promo code created")
+	h.reply(chatID, describePromo(promo), nil)
+}
+
+func parsePromoCreateArgs(payload string, now time.Time) (domain.PromoCode, error) {
+	fields := strings.Fields(payload)
+	if len(fields) < 4 || len(fields) > 5 {
+		return domain.PromoCode{}, errors.New("Неверное число аргументов.")
+	}
+	code, ok := normalizePromoCode(fields[0])
+	if !ok {
+		return domain.PromoCode{}, errors.New("Код — от 3 до 32 латинских букв, цифр, «-» или «_».")
+	}
+	value, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || value <= 0 {
+		return domain.PromoCode{}, errors.New("Значение должно быть положительным целым числом.")
+	}
+	maxUses, err := strconv.Atoi(fields[3])
+	if err != nil || maxUses <= 0 {
+		return domain.PromoCode{}, errors.New("Число активаций должно быть положительным.")
+	}
+	promo := domain.PromoCode{Code: code, Value: value, MaxUses: maxUses}
+	switch kind := strings.ToLower(fields[1]); kind {
+	case "balance":
+		promo.Kind = domain.PromoKindBalance
+		promo.Value = value * 100
+	case string(domain.UserRolePlus), string(domain.UserRolePro):
+		return domain.PromoCode{}, errors.New("Тарифные промокоды появятся, когда у подписок будет срок действия.")
+	default:
+		return domain.PromoCode{}, errors.New("Тип — balance.")
+	}
+	if len(fields) == 5 {
+		days, err := strconv.Atoi(fields[4])
+		if err != nil || days <= 0 {
+			return domain.PromoCode{}, errors.New("Срок действия — положительное число дней.")
+		}
+		expiresAt := now.AddDate(0, 0, days)
+		promo.ExpiresAt = &expiresAt
+	}
+	return promo, nil
+}
+
+func describePromo(promo domain.PromoCode) string {
+	text := fmt.Sprintf("Промокод %s создан: %s на баланс, до %s.", promo.Code, formatMoney(promo.Value, "RUB"), pluralCount(promo.MaxUses, "активации", "активаций", "активаций"))
+	if promo.ExpiresAt != nil {
+		text += fmt.Sprintf(" Действует до %s UTC.", promo.ExpiresAt.Format("02.01.2006 15:04"))
+	}
+	return text
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestParsePromoCreateArgs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	promo, err := parsePromoCreateArgs("spring24 balance 500 100 30", now)
+	if err != nil {
+		t.Fatalf("parse balance promo: %v", err)
+	}
+	if promo.Code != "SPRING24" || promo.Kind != domain.PromoKindBalance || promo.Value != 50000 || promo.MaxUses != 100 {
+		t.Fatalf("unexpected balance promo: %+v", promo)
+	}
+	if promo.ExpiresAt == nil || !promo.ExpiresAt.Equal(now.AddDate(0, 0, 30)) {
+		t.Fatalf("unexpected expiry: %v", promo.ExpiresAt)
+	}
+
+	if !strings.Contains(describePromo(promo), "на баланс") {
+		t.Fatalf("unexpected description: %s", describePromo(promo))
+	}
+
+	// Тариф без срока выдавался бы навсегда, поэтому тарифные промокоды не создаются.
+	for _, bad := range []string{"", "X balance 500 1", "CODE gold 5 1", "CODE balance -5 1", "CODE balance 5 0", "CODE plus 5 1 never", "PRO-TRIAL pro 14 10"} {
+		if _, err := parsePromoCreateArgs(bad, now); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPromoErrorTextIsDistinct(t *testing.T) {
+	seen := map[string]bool{}
+	for _, err := range []error{domain.ErrPromoNotFound, domain.ErrPromoExpired, domain.ErrPromoExhausted, domain.ErrPromoAlreadyUsed, domain.ErrPromoPlanUnsupported} {
+		text, ok := promoErrorText(err)
+		if !ok || seen[text] {
+			t.Fatalf("expected a distinct message for %v, got %q", err, text)
+		}
+		seen[text] = true
+	}
+	if _, ok := promoErrorText(errors.New("db down")); ok {
+		t.Fatal("unexpected errors must not be explained as promo errors")
+	}
+}
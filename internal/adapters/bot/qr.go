@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"rsc.io/qr"
+
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+// qrMaxSize — сторона картинки QR в пикселях: больше не нужно для сканирования с экрана,
+// а фото остаётся лёгким.
+const qrMaxSize = 512
+
+// renderQRCode рисует PNG с QR-кодом text так, чтобы сторона картинки не превышала maxSize.
+func renderQRCode(text string, maxSize int) ([]byte, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	// Encode добавляет белую рамку в 4 модуля с каждой стороны.
+	modules := code.Size + 8
+	scale := maxSize / modules
+	if scale < 1 {
+		return nil, fmt.Errorf("qr code of %d modules does not fit %dpx", modules, maxSize)
+	}
+	code.Scale = scale
+	return code.PNG(), nil
+}
+
+// replyPhoto отправляет картинку с подписью и клавиатурой.
+func (h *Handler) replyPhoto(chatID int64, name string, data []byte, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	photo.Caption = caption
+	if keyboard != nil {
+		photo.ReplyMarkup = keyboard
+	}
+	start := time.Now()
+	_, err := h.bot.Send(photo)
+	metrics.ObserveNetworkRequest("telegram_bot", "send_photo", strconv.FormatInt(chatID, 10), start, err)
+	return err
+}
@@ -0,0 +1,25 @@
+package bot
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRenderQRCodeFitsMaxSize(t *testing.T) {
+	data, err := renderQRCode("https://qr.nspk.ru/AS1000670LSS7DN18SJQDNP4B05KLJL2?type=01&bank=100000000001", qrMaxSize)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if size := img.Bounds().Dx(); size > qrMaxSize || size < qrMaxSize/2 {
+		t.Fatalf("unexpected image size %d for cap %d", size, qrMaxSize)
+	}
+	if _, err := renderQRCode(strings.Repeat("x", 500), 64); err == nil {
+		t.Fatal("expected error when the code does not fit the cap")
+	}
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const (
+	// queueDepthTimeout ограничивает запрос глубины очереди: без оценки ответ всё равно полезен.
+	queueDepthTimeout = 2 * time.Second
+	// queueShortWait — до скольких задач впереди сборка обычно укладывается в минуту-две.
+	queueShortWait = 10
+)
+
+// queueEstimate возвращает строку с примерным местом в очереди для ответа на ручной запрос.
+// Вызывается сразу после постановки задачи, поэтому сама задача уже в очереди. Если очередь
+// не сообщает глубину, возвращает пустую строку.
+func (h *Handler) queueEstimate(ctx context.Context) string {
+	stats, ok := h.jobs.(domain.DigestQueueStats)
+	if !ok {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, queueDepthTimeout)
+	defer cancel()
+	ready, _, err := stats.Depth(ctx)
+	if err != nil {
+		h.log.Debug().Err(err).Msg("bot: queue depth unavailable")
+		return ""
+	}
+	// Отложенные задачи ждут своего времени и перед новой задачей не стоят.
+	return queuePositionNote(ready - 1)
+}
+
+// queuePositionNote описывает, сколько задач впереди.
+func queuePositionNote(ahead int) string {
+	switch {
+	case ahead <= 0:
+		return "Очередь свободна — дайджест начнут собирать сразу."
+	case ahead <= queueShortWait:
+		return fmt.Sprintf("Впереди ~%s, обычно это минута-две.", pluralCount(ahead, "задача", "задачи", "задач"))
+	default:
+		return fmt.Sprintf("Впереди ~%s, сейчас пиковая нагрузка — сборка может занять несколько минут.", pluralCount(ahead, "задача", "задачи", "задач"))
+	}
+}
+
+// withQueueEstimate дописывает к ответу оценку очереди, если она известна.
+func (h *Handler) withQueueEstimate(ctx context.Context, text string) string {
+	if note := h.queueEstimate(ctx); note != "" {
+		return text + "\n" + note
+	}
+	return text
+}
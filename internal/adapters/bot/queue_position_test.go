@@ -0,0 +1,17 @@
+package bot
+
+import "testing"
+
+func TestQueuePositionNote(t *testing.T) {
+	cases := map[int]string{
+		-1: "Очередь свободна — дайджест начнут собирать сразу.",
+		0:  "Очередь свободна — дайджест начнут собирать сразу.",
+		3:  "Впереди ~3 задачи, обычно это минута-две.",
+		25: "Впереди ~25 задач, сейчас пиковая нагрузка — сборка может занять несколько минут.",
+	}
+	for ahead, want := range cases {
+		if got := queuePositionNote(ahead); got != want {
+			t.Fatalf("queuePositionNote(%d) = %q, want %q", ahead, got, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tg-digest-bot/internal/domain"
+)
+
+const (
+	// leaderboardSize — сколько лидеров показывать в /leaderboard.
+	leaderboardSize = 10
+	// flaggedReferralsLimit — сколько отложенных приглашений показывать в /referral_approve.
+	flaggedReferralsLimit = 20
+)
+
+// handleLeaderboard показывает лучших по приглашениям и место самого пользователя.
+func (h *Handler) handleLeaderboard(chatID, tgUserID int64) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.reply(chatID, fmt.Sprintf("Не удалось получить профиль: %v", err), nil)
+		return
+	}
+	top, err := h.users.TopReferrers(leaderboardSize)
+	if err != nil {
+		h.log.Error().Err(err).Msg("bot: top referrers failed")
+		h.reply(chatID, "Не удалось загрузить рейтинг. Попробуйте позже.", nil)
+		return
+	}
+	own, err := h.users.ReferrerRank(user.ID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: referrer rank failed")
+		own = domain.ReferrerStanding{UserID: user.ID, ReferralsCount: user.ReferralsCount}
+	}
+	h.reply(chatID, buildLeaderboardMessage(top, own), nil)
+}
+
+// buildLeaderboardMessage собирает таблицу лидеров. Место пользователя выводится
+// отдельной строкой, если он не попал в топ.
+func buildLeaderboardMessage(top []domain.ReferrerStanding, own domain.ReferrerStanding) string {
+	lines := []string{"🏆 Лидеры по приглашениям", ""}
+	if len(top) == 0 {
+		lines = append(lines, "Пока никто никого не пригласил — станьте первым!")
+	}
+	inTop := false
+	for _, s := range top {
+		line := fmt.Sprintf("%d. %s — %s", s.Rank, referrerDisplayName(s), pluralCount(s.ReferralsCount, "друг", "друга", "друзей"))
+		if s.UserID == own.UserID {
+			line += " (вы)"
+			inTop = true
+		}
+		lines = append(lines, line)
+	}
+	if !inTop {
+		lines = append(lines, "")
+		if own.ReferralsCount > 0 && own.Rank > 0 {
+			lines = append(lines, fmt.Sprintf("Ваше место: %d — %s.", own.Rank, pluralCount(own.ReferralsCount, "друг", "друга", "друзей")))
+		} else {
+			lines = append(lines, "Вы пока никого не пригласили. Ссылка — в разделе «🎁 Рефералы».")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// referrerDisplayName показывает имя без фамилии или замаскированный username,
+// чтобы рейтинг не раскрывал контакты участников.
+func referrerDisplayName(s domain.ReferrerStanding) string {
+	if name := strings.TrimSpace(s.FirstName); name != "" {
+		return name
+	}
+	if username := []rune(strings.TrimSpace(s.Username)); len(username) > 0 {
+		visible := 2
+		if len(username) <= visible {
+			visible = 1
+		}
+		return "@" + string(username[:visible]) + "***"
+	}
+	return "Аноним"
+}
+
+// handleReferralApprove разбирает приглашения, отложенные как подозрительные (только разработчики):
+// без аргумента показывает очередь, с tg_id приглашённого засчитывает его приглашение.
+func (h *Handler) handleReferralApprove(ctx context.Context, chatID, tgUserID int64, payload string) {
+	if !h.isDeveloper(chatID, tgUserID) {
+		return
+	}
+	if payload == "" {
+		flagged, err := h.users.ListFlaggedReferrals(flaggedReferralsLimit)
+		if err != nil {
+			h.log.Error().Err(err).Msg("bot: list flagged referrals failed")
+			h.reply(chatID, "Не удалось загрузить приглашения. Попробуйте позже.", nil)
+			return
+		}
+		h.reply(chatID, buildFlaggedReferralsMessage(flagged), nil)
+		return
+	}
+	referredTGID, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil || referredTGID <= 0 {
+		h.reply(chatID, "Формат: /referral_approve [tg_id приглашённого].", nil)
+		return
+	}
+	result, err := h.users.ApproveReferral(referredTGID)
+	if errors.Is(err, domain.ErrReferralNotFlagged) {
+		h.reply(chatID, fmt.Sprintf("Приглашение пользователя %d не ждёт проверки.", referredTGID), nil)
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("referred", referredTGID).Msg("bot: approve referral failed")
+		h.reply(chatID, "Не удалось засчитать приглашение. Попробуйте позже.", nil)
+		return
+	}
+	referrer := *result.Referrer
+	h.log.Info().Int64("admin", tgUserID).Int64("referred", referredTGID).Int64("referrer", referrer.TGUserID).Msg("bot: flagged referral approved")
+	userID := result.User.ID
+	h.recordBusinessMetric(ctx, domain.BusinessMetric{
+		Event:  domain.BusinessMetricEventReferralApproved,
+		UserID: &userID,
+		Metadata: map[string]any{
+			"referrer_id": referrer.ID,
+			"approved_by": tgUserID,
+		},
+	})
+	text := fmt.Sprintf("Приглашение засчитано: у пользователя %d теперь %s.", referrer.TGUserID, pluralCount(referrer.ReferralsCount, "приглашение", "приглашения", "приглашений"))
+	if result.ReferrerUpgraded {
+		text += fmt.Sprintf(" Тариф повышен до %s.", referrer.Plan().Name)
+		h.notifyPlanUpgrade(referrer, result.PreviousRole)
+	}
+	h.reply(chatID, text, nil)
+}
+
+func buildFlaggedReferralsMessage(flagged []domain.FlaggedReferral) string {
+	if len(flagged) == 0 {
+		return "Отложенных приглашений нет."
+	}
+	lines := []string{"Приглашения на проверке (приглашённый ← пригласивший):"}
+	for _, r := range flagged {
+		line := fmt.Sprintf("• %d ← %d, %s UTC", r.ReferredTGUserID, r.ReferrerTGUserID, r.CreatedAt.UTC().Format("02.01 15:04"))
+		if r.IsBot {
+			line += ", бот"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", "Засчитать: /referral_approve <tg_id приглашённого>")
+	return strings.Join(lines, "\n")
+}
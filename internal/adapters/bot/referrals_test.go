@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// referralReviewUsers — разработчик с очередью отложенных приглашений.
+type referralReviewUsers struct {
+	*stubUsers
+	approved []int64
+}
+
+func (u *referralReviewUsers) ApproveReferral(referredTGUserID int64) (domain.ReferralResult, error) {
+	if referredTGUserID != 555 {
+		return domain.ReferralResult{}, domain.ErrReferralNotFlagged
+	}
+	u.approved = append(u.approved, referredTGUserID)
+	referrer := domain.User{ID: 3, TGUserID: 300, Role: domain.UserRolePlus, ReferralsCount: 3}
+	return domain.ReferralResult{
+		User:             domain.User{ID: 9, TGUserID: referredTGUserID},
+		Applied:          true,
+		Referrer:         &referrer,
+		ReferrerUpgraded: true,
+		PreviousRole:     domain.UserRoleFree,
+	}, nil
+}
+
+func TestReferralApproveCountsFlaggedReferral(t *testing.T) {
+	users := &referralReviewUsers{stubUsers: &stubUsers{user: domain.User{ID: 1, TGUserID: 1, Role: domain.UserRoleDeveloper}}}
+	h, bot := newRoutingHandler(users.stubUsers)
+	h.users = users
+
+	sendText(h, &tgbotapi.User{ID: 1}, "/referral_approve 777")
+	if !strings.Contains(bot.last(), "не ждёт проверки") {
+		t.Fatalf("unexpected reply %q", bot.last())
+	}
+
+	sendText(h, &tgbotapi.User{ID: 1}, "/referral_approve 555")
+	if len(users.approved) != 1 {
+		t.Fatalf("expected referral to be approved, got %v", users.approved)
+	}
+	sent := bot.sent()
+	if len(sent) < 2 || !strings.Contains(sent[len(sent)-2], "Ваш тариф обновлён") {
+		t.Fatalf("expected referrer to be notified about the upgrade, got %q", sent)
+	}
+	if !strings.Contains(bot.last(), "Тариф повышен до Plus") {
+		t.Fatalf("unexpected reply %q", bot.last())
+	}
+}
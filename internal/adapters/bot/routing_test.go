@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/schedule"
+)
+
+// recordingSender запоминает всё, что обработчик отправил в Bot API.
+type recordingSender struct {
+	mu        sync.Mutex
+	texts     []string
+	callbacks []string
+}
+
+func (s *recordingSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg, ok := c.(tgbotapi.MessageConfig); ok {
+		s.texts = append(s.texts, msg.Text)
+	}
+	return tgbotapi.Message{MessageID: len(s.texts)}, nil
+}
+
+func (s *recordingSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cb, ok := c.(tgbotapi.CallbackConfig); ok {
+		s.callbacks = append(s.callbacks, cb.CallbackQueryID)
+	}
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (s *recordingSender) GetFile(tgbotapi.FileConfig) (tgbotapi.File, error) {
+	return tgbotapi.File{}, nil
+}
+
+func (s *recordingSender) SetMyCommands(tgbotapi.SetMyCommandsConfig) error { return nil }
+
+func (s *recordingSender) GetChat(tgbotapi.ChatInfoConfig) (tgbotapi.Chat, error) {
+	return tgbotapi.Chat{}, nil
+}
+
+func (s *recordingSender) GetChatMember(tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error) {
+	return tgbotapi.ChatMember{}, nil
+}
+
+func (s *recordingSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.texts...)
+}
+
+func (s *recordingSender) last() string {
+	texts := s.sent()
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[len(texts)-1]
+}
+
+// stubUsers реализует только те методы UserRepo, что нужны тестам маршрутизации.
+type stubUsers struct {
+	domain.UserRepo
+	user      domain.User
+	manual    domain.ManualRequestState
+	dailyTime time.Time
+}
+
+func (s *stubUsers) GetByTGID(int64) (domain.User, error) { return s.user, nil }
+
+func (s *stubUsers) UpdateDailyTime(_ int64, daily time.Time) error {
+	s.dailyTime = daily
+	return nil
+}
+
+func (s *stubUsers) ReserveManualRequest(int64, time.Time) (domain.ManualRequestState, error) {
+	return s.manual, nil
+}
+
+func newRoutingHandler(users *stubUsers) (*Handler, *recordingSender) {
+	bot := &recordingSender{}
+	h := &Handler{bot: bot, log: zerolog.Nop(), users: users}
+	if users != nil {
+		h.scheduleUC = schedule.NewService(users)
+	}
+	return h, bot
+}
+
+func sendText(h *Handler, from *tgbotapi.User, text string) {
+	h.HandleUpdate(context.Background(), tgbotapi.Update{Message: &tgbotapi.Message{
+		Text: text,
+		From: from,
+		Chat: &tgbotapi.Chat{ID: 42},
+	}})
+}
+
+func TestHandleUpdateDispatchesCommands(t *testing.T) {
+	cases := []struct {
+		text string
+		from *tgbotapi.User
+		want string
+	}{
+		{text: "/nope", from: &tgbotapi.User{ID: 1}, want: "Неизвестная команда"},
+		{text: "/guide", want: "Не удалось определить пользователя"},
+		{text: "/clear_data_confirm", from: &tgbotapi.User{ID: 1}, want: "/clear_data_confirm " + clearDataConfirmWord},
+		{text: "/clear_data", from: &tgbotapi.User{ID: 1}, want: "Удаление аккаунта необратимо"},
+	}
+	for _, tc := range cases {
+		h, bot := newRoutingHandler(nil)
+		sendText(h, tc.from, tc.text)
+		if got := bot.last(); !strings.Contains(got, tc.want) {
+			t.Fatalf("%s: expected reply containing %q, got %q", tc.text, tc.want, got)
+		}
+	}
+}
+
+func TestHandleUpdateHelpSendsHelpMessage(t *testing.T) {
+	h, bot := newRoutingHandler(nil)
+	sendText(h, &tgbotapi.User{ID: 1}, "/help")
+	sent := bot.sent()
+	if len(sent) == 0 || !strings.HasPrefix(h.buildHelpMessage(), sent[0]) {
+		t.Fatalf("expected help message, got %q", sent)
+	}
+}
+
+func TestHandleCallbackAnswersQuery(t *testing.T) {
+	h, bot := newRoutingHandler(nil)
+	h.HandleUpdate(context.Background(), tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb-1",
+		Data:    "add_channel",
+		From:    &tgbotapi.User{ID: 1},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 42}},
+	}})
+	if got := bot.last(); got != "Отправьте /add @alias" {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if len(bot.callbacks) != 1 || bot.callbacks[0] != "cb-1" {
+		t.Fatalf("expected callback cb-1 to be answered, got %v", bot.callbacks)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	cases := map[string]int64{
+		"mute:15":     15,
+		"delete:-3":   -3,
+		"resend:abc":  0,
+		"mute":        0,
+		"tag:1:extra": 0,
+	}
+	for data, want := range cases {
+		if got := parseID(data); got != want {
+			t.Fatalf("parseID(%q) = %d, want %d", data, got, want)
+		}
+	}
+}
+
+func TestParseTagsInput(t *testing.T) {
+	if tags := parseTagsInput("  "); tags != nil {
+		t.Fatalf("expected nil for blank input, got %v", tags)
+	}
+	tags := parseTagsInput("news, tech;\n ; news")
+	if strings.Join(tags, ",") != "news,tech" {
+		t.Fatalf("expected deduplicated news,tech, got %v", tags)
+	}
+}
+
+func TestParseAmountToMinor(t *testing.T) {
+	valid := map[string]int64{
+		"500":     50000,
+		"+10":     1000,
+		"249,9":   24990,
+		"0.05":    5,
+		".5":      50,
+		"1.999":   199,
+		" 1000 ":  100000,
+		"12.3":    1230,
+		"100.00":  10000,
+		"7.":      700,
+		"0012.01": 1201,
+	}
+	for input, want := range valid {
+		got, err := parseAmountToMinor(input)
+		if err != nil {
+			t.Fatalf("parseAmountToMinor(%q): %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("parseAmountToMinor(%q) = %d, want %d", input, got, want)
+		}
+	}
+	for _, input := range []string{"", "-5", "abc", "1.x"} {
+		if _, err := parseAmountToMinor(input); err == nil {
+			t.Fatalf("parseAmountToMinor(%q): expected error", input)
+		}
+	}
+}
+
+func TestScheduleWaitsForTimeInput(t *testing.T) {
+	users := &stubUsers{user: domain.User{ID: 7, TGUserID: 1}}
+	h, bot := newRoutingHandler(users)
+	from := &tgbotapi.User{ID: 1}
+
+	sendText(h, from, "/schedule")
+	if !strings.Contains(bot.last(), "Текущее время ежедневной рассылки") {
+		t.Fatalf("expected schedule prompt, got %q", bot.last())
+	}
+	sendText(h, from, "9-15")
+	if !strings.Contains(bot.last(), "Некорректный формат времени") {
+		t.Fatalf("expected format hint, got %q", bot.last())
+	}
+	sendText(h, from, "21:30")
+	if users.dailyTime.Format("15:04") != "21:30" {
+		t.Fatalf("expected daily time 21:30 to be saved, got %s", users.dailyTime.Format("15:04"))
+	}
+	if !strings.Contains(bot.last(), "Время доставки установлено на 21:30") {
+		t.Fatalf("expected confirmation, got %q", bot.last())
+	}
+
+	sendText(h, from, "22:00")
+	if users.dailyTime.Format("15:04") != "21:30" {
+		t.Fatal("time input must not be accepted once the schedule was saved")
+	}
+}
+
+func TestDigestDateStopsWhenManualQuotaExceeded(t *testing.T) {
+	users := &stubUsers{
+		user:   domain.User{ID: 7, TGUserID: 1, Role: domain.UserRolePlus, Timezone: "UTC"},
+		manual: domain.ManualRequestState{Allowed: false, Plan: domain.PlanForRole(domain.UserRolePlus)},
+	}
+	// Очередь не задана: если обработчик дойдёт до постановки задачи, тест упадёт.
+	h, bot := newRoutingHandler(users)
+	day := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+
+	sendText(h, &tgbotapi.User{ID: 1}, "/digest_date "+day)
+	got := bot.last()
+	if !strings.Contains(got, "Вы достигли лимита запросов для тарифа Plus") || !strings.Contains(got, "3 запроса в сутки") {
+		t.Fatalf("expected manual limit reply, got %q", got)
+	}
+}
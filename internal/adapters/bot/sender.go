@@ -0,0 +1,15 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/adapters/telegram"
+)
+
+// sender — часть Bot API, которой пользуется Handler: клиент Telegram и проверка чатов для /setchat.
+// telegram.BotClient подходит как есть, а в тестах её заменяет запись отправленных сообщений.
+type sender interface {
+	telegram.Client
+	GetChat(config tgbotapi.ChatInfoConfig) (tgbotapi.Chat, error)
+	GetChatMember(config tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error)
+}
@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// setChatCodeTTL — сколько действует код подтверждения привязки чата.
+const setChatCodeTTL = 10 * time.Minute
+
+// handleSetChat привязывает плановые дайджесты к группе или каналу. Формат: /setchat @channel
+// или /setchat -100123 — бот проверяет, что пользователь администрирует чат, а сам бот может
+// в нём писать, и публикует там код, который пользователь присылает обратно в личку;
+// /setchat off возвращает дайджесты в личку.
+func (h *Handler) handleSetChat(chatID, tgUserID int64, payload string) {
+	user, err := h.users.GetByTGID(tgUserID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Msg("bot: get user for setchat failed")
+		h.reply(chatID, "Не удалось загрузить профиль. Попробуйте позже.", nil)
+		return
+	}
+	switch strings.ToLower(payload) {
+	case "":
+		h.reply(chatID, fmt.Sprintf("Плановые дайджесты приходят: %s.\nЧтобы получать их в группе или канале, добавьте туда бота администратором с правом публикации и отправьте /setchat @канал или /setchat <id чата>. Вернуть в личку — /setchat off.", deliveryChatLabel(user.DeliveryChatID)), nil)
+		return
+	case "off", "выкл":
+		if user.DeliveryChatID == 0 {
+			h.reply(chatID, "Дайджесты и так приходят в личные сообщения.", nil)
+			return
+		}
+		if err := h.users.SetDeliveryChat(user.ID, 0); err != nil {
+			h.log.Error().Err(err).Int64("user", user.ID).Msg("bot: unbind delivery chat failed")
+			h.reply(chatID, "Не удалось сохранить настройку. Попробуйте позже.", nil)
+			return
+		}
+		h.reply(chatID, "Готово: плановые дайджесты снова приходят в личные сообщения.", nil)
+		return
+	}
+
+	target, ok := parseChatTarget(payload)
+	if !ok {
+		h.reply(chatID, "Формат: /setchat @канал, /setchat <id чата> или /setchat off.", nil)
+		return
+	}
+	chat, err := h.bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: target})
+	if err != nil {
+		h.log.Warn().Err(err).Str("chat", payload).Msg("bot: get chat for setchat failed")
+		h.reply(chatID, fmt.Sprintf("Не нашёл чат %s. Добавьте бота в группу или канал и повторите.", payload), nil)
+		return
+	}
+	if chat.IsPrivate() {
+		h.reply(chatID, "Укажите группу или канал: личные сообщения и так используются по умолчанию.", nil)
+		return
+	}
+	// Иначе любой пользователь заставил бы бота писать в чужой чат, где тот администратор.
+	requester, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: tgUserID}})
+	if err != nil || !(requester.IsCreator() || requester.IsAdministrator()) {
+		h.reply(chatID, fmt.Sprintf("Привязать дайджесты к «%s» может только владелец или администратор этого чата.", chatName(chat)), nil)
+		return
+	}
+	member, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: h.self.ID}})
+	if err != nil || !canPostDigest(chat, member) {
+		h.reply(chatID, fmt.Sprintf("Бот не может публиковать сообщения в «%s». Сделайте его администратором с правом публикации и повторите.", chatName(chat)), nil)
+		return
+	}
+	code, err := generateConfirmationCode()
+	if err != nil {
+		h.log.Error().Err(err).Msg("bot: generate setchat code failed")
+		h.reply(chatID, "Не удалось создать код подтверждения. Попробуйте позже.", nil)
+		return
+	}
+	if _, err := h.bot.Send(tgbotapi.NewMessage(chat.ID, fmt.Sprintf("Код для привязки дайджестов: %s. Отправьте его боту в личные сообщения.", code))); err != nil {
+		h.log.Warn().Err(err).Int64("chat", chat.ID).Msg("bot: send setchat code failed")
+		h.reply(chatID, fmt.Sprintf("Не удалось отправить сообщение в «%s»: проверьте права бота и повторите.", chatName(chat)), nil)
+		return
+	}
+	h.pending.with(tgUserID, func(st *pendingState) {
+		st.bindChat = chat.ID
+		st.bindCode = code
+		st.bindExpires = time.Now().Add(setChatCodeTTL)
+	})
+	h.reply(chatID, fmt.Sprintf("Бот опубликовал код в «%s». Пришлите его сюда в течение 10 минут, чтобы подтвердить привязку. Отменить — /cancel", chatName(chat)), nil)
+}
+
+// tryHandleSetChatInput сверяет присланный код с опубликованным в чате и привязывает чат.
+func (h *Handler) tryHandleSetChatInput(ctx context.Context, chatID, tgUserID int64, text string) bool {
+	var (
+		bindChat int64
+		code     string
+		expires  time.Time
+	)
+	h.pending.with(tgUserID, func(st *pendingState) {
+		bindChat, code, expires = st.bindChat, st.bindCode, st.bindExpires
+	})
+	if bindChat == 0 || bindChat == chatID {
+		return false
+	}
+	if time.Now().After(expires) {
+		h.clearSetChat(tgUserID)
+		h.reply(chatID, "Код устарел. Начните заново: /setchat", nil)
+		return true
+	}
+	if strings.TrimSpace(text) != code {
+		h.reply(chatID, "Код не подходит. Пришлите код из чата или /cancel, чтобы отменить.", nil)
+		return true
+	}
+	user, err := h.users.GetByTGID(tgUserID)
+	if err == nil {
+		err = h.users.SetDeliveryChat(user.ID, bindChat)
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("tg_user_id", tgUserID).Int64("chat", bindChat).Msg("bot: bind delivery chat failed")
+		h.reply(chatID, "Не удалось сохранить привязку. Попробуйте позже.", nil)
+		return true
+	}
+	h.clearSetChat(tgUserID)
+	h.reply(chatID, "Готово: плановые дайджесты будут приходить в привязанный чат. Вернуть в личку — /setchat off.", nil)
+	return true
+}
+
+func (h *Handler) clearSetChat(tgUserID int64) {
+	h.pending.with(tgUserID, func(st *pendingState) {
+		st.bindChat, st.bindCode, st.bindExpires = 0, "", time.Time{}
+	})
+}
+
+// parseChatTarget разбирает @username или числовой id чата.
+func parseChatTarget(value string) (tgbotapi.ChatConfig, bool) {
+	if strings.HasPrefix(value, "@") && len(value) > 1 && !strings.ContainsAny(value, " \t") {
+		return tgbotapi.ChatConfig{SuperGroupUsername: value}, true
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id == 0 {
+		return tgbotapi.ChatConfig{}, false
+	}
+	return tgbotapi.ChatConfig{ChatID: id}, true
+}
+
+// canPostDigest проверяет, что бот может писать в чат: в канал — только администратор
+// с правом публикации, в группу — любой участник без ограничений.
+func canPostDigest(chat tgbotapi.Chat, member tgbotapi.ChatMember) bool {
+	switch member.Status {
+	case "creator":
+		return true
+	case "administrator":
+		return !chat.IsChannel() || member.CanPostMessages
+	case "member":
+		return !chat.IsChannel()
+	default:
+		return false
+	}
+}
+
+func deliveryChatLabel(chatID int64) string {
+	if chatID == 0 {
+		return "личные сообщения"
+	}
+	return fmt.Sprintf("чат %d", chatID)
+}
+
+func chatName(chat tgbotapi.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	if chat.UserName != "" {
+		return "@" + chat.UserName
+	}
+	return strconv.FormatInt(chat.ID, 10)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestParseChatTarget(t *testing.T) {
+	if cfg, ok := parseChatTarget("@my_channel"); !ok || cfg.SuperGroupUsername != "@my_channel" {
+		t.Fatalf("unexpected username target: %+v, %v", cfg, ok)
+	}
+	if cfg, ok := parseChatTarget("-1001234567890"); !ok || cfg.ChatID != -1001234567890 {
+		t.Fatalf("unexpected id target: %+v, %v", cfg, ok)
+	}
+	for _, bad := range []string{"@", "channel", "0", "@a b"} {
+		if _, ok := parseChatTarget(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestCanPostDigest(t *testing.T) {
+	channel := tgbotapi.Chat{Type: "channel"}
+	group := tgbotapi.Chat{Type: "supergroup"}
+	cases := []struct {
+		name   string
+		chat   tgbotapi.Chat
+		member tgbotapi.ChatMember
+		want   bool
+	}{
+		{"channel admin with posting", channel, tgbotapi.ChatMember{Status: "administrator", CanPostMessages: true}, true},
+		{"channel admin without posting", channel, tgbotapi.ChatMember{Status: "administrator"}, false},
+		{"channel member", channel, tgbotapi.ChatMember{Status: "member"}, false},
+		{"group member", group, tgbotapi.ChatMember{Status: "member"}, true},
+		{"group left", group, tgbotapi.ChatMember{Status: "left"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := canPostDigest(tc.chat, tc.member); got != tc.want {
+				t.Fatalf("canPostDigest() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// chatAdminSender отвечает на GetChatMember статусами из members.
+type chatAdminSender struct {
+	recordingSender
+	members map[int64]string
+}
+
+func (s *chatAdminSender) GetChat(tgbotapi.ChatInfoConfig) (tgbotapi.Chat, error) {
+	return tgbotapi.Chat{ID: -100500, Type: "supergroup", Title: "Команда"}, nil
+}
+
+func (s *chatAdminSender) GetChatMember(cfg tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error) {
+	return tgbotapi.ChatMember{Status: s.members[cfg.UserID]}, nil
+}
+
+func TestSetChatRequiresRequesterAdmin(t *testing.T) {
+	const botID, userID = 99, 1
+	cases := []struct {
+		status   string
+		wantCode bool
+	}{
+		{"member", false},
+		{"left", false},
+		{"administrator", true},
+		{"creator", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.status, func(t *testing.T) {
+			bot := &chatAdminSender{members: map[int64]string{botID: "administrator", userID: tc.status}}
+			h := &Handler{bot: bot, self: tgbotapi.User{ID: botID}, log: zerolog.Nop(), users: &stubUsers{user: domain.User{ID: 7, TGUserID: userID}}}
+			h.handleSetChat(42, userID, "-100500")
+
+			sent := bot.sent()
+			posted := len(sent) > 0 && strings.HasPrefix(sent[0], "Код для привязки")
+			if posted != tc.wantCode {
+				t.Fatalf("status %s: code posted = %v, replies %q", tc.status, posted, sent)
+			}
+			if !tc.wantCode && !strings.Contains(bot.last(), "только владелец или администратор") {
+				t.Fatalf("status %s: unexpected reply %q", tc.status, bot.last())
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+const (
+	editTagsCallbackPrefix  = "edit_tags:"
+	tagAddCallbackPrefix    = "tag_add:"
+	tagRemoveCallbackPrefix = "tag_rm:"
+	tagClearCallbackPrefix  = "tag_clear:"
+)
+
+// handleEditTags показывает теги канала с кнопками удаления и добавления.
+func (h *Handler) handleEditTags(ctx context.Context, chatID, tgUserID, channelID int64) {
+	ch, ok := h.findUserChannel(ctx, chatID, tgUserID, channelID)
+	if !ok {
+		return
+	}
+	h.reply(chatID, buildTagEditorMessage(ch), tagEditorKeyboard(ch))
+}
+
+// handleTagAdd ждёт от пользователя новые теги для канала следующим сообщением.
+func (h *Handler) handleTagAdd(ctx context.Context, chatID, tgUserID, channelID int64) {
+	ch, ok := h.findUserChannel(ctx, chatID, tgUserID, channelID)
+	if !ok {
+		return
+	}
+	h.pending.with(tgUserID, func(st *pendingState) { st.editingTags = ch.ChannelID })
+	h.reply(chatID, fmt.Sprintf("Отправьте теги для %s через запятую, например: новости, аналитика. Отменить — /cancel", channelTitle(ch.Channel)), nil)
+}
+
+// handleTagRemove удаляет тег с номером index из тегов канала.
+func (h *Handler) handleTagRemove(ctx context.Context, chatID, tgUserID, channelID int64, index int) {
+	ch, ok := h.findUserChannel(ctx, chatID, tgUserID, channelID)
+	if !ok {
+		return
+	}
+	if index < 0 || index >= len(ch.Tags) {
+		h.reply(chatID, "Список тегов изменился — откройте его заново через /list", nil)
+		return
+	}
+	tags := append(append([]string{}, ch.Tags[:index]...), ch.Tags[index+1:]...)
+	h.saveChannelTags(ctx, chatID, tgUserID, ch, tags)
+}
+
+// handleTagClear удаляет все теги канала.
+func (h *Handler) handleTagClear(ctx context.Context, chatID, tgUserID, channelID int64) {
+	ch, ok := h.findUserChannel(ctx, chatID, tgUserID, channelID)
+	if !ok {
+		return
+	}
+	h.saveChannelTags(ctx, chatID, tgUserID, ch, nil)
+}
+
+// tryHandleTagInput добавляет присланные теги к каналу, для которого нажата «➕ Добавить».
+func (h *Handler) tryHandleTagInput(ctx context.Context, chatID, tgUserID int64, text string) bool {
+	var channelID int64
+	h.pending.with(tgUserID, func(st *pendingState) { channelID = st.editingTags })
+	if channelID == 0 {
+		return false
+	}
+	added := parseTagsInput(text)
+	if len(added) == 0 {
+		h.reply(chatID, "Отправьте теги через запятую или /cancel, чтобы выйти", nil)
+		return true
+	}
+	ch, ok := h.findUserChannel(ctx, chatID, tgUserID, channelID)
+	if !ok {
+		h.pending.with(tgUserID, func(st *pendingState) { st.editingTags = 0 })
+		return true
+	}
+	if h.saveChannelTags(ctx, chatID, tgUserID, ch, append(append([]string{}, ch.Tags...), added...)) {
+		h.pending.with(tgUserID, func(st *pendingState) { st.editingTags = 0 })
+	}
+	return true
+}
+
+// saveChannelTags сохраняет теги и заново показывает редактор. Возвращает false, если теги не сохранены.
+func (h *Handler) saveChannelTags(ctx context.Context, chatID, tgUserID int64, ch domain.UserChannel, tags []string) bool {
+	tags = channels.NormalizeTags(tags)
+	if err := h.channelUC.UpdateChannelTags(ctx, tgUserID, ch.ChannelID, tags); err != nil {
+		var limitErr *channels.TagLimitError
+		if errors.As(err, &limitErr) {
+			ch.Tags = limitErr.Kept
+			h.reply(chatID, buildTagLimitMessage(limitErr), nil)
+			h.reply(chatID, buildTagEditorMessage(ch), tagEditorKeyboard(ch))
+			return true
+		}
+		h.log.Error().Err(err).Int64("user", tgUserID).Int64("channel", ch.ChannelID).Msg("bot: update channel tags failed")
+		h.reply(chatID, "Не удалось сохранить теги. Попробуйте позже.", nil)
+		return false
+	}
+	ch.Tags = tags
+	h.reply(chatID, buildTagEditorMessage(ch), tagEditorKeyboard(ch))
+	return true
+}
+
+// buildTagLimitMessage перечисляет теги, не сохранённые из-за лимитов.
+func buildTagLimitMessage(err *channels.TagLimitError) string {
+	return fmt.Sprintf("Не сохранены: %s. У канала может быть до %s длиной до %s.",
+		strings.Join(err.Dropped, ", "),
+		pluralCount(err.Limits.MaxCount, "тега", "тегов", "тегов"),
+		pluralCount(err.Limits.MaxLength, "символа", "символов", "символов"))
+}
+
+func (h *Handler) findUserChannel(ctx context.Context, chatID, tgUserID, channelID int64) (domain.UserChannel, bool) {
+	list, err := h.channelUC.ListChannels(ctx, tgUserID, 100, 0)
+	if err != nil {
+		h.log.Error().Err(err).Int64("user", tgUserID).Msg("bot: list channels for tags failed")
+		h.reply(chatID, "Не удалось получить список каналов. Попробуйте позже", nil)
+		return domain.UserChannel{}, false
+	}
+	for _, ch := range list {
+		if ch.ChannelID == channelID {
+			return ch, true
+		}
+	}
+	h.reply(chatID, "Канал не найден среди ваших подписок", nil)
+	return domain.UserChannel{}, false
+}
+
+func channelTitle(ch domain.Channel) string {
+	if ch.Title != "" {
+		return ch.Title
+	}
+	return "@" + ch.Alias
+}
+
+// buildTagEditorMessage перечисляет текущие теги канала.
+func buildTagEditorMessage(ch domain.UserChannel) string {
+	if len(ch.Tags) == 0 {
+		return fmt.Sprintf("🏷 У канала %s пока нет тегов. Нажмите «➕ Добавить», чтобы задать их.", channelTitle(ch.Channel))
+	}
+	return fmt.Sprintf("🏷 Теги канала %s: %s\nНажмите на тег, чтобы удалить его.", channelTitle(ch.Channel), strings.Join(ch.Tags, ", "))
+}
+
+// tagEditorKeyboard строит кнопку удаления на каждый тег и кнопки добавления и очистки.
+// Тег передаётся номером: сам тег может не поместиться в callback_data.
+func tagEditorKeyboard(ch domain.UserChannel) *tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(ch.Tags)/2+2)
+	var row []tgbotapi.InlineKeyboardButton
+	for i, tag := range ch.Tags {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("❌ "+tag, fmt.Sprintf("%s%d:%d", tagRemoveCallbackPrefix, ch.ChannelID, i)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	actions := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", fmt.Sprintf("%s%d", tagAddCallbackPrefix, ch.ChannelID)),
+	}
+	if len(ch.Tags) > 0 {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("🧹 Очистить", fmt.Sprintf("%s%d", tagClearCallbackPrefix, ch.ChannelID)))
+	}
+	rows = append(rows, actions)
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
+
+// parseTagRemoveCallback разбирает callback_data вида tag_rm:<channelID>:<index>.
+func parseTagRemoveCallback(data string) (int64, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(data, tagRemoveCallbackPrefix), ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	channelID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || channelID <= 0 {
+		return 0, 0, false
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return 0, 0, false
+	}
+	return channelID, index, true
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/usecase/channels"
+)
+
+func TestTagEditorKeyboard(t *testing.T) {
+	ch := domain.UserChannel{ChannelID: 42, Tags: []string{"новости", "аналитика", "крипта"}}
+	markup := tagEditorKeyboard(ch)
+	rows := markup.InlineKeyboard
+	if len(rows) != 3 || len(rows[0]) != 2 || len(rows[1]) != 1 || len(rows[2]) != 2 {
+		t.Fatalf("unexpected keyboard layout: %+v", rows)
+	}
+	id, index, ok := parseTagRemoveCallback(*rows[1][0].CallbackData)
+	if !ok || id != 42 || index != 2 {
+		t.Fatalf("unexpected remove callback %q parsed as %d, %d, %v", *rows[1][0].CallbackData, id, index, ok)
+	}
+	if got := *rows[2][0].CallbackData; got != "tag_add:42" {
+		t.Fatalf("unexpected add callback %q", got)
+	}
+
+	empty := tagEditorKeyboard(domain.UserChannel{ChannelID: 42})
+	if len(empty.InlineKeyboard) != 1 || len(empty.InlineKeyboard[0]) != 1 {
+		t.Fatalf("expected only the add button without tags, got %+v", empty.InlineKeyboard)
+	}
+}
+
+func TestParseTagRemoveCallbackRejectsMalformed(t *testing.T) {
+	for _, data := range []string{"tag_rm:42", "tag_rm:x:1", "tag_rm:42:-1", "tag_rm:0:1"} {
+		if _, _, ok := parseTagRemoveCallback(data); ok {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestBuildTagLimitMessage(t *testing.T) {
+	msg := buildTagLimitMessage(&channels.TagLimitError{
+		Limits:  channels.TagLimits{MaxCount: 10, MaxLength: 32},
+		Dropped: []string{"спорт", "кино"},
+	})
+	want := "Не сохранены: спорт, кино. У канала может быть до 10 тегов длиной до 32 символов."
+	if msg != want {
+		t.Fatalf("unexpected message:\n%s", msg)
+	}
+}
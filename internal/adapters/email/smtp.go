@@ -0,0 +1,124 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+// implicitTLSPort — порт SMTPS, где TLS поднимается сразу, без STARTTLS.
+const implicitTLSPort = 465
+
+// SMTP отправляет письма через SMTP-сервер.
+type SMTP struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+var _ domain.EmailSender = (*SMTP)(nil)
+
+// NewSMTP создаёт отправителя писем.
+func NewSMTP(host string, port int, username, password, from string, timeout time.Duration) *SMTP {
+	if port <= 0 {
+		port = 587
+	}
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	return &SMTP{host: host, port: port, username: username, password: password, from: from, timeout: timeout}
+}
+
+// Send отправляет HTML-письмо одному получателю.
+func (s *SMTP) Send(ctx context.Context, to, subject, htmlBody string) (err error) {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("некорректный адрес получателя")
+	}
+	start := time.Now()
+	defer func() {
+		metrics.ObserveNetworkRequest("smtp", "send_mail", s.host, start, err)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("подключение к SMTP: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: s.host}
+	if s.port == implicitTLSPort {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && s.port != implicitTLSPort {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP RCPT TO: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(buildMessage(s.from, to, subject, htmlBody)); err != nil {
+		w.Close()
+		return fmt.Errorf("запись письма: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("завершение письма: %w", err)
+	}
+	return client.Quit()
+}
+
+func buildMessage(from, to, subject, htmlBody string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(htmlBody))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return []byte(b.String())
+}
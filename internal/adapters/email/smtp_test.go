@@ -0,0 +1,32 @@
+package email
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessageEncodesSubjectAndBody(t *testing.T) {
+	body := strings.Repeat("<p>Дайджест</p>", 20)
+	raw := string(buildMessage("bot@example.com", "user@example.com", "Дайджест за 16.10", body))
+
+	headers, encoded, ok := strings.Cut(raw, "\r\n\r\n")
+	if !ok {
+		t.Fatal("expected blank line between headers and body")
+	}
+	if !strings.Contains(headers, "Subject: =?utf-8?b?") {
+		t.Fatalf("expected encoded subject, got headers:\n%s", headers)
+	}
+	for _, line := range strings.Split(strings.TrimRight(encoded, "\r\n"), "\r\n") {
+		if len(line) > 76 {
+			t.Fatalf("body line longer than 76 chars: %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if string(decoded) != body {
+		t.Fatal("decoded body does not match original")
+	}
+}
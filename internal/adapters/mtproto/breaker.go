@@ -0,0 +1,174 @@
+package mtproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tgerr"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+const (
+	// breakerThreshold — сколько подряд отказов всего пула открывают предохранитель.
+	breakerThreshold = 3
+	// breakerWindow — отказы старше окна не считаются подряд идущими.
+	breakerWindow = 10 * time.Minute
+	// breakerCooldown — сколько предохранитель остаётся открытым до пробного запроса.
+	breakerCooldown = 5 * time.Minute
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker перестаёт ходить в MTProto, пока весь пул аккаунтов отказывает,
+// чтобы не усугублять flood-wait повторными попытками всех сессий.
+type circuitBreaker struct {
+	mu           sync.Mutex
+	component    string
+	log          zerolog.Logger
+	threshold    int
+	window       time.Duration
+	cooldown     time.Duration
+	now          func() time.Time
+	state        breakerState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+}
+
+func newCircuitBreaker(component string, log zerolog.Logger) *circuitBreaker {
+	b := &circuitBreaker{
+		component: component,
+		log:       log,
+		threshold: breakerThreshold,
+		window:    breakerWindow,
+		cooldown:  breakerCooldown,
+		now:       time.Now,
+	}
+	metrics.SetMTProtoBreakerState(component, int(breakerClosed))
+	return b
+}
+
+// allow разрешает вызов или возвращает domain.ErrCollectorUnavailable, пока предохранитель открыт.
+// В полуоткрытом состоянии пропускается только один пробный вызов.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return b.unavailable()
+		}
+		b.transition(breakerHalfOpen)
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return b.unavailable()
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *circuitBreaker) unavailable() error {
+	retryIn := b.cooldown - b.now().Sub(b.openedAt)
+	if retryIn < 0 {
+		retryIn = 0
+	}
+	return fmt.Errorf("%s: MTProto пул недоступен, повтор через %s: %w", b.component, retryIn.Round(time.Second), domain.ErrCollectorUnavailable)
+}
+
+// record учитывает результат вызова. poolFailure — все аккаунты отказали по инфраструктурной причине.
+func (b *circuitBreaker) record(poolFailure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.probing = false
+	if !poolFailure {
+		b.failures = 0
+		if b.state != breakerClosed {
+			b.transition(breakerClosed)
+		}
+		return
+	}
+	if b.state == breakerHalfOpen {
+		b.openedAt = now
+		b.transition(breakerOpen)
+		return
+	}
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+		b.failures = 0
+		b.transition(breakerOpen)
+	}
+}
+
+func (b *circuitBreaker) transition(to breakerState) {
+	from := b.state
+	b.state = to
+	metrics.SetMTProtoBreakerState(b.component, int(to))
+	metrics.IncMTProtoBreakerTransition(b.component, to.String())
+	event := b.log.Info()
+	if to == breakerOpen {
+		event = b.log.Warn().Dur("cooldown", b.cooldown)
+	}
+	event.Str("component", b.component).Str("from", from.String()).Str("to", to.String()).Msg("mtproto: состояние предохранителя изменилось")
+}
+
+// isPoolError отличает отказ инфраструктуры (flood-wait, сбои сервера, сеть, таймауты)
+// от ошибок конкретного запроса вроде несуществующего канала.
+func isPoolError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := tgerr.AsFloodWait(err); ok {
+		return true
+	}
+	if rpcErr, ok := tgerr.As(err); ok {
+		return rpcErr.Code >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isAccountInvalid сообщает, что аккаунт неработоспособен сам по себе: сессия отозвана,
+// ключ авторизации не зарегистрирован или аккаунт удалён. Такие ошибки Telegram отдаёт с кодом 401.
+func isAccountInvalid(err error) bool {
+	rpcErr, ok := tgerr.As(err)
+	return ok && rpcErr.Code == 401
+}
@@ -0,0 +1,156 @@
+package mtproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/rs/zerolog"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func newTestBreaker(now *time.Time) *circuitBreaker {
+	b := newCircuitBreaker("test", zerolog.Nop())
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+
+	for i := 0; i < breakerThreshold; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("attempt %d: unexpected error %v", i, err)
+		}
+		b.record(true)
+	}
+	err := b.allow()
+	if !errors.Is(err, domain.ErrCollectorUnavailable) {
+		t.Fatalf("expected ErrCollectorUnavailable, got %v", err)
+	}
+}
+
+func TestCircuitBreakerResetsOutsideWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.record(true)
+	}
+	now = now.Add(breakerWindow + time.Second)
+	b.record(true)
+	if err := b.allow(); err != nil {
+		t.Fatalf("failures outside the window must not open the breaker: %v", err)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.record(true)
+	}
+	b.record(false)
+	b.record(true)
+	if err := b.allow(); err != nil {
+		t.Fatalf("success must reset consecutive failures: %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+	for i := 0; i < breakerThreshold; i++ {
+		b.record(true)
+	}
+
+	now = now.Add(breakerCooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after cooldown must be allowed: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, domain.ErrCollectorUnavailable) {
+		t.Fatalf("only one probe may run while half-open, got %v", err)
+	}
+
+	b.record(true)
+	if b.state != breakerOpen {
+		t.Fatalf("failed probe must reopen the breaker, state %s", b.state)
+	}
+
+	now = now.Add(breakerCooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("second probe must be allowed: %v", err)
+	}
+	b.record(false)
+	if b.state != breakerClosed {
+		t.Fatalf("successful probe must close the breaker, state %s", b.state)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("closed breaker must allow calls: %v", err)
+	}
+}
+
+func TestIsPoolError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "flood wait", err: tgerr.New(420, "FLOOD_WAIT_30"), want: true},
+		{name: "server error", err: tgerr.New(500, "INTERNAL"), want: true},
+		{name: "bad request", err: tgerr.New(400, "USERNAME_INVALID"), want: false},
+		{name: "plain error", err: fmt.Errorf("канал не найден"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isPoolError(tc.err); got != tc.want {
+				t.Fatalf("isPoolError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsAccountInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "auth key unregistered", err: tgerr.New(401, "AUTH_KEY_UNREGISTERED"), want: true},
+		{name: "session revoked", err: fmt.Errorf("вызов: %w", tgerr.New(401, "SESSION_REVOKED")), want: true},
+		{name: "flood wait", err: tgerr.New(420, "FLOOD_WAIT_30"), want: false},
+		{name: "plain error", err: fmt.Errorf("канал не найден"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isAccountInvalid(tc.err); got != tc.want {
+				t.Fatalf("isAccountInvalid(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunWithAccountsEmptyPool(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+	called := false
+	err := runWithAccounts(nil, time.Second, zerolog.Nop(), "test", b, func(context.Context, *tg.Client) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, domain.ErrNoAvailableAccounts) {
+		t.Fatalf("expected ErrNoAvailableAccounts, got %v", err)
+	}
+	if called {
+		t.Fatalf("fn must not be called without accounts")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 	"github.com/rs/zerolog"
 
 	"tg-digest-bot/internal/domain"
@@ -52,10 +53,30 @@ type Collector struct {
 	accounts []Account
 	log      zerolog.Logger
 	timeout  time.Duration
+	breaker  *circuitBreaker
+	overlap  time.Duration
+	// maxMessages — сколько сообщений канала читать за один сбор; 0 — без ограничения.
+	maxMessages int
+	// pools — дополнительные пулы аккаунтов, которые каналы выбирают через Channel.MTProtoPool.
+	pools map[string]accountPool
 }
 
-// NewCollector создаёт MTProto клиент на базе пула аккаунтов.
-func NewCollector(accounts []Account, log zerolog.Logger) (*Collector, error) {
+// accountPool — именованный пул аккаунтов со своим предохранителем: отказ одного региона
+// не останавливает сбор остальных.
+type accountPool struct {
+	accounts []Account
+	breaker  *circuitBreaker
+}
+
+// historyPageSize — сколько сообщений запрашивать за один вызов messages.getHistory.
+const historyPageSize = 100
+
+// defaultCollectTimeout — таймаут сбора канала на один аккаунт, если он не задан.
+const defaultCollectTimeout = 90 * time.Second
+
+// NewCollector создаёт MTProto клиент на базе пула аккаунтов. timeout ограничивает сбор
+// одного канала на каждом аккаунте пула; timeout <= 0 — значение по умолчанию.
+func NewCollector(accounts []Account, log zerolog.Logger, timeout time.Duration) (*Collector, error) {
 	if len(accounts) == 0 {
 		return nil, fmt.Errorf("at least one MTProto account is required")
 	}
@@ -66,11 +87,85 @@ func NewCollector(accounts []Account, log zerolog.Logger) (*Collector, error) {
 		}
 		checked = append(checked, account)
 	}
-	return &Collector{accounts: checked, log: log, timeout: 90 * time.Second}, nil
+	if timeout <= 0 {
+		timeout = defaultCollectTimeout
+	}
+	return &Collector{accounts: checked, log: log, timeout: timeout, breaker: newCircuitBreaker("collector", log)}, nil
+}
+
+// AddPool регистрирует дополнительный пул аккаунтов name. Каналы с этим Channel.MTProtoPool
+// собираются через него, остальные — через пул, переданный в NewCollector. Вызывается до начала сбора.
+func (c *Collector) AddPool(name string, accounts []Account) error {
+	if name == "" {
+		return fmt.Errorf("pool name is required")
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("pool %q: at least one MTProto account is required", name)
+	}
+	for _, account := range accounts {
+		if err := account.validate(); err != nil {
+			return fmt.Errorf("pool %q: %w", name, err)
+		}
+	}
+	if c.pools == nil {
+		c.pools = make(map[string]accountPool)
+	}
+	c.pools[name] = accountPool{accounts: accounts, breaker: newCircuitBreaker(poolComponent(name), c.log)}
+	return nil
+}
+
+// poolComponent — имя пула в логах и метриках сбора.
+func poolComponent(pool string) string {
+	return "collector:" + pool
+}
+
+// SetCollectOverlap расширяет окно Collect24h назад на overlap, чтобы посты на границе
+// суток не терялись между сбором и постановкой дайджеста. Повторно собранные посты
+// схлопываются upsert'ом в SavePosts, а дайджест всё равно фильтрует посты по своему окну.
+func (c *Collector) SetCollectOverlap(overlap time.Duration) {
+	if overlap < 0 {
+		overlap = 0
+	}
+	c.overlap = overlap
+}
+
+// SetMaxMessages ограничивает число сообщений, читаемых из канала за один сбор. История
+// читается от новых к старым, поэтому при срабатывании лимита остаются самые свежие
+// сообщения окна, и ранжирование дайджеста работает только с ними. max <= 0 снимает лимит.
+func (c *Collector) SetMaxMessages(max int) {
+	if max < 0 {
+		max = 0
+	}
+	c.maxMessages = max
+}
+
+// historyPageLimit возвращает размер следующей страницы истории с учётом лимита;
+// 0 — лимит исчерпан.
+func (c *Collector) historyPageLimit(fetched int) int {
+	if c.maxMessages <= 0 {
+		return historyPageSize
+	}
+	remaining := c.maxMessages - fetched
+	if remaining <= 0 {
+		return 0
+	}
+	if remaining > historyPageSize {
+		return historyPageSize
+	}
+	return remaining
 }
 
-// Collect24h собирает историю канала.
+// Collect24h собирает историю канала за сутки плюс перекрытие.
 func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
+	return c.CollectSince(channel, c.windowStart(time.Now().UTC()))
+}
+
+func (c *Collector) windowStart(now time.Time) time.Time {
+	return now.Add(-24*time.Hour - c.overlap)
+}
+
+// CollectSince собирает историю канала, начиная с указанного момента.
+func (c *Collector) CollectSince(channel domain.Channel, since time.Time) ([]domain.Post, error) {
 	alias := channel.Alias
 	if alias == "" {
 		return nil, fmt.Errorf("channel alias is empty")
@@ -80,41 +175,26 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 		return nil, err
 	}
 
-	since := time.Now().UTC().Add(-24 * time.Hour)
+	since = since.UTC()
 	posts := make([]domain.Post, 0, 64)
 
-	runErr := c.withClient(func(ctx context.Context, api *tg.Client) error {
-		start := time.Now()
-		resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{Username: normalized})
-		metrics.ObserveNetworkRequest("mtproto", "contacts_resolve_username", normalized, start, err)
+	runErr := c.withChannelClient(channel, func(ctx context.Context, api *tg.Client) error {
+		peer, err := resolveChannelPeer(ctx, api, channel, normalized)
 		if err != nil {
-			return fmt.Errorf("resolve channel %s: %w", normalized, err)
+			return err
 		}
-
-		var resolvedChannel *tg.Channel
-		for _, chat := range resolved.Chats {
-			ch, ok := chat.(*tg.Channel)
-			if !ok {
-				continue
-			}
-			if channel.TGChannelID != 0 && ch.ID == channel.TGChannelID {
-				resolvedChannel = ch
-				break
-			}
-			if strings.EqualFold(ch.Username, normalized) {
-				resolvedChannel = ch
-				break
-			}
-		}
-		if resolvedChannel == nil {
-			return fmt.Errorf("канал %s не найден", normalized)
-		}
-
-		peer := &tg.InputPeerChannel{ChannelID: resolvedChannel.ID, AccessHash: resolvedChannel.AccessHash}
-		limit := 100
 		maxID := 0
+		fetched := 0
+		// Сообщения не новее курсора уже сохранены предыдущими сборами.
+		floor := int(channel.LastCollectedMsgID)
 
 		for {
+			limit := c.historyPageLimit(fetched)
+			if limit == 0 {
+				c.log.Warn().Str("channel", normalized).Int("max_messages", c.maxMessages).Time("since", since).
+					Msg("collector: достигнут лимит сообщений за сбор, более старые посты окна пропущены")
+				break
+			}
 			req := &tg.MessagesGetHistoryRequest{
 				Peer:  peer,
 				Limit: limit,
@@ -122,8 +202,11 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 			if maxID > 0 {
 				req.MaxID = maxID
 			}
+			if floor > 0 {
+				req.MinID = floor
+			}
 
-			start = time.Now()
+			start := time.Now()
 			history, err := api.MessagesGetHistory(ctx, req)
 			metrics.ObserveNetworkRequest("mtproto", "messages_get_history", normalized, start, err)
 			if err != nil {
@@ -137,6 +220,7 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 			if len(channelMessages.Messages) == 0 {
 				break
 			}
+			fetched += len(channelMessages.Messages)
 
 			oldestID := 0
 			stop := false
@@ -149,6 +233,10 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 				if oldestID == 0 || tm.ID < oldestID {
 					oldestID = tm.ID
 				}
+				if floor > 0 && tm.ID <= floor {
+					stop = true
+					continue
+				}
 
 				published := time.Unix(int64(tm.Date), 0).UTC()
 				if published.Before(since) {
@@ -173,7 +261,8 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 					TGMsgID:     int64(tm.ID),
 					PublishedAt: published,
 					URL:         fmt.Sprintf("https://t.me/%s/%d", normalized, tm.ID),
-					Text:        text,
+					Text:        domain.TruncatePostText(text),
+					FullText:    text,
 					RawMetaJSON: rawMeta,
 					Hash:        hashMessage(channel.ID, tm.ID, text),
 				})
@@ -200,6 +289,26 @@ func (c *Collector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
 	return posts, nil
 }
 
+// resolveChannelPeer находит канал по алиасу и возвращает его адрес для запросов истории.
+func resolveChannelPeer(ctx context.Context, api *tg.Client, channel domain.Channel, normalized string) (*tg.InputPeerChannel, error) {
+	start := time.Now()
+	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{Username: normalized})
+	metrics.ObserveNetworkRequest("mtproto", "contacts_resolve_username", normalized, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("resolve channel %s: %w", normalized, err)
+	}
+	for _, chat := range resolved.Chats {
+		ch, ok := chat.(*tg.Channel)
+		if !ok {
+			continue
+		}
+		if (channel.TGChannelID != 0 && ch.ID == channel.TGChannelID) || strings.EqualFold(ch.Username, normalized) {
+			return &tg.InputPeerChannel{ChannelID: ch.ID, AccessHash: ch.AccessHash}, nil
+		}
+	}
+	return nil, fmt.Errorf("канал %s не найден", normalized)
+}
+
 type messageMeta struct {
 	Views      int        `json:"views,omitempty"`
 	Forwards   int        `json:"forwards,omitempty"`
@@ -255,6 +364,7 @@ type Resolver struct {
 	accounts []Account
 	log      zerolog.Logger
 	timeout  time.Duration
+	breaker  *circuitBreaker
 }
 
 // NewResolver создаёт резолвер с MTProto клиентом.
@@ -269,7 +379,7 @@ func NewResolver(accounts []Account, log zerolog.Logger) (*Resolver, error) {
 		}
 		checked = append(checked, account)
 	}
-	return &Resolver{accounts: checked, log: log, timeout: 20 * time.Second}, nil
+	return &Resolver{accounts: checked, log: log, timeout: 20 * time.Second, breaker: newCircuitBreaker("resolver", log)}, nil
 }
 
 // ResolvePublic возвращает ChannelMeta.
@@ -315,35 +425,122 @@ func (r *Resolver) ResolvePublic(alias string) (domain.ChannelMeta, error) {
 	return meta, nil
 }
 
-func (c *Collector) withClient(fn func(ctx context.Context, api *tg.Client) error) error {
-	return runWithAccounts(c.accounts, c.timeout, c.log, "collector", fn)
+// ProbeHistory резолвит канал и читает одно сообщение истории. Отказы Telegram из-за
+// закрытой истории — это ответ проверки, а не сбой аккаунта, поэтому они возвращаются
+// в ChannelProbe.Reason без перебора остальных аккаунтов пула.
+func (r *Resolver) ProbeHistory(alias string) (domain.ChannelProbe, error) {
+	username, err := normalizeAlias(alias)
+	if err != nil {
+		return domain.ChannelProbe{}, err
+	}
+	var probe domain.ChannelProbe
+	err = r.withClient(func(ctx context.Context, api *tg.Client) error {
+		probe = domain.ChannelProbe{}
+		start := time.Now()
+		resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{Username: username})
+		metrics.ObserveNetworkRequest("mtproto", "contacts_resolve_username", username, start, err)
+		if err != nil {
+			if reason, ok := probeReason(err); ok {
+				probe.Reason = reason
+				return nil
+			}
+			return fmt.Errorf("не удалось получить канал %s: %w", username, err)
+		}
+		var channel *tg.Channel
+		for _, chat := range resolved.Chats {
+			if ch, ok := chat.(*tg.Channel); ok {
+				channel = ch
+				break
+			}
+		}
+		if channel == nil {
+			probe.Reason = domain.ProbeReasonNotChannel
+			return nil
+		}
+		probe.Meta = domain.ChannelMeta{
+			ID:     channel.ID,
+			Alias:  strings.ToLower(channel.Username),
+			Title:  channel.Title,
+			Public: channel.Username != "",
+		}
+		if !probe.Meta.Public {
+			probe.Reason = domain.ProbeReasonPrivate
+			return nil
+		}
+
+		start = time.Now()
+		history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  &tg.InputPeerChannel{ChannelID: channel.ID, AccessHash: channel.AccessHash},
+			Limit: 1,
+		})
+		metrics.ObserveNetworkRequest("mtproto", "messages_get_history", username, start, err)
+		if err != nil {
+			if reason, ok := probeReason(err); ok {
+				probe.Reason = reason
+				return nil
+			}
+			return fmt.Errorf("messages.getHistory: %w", err)
+		}
+		messages, ok := history.(*tg.MessagesChannelMessages)
+		if !ok {
+			return fmt.Errorf("unexpected history response %T", history)
+		}
+		probe.Readable = true
+		probe.Empty = len(messages.Messages) == 0
+		return nil
+	})
+	if err != nil {
+		r.log.Error().Err(err).Str("alias", username).Msg("ошибка проверки истории канала")
+		return domain.ChannelProbe{}, err
+	}
+	return probe, nil
+}
+
+// probeReason распознаёт ответы Telegram, которые означают недоступную историю канала.
+func probeReason(err error) (domain.ProbeReason, bool) {
+	switch {
+	case tgerr.Is(err, "USERNAME_NOT_OCCUPIED", "USERNAME_INVALID", "CHANNEL_INVALID"):
+		return domain.ProbeReasonNotFound, true
+	case tgerr.Is(err, "CHANNEL_PRIVATE", "CHANNEL_PUBLIC_GROUP_NA"):
+		return domain.ProbeReasonPrivate, true
+	case tgerr.Is(err, "CHAT_ADMIN_REQUIRED"):
+		return domain.ProbeReasonAdminRequired, true
+	}
+	return "", false
+}
+
+// withChannelClient выполняет fn через пул канала.
+func (c *Collector) withChannelClient(channel domain.Channel, fn func(ctx context.Context, api *tg.Client) error) error {
+	pool, component := c.poolFor(channel)
+	return runWithAccounts(pool.accounts, c.timeout, c.log, component, pool.breaker, fn)
+}
+
+// poolFor выбирает пул канала; если предпочтительный пул не загружен — основной.
+func (c *Collector) poolFor(channel domain.Channel) (accountPool, string) {
+	if pool, ok := c.pools[channel.MTProtoPool]; ok {
+		return pool, poolComponent(channel.MTProtoPool)
+	}
+	return accountPool{accounts: c.accounts, breaker: c.breaker}, "collector"
 }
 
 func (r *Resolver) withClient(fn func(ctx context.Context, api *tg.Client) error) error {
-	return runWithAccounts(r.accounts, r.timeout, r.log, "resolver", fn)
+	return runWithAccounts(r.accounts, r.timeout, r.log, "resolver", r.breaker, fn)
 }
 
 func normalizeAlias(alias string) (string, error) {
-	trimmed := strings.TrimSpace(alias)
-	trimmed = strings.TrimPrefix(trimmed, "https://")
-	trimmed = strings.TrimPrefix(trimmed, "http://")
-	trimmed = strings.TrimPrefix(trimmed, "t.me/")
-	trimmed = strings.TrimPrefix(trimmed, "@")
-	if trimmed == "" {
-		return "", fmt.Errorf("пустой alias")
-	}
-	if strings.Contains(trimmed, "/") {
-		parts := strings.Split(trimmed, "/")
-		if len(parts) == 0 || parts[0] == "" {
-			return "", fmt.Errorf("некорректный alias")
-		}
-		trimmed = parts[0]
-	}
-	return trimmed, nil
+	return domain.ChannelUsername(alias)
 }
 
-func runWithAccounts(accounts []Account, timeout time.Duration, log zerolog.Logger, component string, fn func(ctx context.Context, api *tg.Client) error) error {
+func runWithAccounts(accounts []Account, timeout time.Duration, log zerolog.Logger, component string, breaker *circuitBreaker, fn func(ctx context.Context, api *tg.Client) error) error {
+	if breaker != nil {
+		if err := breaker.allow(); err != nil {
+			return err
+		}
+	}
 	var attemptErrors []string
+	poolFailure := true
+	// accountsInvalid — все аккаунты отклонены как неавторизованные: пул фактически пуст.
+	accountsInvalid := true
 	for _, account := range accounts {
 		client := telegram.NewClient(account.APIID, account.APIHash, telegram.Options{SessionStorage: account.Storage})
 		err := client.Run(context.Background(), func(ctx context.Context) error {
@@ -352,13 +549,32 @@ func runWithAccounts(accounts []Account, timeout time.Duration, log zerolog.Logg
 			return fn(ctx, client.API())
 		})
 		if err == nil {
+			if breaker != nil {
+				breaker.record(false)
+			}
+			metrics.SetMTProtoPoolExhausted(component, false)
 			return nil
 		}
 		log.Warn().Err(err).Str("account", account.Name).Msg(component + ": MTProto вызов завершился ошибкой, пробуем следующую сессию")
 		attemptErrors = append(attemptErrors, fmt.Sprintf("%s: %v", account.Name, err))
+		if !isPoolError(err) {
+			poolFailure = false
+		}
+		if !isAccountInvalid(err) {
+			accountsInvalid = false
+		}
+	}
+	exhausted := len(attemptErrors) == 0 || accountsInvalid
+	if breaker != nil {
+		breaker.record((poolFailure || exhausted) && len(attemptErrors) > 0)
 	}
-	if len(attemptErrors) == 0 {
-		return fmt.Errorf("%s: нет доступных MTProto аккаунтов", component)
+	metrics.SetMTProtoPoolExhausted(component, exhausted)
+	if exhausted {
+		log.Error().Int("accounts", len(accounts)).Msg(component + ": в пуле MTProto не осталось рабочих аккаунтов")
+		if len(attemptErrors) == 0 {
+			return fmt.Errorf("%s: %w", component, domain.ErrNoAvailableAccounts)
+		}
+		return fmt.Errorf("%s: %w: %s", component, domain.ErrNoAvailableAccounts, strings.Join(attemptErrors, "; "))
 	}
 	return fmt.Errorf("%s: все MTProto аккаунты завершились ошибкой: %s", component, strings.Join(attemptErrors, "; "))
 }
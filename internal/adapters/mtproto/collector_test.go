@@ -0,0 +1,120 @@
+package mtproto
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestCollectorWindowStartIncludesOverlap(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	c := &Collector{}
+	if got, want := c.windowStart(now), now.Add(-24*time.Hour); !got.Equal(want) {
+		t.Fatalf("without overlap: got %s, want %s", got, want)
+	}
+
+	c.SetCollectOverlap(5 * time.Minute)
+	if got, want := c.windowStart(now), time.Date(2024, 1, 1, 11, 55, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("with overlap: got %s, want %s", got, want)
+	}
+
+	c.SetCollectOverlap(-time.Minute)
+	if got, want := c.windowStart(now), now.Add(-24*time.Hour); !got.Equal(want) {
+		t.Fatalf("negative overlap should be ignored: got %s, want %s", got, want)
+	}
+}
+
+func TestCollectorRoutesChannelsByPool(t *testing.T) {
+	account := func(name string) Account {
+		return Account{Name: name, APIID: 1, APIHash: "hash", Storage: &session.StorageMemory{}}
+	}
+	c := &Collector{accounts: []Account{account("main")}}
+	if err := c.AddPool("eu", []Account{account("eu-1")}); err != nil {
+		t.Fatalf("add pool: %v", err)
+	}
+	if err := c.AddPool("us", nil); err == nil {
+		t.Fatal("empty pool should be rejected")
+	}
+
+	pool, component := c.poolFor(domain.Channel{Alias: "news", MTProtoPool: "eu"})
+	if component != "collector:eu" || len(pool.accounts) != 1 || pool.accounts[0].Name != "eu-1" {
+		t.Fatalf("preferred pool: got %s %+v", component, pool.accounts)
+	}
+	for _, preferred := range []string{"", "us"} {
+		pool, component = c.poolFor(domain.Channel{Alias: "news", MTProtoPool: preferred})
+		if component != "collector" || pool.accounts[0].Name != "main" {
+			t.Fatalf("pool %q should fall back to default, got %s %+v", preferred, component, pool.accounts)
+		}
+	}
+}
+
+func TestCollectorHistoryPageLimit(t *testing.T) {
+	c := &Collector{}
+	if got := c.historyPageLimit(10_000); got != historyPageSize {
+		t.Fatalf("without cap: got %d, want %d", got, historyPageSize)
+	}
+
+	c.SetMaxMessages(250)
+	for fetched, want := range map[int]int{0: 100, 100: 100, 200: 50, 250: 0, 300: 0} {
+		if got := c.historyPageLimit(fetched); got != want {
+			t.Fatalf("fetched %d: got %d, want %d", fetched, got, want)
+		}
+	}
+
+	c.SetMaxMessages(-1)
+	if got := c.historyPageLimit(10_000); got != historyPageSize {
+		t.Fatalf("negative cap should disable the limit: got %d", got)
+	}
+}
+
+func TestProbeReason(t *testing.T) {
+	cases := map[string]domain.ProbeReason{
+		"CHANNEL_PRIVATE":       domain.ProbeReasonPrivate,
+		"CHAT_ADMIN_REQUIRED":   domain.ProbeReasonAdminRequired,
+		"USERNAME_NOT_OCCUPIED": domain.ProbeReasonNotFound,
+	}
+	for rpcType, want := range cases {
+		err := fmt.Errorf("messages.getHistory: %w", tgerr.New(400, rpcType))
+		if got, ok := probeReason(err); !ok || got != want {
+			t.Fatalf("%s: got %q (ok=%v), want %q", rpcType, got, ok, want)
+		}
+	}
+	if _, ok := probeReason(tgerr.New(420, "FLOOD_WAIT_30")); ok {
+		t.Fatal("flood wait is an account failure, not a probe verdict")
+	}
+}
+
+func TestPickDiscussedPosts(t *testing.T) {
+	posts := []domain.Post{
+		{RawMetaJSON: []byte(`{"replies":3}`)},
+		{RawMetaJSON: []byte(`{"replies":12}`)},
+		{},
+		{RawMetaJSON: []byte(`{"replies":40}`)},
+		{RawMetaJSON: []byte(`{"replies":5}`)},
+	}
+	got := pickDiscussedPosts(posts, 2, 5)
+	if len(got) != 2 || got[0] != 3 || got[1] != 1 {
+		t.Fatalf("got %v, want [3 1]", got)
+	}
+}
+
+func TestTopCommentsPrefersReactions(t *testing.T) {
+	reacted := &tg.Message{Message: "  agree\nfully "}
+	reacted.SetReactions(tg.MessageReactions{Results: []tg.ReactionCount{{Count: 9}}})
+	messages := []tg.MessageClass{
+		&tg.Message{Message: "plain"},
+		&tg.Message{Message: ""},
+		reacted,
+		&tg.MessageService{},
+	}
+	got := topComments(messages, 3)
+	if len(got) != 2 || got[0] != "agree fully" || got[1] != "plain" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package mtproto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/tg"
+
+	"tg-digest-bot/internal/domain"
+	"tg-digest-bot/internal/infra/metrics"
+)
+
+const (
+	// commentPostsPerChannel — у скольких самых обсуждаемых постов канала читать комментарии.
+	commentPostsPerChannel = 3
+	// commentMinReplies — сколько ответов должно быть у поста, чтобы читать обсуждение.
+	commentMinReplies = 5
+	// commentsPerPost — сколько лучших комментариев сохранять у поста.
+	commentsPerPost = 3
+	// commentsFetchLimit — сколько комментариев запрашивать, чтобы выбрать из них лучшие.
+	commentsFetchLimit = 50
+	commentRuneLimit   = 280
+)
+
+var _ domain.CommentCollector = (*Collector)(nil)
+
+// CollectComments дописывает самым обсуждаемым постам лучшие комментарии через messages.getReplies.
+// У каналов без группы обсуждения у постов нет ответов, и запросов не делается. Ошибка чтения
+// обсуждения одного поста не мешает остальным: такой пост остаётся без комментариев.
+func (c *Collector) CollectComments(channel domain.Channel, posts []domain.Post) ([]domain.Post, error) {
+	picked := pickDiscussedPosts(posts, commentPostsPerChannel, commentMinReplies)
+	if len(picked) == 0 {
+		return posts, nil
+	}
+	normalized, err := normalizeAlias(channel.Alias)
+	if err != nil {
+		return posts, err
+	}
+	enriched := append([]domain.Post(nil), posts...)
+	runErr := c.withChannelClient(channel, func(ctx context.Context, api *tg.Client) error {
+		peer, err := resolveChannelPeer(ctx, api, channel, normalized)
+		if err != nil {
+			return err
+		}
+		for _, idx := range picked {
+			msgID := int(enriched[idx].TGMsgID)
+			start := time.Now()
+			replies, err := api.MessagesGetReplies(ctx, &tg.MessagesGetRepliesRequest{Peer: peer, MsgID: msgID, Limit: commentsFetchLimit})
+			metrics.ObserveNetworkRequest("mtproto", "messages_get_replies", normalized, start, err)
+			if err != nil {
+				c.log.Debug().Err(err).Str("channel", normalized).Int("msg_id", msgID).Msg("collector: не удалось прочитать обсуждение поста")
+				continue
+			}
+			modified, ok := replies.AsModified()
+			if !ok {
+				continue
+			}
+			enriched[idx] = enriched[idx].WithComments(topComments(modified.GetMessages(), commentsPerPost))
+		}
+		return nil
+	})
+	if runErr != nil {
+		return posts, fmt.Errorf("комментарии %s: %w", normalized, runErr)
+	}
+	return enriched, nil
+}
+
+// pickDiscussedPosts возвращает индексы не более limit постов с наибольшим числом ответов,
+// но не меньше minReplies.
+func pickDiscussedPosts(posts []domain.Post, limit, minReplies int) []int {
+	type candidate struct {
+		idx     int
+		replies int
+	}
+	var candidates []candidate
+	for i, post := range posts {
+		var meta messageMeta
+		if len(post.RawMetaJSON) == 0 || json.Unmarshal(post.RawMetaJSON, &meta) != nil {
+			continue
+		}
+		if meta.Replies >= minReplies {
+			candidates = append(candidates, candidate{idx: i, replies: meta.Replies})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].replies > candidates[j].replies })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	picked := make([]int, 0, len(candidates))
+	for _, cand := range candidates {
+		picked = append(picked, cand.idx)
+	}
+	return picked
+}
+
+// topComments выбирает до limit текстовых комментариев с наибольшим числом реакций.
+func topComments(messages []tg.MessageClass, limit int) []string {
+	type comment struct {
+		text      string
+		reactions int
+	}
+	var comments []comment
+	for _, msg := range messages {
+		m, ok := msg.(*tg.Message)
+		if !ok {
+			continue
+		}
+		text := strings.Join(strings.Fields(m.Message), " ")
+		if text == "" {
+			continue
+		}
+		if runes := []rune(text); len(runes) > commentRuneLimit {
+			text = string(runes[:commentRuneLimit]) + "…"
+		}
+		comments = append(comments, comment{text: text, reactions: buildMessageMeta(m).Reactions})
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].reactions > comments[j].reactions })
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	texts := make([]string, 0, len(comments))
+	for _, cm := range comments {
+		texts = append(texts, cm.text)
+	}
+	return texts
+}
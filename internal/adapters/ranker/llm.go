@@ -18,21 +18,22 @@ type chatCompletionClient interface {
 
 // LLMRanker использует LLM для группировки и аннотирования постов.
 type LLMRanker struct {
-	client   chatCompletionClient
-	model    string
-	timeout  time.Duration
-	maxItems int
+	client      chatCompletionClient
+	model       string
+	temperature *float64
+	timeout     time.Duration
+	maxItems    int
 }
 
-// NewLLM создаёт ранжировщик на базе OpenAI Chat Completions.
-func NewLLM(client chatCompletionClient, model string, timeout time.Duration, maxItems int) *LLMRanker {
+// NewLLM создаёт ранжировщик на базе OpenAI Chat Completions. temperature == nil — значение по умолчанию модели.
+func NewLLM(client chatCompletionClient, model string, temperature *float64, timeout time.Duration, maxItems int) *LLMRanker {
 	if maxItems <= 0 {
 		maxItems = 10
 	}
 	if timeout <= 0 {
 		timeout = 300 * time.Second
 	}
-	return &LLMRanker{client: client, model: model, timeout: timeout, maxItems: maxItems}
+	return &LLMRanker{client: client, model: model, temperature: temperature, timeout: timeout, maxItems: maxItems}
 }
 
 type llmPostPayload struct {
@@ -99,9 +100,8 @@ func (r *LLMRanker) Rank(posts []domain.Post) (domain.DigestOutline, error) {
 %s`, r.maxItems, string(body))
 
 	req := openai.ChatCompletionRequest{
-		//Model:       r.model,
-		Model: "gpt-5-mini",
-		//Temperature: 0.2,
+		Model:       r.model,
+		Temperature: r.temperature,
 		//MaxTokens:   8000,
 		Messages: []openai.ChatMessage{
 			{
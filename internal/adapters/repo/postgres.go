@@ -21,10 +21,12 @@ import (
 
 // Postgres реализует репозитории на основе pgxpool.
 type Postgres struct {
-	pool *pgxpool.Pool
+	pool          *pgxpool.Pool
+	referralGuard domain.ReferralGuard
 }
 
 var _ domain.BusinessMetricRepo = (*Postgres)(nil)
+var _ domain.BusinessMetricReader = (*Postgres)(nil)
 var _ domain.FeedbackRepo = (*Postgres)(nil)
 
 const (
@@ -38,6 +40,11 @@ func NewPostgres(pool *pgxpool.Pool) *Postgres {
 	return &Postgres{pool: pool}
 }
 
+// SetReferralGuard включает проверку приглашений на накрутку. Вызывается при старте сервиса.
+func (p *Postgres) SetReferralGuard(guard domain.ReferralGuard) {
+	p.referralGuard = guard
+}
+
 func generateReferralCode() (string, error) {
 	buf := make([]byte, referralCodeLength)
 	if _, err := crand.Read(buf); err != nil {
@@ -109,6 +116,139 @@ func (p *Postgres) RecordBusinessMetric(ctx context.Context, metric domain.Busin
 	return p.saveBusinessMetric(ctx, metric)
 }
 
+// QueryBusinessMetrics возвращает события по фильтру в порядке id.
+func (p *Postgres) QueryBusinessMetrics(ctx context.Context, query domain.BusinessMetricQuery) ([]domain.BusinessMetric, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	conditions := []string{"id > $1"}
+	args := []any{query.AfterID}
+	if query.Event != "" {
+		args = append(args, query.Event)
+		conditions = append(conditions, fmt.Sprintf("event = $%d", len(args)))
+	}
+	if !query.From.IsZero() {
+		args = append(args, query.From)
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+	if !query.To.IsZero() {
+		args = append(args, query.To)
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+	args = append(args, query.Limit)
+	sqlText := fmt.Sprintf(`
+SELECT id, event, user_id, channel_id, metadata, occurred_at
+FROM business_metrics
+WHERE %s
+ORDER BY id
+LIMIT $%d
+`, strings.Join(conditions, " AND "), len(args))
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, sqlText, args...)
+	metrics.ObserveNetworkRequest("postgres", "business_metrics_query", "business_metrics", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.BusinessMetric
+	for rows.Next() {
+		var (
+			m         domain.BusinessMetric
+			userID    sql.NullInt64
+			channelID sql.NullInt64
+			payload   []byte
+		)
+		if err := rows.Scan(&m.ID, &m.Event, &userID, &channelID, &payload, &m.OccurredAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			id := userID.Int64
+			m.UserID = &id
+		}
+		if channelID.Valid {
+			id := channelID.Int64
+			m.ChannelID = &id
+		}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &m.Metadata); err != nil {
+				return nil, fmt.Errorf("метаданные события %d: %w", m.ID, err)
+			}
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// RollupBusinessMetrics пересчитывает дневные агрегаты за сутки day (UTC). Повторный запуск
+// перезаписывает строки, поэтому безопасен и для догоняющих событий.
+func (p *Postgres) RollupBusinessMetrics(ctx context.Context, day time.Time) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO business_metrics_daily (day, event, count, distinct_users)
+SELECT $3::date, event, count(*), count(DISTINCT user_id)
+FROM business_metrics
+WHERE occurred_at >= $1 AND occurred_at < $2
+GROUP BY event
+ON CONFLICT (day, event) DO UPDATE
+SET count = EXCLUDED.count, distinct_users = EXCLUDED.distinct_users, updated_at = now()
+`, from, to, from.Format("2006-01-02"))
+	metrics.ObserveNetworkRequest("postgres", "business_metrics_rollup", "business_metrics_daily", start, err)
+	return err
+}
+
+// ListBusinessMetricsDaily возвращает дневные агрегаты по фильтру в порядке дат.
+func (p *Postgres) ListBusinessMetricsDaily(ctx context.Context, query domain.BusinessMetricQuery) ([]domain.BusinessMetricDaily, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	conditions := []string{"TRUE"}
+	var args []any
+	if query.Event != "" {
+		args = append(args, query.Event)
+		conditions = append(conditions, fmt.Sprintf("event = $%d", len(args)))
+	}
+	if !query.From.IsZero() {
+		args = append(args, query.From.UTC().Format("2006-01-02"))
+		conditions = append(conditions, fmt.Sprintf("day >= $%d::date", len(args)))
+	}
+	if !query.To.IsZero() {
+		args = append(args, query.To.UTC().Format("2006-01-02"))
+		conditions = append(conditions, fmt.Sprintf("day < $%d::date", len(args)))
+	}
+	sqlText := fmt.Sprintf(`
+SELECT day, event, count, distinct_users
+FROM business_metrics_daily
+WHERE %s
+ORDER BY day, event
+`, strings.Join(conditions, " AND "))
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, sqlText, args...)
+	metrics.ObserveNetworkRequest("postgres", "business_metrics_daily_list", "business_metrics_daily", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.BusinessMetricDaily
+	for rows.Next() {
+		var d domain.BusinessMetricDaily
+		if err := rows.Scan(&d.Day, &d.Event, &d.Count, &d.DistinctUsers); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
 // SaveFeedback сохраняет отзыв пользователя.
 func (p *Postgres) SaveFeedback(ctx context.Context, feedback domain.Feedback) error {
 	message := strings.TrimSpace(feedback.Message)
@@ -239,20 +379,25 @@ func (p *Postgres) GetByTGID(tgUserID int64) (domain.User, error) {
 
 	start := time.Now()
 	var (
-		manualDate sql.NullTime
-		referredBy sql.NullInt64
-		tzValue    sql.NullString
-		firstName  sql.NullString
-		lastName   sql.NullString
-		username   sql.NullString
+		manualDate  sql.NullTime
+		referredBy  sql.NullInt64
+		tzValue     sql.NullString
+		firstName   sql.NullString
+		lastName    sql.NullString
+		username    sql.NullString
+		limitOver   sql.NullInt32
+		email       sql.NullString
+		emailAt     sql.NullTime
+		digestLang  sql.NullString
+		pausedUntil sql.NullTime
 	)
 	err := p.pool.QueryRow(ctx, `
-SELECT id, tg_user_id, locale, tz, daily_time, created_at, updated_at, role, manual_requests_total, manual_requests_today, manual_requests_date, referral_code, referrals_count, referred_by, first_name, last_name, username, is_bot
+SELECT id, tg_user_id, locale, tz, daily_time, created_at, updated_at, role, manual_requests_total, manual_requests_today, manual_requests_date, referral_code, referrals_count, referred_by, first_name, last_name, username, is_bot, channel_limit_override, filter_ads, email, email_verified_at, delivery_mode, delivery_disabled, digest_lang, link_previews, paused, paused_until, collect_comments, order_by, COALESCE(delivery_chat_id, 0)
 FROM users WHERE tg_user_id=$1
-`, tgUserID).Scan(&user.ID, &user.TGUserID, &user.Locale, &tzValue, &user.DailyTime, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.ManualRequestsTotal, &user.ManualRequestsToday, &manualDate, &user.ReferralCode, &user.ReferralsCount, &referredBy, &firstName, &lastName, &username, &user.IsBot)
+`, tgUserID).Scan(&user.ID, &user.TGUserID, &user.Locale, &tzValue, &user.DailyTime, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.ManualRequestsTotal, &user.ManualRequestsToday, &manualDate, &user.ReferralCode, &user.ReferralsCount, &referredBy, &firstName, &lastName, &username, &user.IsBot, &limitOver, &user.FilterAds, &email, &emailAt, &user.DeliveryMode, &user.DeliveryDisabled, &digestLang, &user.LinkPreviews, &user.Paused, &pausedUntil, &user.CollectComments, &user.DigestOrder, &user.DeliveryChatID)
 	metrics.ObserveNetworkRequest("postgres", "users_get_by_tgid", "users", start, err)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return domain.User{}, fmt.Errorf("user not found")
+		return domain.User{}, domain.ErrUserNotFound
 	}
 	if manualDate.Valid {
 		ts := manualDate.Time
@@ -274,6 +419,19 @@ FROM users WHERE tg_user_id=$1
 	if username.Valid {
 		user.Username = username.String
 	}
+	if limitOver.Valid {
+		limit := int(limitOver.Int32)
+		user.ChannelLimitOverride = &limit
+	}
+	if email.Valid {
+		user.Email = email.String
+		user.EmailVerified = emailAt.Valid
+	}
+	user.DigestLang = digestLang.String
+	if pausedUntil.Valid {
+		until := pausedUntil.Time
+		user.PausedUntil = &until
+	}
 	return user, err
 }
 
@@ -285,9 +443,10 @@ func (p *Postgres) ListForDailyTime(now time.Time) ([]domain.User, error) {
 
 	start := time.Now()
 	rows, err := p.pool.Query(ctx, `
-SELECT id, tg_user_id, locale, tz, daily_time, created_at, updated_at, role, manual_requests_total, manual_requests_today, manual_requests_date, referral_code, referrals_count, referred_by, first_name, last_name, username, is_bot
-FROM users WHERE daily_time IS NOT NULL
-`)
+SELECT id, tg_user_id, locale, tz, daily_time, created_at, updated_at, role, manual_requests_total, manual_requests_today, manual_requests_date, referral_code, referrals_count, referred_by, first_name, last_name, username, is_bot, COALESCE(delivery_chat_id, 0)
+FROM users WHERE daily_time IS NOT NULL AND (NOT delivery_disabled OR delivery_chat_id IS NOT NULL OR (delivery_mode <> 'telegram' AND email_verified_at IS NOT NULL))
+  AND (NOT paused OR (paused_until IS NOT NULL AND paused_until <= $1))
+`, now)
 	metrics.ObserveNetworkRequest("postgres", "users_list_for_daily_time", "users", start, err)
 	if err != nil {
 		return nil, err
@@ -304,7 +463,7 @@ FROM users WHERE daily_time IS NOT NULL
 			lastName   sql.NullString
 			username   sql.NullString
 		)
-		if err := rows.Scan(&u.ID, &u.TGUserID, &u.Locale, &tzValue, &u.DailyTime, &u.CreatedAt, &u.UpdatedAt, &u.Role, &u.ManualRequestsTotal, &u.ManualRequestsToday, &manualDate, &u.ReferralCode, &u.ReferralsCount, &referredBy, &firstName, &lastName, &username, &u.IsBot); err != nil {
+		if err := rows.Scan(&u.ID, &u.TGUserID, &u.Locale, &tzValue, &u.DailyTime, &u.CreatedAt, &u.UpdatedAt, &u.Role, &u.ManualRequestsTotal, &u.ManualRequestsToday, &manualDate, &u.ReferralCode, &u.ReferralsCount, &referredBy, &firstName, &lastName, &username, &u.IsBot, &u.DeliveryChatID); err != nil {
 			return nil, err
 		}
 		if manualDate.Valid {
@@ -350,6 +509,17 @@ ON CONFLICT (user_id, scheduled_for) DO NOTHING
 	return res.RowsAffected() > 0, nil
 }
 
+// ReleaseScheduleTask удаляет запись о поставленной задаче, чтобы её можно было поставить снова.
+func (p *Postgres) ReleaseScheduleTask(userID int64, scheduledFor time.Time) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `DELETE FROM schedule_tasks WHERE user_id=$1 AND scheduled_for=$2`, userID, scheduledFor)
+	metrics.ObserveNetworkRequest("postgres", "schedule_tasks_release", "schedule_tasks", start, err)
+	return err
+}
+
 // UpdateDailyTime обновляет время.
 func (p *Postgres) UpdateDailyTime(userID int64, daily time.Time) error {
 	ctx, cancel := p.connCtx()
@@ -495,6 +665,71 @@ WHERE id=$1
 	return state, nil
 }
 
+// GrantManualRequests обнуляет сегодняшний счётчик ручных запросов (extra == 0) или
+// выдаёт extra дополнительных запросов на сегодня, уводя счётчик ниже нуля.
+func (p *Postgres) GrantManualRequests(userID int64, extra int, now time.Time) (domain.ManualRequestState, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	metrics.ObserveNetworkRequest("postgres", "begin_tx", "users", start, err)
+	if err != nil {
+		return domain.ManualRequestState{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		user       domain.User
+		manualDate sql.NullTime
+	)
+	start = time.Now()
+	err = tx.QueryRow(ctx, `
+SELECT id, role, manual_requests_total, manual_requests_today, manual_requests_date
+FROM users WHERE id=$1 FOR UPDATE
+`, userID).Scan(&user.ID, &user.Role, &user.ManualRequestsTotal, &user.ManualRequestsToday, &manualDate)
+	metrics.ObserveNetworkRequest("postgres", "users_get_for_update", "users", start, err)
+	if err != nil {
+		return domain.ManualRequestState{}, err
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	usedToday := user.ManualRequestsToday
+	if !manualDate.Valid || !sameDay(manualDate.Time, today) {
+		usedToday = 0
+	}
+	if extra <= 0 {
+		usedToday = 0
+	} else {
+		usedToday -= extra
+	}
+
+	start = time.Now()
+	_, err = tx.Exec(ctx, `
+UPDATE users
+SET manual_requests_today=$2, manual_requests_date=$3, updated_at=now()
+WHERE id=$1
+`, userID, usedToday, today)
+	metrics.ObserveNetworkRequest("postgres", "users_grant_manual_requests", "users", start, err)
+	if err != nil {
+		return domain.ManualRequestState{}, err
+	}
+
+	start = time.Now()
+	err = tx.Commit(ctx)
+	metrics.ObserveNetworkRequest("postgres", "commit", "users", start, err)
+	if err != nil {
+		return domain.ManualRequestState{}, err
+	}
+
+	return domain.ManualRequestState{
+		Allowed:   true,
+		Plan:      user.Plan(),
+		TotalUsed: user.ManualRequestsTotal,
+		UsedToday: usedToday,
+	}, nil
+}
+
 // ApplyReferral закрепляет реферала за пользователем и обновляет награды.
 func (p *Postgres) ApplyReferral(code string, newUserID int64) (domain.ReferralResult, error) {
 	ctx, cancel := p.connCtx()
@@ -617,6 +852,20 @@ FROM users WHERE referral_code=$1 FOR UPDATE
 		return domain.ReferralResult{User: user}, nil
 	}
 
+	signals := domain.ReferralSignals{TGUserID: user.TGUserID, IsBot: user.IsBot}
+	if p.referralGuard.NeedsHistory(signals) {
+		start = time.Now()
+		err = tx.QueryRow(ctx, `
+SELECT count(*) FROM referrals
+WHERE referrer_id=$1 AND created_at > $2 AND tg_user_id >= $3
+`, referrer.ID, time.Now().Add(-p.referralGuard.Window), p.referralGuard.FreshTGUserID).Scan(&signals.RecentFresh)
+		metrics.ObserveNetworkRequest("postgres", "referrals_count_recent", "referrals", start, err)
+		if err != nil {
+			return domain.ReferralResult{}, err
+		}
+	}
+	status := p.referralGuard.Assess(signals)
+
 	start = time.Now()
 	res, err := tx.Exec(ctx, `UPDATE users SET referred_by=$2, updated_at=now() WHERE id=$1 AND referred_by IS NULL`, user.ID, referrer.ID)
 	metrics.ObserveNetworkRequest("postgres", "users_apply_referral", "users", start, err)
@@ -633,23 +882,37 @@ FROM users WHERE referral_code=$1 FOR UPDATE
 		return domain.ReferralResult{User: user}, nil
 	}
 
-	newCount := referrer.ReferralsCount + 1
-	newRole := domain.RoleForReferralProgress(referrer.Role, newCount)
-	previousRole := referrer.Role
-	upgraded := newRole != referrer.Role
-	if upgraded {
-		start = time.Now()
-		_, err = tx.Exec(ctx, `UPDATE users SET referrals_count=$2, role=$3, updated_at=now() WHERE id=$1`, referrer.ID, newCount, newRole)
-		metrics.ObserveNetworkRequest("postgres", "users_update_referrer_with_role", "users", start, err)
-	} else {
-		start = time.Now()
-		_, err = tx.Exec(ctx, `UPDATE users SET referrals_count=$2, updated_at=now() WHERE id=$1`, referrer.ID, newCount)
-		metrics.ObserveNetworkRequest("postgres", "users_update_referrer", "users", start, err)
-	}
+	start = time.Now()
+	_, err = tx.Exec(ctx, `
+INSERT INTO referrals (referred_user_id, referrer_id, status, tg_user_id, locale, is_bot)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+`, user.ID, referrer.ID, string(status), user.TGUserID, user.Locale, user.IsBot)
+	metrics.ObserveNetworkRequest("postgres", "referrals_insert", "referrals", start, err)
 	if err != nil {
 		return domain.ReferralResult{}, err
 	}
 
+	// Подозрительное приглашение не увеличивает счётчик, поэтому не влияет на тариф пригласившего.
+	previousRole := referrer.Role
+	upgraded := false
+	if status == domain.ReferralStatusCounted {
+		newCount := referrer.ReferralsCount + 1
+		newRole := domain.RoleForReferralProgress(referrer.Role, newCount)
+		upgraded = newRole != referrer.Role
+		if upgraded {
+			start = time.Now()
+			_, err = tx.Exec(ctx, `UPDATE users SET referrals_count=$2, role=$3, updated_at=now() WHERE id=$1`, referrer.ID, newCount, newRole)
+			metrics.ObserveNetworkRequest("postgres", "users_update_referrer_with_role", "users", start, err)
+		} else {
+			start = time.Now()
+			_, err = tx.Exec(ctx, `UPDATE users SET referrals_count=$2, updated_at=now() WHERE id=$1`, referrer.ID, newCount)
+			metrics.ObserveNetworkRequest("postgres", "users_update_referrer", "users", start, err)
+		}
+		if err != nil {
+			return domain.ReferralResult{}, err
+		}
+	}
+
 	start = time.Now()
 	err = tx.QueryRow(ctx, `
 SELECT id, tg_user_id, locale, tz, daily_time, created_at, updated_at, role, manual_requests_total, manual_requests_today, manual_requests_date, referral_code, referrals_count, referred_by, first_name, last_name, username, is_bot
@@ -745,6 +1008,7 @@ FROM users WHERE id=$1
 		User:     user,
 		Applied:  true,
 		Referrer: &referrer,
+		Flagged:  status == domain.ReferralStatusFlagged,
 	}
 	if upgraded {
 		result.ReferrerUpgraded = true
@@ -753,119 +1017,874 @@ FROM users WHERE id=$1
 	return result, nil
 }
 
-// UpdateRole обновляет тариф пользователя.
-func (p *Postgres) UpdateRole(userID int64, role domain.UserRole) error {
+// ListFlaggedReferrals возвращает приглашения, ждущие ручной проверки.
+func (p *Postgres) ListFlaggedReferrals(limit int) ([]domain.FlaggedReferral, error) {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
-	_, err := p.pool.Exec(ctx, `UPDATE users SET role=$2, updated_at=now() WHERE id=$1`, userID, role)
-	metrics.ObserveNetworkRequest("postgres", "users_update_role", "users", start, err)
-	return err
-}
-
-func sameDay(a, b time.Time) bool {
-	a = a.UTC()
-	b = b.UTC()
-	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+	rows, err := p.pool.Query(ctx, `
+SELECT r.tg_user_id, ref.tg_user_id, r.is_bot, r.created_at
+FROM referrals r
+JOIN users ref ON ref.id = r.referrer_id
+WHERE r.status = 'flagged'
+ORDER BY r.created_at
+LIMIT $1
+`, limit)
+	metrics.ObserveNetworkRequest("postgres", "referrals_list_flagged", "referrals", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var flagged []domain.FlaggedReferral
+	for rows.Next() {
+		var r domain.FlaggedReferral
+		if err := rows.Scan(&r.ReferredTGUserID, &r.ReferrerTGUserID, &r.IsBot, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, r)
+	}
+	return flagged, rows.Err()
 }
 
-// UpsertChannel сохраняет канал.
-func (p *Postgres) UpsertChannel(meta domain.ChannelMeta) (domain.Channel, error) {
+// ApproveReferral переводит отложенное приглашение в counted и начисляет его пригласившему.
+func (p *Postgres) ApproveReferral(referredTGUserID int64) (domain.ReferralResult, error) {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
-	var ch domain.Channel
 	start := time.Now()
-	err := p.pool.QueryRow(ctx, `
-INSERT INTO channels (tg_channel_id, alias, title, is_allowed)
-VALUES ($1,$2,$3,true)
-ON CONFLICT(alias) DO UPDATE SET tg_channel_id=EXCLUDED.tg_channel_id, title=EXCLUDED.title, is_allowed=true
-RETURNING id, tg_channel_id, alias, title, is_allowed, created_at
-`, meta.ID, meta.Alias, meta.Title).Scan(&ch.ID, &ch.TGChannelID, &ch.Alias, &ch.Title, &ch.IsAllowed, &ch.CreatedAt)
-	metrics.ObserveNetworkRequest("postgres", "channels_upsert", "channels", start, err)
-	return ch, err
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	metrics.ObserveNetworkRequest("postgres", "begin_tx", "referrals", start, err)
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var user, referrer domain.User
+	start = time.Now()
+	err = tx.QueryRow(ctx, `
+SELECT r.referred_user_id, r.tg_user_id, r.referrer_id
+FROM referrals r
+WHERE r.tg_user_id = $1 AND r.status = 'flagged'
+FOR UPDATE
+`, referredTGUserID).Scan(&user.ID, &user.TGUserID, &referrer.ID)
+	metrics.ObserveNetworkRequest("postgres", "referrals_get_flagged", "referrals", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ReferralResult{}, domain.ErrReferralNotFlagged
+	}
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+
+	start = time.Now()
+	err = tx.QueryRow(ctx, `SELECT tg_user_id, role, referrals_count FROM users WHERE id=$1 FOR UPDATE`, referrer.ID).
+		Scan(&referrer.TGUserID, &referrer.Role, &referrer.ReferralsCount)
+	metrics.ObserveNetworkRequest("postgres", "users_get_for_update", "users", start, err)
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+
+	start = time.Now()
+	_, err = tx.Exec(ctx, `UPDATE referrals SET status = 'counted' WHERE referred_user_id = $1`, user.ID)
+	metrics.ObserveNetworkRequest("postgres", "referrals_approve", "referrals", start, err)
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+
+	previousRole := referrer.Role
+	referrer.ReferralsCount++
+	referrer.Role = domain.RoleForReferralProgress(previousRole, referrer.ReferralsCount)
+	start = time.Now()
+	_, err = tx.Exec(ctx, `UPDATE users SET referrals_count=$2, role=$3, updated_at=now() WHERE id=$1`, referrer.ID, referrer.ReferralsCount, referrer.Role)
+	metrics.ObserveNetworkRequest("postgres", "users_update_referrer_with_role", "users", start, err)
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+
+	start = time.Now()
+	err = tx.Commit(ctx)
+	metrics.ObserveNetworkRequest("postgres", "commit", "referrals", start, err)
+	if err != nil {
+		return domain.ReferralResult{}, err
+	}
+	return domain.ReferralResult{
+		User:             user,
+		Applied:          true,
+		Referrer:         &referrer,
+		ReferrerUpgraded: referrer.Role != previousRole,
+		PreviousRole:     previousRole,
+	}, nil
 }
 
-// ListUserChannels возвращает каналы пользователя.
-func (p *Postgres) ListUserChannels(userID int64, limit, offset int) ([]domain.UserChannel, error) {
+// TopReferrers возвращает лидеров по числу приглашений с местами с учётом равенства.
+func (p *Postgres) TopReferrers(limit int) ([]domain.ReferrerStanding, error) {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
 	rows, err := p.pool.Query(ctx, `
-SELECT uc.id, uc.user_id, uc.channel_id, uc.muted, uc.added_at, uc.tags,
-       c.id, c.tg_channel_id, c.alias, c.title, c.is_allowed, c.created_at
-FROM user_channels uc JOIN channels c ON c.id = uc.channel_id
-WHERE uc.user_id=$1
-ORDER BY uc.added_at DESC
-LIMIT $2 OFFSET $3
-`, userID, limit, offset)
-	metrics.ObserveNetworkRequest("postgres", "user_channels_list", "user_channels", start, err)
+SELECT id, RANK() OVER (ORDER BY referrals_count DESC) AS rank, first_name, username, referrals_count
+FROM users WHERE referrals_count > 0
+ORDER BY referrals_count DESC, id
+LIMIT $1
+`, limit)
+	metrics.ObserveNetworkRequest("postgres", "users_top_referrers", "users", start, err)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var channels []domain.UserChannel
+	var standings []domain.ReferrerStanding
 	for rows.Next() {
-		var uc domain.UserChannel
-		if err := rows.Scan(&uc.ID, &uc.UserID, &uc.ChannelID, &uc.Muted, &uc.AddedAt, &uc.Tags,
-			&uc.Channel.ID, &uc.Channel.TGChannelID, &uc.Channel.Alias, &uc.Channel.Title, &uc.Channel.IsAllowed, &uc.Channel.CreatedAt); err != nil {
+		var (
+			s         domain.ReferrerStanding
+			firstName sql.NullString
+			username  sql.NullString
+		)
+		if err := rows.Scan(&s.UserID, &s.Rank, &firstName, &username, &s.ReferralsCount); err != nil {
 			return nil, err
 		}
-		channels = append(channels, uc)
+		s.FirstName = firstName.String
+		s.Username = username.String
+		standings = append(standings, s)
 	}
-	return channels, rows.Err()
+	return standings, rows.Err()
 }
 
-// AttachChannelToUser привязывает канал к пользователю.
-func (p *Postgres) AttachChannelToUser(userID, channelID int64) error {
+// ReferrerRank возвращает место пользователя: 1 + число пользователей с большим числом приглашений.
+func (p *Postgres) ReferrerRank(userID int64) (domain.ReferrerStanding, error) {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
+	var (
+		s         domain.ReferrerStanding
+		firstName sql.NullString
+		username  sql.NullString
+	)
 	start := time.Now()
-	tag, err := p.pool.Exec(ctx, `
-INSERT INTO user_channels (user_id, channel_id)
-VALUES ($1,$2)
-ON CONFLICT (user_id, channel_id) DO NOTHING
-`, userID, channelID)
-	metrics.ObserveNetworkRequest("postgres", "user_channels_attach", "user_channels", start, err)
-	if err == nil && tag.RowsAffected() > 0 {
-		uID := userID
-		chID := channelID
-		_ = p.saveBusinessMetric(ctx, domain.BusinessMetric{
-			Event:     domain.BusinessMetricEventChannelAttached,
-			UserID:    &uID,
-			ChannelID: &chID,
-		})
+	err := p.pool.QueryRow(ctx, `
+SELECT u.id, 1 + (SELECT count(*) FROM users o WHERE o.referrals_count > u.referrals_count), u.first_name, u.username, u.referrals_count
+FROM users u WHERE u.id=$1
+`, userID).Scan(&s.UserID, &s.Rank, &firstName, &username, &s.ReferralsCount)
+	metrics.ObserveNetworkRequest("postgres", "users_referrer_rank", "users", start, err)
+	if err != nil {
+		return domain.ReferrerStanding{}, err
 	}
-	return err
+	s.FirstName = firstName.String
+	s.Username = username.String
+	return s, nil
 }
 
-// DetachChannelFromUser удаляет канал.
-func (p *Postgres) DetachChannelFromUser(userID, channelID int64) error {
+// UpdateRole обновляет тариф пользователя.
+func (p *Postgres) UpdateRole(userID int64, role domain.UserRole) error {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
-	_, err := p.pool.Exec(ctx, `DELETE FROM user_channels WHERE user_id=$1 AND channel_id=$2`, userID, channelID)
-	metrics.ObserveNetworkRequest("postgres", "user_channels_detach", "user_channels", start, err)
+	_, err := p.pool.Exec(ctx, `UPDATE users SET role=$2, updated_at=now() WHERE id=$1`, userID, role)
+	metrics.ObserveNetworkRequest("postgres", "users_update_role", "users", start, err)
 	return err
 }
 
-// SetMuted переключает mute.
-func (p *Postgres) SetMuted(userID, channelID int64, muted bool) error {
+// SetChannelLimitOverride задаёт индивидуальный лимит каналов; nil возвращает лимит тарифа.
+func (p *Postgres) SetChannelLimitOverride(userID int64, limit *int) error {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
-	_, err := p.pool.Exec(ctx, `UPDATE user_channels SET muted=$3 WHERE user_id=$1 AND channel_id=$2`, userID, channelID, muted)
-	metrics.ObserveNetworkRequest("postgres", "user_channels_set_muted", "user_channels", start, err)
+	_, err := p.pool.Exec(ctx, `UPDATE users SET channel_limit_override=$2, updated_at=now() WHERE id=$1`, userID, limit)
+	metrics.ObserveNetworkRequest("postgres", "users_update_channel_limit", "users", start, err)
 	return err
 }
 
-// CountUserChannels считает каналы пользователя.
-func (p *Postgres) CountUserChannels(userID int64) (int, error) {
+// SetCollectComments включает или выключает сбор комментариев обсуждаемых постов.
+func (p *Postgres) SetCollectComments(userID int64, enabled bool) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET collect_comments=$2, updated_at=now() WHERE id=$1`, userID, enabled)
+	metrics.ObserveNetworkRequest("postgres", "users_update_collect_comments", "users", start, err)
+	return err
+}
+
+// SetDeliveryChat привязывает плановые дайджесты к чату; 0 сбрасывает привязку.
+func (p *Postgres) SetDeliveryChat(userID, chatID int64) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET delivery_chat_id=NULLIF($2, 0), updated_at=now() WHERE id=$1`, userID, chatID)
+	metrics.ObserveNetworkRequest("postgres", "users_set_delivery_chat", "users", start, err)
+	return err
+}
+
+// SetDigestOrder задаёт порядок пунктов дайджеста.
+func (p *Postgres) SetDigestOrder(userID int64, order domain.DigestOrder) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET order_by=$2, updated_at=now() WHERE id=$1`, userID, string(order))
+	metrics.ObserveNetworkRequest("postgres", "users_set_digest_order", "users", start, err)
+	return err
+}
+
+// SetLinkPreviews включает или выключает превью ссылки у главного пункта дайджеста.
+func (p *Postgres) SetLinkPreviews(userID int64, enabled bool) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET link_previews=$2, updated_at=now() WHERE id=$1`, userID, enabled)
+	metrics.ObserveNetworkRequest("postgres", "users_update_link_previews", "users", start, err)
+	return err
+}
+
+// SetPaused ставит дайджесты пользователя на паузу или снимает её.
+func (p *Postgres) SetPaused(userID int64, paused bool, until *time.Time) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	if !paused {
+		until = nil
+	}
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET paused=$2, paused_until=$3, updated_at=now() WHERE id=$1`, userID, paused, until)
+	metrics.ObserveNetworkRequest("postgres", "users_update_paused", "users", start, err)
+	return err
+}
+
+// SetFilterAds включает или выключает отсев рекламы в дайджестах пользователя.
+func (p *Postgres) SetFilterAds(userID int64, enabled bool) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET filter_ads=$2, updated_at=now() WHERE id=$1`, userID, enabled)
+	metrics.ObserveNetworkRequest("postgres", "users_update_filter_ads", "users", start, err)
+	return err
+}
+
+// UpdateSettings сохраняет настройки доставки одним запросом, чтобы они менялись атомарно.
+func (p *Postgres) UpdateSettings(userID int64, settings domain.UserSettings) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE users
+SET daily_time=$2, tz=NULLIF($3, ''), digest_lang=NULLIF($4, ''), updated_at=now()
+WHERE id=$1
+`, userID, settings.DailyTime.Format("15:04:05"), strings.TrimSpace(settings.Timezone), settings.DigestLang)
+	metrics.ObserveNetworkRequest("postgres", "users_update_settings", "users", start, err)
+	return err
+}
+
+// SetDigestLang задаёт язык постов в дайджестах пользователя; пустая строка выключает фильтр.
+func (p *Postgres) SetDigestLang(userID int64, lang string) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET digest_lang=NULLIF($2, ''), updated_at=now() WHERE id=$1`, userID, lang)
+	metrics.ObserveNetworkRequest("postgres", "users_update_digest_lang", "users", start, err)
+	return err
+}
+
+// SetDeliveryDisabled отмечает, что Telegram не доставляет пользователю сообщения, или снимает отметку.
+func (p *Postgres) SetDeliveryDisabled(userID int64, disabled bool) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET delivery_disabled=$2, updated_at=now() WHERE id=$1 AND delivery_disabled <> $2`, userID, disabled)
+	metrics.ObserveNetworkRequest("postgres", "users_update_delivery_disabled", "users", start, err)
+	return err
+}
+
+// SetPendingEmail сохраняет адрес, ожидающий подтверждения, и код для него.
+func (p *Postgres) SetPendingEmail(userID int64, email, code string, expiresAt time.Time) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE users SET email_pending=$2, email_code=$3, email_code_expires_at=$4, updated_at=now()
+WHERE id=$1
+`, userID, email, code, expiresAt)
+	metrics.ObserveNetworkRequest("postgres", "users_set_pending_email", "users", start, err)
+	return err
+}
+
+// ConfirmEmail подтверждает ожидающий адрес, если код совпал и не истёк.
+func (p *Postgres) ConfirmEmail(userID int64, code string, now time.Time) (string, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	var email string
+	err := p.pool.QueryRow(ctx, `
+UPDATE users
+SET email=email_pending, email_verified_at=$3, email_pending=NULL, email_code=NULL, email_code_expires_at=NULL, updated_at=now()
+WHERE id=$1 AND email_pending IS NOT NULL AND email_code=$2 AND email_code_expires_at > $3
+RETURNING email
+`, userID, code, now).Scan(&email)
+	metrics.ObserveNetworkRequest("postgres", "users_confirm_email", "users", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", domain.ErrEmailCodeInvalid
+	}
+	return email, err
+}
+
+// SetDeliveryMode задаёт, куда отправлять дайджест.
+func (p *Postgres) SetDeliveryMode(userID int64, mode domain.DeliveryMode) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE users SET delivery_mode=$2, updated_at=now() WHERE id=$1`, userID, string(mode))
+	metrics.ObserveNetworkRequest("postgres", "users_set_delivery_mode", "users", start, err)
+	return err
+}
+
+// ClearEmail удаляет адрес и возвращает доставку в Telegram.
+func (p *Postgres) ClearEmail(userID int64) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE users
+SET email=NULL, email_verified_at=NULL, email_pending=NULL, email_code=NULL, email_code_expires_at=NULL, delivery_mode='telegram', updated_at=now()
+WHERE id=$1
+`, userID)
+	metrics.ObserveNetworkRequest("postgres", "users_clear_email", "users", start, err)
+	return err
+}
+
+// RecordPlanActivation фиксирует оплаченный тариф до его применения.
+func (p *Postgres) RecordPlanActivation(userID, paymentID int64, role domain.UserRole) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO plan_activations (payment_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (payment_id) DO NOTHING
+`, paymentID, userID, role)
+	metrics.ObserveNetworkRequest("postgres", "plan_activations_insert", "plan_activations", start, err)
+	return err
+}
+
+// ActivatePlan в одной транзакции повышает роль пользователя и отмечает активацию выполненной.
+// Тариф ниже текущего не применяется, чтобы повтор старой активации не понизил пользователя.
+func (p *Postgres) ActivatePlan(paymentID int64) (bool, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	metrics.ObserveNetworkRequest("postgres", "begin_tx", "plan_activations", start, err)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		userID      int64
+		role        domain.UserRole
+		activatedAt sql.NullTime
+		currentRole domain.UserRole
+	)
+	start = time.Now()
+	err = tx.QueryRow(ctx, `
+SELECT a.user_id, a.role, a.activated_at, u.role
+FROM plan_activations a
+JOIN users u ON u.id = a.user_id
+WHERE a.payment_id = $1
+FOR UPDATE OF a, u
+`, paymentID).Scan(&userID, &role, &activatedAt, &currentRole)
+	metrics.ObserveNetworkRequest("postgres", "plan_activations_get_for_update", "plan_activations", start, err)
+	if err != nil {
+		return false, err
+	}
+	if activatedAt.Valid {
+		return false, nil
+	}
+
+	if domain.RolePriority(currentRole) < domain.RolePriority(role) {
+		start = time.Now()
+		_, err = tx.Exec(ctx, `UPDATE users SET role=$2, updated_at=now() WHERE id=$1`, userID, role)
+		metrics.ObserveNetworkRequest("postgres", "users_update_role", "users", start, err)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	start = time.Now()
+	_, err = tx.Exec(ctx, `
+UPDATE plan_activations
+SET activated_at = now(), attempts = attempts + 1, last_error = NULL
+WHERE payment_id = $1
+`, paymentID)
+	metrics.ObserveNetworkRequest("postgres", "plan_activations_complete", "plan_activations", start, err)
+	if err != nil {
+		return false, err
+	}
+
+	start = time.Now()
+	err = tx.Commit(ctx)
+	metrics.ObserveNetworkRequest("postgres", "commit", "plan_activations", start, err)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkPlanActivationFailed сохраняет причину неудачной попытки активации.
+func (p *Postgres) MarkPlanActivationFailed(paymentID int64, reason string) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE plan_activations
+SET attempts = attempts + 1, last_error = $2
+WHERE payment_id = $1 AND activated_at IS NULL
+`, paymentID, reason)
+	metrics.ObserveNetworkRequest("postgres", "plan_activations_fail", "plan_activations", start, err)
+	return err
+}
+
+// ListPendingPlanActivations возвращает оплаченные, но ещё не применённые тарифы.
+func (p *Postgres) ListPendingPlanActivations(limit int) ([]domain.PlanActivation, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT a.payment_id, a.user_id, u.tg_user_id, a.role, a.attempts, a.last_error, a.created_at
+FROM plan_activations a
+JOIN users u ON u.id = a.user_id
+WHERE a.activated_at IS NULL
+ORDER BY a.created_at
+LIMIT $1
+`, limit)
+	metrics.ObserveNetworkRequest("postgres", "plan_activations_list_pending", "plan_activations", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.PlanActivation
+	for rows.Next() {
+		var (
+			a         domain.PlanActivation
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&a.PaymentID, &a.UserID, &a.TGUserID, &a.Role, &a.Attempts, &lastError, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.LastError = lastError.String
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// RecordPlanCharge фиксирует покупку тарифа до списания.
+func (p *Postgres) RecordPlanCharge(chargeKey string, userID int64, role domain.UserRole) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO plan_charges (charge_key, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (charge_key) DO NOTHING
+`, chargeKey, userID, role)
+	metrics.ObserveNetworkRequest("postgres", "plan_charges_insert", "plan_charges", start, err)
+	return err
+}
+
+// ResolvePlanCharge удаляет покупку из сверки.
+func (p *Postgres) ResolvePlanCharge(chargeKey string) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `DELETE FROM plan_charges WHERE charge_key=$1`, chargeKey)
+	metrics.ObserveNetworkRequest("postgres", "plan_charges_delete", "plan_charges", start, err)
+	return err
+}
+
+// ListPlanCharges возвращает несверенные покупки тарифа, начатые раньше before, старые — первыми.
+func (p *Postgres) ListPlanCharges(before time.Time, limit int) ([]domain.PlanCharge, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT charge_key, user_id, role, created_at
+FROM plan_charges
+WHERE created_at < $1
+ORDER BY created_at
+LIMIT $2
+`, before, limit)
+	metrics.ObserveNetworkRequest("postgres", "plan_charges_list", "plan_charges", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.PlanCharge
+	for rows.Next() {
+		var c domain.PlanCharge
+		if err := rows.Scan(&c.ChargeKey, &c.UserID, &c.Role, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func sameDay(a, b time.Time) bool {
+	a = a.UTC()
+	b = b.UTC()
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// UpsertChannel сохраняет канал.
+// Канал ищется сначала по tg_channel_id (username мог смениться), затем по алиасу.
+func (p *Postgres) UpsertChannel(meta domain.ChannelMeta) (domain.Channel, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	metrics.ObserveNetworkRequest("postgres", "begin_tx", "channels", start, err)
+	if err != nil {
+		return domain.Channel{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ch domain.Channel
+	if meta.ID != 0 {
+		var existingID int64
+		start = time.Now()
+		err = tx.QueryRow(ctx, `SELECT id FROM channels WHERE tg_channel_id=$1 FOR UPDATE`, meta.ID).Scan(&existingID)
+		metrics.ObserveNetworkRequest("postgres", "channels_get_by_tg_id", "channels", start, err)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			existingID = 0
+		case err != nil:
+			return domain.Channel{}, err
+		}
+
+		if existingID != 0 {
+			var aliasID int64
+			start = time.Now()
+			err = tx.QueryRow(ctx, `SELECT id FROM channels WHERE alias=$1 AND id<>$2`, meta.Alias, existingID).Scan(&aliasID)
+			metrics.ObserveNetworkRequest("postgres", "channels_get_by_alias", "channels", start, err)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return domain.Channel{}, err
+			}
+			if aliasID != 0 {
+				if err := mergeChannelsTx(ctx, tx, existingID, aliasID); err != nil {
+					return domain.Channel{}, err
+				}
+			}
+
+			start = time.Now()
+			err = tx.QueryRow(ctx, `
+UPDATE channels SET alias=$2, title=$3, is_allowed=true
+WHERE id=$1
+RETURNING id, tg_channel_id, alias, title, is_allowed, created_at
+`, existingID, meta.Alias, meta.Title).Scan(&ch.ID, &ch.TGChannelID, &ch.Alias, &ch.Title, &ch.IsAllowed, &ch.CreatedAt)
+			metrics.ObserveNetworkRequest("postgres", "channels_update", "channels", start, err)
+			if err != nil {
+				return domain.Channel{}, err
+			}
+			start = time.Now()
+			err = tx.Commit(ctx)
+			metrics.ObserveNetworkRequest("postgres", "commit", "channels", start, err)
+			return ch, err
+		}
+	}
+
+	start = time.Now()
+	err = tx.QueryRow(ctx, `
+INSERT INTO channels (tg_channel_id, alias, title, is_allowed)
+VALUES ($1,$2,$3,true)
+ON CONFLICT(alias) DO UPDATE SET tg_channel_id=EXCLUDED.tg_channel_id, title=EXCLUDED.title, is_allowed=true
+RETURNING id, tg_channel_id, alias, title, is_allowed, created_at
+`, meta.ID, meta.Alias, meta.Title).Scan(&ch.ID, &ch.TGChannelID, &ch.Alias, &ch.Title, &ch.IsAllowed, &ch.CreatedAt)
+	metrics.ObserveNetworkRequest("postgres", "channels_upsert", "channels", start, err)
+	if err != nil {
+		return domain.Channel{}, err
+	}
+	start = time.Now()
+	err = tx.Commit(ctx)
+	metrics.ObserveNetworkRequest("postgres", "commit", "channels", start, err)
+	return ch, err
+}
+
+// mergeChannelsTx переносит подписки и посты канала dropID в keepID и удаляет dropID.
+func mergeChannelsTx(ctx context.Context, tx pgx.Tx, keepID, dropID int64) error {
+	start := time.Now()
+	_, err := tx.Exec(ctx, `SELECT merge_channels($1, $2)`, keepID, dropID)
+	metrics.ObserveNetworkRequest("postgres", "channels_merge", "channels", start, err)
+	if err != nil {
+		return fmt.Errorf("объединение каналов %d и %d: %w", keepID, dropID, err)
+	}
+	return nil
+}
+
+// ListUserChannels возвращает каналы пользователя.
+func (p *Postgres) ListUserChannels(userID int64, limit, offset int) ([]domain.UserChannel, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT uc.id, uc.user_id, uc.channel_id, uc.muted AND (uc.muted_until IS NULL OR uc.muted_until > now()), uc.muted_until, uc.added_at, uc.tags,
+       c.id, c.tg_channel_id, c.alias, c.title, c.is_allowed, c.created_at, COALESCE(cc.last_msg_id, 0), COALESCE(c.mtproto_pool, '')
+FROM user_channels uc JOIN channels c ON c.id = uc.channel_id
+LEFT JOIN channel_collections cc ON cc.channel_id = c.id
+WHERE uc.user_id=$1
+ORDER BY uc.added_at DESC
+LIMIT $2 OFFSET $3
+`, userID, limit, offset)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_list", "user_channels", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var channels []domain.UserChannel
+	for rows.Next() {
+		var (
+			uc         domain.UserChannel
+			mutedUntil sql.NullTime
+		)
+		if err := rows.Scan(&uc.ID, &uc.UserID, &uc.ChannelID, &uc.Muted, &mutedUntil, &uc.AddedAt, &uc.Tags,
+			&uc.Channel.ID, &uc.Channel.TGChannelID, &uc.Channel.Alias, &uc.Channel.Title, &uc.Channel.IsAllowed, &uc.Channel.CreatedAt, &uc.Channel.LastCollectedMsgID, &uc.Channel.MTProtoPool); err != nil {
+			return nil, err
+		}
+		if uc.Muted && mutedUntil.Valid {
+			until := mutedUntil.Time
+			uc.MutedUntil = &until
+		}
+		channels = append(channels, uc)
+	}
+	return channels, rows.Err()
+}
+
+// AttachChannelToUser привязывает канал к пользователю.
+func (p *Postgres) AttachChannelToUser(userID, channelID int64) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+INSERT INTO user_channels (user_id, channel_id)
+VALUES ($1,$2)
+ON CONFLICT (user_id, channel_id) DO NOTHING
+`, userID, channelID)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_attach", "user_channels", start, err)
+	if err == nil && tag.RowsAffected() > 0 {
+		uID := userID
+		chID := channelID
+		_ = p.saveBusinessMetric(ctx, domain.BusinessMetric{
+			Event:     domain.BusinessMetricEventChannelAttached,
+			UserID:    &uID,
+			ChannelID: &chID,
+		})
+	}
+	return err
+}
+
+// DetachChannelFromUser удаляет канал.
+func (p *Postgres) DetachChannelFromUser(userID, channelID int64) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `DELETE FROM user_channels WHERE user_id=$1 AND channel_id=$2`, userID, channelID)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_detach", "user_channels", start, err)
+	return err
+}
+
+// SetMuted переключает mute.
+func (p *Postgres) SetMuted(userID, channelID int64, muted bool) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE user_channels SET muted=$3, muted_until=NULL WHERE user_id=$1 AND channel_id=$2`, userID, channelID, muted)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_set_muted", "user_channels", start, err)
+	return err
+}
+
+// MuteUntil выключает канал в дайджесте до указанного момента.
+func (p *Postgres) MuteUntil(userID, channelID int64, until time.Time) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `UPDATE user_channels SET muted=true, muted_until=$3 WHERE user_id=$1 AND channel_id=$2`, userID, channelID, until)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_mute_until", "user_channels", start, err)
+	return err
+}
+
+// ClearExpiredMutes снимает истёкшие временные мьюты и возвращает число затронутых каналов.
+func (p *Postgres) ClearExpiredMutes(now time.Time) (int64, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `UPDATE user_channels SET muted=false, muted_until=NULL WHERE muted_until IS NOT NULL AND muted_until <= $1`, now)
+	metrics.ObserveNetworkRequest("postgres", "user_channels_clear_expired_mutes", "user_channels", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeOldPosts удаляет посты, опубликованные раньше olderThan, пачками по batch строк.
+// Посты из дайджестов начиная с keepDigestsSince остаются: на них опираются история и переотправка.
+// Заодно удаляются отметки digest_deliveries старше olderThan; возвращается число удалённых постов.
+func (p *Postgres) PurgeOldPosts(ctx context.Context, olderThan, keepDigestsSince time.Time, batch int) (int64, error) {
+	if batch <= 0 {
+		return 0, fmt.Errorf("некорректный размер пачки: %d", batch)
+	}
+	var total int64
+	for {
+		deleted, err := p.purgePostsBatch(ctx, olderThan, keepDigestsSince, batch)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(batch) {
+			break
+		}
+	}
+	for {
+		deleted, err := p.purgeDeliveriesBatch(ctx, olderThan, batch)
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(batch) {
+			return total, nil
+		}
+	}
+}
+
+// purgeDeliveriesBatch удаляет отметки об отправке старше olderThan: повтор такой давней задачи
+// из очереди уже невозможен.
+func (p *Postgres) purgeDeliveriesBatch(ctx context.Context, olderThan time.Time, batch int) (int64, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `DELETE FROM digest_deliveries WHERE key IN (
+		SELECT key FROM digest_deliveries WHERE sent_at < $1 LIMIT $2
+	)`, olderThan, batch)
+	metrics.ObserveNetworkRequest("postgres", "digest_deliveries_purge_old", "digest_deliveries", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (p *Postgres) purgePostsBatch(ctx context.Context, olderThan, keepDigestsSince time.Time, batch int) (int64, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `DELETE FROM posts WHERE id IN (
+		SELECT p.id FROM posts p
+		WHERE p.published_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_digest_items i
+			JOIN user_digests d ON d.id=i.digest_id
+			WHERE i.post_id=p.id AND d.date >= $2
+		  )
+		LIMIT $3
+	)`, olderThan, keepDigestsSince, batch)
+	metrics.ObserveNetworkRequest("postgres", "posts_purge_old", "posts", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetChannel возвращает канал по идентификатору.
+func (p *Postgres) GetChannel(channelID int64) (domain.Channel, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	var ch domain.Channel
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `
+SELECT id, tg_channel_id, alias, title, is_allowed, created_at, COALESCE(mtproto_pool, '')
+FROM channels WHERE id=$1
+`, channelID).Scan(&ch.ID, &ch.TGChannelID, &ch.Alias, &ch.Title, &ch.IsAllowed, &ch.CreatedAt, &ch.MTProtoPool)
+	metrics.ObserveNetworkRequest("postgres", "channels_get", "channels", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Channel{}, domain.ErrChannelNotFound
+	}
+	return ch, err
+}
+
+// SearchChannels ищет разрешённые каналы по подстроке; популярные каналы идут первыми.
+func (p *Postgres) SearchChannels(query string, limit int) ([]domain.Channel, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || limit <= 0 {
+		return nil, nil
+	}
+	pattern := "%" + escapeLike(query) + "%"
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT c.id, c.tg_channel_id, c.alias, c.title, c.is_allowed, c.created_at
+FROM channels c
+LEFT JOIN user_channels uc ON uc.channel_id = c.id
+WHERE c.is_allowed AND (c.title ILIKE $1 OR c.alias ILIKE $1)
+GROUP BY c.id
+ORDER BY count(uc.id) DESC, c.title
+LIMIT $2
+`, pattern, limit)
+	metrics.ObserveNetworkRequest("postgres", "channels_search", "channels", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var channels []domain.Channel
+	for rows.Next() {
+		var ch domain.Channel
+		if err := rows.Scan(&ch.ID, &ch.TGChannelID, &ch.Alias, &ch.Title, &ch.IsAllowed, &ch.CreatedAt); err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+	return channels, rows.Err()
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// CountUserChannels считает каналы пользователя.
+func (p *Postgres) CountUserChannels(userID int64) (int, error) {
 	var count int
 	ctx, cancel := p.connCtx()
 	defer cancel()
@@ -897,11 +1916,26 @@ func (p *Postgres) SavePosts(channelID int64, posts []domain.Post) error {
 
 	batch := &pgx.Batch{}
 	for _, post := range posts {
+		fullText := post.FullText
+		if fullText == "" {
+			fullText = post.Text
+		}
+		// text_full хранится, только если пост не поместился в text_trunc.
 		batch.Queue(`
-INSERT INTO posts (channel_id, tg_msg_id, published_at, url, text_trunc, raw_meta_json, hash)
-VALUES ($1,$2,$3,$4,$5,$6,$7)
-ON CONFLICT (channel_id, tg_msg_id) DO UPDATE SET text_trunc=EXCLUDED.text_trunc, raw_meta_json=EXCLUDED.raw_meta_json, hash=EXCLUDED.hash
-`, channelID, post.TGMsgID, post.PublishedAt, post.URL, post.Text, post.RawMetaJSON, post.Hash)
+INSERT INTO posts (channel_id, tg_msg_id, published_at, url, text_trunc, text_full, raw_meta_json, hash)
+VALUES ($1,$2,$3,$4,$5,NULLIF($6,$5),$7,$8)
+ON CONFLICT (channel_id, tg_msg_id) DO UPDATE SET text_trunc=EXCLUDED.text_trunc, text_full=EXCLUDED.text_full,
+    raw_meta_json=CASE
+        WHEN posts.hash = EXCLUDED.hash AND posts.raw_meta_json ? 'ad_filter'
+            THEN COALESCE(EXCLUDED.raw_meta_json, '{}'::jsonb) || jsonb_build_object('ad_filter', posts.raw_meta_json->'ad_filter')
+        ELSE EXCLUDED.raw_meta_json
+    END || CASE
+        WHEN posts.raw_meta_json ? 'comments' AND NOT COALESCE(EXCLUDED.raw_meta_json ? 'comments', false)
+            THEN jsonb_build_object('comments', posts.raw_meta_json->'comments')
+        ELSE '{}'::jsonb
+    END,
+    hash=EXCLUDED.hash
+`, channelID, post.TGMsgID, post.PublishedAt, post.URL, domain.TruncatePostText(post.Text), fullText, post.RawMetaJSON, post.Hash)
 	}
 	start := time.Now()
 	br := p.pool.SendBatch(ctx, batch)
@@ -918,8 +1952,13 @@ ON CONFLICT (channel_id, tg_msg_id) DO UPDATE SET text_trunc=EXCLUDED.text_trunc
 	return nil
 }
 
-// ListRecentPosts возвращает посты.
+// ListRecentPosts возвращает посты, опубликованные начиная с since.
 func (p *Postgres) ListRecentPosts(channelIDs []int64, since time.Time) ([]domain.Post, error) {
+	return p.ListRecentPostsBetween(channelIDs, since, time.Now().UTC())
+}
+
+// ListRecentPostsBetween возвращает посты, опубликованные в окне [since, until].
+func (p *Postgres) ListRecentPostsBetween(channelIDs []int64, since, until time.Time) ([]domain.Post, error) {
 	if len(channelIDs) == 0 {
 		return nil, nil
 	}
@@ -929,9 +1968,9 @@ func (p *Postgres) ListRecentPosts(channelIDs []int64, since time.Time) ([]domai
 	start := time.Now()
 	rows, err := p.pool.Query(ctx, `
 SELECT id, channel_id, tg_msg_id, published_at, url, text_trunc, raw_meta_json, hash, created_at
-FROM posts WHERE channel_id = ANY($1) AND published_at >= $2
+FROM posts WHERE channel_id = ANY($1) AND published_at >= $2 AND published_at <= $3
 ORDER BY published_at DESC
-`, channelIDs, since)
+`, channelIDs, since, until)
 	metrics.ObserveNetworkRequest("postgres", "posts_list_recent", "posts", start, err)
 	if err != nil {
 		return nil, err
@@ -948,6 +1987,116 @@ ORDER BY published_at DESC
 	return posts, rows.Err()
 }
 
+// SetPostAdVerdict сохраняет решение фильтра рекламы в raw_meta_json поста.
+func (p *Postgres) SetPostAdVerdict(postID int64, verdict domain.AdVerdict) error {
+	payload, err := json.Marshal(verdict)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err = p.pool.Exec(ctx, `
+UPDATE posts SET raw_meta_json = COALESCE(raw_meta_json, '{}'::jsonb) || jsonb_build_object('ad_filter', $2::jsonb)
+WHERE id=$1
+`, postID, string(payload))
+	metrics.ObserveNetworkRequest("postgres", "posts_update_ad_verdict", "posts", start, err)
+	return err
+}
+
+// SetPostLang сохраняет определённый язык поста в raw_meta_json.
+func (p *Postgres) SetPostLang(postID int64, lang string) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE posts SET raw_meta_json = COALESCE(raw_meta_json, '{}'::jsonb) || jsonb_build_object('lang', $2::text)
+WHERE id=$1
+`, postID, lang)
+	metrics.ObserveNetworkRequest("postgres", "posts_update_lang", "posts", start, err)
+	return err
+}
+
+// GetPost возвращает пост из каналов пользователя вместе с полным текстом.
+func (p *Postgres) GetPost(postID, userID int64) (domain.Post, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	var (
+		post     domain.Post
+		fullText sql.NullString
+	)
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `
+SELECT p.id, p.channel_id, p.tg_msg_id, p.published_at, p.url, p.text_trunc, p.text_full, p.raw_meta_json, p.hash, p.created_at
+FROM posts p
+JOIN user_channels uc ON uc.channel_id = p.channel_id AND uc.user_id = $2
+WHERE p.id=$1
+`, postID, userID).Scan(&post.ID, &post.ChannelID, &post.TGMsgID, &post.PublishedAt, &post.URL, &post.Text, &fullText, &post.RawMetaJSON, &post.Hash, &post.CreatedAt)
+	metrics.ObserveNetworkRequest("postgres", "posts_get", "posts", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Post{}, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return domain.Post{}, err
+	}
+	post.FullText = post.Text
+	if fullText.Valid && fullText.String != "" {
+		post.FullText = fullText.String
+	}
+	return post, nil
+}
+
+// MarkChannelCollected фиксирует время успешного сбора постов канала и сдвигает курсор сообщений.
+func (p *Postgres) MarkChannelCollected(channelID int64, collectedAt time.Time, lastMsgID int64) error {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO channel_collections (channel_id, collected_at, last_msg_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (channel_id) DO UPDATE SET collected_at=EXCLUDED.collected_at,
+    last_msg_id=GREATEST(channel_collections.last_msg_id, EXCLUDED.last_msg_id),
+    updated_at=now()
+`, channelID, collectedAt.UTC(), lastMsgID)
+	metrics.ObserveNetworkRequest("postgres", "channel_collections_upsert", "channel_collections", start, err)
+	return err
+}
+
+// ListChannelCollections возвращает время последнего сбора для каналов.
+func (p *Postgres) ListChannelCollections(channelIDs []int64) (map[int64]time.Time, error) {
+	result := make(map[int64]time.Time, len(channelIDs))
+	if len(channelIDs) == 0 {
+		return result, nil
+	}
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT channel_id, collected_at FROM channel_collections WHERE channel_id = ANY($1)
+`, channelIDs)
+	metrics.ObserveNetworkRequest("postgres", "channel_collections_list", "channel_collections", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			channelID   int64
+			collectedAt time.Time
+		)
+		if err := rows.Scan(&channelID, &collectedAt); err != nil {
+			return nil, err
+		}
+		result[channelID] = collectedAt
+	}
+	return result, rows.Err()
+}
+
 // SaveSummary сохраняет суммаризацию.
 func (p *Postgres) SaveSummary(postID int64, summary domain.Summary) (int64, error) {
 	ctx, cancel := p.connCtx()
@@ -960,10 +2109,11 @@ func (p *Postgres) SaveSummary(postID int64, summary domain.Summary) (int64, err
 	}
 	start := time.Now()
 	err = p.pool.QueryRow(ctx, `
-        INSERT INTO post_summaries (post_id, headline, bullets_json, score)
-        VALUES ($1,$2,$3,$4)
+        INSERT INTO post_summaries (post_id, headline, bullets_json, score, topic, topic_summary)
+        VALUES ($1,$2,$3,$4,$5,$6)
+        ON CONFLICT (post_id) DO UPDATE SET headline = EXCLUDED.headline, bullets_json = EXCLUDED.bullets_json, score = EXCLUDED.score, topic = EXCLUDED.topic, topic_summary = EXCLUDED.topic_summary
         RETURNING id
-    `, postID, summary.Headline, bullets, summary.Score).Scan(&id)
+    `, postID, summary.Headline, bullets, summary.Score, summary.Topic, summary.TopicSummary).Scan(&id)
 	metrics.ObserveNetworkRequest("postgres", "post_summaries_insert", "post_summaries", start, err)
 	return id, err
 }
@@ -980,25 +2130,52 @@ func (p *Postgres) CreateDigest(d domain.Digest) (domain.Digest, error) {
 		return domain.Digest{}, err
 	}
 	defer tx.Rollback(ctx)
+	theses, err := json.Marshal(d.Theses)
+	if err != nil {
+		return domain.Digest{}, err
+	}
 	var digestID int64
 	start = time.Now()
 	err = tx.QueryRow(ctx, `
-INSERT INTO user_digests (user_id, date, items_count)
-VALUES ($1,$2,$3)
-ON CONFLICT (user_id, date) DO UPDATE SET items_count = EXCLUDED.items_count
+INSERT INTO user_digests (user_id, date, scope, items_count, overview, theses_json)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT (user_id, date, scope) DO UPDATE SET items_count = EXCLUDED.items_count, overview = EXCLUDED.overview, theses_json = EXCLUDED.theses_json
 RETURNING id
-`, d.UserID, d.Date, len(d.Items)).Scan(&digestID)
+`, d.UserID, d.Date, d.Scope, len(d.Items), d.Overview, theses).Scan(&digestID)
 	metrics.ObserveNetworkRequest("postgres", "user_digests_upsert", "user_digests", start, err)
 	if err != nil {
 		return domain.Digest{}, err
 	}
+	// Повторная сборка за тот же день заменяет элементы, а не дописывает их.
+	start = time.Now()
+	_, err = tx.Exec(ctx, `DELETE FROM user_digest_items WHERE digest_id=$1`, digestID)
+	metrics.ObserveNetworkRequest("postgres", "user_digest_items_delete", "user_digest_items", start, err)
+	if err != nil {
+		return domain.Digest{}, err
+	}
 	for _, item := range d.Items {
+		bullets, err := json.Marshal(item.Summary.Bullets)
+		if err != nil {
+			return domain.Digest{}, err
+		}
+		var summaryID int64
+		start = time.Now()
+		err = tx.QueryRow(ctx, `
+INSERT INTO post_summaries (post_id, headline, bullets_json, score, topic, topic_summary)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT (post_id) DO UPDATE SET headline = EXCLUDED.headline, bullets_json = EXCLUDED.bullets_json, score = EXCLUDED.score, topic = EXCLUDED.topic, topic_summary = EXCLUDED.topic_summary
+RETURNING id
+`, item.Post.ID, item.Summary.Headline, bullets, item.Summary.Score, item.Summary.Topic, item.Summary.TopicSummary).Scan(&summaryID)
+		metrics.ObserveNetworkRequest("postgres", "post_summaries_insert", "post_summaries", start, err)
+		if err != nil {
+			return domain.Digest{}, err
+		}
 		start = time.Now()
 		_, err = tx.Exec(ctx, `
-INSERT INTO user_digest_items (digest_id, post_id, rank)
-VALUES ($1,$2,$3)
+INSERT INTO user_digest_items (digest_id, post_id, summary_id, rank)
+VALUES ($1,$2,$3,$4)
 ON CONFLICT DO NOTHING
-`, digestID, item.Post.ID, item.Rank)
+`, digestID, item.Post.ID, summaryID, item.Rank)
 		metrics.ObserveNetworkRequest("postgres", "user_digest_items_insert", "user_digest_items", start, err)
 		if err != nil {
 			return domain.Digest{}, err
@@ -1031,12 +2208,12 @@ ON CONFLICT DO NOTHING
 }
 
 // MarkDelivered помечает доставку.
-func (p *Postgres) MarkDelivered(userID int64, date time.Time) error {
+func (p *Postgres) MarkDelivered(digestID int64) error {
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
-	_, err := p.pool.Exec(ctx, `UPDATE user_digests SET delivered_at=now() WHERE user_id=$1 AND date=$2`, userID, date)
+	_, err := p.pool.Exec(ctx, `UPDATE user_digests SET delivered_at=now() WHERE id=$1`, digestID)
 	metrics.ObserveNetworkRequest("postgres", "user_digests_mark_delivered", "user_digests", start, err)
 	return err
 }
@@ -1084,14 +2261,14 @@ WHERE job_id = $1
 	return err
 }
 
-// WasDelivered проверяет наличие доставки.
+// WasDelivered проверяет доставку ежедневного дайджеста.
 func (p *Postgres) WasDelivered(userID int64, date time.Time) (bool, error) {
 	var exists bool
 	ctx, cancel := p.connCtx()
 	defer cancel()
 
 	start := time.Now()
-	err := p.pool.QueryRow(ctx, `SELECT delivered_at IS NOT NULL FROM user_digests WHERE user_id=$1 AND date=$2`, userID, date).Scan(&exists)
+	err := p.pool.QueryRow(ctx, `SELECT delivered_at IS NOT NULL FROM user_digests WHERE user_id=$1 AND date=$2 AND scope=''`, userID, date).Scan(&exists)
 	metrics.ObserveNetworkRequest("postgres", "user_digests_was_delivered", "user_digests", start, err)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return false, nil
@@ -1106,9 +2283,9 @@ func (p *Postgres) ListDigestHistory(userID int64, fromDate time.Time) ([]domain
 
 	start := time.Now()
 	rows, err := p.pool.Query(ctx, `
-        SELECT id, date, delivered_at
+        SELECT id, date, scope, delivered_at
         FROM user_digests WHERE user_id=$1 AND date >= $2
-        ORDER BY date DESC
+        ORDER BY date DESC, id DESC
     `, userID, fromDate)
 	metrics.ObserveNetworkRequest("postgres", "user_digests_list_history", "user_digests", start, err)
 	if err != nil {
@@ -1119,7 +2296,7 @@ func (p *Postgres) ListDigestHistory(userID int64, fromDate time.Time) ([]domain
 	for rows.Next() {
 		var d domain.Digest
 		var delivered sql.NullTime
-		if err := rows.Scan(&d.ID, &d.Date, &delivered); err != nil {
+		if err := rows.Scan(&d.ID, &d.Date, &d.Scope, &delivered); err != nil {
 			return nil, err
 		}
 		if delivered.Valid {
@@ -1132,6 +2309,181 @@ func (p *Postgres) ListDigestHistory(userID int64, fromDate time.Time) ([]domain
 	return digests, rows.Err()
 }
 
+// DigestChannelBreakdown считает пункты дайджеста по каналам, начиная с самых активных.
+func (p *Postgres) DigestChannelBreakdown(digestID int64) ([]domain.DigestChannelShare, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+WITH counts AS (
+    SELECT p.channel_id, COUNT(*) AS items
+    FROM user_digest_items i
+    JOIN posts p ON p.id = i.post_id
+    WHERE i.digest_id = $1
+    GROUP BY p.channel_id
+)
+SELECT c.id, c.tg_channel_id, c.alias, c.title, COALESCE(counts.items, 0),
+       COALESCE(uc.muted AND (uc.muted_until IS NULL OR uc.muted_until > now()), false)
+FROM channels c
+LEFT JOIN counts ON counts.channel_id = c.id
+LEFT JOIN user_channels uc ON uc.channel_id = c.id
+    AND uc.user_id = (SELECT user_id FROM user_digests WHERE id = $1)
+WHERE uc.id IS NOT NULL OR counts.items IS NOT NULL
+ORDER BY COALESCE(counts.items, 0) DESC, c.alias
+`, digestID)
+	metrics.ObserveNetworkRequest("postgres", "user_digest_items_breakdown", "user_digest_items", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var shares []domain.DigestChannelShare
+	for rows.Next() {
+		var share domain.DigestChannelShare
+		if err := rows.Scan(&share.Channel.ID, &share.Channel.TGChannelID, &share.Channel.Alias, &share.Channel.Title, &share.Items, &share.Muted); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// SearchUserDigestPosts ищет по полному тексту постов доставленных дайджестов с русской морфологией.
+// Пост, попавший в несколько дайджестов, возвращается один раз с датой последнего.
+func (p *Postgres) SearchUserDigestPosts(ctx context.Context, userID int64, query string, days, limit, offset int) ([]domain.DigestSearchHit, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	from := time.Now().UTC().AddDate(0, 0, -days)
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT id, channel_id, published_at, url, text_trunc, headline, channel, date FROM (
+    SELECT DISTINCT ON (p.id) p.id, p.channel_id, p.published_at, COALESCE(p.url, '') AS url,
+           COALESCE(p.text_trunc, '') AS text_trunc, COALESCE(s.headline, '') AS headline,
+           COALESCE(NULLIF(c.title, ''), '@' || c.alias) AS channel, d.date
+    FROM user_digests d
+    JOIN user_digest_items i ON i.digest_id = d.id
+    JOIN posts p ON p.id = i.post_id
+    JOIN channels c ON c.id = p.channel_id
+    LEFT JOIN post_summaries s ON s.id = i.summary_id
+    WHERE d.user_id = $1 AND d.delivered_at IS NOT NULL AND d.date >= $2
+      AND to_tsvector('russian', COALESCE(p.text_full, p.text_trunc, '')) @@ plainto_tsquery('russian', $3)
+    ORDER BY p.id, d.date DESC
+) found
+ORDER BY date DESC, published_at DESC, id DESC
+LIMIT $4 OFFSET $5
+`, userID, from, query, limit, offset)
+	metrics.ObserveNetworkRequest("postgres", "user_digest_items_search", "posts", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []domain.DigestSearchHit
+	for rows.Next() {
+		var hit domain.DigestSearchHit
+		if err := rows.Scan(&hit.Post.ID, &hit.Post.ChannelID, &hit.Post.PublishedAt, &hit.Post.URL, &hit.Post.Text, &hit.Headline, &hit.Channel, &hit.Date); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// SnoozeDigest атомарно увеличивает счётчик откладываний, пока он меньше maxSnoozes.
+func (p *Postgres) SnoozeDigest(digestID, userID int64, maxSnoozes int) (bool, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+UPDATE user_digests SET snooze_count = snooze_count + 1
+WHERE id=$1 AND user_id=$2 AND snooze_count < $3
+`, digestID, userID, maxSnoozes)
+	metrics.ObserveNetworkRequest("postgres", "user_digests_snooze", "user_digests", start, err)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetDigestWithItems загружает сохранённый дайджест пользователя вместе с постами и суммаризациями.
+func (p *Postgres) GetDigestWithItems(digestID, userID int64) (domain.Digest, error) {
+	ctx, cancel := p.connCtx()
+	defer cancel()
+
+	var (
+		d         domain.Digest
+		overview  sql.NullString
+		theses    []byte
+		delivered sql.NullTime
+	)
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `
+SELECT id, user_id, date, scope, overview, theses_json, delivered_at, snooze_count
+FROM user_digests WHERE id=$1 AND user_id=$2
+`, digestID, userID).Scan(&d.ID, &d.UserID, &d.Date, &d.Scope, &overview, &theses, &delivered, &d.SnoozeCount)
+	metrics.ObserveNetworkRequest("postgres", "user_digests_get", "user_digests", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Digest{}, domain.ErrDigestNotFound
+	}
+	if err != nil {
+		return domain.Digest{}, err
+	}
+	if overview.Valid {
+		d.Overview = overview.String
+	}
+	if len(theses) > 0 {
+		if err := json.Unmarshal(theses, &d.Theses); err != nil {
+			return domain.Digest{}, fmt.Errorf("распаковка тезисов: %w", err)
+		}
+	}
+	if delivered.Valid {
+		t := delivered.Time
+		d.DeliveredAt = &t
+	}
+
+	start = time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT p.id, p.channel_id, p.tg_msg_id, p.published_at, p.url, p.text_trunc, p.raw_meta_json, p.hash, p.created_at,
+       COALESCE(i.rank, 0), s.headline, s.bullets_json, s.score, s.topic, s.topic_summary
+FROM user_digest_items i
+JOIN posts p ON p.id = i.post_id
+LEFT JOIN post_summaries s ON s.id = i.summary_id
+WHERE i.digest_id = $1
+ORDER BY i.rank, i.id
+`, d.ID)
+	metrics.ObserveNetworkRequest("postgres", "user_digest_items_list", "user_digest_items", start, err)
+	if err != nil {
+		return domain.Digest{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			item         domain.DigestItem
+			headline     sql.NullString
+			bullets      []byte
+			score        sql.NullFloat64
+			topic        sql.NullString
+			topicSummary sql.NullString
+		)
+		if err := rows.Scan(&item.Post.ID, &item.Post.ChannelID, &item.Post.TGMsgID, &item.Post.PublishedAt, &item.Post.URL, &item.Post.Text, &item.Post.RawMetaJSON, &item.Post.Hash, &item.Post.CreatedAt,
+			&item.Rank, &headline, &bullets, &score, &topic, &topicSummary); err != nil {
+			return domain.Digest{}, err
+		}
+		item.Summary.Headline = headline.String
+		item.Summary.Score = score.Float64
+		item.Summary.Topic = topic.String
+		item.Summary.TopicSummary = topicSummary.String
+		if len(bullets) > 0 {
+			if err := json.Unmarshal(bullets, &item.Summary.Bullets); err != nil {
+				return domain.Digest{}, fmt.Errorf("распаковка пунктов: %w", err)
+			}
+		}
+		d.Items = append(d.Items, item)
+	}
+	return d, rows.Err()
+}
+
 // LoadMTProtoSession загружает сохранённую MTProto-сессию.
 func (p *Postgres) LoadMTProtoSession(ctx context.Context, name string) ([]byte, error) {
 	ctx, cancel := p.connCtxWithParent(ctx)
@@ -1190,10 +2542,11 @@ func (p *Postgres) ListMTProtoAccounts(ctx context.Context, pool string) ([]doma
 
 	start := time.Now()
 	rows, err := p.pool.Query(ctx, `
-SELECT name, pool, api_id, api_hash, phone, username, raw_json
-FROM mtproto_accounts
-WHERE pool = $1
-ORDER BY name
+SELECT a.name, a.pool, a.api_id, a.api_hash, a.phone, a.username, a.raw_json, a.enabled, a.updated_at,
+       EXISTS (SELECT 1 FROM mtproto_sessions s WHERE s.name = a.name AND s.data IS NOT NULL)
+FROM mtproto_accounts a
+WHERE a.pool = $1
+ORDER BY a.name
 `, pool)
 	metrics.ObserveNetworkRequest("postgres", "mtproto_accounts_list", "mtproto_accounts", start, err)
 	if err != nil {
@@ -1209,7 +2562,7 @@ ORDER BY name
 			username sql.NullString
 			rawJSON  []byte
 		)
-		if scanErr := rows.Scan(&account.Name, &account.Pool, &account.APIID, &account.APIHash, &phone, &username, &rawJSON); scanErr != nil {
+		if scanErr := rows.Scan(&account.Name, &account.Pool, &account.APIID, &account.APIHash, &phone, &username, &rawJSON, &account.Enabled, &account.UpdatedAt, &account.HasSession); scanErr != nil {
 			return nil, scanErr
 		}
 		if phone.Valid {
@@ -1277,3 +2630,363 @@ SET api_id = EXCLUDED.api_id,
 	metrics.ObserveNetworkRequest("postgres", "mtproto_accounts_upsert", "mtproto_accounts", start, err)
 	return err
 }
+
+// SetMTProtoAccountEnabled включает или отключает аккаунт в пуле.
+func (p *Postgres) SetMTProtoAccountEnabled(ctx context.Context, pool, name string, enabled bool) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	if pool == "" {
+		pool = "default"
+	}
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+UPDATE mtproto_accounts SET enabled = $3, updated_at = now()
+WHERE pool = $1 AND name = $2
+`, pool, name, enabled)
+	metrics.ObserveNetworkRequest("postgres", "mtproto_accounts_set_enabled", "mtproto_accounts", start, err)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMTProtoAccountNotFound
+	}
+	return nil
+}
+
+// ListMTProtoPools возвращает пулы аккаунтов со счётчиками и числом закреплённых каналов.
+// Пул по умолчанию попадает в список всегда, даже если за ним не закреплено ни одного канала явно.
+func (p *Postgres) ListMTProtoPools(ctx context.Context) ([]domain.MTProtoPoolStats, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+WITH pools AS (
+    SELECT pool FROM mtproto_accounts
+    UNION
+    SELECT mtproto_pool FROM channels WHERE mtproto_pool IS NOT NULL
+    UNION
+    SELECT 'default'
+)
+SELECT p.pool,
+       (SELECT count(*) FROM mtproto_accounts a WHERE a.pool = p.pool),
+       (SELECT count(*) FROM mtproto_accounts a WHERE a.pool = p.pool AND a.enabled),
+       (SELECT count(*) FROM mtproto_accounts a
+         WHERE a.pool = p.pool AND EXISTS (SELECT 1 FROM mtproto_sessions s WHERE s.name = a.name AND s.data IS NOT NULL)),
+       (SELECT count(*) FROM channels c WHERE c.mtproto_pool = p.pool)
+FROM pools p
+ORDER BY p.pool
+`)
+	metrics.ObserveNetworkRequest("postgres", "mtproto_pools_list", "mtproto_accounts", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pools []domain.MTProtoPoolStats
+	for rows.Next() {
+		var stats domain.MTProtoPoolStats
+		if err := rows.Scan(&stats.Name, &stats.Accounts, &stats.Enabled, &stats.WithSession, &stats.Channels); err != nil {
+			return nil, err
+		}
+		pools = append(pools, stats)
+	}
+	return pools, rows.Err()
+}
+
+// SetChannelMTProtoPool закрепляет канал за пулом MTProto-аккаунтов.
+func (p *Postgres) SetChannelMTProtoPool(ctx context.Context, alias, pool string) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+UPDATE channels SET mtproto_pool = NULLIF($2, '')
+WHERE alias = $1
+`, alias, pool)
+	metrics.ObserveNetworkRequest("postgres", "channels_set_mtproto_pool", "channels", start, err)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrChannelNotFound
+	}
+	return nil
+}
+
+// AddUsage прибавляет расход LLM к месячному счётчику пользователя.
+func (p *Postgres) AddUsage(ctx context.Context, userID int64, month time.Time, usage domain.LLMUsage) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO user_usage (user_id, month, prompt_tokens, completion_tokens, cost_usd, updated_at)
+VALUES ($1, $2, $3, $4, $5, now())
+ON CONFLICT (user_id, month) DO UPDATE
+SET prompt_tokens = user_usage.prompt_tokens + EXCLUDED.prompt_tokens,
+    completion_tokens = user_usage.completion_tokens + EXCLUDED.completion_tokens,
+    cost_usd = user_usage.cost_usd + EXCLUDED.cost_usd,
+    updated_at = now()
+`, userID, domain.UsageMonth(month), usage.PromptTokens, usage.CompletionTokens, usage.CostUSD)
+	metrics.ObserveNetworkRequest("postgres", "user_usage_add", "user_usage", start, err)
+	return err
+}
+
+// GetUsage возвращает расход LLM пользователя за месяц.
+func (p *Postgres) GetUsage(ctx context.Context, userID int64, month time.Time) (domain.UserUsage, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	usage := domain.UserUsage{UserID: userID, Month: domain.UsageMonth(month)}
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `
+SELECT prompt_tokens, completion_tokens, cost_usd::float8, cap_notified_at
+FROM user_usage WHERE user_id = $1 AND month = $2
+`, userID, usage.Month).Scan(&usage.PromptTokens, &usage.CompletionTokens, &usage.CostUSD, &usage.CapNotifiedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	metrics.ObserveNetworkRequest("postgres", "user_usage_get", "user_usage", start, err)
+	if err != nil {
+		return domain.UserUsage{}, err
+	}
+	return usage, nil
+}
+
+// MarkUsageCapNotified отмечает предупреждение о лимите один раз за месяц.
+func (p *Postgres) MarkUsageCapNotified(ctx context.Context, userID int64, month time.Time) (bool, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+INSERT INTO user_usage (user_id, month, cap_notified_at, updated_at)
+VALUES ($1, $2, now(), now())
+ON CONFLICT (user_id, month) DO UPDATE
+SET cap_notified_at = now(), updated_at = now()
+WHERE user_usage.cap_notified_at IS NULL
+`, userID, domain.UsageMonth(month))
+	metrics.ObserveNetworkRequest("postgres", "user_usage_mark_notified", "user_usage", start, err)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// WasDigestSent проверяет отметку об отправке дайджеста.
+func (p *Postgres) WasDigestSent(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	var sent bool
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM digest_deliveries WHERE key = $1)`, key).Scan(&sent)
+	metrics.ObserveNetworkRequest("postgres", "digest_deliveries_check", "digest_deliveries", start, err)
+	return sent, err
+}
+
+// MarkDigestSent записывает отметку об отправке дайджеста; повторная отметка ничего не меняет.
+func (p *Postgres) MarkDigestSent(ctx context.Context, userID int64, key string) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO digest_deliveries (key, user_id) VALUES ($1, $2)
+ON CONFLICT (key) DO NOTHING
+`, key, userID)
+	metrics.ObserveNetworkRequest("postgres", "digest_deliveries_mark", "digest_deliveries", start, err)
+	return err
+}
+
+// CountRecentPostsByChannel считает сохранённые посты по каждому каналу пользователя.
+func (p *Postgres) CountRecentPostsByChannel(ctx context.Context, userID int64, since time.Time) ([]domain.ChannelPostCount, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `
+SELECT c.id, c.alias, COALESCE(c.title, ''), COUNT(p.id), COUNT(p.id) FILTER (WHERE p.published_at >= $2)
+FROM user_channels uc
+JOIN channels c ON c.id = uc.channel_id
+LEFT JOIN posts p ON p.channel_id = c.id
+WHERE uc.user_id = $1
+GROUP BY c.id, c.alias, c.title
+ORDER BY COUNT(p.id) DESC, c.alias
+`, userID, since)
+	if err != nil {
+		metrics.ObserveNetworkRequest("postgres", "posts_count_by_channel", "posts", start, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []domain.ChannelPostCount
+	for rows.Next() {
+		var c domain.ChannelPostCount
+		if err := rows.Scan(&c.ChannelID, &c.Alias, &c.Title, &c.Total, &c.Recent); err != nil {
+			metrics.ObserveNetworkRequest("postgres", "posts_count_by_channel", "posts", start, err)
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	err = rows.Err()
+	metrics.ObserveNetworkRequest("postgres", "posts_count_by_channel", "posts", start, err)
+	return counts, err
+}
+
+// ClearUserPostCache удаляет старые посты каналов, на которые подписан только этот пользователь.
+// Сводки и пункты его собственных дайджестов удаляются каскадом.
+func (p *Postgres) ClearUserPostCache(ctx context.Context, userID int64, before time.Time) (int64, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+DELETE FROM posts p
+USING user_channels uc
+WHERE uc.user_id = $1
+  AND p.channel_id = uc.channel_id
+  AND p.published_at < $2
+  AND NOT EXISTS (
+      SELECT 1 FROM user_channels other
+      WHERE other.channel_id = p.channel_id AND other.user_id <> $1
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM user_digest_items i
+      JOIN user_digests d ON d.id = i.digest_id
+      WHERE i.post_id = p.id AND d.user_id <> $1
+  )
+`, userID, before)
+	metrics.ObserveNetworkRequest("postgres", "posts_clear_user_cache", "posts", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CreatePromoCode сохраняет новый промокод.
+func (p *Postgres) CreatePromoCode(ctx context.Context, promo domain.PromoCode) error {
+	if promo.Kind == domain.PromoKindPlan {
+		return domain.ErrPromoPlanUnsupported
+	}
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO promo_codes (code, kind, value, max_uses, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, promo.Code, string(promo.Kind), promo.Value, promo.MaxUses, promo.ExpiresAt, promo.CreatedBy)
+	metrics.ObserveNetworkRequest("postgres", "promo_codes_insert", "promo_codes", start, err)
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		return domain.ErrPromoExists
+	}
+	return err
+}
+
+// RedeemPromoCode активирует промокод для пользователя. Строка промокода блокируется до конца
+// транзакции, поэтому счётчик активаций не превысит max_uses при одновременных запросах.
+// Тарифные промокоды не активируются и не расходуются, пока у тарифов нет срока.
+func (p *Postgres) RedeemPromoCode(ctx context.Context, code string, userID int64, now time.Time) (domain.PromoRedemption, error) {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	metrics.ObserveNetworkRequest("postgres", "begin_tx", "promo_codes", start, err)
+	if err != nil {
+		return domain.PromoRedemption{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		promo     domain.PromoCode
+		kind      string
+		plan      sql.NullString
+		expiresAt sql.NullTime
+		createdBy sql.NullInt64
+	)
+	start = time.Now()
+	err = tx.QueryRow(ctx, `
+SELECT code, kind, value, plan, max_uses, used, expires_at, created_by, created_at
+FROM promo_codes WHERE code = $1
+FOR UPDATE
+`, code).Scan(&promo.Code, &kind, &promo.Value, &plan, &promo.MaxUses, &promo.Used, &expiresAt, &createdBy, &promo.CreatedAt)
+	metrics.ObserveNetworkRequest("postgres", "promo_codes_get_for_update", "promo_codes", start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.PromoRedemption{}, domain.ErrPromoNotFound
+	}
+	if err != nil {
+		return domain.PromoRedemption{}, err
+	}
+	promo.Kind = domain.PromoKind(kind)
+	promo.Plan = domain.UserRole(plan.String)
+	promo.CreatedBy = createdBy.Int64
+	if expiresAt.Valid {
+		at := expiresAt.Time
+		promo.ExpiresAt = &at
+	}
+	if promo.Kind == domain.PromoKindPlan {
+		return domain.PromoRedemption{}, domain.ErrPromoPlanUnsupported
+	}
+
+	var appliedAt sql.NullTime
+	start = time.Now()
+	err = tx.QueryRow(ctx, `SELECT applied_at FROM promo_redemptions WHERE code = $1 AND user_id = $2`, code, userID).Scan(&appliedAt)
+	metrics.ObserveNetworkRequest("postgres", "promo_redemptions_get", "promo_redemptions", start, err)
+	switch {
+	case err == nil:
+		if !appliedAt.Valid {
+			return domain.PromoRedemption{Promo: promo, Pending: true}, nil
+		}
+		return domain.PromoRedemption{}, domain.ErrPromoAlreadyUsed
+	case !errors.Is(err, pgx.ErrNoRows):
+		return domain.PromoRedemption{}, err
+	}
+	if promo.ExpiresAt != nil && !now.Before(*promo.ExpiresAt) {
+		return domain.PromoRedemption{}, domain.ErrPromoExpired
+	}
+	if promo.Used >= promo.MaxUses {
+		return domain.PromoRedemption{}, domain.ErrPromoExhausted
+	}
+
+	redemption := domain.PromoRedemption{Promo: promo, Pending: true}
+	start = time.Now()
+	_, err = tx.Exec(ctx, `INSERT INTO promo_redemptions (code, user_id, redeemed_at) VALUES ($1, $2, $3)`, code, userID, now)
+	metrics.ObserveNetworkRequest("postgres", "promo_redemptions_insert", "promo_redemptions", start, err)
+	if err != nil {
+		return domain.PromoRedemption{}, err
+	}
+	start = time.Now()
+	_, err = tx.Exec(ctx, `UPDATE promo_codes SET used = used + 1 WHERE code = $1`, code)
+	metrics.ObserveNetworkRequest("postgres", "promo_codes_use", "promo_codes", start, err)
+	if err != nil {
+		return domain.PromoRedemption{}, err
+	}
+
+	start = time.Now()
+	err = tx.Commit(ctx)
+	metrics.ObserveNetworkRequest("postgres", "commit", "promo_codes", start, err)
+	if err != nil {
+		return domain.PromoRedemption{}, err
+	}
+	redemption.Promo.Used++
+	return redemption, nil
+}
+
+// MarkPromoApplied отмечает зачисление бонуса по промокоду.
+func (p *Postgres) MarkPromoApplied(ctx context.Context, code string, userID int64) error {
+	ctx, cancel := p.connCtxWithParent(ctx)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.pool.Exec(ctx, `
+UPDATE promo_redemptions SET applied_at = now()
+WHERE code = $1 AND user_id = $2 AND applied_at IS NULL
+`, code, userID)
+	metrics.ObserveNetworkRequest("postgres", "promo_redemptions_apply", "promo_redemptions", start, err)
+	return err
+}
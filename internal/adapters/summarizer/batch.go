@@ -0,0 +1,161 @@
+package summarizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tg-digest-bot/internal/domain"
+	openai "tg-digest-bot/internal/infra/openai"
+)
+
+const (
+	// batchMaxPosts ограничивает число постов в одном запросе, чтобы ответ уложился в лимит токенов.
+	batchMaxPosts = 8
+	// batchMaxRunes — бюджет на суммарный текст постов в одном запросе.
+	batchMaxRunes = 8000
+	// batchPostRunes — сколько символов одного поста отправляется в пакетном запросе.
+	batchPostRunes = 1500
+	// batchTokensPerPost — запас токенов ответа на одно резюме.
+	batchTokensPerPost = 250
+)
+
+type batchPayload struct {
+	Items []batchItemPayload `json:"items"`
+}
+
+type batchItemPayload struct {
+	ID       int      `json:"id"`
+	Headline string   `json:"headline"`
+	Bullets  []string `json:"bullets"`
+}
+
+// SummarizeBatch суммирует посты пачками. Если ответ на пачку не разобрался или
+// в нём не хватает постов, недостающие резюме строятся по одному через Summarize.
+func (s *OpenAI) SummarizeBatch(ctx context.Context, posts []domain.Post) ([]domain.Summary, error) {
+	out := make([]domain.Summary, len(posts))
+	for _, chunk := range chunkPosts(posts) {
+		// Ошибка пачки не фатальна: все её посты уйдут в поштучный запасной путь.
+		summaries, _ := s.summarizeChunk(ctx, posts, chunk)
+		for _, idx := range chunk {
+			if summary, ok := summaries[idx]; ok {
+				out[idx] = summary
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			summary, err := s.Summarize(posts[idx])
+			if err != nil {
+				return nil, err
+			}
+			out[idx] = summary
+		}
+	}
+	return out, nil
+}
+
+// summarizeChunk возвращает резюме по индексам posts. Пустые посты не отправляются в LLM.
+func (s *OpenAI) summarizeChunk(ctx context.Context, posts []domain.Post, chunk []int) (map[int]domain.Summary, error) {
+	result := make(map[int]domain.Summary, len(chunk))
+	var b strings.Builder
+	sent := 0
+	withComments := false
+	for _, idx := range chunk {
+		text := strings.TrimSpace(posts[idx].Text)
+		if text == "" {
+			result[idx] = domain.Summary{Headline: "Пост без текста"}
+			continue
+		}
+		fmt.Fprintf(&b, "\n[id=%d]\n%s\n", idx, clipRunes(text, batchPostRunes))
+		if comments := commentsBlock(posts[idx]); comments != "" {
+			b.WriteString(strings.TrimPrefix(comments, "\n"))
+			b.WriteString("\n")
+			withComments = true
+		}
+		sent++
+	}
+	if sent == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout*2)
+	defer cancel()
+
+	userPrompt := fmt.Sprintf(`Подготовь краткие резюме телеграм-постов на русском языке.
+Верни JSON формата {"items": [{"id": 0, "headline": "...", "bullets": ["..."]}]} без пояснений, по одному элементу на каждый пост, id бери из метки поста.
+Посты:%s`, b.String())
+	if withComments {
+		userPrompt += "\nЕсли у поста есть комментарии читателей, последним пунктом в его bullets кратко опиши их реакцию."
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:       s.model,
+		Temperature: s.temperature,
+		MaxTokens:   batchTokensPerPost * sent,
+		Messages: []openai.ChatMessage{
+			{
+				Role:    openai.RoleSystem,
+				Content: "Ты помощник-редактор. Сохраняй факты из текста и не выдумывай ничего нового. Не смешивай содержание разных постов.",
+			},
+			{
+				Role:    openai.RoleUser,
+				Content: userPrompt,
+			},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ResponseFormatTypeJSONObject},
+	}
+
+	resp, err := s.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("openai completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("openai completion: пустой ответ")
+	}
+	var parsed batchPayload
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Choices[0].Message.Content)), &parsed); err != nil {
+		return nil, fmt.Errorf("распаковка ответа LLM: %w", err)
+	}
+	wanted := make(map[int]struct{}, len(chunk))
+	for _, idx := range chunk {
+		wanted[idx] = struct{}{}
+	}
+	for _, item := range parsed.Items {
+		if _, ok := wanted[item.ID]; !ok {
+			continue
+		}
+		headline := strings.TrimSpace(item.Headline)
+		if headline == "" {
+			continue
+		}
+		result[item.ID] = domain.Summary{Headline: headline, Bullets: filterValues(item.Bullets)}
+	}
+	return result, nil
+}
+
+// chunkPosts делит индексы постов на группы с учётом batchMaxPosts и batchMaxRunes.
+func chunkPosts(posts []domain.Post) [][]int {
+	var (
+		chunks  [][]int
+		current []int
+		runes   int
+	)
+	for idx, post := range posts {
+		size := len([]rune(strings.TrimSpace(post.Text)))
+		if size > batchPostRunes {
+			size = batchPostRunes
+		}
+		if len(current) > 0 && (len(current) >= batchMaxPosts || runes+size > batchMaxRunes) {
+			chunks = append(chunks, current)
+			current, runes = nil, 0
+		}
+		current = append(current, idx)
+		runes += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
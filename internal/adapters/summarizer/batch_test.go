@@ -0,0 +1,96 @@
+package summarizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+	openai "tg-digest-bot/internal/infra/openai"
+)
+
+type scriptedClient struct {
+	replies []string
+	calls   []openai.ChatCompletionRequest
+}
+
+func (c *scriptedClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.calls = append(c.calls, req)
+	reply := c.replies[0]
+	if len(c.replies) > 1 {
+		c.replies = c.replies[1:]
+	}
+	var resp openai.ChatCompletionResponse
+	resp.Choices = append(resp.Choices, openai.ChatCompletionChoice{Message: openai.ChatMessage{Content: reply}})
+	return resp, nil
+}
+
+func TestSummarizeBatchSingleRequest(t *testing.T) {
+	client := &scriptedClient{replies: []string{`{"items": [{"id": 1, "headline": "второй"}, {"id": 0, "headline": "первый", "bullets": ["факт"]}]}`}}
+	s := NewOpenAI(client, "m", nil, time.Second)
+
+	summaries, err := s.SummarizeBatch(context.Background(), []domain.Post{{Text: "пост один"}, {Text: "пост два"}})
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(client.calls) != 1 {
+		t.Fatalf("ожидали один запрос, получили %d", len(client.calls))
+	}
+	if summaries[0].Headline != "первый" || summaries[1].Headline != "второй" {
+		t.Fatalf("резюме перепутаны: %+v", summaries)
+	}
+	if len(summaries[0].Bullets) != 1 {
+		t.Fatalf("ожидали тезис у первого поста: %+v", summaries[0])
+	}
+}
+
+func TestSummarizeBatchFallsBackPerPost(t *testing.T) {
+	client := &scriptedClient{replies: []string{"не json", `{"headline": "поштучно"}`}}
+	s := NewOpenAI(client, "m", nil, time.Second)
+
+	summaries, err := s.SummarizeBatch(context.Background(), []domain.Post{{Text: "пост один"}, {Text: "пост два"}})
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(client.calls) != 3 {
+		t.Fatalf("ожидали пакетный и два поштучных запроса, получили %d", len(client.calls))
+	}
+	for i, summary := range summaries {
+		if summary.Headline != "поштучно" {
+			t.Fatalf("пост %d: ожидали запасное резюме, получили %+v", i, summary)
+		}
+	}
+}
+
+func TestChunkPostsRespectsLimits(t *testing.T) {
+	posts := make([]domain.Post, batchMaxPosts+1)
+	if chunks := chunkPosts(posts); len(chunks) != 2 {
+		t.Fatalf("ожидали 2 пачки по числу постов, получили %d", len(chunks))
+	}
+
+	long := strings.Repeat("я", batchPostRunes)
+	posts = make([]domain.Post, 0, 6)
+	for i := 0; i < 6; i++ {
+		posts = append(posts, domain.Post{Text: long})
+	}
+	chunks := chunkPosts(posts)
+	perChunk := batchMaxRunes / batchPostRunes
+	if len(chunks[0]) != perChunk {
+		t.Fatalf("ожидали %d постов в первой пачке, получили %d", perChunk, len(chunks[0]))
+	}
+}
+
+func TestSummarizeBatchSendsComments(t *testing.T) {
+	client := &scriptedClient{replies: []string{`{"items": [{"id": 0, "headline": "пост"}]}`}}
+	s := NewOpenAI(client, "m", nil, time.Second)
+
+	post := domain.Post{Text: "пост один"}.WithComments([]string{"отличная новость"})
+	if _, err := s.SummarizeBatch(context.Background(), []domain.Post{post}); err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	prompt := client.calls[0].Messages[1].Content
+	if !strings.Contains(prompt, "Комментарии читателей:\n— отличная новость") || !strings.Contains(prompt, "реакцию") {
+		t.Fatalf("комментарии не попали в промпт: %s", prompt)
+	}
+}
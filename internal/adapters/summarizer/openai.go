@@ -17,20 +17,21 @@ type chatClient interface {
 
 // OpenAI реализует summarizer через OpenAI Chat Completions.
 type OpenAI struct {
-	client  chatClient
-	model   string
-	timeout time.Duration
+	client      chatClient
+	model       string
+	temperature *float64
+	timeout     time.Duration
 }
 
-// NewOpenAI создаёт провайдер суммаризации.
-func NewOpenAI(client chatClient, model string, timeout time.Duration) *OpenAI {
+// NewOpenAI создаёт провайдер суммаризации. temperature == nil — значение по умолчанию модели.
+func NewOpenAI(client chatClient, model string, temperature *float64, timeout time.Duration) *OpenAI {
 	if model == "" {
 		model = "qwen3:4b"
 	}
 	if timeout <= 0 {
 		timeout = 15 * time.Second
 	}
-	return &OpenAI{client: client, model: model, timeout: timeout}
+	return &OpenAI{client: client, model: model, temperature: temperature, timeout: timeout}
 }
 
 type summaryPayload struct {
@@ -51,10 +52,13 @@ func (s *OpenAI) Summarize(post domain.Post) (domain.Summary, error) {
 Верни JSON формата {"headline": "...", "bullets": ["..."]} без пояснений.
 Текст поста:
 %s`, clipRunes(text, 2000))
+	if comments := commentsBlock(post); comments != "" {
+		userPrompt += comments + "\n" + commentsInstruction
+	}
 
 	req := openai.ChatCompletionRequest{
 		Model:       s.model,
-		Temperature: 0.2,
+		Temperature: s.temperature,
 		MaxTokens:   300,
 		Messages: []openai.ChatMessage{
 			{
@@ -87,6 +91,70 @@ func (s *OpenAI) Summarize(post domain.Post) (domain.Summary, error) {
 	}, nil
 }
 
+type adPayload struct {
+	IsAd bool `json:"is_ad"`
+}
+
+// IsAd просит LLM определить, является ли пост рекламой или партнёрской интеграцией.
+// Использует ту же температуру, что и суммаризация (OPENAI_SUMMARY_TEMPERATURE): модели,
+// которые не принимают свою температуру, иначе отвергали бы запрос классификации.
+func (s *OpenAI) IsAd(post domain.Post) (bool, error) {
+	text := strings.TrimSpace(post.Text)
+	if text == "" {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	req := openai.ChatCompletionRequest{
+		Model:       s.model,
+		Temperature: s.temperature,
+		MaxTokens:   20,
+		Messages: []openai.ChatMessage{
+			{
+				Role:    openai.RoleSystem,
+				Content: "Ты классификатор. Рекламой считаются платные интеграции, промокоды и партнёрские ссылки; новости о компаниях рекламой не являются.",
+			},
+			{
+				Role:    openai.RoleUser,
+				Content: fmt.Sprintf("Является ли этот телеграм-пост рекламой? Верни JSON {\"is_ad\": true|false} без пояснений.\nТекст поста:\n%s", clipRunes(text, 2000)),
+			},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ResponseFormatTypeJSONObject},
+	}
+
+	resp, err := s.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return false, fmt.Errorf("openai completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return false, fmt.Errorf("openai completion: пустой ответ")
+	}
+	var parsed adPayload
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Choices[0].Message.Content)), &parsed); err != nil {
+		return false, fmt.Errorf("распаковка ответа LLM: %w", err)
+	}
+	return parsed.IsAd, nil
+}
+
+// commentsInstruction просит отразить реакцию читателей, если к посту приложены комментарии.
+const commentsInstruction = "Последним пунктом в bullets кратко опиши реакцию читателей по их комментариям."
+
+// commentsBlock возвращает лучшие комментарии поста для промпта или пустую строку.
+func commentsBlock(post domain.Post) string {
+	comments := post.Comments()
+	if len(comments) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nКомментарии читателей:")
+	for _, comment := range comments {
+		b.WriteString("\n— ")
+		b.WriteString(comment)
+	}
+	return b.String()
+}
+
 func filterValues(values []string) []string {
 	out := make([]string, 0, len(values))
 	for _, v := range values {
@@ -0,0 +1,28 @@
+package telegram
+
+import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+// Client — вызовы Bot API, которыми пользуются бот и воркер доставки.
+// Позволяет подменять транспорт и проверять доставку в тестах без живого Telegram.
+type Client interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetFile(config tgbotapi.FileConfig) (tgbotapi.File, error)
+	SetMyCommands(config tgbotapi.SetMyCommandsConfig) error
+}
+
+// BotClient реализует Client поверх *tgbotapi.BotAPI; остальные методы API доступны через встраивание.
+type BotClient struct {
+	*tgbotapi.BotAPI
+}
+
+// NewClient оборачивает готовый BotAPI.
+func NewClient(api *tgbotapi.BotAPI) *BotClient {
+	return &BotClient{BotAPI: api}
+}
+
+// SetMyCommands регистрирует меню команд бота.
+func (c *BotClient) SetMyCommands(config tgbotapi.SetMyCommandsConfig) error {
+	_, err := c.Request(config)
+	return err
+}
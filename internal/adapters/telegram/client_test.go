@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordingHTTP запоминает вызванные методы Bot API и отвечает успехом.
+type recordingHTTP struct {
+	methods []string
+}
+
+func (r *recordingHTTP) Do(req *http.Request) (*http.Response, error) {
+	r.methods = append(r.methods, req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:])
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"ok":true,"result":true}`)), Header: make(http.Header)}, nil
+}
+
+func TestBotClientSetMyCommands(t *testing.T) {
+	transport := &recordingHTTP{}
+	api := &tgbotapi.BotAPI{Token: "test", Client: transport}
+	api.SetAPIEndpoint("http://bot.test/bot%s/%s")
+
+	var client Client = NewClient(api)
+	if err := client.SetMyCommands(tgbotapi.NewSetMyCommands(tgbotapi.BotCommand{Command: "help", Description: "Справка"})); err != nil {
+		t.Fatalf("SetMyCommands: %v", err)
+	}
+	if len(transport.methods) != 1 || transport.methods[0] != "setMyCommands" {
+		t.Fatalf("expected a single setMyCommands call, got %v", transport.methods)
+	}
+}
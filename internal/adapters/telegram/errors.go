@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"errors"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// IsChatUnreachable сообщает, что Bot API больше не доставит сообщения в чат:
+// пользователь заблокировал бота, удалил аккаунт или чат не найден, а в группе или канале —
+// бота исключили или лишили права писать. Повтор такой отправки бесполезен.
+func IsChatUnreachable(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	switch apiErr.Code {
+	case 403:
+		return strings.Contains(message, "bot was blocked by the user") ||
+			strings.Contains(message, "user is deactivated") ||
+			strings.Contains(message, "bot can't initiate conversation") ||
+			strings.Contains(message, "bot was kicked") ||
+			strings.Contains(message, "bot is not a member")
+	case 400:
+		return strings.Contains(message, "chat not found") ||
+			strings.Contains(message, "not enough rights") ||
+			strings.Contains(message, "have no rights to send") ||
+			strings.Contains(message, "need administrator rights")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestIsChatUnreachable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, true},
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: user is deactivated"}, true},
+		{fmt.Errorf("send: %w", &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}), true},
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot is not a member of the channel chat"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: need administrator rights in the channel chat"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: message is too long"}, false},
+		{&tgbotapi.Error{Code: 429, Message: "Too Many Requests: retry after 5"}, false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tc := range cases {
+		if got := IsChatUnreachable(tc.err); got != tc.want {
+			t.Fatalf("IsChatUnreachable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
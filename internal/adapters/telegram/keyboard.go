@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// ExpandCallbackPrefix — префикс callback-данных кнопки «Подробнее».
+const ExpandCallbackPrefix = "expand:"
+
+// SnoozeCallbackPrefix — префикс callback-данных кнопки «Напомнить через 2ч».
+const SnoozeCallbackPrefix = "snooze:"
+
+const expandLabelLimit = 32
+
+// ExpandKeyboard строит клавиатуру с кнопкой «Подробнее» для каждого пункта дайджеста.
+func ExpandKeyboard(items []domain.DigestItem) *tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(items))
+	for _, item := range items {
+		if item.Post.ID <= 0 {
+			continue
+		}
+		label := strings.TrimSpace(item.Summary.Headline)
+		if label == "" {
+			label = "пост"
+		}
+		if runes := []rune(label); len(runes) > expandLabelLimit {
+			label = string(runes[:expandLabelLimit]) + "…"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📖 Подробнее: "+label, fmt.Sprintf("%s%d", ExpandCallbackPrefix, item.Post.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
+
+// WithSnoozeButton добавляет в конец клавиатуры кнопку откладывания дайджеста.
+func WithSnoozeButton(markup *tgbotapi.InlineKeyboardMarkup, digestID int64) *tgbotapi.InlineKeyboardMarkup {
+	if digestID <= 0 {
+		return markup
+	}
+	row := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⏰ Напомнить через 2ч", fmt.Sprintf("%s%d", SnoozeCallbackPrefix, digestID)),
+	)
+	if markup == nil {
+		snooze := tgbotapi.NewInlineKeyboardMarkup(row)
+		return &snooze
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+	return markup
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestExpandKeyboardBuildsButtonPerPost(t *testing.T) {
+	items := []domain.DigestItem{
+		{Post: domain.Post{ID: 10}, Summary: domain.Summary{Headline: "Первый"}},
+		{Post: domain.Post{ID: 0}, Summary: domain.Summary{Headline: "Без id"}},
+		{Post: domain.Post{ID: 11}},
+	}
+
+	markup := ExpandKeyboard(items)
+	if markup == nil {
+		t.Fatal("expected keyboard")
+	}
+	if len(markup.InlineKeyboard) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(markup.InlineKeyboard))
+	}
+	if data := markup.InlineKeyboard[0][0].CallbackData; data == nil || *data != "expand:10" {
+		t.Fatalf("unexpected callback data %v", data)
+	}
+}
+
+func TestExpandKeyboardEmpty(t *testing.T) {
+	if ExpandKeyboard(nil) != nil {
+		t.Fatal("expected nil keyboard for empty digest")
+	}
+}
+
+func TestWithSnoozeButton(t *testing.T) {
+	markup := WithSnoozeButton(ExpandKeyboard([]domain.DigestItem{{Post: domain.Post{ID: 10}}}), 7)
+	if len(markup.InlineKeyboard) != 2 {
+		t.Fatalf("expected snooze row appended, got %d rows", len(markup.InlineKeyboard))
+	}
+	if data := markup.InlineKeyboard[1][0].CallbackData; data == nil || *data != "snooze:7" {
+		t.Fatalf("unexpected callback data %v", data)
+	}
+	if WithSnoozeButton(nil, 7) == nil {
+		t.Fatal("expected keyboard with snooze button for digest without posts")
+	}
+	if WithSnoozeButton(nil, 0) != nil {
+		t.Fatal("expected no keyboard without digest id")
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "encoding/json"
+
+// postMetaAdFilterKey — ключ в raw_meta_json поста, под которым хранится решение фильтра рекламы.
+const postMetaAdFilterKey = "ad_filter"
+
+// AdVerdict хранит решение фильтра рекламы по посту.
+type AdVerdict struct {
+	IsAd   bool   `json:"is_ad"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AdVerdict возвращает сохранённое решение фильтра рекламы, если пост уже классифицировался.
+func (p Post) AdVerdict() (AdVerdict, bool) {
+	if len(p.RawMetaJSON) == 0 {
+		return AdVerdict{}, false
+	}
+	var meta map[string]json.RawMessage
+	if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+		return AdVerdict{}, false
+	}
+	raw, ok := meta[postMetaAdFilterKey]
+	if !ok {
+		return AdVerdict{}, false
+	}
+	var verdict AdVerdict
+	if err := json.Unmarshal(raw, &verdict); err != nil {
+		return AdVerdict{}, false
+	}
+	return verdict, true
+}
@@ -16,6 +16,9 @@ var (
 
 	// ErrInsufficientFunds возвращается, когда на счёте недостаточно средств.
 	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrPaymentNotFound возвращается, когда платёж с таким ключом идемпотентности не найден.
+	ErrPaymentNotFound = errors.New("payment not found")
 )
 
 // Money описывает сумму в минимальных единицах валюты.
@@ -33,6 +36,14 @@ type BillingAccount struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Статусы счёта в сервисе биллинга.
+const (
+	InvoiceStatusPending       = "pending"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusCancelled     = "cancelled"
+)
+
 // Invoice описывает счёт на оплату.
 type Invoice struct {
 	ID             int64          `json:"id"`
@@ -45,6 +56,19 @@ type Invoice struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	PaidAt         *time.Time     `json:"paid_at"`
+	PaidAmount     int64          `json:"paid_amount"`
+	ExpiresAt      *time.Time     `json:"expires_at"`
+}
+
+// InvoiceStatus — сводка по оплате счёта для ручной проверки пользователем.
+type InvoiceStatus struct {
+	InvoiceID  int64
+	AccountID  int64
+	Status     string
+	Amount     Money
+	PaidAmount int64
+	PaidAt     *time.Time
+	ExpiresAt  *time.Time
 }
 
 // InvoiceSBPMetadata хранит информацию о QR-коде СБП, связанной со счётом.
@@ -108,7 +132,11 @@ type Billing interface {
 	RegisterIncomingPayment(ctx context.Context, params RegisterIncomingPaymentParams) (Payment, error)
 	GetInvoiceByID(ctx context.Context, invoiceID int64) (Invoice, error)
 	GetInvoiceByIdempotencyKey(ctx context.Context, key string) (Invoice, error)
+	// GetInvoiceStatus возвращает состояние оплаты счёта, не дожидаясь вебхука.
+	GetInvoiceStatus(ctx context.Context, invoiceID int64) (InvoiceStatus, error)
 	ChargeAccount(ctx context.Context, params ChargeAccountParams) (Payment, error)
+	// GetPaymentByIdempotencyKey находит платёж или списание по ключу, с которым его провели.
+	GetPaymentByIdempotencyKey(ctx context.Context, key string) (Payment, error)
 }
 
 // ExtractInvoiceSBPMetadata извлекает информацию о QR-коде СБП из метаданных счёта.
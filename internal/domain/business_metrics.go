@@ -7,6 +7,8 @@ import (
 
 // BusinessMetric описывает бизнесовое событие, которое сохраняется для последующего анализа.
 type BusinessMetric struct {
+	// ID заполняется только при чтении и служит курсором выгрузки.
+	ID         int64
 	Event      string
 	UserID     *int64
 	ChannelID  *int64
@@ -27,9 +29,46 @@ const (
 	BusinessMetricEventDigestBuilt = "digest_built"
 	// BusinessMetricEventDigestDelivered фиксирует успешную доставку дайджеста пользователю.
 	BusinessMetricEventDigestDelivered = "digest_delivered"
+	// BusinessMetricEventDeliveryDisabled фиксирует отключение доставки: пользователь заблокировал бота.
+	BusinessMetricEventDeliveryDisabled = "delivery_disabled"
+	// BusinessMetricEventManualRequestsGranted фиксирует ручную выдачу запросов поддержкой.
+	BusinessMetricEventManualRequestsGranted = "manual_requests_granted"
+	// BusinessMetricEventReferralFlagged фиксирует приглашение, отложенное на ручную проверку.
+	BusinessMetricEventReferralFlagged = "referral_flagged"
+	// BusinessMetricEventReferralApproved фиксирует ручное подтверждение отложенного приглашения.
+	BusinessMetricEventReferralApproved = "referral_approved"
+	// BusinessMetricEventPromoRedeemed фиксирует активацию промокода.
+	BusinessMetricEventPromoRedeemed = "promo_redeemed"
 )
 
 // BusinessMetricRepo сохраняет бизнесовые события.
 type BusinessMetricRepo interface {
 	RecordBusinessMetric(ctx context.Context, metric BusinessMetric) error
 }
+
+// BusinessMetricQuery задаёт выборку событий для выгрузки аналитики.
+type BusinessMetricQuery struct {
+	// Event — имя события; пустая строка означает все события.
+	Event string
+	// From и To ограничивают occurred_at полуинтервалом [From, To); нулевое время не ограничивает.
+	From time.Time
+	To   time.Time
+	// AfterID — курсор: возвращаются события с id больше указанного.
+	AfterID int64
+	Limit   int
+}
+
+// BusinessMetricDaily — число событий и уникальных пользователей за день (UTC).
+type BusinessMetricDaily struct {
+	Day           time.Time
+	Event         string
+	Count         int64
+	DistinctUsers int64
+}
+
+// BusinessMetricReader читает накопленные события для аналитики.
+type BusinessMetricReader interface {
+	QueryBusinessMetrics(ctx context.Context, query BusinessMetricQuery) ([]BusinessMetric, error)
+	// ListBusinessMetricsDaily возвращает дневные агрегаты; AfterID и Limit не используются.
+	ListBusinessMetricsDaily(ctx context.Context, query BusinessMetricQuery) ([]BusinessMetricDaily, error)
+}
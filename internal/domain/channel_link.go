@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInviteLink возвращается для ссылок-приглашений (t.me/+…, t.me/joinchat/…): они ведут в приватные каналы.
+var ErrInviteLink = errors.New("это приватный канал: ссылки-приглашения не поддерживаются")
+
+// ErrChannelLinkInvalid возвращается, если из ввода не удалось извлечь username канала.
+var ErrChannelLinkInvalid = errors.New("некорректная ссылка на канал")
+
+// ChannelUsername извлекает username канала из @alias или ссылки t.me: поддерживаются
+// веб-превью t.me/s/<name>, ссылки на посты и параметры запроса. Регистр не меняется.
+func ChannelUsername(input string) (string, error) {
+	rest := strings.TrimSpace(input)
+	if cut, _, found := strings.Cut(rest, "?"); found {
+		rest = cut
+	}
+	if cut, _, found := strings.Cut(rest, "#"); found {
+		rest = cut
+	}
+	lower := strings.ToLower(rest)
+	for _, scheme := range []string{"https://", "http://"} {
+		if strings.HasPrefix(lower, scheme) {
+			rest, lower = rest[len(scheme):], lower[len(scheme):]
+			break
+		}
+	}
+	if strings.HasPrefix(lower, "www.") {
+		rest, lower = rest[len("www."):], lower[len("www."):]
+	}
+	isLink := false
+	for _, host := range []string{"t.me/", "telegram.me/"} {
+		if strings.HasPrefix(lower, host) {
+			rest = rest[len(host):]
+			isLink = true
+			break
+		}
+	}
+	if !isLink {
+		rest = strings.TrimPrefix(rest, "@")
+	}
+
+	segments := strings.FieldsFunc(rest, func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return "", ErrChannelLinkInvalid
+	}
+	first := segments[0]
+	if strings.HasPrefix(first, "+") || strings.EqualFold(first, "joinchat") {
+		return "", ErrInviteLink
+	}
+	if isLink && first == "s" {
+		if len(segments) < 2 {
+			return "", ErrChannelLinkInvalid
+		}
+		first = segments[1]
+	}
+	if !isLink && len(segments) > 1 {
+		return "", ErrChannelLinkInvalid
+	}
+	return first, nil
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChannelUsername(t *testing.T) {
+	cases := map[string]string{
+		"@Example":                          "Example",
+		"example":                           "example",
+		"t.me/golang":                       "golang",
+		"https://t.me/golang":               "golang",
+		"http://www.t.me/golang/":           "golang",
+		"https://telegram.me/golang":        "golang",
+		"https://t.me/s/golang":             "golang",
+		"t.me/s/golang/1234":                "golang",
+		"https://t.me/golang/42?single":     "golang",
+		"https://t.me/s/golang?before=100":  "golang",
+		"https://T.ME/golang#comments":      "golang",
+		"  https://t.me/golang?utm=x&y=z  ": "golang",
+	}
+	for input, want := range cases {
+		got, err := ChannelUsername(input)
+		if err != nil {
+			t.Fatalf("%q: не ожидали ошибку: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("%q: ожидали %q, получили %q", input, want, got)
+		}
+	}
+}
+
+func TestChannelUsernameRejectsInviteLinks(t *testing.T) {
+	for _, input := range []string{
+		"https://t.me/+AbCdEf123",
+		"t.me/joinchat/AbCdEf123",
+		"https://t.me/joinchat/AbCdEf123?ref=1",
+		"+AbCdEf123",
+	} {
+		if _, err := ChannelUsername(input); !errors.Is(err, ErrInviteLink) {
+			t.Fatalf("%q: ожидали ErrInviteLink, получили %v", input, err)
+		}
+	}
+}
+
+func TestChannelUsernameRejectsGarbage(t *testing.T) {
+	for _, input := range []string{"", "   ", "@", "https://t.me/", "t.me/s/", "foo/bar"} {
+		if _, err := ChannelUsername(input); !errors.Is(err, ErrChannelLinkInvalid) {
+			t.Fatalf("%q: ожидали ErrChannelLinkInvalid, получили %v", input, err)
+		}
+	}
+}
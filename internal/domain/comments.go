@@ -0,0 +1,78 @@
+package domain
+
+import "encoding/json"
+
+// postMetaCommentsKey — ключ в raw_meta_json поста с лучшими комментариями из обсуждения.
+const postMetaCommentsKey = "comments"
+
+// CommentCollector дополняет посты лучшими комментариями из связанной группы обсуждения.
+// Посты канала без обсуждения возвращаются без изменений.
+type CommentCollector interface {
+	CollectComments(channel Channel, posts []Post) ([]Post, error)
+}
+
+// Comments возвращает сохранённые комментарии поста.
+func (p Post) Comments() []string {
+	if len(p.RawMetaJSON) == 0 {
+		return nil
+	}
+	var meta struct {
+		Comments []string `json:"comments"`
+	}
+	if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+		return nil
+	}
+	return meta.Comments
+}
+
+// WithComments возвращает копию поста, в мете которого сохранены комментарии.
+func (p Post) WithComments(comments []string) Post {
+	if len(comments) == 0 {
+		return p
+	}
+	meta := map[string]json.RawMessage{}
+	if len(p.RawMetaJSON) > 0 {
+		if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+			return p
+		}
+	}
+	encoded, err := json.Marshal(comments)
+	if err != nil {
+		return p
+	}
+	meta[postMetaCommentsKey] = encoded
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return p
+	}
+	p.RawMetaJSON = raw
+	return p
+}
+
+// WithoutComments возвращает копию поста без сохранённых комментариев. Комментарии лежат
+// в общей мете поста, поэтому их убирают из дайджестов пользователей без доступа к ним.
+func (p Post) WithoutComments() Post {
+	if len(p.RawMetaJSON) == 0 {
+		return p
+	}
+	meta := map[string]json.RawMessage{}
+	if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+		return p
+	}
+	if _, ok := meta[postMetaCommentsKey]; !ok {
+		return p
+	}
+	delete(meta, postMetaCommentsKey)
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return p
+	}
+	p.RawMetaJSON = raw
+	return p
+}
+
+// WantsComments сообщает, нужно ли собирать комментарии для дайджестов пользователя:
+// настройка /comments действует только на тарифах, где сбор комментариев доступен.
+func (u User) WantsComments() bool {
+	return u.CollectComments && u.Plan().Comments
+}
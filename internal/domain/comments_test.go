@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPostWithCommentsKeepsMeta(t *testing.T) {
+	post := Post{RawMetaJSON: json.RawMessage(`{"views":10,"replies":7}`)}
+	if got := post.Comments(); got != nil {
+		t.Fatalf("Comments() = %v, want nil", got)
+	}
+
+	post = post.WithComments([]string{"first", "second"})
+	if got := post.Comments(); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("Comments() = %v, want [first second]", got)
+	}
+	var meta map[string]any
+	if err := json.Unmarshal(post.RawMetaJSON, &meta); err != nil {
+		t.Fatalf("unmarshal meta: %v", err)
+	}
+	if meta["views"] != float64(10) || meta["replies"] != float64(7) {
+		t.Fatalf("existing meta lost: %v", meta)
+	}
+}
+
+func TestPostWithoutComments(t *testing.T) {
+	post := Post{RawMetaJSON: json.RawMessage(`{"views":10}`)}.WithComments([]string{"first"})
+	stripped := post.WithoutComments()
+	if got := stripped.Comments(); got != nil {
+		t.Fatalf("Comments() = %v, want nil", got)
+	}
+	if post.Comments() == nil {
+		t.Fatal("original post must keep its comments")
+	}
+	var meta map[string]any
+	if err := json.Unmarshal(stripped.RawMetaJSON, &meta); err != nil || meta["views"] != float64(10) {
+		t.Fatalf("existing meta lost: %v, %v", meta, err)
+	}
+}
+
+func TestUserWantsComments(t *testing.T) {
+	cases := []struct {
+		name string
+		user User
+		want bool
+	}{
+		{name: "free enabled", user: User{Role: UserRoleFree, CollectComments: true}, want: false},
+		{name: "pro disabled", user: User{Role: UserRolePro}, want: false},
+		{name: "pro enabled", user: User{Role: UserRolePro, CollectComments: true}, want: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.user.WantsComments(); got != tc.want {
+				t.Fatalf("WantsComments() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
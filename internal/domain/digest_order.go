@@ -0,0 +1,23 @@
+package domain
+
+// DigestOrder задаёт порядок пунктов в дайджесте.
+type DigestOrder string
+
+const (
+	// DigestOrderRelevance — по оценке LLM (по умолчанию).
+	DigestOrderRelevance DigestOrder = "relevance"
+	// DigestOrderTime — по времени публикации, сначала новые.
+	DigestOrderTime DigestOrder = "time"
+	// DigestOrderEngagement — по просмотрам и реакциям.
+	DigestOrderEngagement DigestOrder = "engagement"
+)
+
+// ParseDigestOrder разбирает порядок пунктов из пользовательского ввода.
+func ParseDigestOrder(value string) (DigestOrder, bool) {
+	switch DigestOrder(value) {
+	case DigestOrderRelevance, DigestOrderTime, DigestOrderEngagement:
+		return DigestOrder(value), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DeliveryMode определяет, куда отправлять дайджест.
+type DeliveryMode string
+
+const (
+	// DeliveryTelegram — только в Telegram (по умолчанию).
+	DeliveryTelegram DeliveryMode = "telegram"
+	// DeliveryEmail — только на подтверждённую почту.
+	DeliveryEmail DeliveryMode = "email"
+	// DeliveryBoth — в Telegram и на почту.
+	DeliveryBoth DeliveryMode = "both"
+)
+
+// ParseDeliveryMode разбирает режим доставки из пользовательского ввода.
+func ParseDeliveryMode(value string) (DeliveryMode, bool) {
+	switch DeliveryMode(value) {
+	case DeliveryTelegram, DeliveryEmail, DeliveryBoth:
+		return DeliveryMode(value), true
+	default:
+		return "", false
+	}
+}
+
+// ErrEmailCodeInvalid возвращается, если код подтверждения не совпал или истёк.
+var ErrEmailCodeInvalid = errors.New("неверный или просроченный код подтверждения")
+
+// DeliverByEmail сообщает, нужно ли отправлять дайджест на почту.
+func (u User) DeliverByEmail() bool {
+	if u.Email == "" || !u.EmailVerified {
+		return false
+	}
+	return u.DeliveryMode == DeliveryEmail || u.DeliveryMode == DeliveryBoth
+}
+
+// DeliverByTelegram сообщает, нужно ли отправлять дайджест в Telegram.
+// Без подтверждённой почты Telegram остаётся единственным каналом.
+func (u User) DeliverByTelegram() bool {
+	return u.DeliveryMode != DeliveryEmail || !u.DeliverByEmail()
+}
+
+// EmailRepo хранит почтовый адрес пользователя и состояние его подтверждения.
+type EmailRepo interface {
+	SetPendingEmail(userID int64, email, code string, expiresAt time.Time) error
+	// ConfirmEmail переносит ожидающий адрес в подтверждённые и возвращает его.
+	ConfirmEmail(userID int64, code string, now time.Time) (string, error)
+	SetDeliveryMode(userID int64, mode DeliveryMode) error
+	ClearEmail(userID int64) error
+}
+
+// EmailSender отправляет HTML-письма.
+type EmailSender interface {
+	Send(ctx context.Context, to, subject, htmlBody string) error
+}
+
+// DigestChatID возвращает чат для плановых дайджестов: привязанный через /setchat или личку.
+func (u User) DigestChatID() int64 {
+	if u.DeliveryChatID != 0 {
+		return u.DeliveryChatID
+	}
+	return u.TGUserID
+}
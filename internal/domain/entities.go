@@ -22,6 +22,44 @@ type User struct {
 	ReferralCode        string
 	ReferralsCount      int
 	ReferredByID        *int64
+	// ChannelLimitOverride — индивидуальный лимит каналов: nil — по тарифу, 0 — без ограничений.
+	ChannelLimitOverride *int
+	// FilterAds включает отсев рекламных постов из дайджестов.
+	FilterAds bool
+	// DigestLang — язык постов для дайджеста; пустая строка выключает фильтр.
+	DigestLang string
+	// LinkPreviews — главный пункт дайджеста приходит отдельным сообщением с превью ссылки.
+	LinkPreviews bool
+	// CollectComments — собирать лучшие комментарии обсуждаемых постов (/comments).
+	CollectComments bool
+	// DigestOrder — порядок пунктов дайджеста (/order).
+	DigestOrder DigestOrder
+	// DeliveryChatID — группа или канал для плановых дайджестов (/setchat); 0 — личные сообщения.
+	DeliveryChatID int64
+	// DeliveryDisabled — Telegram больше не доставляет сообщения пользователю (бот заблокирован).
+	DeliveryDisabled bool
+	// Paused — пользователь поставил дайджесты на паузу; PausedUntil — срок паузы, nil — без срока.
+	Paused      bool
+	PausedUntil *time.Time
+	// Email — подтверждённый адрес для доставки дайджеста.
+	Email         string
+	EmailVerified bool
+	DeliveryMode  DeliveryMode
+}
+
+// IsPaused сообщает, стоят ли дайджесты на паузе в момент now. Истёкшая пауза снимается сама.
+func (u User) IsPaused(now time.Time) bool {
+	if !u.Paused {
+		return false
+	}
+	return u.PausedUntil == nil || now.Before(*u.PausedUntil)
+}
+
+// UserSettings — настройки доставки, которые сохраняются одним обновлением.
+type UserSettings struct {
+	DailyTime  time.Time
+	Timezone   string
+	DigestLang string
 }
 
 // TelegramProfile содержит данные пользователя Telegram, полученные от Bot API.
@@ -42,6 +80,17 @@ type ReferralResult struct {
 	Referrer         *User
 	ReferrerUpgraded bool
 	PreviousRole     UserRole
+	// Flagged — приглашение похоже на накрутку и не засчитано до ручной проверки.
+	Flagged bool
+}
+
+// ReferrerStanding — место пользователя в рейтинге по приглашениям.
+type ReferrerStanding struct {
+	UserID         int64
+	Rank           int
+	FirstName      string
+	Username       string
+	ReferralsCount int
 }
 
 // Channel описывает публичный канал Telegram.
@@ -52,6 +101,10 @@ type Channel struct {
 	Title       string
 	IsAllowed   bool
 	CreatedAt   time.Time
+	// LastCollectedMsgID — последний собранный tg_msg_id, ниже которого историю не перечитываем.
+	LastCollectedMsgID int64
+	// MTProtoPool — пул аккаунтов, через который собирается канал; пусто — пул по умолчанию.
+	MTProtoPool string
 }
 
 // UserChannel хранит состояние подписки пользователя на канал.
@@ -60,9 +113,11 @@ type UserChannel struct {
 	UserID    int64
 	ChannelID int64
 	Muted     bool
-	AddedAt   time.Time
-	Channel   Channel
-	Tags      []string
+	// MutedUntil — срок временного мьюта; nil означает мьют без срока.
+	MutedUntil *time.Time
+	AddedAt    time.Time
+	Channel    Channel
+	Tags       []string
 }
 
 // Post представляет сообщение канала.
@@ -73,6 +128,7 @@ type Post struct {
 	PublishedAt time.Time
 	URL         string
 	Text        string
+	FullText    string
 	RawMetaJSON []byte
 	Hash        string
 	CreatedAt   time.Time
@@ -117,6 +173,41 @@ type Digest struct {
 	Theses      []string
 	Items       []DigestItem
 	DeliveredAt *time.Time
+	// Scope отличает дайджест канала, тегов или прошедшего дня от ежедневного (пустая строка):
+	// у каждого своя запись за дату.
+	Scope string
+	// SnoozeCount — сколько раз дайджест уже откладывали.
+	SnoozeCount int
+	// TopChannel — канал с наибольшей вовлечённостью среди пунктов; nil, если каналов меньше двух.
+	// Не сохраняется вместе с дайджестом.
+	TopChannel *DigestTopChannel
+	// Simplified — сводки построены без LLM, потому что исчерпан месячный лимит тарифа. Не сохраняется.
+	Simplified bool
+}
+
+// DigestTopChannel описывает «канал дня» в дайджесте.
+type DigestTopChannel struct {
+	ChannelID  int64
+	Title      string
+	Alias      string
+	Engagement float64
+}
+
+// DigestChannelShare — сколько пунктов канал дал в дайджест. Muted — канал сейчас выключен у пользователя.
+type DigestChannelShare struct {
+	Channel Channel
+	Items   int
+	Muted   bool
+}
+
+// MTProtoPoolStats описывает пул MTProto-аккаунтов для обзора администратором.
+type MTProtoPoolStats struct {
+	Name        string
+	Accounts    int
+	Enabled     int
+	WithSession int
+	// Channels — сколько каналов явно закреплено за пулом.
+	Channels int
 }
 
 // MTProtoAccount описывает авторизационные данные Telegram-аккаунта.
@@ -128,4 +219,9 @@ type MTProtoAccount struct {
 	Phone    string
 	Username string
 	RawJSON  []byte
+	// Enabled — участвует ли аккаунт в пулах сервисов.
+	Enabled bool
+	// HasSession — есть ли сохранённая MTProto-сессия.
+	HasSession bool
+	UpdatedAt  time.Time
 }
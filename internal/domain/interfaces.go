@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -18,9 +19,34 @@ type ChannelResolver interface {
 	ResolvePublic(alias string) (ChannelMeta, error)
 }
 
-// Collector выгружает сообщения за последние 24 часа.
+// ProbeReason объясняет, почему посты канала нельзя собирать.
+type ProbeReason string
+
+const (
+	ProbeReasonNotFound      ProbeReason = "not_found"
+	ProbeReasonNotChannel    ProbeReason = "not_channel"
+	ProbeReasonPrivate       ProbeReason = "private"
+	ProbeReasonAdminRequired ProbeReason = "admin_required"
+)
+
+// ChannelProbe — результат пробного чтения истории канала.
+type ChannelProbe struct {
+	Meta     ChannelMeta
+	Readable bool
+	// Empty — история читается, но постов в канале пока нет.
+	Empty  bool
+	Reason ProbeReason
+}
+
+// ChannelProber проверяет, что историю канала можно читать, не добавляя его.
+type ChannelProber interface {
+	ProbeHistory(alias string) (ChannelProbe, error)
+}
+
+// Collector выгружает сообщения каналов.
 type Collector interface {
 	Collect24h(channel Channel) ([]Post, error)
+	CollectSince(channel Channel, since time.Time) ([]Post, error)
 }
 
 // Ranker анализирует посты и возвращает дайджест.
@@ -33,6 +59,17 @@ type Summarizer interface {
 	Summarize(post Post) (Summary, error)
 }
 
+// BatchSummarizer строит краткие содержания нескольких постов за один запрос.
+// Результат совпадает по длине и порядку с posts.
+type BatchSummarizer interface {
+	SummarizeBatch(ctx context.Context, posts []Post) ([]Summary, error)
+}
+
+// AdClassifier определяет, является ли пост рекламой.
+type AdClassifier interface {
+	IsAd(post Post) (bool, error)
+}
+
 // DigestService отвечает за построение и доставку дайджестов.
 type DigestService interface {
 	BuildAndSendNow(userID int64) error
@@ -48,9 +85,33 @@ type UserRepo interface {
 	ListForDailyTime(now time.Time) ([]User, error)
 	UpdateDailyTime(userID int64, daily time.Time) error
 	UpdateTimezone(userID int64, timezone string) error
+	SetChannelLimitOverride(userID int64, limit *int) error
+	SetFilterAds(userID int64, enabled bool) error
+	SetLinkPreviews(userID int64, enabled bool) error
+	SetCollectComments(userID int64, enabled bool) error
+	SetDigestOrder(userID int64, order DigestOrder) error
+	// SetDeliveryChat привязывает плановые дайджесты к чату; 0 возвращает их в личку.
+	SetDeliveryChat(userID, chatID int64) error
+	// SetPaused ставит дайджесты на паузу до until (nil — без срока) или снимает паузу.
+	SetPaused(userID int64, paused bool, until *time.Time) error
+	// SetDigestLang задаёт язык постов для дайджеста; пустая строка выключает фильтр.
+	SetDigestLang(userID int64, lang string) error
+	// UpdateSettings сохраняет время доставки, часовой пояс и язык одним обновлением.
+	UpdateSettings(userID int64, settings UserSettings) error
+	SetDeliveryDisabled(userID int64, disabled bool) error
 	DeleteUserData(userID int64) error
 	ReserveManualRequest(userID int64, now time.Time) (ManualRequestState, error)
+	GrantManualRequests(userID int64, extra int, now time.Time) (ManualRequestState, error)
 	ApplyReferral(code string, newUserID int64) (ReferralResult, error)
+	// TopReferrers возвращает лидеров по числу приглашений; пользователи без приглашений не попадают.
+	TopReferrers(limit int) ([]ReferrerStanding, error)
+	// ReferrerRank возвращает место пользователя в рейтинге приглашений.
+	ReferrerRank(userID int64) (ReferrerStanding, error)
+	// ListFlaggedReferrals возвращает приглашения, ждущие ручной проверки, от старых к новым.
+	ListFlaggedReferrals(limit int) ([]FlaggedReferral, error)
+	// ApproveReferral засчитывает отложенное приглашение пользователя referredTGUserID и повышает
+	// тариф пригласившего, как при обычном приглашении. ErrReferralNotFlagged — если засчитывать нечего.
+	ApproveReferral(referredTGUserID int64) (ReferralResult, error)
 	UpdateRole(userID int64, role UserRole) error
 }
 
@@ -61,7 +122,11 @@ type ChannelRepo interface {
 	AttachChannelToUser(userID, channelID int64) error
 	DetachChannelFromUser(userID, channelID int64) error
 	SetMuted(userID, channelID int64, muted bool) error
+	MuteUntil(userID, channelID int64, until time.Time) error
 	CountUserChannels(userID int64) (int, error)
+	GetChannel(channelID int64) (Channel, error)
+	// SearchChannels ищет разрешённые каналы по подстроке в названии или алиасе.
+	SearchChannels(query string, limit int) ([]Channel, error)
 	UpdateUserChannelTags(userID, channelID int64, tags []string) error
 }
 
@@ -69,15 +134,105 @@ type ChannelRepo interface {
 type PostRepo interface {
 	SavePosts(channelID int64, posts []Post) error
 	ListRecentPosts(channelIDs []int64, since time.Time) ([]Post, error)
+	// ListRecentPostsBetween возвращает посты, опубликованные в окне [since, until].
+	ListRecentPostsBetween(channelIDs []int64, since, until time.Time) ([]Post, error)
+	// GetPost возвращает пост с полным текстом, если он из каналов пользователя userID,
+	// иначе ErrPostNotFound.
+	GetPost(postID, userID int64) (Post, error)
 	SaveSummary(postID int64, summary Summary) (int64, error)
+	SetPostAdVerdict(postID int64, verdict AdVerdict) error
+	SetPostLang(postID int64, lang string) error
+}
+
+// PostCacheWindow — за сколько последних часов посты нужны для ближайших дайджестов;
+// более старые пользователь может удалить командой /cache clear.
+const PostCacheWindow = 48 * time.Hour
+
+// ChannelPostCount — сколько постов канала хранит бот.
+type ChannelPostCount struct {
+	ChannelID int64
+	Alias     string
+	Title     string
+	Total     int
+	// Recent — посты, опубликованные не раньше запрошенного момента.
+	Recent int
+}
+
+// PostCacheRepo показывает и очищает сохранённые посты каналов пользователя.
+type PostCacheRepo interface {
+	CountRecentPostsByChannel(ctx context.Context, userID int64, since time.Time) ([]ChannelPostCount, error)
+	// ClearUserPostCache удаляет посты старше before в каналах, на которые подписан только userID,
+	// кроме постов из дайджестов других пользователей. Возвращает число удалённых постов.
+	ClearUserPostCache(ctx context.Context, userID int64, before time.Time) (int64, error)
+}
+
+// DigestSearchHit — пост из доставленного дайджеста, найденный по тексту.
+type DigestSearchHit struct {
+	Date     time.Time
+	Post     Post
+	Headline string
+	Channel  string
 }
 
+// DigestSearchRepo ищет по текстам постов из доставленных пользователю дайджестов за последние days дней.
+type DigestSearchRepo interface {
+	SearchUserDigestPosts(ctx context.Context, userID int64, query string, days, limit, offset int) ([]DigestSearchHit, error)
+}
+
+// ErrMTProtoAccountNotFound возвращается, если аккаунта нет в пуле.
+var ErrMTProtoAccountNotFound = errors.New("MTProto-аккаунт не найден")
+
+// MTProtoAccountRepo даёт операторам управлять пулом MTProto-аккаунтов.
+type MTProtoAccountRepo interface {
+	ListMTProtoAccounts(ctx context.Context, pool string) ([]MTProtoAccount, error)
+	SetMTProtoAccountEnabled(ctx context.Context, pool, name string, enabled bool) error
+	// ListMTProtoPools возвращает все пулы, в которых есть аккаунты или закреплённые каналы.
+	ListMTProtoPools(ctx context.Context) ([]MTProtoPoolStats, error)
+	// SetChannelMTProtoPool закрепляет канал за пулом; пустой pool возвращает пул по умолчанию.
+	SetChannelMTProtoPool(ctx context.Context, alias, pool string) error
+}
+
+// ErrCollectorUnavailable возвращается, когда сбор временно отключён из-за отказа всего пула MTProto.
+var ErrCollectorUnavailable = errors.New("сбор постов временно недоступен")
+
+// ErrNoAvailableAccounts возвращается, когда в пуле MTProto не осталось рабочих аккаунтов:
+// пул пуст или все сессии отозваны. Аккаунты могут вернуться, поэтому задачу стоит повторить.
+var ErrNoAvailableAccounts = errors.New("нет доступных MTProto аккаунтов")
+
+// ErrUserNotFound возвращается, если пользователь ещё не запускал бота.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrChannelNotFound возвращается, если канал не найден.
+var ErrChannelNotFound = errors.New("канал не найден")
+
+// ErrPostNotFound возвращается, если пост не найден.
+var ErrPostNotFound = errors.New("пост не найден")
+
+// ChannelCollectionRepo хранит время последнего успешного сбора постов канала.
+type ChannelCollectionRepo interface {
+	MarkChannelCollected(channelID int64, collectedAt time.Time, lastMsgID int64) error
+	ListChannelCollections(channelIDs []int64) (map[int64]time.Time, error)
+}
+
+// ErrDigestNotFound возвращается, если дайджест не найден или принадлежит другому пользователю.
+var ErrDigestNotFound = errors.New("дайджест не найден")
+
+// DigestHistoryDays — за сколько дней доступны прошлые дайджесты для /resend;
+// их посты не удаляются очисткой по сроку хранения.
+const DigestHistoryDays = 14
+
 // DigestRepo сохраняет и возвращает дайджесты.
 type DigestRepo interface {
 	CreateDigest(digest Digest) (Digest, error)
-	MarkDelivered(userID int64, date time.Time) error
+	MarkDelivered(digestID int64) error
 	WasDelivered(userID int64, date time.Time) (bool, error)
 	ListDigestHistory(userID int64, fromDate time.Time) ([]Digest, error)
+	GetDigestWithItems(digestID, userID int64) (Digest, error)
+	// DigestChannelBreakdown возвращает вклад каждого канала в дайджест: все подписки
+	// пользователя, включая не давшие ни одного пункта, и каналы, от которых он уже отписался.
+	DigestChannelBreakdown(digestID int64) ([]DigestChannelShare, error)
+	// SnoozeDigest увеличивает счётчик откладываний; false — лимит maxSnoozes исчерпан.
+	SnoozeDigest(digestID, userID int64, maxSnoozes int) (bool, error)
 }
 
 // Cache используется для простых TTL-хранилищ.
@@ -87,6 +242,29 @@ type Cache interface {
 	Get(key string) ([]byte, error)
 }
 
+// SeenStore запоминает ключи на время TTL, чтобы не обрабатывать повторы.
+type SeenStore interface {
+	// MarkSeen возвращает true, если ключ встретился впервые за TTL.
+	MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Seen сообщает, запомнен ли ключ, не запоминая его.
+	Seen(ctx context.Context, key string) (bool, error)
+}
+
+// PlanActivationRepo гарантирует выдачу оплаченного тарифа даже при сбое после списания.
+type PlanActivationRepo interface {
+	RecordPlanActivation(userID, paymentID int64, role UserRole) error
+	// ActivatePlan применяет тариф; false означает, что активация уже была выполнена ранее.
+	ActivatePlan(paymentID int64) (bool, error)
+	MarkPlanActivationFailed(paymentID int64, reason string) error
+	ListPendingPlanActivations(limit int) ([]PlanActivation, error)
+	// RecordPlanCharge фиксирует покупку тарифа до списания.
+	RecordPlanCharge(chargeKey string, userID int64, role UserRole) error
+	// ResolvePlanCharge снимает покупку со сверки: активация записана или списания не было.
+	ResolvePlanCharge(chargeKey string) error
+	// ListPlanCharges возвращает несверенные покупки, начатые раньше before.
+	ListPlanCharges(before time.Time, limit int) ([]PlanCharge, error)
+}
+
 // FeedbackRepo сохраняет отзывы пользователей.
 type FeedbackRepo interface {
 	SaveFeedback(ctx context.Context, feedback Feedback) error
@@ -2,6 +2,9 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,19 @@ const (
 	DigestCauseManual DigestJobCause = "manual"
 	// DigestCauseScheduled — дайджест запланирован по расписанию.
 	DigestCauseScheduled DigestJobCause = "scheduled"
+	// DigestCauseSnoozed — повторная отправка сохранённого дайджеста, отложенного пользователем.
+	DigestCauseSnoozed DigestJobCause = "snoozed"
+	// DigestCauseCollectOnly — только сбор постов каналов пользователя заранее, без сборки и отправки.
+	DigestCauseCollectOnly DigestJobCause = "collect_only"
+	// DigestCausePeek — пробный дайджест канала Alias, который пользователь ещё не добавил.
+	DigestCausePeek DigestJobCause = "peek"
+)
+
+const (
+	// DigestSnoozeDelay — на сколько откладывается дайджест кнопкой «Напомнить».
+	DigestSnoozeDelay = 2 * time.Hour
+	// MaxDigestSnoozes — сколько раз можно отложить один дайджест.
+	MaxDigestSnoozes = 2
 )
 
 // DigestJob содержит информацию о задаче построения дайджеста.
@@ -25,6 +41,60 @@ type DigestJob struct {
 	Date        time.Time      `json:"date"`
 	RequestedAt time.Time      `json:"requested_at"`
 	Cause       DigestJobCause `json:"cause"`
+	// DigestID — сохранённый дайджест, который нужно отправить повторно без новой сборки.
+	DigestID int64 `json:"digest_id,omitempty"`
+	// PastDay — дайджест за прошедший календарный день Date в часовом поясе пользователя,
+	// а не за 24 часа до Date.
+	PastDay bool `json:"past_day,omitempty"`
+	// Alias — канал пробного дайджеста (DigestCausePeek).
+	Alias string `json:"alias,omitempty"`
+}
+
+// PastDayWindow возвращает границы календарного дня Date в часовом поясе loc.
+func (j DigestJob) PastDayWindow(loc *time.Location) (since, until time.Time) {
+	year, month, day := j.Date.Date()
+	since = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return since, since.AddDate(0, 0, 1)
+}
+
+// DigestScope — под каким Digest.Scope сохраняется дайджест задачи.
+func (j DigestJob) DigestScope() string {
+	switch {
+	case j.ChannelID > 0:
+		return fmt.Sprintf("channel:%d", j.ChannelID)
+	case len(j.Tags) > 0:
+		return "tags:" + strings.Join(normalizedTags(j.Tags), ",")
+	case j.PastDay:
+		return "day"
+	}
+	return ""
+}
+
+func normalizedTags(raw []string) []string {
+	tags := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// DeliveryKey — ключ отправки дайджеста задачи по транспорту transport ("telegram", "email").
+// Повторные доставки задачи из очереди дают тот же ключ, а осознанные повторы
+// (/digest_now несколько раз за день, /force_schedule) различаются временем запроса.
+func (j DigestJob) DeliveryKey(transport string) string {
+	return fmt.Sprintf("%d|%s|ch:%d|tags:%s|digest:%d|%s|%s|%s",
+		j.UserTGID, j.Cause, j.ChannelID, strings.Join(normalizedTags(j.Tags), ","), j.DigestID,
+		j.Date.UTC().Format("2006-01-02"), j.RequestedAt.UTC().Format(time.RFC3339Nano), transport)
+}
+
+// DigestDeliveryRepo помнит отправленные пользователю дайджесты независимо от статуса задачи,
+// чтобы повтор задачи после успешной отправки не прислал дайджест ещё раз.
+type DigestDeliveryRepo interface {
+	WasDigestSent(ctx context.Context, key string) (bool, error)
+	MarkDigestSent(ctx context.Context, userID int64, key string) error
 }
 
 // DigestQueue описывает очередь задач на построение дайджестов.
@@ -33,14 +103,27 @@ type DigestQueue interface {
 	Receive(ctx context.Context) (DigestJob, DigestAckFunc, error)
 }
 
+// DelayedDigestQueue умеет публиковать задачи с отложенным выполнением.
+type DelayedDigestQueue interface {
+	EnqueueDelayed(ctx context.Context, job DigestJob, delay time.Duration) error
+}
+
+// DigestQueueStats сообщает глубину очереди задач: сколько задач ждут обработки
+// и сколько отложены до повтора.
+type DigestQueueStats interface {
+	Depth(ctx context.Context) (ready, delayed int, err error)
+}
+
 // DigestAckFunc подтверждает успешную обработку или запрашивает повтор доставки задачи.
 type DigestAckFunc func(success bool) error
 
 // ScheduleTaskRepo отвечает за идемпотентное планирование задач дайджеста.
 type ScheduleTaskRepo interface {
-	// Acquire помечает выполнение задачи на указанное время и возвращает true,
+	// AcquireScheduleTask помечает выполнение задачи на указанное время и возвращает true,
 	// если запись была создана. При конфликте возвращает false без ошибки.
-	Acquire(userID int64, scheduledFor time.Time) (bool, error)
+	AcquireScheduleTask(userID int64, scheduledFor time.Time) (bool, error)
+	// ReleaseScheduleTask удаляет отметку, чтобы задачу на это время можно было поставить снова.
+	ReleaseScheduleTask(userID int64, scheduledFor time.Time) error
 }
 
 // DigestJobStatusRepo отвечает за отслеживание статуса доставки задач дайджеста.
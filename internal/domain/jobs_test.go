@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestJobDeliveryKey(t *testing.T) {
+	requested := time.Date(2024, 5, 10, 9, 0, 0, 123, time.UTC)
+	job := DigestJob{ID: "a", UserTGID: 42, Cause: DigestCauseManual, Tags: []string{"News", " tech "}, Date: requested, RequestedAt: requested}
+
+	redelivered := job
+	redelivered.ID = "b"
+	redelivered.Tags = []string{"tech", "news"}
+	if job.DeliveryKey("telegram") != redelivered.DeliveryKey("telegram") {
+		t.Fatalf("redelivery of the same request must share the key")
+	}
+
+	again := job
+	again.RequestedAt = requested.Add(time.Minute)
+	if job.DeliveryKey("telegram") == again.DeliveryKey("telegram") {
+		t.Fatalf("a new manual request must get its own key")
+	}
+	if job.DeliveryKey("telegram") == job.DeliveryKey("email") {
+		t.Fatalf("transports must be tracked separately")
+	}
+}
+
+func TestDigestJobDigestScope(t *testing.T) {
+	cases := map[string]DigestJob{
+		"":           {Cause: DigestCauseScheduled},
+		"channel:7":  {ChannelID: 7, Tags: []string{"news"}},
+		"tags:ai,go": {Tags: []string{" Go", "ai", ""}},
+		"day":        {PastDay: true},
+	}
+	for want, job := range cases {
+		if got := job.DigestScope(); got != want {
+			t.Fatalf("DigestScope(%+v) = %q, want %q", job, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "encoding/json"
+
+// postMetaLangKey — ключ в raw_meta_json поста с определённым языком текста.
+const postMetaLangKey = "lang"
+
+// Lang возвращает сохранённый язык поста. Пустая строка при ok=true означает,
+// что язык уже определяли, но не распознали.
+func (p Post) Lang() (string, bool) {
+	if len(p.RawMetaJSON) == 0 {
+		return "", false
+	}
+	var meta map[string]json.RawMessage
+	if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+		return "", false
+	}
+	raw, ok := meta[postMetaLangKey]
+	if !ok {
+		return "", false
+	}
+	var lang string
+	if err := json.Unmarshal(raw, &lang); err != nil {
+		return "", false
+	}
+	return lang, true
+}
+
+// WithLang возвращает копию поста, в мете которого сохранён язык.
+func (p Post) WithLang(lang string) Post {
+	meta := map[string]json.RawMessage{}
+	if len(p.RawMetaJSON) > 0 {
+		if err := json.Unmarshal(p.RawMetaJSON, &meta); err != nil {
+			return p
+		}
+	}
+	encoded, err := json.Marshal(lang)
+	if err != nil {
+		return p
+	}
+	meta[postMetaLangKey] = encoded
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return p
+	}
+	p.RawMetaJSON = raw
+	return p
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// PlanActivation — оплаченный тариф, который ещё нужно (или уже удалось) выдать пользователю.
+// Ключ идемпотентности — идентификатор платежа в биллинге.
+type PlanActivation struct {
+	PaymentID   int64
+	UserID      int64
+	TGUserID    int64
+	Role        UserRole
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+	ActivatedAt *time.Time
+}
+
+// PlanCharge — покупка тарифа, записанная до списания. Ключ — идемпотентный ключ списания:
+// по нему сверка находит платёж в биллинге, если ответ на списание потерялся или активацию
+// не удалось сохранить.
+type PlanCharge struct {
+	ChargeKey string
+	UserID    int64
+	Role      UserRole
+	CreatedAt time.Time
+}
@@ -0,0 +1,14 @@
+package domain
+
+// PostTextTruncRunes — сколько символов поста хранится в text_trunc. Суммаризатор всё равно
+// читает не больше, а полный текст для «Подробнее» лежит в text_full.
+const PostTextTruncRunes = 2000
+
+// TruncatePostText обрезает текст поста до PostTextTruncRunes символов.
+func TruncatePostText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= PostTextTruncRunes {
+		return text
+	}
+	return string(runes[:PostTextTruncRunes])
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncatePostText(t *testing.T) {
+	short := "короткий пост"
+	if got := TruncatePostText(short); got != short {
+		t.Fatalf("expected short text unchanged, got %q", got)
+	}
+	long := strings.Repeat("я", PostTextTruncRunes+10)
+	got := TruncatePostText(long)
+	if utf8.RuneCountInString(got) != PostTextTruncRunes || !utf8.ValidString(got) {
+		t.Fatalf("expected %d valid runes, got %d", PostTextTruncRunes, utf8.RuneCountInString(got))
+	}
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// PromoKind — что даёт промокод.
+type PromoKind string
+
+const (
+	// PromoKindBalance зачисляет Value копеек на баланс.
+	PromoKindBalance PromoKind = "balance"
+	// PromoKindPlan зарезервирован под тариф Plan на Value дней. Пока тарифы не истекают,
+	// такие промокоды не создаются и не активируются.
+	PromoKindPlan PromoKind = "plan"
+)
+
+var (
+	// ErrPromoNotFound возвращается для неизвестного промокода.
+	ErrPromoNotFound = errors.New("промокод не найден")
+	// ErrPromoExpired возвращается, если срок действия промокода истёк.
+	ErrPromoExpired = errors.New("срок действия промокода истёк")
+	// ErrPromoExhausted возвращается, если промокод активировали максимальное число раз.
+	ErrPromoExhausted = errors.New("промокод закончился")
+	// ErrPromoAlreadyUsed возвращается при повторной активации тем же пользователем.
+	ErrPromoAlreadyUsed = errors.New("промокод уже активирован")
+	// ErrPromoPlanUnsupported возвращается для тарифных промокодов, пока у тарифов нет срока.
+	ErrPromoPlanUnsupported = errors.New("тарифные промокоды не поддерживаются")
+	// ErrPromoExists возвращается при создании промокода с занятым кодом.
+	ErrPromoExists = errors.New("промокод уже существует")
+)
+
+// PromoCode описывает промокод маркетинговой кампании.
+type PromoCode struct {
+	Code      string
+	Kind      PromoKind
+	Value     int64
+	Plan      UserRole
+	MaxUses   int
+	Used      int
+	ExpiresAt *time.Time
+	CreatedBy int64
+	CreatedAt time.Time
+}
+
+// PromoRedemption — результат активации промокода пользователем.
+type PromoRedemption struct {
+	Promo PromoCode
+	// Pending — зачисление ещё не подтверждено биллингом, повтор /promo его довершит.
+	Pending bool
+}
+
+// PromoRepo хранит промокоды и их активации.
+type PromoRepo interface {
+	CreatePromoCode(ctx context.Context, promo PromoCode) error
+	// RedeemPromoCode атомарно проверяет срок и лимиты и фиксирует активацию пользователем.
+	// Незавершённое зачисление баланса возвращается повторно с Pending, чтобы его можно было
+	// довести до конца.
+	RedeemPromoCode(ctx context.Context, code string, userID int64, now time.Time) (PromoRedemption, error)
+	// MarkPromoApplied отмечает, что биллинг зачислил бонус по промокоду.
+	MarkPromoApplied(ctx context.Context, code string, userID int64) error
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrReferralNotFlagged возвращается, если приглашение не ждёт ручной проверки.
+var ErrReferralNotFlagged = errors.New("приглашение не ждёт проверки")
+
+// ReferralStatus описывает, засчитано ли приглашение пригласившему.
+type ReferralStatus string
+
+const (
+	// ReferralStatusCounted — приглашение засчитано и учитывается в прогрессе тарифа.
+	ReferralStatusCounted ReferralStatus = "counted"
+	// ReferralStatusFlagged — приглашение выглядит как накрутка и ждёт ручной проверки.
+	ReferralStatusFlagged ReferralStatus = "flagged"
+)
+
+// FlaggedReferral — приглашение, отложенное до ручной проверки.
+type FlaggedReferral struct {
+	ReferredTGUserID int64
+	ReferrerTGUserID int64
+	IsBot            bool
+	CreatedAt        time.Time
+}
+
+// ReferralSignals — данные о приглашённом аккаунте и недавних приглашениях пригласившего.
+type ReferralSignals struct {
+	TGUserID int64
+	IsBot    bool
+	// RecentFresh — сколько свежих аккаунтов пригласивший уже привёл за окно проверки.
+	RecentFresh int
+}
+
+// ReferralGuard задаёт эвристики против накрутки рефералов одноразовыми аккаунтами.
+// Нулевое значение выключает проверку, и все приглашения засчитываются сразу.
+type ReferralGuard struct {
+	Enabled bool
+	// Window — за какой период считать приглашения свежих аккаунтов.
+	Window time.Duration
+	// BurstLimit — сколько свежих аккаунтов за окно допускается без ручной проверки.
+	BurstLimit int
+	// FreshTGUserID — Telegram ID, начиная с которого аккаунт считается недавно созданным:
+	// ID выдаются по возрастанию, поэтому это грубая оценка возраста аккаунта.
+	FreshTGUserID int64
+}
+
+// IsFresh сообщает, считается ли аккаунт недавно созданным.
+func (g ReferralGuard) IsFresh(tgUserID int64) bool {
+	return g.FreshTGUserID > 0 && tgUserID >= g.FreshTGUserID
+}
+
+// NeedsHistory сообщает, нужно ли для решения смотреть недавние приглашения.
+// Старые аккаунты засчитываются без дополнительных запросов.
+func (g ReferralGuard) NeedsHistory(s ReferralSignals) bool {
+	return g.Enabled && !s.IsBot && g.BurstLimit > 0 && g.IsFresh(s.TGUserID)
+}
+
+// Assess решает, засчитать приглашение сразу или отправить на ручную проверку.
+func (g ReferralGuard) Assess(s ReferralSignals) ReferralStatus {
+	if !g.Enabled {
+		return ReferralStatusCounted
+	}
+	if s.IsBot {
+		return ReferralStatusFlagged
+	}
+	if g.BurstLimit > 0 && g.IsFresh(s.TGUserID) && s.RecentFresh >= g.BurstLimit {
+		return ReferralStatusFlagged
+	}
+	return ReferralStatusCounted
+}
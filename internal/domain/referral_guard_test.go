@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReferralGuardAssess(t *testing.T) {
+	guard := ReferralGuard{Enabled: true, Window: 24 * time.Hour, BurstLimit: 3, FreshTGUserID: 7_000_000_000}
+	tests := []struct {
+		name    string
+		guard   ReferralGuard
+		signals ReferralSignals
+		want    ReferralStatus
+	}{
+		{name: "disabled guard counts bots", guard: ReferralGuard{}, signals: ReferralSignals{IsBot: true}, want: ReferralStatusCounted},
+		{name: "bot account flagged", guard: guard, signals: ReferralSignals{TGUserID: 100, IsBot: true}, want: ReferralStatusFlagged},
+		{name: "old account ignores burst", guard: guard, signals: ReferralSignals{TGUserID: 100, RecentFresh: 10}, want: ReferralStatusCounted},
+		{name: "fresh account below limit", guard: guard, signals: ReferralSignals{TGUserID: 7_100_000_000, RecentFresh: 2}, want: ReferralStatusCounted},
+		{name: "fresh account at limit", guard: guard, signals: ReferralSignals{TGUserID: 7_100_000_000, RecentFresh: 3}, want: ReferralStatusFlagged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.guard.Assess(tt.signals); got != tt.want {
+				t.Fatalf("Assess(%+v) = %v, want %v", tt.signals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferralGuardNeedsHistory(t *testing.T) {
+	guard := ReferralGuard{Enabled: true, BurstLimit: 3, FreshTGUserID: 7_000_000_000}
+	if guard.NeedsHistory(ReferralSignals{TGUserID: 100}) {
+		t.Fatalf("old accounts should skip the history lookup")
+	}
+	if !guard.NeedsHistory(ReferralSignals{TGUserID: 7_000_000_000}) {
+		t.Fatalf("fresh accounts should check recent referrals")
+	}
+	if (ReferralGuard{}).NeedsHistory(ReferralSignals{TGUserID: 7_000_000_000}) {
+		t.Fatalf("disabled guard should never query history")
+	}
+}
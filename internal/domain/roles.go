@@ -1,6 +1,9 @@
 package domain
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // UserRole описывает тариф пользователя.
 type UserRole string
@@ -19,6 +22,11 @@ type UserPlan struct {
 	ChannelLimit     int
 	ManualDailyLimit int
 	ManualIntroTotal int
+	DigestItemLimit  int
+	// Comments — доступен ли сбор комментариев из обсуждений каналов.
+	Comments bool
+	// PastDigestDays — за сколько прошедших дней можно собрать дайджест через /digest_date; 0 — недоступно.
+	PastDigestDays int
 }
 
 var plans = map[UserRole]UserPlan{
@@ -28,28 +36,38 @@ var plans = map[UserRole]UserPlan{
 		ChannelLimit:     3,
 		ManualDailyLimit: 1,
 		ManualIntroTotal: 10,
+		DigestItemLimit:  5,
 	},
 	UserRolePlus: {
 		Role:             UserRolePlus,
 		Name:             "Plus",
 		ChannelLimit:     10,
 		ManualDailyLimit: 3,
+		DigestItemLimit:  10,
+		PastDigestDays:   2,
 	},
 	UserRolePro: {
 		Role:             UserRolePro,
 		Name:             "Pro",
 		ChannelLimit:     15,
 		ManualDailyLimit: 6,
+		DigestItemLimit:  20,
+		Comments:         true,
+		PastDigestDays:   3,
 	},
 	UserRoleDeveloper: {
 		Role:             UserRoleDeveloper,
 		Name:             "Developer",
 		ChannelLimit:     0,
 		ManualDailyLimit: 0,
+		DigestItemLimit:  0,
+		Comments:         true,
+		PastDigestDays:   7,
 	},
 }
 
-const (
+// Пороги приглашений для апгрейда; меняются только при старте через SetReferralTiers.
+var (
 	referralsForPlus = 3
 	referralsForPro  = 5
 )
@@ -59,6 +77,26 @@ func ReferralProgressTargets() (int, int) {
 	return referralsForPlus, referralsForPro
 }
 
+// SetReferralTiers задаёт пороги приглашений для Plus и Pro. Пороги должны расти:
+// 0 < plus < pro, иначе значения не меняются и возвращается ошибка.
+func SetReferralTiers(plus, pro int) error {
+	if plus <= 0 || pro <= plus {
+		return fmt.Errorf("некорректные пороги рефералов: plus=%d, pro=%d (нужно 0 < plus < pro)", plus, pro)
+	}
+	referralsForPlus = plus
+	referralsForPro = pro
+	return nil
+}
+
+// ParseUserRole разбирает имя тарифа без учёта регистра и пробелов.
+func ParseUserRole(value string) (UserRole, bool) {
+	role := UserRole(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := plans[role]; !ok {
+		return "", false
+	}
+	return role, true
+}
+
 // PlanForRole возвращает тариф для роли.
 func PlanForRole(role UserRole) UserPlan {
 	if plan, ok := plans[UserRole(strings.ToLower(string(role)))]; ok {
@@ -72,6 +110,41 @@ func (u User) Plan() UserPlan {
 	return PlanForRole(u.Role)
 }
 
+// ChannelLimit возвращает эффективный лимит каналов с учётом индивидуального override.
+// Ноль означает отсутствие ограничений.
+func (u User) ChannelLimit() int {
+	if u.ChannelLimitOverride != nil {
+		return *u.ChannelLimitOverride
+	}
+	return u.Plan().ChannelLimit
+}
+
+// DigestItems возвращает количество пунктов дайджеста для тарифа с учётом глобального потолка.
+// Ноль означает отсутствие ограничений.
+func (p UserPlan) DigestItems(ceiling int) int {
+	limit := p.DigestItemLimit
+	if ceiling > 0 && (limit <= 0 || limit > ceiling) {
+		limit = ceiling
+	}
+	return limit
+}
+
+// RolePriority упорядочивает тарифы: чем больше значение, тем шире возможности.
+func RolePriority(role UserRole) int {
+	switch role {
+	case UserRoleFree:
+		return 0
+	case UserRolePlus:
+		return 1
+	case UserRolePro:
+		return 2
+	case UserRoleDeveloper:
+		return 3
+	default:
+		return -1
+	}
+}
+
 // RoleForReferralProgress возвращает новую роль с учётом количества приглашённых друзей.
 func RoleForReferralProgress(current UserRole, referrals int) UserRole {
 	switch current {
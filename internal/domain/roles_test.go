@@ -24,3 +24,106 @@ func TestRoleForReferralProgress(t *testing.T) {
 		})
 	}
 }
+
+func TestUserPlanDigestItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    UserRole
+		ceiling int
+		want    int
+	}{
+		{name: "free below ceiling", role: UserRoleFree, ceiling: 10, want: 5},
+		{name: "pro clamped by ceiling", role: UserRolePro, ceiling: 10, want: 10},
+		{name: "pro without ceiling", role: UserRolePro, ceiling: 0, want: 20},
+		{name: "developer uses ceiling", role: UserRoleDeveloper, ceiling: 15, want: 15},
+		{name: "developer unlimited", role: UserRoleDeveloper, ceiling: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlanForRole(tt.role).DigestItems(tt.ceiling); got != tt.want {
+				t.Fatalf("DigestItems(%d) for %v = %d, want %d", tt.ceiling, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserChannelLimit(t *testing.T) {
+	zero, twenty := 0, 20
+	cases := []struct {
+		name string
+		user User
+		want int
+	}{
+		{name: "plan limit", user: User{Role: UserRoleFree}, want: 3},
+		{name: "override", user: User{Role: UserRoleFree, ChannelLimitOverride: &twenty}, want: 20},
+		{name: "override unlimited", user: User{Role: UserRolePlus, ChannelLimitOverride: &zero}, want: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.user.ChannelLimit(); got != tc.want {
+				t.Fatalf("ChannelLimit() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUserDeliveryChannels(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     User
+		email    bool
+		telegram bool
+	}{
+		{name: "default", user: User{}, email: false, telegram: true},
+		{name: "email only", user: User{Email: "a@b.c", EmailVerified: true, DeliveryMode: DeliveryEmail}, email: true, telegram: false},
+		{name: "both", user: User{Email: "a@b.c", EmailVerified: true, DeliveryMode: DeliveryBoth}, email: true, telegram: true},
+		{name: "unverified email only", user: User{Email: "a@b.c", DeliveryMode: DeliveryEmail}, email: false, telegram: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.user.DeliverByEmail(); got != tc.email {
+				t.Fatalf("DeliverByEmail() = %v, want %v", got, tc.email)
+			}
+			if got := tc.user.DeliverByTelegram(); got != tc.telegram {
+				t.Fatalf("DeliverByTelegram() = %v, want %v", got, tc.telegram)
+			}
+		})
+	}
+}
+
+func TestSetReferralTiers(t *testing.T) {
+	plus, pro := ReferralProgressTargets()
+	t.Cleanup(func() {
+		if err := SetReferralTiers(plus, pro); err != nil {
+			t.Fatalf("restore tiers: %v", err)
+		}
+	})
+
+	for _, tiers := range [][2]int{{0, 5}, {3, 3}, {5, 2}, {-1, 4}} {
+		if err := SetReferralTiers(tiers[0], tiers[1]); err == nil {
+			t.Fatalf("SetReferralTiers(%d, %d) expected error", tiers[0], tiers[1])
+		}
+	}
+	if gotPlus, gotPro := ReferralProgressTargets(); gotPlus != plus || gotPro != pro {
+		t.Fatalf("invalid tiers must not change targets, got %d/%d", gotPlus, gotPro)
+	}
+
+	if err := SetReferralTiers(2, 4); err != nil {
+		t.Fatalf("SetReferralTiers(2, 4): %v", err)
+	}
+	if got := RoleForReferralProgress(UserRoleFree, 2); got != UserRolePlus {
+		t.Fatalf("2 referrals with plus=2: got %v, want plus", got)
+	}
+	if got := RoleForReferralProgress(UserRolePlus, 4); got != UserRolePro {
+		t.Fatalf("4 referrals with pro=4: got %v, want pro", got)
+	}
+}
+
+func TestParseUserRole(t *testing.T) {
+	if role, ok := ParseUserRole(" Plus "); !ok || role != UserRolePlus {
+		t.Fatalf("expected plus, got %q/%v", role, ok)
+	}
+	if _, ok := ParseUserRole("gold"); ok {
+		t.Fatalf("unknown plan must be rejected")
+	}
+}
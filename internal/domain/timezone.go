@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Часовой пояс пользователей, которые не выбрали свой; меняется только при старте через SetDefaultTimezone.
+var defaultTimezone = "Europe/Moscow"
+
+// DefaultTimezone возвращает часовой пояс по умолчанию.
+func DefaultTimezone() string {
+	return defaultTimezone
+}
+
+// SetDefaultTimezone задаёт часовой пояс по умолчанию. Неизвестный пояс не применяется.
+func SetDefaultTimezone(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("часовой пояс по умолчанию не задан")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("неизвестный часовой пояс по умолчанию %q: %w", name, err)
+	}
+	defaultTimezone = name
+	return nil
+}
+
+// TimezoneName возвращает часовой пояс пользователя, а если он не выбран — пояс по умолчанию.
+func (u User) TimezoneName() string {
+	if tz := strings.TrimSpace(u.Timezone); tz != "" {
+		return tz
+	}
+	return defaultTimezone
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LLMUsage — токены LLM и их стоимость в долларах.
+type LLMUsage struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	CostUSD          float64
+}
+
+// TotalTokens возвращает сумму входящих и исходящих токенов.
+func (u LLMUsage) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UserUsage — расход LLM пользователя за календарный месяц (UTC).
+type UserUsage struct {
+	UserID int64
+	Month  time.Time
+	LLMUsage
+	// CapNotifiedAt — когда пользователя предупредили об исчерпании лимита в этом месяце.
+	CapNotifiedAt *time.Time
+}
+
+// UsageMonth возвращает первое число месяца t в UTC — ключ помесячного учёта.
+func UsageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// UsageRepo хранит помесячный расход LLM пользователей. Новый месяц начинается с нуля.
+type UsageRepo interface {
+	AddUsage(ctx context.Context, userID int64, month time.Time, usage LLMUsage) error
+	// GetUsage возвращает расход за месяц; если записей нет — нулевой расход без ошибки.
+	GetUsage(ctx context.Context, userID int64, month time.Time) (UserUsage, error)
+	// MarkUsageCapNotified отмечает предупреждение о лимите; false — в этом месяце уже предупреждали.
+	MarkUsageCapNotified(ctx context.Context, userID int64, month time.Time) (bool, error)
+}
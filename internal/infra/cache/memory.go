@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepEvery — через сколько вставок вычищать истёкшие ключи.
+const memorySweepEvery = 1024
+
+// MemorySeen реализует domain.SeenStore в памяти процесса. Подходит для одного инстанса.
+type MemorySeen struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	expires map[string]time.Time
+	inserts int
+}
+
+// NewMemorySeen создаёт хранилище в памяти.
+func NewMemorySeen() *MemorySeen {
+	return &MemorySeen{now: time.Now, expires: make(map[string]time.Time)}
+}
+
+// MarkSeen запоминает ключ на ttl и сообщает, встретился ли он впервые.
+func (m *MemorySeen) MarkSeen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if exp, ok := m.expires[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	m.expires[key] = now.Add(ttl)
+	m.inserts++
+	if m.inserts%memorySweepEvery == 0 {
+		for k, exp := range m.expires {
+			if !now.Before(exp) {
+				delete(m.expires, k)
+			}
+		}
+	}
+	return true, nil
+}
+
+// Seen сообщает, запомнен ли ключ и не истёк ли его ttl.
+func (m *MemorySeen) Seen(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exp, ok := m.expires[key]
+	return ok && m.now().Before(exp), nil
+}
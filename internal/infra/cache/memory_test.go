@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemorySeenExpires(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemorySeen()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if first, _ := m.MarkSeen(ctx, "tg:update:1", time.Minute); !first {
+		t.Fatal("ожидали первое появление ключа")
+	}
+	if first, _ := m.MarkSeen(ctx, "tg:update:1", time.Minute); first {
+		t.Fatal("повтор в пределах TTL должен отсекаться")
+	}
+	if first, _ := m.MarkSeen(ctx, "tg:update:2", time.Minute); !first {
+		t.Fatal("другой ключ не должен считаться повтором")
+	}
+	now = now.Add(time.Minute)
+	if first, _ := m.MarkSeen(ctx, "tg:update:1", time.Minute); !first {
+		t.Fatal("после TTL ключ снова считается новым")
+	}
+}
+
+func TestMemorySeenDoesNotMark(t *testing.T) {
+	m := NewMemorySeen()
+	ctx := context.Background()
+	if seen, _ := m.Seen(ctx, "tg:update:1"); seen {
+		t.Fatal("неизвестный ключ не должен считаться увиденным")
+	}
+	if first, _ := m.MarkSeen(ctx, "tg:update:1", time.Minute); !first {
+		t.Fatal("проверка Seen не должна запоминать ключ")
+	}
+	if seen, _ := m.Seen(ctx, "tg:update:1"); !seen {
+		t.Fatal("ожидали запомненный ключ")
+	}
+}
@@ -43,3 +43,14 @@ func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
 func (c *RedisCache) Get(key string) ([]byte, error) {
 	return c.client.Get(context.Background(), key).Bytes()
 }
+
+// MarkSeen помечает ключ через SETNX и сообщает, встретился ли он впервые.
+func (c *RedisCache) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, "1", ttl).Result()
+}
+
+// Seen проверяет ключ через EXISTS.
+func (c *RedisCache) Seen(ctx context.Context, key string) (bool, error) {
+	n, err := c.client.Exists(ctx, key).Result()
+	return n > 0, err
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -12,41 +14,248 @@ type AppConfig struct {
 	AppEnv string `envconfig:"APP_ENV" default:"dev"`
 	TZ     string `envconfig:"TZ" default:"Europe/Amsterdam"`
 	Port   int    `envconfig:"PORT" default:"8080"`
+	// DefaultTimezone — часовой пояс пользователей, которые не выбрали свой.
+	DefaultTimezone string `envconfig:"DEFAULT_TIMEZONE" default:"Europe/Moscow"`
+
+	Log struct {
+		// Format — json для сбора логов или console для чтения глазами.
+		Format string `envconfig:"LOG_FORMAT" default:"json"`
+		// Level — уровень zerolog (debug, info, warn, error); пусто — debug в dev, иначе info.
+		Level string `envconfig:"LOG_LEVEL"`
+	} `envconfig:""`
 
 	Telegram struct {
-		Token      string `envconfig:"TG_BOT_TOKEN"`
+		Token string `envconfig:"TG_BOT_TOKEN"`
+		// Mode — как бот получает апдейты: webhook (HTTP-эндпоинт) или polling (getUpdates без публичного URL).
+		Mode       string `envconfig:"BOT_MODE" default:"webhook"`
 		WebhookURL string `envconfig:"TG_WEBHOOK_URL"`
+		// WebhookSecret передаётся Telegram как secret_token и сверяется в каждом запросе вебхука.
+		WebhookSecret string `envconfig:"TG_WEBHOOK_SECRET"`
+		// UpdateDedupTTL — сколько помнить update_id, чтобы не выполнять повторно доставленные апдейты.
+		UpdateDedupTTL time.Duration `envconfig:"TG_UPDATE_DEDUP_TTL" default:"1h"`
 	} `envconfig:""`
 
 	MTProto struct {
 		SessionName string `envconfig:"MTPROTO_SESSION_NAME" default:"18143729742"`
 		GlobalRPS   int    `envconfig:"MTPROTO_GLOBAL_RPS" default:"20"`
+		// CollectOverlap — на сколько раньше суток начинать сбор, чтобы не терять посты на границе окна.
+		CollectOverlap time.Duration `envconfig:"MTPROTO_COLLECT_OVERLAP" default:"5m"`
+	} `envconfig:""`
+
+	Collect struct {
+		// ChannelTimeout — сколько ждать сбора одного канала на каждом аккаунте пула.
+		ChannelTimeout time.Duration `envconfig:"COLLECT_CHANNEL_TIMEOUT" default:"90s"`
+		// Concurrency — сколько каналов одной задачи собирать параллельно.
+		Concurrency int `envconfig:"COLLECT_CONCURRENCY" default:"1"`
+		// PlanConcurrency переопределяет Concurrency для тарифов, формат plus:2,pro:4.
+		PlanConcurrency map[string]int `envconfig:"COLLECT_CONCURRENCY_BY_PLAN" default:"plus:2,pro:4,developer:4"`
+		// JobTimeout — верхняя граница сбора всех каналов задачи.
+		JobTimeout time.Duration `envconfig:"COLLECT_JOB_TIMEOUT" default:"10m"`
+		// MaxMessages — сколько сообщений одного канала читать за сбор; при превышении остаются
+		// самые свежие, и дайджест ранжирует только их. 0 — без ограничения.
+		MaxMessages int `envconfig:"COLLECT_MAX_MESSAGES" default:"500"`
+		// MaxAttempts — сколько раз обрабатывать задачу дайджеста, прежде чем сдаться.
+		MaxAttempts int `envconfig:"COLLECT_MAX_ATTEMPTS" default:"5"`
+		// ManualMaxAttempts — то же для ручных дайджестов: пользователь ждёт ответа, поэтому
+		// ошибку лучше показать быстрее, чем долго повторять.
+		ManualMaxAttempts int `envconfig:"COLLECT_MANUAL_MAX_ATTEMPTS" default:"2"`
+	} `envconfig:""`
+
+	Retention struct {
+		// Posts — сколько хранить собранные посты; сводки и элементы дайджестов удаляются каскадом.
+		Posts time.Duration `envconfig:"POST_RETENTION" default:"720h"`
+		// BatchSize — сколько постов удалять одним запросом, чтобы не держать долгие блокировки.
+		BatchSize int `envconfig:"POST_PURGE_BATCH" default:"1000"`
+	} `envconfig:""`
+
+	Scheduler struct {
+		// DryRun — планировщик только пишет в лог, кому и на какое время поставил бы дайджест,
+		// не бронируя задачи и ничего не ставя в очередь.
+		DryRun bool `envconfig:"SCHEDULER_DRY_RUN" default:"false"`
+		// Jitter — окно случайной задержки плановых дайджестов, чтобы пользователи с одинаковым
+		// временем не нагружали коллектор в одну минуту. Задержка кратна минуте; 0 — без разброса.
+		Jitter time.Duration `envconfig:"SCHEDULER_JITTER" default:"0s"`
 	} `envconfig:""`
 
 	PGDSN string `envconfig:"PG_DSN"`
 
 	RabbitURL string `envconfig:"RABBITMQ_URL"`
 
+	// RedisURL — общее хранилище для нескольких инстансов; пусто — состояние в памяти процесса.
+	RedisURL string `envconfig:"REDIS_URL"`
+
 	Limits struct {
 		DigestMax int `envconfig:"DIGEST_MAX_ITEMS" default:"10"`
+		// TagsPerChannel и TagLength ограничивают число тегов канала и длину тега в символах.
+		TagsPerChannel int `envconfig:"TAGS_PER_CHANNEL_MAX" default:"10"`
+		TagLength      int `envconfig:"TAG_MAX_LENGTH" default:"32"`
+		// ClearDataWindow — сколько ждём /clear_data_confirm после /clear_data.
+		ClearDataWindow time.Duration `envconfig:"CLEAR_DATA_CONFIRM_WINDOW" default:"5m"`
 	} `envconfig:""`
 
 	Queues struct {
 		Digest string `envconfig:"DIGEST_QUEUE_KEY" default:"digest_jobs"`
 	} `envconfig:""`
 
-	OpenAI struct {
-		APIKey  string        `envconfig:"OPENAI_API_KEY"`
-		BaseURL string        `envconfig:"OPENAI_BASE_URL"`
-		Model   string        `envconfig:"OPENAI_MODEL" default:"qwen3:4b"`
-		Timeout time.Duration `envconfig:"OPENAI_TIMEOUT" default:"1200s"`
+	OpenAI OpenAIConfig `envconfig:""`
+
+	DigestLayout struct {
+		// Header и Footer — шаблоны text/template с полями .Date, .ItemCount и .PlanName.
+		// Footer == nil — стандартный подвал, пустая строка убирает подвал совсем.
+		Header string  `envconfig:"DIGEST_HEADER_TEMPLATE"`
+		Footer *string `envconfig:"DIGEST_FOOTER_TEMPLATE"`
+	} `envconfig:""`
+
+	DigestEmpty struct {
+		// Шаблоны text/template сообщения о пустом дайджесте с полями .NextRun, .Channel и .Muted;
+		// пустая строка — стандартный текст.
+		Scheduled string `envconfig:"DIGEST_EMPTY_SCHEDULED_TEMPLATE"`
+		Manual    string `envconfig:"DIGEST_EMPTY_MANUAL_TEMPLATE"`
+		Channel   string `envconfig:"DIGEST_EMPTY_CHANNEL_TEMPLATE"`
+		Tags      string `envconfig:"DIGEST_EMPTY_TAGS_TEMPLATE"`
+	} `envconfig:""`
+
+	DigestProgress struct {
+		// Plans — тарифы, которым перед долгой сборкой приходит заглушка, заменяемая дайджестом; пусто — выключено.
+		Plans []string `envconfig:"DIGEST_PROGRESS_PLANS"`
+		// MinChannels — с какого числа каналов сборка считается долгой.
+		MinChannels int `envconfig:"DIGEST_PROGRESS_MIN_CHANNELS" default:"3"`
+	} `envconfig:""`
+
+	AdFilter struct {
+		// Patterns — дополнительные регулярные выражения, по одному на строку.
+		Patterns string `envconfig:"AD_FILTER_PATTERNS"`
+		UseLLM   bool   `envconfig:"AD_FILTER_LLM" default:"false"`
+	} `envconfig:""`
+
+	Referrals struct {
+		// Leaderboard включает команду /leaderboard с рейтингом пригласивших.
+		Leaderboard bool `envconfig:"REFERRAL_LEADERBOARD_ENABLED" default:"false"`
+		// PlusTarget и ProTarget — сколько приглашений нужно для апгрейда до Plus и Pro.
+		PlusTarget int `envconfig:"REFERRAL_PLUS_TARGET" default:"3"`
+		ProTarget  int `envconfig:"REFERRAL_PRO_TARGET" default:"5"`
+	} `envconfig:""`
+
+	ReferralGuard struct {
+		// Подозрительные приглашения не засчитываются до ручной проверки.
+		Enabled       bool          `envconfig:"REFERRAL_GUARD_ENABLED" default:"false"`
+		Window        time.Duration `envconfig:"REFERRAL_GUARD_WINDOW" default:"24h"`
+		BurstLimit    int           `envconfig:"REFERRAL_GUARD_BURST_LIMIT" default:"3"`
+		FreshTGUserID int64         `envconfig:"REFERRAL_GUARD_FRESH_TG_ID" default:"7000000000"`
+	} `envconfig:""`
+
+	SMTP struct {
+		Host     string        `envconfig:"SMTP_HOST"`
+		Port     int           `envconfig:"SMTP_PORT" default:"587"`
+		Username string        `envconfig:"SMTP_USERNAME"`
+		Password string        `envconfig:"SMTP_PASSWORD"`
+		From     string        `envconfig:"SMTP_FROM"`
+		Timeout  time.Duration `envconfig:"SMTP_TIMEOUT" default:"15s"`
 	} `envconfig:""`
 
 	Billing struct {
 		BaseURL  string        `envconfig:"BILLING_BASE_URL"`
 		Timeout  time.Duration `envconfig:"BILLING_TIMEOUT" default:"10s"`
 		APIToken string        `envconfig:"BILLING_API_TOKEN"`
+		TopUp    TopUpConfig   `envconfig:""`
 	} `envconfig:""`
+
+	Admin struct {
+		APIToken string `envconfig:"ADMIN_API_TOKEN"`
+	} `envconfig:""`
+}
+
+// TopUpConfig задаёт суммы пополнения баланса в боте, в целых рублях.
+type TopUpConfig struct {
+	// Presets — суммы кнопок быстрого пополнения.
+	Presets []int64 `envconfig:"BILLING_TOPUP_PRESETS" default:"300,500,1000"`
+	Min     int64   `envconfig:"BILLING_TOPUP_MIN" default:"1"`
+	// Max защищает от опечаток вроде лишнего нуля; 0 — без ограничения.
+	Max int64 `envconfig:"BILLING_TOPUP_MAX" default:"100000"`
+	// LowBalance — после списания с остатком ниже этой суммы бот предложит пополнить счёт; 0 — не предупреждать.
+	LowBalance int64 `envconfig:"BILLING_LOW_BALANCE" default:"300"`
+}
+
+// maxTopUpPresets — больше кнопок в один ряд клавиатуры не помещается.
+const maxTopUpPresets = 5
+
+// Validate проверяет, что пресеты лежат между минимумом и максимумом пополнения.
+func (c TopUpConfig) Validate() error {
+	if c.Min < 1 {
+		return fmt.Errorf("BILLING_TOPUP_MIN должен быть не меньше 1, получено %d", c.Min)
+	}
+	if c.Max < 0 || (c.Max > 0 && c.Max < c.Min) {
+		return fmt.Errorf("BILLING_TOPUP_MAX должен быть 0 или не меньше BILLING_TOPUP_MIN, получено %d", c.Max)
+	}
+	if c.LowBalance < 0 {
+		return fmt.Errorf("BILLING_LOW_BALANCE не может быть отрицательным, получено %d", c.LowBalance)
+	}
+	if len(c.Presets) > maxTopUpPresets {
+		return fmt.Errorf("BILLING_TOPUP_PRESETS: не больше %d сумм, получено %d", maxTopUpPresets, len(c.Presets))
+	}
+	for _, preset := range c.Presets {
+		if preset < c.Min || (c.Max > 0 && preset > c.Max) {
+			return fmt.Errorf("BILLING_TOPUP_PRESETS: сумма %d вне диапазона пополнения", preset)
+		}
+	}
+	return nil
+}
+
+// OpenAIConfig описывает доступ к LLM и модели для отдельных задач.
+type OpenAIConfig struct {
+	APIKey  string        `envconfig:"OPENAI_API_KEY"`
+	BaseURL string        `envconfig:"OPENAI_BASE_URL"`
+	Model   string        `envconfig:"OPENAI_MODEL" default:"qwen3:4b"`
+	Timeout time.Duration `envconfig:"OPENAI_TIMEOUT" default:"1200s"`
+
+	// SummaryModel и RankModel переопределяют Model для суммаризации и ранжирования.
+	SummaryModel string `envconfig:"OPENAI_SUMMARY_MODEL"`
+	RankModel    string `envconfig:"OPENAI_RANK_MODEL"`
+	// Пустая температура не передаётся в запрос, и модель использует своё значение по умолчанию.
+	SummaryTemperature *float64 `envconfig:"OPENAI_SUMMARY_TEMPERATURE" default:"0.2"`
+	RankTemperature    *float64 `envconfig:"OPENAI_RANK_TEMPERATURE"`
+
+	// MonthlyTokenCaps — мягкий месячный лимит токенов по тарифам, формат free:200000,plus:1000000.
+	// После него сводки строятся без LLM до начала следующего месяца; тариф без лимита не указывается.
+	// По умолчанию лимитов нет.
+	MonthlyTokenCaps map[string]int64 `envconfig:"OPENAI_MONTHLY_TOKEN_CAP_BY_PLAN"`
+	// Цены в долларах за миллион токенов — только для учёта расхода в user_usage.
+	PromptPricePer1M     float64 `envconfig:"OPENAI_PROMPT_PRICE_PER_1M" default:"0"`
+	CompletionPricePer1M float64 `envconfig:"OPENAI_COMPLETION_PRICE_PER_1M" default:"0"`
+}
+
+// Cost оценивает стоимость токенов в долларах по настроенным ценам.
+func (c OpenAIConfig) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*c.PromptPricePer1M + float64(completionTokens)*c.CompletionPricePer1M) / 1e6
+}
+
+// SummaryModelName возвращает модель для суммаризации, по умолчанию — базовую.
+func (c OpenAIConfig) SummaryModelName() string {
+	if model := strings.TrimSpace(c.SummaryModel); model != "" {
+		return model
+	}
+	return c.Model
+}
+
+// RankModelName возвращает модель для ранжирования, по умолчанию — базовую.
+func (c OpenAIConfig) RankModelName() string {
+	if model := strings.TrimSpace(c.RankModel); model != "" {
+		return model
+	}
+	return c.Model
+}
+
+// Validate проверяет, что температуры лежат в допустимом диапазоне API.
+func (c OpenAIConfig) Validate() error {
+	for name, t := range map[string]*float64{
+		"OPENAI_SUMMARY_TEMPERATURE": c.SummaryTemperature,
+		"OPENAI_RANK_TEMPERATURE":    c.RankTemperature,
+	} {
+		if t != nil && (*t < 0 || *t > 2) {
+			return fmt.Errorf("%s должна быть от 0 до 2, получено %v", name, *t)
+		}
+	}
+	return nil
 }
 
 // Load загружает конфиг из окружения.
@@ -55,5 +264,8 @@ func Load() AppConfig {
 	if err := envconfig.Process("", &cfg); err != nil {
 		log.Fatalf("не удалось загрузить конфиг: %v", err)
 	}
+	if err := cfg.OpenAI.Validate(); err != nil {
+		log.Fatalf("некорректный конфиг OpenAI: %v", err)
+	}
 	return cfg
 }
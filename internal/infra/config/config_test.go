@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+func TestOpenAIModelFallback(t *testing.T) {
+	t.Setenv("OPENAI_MODEL", "base")
+	t.Setenv("OPENAI_RANK_MODEL", "strong")
+
+	var cfg AppConfig
+	if err := envconfig.Process("", &cfg); err != nil {
+		t.Fatalf("не удалось загрузить конфиг: %v", err)
+	}
+	if got := cfg.OpenAI.SummaryModelName(); got != "base" {
+		t.Fatalf("ожидали базовую модель для суммаризации, получили %q", got)
+	}
+	if got := cfg.OpenAI.RankModelName(); got != "strong" {
+		t.Fatalf("ожидали отдельную модель ранжирования, получили %q", got)
+	}
+	if cfg.OpenAI.SummaryTemperature == nil || *cfg.OpenAI.SummaryTemperature != 0.2 {
+		t.Fatalf("ожидали температуру суммаризации по умолчанию 0.2, получили %v", cfg.OpenAI.SummaryTemperature)
+	}
+	if cfg.OpenAI.RankTemperature != nil {
+		t.Fatalf("температура ранжирования по умолчанию не должна передаваться, получили %v", *cfg.OpenAI.RankTemperature)
+	}
+}
+
+func TestOpenAIValidateTemperature(t *testing.T) {
+	bad := 2.5
+	cfg := OpenAIConfig{RankTemperature: &bad}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("ожидали ошибку для температуры вне диапазона")
+	}
+	ok := 0.0
+	cfg = OpenAIConfig{RankTemperature: &ok}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+}
+
+func TestTopUpDefaultsAndValidate(t *testing.T) {
+	var cfg AppConfig
+	if err := envconfig.Process("", &cfg); err != nil {
+		t.Fatalf("не удалось загрузить конфиг: %v", err)
+	}
+	topUp := cfg.Billing.TopUp
+	if len(topUp.Presets) != 3 || topUp.Presets[0] != 300 || topUp.Min != 1 || topUp.Max != 100000 {
+		t.Fatalf("неожиданные значения по умолчанию: %+v", topUp)
+	}
+	if err := topUp.Validate(); err != nil {
+		t.Fatalf("значения по умолчанию должны проходить проверку: %v", err)
+	}
+	for name, bad := range map[string]TopUpConfig{
+		"пресет выше максимума":  {Presets: []int64{500, 5000}, Min: 1, Max: 1000},
+		"пресет ниже минимума":   {Presets: []int64{50}, Min: 100},
+		"максимум ниже минимума": {Min: 100, Max: 10},
+		"нулевой минимум":        {Min: 0},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("%s: ожидали ошибку", name)
+		}
+	}
+	if err := (TopUpConfig{Presets: []int64{1000000}, Min: 1}).Validate(); err != nil {
+		t.Fatalf("без максимума пресет не ограничен: %v", err)
+	}
+}
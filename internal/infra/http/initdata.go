@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 
@@ -26,6 +29,9 @@ func WebAppAuthMiddleware(botToken string) func(http.Handler) http.Handler {
 				http.Error(w, "подпись недействительна", http.StatusUnauthorized)
 				return
 			}
+			if tgUserID, ok := initDataUserID(initData); ok {
+				r = r.WithContext(context.WithValue(r.Context(), webAppUserKey{}, tgUserID))
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -49,6 +55,29 @@ func validateInitData(initData string, secret []byte) bool {
 	return hmac.Equal(calc, expected)
 }
 
+type webAppUserKey struct{}
+
+// WebAppUserID возвращает Telegram ID пользователя из проверенного initData.
+func WebAppUserID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(webAppUserKey{}).(int64)
+	return id, ok
+}
+
+// initDataUserID достаёт id из JSON-поля user в initData.
+func initDataUserID(initData string) (int64, bool) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return 0, false
+	}
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID <= 0 {
+		return 0, false
+	}
+	return user.ID, true
+}
+
 // RequestID возвращает request ID из контекста chi.
 func RequestID(r *http.Request) string {
 	return middleware.GetReqID(r.Context())
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestInitDataUserID(t *testing.T) {
+	initData := "auth_date=1700000000&user=" + url.QueryEscape(`{"id":42,"first_name":"Ann"}`) + "&hash=abc"
+	if id, ok := initDataUserID(initData); !ok || id != 42 {
+		t.Fatalf("ожидали 42, получили %d (ok=%v)", id, ok)
+	}
+	for _, data := range []string{"auth_date=1&hash=abc", "user=%7Bbroken&hash=abc", "user=" + url.QueryEscape(`{"id":0}`)} {
+		if _, ok := initDataUserID(data); ok {
+			t.Fatalf("%q: не ожидали пользователя", data)
+		}
+	}
+}
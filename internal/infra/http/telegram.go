@@ -0,0 +1,24 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// TelegramSecretHeader — заголовок, в котором Telegram присылает secret_token вебхука.
+const TelegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// TelegramWebhookMiddleware пропускает только запросы с secret_token, заданным при
+// регистрации вебхука. Проверка идёт до чтения тела, чтобы чужие апдейты не разбирались.
+func TelegramWebhookMiddleware(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(TelegramSecretHeader)
+			if secret == "" || provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) != 1 {
+				http.Error(w, "недействительный секрет вебхука", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
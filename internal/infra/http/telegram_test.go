@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTelegramWebhookMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	cases := []struct {
+		name     string
+		secret   string
+		provided string
+		want     int
+	}{
+		{"match", "s3cret_token", "s3cret_token", http.StatusOK},
+		{"mismatch", "s3cret_token", "other", http.StatusForbidden},
+		{"missing header", "s3cret_token", "", http.StatusForbidden},
+		{"secret not configured", "", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/bot/webhook", nil)
+		if tc.provided != "" {
+			req.Header.Set(TelegramSecretHeader, tc.provided)
+		}
+		rec := httptest.NewRecorder()
+		TelegramWebhookMiddleware(tc.secret)(next).ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: ожидали %d, получили %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// TokenAuthMiddleware пропускает только запросы с сервисным токеном
+// в заголовке Authorization (Bearer) или X-API-Token.
+func TokenAuthMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := extractToken(r)
+			if token == "" || provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				http.Error(w, "недействительный токен", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func extractToken(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		const bearerPrefix = "Bearer "
+		if strings.HasPrefix(authHeader, bearerPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
+		}
+		return strings.TrimSpace(authHeader)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Token"))
+}
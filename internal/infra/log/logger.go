@@ -1,19 +1,61 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
 )
 
-// NewLogger создаёт настроенный zerolog.
-func NewLogger(appEnv string) zerolog.Logger {
-	level := zerolog.InfoLevel
+// Форматы логов.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// NewLogger создаёт настроенный zerolog и делает его глобальным, чтобы пакетный log.* писал
+// так же. format — json или console; level — уровень zerolog, пустой — debug в dev и info
+// в остальных окружениях. Уровень применяется глобально. При ошибке возвращается логгер
+// с JSON и уровнем по умолчанию, чтобы вызывающий мог о ней написать.
+func NewLogger(appEnv, format, level string) (zerolog.Logger, error) {
+	zerolog.TimeFieldFormat = time.RFC3339
+	lvl, lvlErr := parseLevel(appEnv, level)
+	out, fmtErr := writer(format)
+	zerolog.SetGlobalLevel(lvl)
+	logger := zerolog.New(out).With().Timestamp().Logger()
+	zlog.Logger = logger
+	if lvlErr != nil {
+		return logger, lvlErr
+	}
+	return logger, fmtErr
+}
+
+func parseLevel(appEnv, level string) (zerolog.Level, error) {
+	fallback := zerolog.InfoLevel
 	if appEnv == "dev" {
-		level = zerolog.DebugLevel
+		fallback = zerolog.DebugLevel
+	}
+	if level == "" {
+		return fallback, nil
+	}
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return fallback, fmt.Errorf("некорректный LOG_LEVEL %q: %w", level, err)
+	}
+	return lvl, nil
+}
+
+func writer(format string) (io.Writer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return os.Stdout, nil
+	case FormatConsole:
+		return zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}, nil
+	default:
+		return os.Stdout, fmt.Errorf("некорректный LOG_FORMAT %q: ожидается json или console", format)
 	}
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(level)
-	zerolog.TimeFieldFormat = time.RFC3339
-	return logger
 }
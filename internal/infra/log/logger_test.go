@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		env, level string
+		want       zerolog.Level
+	}{
+		{"dev", "", zerolog.DebugLevel},
+		{"prod", "", zerolog.InfoLevel},
+		{"dev", "WARN", zerolog.WarnLevel},
+		{"prod", "debug", zerolog.DebugLevel},
+	}
+	for _, tc := range cases {
+		got, err := parseLevel(tc.env, tc.level)
+		if err != nil || got != tc.want {
+			t.Fatalf("parseLevel(%q, %q) = %v, %v; ожидали %v", tc.env, tc.level, got, err, tc.want)
+		}
+	}
+	if _, err := parseLevel("prod", "loud"); err == nil {
+		t.Fatalf("ожидали ошибку для неизвестного уровня")
+	}
+}
+
+func TestWriterFormats(t *testing.T) {
+	if _, ok := mustWriter(t, "console").(zerolog.ConsoleWriter); !ok {
+		t.Fatalf("для console ожидали ConsoleWriter")
+	}
+	if _, ok := mustWriter(t, "json").(zerolog.ConsoleWriter); ok {
+		t.Fatalf("для json не ожидали ConsoleWriter")
+	}
+	if _, err := writer("xml"); err == nil {
+		t.Fatalf("ожидали ошибку для неизвестного формата")
+	}
+}
+
+func mustWriter(t *testing.T, format string) any {
+	t.Helper()
+	w, err := writer(format)
+	if err != nil {
+		t.Fatalf("writer(%q): %v", format, err)
+	}
+	return w
+}
+
+func TestNewLoggerAppliesLevelGlobally(t *testing.T) {
+	prev := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(prev) })
+
+	var buf bytes.Buffer
+	component := zerolog.New(&buf)
+
+	if _, err := NewLogger("prod", FormatJSON, "info"); err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	component.Debug().Msg("скрыто")
+	if buf.Len() != 0 {
+		t.Fatalf("info должен скрывать debug, получили %q", buf.String())
+	}
+
+	if _, err := NewLogger("prod", FormatJSON, "debug"); err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	component.Debug().Msg("видно")
+	if !strings.Contains(buf.String(), "видно") {
+		t.Fatalf("debug должен показывать отладочные сообщения, получили %q", buf.String())
+	}
+}
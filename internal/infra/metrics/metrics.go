@@ -30,6 +30,11 @@ var (
 		Name: "bot_send_errors_total",
 		Help: "Ошибки отправки сообщений ботом",
 	})
+	PostsPurged = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "posts_purged_per_run",
+		Help:    "Сколько постов удалено за один проход очистки по сроку хранения",
+		Buckets: prometheus.ExponentialBuckets(1, 10, 7),
+	})
 
 	NetworkRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "network_request_duration_seconds",
@@ -67,6 +72,21 @@ var (
 		Name: "digest_requests_by_channel_total",
 		Help: "Количество запросов на построение дайджеста по каналам",
 	}, []string{"channel_id"})
+
+	MTProtoBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mtproto_breaker_state",
+		Help: "Состояние предохранителя MTProto: 0 — закрыт, 1 — открыт, 2 — полуоткрыт",
+	}, []string{"component"})
+
+	MTProtoBreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mtproto_breaker_transitions_total",
+		Help: "Переходы предохранителя MTProto между состояниями",
+	}, []string{"component", "state"})
+
+	MTProtoPoolExhausted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mtproto_pool_exhausted",
+		Help: "1 — в пуле MTProto не осталось рабочих аккаунтов (критический алерт), 0 — пул работает",
+	}, []string{"component"})
 )
 
 // MustRegister регистрирует метрики.
@@ -76,6 +96,7 @@ func MustRegister(registerer prometheus.Registerer) {
 		CollectorErrors,
 		DigestBuildSeconds,
 		BotSendErrors,
+		PostsPurged,
 		NetworkRequestDuration,
 		NetworkRequestTotal,
 		LLMGenerationDuration,
@@ -83,13 +104,25 @@ func MustRegister(registerer prometheus.Registerer) {
 		DigestRequestsTotal,
 		DigestRequestsByUser,
 		DigestRequestsByChannel,
+		MTProtoBreakerState,
+		MTProtoBreakerTransitions,
+		MTProtoPoolExhausted,
 	)
 }
 
-// StartServer запускает HTTP сервер с эндпоинтом /metrics.
-func StartServer(ctx context.Context, logger zerolog.Logger, addr string) {
+// Route — дополнительный служебный эндпоинт на порту метрик.
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// StartServer запускает HTTP сервер с эндпоинтом /metrics и дополнительными маршрутами routes.
+func StartServer(ctx context.Context, logger zerolog.Logger, addr string, routes ...Route) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	for _, route := range routes {
+		mux.Handle(route.Pattern, route.Handler)
+	}
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      mux,
@@ -173,3 +206,22 @@ func IncDigestForUser(userID int64) {
 func IncDigestForChannel(channelID int64) {
 	DigestRequestsByChannel.WithLabelValues(strconv.FormatInt(channelID, 10)).Inc()
 }
+
+// SetMTProtoBreakerState фиксирует текущее состояние предохранителя MTProto.
+func SetMTProtoBreakerState(component string, state int) {
+	MTProtoBreakerState.WithLabelValues(component).Set(float64(state))
+}
+
+// SetMTProtoPoolExhausted отмечает, остались ли в пуле MTProto рабочие аккаунты.
+func SetMTProtoPoolExhausted(component string, exhausted bool) {
+	value := 0.0
+	if exhausted {
+		value = 1
+	}
+	MTProtoPoolExhausted.WithLabelValues(component).Set(value)
+}
+
+// IncMTProtoBreakerTransition считает переход предохранителя MTProto в состояние state.
+func IncMTProtoBreakerTransition(component, state string) {
+	MTProtoBreakerTransitions.WithLabelValues(component, state).Inc()
+}
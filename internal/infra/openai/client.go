@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"tg-digest-bot/internal/infra/metrics"
@@ -21,6 +22,20 @@ type Client struct {
 	http    *http.Client
 	baseURL string
 	apiKey  string
+
+	promptTokens     atomic.Int64
+	completionTokens atomic.Int64
+}
+
+// Usage — сумма токенов всех ответов клиента с момента создания.
+type Usage struct {
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// Usage возвращает накопленный расход токенов. Разница двух снимков даёт расход за период.
+func (c *Client) Usage() Usage {
+	return Usage{PromptTokens: c.promptTokens.Load(), CompletionTokens: c.completionTokens.Load()}
 }
 
 // NewClient создаёт клиента OpenAI.
@@ -38,13 +53,19 @@ func NewClient(apiKey, baseURL string, timeout time.Duration) *Client {
 
 // ChatCompletionRequest описывает тело запроса.
 type ChatCompletionRequest struct {
-	Model          string                        `json:"model"`
-	Messages       []ChatMessage                 `json:"messages"`
-	Temperature    float64                       `json:"temperature,omitempty"`
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	// Temperature — nil оставляет значение по умолчанию провайдера (некоторые модели не принимают другое).
+	Temperature    *float64                      `json:"temperature,omitempty"`
 	MaxTokens      int                           `json:"max_tokens,omitempty"`
 	ResponseFormat *ChatCompletionResponseFormat `json:"response_format,omitempty"`
 }
 
+// Float64 возвращает указатель на значение, удобно для необязательных параметров запроса.
+func Float64(v float64) *float64 {
+	return &v
+}
+
 // ChatMessage представляет сообщение в диалоге.
 type ChatMessage struct {
 	Role    string `json:"role"`
@@ -141,6 +162,8 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionReq
 	}
 	metrics.ObserveNetworkRequest("openai", "chat_completions", req.Model, start, nil)
 	if completion.Usage != nil {
+		c.promptTokens.Add(int64(completion.Usage.PromptTokens))
+		c.completionTokens.Add(int64(completion.Usage.CompletionTokens))
 		metrics.ObserveLLMGeneration(req.Model, time.Since(start), completion.Usage.PromptTokens, completion.Usage.CompletionTokens, completion.Usage.TotalTokens)
 	}
 	return completion, nil
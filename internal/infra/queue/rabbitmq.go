@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -18,6 +19,17 @@ import (
 const (
 	defaultPrefetch    = 1
 	publishContentType = "application/json"
+	// delayedQueueSuffix — суффикс очереди, где задачи ждут истечения TTL,
+	// после чего RabbitMQ перекладывает их в основную очередь через dead-letter.
+	delayedQueueSuffix = ".delayed"
+	// jitterQueueSuffix — отдельная очередь для коротких случайных задержек плановых задач,
+	// чтобы они не стояли за отложенными на часы дайджестами.
+	jitterQueueSuffix = ".jitter"
+)
+
+var (
+	_ domain.DelayedDigestQueue = (*RabbitDigestQueue)(nil)
+	_ domain.DigestQueueStats   = (*RabbitDigestQueue)(nil)
 )
 
 // RabbitDigestQueue реализует очередь задач через AMQP соединение с RabbitMQ.
@@ -26,6 +38,8 @@ type RabbitDigestQueue struct {
 	channel    *amqp.Channel
 	deliveries <-chan amqp.Delivery
 	queue      string
+	delayed    string
+	jitter     string
 }
 
 // NewRabbitDigestQueue создаёт очередь и настраивает потребителя.
@@ -67,6 +81,26 @@ func NewRabbitDigestQueue(amqpURL, queue string) (*RabbitDigestQueue, error) {
 		return nil, fmt.Errorf("declare queue: %w", err)
 	}
 
+	delayed := queue + delayedQueueSuffix
+	jitter := queue + jitterQueueSuffix
+	for _, name := range []string{delayed, jitter} {
+		if _, err := ch.QueueDeclare(
+			name,
+			true,  // durable
+			false, // auto-delete
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			},
+		); err != nil {
+			ch.Close()
+			conn.Close()
+			return nil, fmt.Errorf("declare delayed queue %s: %w", name, err)
+		}
+	}
+
 	deliveries, err := ch.Consume(
 		queue,
 		"",    // consumer
@@ -87,11 +121,37 @@ func NewRabbitDigestQueue(amqpURL, queue string) (*RabbitDigestQueue, error) {
 		channel:    ch,
 		deliveries: deliveries,
 		queue:      queue,
+		delayed:    delayed,
+		jitter:     jitter,
 	}, nil
 }
 
 // Enqueue публикует задачу в очередь RabbitMQ.
 func (q *RabbitDigestQueue) Enqueue(ctx context.Context, job domain.DigestJob) error {
+	return q.publish(ctx, q.queue, job, 0)
+}
+
+// EnqueueDelayed публикует задачу, которая попадёт в основную очередь через delay.
+// Все отложенные задачи используют одинаковую задержку, поэтому TTL на уровне
+// сообщения не блокирует очередь более ранними сообщениями с большим сроком.
+func (q *RabbitDigestQueue) EnqueueDelayed(ctx context.Context, job domain.DigestJob, delay time.Duration) error {
+	if delay <= 0 {
+		return q.Enqueue(ctx, job)
+	}
+	return q.publish(ctx, q.delayed, job, delay)
+}
+
+// EnqueueJittered публикует задачу со случайной задержкой в пределах нескольких минут.
+// RabbitMQ снимает просроченные сообщения только с головы очереди, поэтому задачи одного
+// тика нужно публиковать по возрастанию задержки: тогда ни одна не ждёт дольше окна разброса.
+func (q *RabbitDigestQueue) EnqueueJittered(ctx context.Context, job domain.DigestJob, delay time.Duration) error {
+	if delay <= 0 {
+		return q.Enqueue(ctx, job)
+	}
+	return q.publish(ctx, q.jitter, job, delay)
+}
+
+func (q *RabbitDigestQueue) publish(ctx context.Context, queue string, job domain.DigestJob, delay time.Duration) error {
 	if job.ID == "" {
 		job.ID = uuid.NewString()
 	}
@@ -100,16 +160,21 @@ func (q *RabbitDigestQueue) Enqueue(ctx context.Context, job domain.DigestJob) e
 		return fmt.Errorf("marshal job: %w", err)
 	}
 
-	start := time.Now()
-	err = q.channel.PublishWithContext(ctx, "", q.queue, false, false, amqp.Publishing{
+	msg := amqp.Publishing{
 		DeliveryMode: amqp.Persistent,
 		ContentType:  publishContentType,
 		Body:         payload,
 		Timestamp:    time.Now().UTC(),
 		Type:         string(job.Cause),
 		MessageId:    job.ID,
-	})
-	metrics.ObserveNetworkRequest("rabbitmq", "publish", q.queue, start, err)
+	}
+	if delay > 0 {
+		msg.Expiration = strconv.FormatInt(delay.Milliseconds(), 10)
+	}
+
+	start := time.Now()
+	err = q.channel.PublishWithContext(ctx, "", queue, false, false, msg)
+	metrics.ObserveNetworkRequest("rabbitmq", "publish", queue, start, err)
 	if err != nil {
 		return fmt.Errorf("publish message: %w", err)
 	}
@@ -161,6 +226,32 @@ func (q *RabbitDigestQueue) Receive(ctx context.Context) (domain.DigestJob, doma
 	}
 }
 
+// Depth возвращает число сообщений в основной и отложенных очередях. Запрос идёт через
+// отдельный канал: ошибка пассивного объявления закрывает канал, а канал потребителя трогать нельзя.
+func (q *RabbitDigestQueue) Depth(_ context.Context) (ready, delayed int, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveNetworkRequest("rabbitmq", "queue_depth", q.queue, start, err)
+	}()
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return 0, 0, fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
+	main, err := ch.QueueDeclarePassive(q.queue, true, false, false, false, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("inspect queue: %w", err)
+	}
+	for _, name := range []string{q.delayed, q.jitter} {
+		wait, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+		if err != nil {
+			return 0, 0, fmt.Errorf("inspect delayed queue %s: %w", name, err)
+		}
+		delayed += wait.Messages
+	}
+	return main.Messages, delayed, nil
+}
+
 // Close освобождает ресурсы очереди.
 func (q *RabbitDigestQueue) Close() error {
 	if q.channel != nil {
@@ -0,0 +1,75 @@
+package channels
+
+import (
+	"errors"
+	"net/http"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// Машиночитаемые коды ошибок добавления канала. Значения стабильны: на них
+// опираются клиенты API.
+const (
+	CodeAliasInvalid    = "alias_invalid"
+	CodeChannelLimit    = "channel_limit"
+	CodePrivateChannel  = "private_channel"
+	CodeInviteLink      = "invite_link"
+	CodeChannelNotFound = "channel_not_found"
+	CodeTagLimit        = "tag_limit"
+	CodeInternal        = "internal"
+)
+
+var (
+	ErrChannelLimit   = newError(CodeChannelLimit, http.StatusForbidden, "превышен лимит каналов")
+	ErrPrivateChannel = newError(CodePrivateChannel, http.StatusUnprocessableEntity, "канал приватный или недоступен")
+	ErrAliasInvalid   = newError(CodeAliasInvalid, http.StatusBadRequest, "некорректный алиас")
+)
+
+// ErrProbeUnavailable возвращается, если резолвер не умеет проверять историю канала.
+var ErrProbeUnavailable = errors.New("проверка канала недоступна")
+
+// Error — ошибка сценария работы с каналами с кодом и подсказкой HTTP-статуса.
+type Error struct {
+	code   string
+	status int
+	err    error
+}
+
+func newError(code string, status int, msg string) *Error {
+	return &Error{code: code, status: status, err: errors.New(msg)}
+}
+
+func (e *Error) Error() string { return e.err.Error() }
+
+// Unwrap отдаёт исходную ошибку, чтобы errors.Is работал и для обёрнутых доменных ошибок.
+func (e *Error) Unwrap() error { return e.err }
+
+// Code возвращает стабильный машиночитаемый код.
+func (e *Error) Code() string { return e.code }
+
+// HTTPStatus возвращает рекомендуемый HTTP-статус ответа.
+func (e *Error) HTTPStatus() int { return e.status }
+
+// AsAPIError приводит ошибку сервиса каналов к Error. Доменные ошибки получают свои
+// коды, всё неизвестное — CodeInternal и статус 500. Для nil возвращает nil.
+func AsAPIError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var limitErr *TagLimitError
+	if errors.As(err, &limitErr) {
+		return &Error{code: CodeTagLimit, status: http.StatusBadRequest, err: err}
+	}
+	switch {
+	case errors.Is(err, domain.ErrInviteLink):
+		return &Error{code: CodeInviteLink, status: http.StatusUnprocessableEntity, err: err}
+	case errors.Is(err, domain.ErrChannelNotFound):
+		return &Error{code: CodeChannelNotFound, status: http.StatusNotFound, err: err}
+	default:
+		return &Error{code: CodeInternal, status: http.StatusInternalServerError, err: err}
+	}
+}
@@ -6,23 +6,20 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"tg-digest-bot/internal/domain"
 )
 
-var (
-	ErrChannelLimit   = errors.New("превышен лимит каналов")
-	ErrPrivateChannel = errors.New("канал приватный или недоступен")
-	ErrAliasInvalid   = errors.New("некорректный алиас")
-)
-
-var aliasRegex = regexp.MustCompile(`(?i)^(?:@|https?://t\.me/|t\.me/)?([a-z0-9_]{5,})$`)
+var aliasRegex = regexp.MustCompile(`(?i)^[a-z0-9_]{5,}$`)
 
 // Service управляет каналами пользователя.
 type Service struct {
 	repo     domain.ChannelRepo
 	resolver domain.ChannelResolver
 	userRepo domain.UserRepo
+
+	tagLimits TagLimits
 }
 
 // NewService создаёт новый сервис каналов.
@@ -30,14 +27,22 @@ func NewService(repo domain.ChannelRepo, resolver domain.ChannelResolver, userRe
 	return &Service{repo: repo, resolver: resolver, userRepo: userRepo}
 }
 
+// SetTagLimits задаёт ограничения на число и длину тегов канала.
+func (s *Service) SetTagLimits(limits TagLimits) {
+	s.tagLimits = limits
+}
+
 // ParseAlias приводит ввод пользователя к каноничному алиасу.
+// Для ссылок-приглашений возвращает domain.ErrInviteLink.
 func ParseAlias(input string) (string, error) {
-	trim := strings.TrimSpace(input)
-	matches := aliasRegex.FindStringSubmatch(trim)
-	if len(matches) < 2 {
+	username, err := domain.ChannelUsername(input)
+	if errors.Is(err, domain.ErrInviteLink) {
+		return "", err
+	}
+	if err != nil || !aliasRegex.MatchString(username) {
 		return "", ErrAliasInvalid
 	}
-	return strings.ToLower(matches[1]), nil
+	return strings.ToLower(username), nil
 }
 
 // AddChannel добавляет канал пользователю.
@@ -50,13 +55,8 @@ func (s *Service) AddChannel(ctx context.Context, tgUserID int64, alias string)
 	if err != nil {
 		return domain.Channel{}, fmt.Errorf("получение пользователя: %w", err)
 	}
-	count, err := s.repo.CountUserChannels(user.ID)
-	if err != nil {
-		return domain.Channel{}, fmt.Errorf("подсчёт каналов: %w", err)
-	}
-	plan := user.Plan()
-	if plan.ChannelLimit > 0 && count >= plan.ChannelLimit {
-		return domain.Channel{}, ErrChannelLimit
+	if err := s.checkChannelLimit(user); err != nil {
+		return domain.Channel{}, err
 	}
 	meta, err := s.resolver.ResolvePublic(parsed)
 	if err != nil {
@@ -75,6 +75,99 @@ func (s *Service) AddChannel(ctx context.Context, tgUserID int64, alias string)
 	return channel, nil
 }
 
+// CheckChannel проверяет, что посты канала можно собирать, не добавляя его пользователю.
+func (s *Service) CheckChannel(ctx context.Context, alias string) (domain.ChannelProbe, error) {
+	parsed, err := ParseAlias(alias)
+	if err != nil {
+		return domain.ChannelProbe{}, err
+	}
+	prober, ok := s.resolver.(domain.ChannelProber)
+	if !ok {
+		return domain.ChannelProbe{}, ErrProbeUnavailable
+	}
+	probe, err := prober.ProbeHistory(parsed)
+	if err != nil {
+		return domain.ChannelProbe{}, fmt.Errorf("проверка канала: %w", err)
+	}
+	return probe, nil
+}
+
+// AddFoundChannel привязывает к пользователю канал, найденный через SearchChannels.
+func (s *Service) AddFoundChannel(ctx context.Context, tgUserID, channelID int64) (domain.Channel, error) {
+	user, err := s.userRepo.GetByTGID(tgUserID)
+	if err != nil {
+		return domain.Channel{}, fmt.Errorf("получение пользователя: %w", err)
+	}
+	channel, err := s.repo.GetChannel(channelID)
+	if err != nil {
+		return domain.Channel{}, fmt.Errorf("получение канала: %w", err)
+	}
+	if !channel.IsAllowed {
+		return domain.Channel{}, ErrPrivateChannel
+	}
+	if err := s.checkChannelLimit(user); err != nil {
+		return domain.Channel{}, err
+	}
+	if err := s.repo.AttachChannelToUser(user.ID, channel.ID); err != nil {
+		return domain.Channel{}, fmt.Errorf("привязка канала: %w", err)
+	}
+	return channel, nil
+}
+
+// SearchChannels ищет известные боту каналы по ключевому слову.
+func (s *Service) SearchChannels(ctx context.Context, query string, limit int) ([]domain.Channel, error) {
+	query = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	if query == "" {
+		return nil, nil
+	}
+	return s.repo.SearchChannels(query, limit)
+}
+
+// LookupChannel находит канал по алиасу, не привязывая его к пользователю. Сначала проверяются
+// каналы, уже известные боту, и только затем идёт запрос в Telegram; найденный канал сохраняется,
+// чтобы следующие поиски обходились без MTProto.
+func (s *Service) LookupChannel(ctx context.Context, alias string) (domain.Channel, error) {
+	parsed, err := ParseAlias(alias)
+	if err != nil {
+		return domain.Channel{}, err
+	}
+	known, err := s.repo.SearchChannels(parsed, lookupKnownLimit)
+	if err != nil {
+		return domain.Channel{}, fmt.Errorf("поиск канала: %w", err)
+	}
+	for _, ch := range known {
+		if strings.EqualFold(ch.Alias, parsed) {
+			return ch, nil
+		}
+	}
+	meta, err := s.resolver.ResolvePublic(parsed)
+	if err != nil {
+		return domain.Channel{}, fmt.Errorf("резолв канала: %w", err)
+	}
+	if !meta.Public {
+		return domain.Channel{}, ErrPrivateChannel
+	}
+	channel, err := s.repo.UpsertChannel(meta)
+	if err != nil {
+		return domain.Channel{}, fmt.Errorf("сохранение канала: %w", err)
+	}
+	return channel, nil
+}
+
+// lookupKnownLimit — сколько известных каналов просматривать в поиске точного совпадения алиаса.
+const lookupKnownLimit = 20
+
+func (s *Service) checkChannelLimit(user domain.User) error {
+	count, err := s.repo.CountUserChannels(user.ID)
+	if err != nil {
+		return fmt.Errorf("подсчёт каналов: %w", err)
+	}
+	if limit := user.ChannelLimit(); limit > 0 && count >= limit {
+		return ErrChannelLimit
+	}
+	return nil
+}
+
 // ListChannels возвращает каналы пользователя.
 func (s *Service) ListChannels(ctx context.Context, tgUserID int64, limit, offset int) ([]domain.UserChannel, error) {
 	user, err := s.userRepo.GetByTGID(tgUserID)
@@ -84,6 +177,15 @@ func (s *Service) ListChannels(ctx context.Context, tgUserID int64, limit, offse
 	return s.repo.ListUserChannels(user.ID, limit, offset)
 }
 
+// CountChannels возвращает количество каналов пользователя.
+func (s *Service) CountChannels(ctx context.Context, tgUserID int64) (int, error) {
+	user, err := s.userRepo.GetByTGID(tgUserID)
+	if err != nil {
+		return 0, fmt.Errorf("получение пользователя: %w", err)
+	}
+	return s.repo.CountUserChannels(user.ID)
+}
+
 // ToggleMute переключает статус мутирования канала.
 func (s *Service) ToggleMute(ctx context.Context, tgUserID, channelID int64, mute bool) error {
 	user, err := s.userRepo.GetByTGID(tgUserID)
@@ -93,6 +195,15 @@ func (s *Service) ToggleMute(ctx context.Context, tgUserID, channelID int64, mut
 	return s.repo.SetMuted(user.ID, channelID, mute)
 }
 
+// MuteFor выключает канал в дайджесте до момента until.
+func (s *Service) MuteFor(ctx context.Context, tgUserID, channelID int64, until time.Time) error {
+	user, err := s.userRepo.GetByTGID(tgUserID)
+	if err != nil {
+		return fmt.Errorf("получение пользователя: %w", err)
+	}
+	return s.repo.MuteUntil(user.ID, channelID, until)
+}
+
 // RemoveChannel отвязывает канал от пользователя.
 func (s *Service) RemoveChannel(ctx context.Context, tgUserID, channelID int64) error {
 	user, err := s.userRepo.GetByTGID(tgUserID)
@@ -102,13 +213,15 @@ func (s *Service) RemoveChannel(ctx context.Context, tgUserID, channelID int64)
 	return s.repo.DetachChannelFromUser(user.ID, channelID)
 }
 
-// UpdateChannelTags обновляет список тегов для канала пользователя.
+// UpdateChannelTags обновляет список тегов для канала пользователя. Теги сверх лимитов
+// отбрасываются: остальные сохраняются, а отброшенные возвращаются в *TagLimitError.
 func (s *Service) UpdateChannelTags(ctx context.Context, tgUserID, channelID int64, tags []string) error {
 	user, err := s.userRepo.GetByTGID(tgUserID)
 	if err != nil {
 		return fmt.Errorf("получение пользователя: %w", err)
 	}
-	cleaned := NormalizeTags(tags)
+	limits := s.tagLimits.withDefaults()
+	cleaned, dropped := limits.Apply(NormalizeTags(tags))
 	channels, err := s.repo.ListUserChannels(user.ID, 100, 0)
 	if err != nil {
 		return fmt.Errorf("получение каналов: %w", err)
@@ -123,7 +236,13 @@ func (s *Service) UpdateChannelTags(ctx context.Context, tgUserID, channelID int
 	if !found {
 		return fmt.Errorf("канал не найден среди подписок пользователя")
 	}
-	return s.repo.UpdateUserChannelTags(user.ID, channelID, cleaned)
+	if err := s.repo.UpdateUserChannelTags(user.ID, channelID, cleaned); err != nil {
+		return err
+	}
+	if len(dropped) > 0 {
+		return &TagLimitError{Limits: limits, Kept: cleaned, Dropped: dropped}
+	}
+	return nil
 }
 
 // NormalizeTags удаляет пустые и дублирующиеся значения, сохраняя порядок.
@@ -1,12 +1,22 @@
 package channels
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tg-digest-bot/internal/domain"
+)
 
 func TestParseAlias(t *testing.T) {
 	cases := map[string]string{
-		"@Example":       "example",
-		"https://t.me/A": "",
-		"t.me/golang":    "golang",
+		"@Example":                        "example",
+		"https://t.me/A":                  "",
+		"t.me/golang":                     "golang",
+		"https://t.me/s/GoLang?before=10": "golang",
+		"t.me/joinchat/AbCdEf":            "",
 	}
 	for input, expected := range cases {
 		alias, err := ParseAlias(input)
@@ -35,3 +45,60 @@ func TestNormalizeTags(t *testing.T) {
 		t.Fatalf("неожиданное содержимое: %#v", normalized)
 	}
 }
+
+func TestTagLimitsApply(t *testing.T) {
+	limits := TagLimits{MaxCount: 3, MaxLength: 5}
+	kept, dropped := limits.Apply([]string{"новос", "аналитика", "игры", "кино", "спорт"})
+	if strings.Join(kept, ",") != "новос,игры,кино" {
+		t.Fatalf("неожиданные сохранённые теги: %#v", kept)
+	}
+	if strings.Join(dropped, ",") != "аналитика,спорт" {
+		t.Fatalf("неожиданные отброшенные теги: %#v", dropped)
+	}
+
+	kept, dropped = limits.Apply([]string{"а", "б", "в"})
+	if len(kept) != 3 || len(dropped) != 0 {
+		t.Fatalf("теги на границе лимита не должны отбрасываться: %#v, %#v", kept, dropped)
+	}
+
+	tags := make([]string, DefaultMaxTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("тег%d", i)
+	}
+	tags[0] = strings.Repeat("я", DefaultMaxTagLength+1)
+	kept, dropped = TagLimits{}.Apply(tags)
+	if len(kept) != DefaultMaxTags || len(dropped) != 1 || dropped[0] != tags[0] {
+		t.Fatalf("нулевые лимиты должны означать стандартные: %d сохранено, отброшены %#v", len(kept), dropped)
+	}
+}
+
+func TestAsAPIError(t *testing.T) {
+	cases := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{ErrAliasInvalid, CodeAliasInvalid, http.StatusBadRequest},
+		{ErrChannelLimit, CodeChannelLimit, http.StatusForbidden},
+		{ErrPrivateChannel, CodePrivateChannel, http.StatusUnprocessableEntity},
+		{fmt.Errorf("обёртка: %w", &TagLimitError{Dropped: []string{"x"}}), CodeTagLimit, http.StatusBadRequest},
+		{domain.ErrInviteLink, CodeInviteLink, http.StatusUnprocessableEntity},
+		{fmt.Errorf("резолв канала: %w", domain.ErrChannelNotFound), CodeChannelNotFound, http.StatusNotFound},
+		{errors.New("boom"), CodeInternal, http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		apiErr := AsAPIError(tc.err)
+		if apiErr.Code() != tc.code || apiErr.HTTPStatus() != tc.status {
+			t.Fatalf("%v: ожидали %s/%d, получили %s/%d", tc.err, tc.code, tc.status, apiErr.Code(), apiErr.HTTPStatus())
+		}
+		if !errors.Is(apiErr, tc.err) {
+			t.Fatalf("%v: исходная ошибка потеряна", tc.err)
+		}
+	}
+	if AsAPIError(nil) != nil {
+		t.Fatalf("ожидали nil для nil")
+	}
+	if _, err := ParseAlias("x"); !errors.Is(err, ErrAliasInvalid) {
+		t.Fatalf("ожидали ErrAliasInvalid, получили %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения тегов по умолчанию.
+const (
+	DefaultMaxTags      = 10
+	DefaultMaxTagLength = 32
+)
+
+// TagLimits ограничивает теги одного канала. Значения меньше 1 заменяются стандартными.
+type TagLimits struct {
+	MaxCount  int
+	MaxLength int
+}
+
+func (l TagLimits) withDefaults() TagLimits {
+	if l.MaxCount < 1 {
+		l.MaxCount = DefaultMaxTags
+	}
+	if l.MaxLength < 1 {
+		l.MaxLength = DefaultMaxTagLength
+	}
+	return l
+}
+
+// Apply оставляет не более MaxCount тегов длиной до MaxLength символов в исходном порядке;
+// остальные возвращает во втором срезе.
+func (l TagLimits) Apply(tags []string) (kept, dropped []string) {
+	l = l.withDefaults()
+	kept = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > l.MaxLength || len(kept) == l.MaxCount {
+			dropped = append(dropped, tag)
+			continue
+		}
+		kept = append(kept, tag)
+	}
+	return kept, dropped
+}
+
+// TagLimitError сообщает, что часть тегов не сохранена из-за лимитов. Теги из Kept
+// при этом уже записаны.
+type TagLimitError struct {
+	Limits  TagLimits
+	Kept    []string
+	Dropped []string
+}
+
+func (e *TagLimitError) Error() string {
+	return fmt.Sprintf("теги не сохранены (не больше %d тегов по %d символов): %s",
+		e.Limits.MaxCount, e.Limits.MaxLength, strings.Join(e.Dropped, ", "))
+}
@@ -0,0 +1,65 @@
+package digest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// DefaultAdPatterns — консервативный набор признаков рекламы: явные хэштеги,
+// маркировка erid и ссылки известных партнёрских сетей.
+var DefaultAdPatterns = []string{
+	`(?i)#(?:реклама|промо|ad|ads|promo|sponsored)(?:$|[^\p{L}\p{N}_])`,
+	`(?i)(?:^|[^\p{L}\p{N}_])erid\s*[:=]`,
+	`(?i)https?://(?:[\w-]+\.)*(?:admitad\.com|ad\.admitad\.com|aliclick\.pro|epnclick\.ru|fas\.st|s\.click\.aliexpress\.com)`,
+	`(?i)[?&](?:aff|aff_id|affiliate|partner_id)=`,
+}
+
+// AdFilter отсеивает рекламные посты по регулярным выражениям и, опционально, по решению LLM.
+type AdFilter struct {
+	patterns   []*regexp.Regexp
+	classifier domain.AdClassifier
+}
+
+// NewAdFilter компилирует шаблоны и создаёт фильтр. classifier может быть nil.
+func NewAdFilter(patterns []string, classifier domain.AdClassifier) (*AdFilter, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("шаблон рекламы %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return &AdFilter{patterns: compiled, classifier: classifier}, nil
+}
+
+// Classify определяет, является ли пост рекламой. Ошибка LLM не приводит к отсеву поста.
+func (f *AdFilter) Classify(post domain.Post) (domain.AdVerdict, error) {
+	text := post.FullText
+	if text == "" {
+		text = post.Text
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			return domain.AdVerdict{IsAd: true, Reason: "pattern: " + re.String()}, nil
+		}
+	}
+	if f.classifier == nil {
+		return domain.AdVerdict{}, nil
+	}
+	isAd, err := f.classifier.IsAd(post)
+	if err != nil {
+		return domain.AdVerdict{}, err
+	}
+	if isAd {
+		return domain.AdVerdict{IsAd: true, Reason: "llm"}, nil
+	}
+	return domain.AdVerdict{}, nil
+}
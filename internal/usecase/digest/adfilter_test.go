@@ -0,0 +1,110 @@
+package digest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+type fakeAdClassifier struct {
+	calls int
+	isAd  bool
+	err   error
+}
+
+func (c *fakeAdClassifier) IsAd(_ domain.Post) (bool, error) {
+	c.calls++
+	return c.isAd, c.err
+}
+
+func TestAdFilterDefaultPatterns(t *testing.T) {
+	filter, err := NewAdFilter(DefaultAdPatterns, nil)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	cases := []struct {
+		text string
+		isAd bool
+	}{
+		{text: "Скидка 20% по промокоду #реклама", isAd: true},
+		{text: "#ad New gadget review", isAd: true},
+		{text: "Реклама. ООО «Ромашка», ИНН 7700000000, erid: 2Vtzqx", isAd: true},
+		{text: "Подробнее: https://ad.admitad.com/g/abc123/", isAd: true},
+		{text: "Обзор рынка рекламы за квартал", isAd: false},
+		{text: "#admin объявление о сборе", isAd: false},
+	}
+	for _, tc := range cases {
+		verdict, err := filter.Classify(domain.Post{Text: tc.text})
+		if err != nil {
+			t.Fatalf("неожиданная ошибка для %q: %v", tc.text, err)
+		}
+		if verdict.IsAd != tc.isAd {
+			t.Fatalf("для %q ожидали is_ad=%v, получили %+v", tc.text, tc.isAd, verdict)
+		}
+	}
+}
+
+func TestNewAdFilterRejectsInvalidPattern(t *testing.T) {
+	if _, err := NewAdFilter([]string{"("}, nil); err == nil {
+		t.Fatal("ожидали ошибку компиляции шаблона")
+	}
+}
+
+func TestBuildForDateDropsAdsAndCachesVerdict(t *testing.T) {
+	cached := mustJSON(map[string]any{"ad_filter": domain.AdVerdict{IsAd: true, Reason: "llm"}})
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42, FilterAds: true},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "Новость дня", PublishedAt: time.Now()},
+			{ID: 2, ChannelID: 1, Text: "Промокод внутри #реклама", PublishedAt: time.Now()},
+			{ID: 3, ChannelID: 1, Text: "Ранее отмеченный пост", RawMetaJSON: cached, PublishedAt: time.Now()},
+		},
+	}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+	classifier := &fakeAdClassifier{}
+	filter, err := NewAdFilter(DefaultAdPatterns, classifier)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	service.SetAdFilter(filter)
+
+	if _, err := service.BuildForDate(42, time.Now()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(ranker.captured) != 1 || ranker.captured[0].ID != 1 {
+		t.Fatalf("ожидали только пост 1, получили %+v", ranker.captured)
+	}
+	if classifier.calls != 1 {
+		t.Fatalf("LLM должен вызываться только для поста без совпадений, вызовов: %d", classifier.calls)
+	}
+	if !repo.adVerdicts[2].IsAd || repo.adVerdicts[1].IsAd {
+		t.Fatalf("решения не сохранены: %+v", repo.adVerdicts)
+	}
+	if _, ok := repo.adVerdicts[3]; ok {
+		t.Fatal("пост с сохранённым решением не должен классифицироваться повторно")
+	}
+}
+
+func TestBuildForDateKeepsPostsWhenClassifierFails(t *testing.T) {
+	repo := &stubRepo{
+		user:  domain.User{ID: 1, TGUserID: 42, FilterAds: true},
+		posts: []domain.Post{{ID: 1, ChannelID: 1, Text: "Новость дня", PublishedAt: time.Now()}},
+	}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+	filter, _ := NewAdFilter(DefaultAdPatterns, &fakeAdClassifier{err: errors.New("timeout")})
+	service.SetAdFilter(filter)
+
+	if _, err := service.BuildForDate(42, time.Now()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(ranker.captured) != 1 {
+		t.Fatalf("пост должен остаться при ошибке классификатора, получили %+v", ranker.captured)
+	}
+	if len(repo.adVerdicts) != 0 {
+		t.Fatalf("решение не должно сохраняться при ошибке: %+v", repo.adVerdicts)
+	}
+}
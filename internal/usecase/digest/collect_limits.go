@@ -0,0 +1,64 @@
+package digest
+
+import (
+	"errors"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// CollectLimits ограничивает сбор каналов в рамках одной задачи дайджеста.
+type CollectLimits struct {
+	// Concurrency — сколько каналов собирать одновременно. PlanConcurrency переопределяет
+	// значение для отдельных тарифов. Значения меньше 1 означают последовательный сбор.
+	Concurrency     int
+	PlanConcurrency map[domain.UserRole]int
+	// JobTimeout — верхняя граница на сбор всех каналов задачи; 0 — без ограничения.
+	JobTimeout time.Duration
+}
+
+func (l CollectLimits) concurrency(role domain.UserRole) int {
+	n := l.Concurrency
+	if v, ok := l.PlanConcurrency[role]; ok {
+		n = v
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// ChannelError — канал, который не удалось собрать, и причина.
+type ChannelError struct {
+	Channel domain.Channel
+	Err     error
+}
+
+// CollectResult — итог сбора каналов задачи: сколько каналов собрано и какие не удалось.
+type CollectResult struct {
+	Collected int
+	Failed    []ChannelError
+}
+
+// FailedAliases возвращает алиасы несобранных каналов в порядке их сбора.
+func (r CollectResult) FailedAliases() []string {
+	aliases := make([]string, 0, len(r.Failed))
+	for _, f := range r.Failed {
+		aliases = append(aliases, "@"+f.Channel.Alias)
+	}
+	return aliases
+}
+
+// err возвращает ошибку сбора в целом: недоступность пула MTProto или отсутствие в нём рабочих
+// аккаунтов, чтобы задачу повторили, или первую ошибку, если не собрался ни один канал.
+func (r CollectResult) err() error {
+	for _, f := range r.Failed {
+		if errors.Is(f.Err, domain.ErrCollectorUnavailable) || errors.Is(f.Err, domain.ErrNoAvailableAccounts) {
+			return f.Err
+		}
+	}
+	if r.Collected == 0 && len(r.Failed) > 0 {
+		return r.Failed[0].Err
+	}
+	return nil
+}
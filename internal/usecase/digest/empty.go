@@ -0,0 +1,114 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// EmptyData — данные, доступные в шаблонах сообщения о пустом дайджесте.
+type EmptyData struct {
+	// NextRun — следующая доставка по расписанию во времени пользователя; нулевое значение — неизвестна.
+	NextRun time.Time
+	// Channel — канал дайджеста по одному каналу, Muted — выключен ли он командой /mute.
+	Channel string
+	Muted   bool
+}
+
+// Шаблоны сообщений о пустом дайджесте по умолчанию.
+const (
+	DefaultEmptyScheduledTemplate = `За последние 24 часа в ваших каналах не было новых постов — бот работает, просто каналы молчали.` +
+		`{{if not .NextRun.IsZero}} Следующий дайджест придёт {{.NextRun.Format "02.01 в 15:04"}}.{{end}}`
+	DefaultEmptyManualTemplate  = `За последние 24 часа ничего не найдено`
+	DefaultEmptyChannelTemplate = `В канале {{.Channel}} за последние 24 часа ничего не найдено.` +
+		`{{if .Muted}} Канал выключен — верните его в дайджест командой /unmute {{.Channel}}.{{else}} Проверьте в /list, не выключен ли канал.{{end}}`
+	DefaultEmptyTagsTemplate = `Не удалось найти новые посты по выбранным тегам`
+)
+
+// EmptyMessages выбирает текст о пустом дайджесте по типу задачи.
+type EmptyMessages struct {
+	scheduled *template.Template
+	manual    *template.Template
+	channel   *template.Template
+	tags      *template.Template
+}
+
+var defaultEmptyMessages = mustEmptyMessages("", "", "", "")
+
+// NewEmptyMessages разбирает и пробно исполняет шаблоны. Пустой шаблон заменяется стандартным.
+func NewEmptyMessages(scheduled, manual, channel, tags string) (*EmptyMessages, error) {
+	m := &EmptyMessages{}
+	sample := EmptyData{NextRun: time.Now().UTC(), Channel: "@channel", Muted: true}
+	for _, t := range []struct {
+		name, text, fallback string
+		dst                  **template.Template
+	}{
+		{"scheduled", scheduled, DefaultEmptyScheduledTemplate, &m.scheduled},
+		{"manual", manual, DefaultEmptyManualTemplate, &m.manual},
+		{"channel", channel, DefaultEmptyChannelTemplate, &m.channel},
+		{"tags", tags, DefaultEmptyTagsTemplate, &m.tags},
+	} {
+		text := t.text
+		if strings.TrimSpace(text) == "" {
+			text = t.fallback
+		}
+		tmpl, err := template.New(t.name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("разбор шаблона пустого дайджеста %s: %w", t.name, err)
+		}
+		if _, err := renderEmpty(tmpl, sample); err != nil {
+			return nil, fmt.Errorf("шаблон пустого дайджеста %s: %w", t.name, err)
+		}
+		*t.dst = tmpl
+	}
+	return m, nil
+}
+
+// DefaultEmptyMessages возвращает стандартные сообщения о пустом дайджесте.
+func DefaultEmptyMessages() *EmptyMessages {
+	return defaultEmptyMessages
+}
+
+func mustEmptyMessages(scheduled, manual, channel, tags string) *EmptyMessages {
+	m, err := NewEmptyMessages(scheduled, manual, channel, tags)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Message возвращает текст для пустого дайджеста задачи job. Если шаблон не исполнился
+// на реальных данных, используется стандартный.
+func (m *EmptyMessages) Message(job domain.DigestJob, data EmptyData) string {
+	if m == nil {
+		m = defaultEmptyMessages
+	}
+	pick := func(set *EmptyMessages) *template.Template {
+		switch {
+		case job.ChannelID > 0:
+			return set.channel
+		case len(job.Tags) > 0:
+			return set.tags
+		case job.Cause == domain.DigestCauseScheduled:
+			return set.scheduled
+		default:
+			return set.manual
+		}
+	}
+	text, err := renderEmpty(pick(m), data)
+	if err != nil || text == "" {
+		text, _ = renderEmpty(pick(defaultEmptyMessages), data)
+	}
+	return text
+}
+
+func renderEmpty(tmpl *template.Template, data EmptyData) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
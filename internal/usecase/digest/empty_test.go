@@ -0,0 +1,40 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestEmptyMessagesDependOnCause(t *testing.T) {
+	messages := DefaultEmptyMessages()
+	next := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	scheduled := messages.Message(domain.DigestJob{Cause: domain.DigestCauseScheduled}, EmptyData{NextRun: next})
+	if !strings.Contains(scheduled, "бот работает") || !strings.Contains(scheduled, "17.10 в 09:00") {
+		t.Fatalf("ожидали пояснение и время следующей доставки: %q", scheduled)
+	}
+	if manual := messages.Message(domain.DigestJob{Cause: domain.DigestCauseManual}, EmptyData{}); manual != "За последние 24 часа ничего не найдено" {
+		t.Fatalf("неожиданное сообщение для ручного запроса: %q", manual)
+	}
+	muted := messages.Message(domain.DigestJob{ChannelID: 7}, EmptyData{Channel: "@news", Muted: true})
+	if !strings.Contains(muted, "/unmute @news") {
+		t.Fatalf("ожидали подсказку про мьют: %q", muted)
+	}
+}
+
+func TestNewEmptyMessagesUsesCustomTemplates(t *testing.T) {
+	messages, err := NewEmptyMessages(`Тишина, ждите {{.NextRun.Format "15:04"}}`, "", "", "")
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	got := messages.Message(domain.DigestJob{Cause: domain.DigestCauseScheduled}, EmptyData{NextRun: time.Date(2026, 10, 17, 21, 30, 0, 0, time.UTC)})
+	if got != "Тишина, ждите 21:30" {
+		t.Fatalf("неожиданное сообщение: %q", got)
+	}
+	if _, err := NewEmptyMessages("", "{{.Unknown}}", "", ""); err == nil {
+		t.Fatalf("ожидали ошибку на неизвестном поле")
+	}
+}
@@ -13,8 +13,14 @@ const (
 	footerLinkName = "Coffee Break News"
 )
 
-// FormatDigest формирует текстовое представление дайджеста для отправки пользователю.
+// FormatDigest формирует текстовое представление дайджеста для отправки пользователю
+// со стандартными шапкой и подвалом.
 func FormatDigest(d domain.Digest) string {
+	return defaultLayout.Format(d, "")
+}
+
+// formatDigestBody собирает итоги, тезисы и темы дня без шапки и подвала.
+func formatDigestBody(d domain.Digest) string {
 	var sections []string
 
 	if overview := strings.TrimSpace(d.Overview); overview != "" {
@@ -37,15 +43,39 @@ func FormatDigest(d domain.Digest) string {
 		}
 	}
 
+	if banner := buildTopChannelBanner(d.TopChannel); banner != "" {
+		sections = append(sections, banner)
+	}
+
 	if topics := buildTopicSections(d.Items); topics != "" {
 		sections = append(sections, topics)
 	}
 
-	sections = append(sections, buildFooterSection())
-
 	return strings.TrimSpace(strings.Join(sections, "\n\n"))
 }
 
+// FormatDigestEmail формирует тему и HTML-тело письма с дайджестом в стандартном оформлении.
+func FormatDigestEmail(d domain.Digest) (string, string) {
+	return defaultLayout.FormatEmail(d, "")
+}
+
+// FormatEmail формирует тему и HTML-тело письма с дайджестом.
+func (l *Layout) FormatEmail(d domain.Digest, planName string) (string, string) {
+	subject := "Дайджест каналов"
+	if !d.Date.IsZero() {
+		subject = fmt.Sprintf("Дайджест каналов за %s", d.Date.Format("02.01.2006"))
+	}
+	body := strings.ReplaceAll(l.Format(d, planName), "\n", "<br>\n")
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>%s</title></head>
+<body style="font-family: -apple-system, Segoe UI, Roboto, sans-serif; font-size: 15px; line-height: 1.5; max-width: 640px; margin: 0 auto; padding: 16px;">
+%s
+</body>
+</html>`, escapeHTML(subject), body)
+	return subject, page
+}
+
 func buildTopicSections(items []domain.DigestItem) string {
 	if len(items) == 0 {
 		return ""
@@ -121,6 +151,41 @@ func buildTopicSections(items []domain.DigestItem) string {
 	return strings.TrimSpace(builder.String())
 }
 
+// formatLeadItem оформляет главный пункт дайджеста отдельным сообщением. Ссылка в нём
+// одна, поэтому Telegram строит превью по посту.
+func formatLeadItem(item domain.DigestItem) string {
+	headline := strings.TrimSpace(item.Summary.Headline)
+	if headline == "" {
+		headline = "Открыть пост"
+	}
+	lines := []string{
+		"⭐ <b>Главное за день</b>",
+		fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(strings.TrimSpace(item.Post.URL)), escapeHTML(headline)),
+	}
+	if bullets := filterNonEmptyStrings(item.Summary.Bullets); len(bullets) > 0 {
+		lines = append(lines, escapeHTML(strings.Join(bullets, " ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func buildTopChannelBanner(top *domain.DigestTopChannel) string {
+	if top == nil {
+		return ""
+	}
+	name := strings.TrimSpace(top.Title)
+	alias := strings.TrimPrefix(strings.TrimSpace(top.Alias), "@")
+	switch {
+	case name == "" && alias == "":
+		return ""
+	case name == "":
+		return fmt.Sprintf("🔥 Канал дня: @%s", escapeHTML(alias))
+	case alias == "":
+		return fmt.Sprintf("🔥 Канал дня: <b>%s</b>", escapeHTML(name))
+	default:
+		return fmt.Sprintf("🔥 Канал дня: <a href=\"https://t.me/%s\">%s</a>", html.EscapeString(alias), escapeHTML(name))
+	}
+}
+
 func buildFooterSection() string {
 	return fmt.Sprintf("<a href=\"%s\">Дайджест создан с помощью %s</a>", html.EscapeString(footerLinkURL), escapeHTML(footerLinkName))
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"tg-digest-bot/internal/domain"
 )
@@ -51,3 +52,98 @@ func mustContain(t *testing.T, s, substr string) {
 		t.Fatalf("ожидали найти подстроку %q в %q", substr, s)
 	}
 }
+
+func TestFormatDigestEmail(t *testing.T) {
+	digest := domain.Digest{
+		Date:     time.Date(2024, 10, 16, 0, 0, 0, 0, time.UTC),
+		Overview: "Первая строка",
+	}
+	subject, body := FormatDigestEmail(digest)
+	if subject != "Дайджест каналов за 16.10.2024" {
+		t.Fatalf("неожиданная тема: %q", subject)
+	}
+	if !strings.Contains(body, "<b>Итоги дня</b><br>\nПервая строка") {
+		t.Fatalf("ожидали переносы строк в виде <br>, получили:\n%s", body)
+	}
+	if !strings.HasPrefix(body, "<!DOCTYPE html>") {
+		t.Fatal("ожидали полноценный HTML-документ")
+	}
+}
+
+func TestLayoutDefaultMatchesFormatDigest(t *testing.T) {
+	digest := domain.Digest{Overview: "Коротко о главном"}
+	if got, want := DefaultLayout().Format(digest, "pro"), FormatDigest(digest); got != want {
+		t.Fatalf("стандартное оформление изменилось:\n%s\n---\n%s", got, want)
+	}
+}
+
+func TestLayoutRendersHeaderAndFooter(t *testing.T) {
+	layout, err := NewLayout(`Дайджест за {{.Date.Format "02.01.2006"}}: {{.ItemCount}}`, `Тариф {{.PlanName}}`)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	digest := domain.Digest{
+		Date:     time.Date(2024, 10, 16, 0, 0, 0, 0, time.UTC),
+		Overview: "Итог",
+		Items:    []domain.DigestItem{{Post: domain.Post{URL: "https://t.me/a/1"}, Summary: domain.Summary{Headline: "Новость"}}},
+	}
+	formatted := layout.Format(digest, "pro")
+	if !strings.HasPrefix(formatted, "Дайджест за 16.10.2024: 1") {
+		t.Fatalf("ожидали шапку в начале: %q", formatted)
+	}
+	if !strings.HasSuffix(formatted, "Тариф pro") {
+		t.Fatalf("ожидали подвал в конце: %q", formatted)
+	}
+	if strings.Contains(formatted, footerLinkName) {
+		t.Fatalf("стандартный подвал должен быть заменён: %q", formatted)
+	}
+}
+
+func TestNewLayoutRejectsBrokenTemplates(t *testing.T) {
+	if _, err := NewLayout("{{.Date", ""); err == nil {
+		t.Fatalf("ожидали ошибку разбора")
+	}
+	if _, err := NewLayout("", "{{.Unknown}}"); err == nil {
+		t.Fatalf("ожидали ошибку на неизвестном поле")
+	}
+}
+
+func TestFormatDigestTopChannelBanner(t *testing.T) {
+	digest := domain.Digest{
+		TopChannel: &domain.DigestTopChannel{ChannelID: 1, Title: "Новости <24>", Alias: "news24"},
+		Items: []domain.DigestItem{
+			{Post: domain.Post{URL: "https://t.me/news24/1"}, Summary: domain.Summary{Headline: "Событие"}},
+		},
+	}
+	formatted := FormatDigest(digest)
+	mustContain(t, formatted, "🔥 Канал дня: <a href=\"https://t.me/news24\">Новости &lt;24&gt;</a>")
+	if strings.Index(formatted, "Канал дня") > strings.Index(formatted, "Событие") {
+		t.Fatalf("баннер должен стоять над пунктами: %q", formatted)
+	}
+}
+
+func TestLayoutFormatWithLead(t *testing.T) {
+	layout, err := NewLayout(`Постов: {{.ItemCount}}`, "")
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	digest := domain.Digest{Items: []domain.DigestItem{
+		{Post: domain.Post{URL: "https://t.me/a/1"}, Summary: domain.Summary{Headline: "Главное", Bullets: []string{"подробности"}}},
+		{Post: domain.Post{URL: "https://t.me/a/2"}, Summary: domain.Summary{Headline: "Второе"}},
+	}}
+
+	lead, body := layout.FormatWithLead(digest, "")
+	mustContain(t, lead, "<a href=\"https://t.me/a/1\">Главное</a>")
+	mustContain(t, lead, "подробности")
+	mustContain(t, body, "Постов: 2")
+	mustContain(t, body, "Второе")
+	if strings.Contains(body, "t.me/a/1") {
+		t.Fatalf("главный пункт не должен повторяться в основном сообщении:\n%s", body)
+	}
+
+	digest.Items[0].Post.URL = ""
+	lead, body = layout.FormatWithLead(digest, "")
+	if lead != "" || body != layout.Format(digest, "") {
+		t.Fatalf("без ссылки у главного пункта дайджест не делится")
+	}
+}
@@ -0,0 +1,95 @@
+package digest
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLangLetters — меньше букв недостаточно, чтобы уверенно определить язык.
+const minLangLetters = 20
+
+// langStopwords — частотные служебные слова языков с латиницей.
+var langStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "this", "are", "was", "it", "on", "you"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "eine", "auf", "für", "sich", "auch", "ich"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "dans", "pour", "pas", "que", "qui", "sur", "avec", "du"},
+	"es": {"el", "los", "las", "y", "es", "una", "por", "para", "con", "que", "del", "se", "como", "pero", "más"},
+	"it": {"il", "di", "che", "è", "non", "una", "per", "con", "sono", "gli", "della", "anche", "come", "nel", "questo"},
+	"pt": {"o", "os", "e", "não", "uma", "para", "com", "que", "do", "da", "em", "por", "mais", "como", "são"},
+}
+
+// DetectLanguage грубо определяет язык текста по алфавиту и частотным словам.
+// Возвращает код ISO 639-1 или пустую строку, если текста мало или язык не распознан.
+func DetectLanguage(text string) string {
+	var cyrillic, latin, ukrainian, russian int
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			switch r {
+			case 'і', 'ї', 'є', 'ґ':
+				ukrainian++
+			case 'ы', 'э', 'ъ', 'ё':
+				russian++
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if cyrillic+latin < minLangLetters {
+		return ""
+	}
+	if cyrillic >= latin {
+		if ukrainian > russian {
+			return "uk"
+		}
+		return "ru"
+	}
+	return detectLatinLanguage(text)
+}
+
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	best, bestHits, tie := "", 0, false
+	for lang, stopwords := range langStopwords {
+		hits := 0
+		for _, word := range words {
+			for _, stop := range stopwords {
+				if word == stop {
+					hits++
+					break
+				}
+			}
+		}
+		switch {
+		case hits > bestHits:
+			best, bestHits, tie = lang, hits, false
+		case hits == bestHits:
+			tie = true
+		}
+	}
+	if bestHits < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// NormalizeLang приводит код языка или локаль Telegram (en-US, pt_BR) к ISO 639-1.
+func NormalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if idx := strings.IndexAny(lang, "-_"); idx > 0 {
+		lang = lang[:idx]
+	}
+	return lang
+}
+
+// IsSupportedLang сообщает, умеет ли DetectLanguage распознавать язык.
+func IsSupportedLang(lang string) bool {
+	if lang == "ru" || lang == "uk" {
+		return true
+	}
+	_, ok := langStopwords[lang]
+	return ok
+}
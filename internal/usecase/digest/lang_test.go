@@ -0,0 +1,76 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{text: "Центробанк сохранил ключевую ставку и объяснил это замедлением инфляции", want: "ru"},
+		{text: "Уряд ухвалив нові правила для підприємців, які почнуть діяти з їхнього дозволу", want: "uk"},
+		{text: "The central bank kept the key rate unchanged and this is expected to continue", want: "en"},
+		{text: "Die Regierung hat die neue Regel beschlossen und das ist nicht das Ende", want: "de"},
+		{text: "Коротко", want: ""},
+		{text: "OpenAI GPT Claude Gemini Llama Mistral Qwen", want: ""},
+	}
+	for _, tc := range cases {
+		if got := DetectLanguage(tc.text); got != tc.want {
+			t.Fatalf("для %q ожидали %q, получили %q", tc.text, tc.want, got)
+		}
+	}
+}
+
+func TestNormalizeLang(t *testing.T) {
+	for input, want := range map[string]string{"en-US": "en", "pt_BR": "pt", " RU ": "ru", "": ""} {
+		if got := NormalizeLang(input); got != want {
+			t.Fatalf("для %q ожидали %q, получили %q", input, want, got)
+		}
+	}
+	if !IsSupportedLang("uk") || IsSupportedLang("zz") {
+		t.Fatal("неверный список поддерживаемых языков")
+	}
+}
+
+func TestBuildForDateDropsForeignLanguageAndCachesIt(t *testing.T) {
+	cached := domain.Post{ID: 3, ChannelID: 1, Text: "Original text", PublishedAt: time.Now()}.WithLang("en")
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42, DigestLang: "ru"},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "Центробанк сохранил ключевую ставку на прежнем уровне", PublishedAt: time.Now()},
+			{ID: 2, ChannelID: 1, Text: "The central bank kept the key rate and this is the news", PublishedAt: time.Now()},
+			cached,
+			{ID: 4, ChannelID: 1, Text: "🔥🔥🔥", PublishedAt: time.Now()},
+		},
+	}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+
+	if _, err := service.BuildForDate(42, time.Now()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(ranker.captured) != 2 || ranker.captured[0].ID != 1 || ranker.captured[1].ID != 4 {
+		t.Fatalf("ожидали посты 1 и 4, получили %+v", ranker.captured)
+	}
+	if repo.postLangs[1] != "ru" || repo.postLangs[2] != "en" {
+		t.Fatalf("язык не сохранён: %+v", repo.postLangs)
+	}
+	if _, ok := repo.postLangs[3]; ok {
+		t.Fatal("пост с сохранённым языком не должен определяться повторно")
+	}
+}
+
+func TestPostWithLangKeepsMeta(t *testing.T) {
+	post := domain.Post{RawMetaJSON: mustJSON(map[string]any{"views": 10})}.WithLang("uk")
+	if lang, ok := post.Lang(); !ok || lang != "uk" {
+		t.Fatalf("ожидали uk, получили %q (ok=%v)", lang, ok)
+	}
+	if engagementScore(post) != 10 {
+		t.Fatalf("мета поста потеряна: %s", post.RawMetaJSON)
+	}
+}
@@ -0,0 +1,132 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// LayoutData — данные, доступные в шаблонах шапки и подвала дайджеста.
+type LayoutData struct {
+	Date      time.Time
+	ItemCount int
+	PlanName  string
+}
+
+// Layout задаёт шапку и подвал дайджеста шаблонами text/template.
+// Результат шаблона вставляется в HTML-сообщение как есть.
+type Layout struct {
+	header *template.Template
+	footer *template.Template
+}
+
+// DefaultFooterTemplate воспроизводит подвал, который дайджест выводил до появления шаблонов.
+var DefaultFooterTemplate = buildFooterSection()
+
+var defaultLayout = mustLayout("", DefaultFooterTemplate)
+
+// NewLayout разбирает шаблоны и пробно исполняет их, чтобы ошибки всплыли при старте, а не при рассылке.
+// Пустой шаблон отключает соответствующую секцию.
+func NewLayout(header, footer string) (*Layout, error) {
+	l := &Layout{}
+	var err error
+	if l.header, err = parseLayoutTemplate("header", header); err != nil {
+		return nil, err
+	}
+	if l.footer, err = parseLayoutTemplate("footer", footer); err != nil {
+		return nil, err
+	}
+	sample := LayoutData{Date: time.Now().UTC(), ItemCount: 1, PlanName: "free"}
+	if _, err := l.render(l.header, sample); err != nil {
+		return nil, fmt.Errorf("шаблон шапки дайджеста: %w", err)
+	}
+	if _, err := l.render(l.footer, sample); err != nil {
+		return nil, fmt.Errorf("шаблон подвала дайджеста: %w", err)
+	}
+	return l, nil
+}
+
+// DefaultLayout возвращает оформление без шапки и со стандартным подвалом.
+func DefaultLayout() *Layout {
+	return defaultLayout
+}
+
+func mustLayout(header, footer string) *Layout {
+	l, err := NewLayout(header, footer)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func parseLayoutTemplate(name, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("разбор шаблона %s дайджеста: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func (l *Layout) render(tmpl *template.Template, data LayoutData) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Format формирует дайджест с шапкой и подвалом по шаблонам. planName попадает в шаблоны как есть.
+// Если шаблон не исполнился на реальных данных, секция берётся из стандартного оформления.
+func (l *Layout) Format(d domain.Digest, planName string) string {
+	return l.format(d, len(d.Items), planName)
+}
+
+// FormatWithLead выносит главный пункт в отдельное сообщение lead, чтобы у него
+// показывалось превью ссылки; body — остальной дайджест. Если у главного пункта
+// нет ссылки, lead пустой, а body совпадает с Format.
+func (l *Layout) FormatWithLead(d domain.Digest, planName string) (lead, body string) {
+	if len(d.Items) == 0 || strings.TrimSpace(d.Items[0].Post.URL) == "" {
+		return "", l.Format(d, planName)
+	}
+	lead = formatLeadItem(d.Items[0])
+	rest := d
+	rest.Items = d.Items[1:]
+	return lead, l.format(rest, len(d.Items), planName)
+}
+
+func (l *Layout) format(d domain.Digest, itemCount int, planName string) string {
+	if l == nil {
+		l = defaultLayout
+	}
+	data := LayoutData{Date: d.Date, ItemCount: itemCount, PlanName: planName}
+	header, err := l.render(l.header, data)
+	if err != nil {
+		header = ""
+	}
+	footer, err := l.render(l.footer, data)
+	if err != nil {
+		footer = DefaultFooterTemplate
+	}
+
+	sections := make([]string, 0, 3)
+	if header != "" {
+		sections = append(sections, header)
+	}
+	if body := formatDigestBody(d); body != "" {
+		sections = append(sections, body)
+	}
+	if footer != "" {
+		sections = append(sections, footer)
+	}
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,23 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// PreviewItems — сколько пунктов в пробном дайджесте канала для /peek.
+const PreviewItems = 3
+
+// PreviewChannel строит пробный дайджест ещё не добавленного канала: собирает посты
+// за последние сутки и ранжирует не больше PreviewItems самых популярных. Посты и вердикты
+// фильтров не сохраняются — канал может так и не попасть в подписки.
+func (s *Service) PreviewChannel(user domain.User, channel domain.Channel) (domain.Digest, error) {
+	posts, err := s.collector.Collect24h(channel)
+	if err != nil {
+		return domain.Digest{}, fmt.Errorf("сбор истории %s: %w", channel.Alias, err)
+	}
+	posts = filterTopPosts(posts, PreviewItems)
+	return s.buildDigestFromPosts(user, time.Now().UTC(), posts)
+}
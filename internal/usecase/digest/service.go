@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"tg-digest-bot/internal/domain"
 	"tg-digest-bot/internal/infra/metrics"
 )
@@ -23,21 +25,41 @@ const topPostsPerChannel = 10
 
 // Service реализует бизнес-логику построения дайджестов.
 type Service struct {
-	users      domain.UserRepo
-	channels   domain.ChannelRepo
-	posts      domain.PostRepo
-	digestRepo domain.DigestRepo
-	summarizer domain.Summarizer
-	ranker     domain.Ranker
-	collector  domain.Collector
-	maxItems   int
+	users       domain.UserRepo
+	channels    domain.ChannelRepo
+	posts       domain.PostRepo
+	collections domain.ChannelCollectionRepo
+	digestRepo  domain.DigestRepo
+	summarizer  domain.Summarizer
+	ranker      domain.Ranker
+	collector   domain.Collector
+	maxItems    int
+	adFilter    *AdFilter
+
+	collectLimits CollectLimits
+	spendCap      *SpendCap
 }
 
 var _ domain.DigestService = (*Service)(nil)
 
 // NewService создаёт сервис дайджестов.
-func NewService(users domain.UserRepo, channels domain.ChannelRepo, posts domain.PostRepo, digestRepo domain.DigestRepo, summarizer domain.Summarizer, ranker domain.Ranker, collector domain.Collector, maxItems int) *Service {
-	return &Service{users: users, channels: channels, posts: posts, digestRepo: digestRepo, summarizer: summarizer, ranker: ranker, collector: collector, maxItems: maxItems}
+func NewService(users domain.UserRepo, channels domain.ChannelRepo, posts domain.PostRepo, collections domain.ChannelCollectionRepo, digestRepo domain.DigestRepo, summarizer domain.Summarizer, ranker domain.Ranker, collector domain.Collector, maxItems int) *Service {
+	return &Service{users: users, channels: channels, posts: posts, collections: collections, digestRepo: digestRepo, summarizer: summarizer, ranker: ranker, collector: collector, maxItems: maxItems}
+}
+
+// SetAdFilter подключает фильтр рекламы для пользователей, включивших /filter_ads.
+func (s *Service) SetAdFilter(filter *AdFilter) {
+	s.adFilter = filter
+}
+
+// SetCollectLimits задаёт параллельность и общий таймаут сбора каналов одной задачи.
+func (s *Service) SetCollectLimits(limits CollectLimits) {
+	s.collectLimits = limits
+}
+
+// SetSpendCap включает мягкий месячный лимит LLM; nil выключает.
+func (s *Service) SetSpendCap(spendCap *SpendCap) {
+	s.spendCap = spendCap
 }
 
 // BuildAndSendNow строит дайджест и помечает его доставленным.
@@ -50,11 +72,17 @@ func (s *Service) BuildAndSendNow(userID int64) error {
 	if err != nil {
 		return fmt.Errorf("сохранение дайджеста: %w", err)
 	}
-	return s.digestRepo.MarkDelivered(saved.UserID, saved.Date)
+	return s.digestRepo.MarkDelivered(saved.ID)
 }
 
-// BuildForDate строит дайджест за указанный день.
+// BuildForDate строит дайджест за сутки, предшествующие date.
 func (s *Service) BuildForDate(userID int64, date time.Time) (domain.Digest, error) {
+	return s.BuildForWindow(userID, date, date.Add(-24*time.Hour), date)
+}
+
+// BuildForWindow строит дайджест за день date по постам, опубликованным в окне [since, until].
+// Нужен для дайджестов за прошедшие дни, когда более свежие посты в него попадать не должны.
+func (s *Service) BuildForWindow(userID int64, date, since, until time.Time) (domain.Digest, error) {
 	user, userChannels, err := s.loadUserAndChannels(userID)
 	if err != nil {
 		return domain.Digest{}, err
@@ -62,18 +90,28 @@ func (s *Service) BuildForDate(userID int64, date time.Time) (domain.Digest, err
 
 	var channelIDs []int64
 	for _, ch := range userChannels {
+		if ch.Muted {
+			continue
+		}
 		channelIDs = append(channelIDs, ch.ChannelID)
 	}
 
-	since := date.Add(-24 * time.Hour)
-	posts, err := s.posts.ListRecentPosts(channelIDs, since)
+	// Сбор захватывает чуть больше суток, но в дайджест попадает только запрошенное окно.
+	posts, err := s.posts.ListRecentPostsBetween(channelIDs, since, until)
 	if err != nil {
 		return domain.Digest{}, fmt.Errorf("получение постов: %w", err)
 	}
 
+	posts = s.dropAds(user, posts)
+	posts = s.dropForeignLanguage(user, posts)
 	posts = filterTopPosts(posts, topPostsPerChannel)
 
-	return s.buildDigestFromPosts(user, date, posts)
+	digest, err := s.buildDigestFromPosts(user, date, posts)
+	if err != nil {
+		return domain.Digest{}, err
+	}
+	digest.TopChannel = pickTopChannel(digest.Items, userChannels)
+	return digest, nil
 }
 
 // BuildChannelForDate строит дайджест за указанный день по конкретному каналу.
@@ -99,10 +137,12 @@ func (s *Service) BuildChannelForDate(userID, channelID int64, date time.Time) (
 	}
 
 	since := date.Add(-24 * time.Hour)
-	posts, err := s.posts.ListRecentPosts([]int64{channelID}, since)
+	posts, err := s.posts.ListRecentPostsBetween([]int64{channelID}, since, date)
 	if err != nil {
 		return domain.Digest{}, fmt.Errorf("получение постов: %w", err)
 	}
+	posts = s.dropAds(user, posts)
+	posts = s.dropForeignLanguage(user, posts)
 
 	return s.buildDigestFromPosts(user, date, posts)
 }
@@ -121,7 +161,7 @@ func (s *Service) BuildTagsForDate(userID int64, tags []string, date time.Time)
 
 	channelIDs := make([]int64, 0, len(userChannels))
 	for _, ch := range userChannels {
-		if len(ch.Tags) == 0 {
+		if len(ch.Tags) == 0 || ch.Muted {
 			continue
 		}
 		if hasAnyTag(ch.Tags, cleaned) {
@@ -134,31 +174,130 @@ func (s *Service) BuildTagsForDate(userID int64, tags []string, date time.Time)
 	}
 
 	since := date.Add(-24 * time.Hour)
-	posts, err := s.posts.ListRecentPosts(channelIDs, since)
+	posts, err := s.posts.ListRecentPostsBetween(channelIDs, since, date)
 	if err != nil {
 		return domain.Digest{}, fmt.Errorf("получение постов: %w", err)
 	}
 
+	posts = s.dropAds(user, posts)
+	posts = s.dropForeignLanguage(user, posts)
 	posts = filterTopPosts(posts, topPostsPerChannel)
 
-	return s.buildDigestFromPosts(user, date, posts)
+	digest, err := s.buildDigestFromPosts(user, date, posts)
+	if err != nil {
+		return domain.Digest{}, err
+	}
+	digest.TopChannel = pickTopChannel(digest.Items, userChannels)
+	return digest, nil
 }
 
-// CollectNow запускает сбор постов у списка каналов.
-func (s *Service) CollectNow(ctx context.Context, channels []domain.Channel) error {
-	for _, ch := range channels {
-		metrics.IncDigestForChannel(ch.ID)
-		posts, err := s.collector.Collect24h(ch)
-		if err != nil {
-			return fmt.Errorf("сбор истории %s: %w", ch.Alias, err)
+// CollectNow собирает посты каналов, обрабатывая одновременно столько каналов, сколько
+// разрешено тарифу пользователя. Если пользователь включил /comments, к обсуждаемым постам
+// дописываются лучшие комментарии. Ошибка одного канала не останавливает остальные и попадает
+// в CollectResult.Failed. Ошибка возвращается, если не собрался ни один канал, пул MTProto
+// недоступен или истёк JobTimeout: после него новые каналы не берутся, а уже начатые
+// завершаются по таймауту коллектора.
+func (s *Service) CollectNow(ctx context.Context, user domain.User, channels []domain.Channel) (CollectResult, error) {
+	if s.collectLimits.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.collectLimits.JobTimeout)
+		defer cancel()
+	}
+	errs := make([]error, len(channels))
+	started := make([]bool, len(channels))
+	var g errgroup.Group
+	g.SetLimit(s.collectLimits.concurrency(user.Role))
+	for i, ch := range channels {
+		if ctx.Err() != nil {
+			break
 		}
-		if err := s.posts.SavePosts(ch.ID, posts); err != nil {
-			return fmt.Errorf("сохранение постов: %w", err)
+		started[i] = true
+		g.Go(func() error {
+			errs[i] = s.collectChannel(ch, user.WantsComments())
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var result CollectResult
+	for i, ch := range channels {
+		switch {
+		case !started[i]:
+		case errs[i] != nil:
+			result.Failed = append(result.Failed, ChannelError{Channel: ch, Err: errs[i]})
+		default:
+			result.Collected++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("сбор каналов прерван: %w", err)
+	}
+	return result, result.err()
+}
+
+func (s *Service) collectChannel(ch domain.Channel, comments bool) error {
+	metrics.IncDigestForChannel(ch.ID)
+	posts, err := s.collector.Collect24h(ch)
+	if err != nil {
+		return fmt.Errorf("сбор истории %s: %w", ch.Alias, err)
+	}
+	if cc, ok := s.collector.(domain.CommentCollector); ok && comments {
+		// Комментарии — дополнение: без них пост всё равно попадает в дайджест.
+		if enriched, err := cc.CollectComments(ch, posts); err == nil {
+			posts = enriched
+		}
+	}
+	for i := range posts {
+		text := posts[i].FullText
+		if text == "" {
+			text = posts[i].Text
+		}
+		posts[i] = posts[i].WithLang(DetectLanguage(text))
+	}
+	if err := s.posts.SavePosts(ch.ID, posts); err != nil {
+		return fmt.Errorf("сохранение постов: %w", err)
+	}
+	if s.collections != nil {
+		lastMsgID := ch.LastCollectedMsgID
+		for _, post := range posts {
+			if post.TGMsgID > lastMsgID {
+				lastMsgID = post.TGMsgID
+			}
+		}
+		if err := s.collections.MarkChannelCollected(ch.ID, time.Now().UTC(), lastMsgID); err != nil {
+			return fmt.Errorf("отметка сбора канала: %w", err)
 		}
 	}
 	return nil
 }
 
+// CollectStale собирает посты только тех каналов, которые не собирались после fresh.
+// Используется при повторных попытках задачи, чтобы не ходить в MTProto заново.
+func (s *Service) CollectStale(ctx context.Context, user domain.User, channels []domain.Channel, fresh time.Time) (CollectResult, error) {
+	if s.collections == nil {
+		return s.CollectNow(ctx, user, channels)
+	}
+	ids := make([]int64, 0, len(channels))
+	for _, ch := range channels {
+		ids = append(ids, ch.ID)
+	}
+	collected, err := s.collections.ListChannelCollections(ids)
+	if err != nil {
+		return CollectResult{}, fmt.Errorf("получение времени сбора: %w", err)
+	}
+	stale := make([]domain.Channel, 0, len(channels))
+	for _, ch := range channels {
+		if at, ok := collected[ch.ID]; ok && !at.Before(fresh) {
+			continue
+		}
+		stale = append(stale, ch)
+	}
+	if len(stale) == 0 {
+		return CollectResult{}, nil
+	}
+	return s.CollectNow(ctx, user, stale)
+}
+
 func (s *Service) loadUserAndChannels(userTGID int64) (domain.User, []domain.UserChannel, error) {
 	user, err := s.users.GetByTGID(userTGID)
 	if err != nil {
@@ -174,39 +313,144 @@ func (s *Service) loadUserAndChannels(userTGID int64) (domain.User, []domain.Use
 	return user, userChannels, nil
 }
 
+// dropAds убирает рекламные посты, если пользователь включил фильтр.
+// Решение сохраняется в мете поста, чтобы не классифицировать его повторно.
+func (s *Service) dropAds(user domain.User, posts []domain.Post) []domain.Post {
+	if s.adFilter == nil || !user.FilterAds || len(posts) == 0 {
+		return posts
+	}
+	kept := make([]domain.Post, 0, len(posts))
+	for _, post := range posts {
+		verdict, ok := post.AdVerdict()
+		if !ok {
+			var err error
+			verdict, err = s.adFilter.Classify(post)
+			if err != nil {
+				// Классификатор недоступен — лучше показать пост, чем потерять его.
+				kept = append(kept, post)
+				continue
+			}
+			// Ошибка сохранения не критична: в худшем случае пост классифицируется ещё раз.
+			_ = s.posts.SetPostAdVerdict(post.ID, verdict)
+		}
+		if verdict.IsAd {
+			continue
+		}
+		kept = append(kept, post)
+	}
+	return kept
+}
+
+// dropForeignLanguage оставляет посты на языке, выбранном пользователем. Посты, язык
+// которых не распознан, остаются в дайджесте.
+func (s *Service) dropForeignLanguage(user domain.User, posts []domain.Post) []domain.Post {
+	if user.DigestLang == "" || len(posts) == 0 {
+		return posts
+	}
+	kept := make([]domain.Post, 0, len(posts))
+	for _, post := range posts {
+		lang, ok := post.Lang()
+		if !ok {
+			// Посты, собранные до появления фильтра, размечаем при первом обращении.
+			lang = DetectLanguage(post.Text)
+			_ = s.posts.SetPostLang(post.ID, lang)
+		}
+		if lang != "" && lang != user.DigestLang {
+			continue
+		}
+		kept = append(kept, post)
+	}
+	return kept
+}
+
 func (s *Service) buildDigestFromPosts(user domain.User, date time.Time, posts []domain.Post) (domain.Digest, error) {
 	if len(posts) == 0 {
 		return domain.Digest{UserID: user.ID, Date: date, Items: nil}, nil
 	}
 
-	outline, err := s.ranker.Rank(posts)
+	if !user.WantsComments() {
+		// Комментарии собраны для другого подписчика канала и в этот дайджест не попадают.
+		stripped := make([]domain.Post, len(posts))
+		for i, post := range posts {
+			stripped[i] = post.WithoutComments()
+		}
+		posts = stripped
+	}
+
+	ranker, summarizer, simplified := s.llmFor(user)
+	outline, err := ranker.Rank(posts)
 	if err != nil {
 		return domain.Digest{}, fmt.Errorf("ранжирование: %w", err)
 	}
 
 	if len(outline.Items) == 0 {
-		return domain.Digest{UserID: user.ID, Date: date, Overview: outline.Overview, Theses: outline.Theses, Items: nil}, nil
+		return domain.Digest{UserID: user.ID, Date: date, Overview: outline.Overview, Theses: outline.Theses, Items: nil, Simplified: simplified}, nil
 	}
 
 	sort.SliceStable(outline.Items, func(i, j int) bool { return outline.Items[i].Score > outline.Items[j].Score })
-	if s.maxItems > 0 && len(outline.Items) > s.maxItems {
-		outline.Items = outline.Items[:s.maxItems]
+	if limit := user.Plan().DigestItems(s.maxItems); limit > 0 && len(outline.Items) > limit {
+		outline.Items = outline.Items[:limit]
 	}
 
+	if err := fillSummaries(summarizer, outline.Items); err != nil {
+		return domain.Digest{}, fmt.Errorf("суммаризация: %w", err)
+	}
+	orderItems(outline.Items, user.DigestOrder)
+
 	items := make([]domain.DigestItem, 0, len(outline.Items))
 	for idx, rp := range outline.Items {
-		summary := rp.Summary
-		if summary.Headline == "" {
-			var err error
-			summary, err = s.summarizer.Summarize(rp.Post)
-			if err != nil {
-				return domain.Digest{}, fmt.Errorf("суммаризация: %w", err)
+		items = append(items, domain.DigestItem{Post: rp.Post, Summary: rp.Summary, Rank: idx + 1})
+	}
+
+	return domain.Digest{UserID: user.ID, Date: date.Truncate(24 * time.Hour), Overview: outline.Overview, Theses: outline.Theses, Items: items, Simplified: simplified}, nil
+}
+
+// llmFor выбирает ранжировщик и суммаризатор: после исчерпания лимита тарифа — экстрактивные.
+// Если расход не удалось прочитать, лимит не применяется: дайджест важнее точного учёта.
+func (s *Service) llmFor(user domain.User) (domain.Ranker, domain.Summarizer, bool) {
+	if s.spendCap == nil {
+		return s.ranker, s.summarizer, false
+	}
+	exceeded, err := s.spendCap.Exceeded(context.Background(), user, time.Now())
+	if err != nil || !exceeded {
+		return s.ranker, s.summarizer, false
+	}
+	return s.spendCap.Ranker, s.spendCap.Summarizer, true
+}
+
+// fillSummaries дописывает резюме постам, для которых ранжировщик его не вернул.
+// Если суммаризатор умеет работать пачками, все такие посты уходят одним вызовом.
+func fillSummaries(summarizer domain.Summarizer, items []domain.RankedPost) error {
+	missing := make([]int, 0, len(items))
+	for idx, rp := range items {
+		if rp.Summary.Headline == "" {
+			missing = append(missing, idx)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if batch, ok := summarizer.(domain.BatchSummarizer); ok && len(missing) > 1 {
+		posts := make([]domain.Post, 0, len(missing))
+		for _, idx := range missing {
+			posts = append(posts, items[idx].Post)
+		}
+		summaries, err := batch.SummarizeBatch(context.Background(), posts)
+		if err == nil && len(summaries) == len(posts) {
+			for i, idx := range missing {
+				items[idx].Summary = summaries[i]
 			}
+			return nil
 		}
-		items = append(items, domain.DigestItem{Post: rp.Post, Summary: summary, Rank: idx + 1})
 	}
-
-	return domain.Digest{UserID: user.ID, Date: date.Truncate(24 * time.Hour), Overview: outline.Overview, Theses: outline.Theses, Items: items}, nil
+	for _, idx := range missing {
+		summary, err := summarizer.Summarize(items[idx].Post)
+		if err != nil {
+			return err
+		}
+		items[idx].Summary = summary
+	}
+	return nil
 }
 
 func filterTopPosts(posts []domain.Post, perChannelLimit int) []domain.Post {
@@ -276,6 +520,55 @@ func hasAnyTag(channelTags, requested []string) bool {
 	return false
 }
 
+// pickTopChannel выбирает канал, чьи пункты дайджеста набрали больше всего просмотров и реакций.
+// Для дайджеста из одного канала выделять нечего, поэтому возвращается nil.
+func pickTopChannel(items []domain.DigestItem, userChannels []domain.UserChannel) *domain.DigestTopChannel {
+	scores := make(map[int64]float64)
+	for _, item := range items {
+		scores[item.Post.ChannelID] += engagementScore(item.Post)
+	}
+	if len(scores) < 2 {
+		return nil
+	}
+	var (
+		bestID    int64
+		bestScore float64
+	)
+	for _, item := range items {
+		// Обходим пункты по порядку, чтобы при равенстве побеждал канал с более высоким пунктом.
+		id := item.Post.ChannelID
+		if score := scores[id]; score > bestScore {
+			bestID, bestScore = id, score
+		}
+	}
+	if bestScore <= 0 {
+		return nil
+	}
+	top := &domain.DigestTopChannel{ChannelID: bestID, Engagement: bestScore}
+	for _, uc := range userChannels {
+		if uc.ChannelID == bestID {
+			top.Title = uc.Channel.Title
+			top.Alias = uc.Channel.Alias
+			break
+		}
+	}
+	if top.Title == "" && top.Alias == "" {
+		return nil
+	}
+	return top
+}
+
+// orderItems переставляет отобранные пункты в порядке, выбранном пользователем. Отбор
+// по оценке LLM не меняется; при равенстве сохраняется её порядок.
+func orderItems(items []domain.RankedPost, order domain.DigestOrder) {
+	switch order {
+	case domain.DigestOrderTime:
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Post.PublishedAt.After(items[j].Post.PublishedAt) })
+	case domain.DigestOrderEngagement:
+		sort.SliceStable(items, func(i, j int) bool { return engagementScore(items[i].Post) > engagementScore(items[j].Post) })
+	}
+}
+
 func engagementScore(post domain.Post) float64 {
 	if len(post.RawMetaJSON) == 0 {
 		return 0
@@ -1,7 +1,11 @@
 package digest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +16,8 @@ type stubRepo struct {
 	user         domain.User
 	posts        []domain.Post
 	userChannels []domain.UserChannel
+	adVerdicts   map[int64]domain.AdVerdict
+	postLangs    map[int64]string
 }
 
 func (s *stubRepo) UpsertByTGID(_ domain.TelegramProfile) (domain.User, bool, error) {
@@ -21,16 +27,37 @@ func (s *stubRepo) GetByTGID(_ int64) (domain.User, error) { return s.user, nil
 func (s *stubRepo) ListForDailyTime(_ time.Time) ([]domain.User, error) {
 	return []domain.User{s.user}, nil
 }
-func (s *stubRepo) UpdateDailyTime(_ int64, _ time.Time) error  { return nil }
-func (s *stubRepo) UpdateTimezone(_ int64, _ string) error      { return nil }
-func (s *stubRepo) UpdateRole(_ int64, _ domain.UserRole) error { return nil }
-func (s *stubRepo) DeleteUserData(_ int64) error                { return nil }
+func (s *stubRepo) UpdateDailyTime(_ int64, _ time.Time) error          { return nil }
+func (s *stubRepo) UpdateTimezone(_ int64, _ string) error              { return nil }
+func (s *stubRepo) SetChannelLimitOverride(_ int64, _ *int) error       { return nil }
+func (s *stubRepo) SetFilterAds(_ int64, _ bool) error                  { return nil }
+func (s *stubRepo) SetLinkPreviews(_ int64, _ bool) error               { return nil }
+func (s *stubRepo) SetCollectComments(_ int64, _ bool) error            { return nil }
+func (s *stubRepo) SetDigestOrder(_ int64, _ domain.DigestOrder) error  { return nil }
+func (s *stubRepo) SetDeliveryChat(_, _ int64) error                    { return nil }
+func (s *stubRepo) SetPaused(_ int64, _ bool, _ *time.Time) error       { return nil }
+func (s *stubRepo) SetDigestLang(_ int64, _ string) error               { return nil }
+func (s *stubRepo) UpdateSettings(_ int64, _ domain.UserSettings) error { return nil }
+func (s *stubRepo) SetDeliveryDisabled(_ int64, _ bool) error           { return nil }
+func (s *stubRepo) UpdateRole(_ int64, _ domain.UserRole) error         { return nil }
+func (s *stubRepo) DeleteUserData(_ int64) error                        { return nil }
 func (s *stubRepo) ReserveManualRequest(_ int64, _ time.Time) (domain.ManualRequestState, error) {
 	return domain.ManualRequestState{Allowed: true, Plan: domain.PlanForRole(domain.UserRoleFree)}, nil
 }
+func (s *stubRepo) GrantManualRequests(_ int64, _ int, _ time.Time) (domain.ManualRequestState, error) {
+	return domain.ManualRequestState{Allowed: true, Plan: domain.PlanForRole(domain.UserRoleFree)}, nil
+}
 func (s *stubRepo) ApplyReferral(_ string, _ int64) (domain.ReferralResult, error) {
 	return domain.ReferralResult{User: s.user}, nil
 }
+func (s *stubRepo) TopReferrers(_ int) ([]domain.ReferrerStanding, error) { return nil, nil }
+func (s *stubRepo) ReferrerRank(_ int64) (domain.ReferrerStanding, error) {
+	return domain.ReferrerStanding{}, nil
+}
+func (s *stubRepo) ListFlaggedReferrals(int) ([]domain.FlaggedReferral, error) { return nil, nil }
+func (s *stubRepo) ApproveReferral(int64) (domain.ReferralResult, error) {
+	return domain.ReferralResult{}, nil
+}
 func (s *stubRepo) UpsertChannel(_ domain.ChannelMeta) (domain.Channel, error) {
 	return domain.Channel{}, nil
 }
@@ -40,15 +67,25 @@ func (s *stubRepo) ListUserChannels(_ int64, _ int, _ int) ([]domain.UserChannel
 	}
 	return s.userChannels, nil
 }
-func (s *stubRepo) AttachChannelToUser(_ int64, _ int64) error   { return nil }
-func (s *stubRepo) DetachChannelFromUser(_ int64, _ int64) error { return nil }
-func (s *stubRepo) SetMuted(_ int64, _ int64, _ bool) error      { return nil }
-func (s *stubRepo) CountUserChannels(_ int64) (int, error)       { return len(s.userChannels), nil }
+func (s *stubRepo) AttachChannelToUser(_ int64, _ int64) error    { return nil }
+func (s *stubRepo) DetachChannelFromUser(_ int64, _ int64) error  { return nil }
+func (s *stubRepo) SetMuted(_ int64, _ int64, _ bool) error       { return nil }
+func (s *stubRepo) MuteUntil(_ int64, _ int64, _ time.Time) error { return nil }
+func (s *stubRepo) CountUserChannels(_ int64) (int, error)        { return len(s.userChannels), nil }
+func (s *stubRepo) GetChannel(_ int64) (domain.Channel, error) {
+	return domain.Channel{}, domain.ErrChannelNotFound
+}
+func (s *stubRepo) SearchChannels(_ string, _ int) ([]domain.Channel, error) { return nil, nil }
 func (s *stubRepo) UpdateUserChannelTags(userID, channelID int64, tags []string) error {
 	return nil
 }
 func (s *stubRepo) SavePosts(_ int64, _ []domain.Post) error { return nil }
-func (s *stubRepo) ListRecentPosts(channelIDs []int64, _ time.Time) ([]domain.Post, error) {
+func (s *stubRepo) ListRecentPosts(channelIDs []int64, since time.Time) ([]domain.Post, error) {
+	return s.ListRecentPostsBetween(channelIDs, since, time.Now())
+}
+
+// ListRecentPostsBetween пропускает посты без даты: в большинстве тестов она не важна.
+func (s *stubRepo) ListRecentPostsBetween(channelIDs []int64, since, until time.Time) ([]domain.Post, error) {
 	if len(channelIDs) == 0 {
 		return nil, nil
 	}
@@ -58,16 +95,41 @@ func (s *stubRepo) ListRecentPosts(channelIDs []int64, _ time.Time) ([]domain.Po
 	}
 	var filtered []domain.Post
 	for _, post := range s.posts {
-		if _, ok := lookup[post.ChannelID]; ok {
+		inWindow := post.PublishedAt.IsZero() || (!post.PublishedAt.Before(since) && !post.PublishedAt.After(until))
+		if _, ok := lookup[post.ChannelID]; ok && inWindow {
 			filtered = append(filtered, post)
 		}
 	}
 	return filtered, nil
 }
+func (s *stubRepo) GetPost(_, _ int64) (domain.Post, error) {
+	return domain.Post{}, domain.ErrPostNotFound
+}
 func (s *stubRepo) SaveSummary(_ int64, _ domain.Summary) (int64, error) { return 1, nil }
-func (s *stubRepo) CreateDigest(d domain.Digest) (domain.Digest, error)  { return d, nil }
-func (s *stubRepo) MarkDelivered(_ int64, _ time.Time) error             { return nil }
-func (s *stubRepo) WasDelivered(_ int64, _ time.Time) (bool, error)      { return false, nil }
+func (s *stubRepo) SetPostLang(postID int64, lang string) error {
+	if s.postLangs == nil {
+		s.postLangs = make(map[int64]string)
+	}
+	s.postLangs[postID] = lang
+	return nil
+}
+func (s *stubRepo) SetPostAdVerdict(postID int64, verdict domain.AdVerdict) error {
+	if s.adVerdicts == nil {
+		s.adVerdicts = make(map[int64]domain.AdVerdict)
+	}
+	s.adVerdicts[postID] = verdict
+	return nil
+}
+func (s *stubRepo) CreateDigest(d domain.Digest) (domain.Digest, error) { return d, nil }
+func (s *stubRepo) MarkDelivered(int64) error                           { return nil }
+func (s *stubRepo) WasDelivered(_ int64, _ time.Time) (bool, error)     { return false, nil }
+func (s *stubRepo) GetDigestWithItems(_ int64, _ int64) (domain.Digest, error) {
+	return domain.Digest{}, domain.ErrDigestNotFound
+}
+func (s *stubRepo) DigestChannelBreakdown(_ int64) ([]domain.DigestChannelShare, error) {
+	return nil, nil
+}
+func (s *stubRepo) SnoozeDigest(_ int64, _ int64, _ int) (bool, error) { return true, nil }
 func (s *stubRepo) ListDigestHistory(_ int64, _ time.Time) ([]domain.Digest, error) {
 	return nil, nil
 }
@@ -76,7 +138,7 @@ func TestBuildForDate(t *testing.T) {
 	repo := &stubRepo{user: domain.User{ID: 1, TGUserID: 42}, posts: []domain.Post{{ID: 1, ChannelID: 1, URL: "https://t.me/a/1", Text: "пример", PublishedAt: time.Now()}}}
 	sum := &fakeSummarizer{}
 	ranker := &fakeRanker{}
-	service := NewService(repo, repo, repo, repo, sum, ranker, nil, 10)
+	service := NewService(repo, repo, repo, nil, repo, sum, ranker, nil, 10)
 	digest, err := service.BuildForDate(42, time.Now())
 	if err != nil {
 		t.Fatalf("не ожидали ошибку: %v", err)
@@ -105,7 +167,7 @@ func TestBuildForDateFiltersTopPosts(t *testing.T) {
 	}
 	sum := &fakeSummarizer{}
 	ranker := &fakeRanker{}
-	service := NewService(repo, repo, repo, repo, sum, ranker, nil, 10)
+	service := NewService(repo, repo, repo, nil, repo, sum, ranker, nil, 10)
 
 	_, err := service.BuildForDate(42, time.Now())
 	if err != nil {
@@ -142,7 +204,7 @@ func TestBuildForDateFiltersTopPostsPerChannel(t *testing.T) {
 	}
 	sum := &fakeSummarizer{}
 	ranker := &fakeRanker{}
-	service := NewService(repo, repo, repo, repo, sum, ranker, nil, 10)
+	service := NewService(repo, repo, repo, nil, repo, sum, ranker, nil, 10)
 
 	_, err := service.BuildForDate(42, time.Now())
 	if err != nil {
@@ -164,6 +226,148 @@ func TestBuildForDateFiltersTopPostsPerChannel(t *testing.T) {
 	}
 }
 
+func TestBuildForDateSummarizesInBatch(t *testing.T) {
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "один"},
+			{ID: 2, ChannelID: 1, Text: "два"},
+			{ID: 3, ChannelID: 1, Text: "три"},
+		},
+		userChannels: []domain.UserChannel{{ChannelID: 1}},
+	}
+	sum := &batchSummarizer{}
+	service := NewService(repo, repo, repo, nil, repo, sum, unsummarizedRanker{}, nil, 10)
+
+	digest, err := service.BuildForDate(42, time.Now())
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if sum.batchCalls != 1 || sum.singleCalls != 0 {
+		t.Fatalf("ожидали один пакетный вызов, получили пакетных %d и поштучных %d", sum.batchCalls, sum.singleCalls)
+	}
+	if len(digest.Items) != 3 || digest.Items[2].Summary.Headline != "пачкой" {
+		t.Fatalf("ожидали резюме из пакетного вызова: %+v", digest.Items)
+	}
+}
+
+func TestBuildForDateHidesCommentsFromUsersWithoutAccess(t *testing.T) {
+	post := domain.Post{ID: 1, ChannelID: 1, Text: "пост"}.WithComments([]string{"комментарий"})
+	for _, tc := range []struct {
+		name string
+		user domain.User
+		want bool
+	}{
+		{name: "free", user: domain.User{ID: 1, TGUserID: 42, CollectComments: true}, want: false},
+		{name: "pro", user: domain.User{ID: 1, TGUserID: 42, Role: domain.UserRolePro, CollectComments: true}, want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &stubRepo{user: tc.user, posts: []domain.Post{post}, userChannels: []domain.UserChannel{{ChannelID: 1}}}
+			ranker := &fakeRanker{}
+			service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+			if _, err := service.BuildForDate(42, time.Now()); err != nil {
+				t.Fatalf("не ожидали ошибку: %v", err)
+			}
+			if len(ranker.captured) != 1 {
+				t.Fatalf("ожидали один пост, получили %d", len(ranker.captured))
+			}
+			if got := ranker.captured[0].Comments() != nil; got != tc.want {
+				t.Fatalf("комментарии в дайджесте: %v, ожидали %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildForDateHighlightsTopChannel(t *testing.T) {
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "а", RawMetaJSON: mustJSON(map[string]int{"views": 500})},
+			{ID: 2, ChannelID: 2, Text: "б", RawMetaJSON: mustJSON(map[string]int{"views": 300, "reactions": 5})},
+			{ID: 3, ChannelID: 1, Text: "в", RawMetaJSON: mustJSON(map[string]int{"views": 100})},
+		},
+		userChannels: []domain.UserChannel{
+			{ChannelID: 1, Channel: domain.Channel{ID: 1, Alias: "first", Title: "Первый"}},
+			{ChannelID: 2, Channel: domain.Channel{ID: 2, Alias: "second", Title: "Второй"}},
+		},
+	}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, unsummarizedRanker{}, nil, 10)
+
+	digest, err := service.BuildForDate(42, time.Now())
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if digest.TopChannel == nil || digest.TopChannel.ChannelID != 2 || digest.TopChannel.Title != "Второй" {
+		t.Fatalf("ожидали канал дня «Второй», получили %+v", digest.TopChannel)
+	}
+
+	repo.userChannels = repo.userChannels[:1]
+	repo.posts = []domain.Post{repo.posts[0], repo.posts[2]}
+	digest, err = service.BuildForDate(42, time.Now())
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if digest.TopChannel != nil {
+		t.Fatalf("для одного канала выделение не нужно, получили %+v", digest.TopChannel)
+	}
+}
+
+func TestBuildForDateAppliesDigestOrder(t *testing.T) {
+	now := time.Now()
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "а", PublishedAt: now.Add(-time.Hour), RawMetaJSON: mustJSON(map[string]int{"views": 10})},
+			{ID: 2, ChannelID: 1, Text: "б", PublishedAt: now.Add(-3 * time.Hour), RawMetaJSON: mustJSON(map[string]int{"views": 900})},
+			{ID: 3, ChannelID: 1, Text: "в", PublishedAt: now.Add(-2 * time.Hour), RawMetaJSON: mustJSON(map[string]int{"views": 50})},
+		},
+	}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, unsummarizedRanker{}, nil, 10)
+
+	for order, want := range map[domain.DigestOrder][]int64{
+		"":                           {2, 3, 1},
+		domain.DigestOrderRelevance:  {2, 3, 1},
+		domain.DigestOrderTime:       {1, 3, 2},
+		domain.DigestOrderEngagement: {2, 3, 1},
+	} {
+		repo.user.DigestOrder = order
+		digest, err := service.BuildForDate(42, now)
+		if err != nil {
+			t.Fatalf("не ожидали ошибку: %v", err)
+		}
+		for i, item := range digest.Items {
+			if item.Post.ID != want[i] || item.Rank != i+1 {
+				t.Fatalf("порядок %q: ожидали посты %v, получили %+v", order, want, digest.Items)
+			}
+		}
+	}
+}
+
+func TestBuildForWindowSkipsPostsOutsideWindow(t *testing.T) {
+	day := time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "в окне", PublishedAt: day.Add(10 * time.Hour)},
+			{ID: 2, ChannelID: 1, Text: "на следующий день", PublishedAt: day.Add(30 * time.Hour)},
+			{ID: 3, ChannelID: 1, Text: "накануне", PublishedAt: day.Add(-2 * time.Hour)},
+		},
+	}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+
+	digest, err := service.BuildForWindow(42, day, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(ranker.captured) != 1 || ranker.captured[0].ID != 1 {
+		t.Fatalf("ожидали только пост из окна, получили %+v", ranker.captured)
+	}
+	if !digest.Date.Equal(day) {
+		t.Fatalf("ожидали дату дайджеста %s, получили %s", day, digest.Date)
+	}
+}
+
 func TestBuildChannelForDate(t *testing.T) {
 	var posts []domain.Post
 	for i := 0; i < 5; i++ {
@@ -177,7 +381,7 @@ func TestBuildChannelForDate(t *testing.T) {
 	}
 	sum := &fakeSummarizer{}
 	ranker := &fakeRanker{}
-	service := NewService(repo, repo, repo, repo, sum, ranker, nil, 10)
+	service := NewService(repo, repo, repo, nil, repo, sum, ranker, nil, 10)
 
 	_, err := service.BuildChannelForDate(42, 1, time.Now())
 	if err != nil {
@@ -203,7 +407,7 @@ func TestBuildTagsForDateFiltersChannels(t *testing.T) {
 	}
 	sum := &fakeSummarizer{}
 	ranker := &fakeRanker{}
-	service := NewService(repo, repo, repo, repo, sum, ranker, nil, 10)
+	service := NewService(repo, repo, repo, nil, repo, sum, ranker, nil, 10)
 
 	_, err := service.BuildTagsForDate(42, []string{"Новости"}, time.Now())
 	if err != nil {
@@ -218,6 +422,118 @@ func TestBuildTagsForDateFiltersChannels(t *testing.T) {
 	}
 }
 
+func TestCollectStaleSkipsFreshChannels(t *testing.T) {
+	repo := &stubRepo{}
+	now := time.Now().UTC()
+	collections := &fakeCollections{collected: map[int64]time.Time{1: now, 2: now.Add(-2 * time.Hour)}}
+	collector := &fakeCollector{}
+	service := NewService(repo, repo, repo, collections, repo, &fakeSummarizer{}, &fakeRanker{}, collector, 10)
+
+	channels := []domain.Channel{{ID: 1, Alias: "fresh"}, {ID: 2, Alias: "stale"}}
+	if _, err := service.CollectStale(context.Background(), domain.User{Role: domain.UserRoleFree}, channels, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(collector.aliases) != 1 || collector.aliases[0] != "stale" {
+		t.Fatalf("ожидали сбор только устаревшего канала, получили %v", collector.aliases)
+	}
+	if at := collections.collected[2]; at.Before(now) {
+		t.Fatalf("ожидали обновление времени сбора канала")
+	}
+}
+
+func TestCollectNowReportsFailedChannels(t *testing.T) {
+	collector := &fakeCollector{fail: map[string]error{"broken": errors.New("FLOOD_WAIT")}}
+	service := NewService(&stubRepo{}, nil, &stubRepo{}, nil, nil, nil, nil, collector, 10)
+
+	channels := []domain.Channel{{ID: 1, Alias: "ok"}, {ID: 2, Alias: "broken"}}
+	result, err := service.CollectNow(context.Background(), domain.User{Role: domain.UserRoleFree}, channels)
+	if err != nil {
+		t.Fatalf("ошибка одного канала не должна прерывать сбор: %v", err)
+	}
+	if result.Collected != 1 || len(result.Failed) != 1 || result.Failed[0].Channel.Alias != "broken" {
+		t.Fatalf("неожиданный итог сбора: %+v", result)
+	}
+	if aliases := result.FailedAliases(); len(aliases) != 1 || aliases[0] != "@broken" {
+		t.Fatalf("неожиданные алиасы: %v", aliases)
+	}
+
+	if _, err := service.CollectNow(context.Background(), domain.User{Role: domain.UserRoleFree}, channels[1:]); err == nil {
+		t.Fatalf("ожидали ошибку, если не собрался ни один канал")
+	}
+
+	collector.fail["broken"] = fmt.Errorf("сбор: %w", domain.ErrCollectorUnavailable)
+	if _, err := service.CollectNow(context.Background(), domain.User{Role: domain.UserRoleFree}, channels); !errors.Is(err, domain.ErrCollectorUnavailable) {
+		t.Fatalf("недоступность пула должна возвращаться ошибкой, получили %v", err)
+	}
+
+	collector.fail["broken"] = fmt.Errorf("сбор: %w", domain.ErrNoAvailableAccounts)
+	if _, err := service.CollectNow(context.Background(), domain.User{Role: domain.UserRoleFree}, channels); !errors.Is(err, domain.ErrNoAvailableAccounts) {
+		t.Fatalf("пустой пул аккаунтов должен возвращаться ошибкой, получили %v", err)
+	}
+}
+
+// slowCollector считает, сколько каналов собирается одновременно.
+type slowCollector struct {
+	delay    time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	calls    int
+}
+
+func (c *slowCollector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
+	return c.CollectSince(channel, time.Now().Add(-24*time.Hour))
+}
+
+func (c *slowCollector) CollectSince(domain.Channel, time.Time) ([]domain.Post, error) {
+	c.mu.Lock()
+	c.calls++
+	c.inFlight++
+	if c.inFlight > c.peak {
+		c.peak = c.inFlight
+	}
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return nil, nil
+}
+
+func TestCollectNowRespectsPlanConcurrency(t *testing.T) {
+	channels := make([]domain.Channel, 6)
+	for i := range channels {
+		channels[i] = domain.Channel{ID: int64(i + 1)}
+	}
+	limits := CollectLimits{Concurrency: 1, PlanConcurrency: map[domain.UserRole]int{domain.UserRolePro: 3}}
+	for role, want := range map[domain.UserRole]int{domain.UserRoleFree: 1, domain.UserRolePro: 3} {
+		collector := &slowCollector{delay: 20 * time.Millisecond}
+		service := NewService(&stubRepo{}, nil, &stubRepo{}, nil, nil, nil, nil, collector, 10)
+		service.SetCollectLimits(limits)
+		if _, err := service.CollectNow(context.Background(), domain.User{Role: role}, channels); err != nil {
+			t.Fatalf("%s: не ожидали ошибку: %v", role, err)
+		}
+		if collector.calls != len(channels) || collector.peak != want {
+			t.Fatalf("%s: ожидали %d вызовов и не больше %d одновременно, получили %d и %d", role, len(channels), want, collector.calls, collector.peak)
+		}
+	}
+}
+
+func TestCollectNowStopsAtJobTimeout(t *testing.T) {
+	channels := []domain.Channel{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	collector := &slowCollector{delay: 30 * time.Millisecond}
+	service := NewService(&stubRepo{}, nil, &stubRepo{}, nil, nil, nil, nil, collector, 10)
+	service.SetCollectLimits(CollectLimits{Concurrency: 1, JobTimeout: 40 * time.Millisecond})
+
+	_, err := service.CollectNow(context.Background(), domain.User{Role: domain.UserRoleFree}, channels)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ожидали превышение общего таймаута, получили %v", err)
+	}
+	if collector.calls >= len(channels) {
+		t.Fatalf("после таймаута новые каналы не должны браться, собрано %d", collector.calls)
+	}
+}
+
 func mustJSON(v any) []byte {
 	raw, err := json.Marshal(v)
 	if err != nil {
@@ -232,6 +548,35 @@ func (f *fakeSummarizer) Summarize(post domain.Post) (domain.Summary, error) {
 	return domain.Summary{Headline: "ok"}, nil
 }
 
+type batchSummarizer struct {
+	batchCalls  int
+	singleCalls int
+}
+
+func (b *batchSummarizer) Summarize(post domain.Post) (domain.Summary, error) {
+	b.singleCalls++
+	return domain.Summary{Headline: "поштучно"}, nil
+}
+
+func (b *batchSummarizer) SummarizeBatch(_ context.Context, posts []domain.Post) ([]domain.Summary, error) {
+	b.batchCalls++
+	out := make([]domain.Summary, len(posts))
+	for i := range posts {
+		out[i] = domain.Summary{Headline: "пачкой"}
+	}
+	return out, nil
+}
+
+type unsummarizedRanker struct{}
+
+func (unsummarizedRanker) Rank(posts []domain.Post) (domain.DigestOutline, error) {
+	items := make([]domain.RankedPost, 0, len(posts))
+	for i, post := range posts {
+		items = append(items, domain.RankedPost{Post: post, Score: float64(len(posts) - i)})
+	}
+	return domain.DigestOutline{Items: items}, nil
+}
+
 type fakeRanker struct {
 	captured []domain.Post
 }
@@ -247,3 +592,89 @@ func (f *fakeRanker) Rank(posts []domain.Post) (domain.DigestOutline, error) {
 		Items:    []domain.RankedPost{{Post: posts[0], Score: 1, Summary: domain.Summary{Headline: "ok"}}},
 	}, nil
 }
+
+type fakeCollections struct {
+	collected map[int64]time.Time
+}
+
+func (f *fakeCollections) MarkChannelCollected(channelID int64, collectedAt time.Time, _ int64) error {
+	f.collected[channelID] = collectedAt
+	return nil
+}
+
+func (f *fakeCollections) ListChannelCollections(channelIDs []int64) (map[int64]time.Time, error) {
+	out := make(map[int64]time.Time, len(channelIDs))
+	for _, id := range channelIDs {
+		if at, ok := f.collected[id]; ok {
+			out[id] = at
+		}
+	}
+	return out, nil
+}
+
+type fakeCollector struct {
+	aliases []string
+	// fail — ошибки сбора по алиасу канала.
+	fail map[string]error
+	// posts — посты, которые вернёт сбор канала.
+	posts map[string][]domain.Post
+}
+
+func (f *fakeCollector) Collect24h(channel domain.Channel) ([]domain.Post, error) {
+	return f.CollectSince(channel, time.Now().Add(-24*time.Hour))
+}
+
+func (f *fakeCollector) CollectSince(channel domain.Channel, _ time.Time) ([]domain.Post, error) {
+	f.aliases = append(f.aliases, channel.Alias)
+	return f.posts[channel.Alias], f.fail[channel.Alias]
+}
+
+func TestPreviewChannelRanksOnlyTopPosts(t *testing.T) {
+	var posts []domain.Post
+	for i := 0; i < 6; i++ {
+		posts = append(posts, domain.Post{TGMsgID: int64(i + 1), Text: "пост", RawMetaJSON: mustJSON(map[string]int{"views": i * 10})})
+	}
+	repo := &stubRepo{user: domain.User{ID: 1, TGUserID: 42}}
+	collector := &fakeCollector{posts: map[string][]domain.Post{"peek": posts}}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, collector, 10)
+
+	digest, err := service.PreviewChannel(repo.user, domain.Channel{Alias: "peek"})
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(ranker.captured) != PreviewItems {
+		t.Fatalf("ожидали %d поста в ранжировании, получили %d", PreviewItems, len(ranker.captured))
+	}
+	if ranker.captured[0].TGMsgID != 6 {
+		t.Fatalf("ожидали самый просматриваемый пост первым, получили %+v", ranker.captured[0])
+	}
+	if len(digest.Items) == 0 {
+		t.Fatalf("ожидали пункты пробного дайджеста")
+	}
+
+	collector.fail = map[string]error{"peek": errors.New("канал недоступен")}
+	if _, err := service.PreviewChannel(repo.user, domain.Channel{Alias: "peek"}); err == nil {
+		t.Fatalf("ожидали ошибку сбора")
+	}
+}
+
+func TestBuildForDateSkipsMutedChannels(t *testing.T) {
+	repo := &stubRepo{
+		user: domain.User{ID: 1, TGUserID: 42},
+		posts: []domain.Post{
+			{ID: 1, ChannelID: 1, Text: "активный канал", PublishedAt: time.Now()},
+			{ID: 2, ChannelID: 2, Text: "канал на мьюте", PublishedAt: time.Now()},
+		},
+		userChannels: []domain.UserChannel{{ChannelID: 1}, {ChannelID: 2, Muted: true}},
+	}
+	ranker := &fakeRanker{}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, ranker, nil, 10)
+
+	if _, err := service.BuildForDate(42, time.Now()); err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if len(ranker.captured) != 1 || ranker.captured[0].ChannelID != 1 {
+		t.Fatalf("ожидали посты только немьютнутого канала, получили %+v", ranker.captured)
+	}
+}
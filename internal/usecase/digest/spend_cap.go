@@ -0,0 +1,53 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// SpendCap — мягкий месячный лимит токенов LLM по тарифам. После его исчерпания
+// дайджест строится без LLM: Ranker и Summarizer должны быть экстрактивными.
+type SpendCap struct {
+	Usage domain.UsageRepo
+	// PlanTokens — лимит токенов в месяц; тарифа нет в карте или 0 — без ограничений.
+	PlanTokens map[domain.UserRole]int64
+	Ranker     domain.Ranker
+	Summarizer domain.Summarizer
+}
+
+// Limit возвращает месячный лимит токенов тарифа; 0 — без ограничений.
+func (c SpendCap) Limit(role domain.UserRole) int64 {
+	return c.PlanTokens[domain.PlanForRole(role).Role]
+}
+
+// Exceeded сообщает, израсходован ли лимит пользователя в месяце now.
+func (c SpendCap) Exceeded(ctx context.Context, user domain.User, now time.Time) (bool, error) {
+	limit := c.Limit(user.Role)
+	if limit <= 0 || c.Usage == nil {
+		return false, nil
+	}
+	usage, err := c.Usage.GetUsage(ctx, user.ID, now)
+	if err != nil {
+		return false, err
+	}
+	return usage.TotalTokens() >= limit, nil
+}
+
+// ParsePlanTokenCaps переводит лимиты из конфига (имя тарифа → токены) в карту по ролям.
+func ParsePlanTokenCaps(raw map[string]int64) (map[domain.UserRole]int64, error) {
+	caps := make(map[domain.UserRole]int64, len(raw))
+	for name, tokens := range raw {
+		role, ok := domain.ParseUserRole(name)
+		if !ok {
+			return nil, fmt.Errorf("неизвестный тариф %q", name)
+		}
+		if tokens < 0 {
+			return nil, fmt.Errorf("отрицательный лимит для тарифа %q", name)
+		}
+		caps[role] = tokens
+	}
+	return caps, nil
+}
@@ -0,0 +1,60 @@
+package digest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+type stubUsage struct {
+	domain.UsageRepo
+	tokens int64
+}
+
+func (s *stubUsage) GetUsage(_ context.Context, userID int64, month time.Time) (domain.UserUsage, error) {
+	return domain.UserUsage{UserID: userID, Month: month, LLMUsage: domain.LLMUsage{PromptTokens: s.tokens}}, nil
+}
+
+func TestBuildForDateFallsBackAfterSpendCap(t *testing.T) {
+	repo := &stubRepo{user: domain.User{ID: 1, TGUserID: 42, Role: domain.UserRoleFree}, posts: []domain.Post{{ID: 1, ChannelID: 1, URL: "https://t.me/a/1", Text: "пример", PublishedAt: time.Now()}}}
+	llmRanker, extractive := &fakeRanker{}, &fakeRanker{}
+	usage := &stubUsage{tokens: 999}
+	service := NewService(repo, repo, repo, nil, repo, &fakeSummarizer{}, llmRanker, nil, 10)
+	service.SetSpendCap(&SpendCap{
+		Usage:      usage,
+		PlanTokens: map[domain.UserRole]int64{domain.UserRoleFree: 1000},
+		Ranker:     extractive,
+		Summarizer: &fakeSummarizer{},
+	})
+
+	digest, err := service.BuildForDate(42, time.Now())
+	if err != nil || digest.Simplified || len(llmRanker.captured) != 1 {
+		t.Fatalf("до лимита ожидали LLM-ранжирование: simplified=%v, err=%v", digest.Simplified, err)
+	}
+
+	usage.tokens = 1000
+	digest, err = service.BuildForDate(42, time.Now())
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if !digest.Simplified || len(extractive.captured) != 1 {
+		t.Fatalf("после лимита ожидали экстрактивное ранжирование, simplified=%v", digest.Simplified)
+	}
+
+	repo.user.Role = domain.UserRolePro
+	if digest, _ = service.BuildForDate(42, time.Now()); digest.Simplified {
+		t.Fatalf("тариф без лимита не должен упрощаться")
+	}
+}
+
+func TestParsePlanTokenCaps(t *testing.T) {
+	caps, err := ParsePlanTokenCaps(map[string]int64{"Plus": 10, "pro": 0})
+	if err != nil || caps[domain.UserRolePlus] != 10 {
+		t.Fatalf("ожидали лимит plus=10, получили %v, %v", caps, err)
+	}
+	if _, err := ParsePlanTokenCaps(map[string]int64{"gold": 1}); err == nil {
+		t.Fatalf("неизвестный тариф должен давать ошибку")
+	}
+}
@@ -34,7 +34,7 @@ func (s *Service) UpdateDailyTime(ctx context.Context, tgUserID int64, local tim
 
 // UpdateTimezone сохраняет часовой пояс пользователя.
 func (s *Service) UpdateTimezone(ctx context.Context, tgUserID int64, timezone string) error {
-	normalized, err := normalizeTimezone(timezone)
+	normalized, err := NormalizeTimezone(timezone)
 	if err != nil {
 		return err
 	}
@@ -48,7 +48,8 @@ func (s *Service) UpdateTimezone(ctx context.Context, tgUserID int64, timezone s
 	return nil
 }
 
-func normalizeTimezone(raw string) (string, error) {
+// NormalizeTimezone приводит ввод к имени пояса IANA с учётом регистра и пробелов.
+func NormalizeTimezone(raw string) (string, error) {
 	candidate := strings.TrimSpace(raw)
 	if candidate == "" {
 		return "", ErrInvalidTimezone
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// Window — допуск вокруг времени доставки, в пределах которого планировщик ставит дайджест.
+const Window = 10 * time.Minute
+
+// NextWindow возвращает время ближайшей доставки в UTC, если текущий момент попадает
+// в окно вокруг него. При некорректном часовом поясе расчёт ведётся в UTC, а ошибка
+// загрузки пояса возвращается вместе с результатом.
+func NextWindow(now time.Time, user domain.User) (time.Time, bool, error) {
+	loc, loadErr := userLocation(user)
+	userNow := now.In(loc)
+	scheduledLocal := dailyAt(userNow, user.DailyTime)
+
+	if userNow.After(scheduledLocal.Add(Window)) {
+		// Переходим на следующий календарный день, а не на +24 часа: в день
+		// перевода часов сутки короче или длиннее.
+		scheduledLocal = dailyAt(userNow.AddDate(0, 0, 1), user.DailyTime)
+	}
+
+	diff := scheduledLocal.Sub(userNow)
+	if diff < -Window || diff > Window {
+		return time.Time{}, false, loadErr
+	}
+
+	return scheduledLocal.UTC(), true, loadErr
+}
+
+// NextRun возвращает ближайшее время доставки не раньше now. Ошибка часового пояса
+// обрабатывается так же, как в NextWindow.
+func NextRun(now time.Time, user domain.User) (time.Time, error) {
+	loc, loadErr := userLocation(user)
+	userNow := now.In(loc)
+	scheduledLocal := dailyAt(userNow, user.DailyTime)
+	if userNow.After(scheduledLocal) {
+		scheduledLocal = dailyAt(userNow.AddDate(0, 0, 1), user.DailyTime)
+	}
+	return scheduledLocal, loadErr
+}
+
+// TodaySlot возвращает время доставки в текущие сутки пользователя в UTC — тот же
+// ключ, под которым планировщик бронирует задачу в этот день.
+func TodaySlot(now time.Time, user domain.User) (time.Time, error) {
+	loc, loadErr := userLocation(user)
+	return dailyAt(now.In(loc), user.DailyTime).UTC(), loadErr
+}
+
+func userLocation(user domain.User) (*time.Location, error) {
+	loc, err := time.LoadLocation(user.TimezoneName())
+	if err != nil {
+		return time.UTC, err
+	}
+	return loc, nil
+}
+
+// dailyAt собирает время доставки в день и часовом поясе day.
+func dailyAt(day time.Time, daily time.Time) time.Time {
+	d := daily.In(time.UTC)
+	return time.Date(day.Year(), day.Month(), day.Day(), d.Hour(), d.Minute(), d.Second(), 0, day.Location())
+}
@@ -0,0 +1,198 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+func dailyTime(hour, minute int) time.Time {
+	return time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("нет tzdata для %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestNextWindowFallsBackToUTC(t *testing.T) {
+	user := domain.User{Timezone: "Mars/Olympus", DailyTime: dailyTime(9, 0)}
+	now := time.Date(2024, 5, 10, 9, 3, 0, 0, time.UTC)
+
+	scheduled, ok, err := NextWindow(now, user)
+	if err == nil {
+		t.Fatalf("ожидали ошибку загрузки часового пояса")
+	}
+	if !ok {
+		t.Fatalf("ожидали попадание в окно при расчёте по UTC")
+	}
+	want := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	if !scheduled.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s", want, scheduled)
+	}
+}
+
+func TestNextWindowEmptyTimezoneUsesDefault(t *testing.T) {
+	user := domain.User{DailyTime: dailyTime(21, 30)}
+	now := time.Date(2024, 5, 10, 21, 30, 0, 0, mustLocation(t, domain.DefaultTimezone()))
+
+	scheduled, ok, err := NextWindow(now, user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if !ok || !scheduled.Equal(now) {
+		t.Fatalf("ожидали %s, получили %s (ok=%v)", now, scheduled, ok)
+	}
+
+	if err := domain.SetDefaultTimezone("Asia/Yekaterinburg"); err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	t.Cleanup(func() { _ = domain.SetDefaultTimezone("Europe/Moscow") })
+	if _, ok, _ := NextWindow(now, user); ok {
+		t.Fatalf("после смены пояса по умолчанию 21:30 по Москве не должно попадать в окно")
+	}
+}
+
+func TestNextWindowBoundaries(t *testing.T) {
+	loc := mustLocation(t, "Europe/Moscow")
+	user := domain.User{Timezone: "Europe/Moscow", DailyTime: dailyTime(9, 0)}
+	scheduledLocal := time.Date(2024, 5, 10, 9, 0, 0, 0, loc)
+
+	cases := []struct {
+		name   string
+		offset time.Duration
+		ok     bool
+	}{
+		{"раньше окна", -Window - time.Second, false},
+		{"начало окна", -Window, true},
+		{"точно по времени", 0, true},
+		{"конец окна", Window, true},
+		{"после окна", Window + time.Second, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := scheduledLocal.Add(tc.offset).UTC()
+			scheduled, ok, err := NextWindow(now, user)
+			if err != nil {
+				t.Fatalf("не ожидали ошибку: %v", err)
+			}
+			if ok != tc.ok {
+				t.Fatalf("ожидали ok=%v, получили %v", tc.ok, ok)
+			}
+			if ok && !scheduled.Equal(scheduledLocal) {
+				t.Fatalf("ожидали %s, получили %s", scheduledLocal.UTC(), scheduled)
+			}
+			if ok && scheduled.Location() != time.UTC {
+				t.Fatalf("ожидали время в UTC, получили %s", scheduled.Location())
+			}
+		})
+	}
+}
+
+func TestNextWindowWrapsToNextDay(t *testing.T) {
+	loc := mustLocation(t, "Asia/Tokyo")
+	user := domain.User{Timezone: "Asia/Tokyo", DailyTime: dailyTime(0, 5)}
+	now := time.Date(2024, 12, 31, 23, 58, 0, 0, loc)
+
+	scheduled, ok, err := NextWindow(now.UTC(), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	want := time.Date(2025, 1, 1, 0, 5, 0, 0, loc)
+	if !ok || !scheduled.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s (ok=%v)", want.UTC(), scheduled, ok)
+	}
+}
+
+func TestNextWindowDSTDay(t *testing.T) {
+	loc := mustLocation(t, "Europe/Berlin")
+	user := domain.User{Timezone: "Europe/Berlin", DailyTime: dailyTime(9, 0)}
+
+	// 31.03.2024 часы в Берлине переводятся вперёд: 09:00 по местному — это 07:00 UTC, а не 08:00.
+	scheduled, ok, err := NextWindow(time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	want := time.Date(2024, 3, 31, 9, 0, 0, 0, loc)
+	if !ok || !scheduled.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s (ok=%v)", want.UTC(), scheduled, ok)
+	}
+	if _, ok, _ := NextWindow(time.Date(2024, 3, 31, 8, 0, 0, 0, time.UTC), user); ok {
+		t.Fatalf("не ожидали срабатывания по зимнему смещению")
+	}
+}
+
+func TestNextWindowWrapAcrossDSTChange(t *testing.T) {
+	loc := mustLocation(t, "America/Santiago")
+	user := domain.User{Timezone: "America/Santiago", DailyTime: dailyTime(0, 5)}
+
+	// В ночь на 07.04.2024 в Сантьяго часы переводятся назад в полночь,
+	// поэтому сутки длиннее 24 часов и простое +24h дало бы 23:05.
+	now := time.Date(2024, 4, 6, 23, 58, 0, 0, loc)
+	if _, ok, err := NextWindow(now.UTC(), user); err != nil || ok {
+		t.Fatalf("не ожидали срабатывания за час до местных 00:05 (ok=%v, err=%v)", ok, err)
+	}
+
+	want := time.Date(2024, 4, 7, 0, 5, 0, 0, loc)
+	scheduled, ok, err := NextWindow(want.Add(-time.Minute).UTC(), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if !ok || !scheduled.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s (ok=%v)", want.UTC(), scheduled, ok)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	loc := mustLocation(t, "Europe/Berlin")
+	user := domain.User{Timezone: "Europe/Berlin", DailyTime: dailyTime(9, 0)}
+
+	next, err := NextRun(time.Date(2024, 3, 30, 7, 0, 0, 0, time.UTC), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if want := time.Date(2024, 3, 30, 9, 0, 0, 0, loc); !next.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s", want, next)
+	}
+	if next.Location().String() != "Europe/Berlin" {
+		t.Fatalf("ожидали время в поясе пользователя, получили %s", next.Location())
+	}
+
+	next, err = NextRun(time.Date(2024, 3, 30, 9, 0, 0, 0, time.UTC), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if want := time.Date(2024, 3, 31, 9, 0, 0, 0, loc); !next.Equal(want) {
+		t.Fatalf("ожидали %s, получили %s", want, next)
+	}
+
+	if _, err := NextRun(time.Now(), domain.User{Timezone: "Mars/Olympus"}); err == nil {
+		t.Fatalf("ожидали ошибку загрузки часового пояса")
+	}
+}
+
+func TestTodaySlotMatchesScheduledKey(t *testing.T) {
+	user := domain.User{Timezone: "Asia/Tokyo", DailyTime: dailyTime(9, 0)}
+
+	// 10:00 по Токио: сегодняшняя доставка уже прошла, но слот остаётся сегодняшним.
+	slot, err := TodaySlot(time.Date(2024, 3, 30, 1, 0, 0, 0, time.UTC), user)
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if want := time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC); !slot.Equal(want) || slot.Location() != time.UTC {
+		t.Fatalf("ожидали %s в UTC, получили %s", want, slot)
+	}
+
+	scheduled, ok, err := NextWindow(time.Date(2024, 3, 30, 0, 5, 0, 0, time.UTC), user)
+	if err != nil || !ok {
+		t.Fatalf("ожидали попадание в окно, ok=%v err=%v", ok, err)
+	}
+	if !scheduled.Equal(slot) {
+		t.Fatalf("слот %s не совпадает с ключом планировщика %s", slot, scheduled)
+	}
+}
@@ -0,0 +1,107 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+	digestusecase "tg-digest-bot/internal/usecase/digest"
+	"tg-digest-bot/internal/usecase/schedule"
+)
+
+// Patch — изменения настроек; nil-поле оставляет текущее значение.
+type Patch struct {
+	DailyTime  *string
+	Timezone   *string
+	DigestLang *string
+}
+
+// ValidationError перечисляет некорректные поля: имя поля → описание ошибки.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "некорректные настройки: " + strings.Join(names, ", ")
+}
+
+// Service читает и меняет настройки доставки пользователя.
+type Service struct {
+	users domain.UserRepo
+}
+
+// NewService создаёт сервис настроек.
+func NewService(users domain.UserRepo) *Service {
+	return &Service{users: users}
+}
+
+// Get возвращает пользователя с текущими настройками.
+func (s *Service) Get(ctx context.Context, tgUserID int64) (domain.User, error) {
+	user, err := s.users.GetByTGID(tgUserID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("получение пользователя: %w", err)
+	}
+	return user, nil
+}
+
+// Update проверяет все поля патча и сохраняет их одним обновлением. При ошибках
+// валидации ничего не меняется, а возвращается *ValidationError со всеми полями сразу.
+func (s *Service) Update(ctx context.Context, tgUserID int64, patch Patch) (domain.User, error) {
+	user, err := s.users.GetByTGID(tgUserID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("получение пользователя: %w", err)
+	}
+	next, err := apply(user, patch)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := s.users.UpdateSettings(user.ID, next); err != nil {
+		return domain.User{}, fmt.Errorf("сохранение настроек: %w", err)
+	}
+	return s.Get(ctx, tgUserID)
+}
+
+func apply(user domain.User, patch Patch) (domain.UserSettings, error) {
+	next := domain.UserSettings{
+		DailyTime:  user.DailyTime,
+		Timezone:   user.Timezone,
+		DigestLang: user.DigestLang,
+	}
+	fields := make(map[string]string)
+	if patch.DailyTime != nil {
+		daily, err := time.Parse("15:04", strings.TrimSpace(*patch.DailyTime))
+		if err != nil {
+			fields["daily_time"] = "ожидается время в формате ЧЧ:ММ"
+		} else {
+			next.DailyTime = daily
+		}
+	}
+	if patch.Timezone != nil {
+		tz, err := schedule.NormalizeTimezone(*patch.Timezone)
+		if err != nil {
+			fields["timezone"] = "неизвестный часовой пояс"
+		} else {
+			next.Timezone = tz
+		}
+	}
+	if patch.DigestLang != nil {
+		lang := digestusecase.NormalizeLang(*patch.DigestLang)
+		if lang != "" && !digestusecase.IsSupportedLang(lang) {
+			fields["digest_lang"] = "язык не поддерживается"
+		} else {
+			next.DigestLang = lang
+		}
+	}
+	if len(fields) > 0 {
+		return domain.UserSettings{}, &ValidationError{Fields: fields}
+	}
+	return next, nil
+}
@@ -0,0 +1,69 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tg-digest-bot/internal/domain"
+)
+
+// stubUsers реализует только методы UserRepo, нужные сервису настроек.
+type stubUsers struct {
+	domain.UserRepo
+	user    domain.User
+	updates int
+}
+
+func (s *stubUsers) GetByTGID(int64) (domain.User, error) { return s.user, nil }
+
+func (s *stubUsers) UpdateSettings(_ int64, settings domain.UserSettings) error {
+	s.updates++
+	s.user.DailyTime = settings.DailyTime
+	s.user.Timezone = settings.Timezone
+	s.user.DigestLang = settings.DigestLang
+	return nil
+}
+
+func ptr(s string) *string { return &s }
+
+func TestUpdateNormalizesAndKeepsUntouchedFields(t *testing.T) {
+	users := &stubUsers{user: domain.User{ID: 1, Timezone: "Europe/Berlin", DigestLang: "de", DailyTime: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)}}
+	svc := NewService(users)
+
+	got, err := svc.Update(context.Background(), 42, Patch{Timezone: ptr("europe/moscow"), DigestLang: ptr(" EN-us ")})
+	if err != nil {
+		t.Fatalf("не ожидали ошибку: %v", err)
+	}
+	if got.Timezone != "Europe/Moscow" || got.DigestLang != "en" {
+		t.Fatalf("ожидали нормализованные значения, получили %q/%q", got.Timezone, got.DigestLang)
+	}
+	if got.DailyTime.Format("15:04") != "09:00" {
+		t.Fatalf("время не передавали, оно должно остаться 09:00, получили %s", got.DailyTime.Format("15:04"))
+	}
+
+	got, err = svc.Update(context.Background(), 42, Patch{DigestLang: ptr("")})
+	if err != nil || got.DigestLang != "" {
+		t.Fatalf("пустой язык выключает фильтр: %q, %v", got.DigestLang, err)
+	}
+}
+
+func TestUpdateReportsAllInvalidFieldsWithoutSaving(t *testing.T) {
+	users := &stubUsers{user: domain.User{ID: 1}}
+	svc := NewService(users)
+
+	_, err := svc.Update(context.Background(), 42, Patch{DailyTime: ptr("25:99"), Timezone: ptr("Mars/Olympus"), DigestLang: ptr("xx")})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ожидали ValidationError, получили %v", err)
+	}
+	for _, field := range []string{"daily_time", "timezone", "digest_lang"} {
+		if verr.Fields[field] == "" {
+			t.Fatalf("нет ошибки для поля %s: %#v", field, verr.Fields)
+		}
+	}
+	if users.updates != 0 {
+		t.Fatalf("при ошибке валидации настройки не должны сохраняться")
+	}
+}
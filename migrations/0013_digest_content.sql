@@ -0,0 +1,9 @@
+ALTER TABLE user_digests
+    ADD COLUMN IF NOT EXISTS overview TEXT,
+    ADD COLUMN IF NOT EXISTS theses_json JSONB;
+
+ALTER TABLE post_summaries
+    ADD COLUMN IF NOT EXISTS topic TEXT,
+    ADD COLUMN IF NOT EXISTS topic_summary TEXT;
+
+CREATE INDEX IF NOT EXISTS user_digest_items_digest_id_idx ON user_digest_items(digest_id);
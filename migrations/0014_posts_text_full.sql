@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS text_full TEXT;
@@ -0,0 +1,57 @@
+CREATE OR REPLACE FUNCTION merge_channels(keep_id BIGINT, drop_id BIGINT) RETURNS VOID AS $$
+BEGIN
+    IF keep_id = drop_id THEN
+        RETURN;
+    END IF;
+
+    -- Подписки: переносим только те, которых ещё нет у оставляемого канала.
+    UPDATE user_channels uc SET channel_id = keep_id
+    WHERE uc.channel_id = drop_id
+      AND NOT EXISTS (
+          SELECT 1 FROM user_channels k WHERE k.user_id = uc.user_id AND k.channel_id = keep_id
+      );
+    DELETE FROM user_channels WHERE channel_id = drop_id;
+
+    -- Дубли постов: перевешиваем ссылки дайджестов и суммаризаций на уже существующий пост.
+    UPDATE user_digest_items i SET post_id = k.id
+    FROM posts d JOIN posts k ON k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+    WHERE d.channel_id = drop_id AND i.post_id = d.id;
+    UPDATE post_summaries s SET post_id = k.id
+    FROM posts d JOIN posts k ON k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+    WHERE d.channel_id = drop_id AND s.post_id = d.id;
+
+    UPDATE posts p SET channel_id = keep_id
+    WHERE p.channel_id = drop_id
+      AND NOT EXISTS (
+          SELECT 1 FROM posts k WHERE k.channel_id = keep_id AND k.tg_msg_id = p.tg_msg_id
+      );
+    DELETE FROM posts WHERE channel_id = drop_id;
+
+    DELETE FROM channels WHERE id = drop_id;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    dup RECORD;
+BEGIN
+    FOR dup IN
+        SELECT c.id AS drop_id, k.keep_id
+        FROM channels c
+        JOIN (
+            SELECT tg_channel_id, MIN(id) AS keep_id
+            FROM channels
+            WHERE tg_channel_id IS NOT NULL AND tg_channel_id <> 0
+            GROUP BY tg_channel_id
+            HAVING COUNT(*) > 1
+        ) k ON k.tg_channel_id = c.tg_channel_id
+        WHERE c.id <> k.keep_id
+    LOOP
+        PERFORM merge_channels(dup.keep_id, dup.drop_id);
+    END LOOP;
+END;
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS channels_tg_channel_id_uq
+    ON channels (tg_channel_id)
+    WHERE tg_channel_id IS NOT NULL AND tg_channel_id <> 0;
@@ -0,0 +1,2 @@
+ALTER TABLE channel_collections
+    ADD COLUMN IF NOT EXISTS last_msg_id BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS plan_activations (
+    payment_id   BIGINT PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role         TEXT NOT NULL,
+    attempts     INT NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    activated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS plan_activations_pending_idx ON plan_activations(created_at) WHERE activated_at IS NULL;
@@ -0,0 +1,3 @@
+-- NULL — лимит берётся из тарифа, 0 — без ограничений, N > 0 — индивидуальный лимит.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS channel_limit_override INT CHECK (channel_limit_override IS NULL OR channel_limit_override >= 0);
@@ -0,0 +1,3 @@
+-- Пользовательская настройка отсева рекламных постов из дайджестов.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS filter_ads BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+-- Доставка дайджеста на почту: подтверждённый адрес, ожидающий подтверждения адрес с кодом и режим доставки.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email TEXT,
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS email_pending TEXT,
+    ADD COLUMN IF NOT EXISTS email_code TEXT,
+    ADD COLUMN IF NOT EXISTS email_code_expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS delivery_mode TEXT NOT NULL DEFAULT 'telegram'
+        CHECK (delivery_mode IN ('telegram', 'email', 'both'));
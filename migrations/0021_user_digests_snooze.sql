@@ -0,0 +1,3 @@
+-- Сколько раз пользователь откладывал дайджест кнопкой «Напомнить через 2ч».
+ALTER TABLE user_digests
+    ADD COLUMN IF NOT EXISTS snooze_count INT NOT NULL DEFAULT 0;
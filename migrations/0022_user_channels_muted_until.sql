@@ -0,0 +1,5 @@
+-- Временный мьют: канал считается выключенным, пока muted_until > now(). NULL — мьют без срока.
+ALTER TABLE user_channels
+    ADD COLUMN IF NOT EXISTS muted_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_user_channels_muted_until ON user_channels (muted_until) WHERE muted_until IS NOT NULL;
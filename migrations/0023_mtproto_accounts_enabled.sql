@@ -0,0 +1,3 @@
+-- Отключённые аккаунты не попадают в пулы collector и bot-gateway, запись и сессия сохраняются.
+ALTER TABLE mtproto_accounts
+    ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
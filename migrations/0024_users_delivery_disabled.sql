@@ -0,0 +1,3 @@
+-- Пользователь заблокировал бота или чат пропал: плановые дайджесты в Telegram не ставятся, пока он снова не нажмёт /start.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS delivery_disabled BOOLEAN NOT NULL DEFAULT FALSE;
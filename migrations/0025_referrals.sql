@@ -0,0 +1,13 @@
+-- Приглашения с данными приглашённого на момент входа: по ним ищем накрутку, подозрительные (flagged) не засчитываются до ручной проверки.
+CREATE TABLE IF NOT EXISTS referrals (
+    referred_user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    referrer_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status           TEXT NOT NULL DEFAULT 'counted',
+    tg_user_id       BIGINT NOT NULL,
+    locale           TEXT,
+    is_bot           BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS referrals_referrer_created_idx ON referrals(referrer_id, created_at);
+CREATE INDEX IF NOT EXISTS referrals_flagged_idx ON referrals(created_at) WHERE status = 'flagged';
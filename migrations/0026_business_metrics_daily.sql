@@ -0,0 +1,9 @@
+-- Дневные агрегаты business_metrics для дашбордов; пересчитываются планировщиком за прошедший день.
+CREATE TABLE IF NOT EXISTS business_metrics_daily (
+    day            DATE NOT NULL,
+    event          TEXT NOT NULL,
+    count          BIGINT NOT NULL,
+    distinct_users BIGINT NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (day, event)
+);
@@ -0,0 +1,3 @@
+-- Язык постов в дайджестах пользователя (/filter_lang): NULL — показывать посты на любом языке.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS digest_lang TEXT;
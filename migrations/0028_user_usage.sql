@@ -0,0 +1,11 @@
+-- Помесячный расход LLM на пользователя для мягкого лимита тарифа; новый месяц — новая строка.
+CREATE TABLE IF NOT EXISTS user_usage (
+    user_id            BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month              DATE NOT NULL,
+    prompt_tokens      BIGINT NOT NULL DEFAULT 0,
+    completion_tokens  BIGINT NOT NULL DEFAULT 0,
+    cost_usd           NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    cap_notified_at    TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, month)
+);
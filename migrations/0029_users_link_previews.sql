@@ -0,0 +1,3 @@
+-- Превью ссылки у главного пункта дайджеста (/link_previews): по умолчанию выключено.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS link_previews BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,8 @@
+-- Отметки отправленных дайджестов: повтор задачи из очереди не присылает дайджест второй раз.
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+    key     TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS digest_deliveries_sent_at_idx ON digest_deliveries (sent_at);
@@ -0,0 +1,4 @@
+-- Индексы для очистки постов по сроку хранения: выборка старых постов и каскадное удаление связанных строк.
+CREATE INDEX IF NOT EXISTS posts_published_at_idx ON posts (published_at);
+CREATE INDEX IF NOT EXISTS user_digest_items_post_id_idx ON user_digest_items (post_id);
+CREATE INDEX IF NOT EXISTS post_summaries_post_id_idx ON post_summaries (post_id);
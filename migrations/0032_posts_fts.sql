@@ -0,0 +1,2 @@
+-- Полнотекстовый индекс постов для /find; выражение должно совпадать с запросом SearchUserDigestPosts.
+CREATE INDEX IF NOT EXISTS posts_text_fts_idx ON posts USING GIN (to_tsvector('russian', COALESCE(text_trunc, '')));
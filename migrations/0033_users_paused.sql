@@ -0,0 +1,4 @@
+-- Пауза всех дайджестов (/pause): paused_until NULL при paused=true — пауза без срока.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS paused_until TIMESTAMPTZ;
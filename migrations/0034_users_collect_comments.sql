@@ -0,0 +1,3 @@
+-- Сбор комментариев обсуждаемых постов (/comments, тариф Pro): по умолчанию выключено.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS collect_comments BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Порядок пунктов дайджеста (/order): по умолчанию — оценка LLM.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS order_by TEXT NOT NULL DEFAULT 'relevance'
+        CHECK (order_by IN ('relevance', 'time', 'engagement'));
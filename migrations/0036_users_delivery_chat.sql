@@ -0,0 +1,3 @@
+-- Чат для плановых дайджестов (/setchat): группа или канал вместо лички. NULL — личные сообщения.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS delivery_chat_id BIGINT;
@@ -0,0 +1,3 @@
+-- Пул MTProto-аккаунтов, через который собирается канал (например, по региону). NULL — пул по умолчанию.
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS mtproto_pool TEXT;
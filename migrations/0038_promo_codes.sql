@@ -0,0 +1,21 @@
+-- Промокоды: kind=balance зачисляет value копеек, kind=plan выдаёт тариф plan (value — срок в днях).
+CREATE TABLE IF NOT EXISTS promo_codes (
+    code       TEXT PRIMARY KEY,
+    kind       TEXT NOT NULL CHECK (kind IN ('balance', 'plan')),
+    value      BIGINT NOT NULL CHECK (value > 0),
+    plan       TEXT,
+    max_uses   INT NOT NULL CHECK (max_uses > 0),
+    used       INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    created_by BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Одна активация на пользователя; applied_at ставится, когда бонус на баланс зачислен.
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    code        TEXT NOT NULL REFERENCES promo_codes(code) ON DELETE CASCADE,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    applied_at  TIMESTAMPTZ,
+    PRIMARY KEY (code, user_id)
+);
@@ -0,0 +1,10 @@
+-- Покупка тарифа записывается до списания: если ответ биллинга потерялся или активацию
+-- не удалось сохранить, сверка найдёт списание по charge_key и выдаст тариф.
+CREATE TABLE IF NOT EXISTS plan_charges (
+    charge_key TEXT PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS plan_charges_created_idx ON plan_charges(created_at);
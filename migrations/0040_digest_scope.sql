@@ -0,0 +1,18 @@
+-- Одна суммаризация на пост: пересборка дайджеста обновляет её, а не дописывает новую.
+UPDATE user_digest_items i SET summary_id = k.keep_id
+FROM (SELECT id, max(id) OVER (PARTITION BY post_id) AS keep_id FROM post_summaries) k
+WHERE i.summary_id = k.id AND k.id <> k.keep_id;
+
+DELETE FROM post_summaries s
+USING post_summaries newer
+WHERE s.post_id = newer.post_id AND s.id < newer.id;
+
+DROP INDEX IF EXISTS post_summaries_post_id_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS post_summaries_post_id_key ON post_summaries (post_id);
+
+-- Дайджесты канала, тегов и прошедшего дня хранятся отдельно от ежедневного за ту же дату.
+ALTER TABLE user_digests
+    ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE user_digests DROP CONSTRAINT IF EXISTS user_digests_user_id_date_key;
+CREATE UNIQUE INDEX IF NOT EXISTS user_digests_user_date_scope_key ON user_digests (user_id, date, scope);
@@ -0,0 +1,45 @@
+-- post_summaries теперь уникальна по post_id: при слиянии каналов суммаризация дубля поста
+-- удаляется, если у оставляемого поста она уже есть, а ссылки дайджестов переходят на неё.
+CREATE OR REPLACE FUNCTION merge_channels(keep_id BIGINT, drop_id BIGINT) RETURNS VOID AS $$
+BEGIN
+    IF keep_id = drop_id THEN
+        RETURN;
+    END IF;
+
+    -- Подписки: переносим только те, которых ещё нет у оставляемого канала.
+    UPDATE user_channels uc SET channel_id = keep_id
+    WHERE uc.channel_id = drop_id
+      AND NOT EXISTS (
+          SELECT 1 FROM user_channels k WHERE k.user_id = uc.user_id AND k.channel_id = keep_id
+      );
+    DELETE FROM user_channels WHERE channel_id = drop_id;
+
+    -- Дубли постов: перевешиваем ссылки дайджестов и суммаризаций на уже существующий пост.
+    UPDATE user_digest_items i SET post_id = k.id
+    FROM posts d JOIN posts k ON k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+    WHERE d.channel_id = drop_id AND i.post_id = d.id;
+    UPDATE user_digest_items i SET summary_id = ks.id
+    FROM post_summaries s
+    JOIN posts d ON d.id = s.post_id
+    JOIN posts k ON k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+    JOIN post_summaries ks ON ks.post_id = k.id
+    WHERE d.channel_id = drop_id AND i.summary_id = s.id;
+    DELETE FROM post_summaries s
+    USING posts d, posts k, post_summaries ks
+    WHERE d.channel_id = drop_id AND s.post_id = d.id
+      AND k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+      AND ks.post_id = k.id;
+    UPDATE post_summaries s SET post_id = k.id
+    FROM posts d JOIN posts k ON k.channel_id = keep_id AND k.tg_msg_id = d.tg_msg_id
+    WHERE d.channel_id = drop_id AND s.post_id = d.id;
+
+    UPDATE posts p SET channel_id = keep_id
+    WHERE p.channel_id = drop_id
+      AND NOT EXISTS (
+          SELECT 1 FROM posts k WHERE k.channel_id = keep_id AND k.tg_msg_id = p.tg_msg_id
+      );
+    DELETE FROM posts WHERE channel_id = drop_id;
+
+    DELETE FROM channels WHERE id = drop_id;
+END;
+$$ LANGUAGE plpgsql;
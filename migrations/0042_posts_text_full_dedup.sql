@@ -0,0 +1,2 @@
+-- text_full нужен, только если пост длиннее text_trunc; копии короткого текста не храним.
+UPDATE posts SET text_full = NULL WHERE text_full = text_trunc;
@@ -0,0 +1,4 @@
+-- /find ищет по полному тексту поста: text_trunc обрезан, а text_full заполнен только у длинных постов.
+-- Выражение должно совпадать с запросом SearchUserDigestPosts.
+DROP INDEX IF EXISTS posts_text_fts_idx;
+CREATE INDEX IF NOT EXISTS posts_text_fts_idx ON posts USING GIN (to_tsvector('russian', COALESCE(text_full, text_trunc, '')));